@@ -0,0 +1,227 @@
+// Package gpagorm provides cross-instance advisory locks
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// lockTableTTL is how long a lock table row stays valid without renewal
+	lockTableTTL = 30 * time.Second
+	// lockPollInterval is how often a waiting lock table acquirer retries
+	lockPollInterval = 100 * time.Millisecond
+)
+
+// lockRow is a row of the lock table used when the database has no advisory locks
+type lockRow struct {
+	Name      string    `gorm:"primaryKey;size:255"`
+	Owner     string    `gorm:"size:64;not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// TableName returns the lock table name
+func (lockRow) TableName() string { return "gpagorm_locks" }
+
+// WithAdvisoryLock runs fn while holding an exclusive lock on key shared by
+// every process using the same database. It blocks until the lock is
+// acquired or ctx is done. Postgres uses pg_advisory_lock, MySQL GET_LOCK,
+// and other databases a lease row in the gpagorm_locks table.
+func (p *Provider) WithAdvisoryLock(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	var (
+		release func() error
+		err     error
+	)
+	switch dialectName(p.db) {
+	case "postgres":
+		release, err = p.sessionLock(ctx,
+			"SELECT pg_advisory_lock($1)", "SELECT pg_advisory_unlock($1)", advisoryLockID(key))
+	case "mysql":
+		release, err = p.sessionLock(ctx,
+			"SELECT GET_LOCK(?, -1)", "SELECT RELEASE_LOCK(?)", mysqlLockName(key))
+	default:
+		release, err = p.tableLock(ctx, key)
+	}
+	if err != nil {
+		return err
+	}
+
+	fnErr := fn(ctx)
+	return errors.Join(fnErr, release())
+}
+
+// sessionLock acquires a session-scoped lock on a dedicated connection
+func (p *Provider) sessionLock(ctx context.Context, lockSQL, unlockSQL string, arg interface{}) (func() error, error) {
+	sqlDB, err := p.db.DB()
+	if err != nil {
+		return nil, convertGormError(err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, convertGormError(err)
+	}
+
+	var acquired interface{}
+	if err := conn.QueryRowContext(ctx, lockSQL, arg).Scan(&acquired); err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, lockTimeoutError(ctx)
+		}
+		return nil, convertGormError(err)
+	}
+	if !lockSucceeded(acquired) {
+		conn.Close()
+		return nil, gpa.NewError(gpa.ErrorTypeDatabase, "failed to acquire advisory lock")
+	}
+
+	return func() error {
+		defer conn.Close()
+		var released interface{}
+		if err := conn.QueryRowContext(context.Background(), unlockSQL, arg).Scan(&released); err != nil {
+			return convertGormError(err)
+		}
+		if !lockSucceeded(released) {
+			return gpa.NewError(gpa.ErrorTypeDatabase, "advisory lock was not held when released")
+		}
+		return nil
+	}, nil
+}
+
+// lockSucceeded interprets the result of a lock or unlock query:
+// pg_advisory_lock returns void, which drivers hand back as an empty
+// string, pg_advisory_unlock a boolean, and GET_LOCK and RELEASE_LOCK 1 on
+// success, 0 on failure and NULL on error
+func lockSucceeded(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int64:
+		return v == 1
+	case []byte:
+		return lockSucceeded(string(v))
+	case string:
+		if v == "" {
+			return true
+		}
+		ok, _ := strconv.ParseBool(v)
+		return ok
+	default:
+		return false
+	}
+}
+
+// tableLock acquires a lease row, renewing it until released
+func (p *Provider) tableLock(ctx context.Context, key string) (func() error, error) {
+	db := p.db.WithContext(ctx)
+	if err := ensureLockTable(db); err != nil {
+		return nil, err
+	}
+
+	owner := NewUUID().String()
+	for {
+		acquired, err := acquireLease(db, key, owner, lockTableTTL)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, lockTimeoutError(ctx)
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(lockTableTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				renewLease(p.db, key, owner, lockTableTTL)
+			}
+		}
+	}()
+
+	return func() error {
+		close(stop)
+		<-done
+		return releaseLease(p.db, key, owner)
+	}, nil
+}
+
+// ensureLockTable creates the lock table if it does not exist
+func ensureLockTable(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if migrator.HasTable(&lockRow{}) {
+		return nil
+	}
+	if err := migrator.CreateTable(&lockRow{}); err != nil && !migrator.HasTable(&lockRow{}) {
+		return convertGormError(err)
+	}
+	return nil
+}
+
+// acquireLease takes the lease row for name if it is free or expired
+func acquireLease(db *gorm.DB, name, owner string, ttl time.Duration) (bool, error) {
+	now := db.NowFunc()
+	if err := db.Where("name = ? AND expires_at < ?", name, now).Delete(&lockRow{}).Error; err != nil {
+		return false, convertGormError(err)
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&lockRow{Name: name, Owner: owner, ExpiresAt: now.Add(ttl)})
+	if result.Error != nil {
+		return false, convertGormError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// renewLease extends the lease if owner still holds it
+func renewLease(db *gorm.DB, name, owner string, ttl time.Duration) (bool, error) {
+	result := db.Model(&lockRow{}).Where("name = ? AND owner = ?", name, owner).
+		Update("expires_at", db.NowFunc().Add(ttl))
+	if result.Error != nil {
+		return false, convertGormError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// releaseLease deletes the lease if owner still holds it
+func releaseLease(db *gorm.DB, name, owner string) error {
+	err := db.Where("name = ? AND owner = ?", name, owner).Delete(&lockRow{}).Error
+	return convertGormError(err)
+}
+
+// advisoryLockID maps a lock key to a Postgres advisory lock id
+func advisoryLockID(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// mysqlLockName fits a lock key within MySQL's 64 character limit
+func mysqlLockName(key string) string {
+	if len(key) <= 64 {
+		return key
+	}
+	return fmt.Sprintf("gpagorm:%016x", uint64(advisoryLockID(key)))
+}
+
+// lockTimeoutError reports a lock that was not acquired before ctx ended
+func lockTimeoutError(ctx context.Context) error {
+	return gpa.NewErrorWithCause(gpa.ErrorTypeTimeout, "timed out waiting for advisory lock", ctx.Err())
+}
@@ -0,0 +1,125 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestWithAdvisoryLock(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	ran := false
+	err := provider.WithAdvisoryLock(ctx, "jobs:nightly", func(ctx context.Context) error {
+		ran = true
+
+		var held int64
+		provider.db.Model(&lockRow{}).Where("name = ?", "jobs:nightly").Count(&held)
+		if held != 1 {
+			t.Errorf("Expected lock row while held, got %d", held)
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, 250*time.Millisecond)
+		defer cancel()
+		err := provider.WithAdvisoryLock(waitCtx, "jobs:nightly", func(context.Context) error {
+			t.Error("Expected nested acquisition of a held lock to block")
+			return nil
+		})
+		var gpaErr gpa.GPAError
+		if !errors.As(err, &gpaErr) || gpaErr.Type != gpa.ErrorTypeTimeout {
+			t.Errorf("Expected timeout error, got %v", err)
+		}
+
+		return provider.WithAdvisoryLock(ctx, "jobs:other", func(context.Context) error { return nil })
+	})
+	if err != nil {
+		t.Fatalf("Failed to run with lock: %v", err)
+	}
+	if !ran {
+		t.Fatal("Expected fn to run")
+	}
+
+	var remaining int64
+	provider.db.Model(&lockRow{}).Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected locks to be released, %d remain", remaining)
+	}
+
+	fnErr := errors.New("job failed")
+	if err := provider.WithAdvisoryLock(ctx, "jobs:nightly", func(context.Context) error { return fnErr }); !errors.Is(err, fnErr) {
+		t.Errorf("Expected fn error to be returned, got %v", err)
+	}
+}
+
+func TestAcquireLeaseExpired(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	if err := ensureLockTable(provider.db); err != nil {
+		t.Fatalf("Failed to create lock table: %v", err)
+	}
+	if ok, err := acquireLease(provider.db, "stale", "a", -time.Second); err != nil || !ok {
+		t.Fatalf("Expected first lease, got %v %v", ok, err)
+	}
+	if ok, err := acquireLease(provider.db, "stale", "b", time.Minute); err != nil || !ok {
+		t.Fatalf("Expected expired lease to be taken over, got %v %v", ok, err)
+	}
+	if ok, _ := acquireLease(provider.db, "stale", "c", time.Minute); ok {
+		t.Error("Expected live lease to be held")
+	}
+	if ok, _ := renewLease(provider.db, "stale", "a", time.Minute); ok {
+		t.Error("Expected previous owner not to renew")
+	}
+}
+
+func TestLockSucceeded(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  bool
+	}{
+		{"", true}, // pg_advisory_lock's void
+		{[]byte(""), true},
+		{true, true}, // pg_advisory_unlock
+		{false, false},
+		{int64(1), true}, // GET_LOCK and RELEASE_LOCK
+		{int64(0), false},
+		{[]byte("1"), true},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := lockSucceeded(tt.value); got != tt.want {
+			t.Errorf("lockSucceeded(%#v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSessionLockResults(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// A void lock result and a true unlock result, as Postgres returns them
+	release, err := provider.sessionLock(ctx, "SELECT '' WHERE ? IS NOT NULL", "SELECT ? = 7", 7)
+	if err != nil {
+		t.Fatalf("Expected the lock to be acquired, got %v", err)
+	}
+	if err := release(); err != nil {
+		t.Errorf("Expected the lock to be released, got %v", err)
+	}
+
+	if _, err := provider.sessionLock(ctx, "SELECT ? = 0", "SELECT 1", 7); err == nil {
+		t.Error("Expected a failed acquisition to be reported")
+	}
+	release, err = provider.sessionLock(ctx, "SELECT ?", "SELECT ? = 0", 1)
+	if err != nil {
+		t.Fatalf("Expected the lock to be acquired, got %v", err)
+	}
+	if err := release(); err == nil {
+		t.Error("Expected a failed release to be reported")
+	}
+}
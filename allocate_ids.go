@@ -0,0 +1,105 @@
+// Package gpagorm provides pre-allocation of generated primary keys
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// AllocateIDs reserves n values of T's auto-increment primary key in one
+// round trip and returns them in ascending order. Rows inserted with a
+// reserved ID keep it and later generated IDs skip the reserved ones, so
+// a bulk importer can assign child foreign keys before inserting the
+// parents. IDs that end up unused are simply skipped.
+//
+// Postgres draws from the column's sequence; the IDs are contiguous unless
+// other sessions draw at the same time. SQLite advances the table's
+// AUTOINCREMENT counter and needs the table to be declared AUTOINCREMENT,
+// as GORM does. MySQL and SQL Server cannot reserve identity values
+// without inserting rows and are not supported.
+func (r *Repository[T]) AllocateIDs(ctx context.Context, n int) (ids []int64, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationAllocateIDs}, func(ctx context.Context) error {
+		var err error
+		ids, err = r.allocateIDs(ctx, n)
+		return err
+	})
+	return ids, err
+}
+
+// allocateIDs implements AllocateIDs
+func (r *Repository[T]) allocateIDs(ctx context.Context, n int) ([]int64, error) {
+	if n <= 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("cannot allocate %d IDs", n))
+	}
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+	pk := s.PrioritizedPrimaryField
+	if pk == nil || !pk.AutoIncrement {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, s.Name+" has no auto-increment primary key")
+	}
+	table := s.Table
+	if r.table != "" {
+		table = r.table
+	}
+
+	db := r.session(ctx)
+	var ids []int64
+	switch dialect := dialectName(db); dialect {
+	case "postgres":
+		err = db.Raw("SELECT nextval(pg_get_serial_sequence(?, ?)) FROM generate_series(1, ?) ORDER BY 1",
+			db.Statement.Quote(table), pk.DBName, n).Scan(&ids).Error
+	case "sqlite":
+		ids, err = allocateSQLiteIDs(db, table, pk.DBName, n)
+	default:
+		return nil, gpa.NewError(gpa.ErrorTypeUnsupported, "ID allocation is not supported on "+dialect)
+	}
+	if err != nil {
+		return nil, convertGormError(err)
+	}
+	return ids, nil
+}
+
+// allocateSQLiteIDs advances the AUTOINCREMENT counter of table by n past
+// both its current value and the largest key in use
+func allocateSQLiteIDs(db *gorm.DB, table, column string, n int) ([]int64, error) {
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		table = table[i+1:]
+	}
+
+	var ddl string
+	if err := db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&ddl).Error; err != nil {
+		return nil, err
+	}
+	if !strings.Contains(strings.ToUpper(ddl), "AUTOINCREMENT") {
+		return nil, gpa.NewError(gpa.ErrorTypeUnsupported, "ID allocation needs an AUTOINCREMENT key on table "+table)
+	}
+
+	var last int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		inUse := fmt.Sprintf("(SELECT COALESCE(MAX(%s), 0) FROM %s)", tx.Statement.Quote(column), tx.Statement.Quote(table))
+		result := tx.Raw("UPDATE sqlite_sequence SET seq = MAX(seq, "+inUse+") + ? WHERE name = ? RETURNING seq", n, table).Scan(&last)
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+		// The counter row appears with the table's first insert
+		return tx.Raw("INSERT INTO sqlite_sequence (name, seq) VALUES (?, "+inUse+" + ?) RETURNING seq", table, n).Scan(&last).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = last - int64(n) + 1 + int64(i)
+	}
+	return ids, nil
+}
@@ -0,0 +1,63 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestAllocateIDs(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	// Before the table's first insert
+	ids, err := repo.AllocateIDs(ctx, 3)
+	if err != nil {
+		t.Fatalf("AllocateIDs failed: %v", err)
+	}
+	if fmt.Sprint(ids) != "[1 2 3]" {
+		t.Errorf("Expected [1 2 3], got %v", ids)
+	}
+
+	if err := repo.Create(ctx, &TestUser{ID: uint(ids[1]), Name: "Reserved", Email: "reserved@example.com"}); err != nil {
+		t.Fatalf("Create with a reserved ID failed: %v", err)
+	}
+	generated := &TestUser{Name: "Generated", Email: "generated@example.com"}
+	if err := repo.Create(ctx, generated); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if generated.ID != 4 {
+		t.Errorf("Expected the generated ID to skip the reserved ones, got %d", generated.ID)
+	}
+
+	ids, err = repo.AllocateIDs(ctx, 2)
+	if err != nil || fmt.Sprint(ids) != "[5 6]" {
+		t.Errorf("Expected [5 6], got %v (%v)", ids, err)
+	}
+
+	if _, err := repo.AllocateIDs(ctx, 0); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for 0 IDs, got %v", err)
+	}
+}
+
+func TestAllocateIDsPostgres(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry-run postgres: %v", err)
+	}
+	var sql string
+	db.Callback().Row().After("*").Register("test:record", func(db *gorm.DB) { sql = db.Statement.SQL.String() })
+
+	// Dry runs cannot scan the reserved IDs; only the statement is checked
+	NewRepository[TestUser](db, nil).WithTable("app.users").AllocateIDs(context.Background(), 100)
+	if want := "SELECT nextval(pg_get_serial_sequence($1, $2)) FROM generate_series(1, $3)"; !strings.Contains(sql, want) {
+		t.Errorf("Expected %s, got %s", want, sql)
+	}
+}
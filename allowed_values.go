@@ -0,0 +1,234 @@
+// Package gpagorm provides enum and check-constraint aware value validation
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// AllowedValuesError reports a field value outside its allowed set
+type AllowedValuesError struct {
+	Field   string
+	Value   interface{}
+	Allowed []string
+}
+
+// Error returns the error message for AllowedValuesError.
+func (e *AllowedValuesError) Error() string {
+	return fmt.Sprintf("invalid value %v for field %s: must be one of %s",
+		e.Value, e.Field, strings.Join(e.Allowed, ", "))
+}
+
+// parseEnumTag parses an `enum:"a,b,c"` struct tag
+func parseEnumTag(field *schema.Field) []string {
+	tag, ok := field.Tag.Lookup("enum")
+	if !ok || tag == "" {
+		return nil
+	}
+	values := strings.Split(tag, ",")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+// checkAllowedValues validates entity fields against their allowed values,
+// taken from `enum` struct tags and from constraints discovered with
+// Provider.DiscoverAllowedValues.
+func (r *Repository[T]) checkAllowedValues(ctx context.Context, entity *T) error {
+	s, discovered, err := r.allowedValueSources()
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(entity).Elem()
+	for _, field := range s.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		allowed := allowedValues(field, discovered)
+		if allowed == nil {
+			continue
+		}
+
+		value, isZero := field.ValueOf(ctx, rv)
+		if isZero && field.HasDefaultValue {
+			// Left to the database default
+			continue
+		}
+		if err := checkAllowedValue(field, value, allowed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkAllowedUpdates validates the values of an update map like
+// checkAllowedValues; SQL expressions are left to the database
+func (r *Repository[T]) checkAllowedUpdates(updates map[string]interface{}) error {
+	s, discovered, err := r.allowedValueSources()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(updates)) {
+		field := s.LookUpField(key)
+		if field == nil || field.DBName == "" {
+			continue
+		}
+		allowed := allowedValues(field, discovered)
+		if allowed == nil {
+			continue
+		}
+		value := updates[key]
+		if _, ok := value.(clause.Expression); ok {
+			continue
+		}
+		if err := checkAllowedValue(field, value, allowed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allowedValueSources returns the schema of T and the allowed values
+// discovered for the repository's table
+func (r *Repository[T]) allowedValueSources() (*schema.Schema, map[string][]string, error) {
+	s, err := r.schema()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var discovered map[string][]string
+	if r.provider != nil {
+		table := s.Table
+		if r.table != "" {
+			table = r.table
+		}
+		r.provider.mu.RLock()
+		discovered = r.provider.allowedValues[table]
+		r.provider.mu.RUnlock()
+	}
+	return s, discovered, nil
+}
+
+// allowedValues returns the values allowed for field, from its `enum`
+// tag or else from discovered, or nil when any value is allowed
+func allowedValues(field *schema.Field, discovered map[string][]string) []string {
+	if allowed := parseEnumTag(field); allowed != nil {
+		return allowed
+	}
+	return discovered[field.DBName]
+}
+
+// checkAllowedValue fails when value is not one of allowed; nil values
+// are left to the column's nullability
+func checkAllowedValue(field *schema.Field, value interface{}, allowed []string) error {
+	if value == nil {
+		return nil
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		value = v.Elem().Interface()
+	}
+
+	str := fmt.Sprint(value)
+	for _, candidate := range allowed {
+		if candidate == str {
+			return nil
+		}
+	}
+	return gpa.NewErrorWithCause(gpa.ErrorTypeValidation, "validation failed",
+		&AllowedValuesError{Field: field.Name, Value: value, Allowed: allowed})
+}
+
+// checkConstraintValues matches the literal lists of simple CHECK
+// constraints such as "status IN ('a','b')" or Postgres' rewritten
+// "status = ANY (ARRAY['a'::text, 'b'::text])"
+var (
+	checkColumnPattern  = regexp.MustCompile(`^CHECK \(+"?([a-zA-Z_][a-zA-Z0-9_]*)"?\)?(?:::[a-z ]+)? (?:IN \(|= ANY \(+ARRAY\[)`)
+	checkLiteralPattern = regexp.MustCompile(`'((?:[^']|'')*)'`)
+)
+
+// DiscoverAllowedValues introspects Postgres enum column types and simple
+// CHECK ... IN constraints for the tables of models, so Create and Update
+// can reject invalid values with a field-level validation error instead
+// of an opaque database error. A table name may be given in place of a
+// model, for repositories using WithTable. Other dialects only use `enum`
+// struct tags.
+func (p *Provider) DiscoverAllowedValues(ctx context.Context, models ...interface{}) error {
+	if dialectName(p.db) != "postgres" {
+		return nil
+	}
+
+	for _, model := range models {
+		table, ok := model.(string)
+		if !ok {
+			stmt := &gorm.Statement{DB: p.db}
+			if err := stmt.Parse(model); err != nil {
+				return convertGormError(err)
+			}
+			table = stmt.Schema.Table
+		}
+		relname := table[strings.LastIndex(table, ".")+1:]
+		columns := make(map[string][]string)
+
+		var enumRows []struct {
+			Column string
+			Label  string
+		}
+		err := p.db.WithContext(ctx).Raw(`SELECT a.attname AS "column", e.enumlabel AS label
+			FROM pg_attribute a
+			JOIN pg_class c ON a.attrelid = c.oid
+			JOIN pg_enum e ON e.enumtypid = a.atttypid
+			WHERE c.relname = ? AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY a.attname, e.enumsortorder`, relname).Scan(&enumRows).Error
+		if err != nil {
+			return convertGormError(err)
+		}
+		for _, row := range enumRows {
+			columns[row.Column] = append(columns[row.Column], row.Label)
+		}
+
+		var checks []string
+		err = p.db.WithContext(ctx).Raw(`SELECT pg_get_constraintdef(con.oid)
+			FROM pg_constraint con
+			JOIN pg_class c ON con.conrelid = c.oid
+			WHERE c.relname = ? AND con.contype = 'c'`, relname).Scan(&checks).Error
+		if err != nil {
+			return convertGormError(err)
+		}
+		for _, check := range checks {
+			match := checkColumnPattern.FindStringSubmatch(check)
+			if match == nil {
+				continue
+			}
+			if _, ok := columns[match[1]]; ok {
+				continue
+			}
+			for _, literal := range checkLiteralPattern.FindAllStringSubmatch(check, -1) {
+				columns[match[1]] = append(columns[match[1]], strings.ReplaceAll(literal[1], "''", "'"))
+			}
+		}
+
+		p.mu.Lock()
+		if p.allowedValues == nil {
+			p.allowedValues = make(map[string]map[string][]string)
+		}
+		p.allowedValues[table] = columns
+		p.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+type TestAccount struct {
+	ID     uint    `gorm:"primaryKey"`
+	Status string  `enum:"active,suspended"`
+	Tier   *string `enum:"free, pro"`
+}
+
+func TestAllowedValuesValidation(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	if err := provider.db.AutoMigrate(&TestAccount{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[TestAccount](provider.db, provider)
+	ctx := context.Background()
+
+	account := &TestAccount{Status: "active"}
+	if err := repo.Create(ctx, account); err != nil {
+		t.Fatalf("Expected valid account to be created, got %v", err)
+	}
+
+	account.Status = "deleted"
+	err := repo.Update(ctx, account)
+	if !gpa.IsErrorType(err, gpa.ErrorTypeValidation) {
+		t.Fatalf("Expected validation error, got %v", err)
+	}
+	var allowedErr *AllowedValuesError
+	if !errors.As(err, &allowedErr) || allowedErr.Field != "Status" {
+		t.Errorf("Expected AllowedValuesError for Status, got %v", err)
+	}
+
+	tier := "enterprise"
+	err = repo.CreateBatch(ctx, []*TestAccount{{Status: "active", Tier: &tier}})
+	if !errors.As(err, &allowedErr) || allowedErr.Field != "Tier" {
+		t.Errorf("Expected AllowedValuesError for Tier, got %v", err)
+	}
+}
+
+func TestAllowedValuesForUpdatesAndTableOverrides(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	if err := provider.db.AutoMigrate(&TestAccount{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := provider.db.Table("test_users_archive").AutoMigrate(&TestUser{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	ctx := context.Background()
+
+	// Update maps are checked against the enum tags
+	accounts := NewRepository[TestAccount](provider.db, provider)
+	account := &TestAccount{Status: "active"}
+	if err := accounts.Create(ctx, account); err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	var allowedErr *AllowedValuesError
+	err := accounts.UpdatePartial(ctx, account.ID, map[string]interface{}{"status": "deleted"})
+	if !errors.As(err, &allowedErr) || allowedErr.Field != "Status" {
+		t.Errorf("Expected AllowedValuesError for Status, got %v", err)
+	}
+	if err := accounts.UpdatePartial(ctx, account.ID, map[string]interface{}{"status": "suspended", "tier": nil}); err != nil {
+		t.Errorf("Expected valid update to succeed, got %v", err)
+	}
+
+	// Values discovered for a table apply to repositories writing to it
+	provider.mu.Lock()
+	provider.allowedValues = map[string]map[string][]string{"test_users_archive": {"name": {"Alice"}}}
+	provider.mu.Unlock()
+	archive := NewRepository[TestUser](provider.db, provider).WithTable("test_users_archive")
+	err = archive.Create(ctx, &TestUser{Name: "Bob", Email: "bob@example.com"})
+	if !errors.As(err, &allowedErr) || allowedErr.Field != "Name" {
+		t.Errorf("Expected AllowedValuesError for Name, got %v", err)
+	}
+	if err := NewRepository[TestUser](provider.db, provider).Create(ctx, &TestUser{Name: "Bob", Email: "bob@example.com"}); err != nil {
+		t.Errorf("Expected other tables to be unaffected, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+// Package gpagorm provides Postgres array conditions
+package gpagorm
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// Operators on Postgres array columns, such as pq.StringArray or []int64
+// with a serializer, for use with gpa.Where or the helpers below
+const (
+	// OpArrayContains matches arrays holding every given element (@>)
+	OpArrayContains gpa.Operator = "ARRAY_CONTAINS"
+	// OpArrayOverlaps matches arrays sharing an element with the given ones (&&)
+	OpArrayOverlaps gpa.Operator = "ARRAY_OVERLAPS"
+	// OpArrayAny matches arrays holding the given element (= ANY)
+	OpArrayAny gpa.Operator = "ARRAY_ANY"
+)
+
+// ArrayContains filters on the array column field holding every element
+// of values:
+//
+//	repo.Query(ctx, gpagorm.ArrayContains("tags", []string{"go", "sql"}))
+func ArrayContains(field string, values interface{}) gpa.QueryOption {
+	return gpa.Where(field, OpArrayContains, values)
+}
+
+// ArrayOverlaps filters on the array column field holding at least one
+// element of values
+func ArrayOverlaps(field string, values interface{}) gpa.QueryOption {
+	return gpa.Where(field, OpArrayOverlaps, values)
+}
+
+// ArrayAny filters on the array column field holding value
+func ArrayAny(field string, value interface{}) gpa.QueryOption {
+	return gpa.Where(field, OpArrayAny, value)
+}
+
+// arraySQL returns the predicate of an array operator on field and its
+// argument. Only Postgres has array columns; other dialects return an
+// unsupported error.
+func arraySQL(db *gorm.DB, field string, op gpa.Operator, value interface{}) (string, []interface{}, error) {
+	if dialect := dialectName(db); dialect != "postgres" {
+		return "", nil, gpa.NewError(gpa.ErrorTypeUnsupported, "array operators are not supported on "+dialect)
+	}
+
+	if op == OpArrayAny {
+		return "? = ANY(" + field + ")", []interface{}{value}, nil
+	}
+	array, err := postgresArray(value)
+	if err != nil {
+		return "", nil, err
+	}
+	if op == OpArrayContains {
+		return field + " @> ?", []interface{}{array}, nil
+	}
+	return field + " && ?", []interface{}{array}, nil
+}
+
+// postgresArray returns value as an argument the driver sends as an array.
+// Values implementing driver.Valuer, such as pq.StringArray, are passed
+// as is; slices are written as an array literal, which Postgres types
+// from the compared column.
+func postgresArray(value interface{}) (interface{}, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		return valuer, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("array condition needs a slice, got %T", value))
+	}
+	elems := make([]string, rv.Len())
+	for i := range elems {
+		elem := rv.Index(i)
+		for elem.Kind() == reflect.Interface || elem.Kind() == reflect.Pointer {
+			if elem.IsNil() {
+				break
+			}
+			elem = elem.Elem()
+		}
+		switch elem.Kind() {
+		case reflect.Interface, reflect.Pointer:
+			elems[i] = "NULL"
+		case reflect.String:
+			elems[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(elem.String()) + `"`
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			elems[i] = fmt.Sprint(elem.Interface())
+		default:
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("unsupported array element %T", elem.Interface()))
+		}
+	}
+	return "{" + strings.Join(elems, ",") + "}", nil
+}
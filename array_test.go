@@ -0,0 +1,68 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type testArticle struct {
+	ID     uint
+	Tags   []string `gorm:"type:text[]"`
+	Scores []int64  `gorm:"type:bigint[]"`
+}
+
+func TestArrayConditions(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry-run postgres: %v", err)
+	}
+	repo := NewRepository[testArticle](db, nil)
+
+	tests := []struct {
+		name string
+		opt  gpa.QueryOption
+		sql  string
+		arg  interface{}
+	}{
+		{"contains", ArrayContains("tags", []string{"go", `say "hi"`}), `SELECT * FROM "test_articles" WHERE "tags" @> $1`, `{"go","say \"hi\""}`},
+		{"overlaps", ArrayOverlaps("scores", []int64{1, 2}), `SELECT * FROM "test_articles" WHERE "scores" && $1`, "{1,2}"},
+		{"any", ArrayAny("tags", "go"), `SELECT * FROM "test_articles" WHERE $1 = ANY("tags")`, "go"},
+		{"where", gpa.Where("tags", OpArrayContains, []interface{}{"go", nil}), `SELECT * FROM "test_articles" WHERE "tags" @> $1`, `{"go",NULL}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []*testArticle
+			stmt := repo.buildQuery(context.Background(), tt.opt).Find(&rows).Statement
+			if stmt.Error != nil {
+				t.Fatalf("Query failed: %v", stmt.Error)
+			}
+			if got := stmt.SQL.String(); got != tt.sql {
+				t.Errorf("Expected %q, got %q", tt.sql, got)
+			}
+			if len(stmt.Vars) != 1 || stmt.Vars[0] != tt.arg {
+				t.Errorf("Expected argument %v, got %v", tt.arg, stmt.Vars)
+			}
+		})
+	}
+
+	var rows []*testArticle
+	err = repo.buildQuery(context.Background(), ArrayContains("tags", "go")).Find(&rows).Error
+	if !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for a non-slice value, got %v", err)
+	}
+}
+
+func TestArrayConditionsUnsupported(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	_, err := repo.Query(context.Background(), ArrayOverlaps("name", []string{"a"}))
+	if !gpa.IsErrorType(err, gpa.ErrorTypeUnsupported) {
+		t.Errorf("Expected unsupported on SQLite, got %v", err)
+	}
+}
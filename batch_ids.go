@@ -0,0 +1,191 @@
+// Package gpagorm provides insert ID back-filling for batch creates
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// createBatchSize is the number of rows per INSERT in batch creates
+const createBatchSize = 100
+
+// BatchResult is the result of CreateBatchResult
+type BatchResult struct {
+	rowsAffected  int64
+	firstInsertID int64
+	lastInsertID  int64
+}
+
+// RowsAffected returns the number of rows inserted
+func (r *BatchResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// LastInsertId returns the highest ID generated by the batch, or 0 when
+// the database generated none
+func (r *BatchResult) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+// FirstInsertId returns the lowest ID generated by the batch, or 0 when
+// the database generated none
+func (r *BatchResult) FirstInsertId() (int64, error) {
+	return r.firstInsertID, nil
+}
+
+// CreateBatchResult inserts entities like CreateBatch and reports the rows
+// inserted and the range of IDs generated for them. Every entity without
+// a primary key gets its generated ID assigned, on MySQL too: IDs are
+// derived from the first insert ID, as a multi-row INSERT ... VALUES gets
+// consecutive IDs in every innodb_autoinc_lock_mode, and the rows are
+// inserted one by one in a transaction when some entities carry an ID or
+// the server's settings cannot be read.
+func (r *Repository[T]) CreateBatchResult(ctx context.Context, entities []*T) (result *BatchResult, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationCreateBatch, Entity: entities}, func(ctx context.Context) error {
+		var err error
+		result, err = r.createBatchResult(ctx, entities)
+		return err
+	})
+	return result, err
+}
+
+// createBatchResult implements CreateBatchResult
+func (r *Repository[T]) createBatchResult(ctx context.Context, entities []*T) (*BatchResult, error) {
+	if err := r.prepareCreate(ctx, entities); err != nil {
+		return nil, err
+	}
+
+	// Entities the database generates an integer ID for
+	var pk *schema.Field
+	var generated []*T
+	if s, err := r.schema(); err == nil && s.PrioritizedPrimaryField != nil && s.PrioritizedPrimaryField.AutoIncrement {
+		pk = s.PrioritizedPrimaryField
+		for _, entity := range entities {
+			if _, zero := pk.ValueOf(ctx, reflect.ValueOf(entity).Elem()); zero {
+				generated = append(generated, entity)
+			}
+		}
+	}
+
+	db := r.session(ctx)
+	result := &BatchResult{}
+	if dialectName(db) == "mysql" && len(generated) > 0 {
+		if err := r.createBatchMySQL(ctx, db, entities, pk, len(generated) == len(entities), result); err != nil {
+			return nil, convertGormError(err)
+		}
+	} else {
+		tx := db.CreateInBatches(entities, createBatchSize)
+		if tx.Error != nil {
+			return nil, convertGormError(tx.Error)
+		}
+		result.rowsAffected = tx.RowsAffected
+	}
+
+	for i, entity := range generated {
+		value, _ := pk.ValueOf(ctx, reflect.ValueOf(entity).Elem())
+		id := reflect.ValueOf(value)
+		var n int64
+		switch id.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = id.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = int64(id.Uint())
+		default:
+			continue
+		}
+		if i == 0 || n < result.firstInsertID {
+			result.firstInsertID = n
+		}
+		if n > result.lastInsertID {
+			result.lastInsertID = n
+		}
+	}
+
+	r.finishCreate(ctx, entities)
+	return result, nil
+}
+
+// createBatchMySQL inserts entities on MySQL, where only the first ID
+// generated by a multi-row INSERT is reported. When every entity needs an
+// ID and the server's settings are known, each INSERT's IDs are derived
+// from its first one and the server's increment; otherwise the entities
+// are inserted one by one, each reporting its own ID.
+func (r *Repository[T]) createBatchMySQL(ctx context.Context, db *gorm.DB, entities []*T, pk *schema.Field, allGenerated bool, result *BatchResult) error {
+	autoInc, known := r.provider.mysqlAutoIncrement(db)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if !allGenerated || !known || !autoInc.consecutive() {
+			for _, entity := range entities {
+				created := tx.Create(entity)
+				if created.Error != nil {
+					return created.Error
+				}
+				result.rowsAffected += created.RowsAffected
+			}
+			return nil
+		}
+
+		for start := 0; start < len(entities); start += createBatchSize {
+			batch := entities[start:min(start+createBatchSize, len(entities))]
+			inserted := gorm.WithResult()
+			created := tx.Clauses(inserted).Create(&batch)
+			if created.Error != nil {
+				return created.Error
+			}
+			result.rowsAffected += created.RowsAffected
+			firstID, err := inserted.Result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			for i, entity := range batch {
+				id := firstID + int64(i)*autoInc.increment
+				if err := pk.Set(ctx, reflect.ValueOf(entity).Elem(), id); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// mysqlAutoIncrement is how a MySQL server allocates auto-increment values
+type mysqlAutoIncrement struct {
+	lockMode  int64 // innodb_autoinc_lock_mode
+	increment int64 // auto_increment_increment
+}
+
+// consecutive reports whether a multi-row INSERT ... VALUES gets
+// consecutive IDs. Its row count is known up front, so InnoDB reserves
+// them in one go in every lock mode; only inserts of unknown size, such
+// as INSERT ... SELECT, interleave under mode 2.
+func (a mysqlAutoIncrement) consecutive() bool {
+	return a.lockMode >= 0 && a.lockMode <= 2
+}
+
+// mysqlAutoIncrement returns the server's auto-increment settings and
+// whether they could be read. They are read once per provider; a failed
+// read is retried by the next call.
+func (p *Provider) mysqlAutoIncrement(db *gorm.DB) (mysqlAutoIncrement, bool) {
+	if p != nil {
+		p.mu.RLock()
+		settings := p.autoIncrement
+		p.mu.RUnlock()
+		if settings.increment > 0 {
+			return settings, true
+		}
+	}
+
+	var settings mysqlAutoIncrement
+	row := db.Session(&gorm.Session{NewDB: true}).Raw("SELECT @@innodb_autoinc_lock_mode, @@auto_increment_increment").Row()
+	if err := row.Scan(&settings.lockMode, &settings.increment); err != nil || settings.increment <= 0 {
+		return mysqlAutoIncrement{}, false
+	}
+	if p != nil {
+		p.mu.Lock()
+		p.autoIncrement = settings
+		p.mu.Unlock()
+	}
+	return settings, true
+}
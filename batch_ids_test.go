@@ -0,0 +1,142 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCreateBatchResult(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	users := make([]*TestUser, 150)
+	for i := range users {
+		users[i] = &TestUser{Name: fmt.Sprintf("user%d", i), Email: fmt.Sprintf("user%d@example.com", i)}
+	}
+	result, err := repo.CreateBatchResult(ctx, users)
+	if err != nil {
+		t.Fatalf("CreateBatchResult failed: %v", err)
+	}
+	rows, _ := result.RowsAffected()
+	first, _ := result.FirstInsertId()
+	last, _ := result.LastInsertId()
+	if rows != 150 || first != int64(users[0].ID) || last != int64(users[149].ID) || last-first != 149 {
+		t.Errorf("Unexpected result: rows %d, IDs %d..%d", rows, first, last)
+	}
+}
+
+// mysqlBatchCassette replays a MySQL server with the given auto-increment
+// settings answering the inserts of two users
+func mysqlBatchCassette(lockMode, increment int64, inserts ...Interaction) *Cassette {
+	interactions := []Interaction{
+		{Query: "SELECT @@innodb_autoinc_lock_mode, @@auto_increment_increment",
+			Columns: []string{"@@innodb_autoinc_lock_mode", "@@auto_increment_increment"},
+			Rows:    [][]interface{}{{lockMode, increment}}},
+		{Query: "BEGIN"},
+	}
+	interactions = append(interactions, inserts...)
+	interactions = append(interactions, Interaction{Query: "COMMIT"})
+	return &Cassette{Dialect: "mysql", ServerVersion: "8.0.36", Interactions: interactions}
+}
+
+func TestCreateBatchResultMySQL(t *testing.T) {
+	ctx := context.Background()
+	const insertOne = "INSERT INTO `test_users` (`name`,`email`,`age`) VALUES (?,?,?)"
+	const insertTwo = "INSERT INTO `test_users` (`name`,`email`,`age`) VALUES (?,?,?),(?,?,?)"
+	newUsers := func() []*TestUser {
+		return []*TestUser{{Name: "a", Email: "a@example.com"}, {Name: "b", Email: "b@example.com"}}
+	}
+
+	tests := []struct {
+		name      string
+		cassette  *Cassette
+		wantIDs   [2]uint
+		wantFirst int64
+		wantLast  int64
+	}{
+		{
+			name: "consecutive with increment",
+			cassette: mysqlBatchCassette(1, 10, Interaction{Query: insertTwo,
+				Args: []interface{}{"a", "a@example.com", 0, "b", "b@example.com", 0}, RowsAffected: 2, LastInsertID: 101}),
+			wantIDs:   [2]uint{101, 111},
+			wantFirst: 101,
+			wantLast:  111,
+		},
+		{
+			name: "interleaved mode still consecutive",
+			cassette: mysqlBatchCassette(2, 1, Interaction{Query: insertTwo,
+				Args: []interface{}{"a", "a@example.com", 0, "b", "b@example.com", 0}, RowsAffected: 2, LastInsertID: 7}),
+			wantIDs:   [2]uint{7, 8},
+			wantFirst: 7,
+			wantLast:  8,
+		},
+		{
+			name: "unknown settings insert row by row",
+			cassette: &Cassette{Dialect: "mysql", ServerVersion: "8.0.36", Interactions: []Interaction{
+				{Query: "SELECT @@innodb_autoinc_lock_mode, @@auto_increment_increment", Err: "access denied"},
+				{Query: "BEGIN"},
+				{Query: insertOne, Args: []interface{}{"a", "a@example.com", 0}, RowsAffected: 1, LastInsertID: 7},
+				{Query: insertOne, Args: []interface{}{"b", "b@example.com", 0}, RowsAffected: 1, LastInsertID: 12},
+				{Query: "COMMIT"},
+			}},
+			wantIDs:   [2]uint{7, 12},
+			wantFirst: 7,
+			wantLast:  12,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewReplayProvider(tt.cassette)
+			if err != nil {
+				t.Fatalf("NewReplayProvider failed: %v", err)
+			}
+			repo := NewRepository[TestUser](provider.db, provider)
+			users := newUsers()
+			result, err := repo.CreateBatchResult(ctx, users)
+			if err != nil {
+				t.Fatalf("CreateBatchResult failed: %v", err)
+			}
+			if users[0].ID != tt.wantIDs[0] || users[1].ID != tt.wantIDs[1] {
+				t.Errorf("Expected IDs %v, got %d and %d", tt.wantIDs, users[0].ID, users[1].ID)
+			}
+			first, _ := result.FirstInsertId()
+			last, _ := result.LastInsertId()
+			if rows, _ := result.RowsAffected(); rows != 2 || first != tt.wantFirst || last != tt.wantLast {
+				t.Errorf("Unexpected result: rows %d, IDs %d..%d", rows, first, last)
+			}
+			if remaining := provider.ReplayRemaining(); len(remaining) != 0 {
+				t.Errorf("Expected the whole cassette to be replayed, %d interactions left", len(remaining))
+			}
+		})
+	}
+}
+
+func TestMySQLAutoIncrementRetriesFailedRead(t *testing.T) {
+	const query = "SELECT @@innodb_autoinc_lock_mode, @@auto_increment_increment"
+	columns := []string{"@@innodb_autoinc_lock_mode", "@@auto_increment_increment"}
+	provider, err := NewReplayProvider(&Cassette{Dialect: "mysql", ServerVersion: "8.0.36", Interactions: []Interaction{
+		{Query: query, Err: "connection reset"},
+		{Query: query, Columns: columns, Rows: [][]interface{}{{2, 5}}},
+	}})
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+
+	if _, known := provider.mysqlAutoIncrement(provider.db); known {
+		t.Fatal("Expected the failed read to report unknown settings")
+	}
+	// The failure is not cached: the next call reads again, and its
+	// result is kept
+	for i := 0; i < 2; i++ {
+		settings, known := provider.mysqlAutoIncrement(provider.db)
+		if !known || settings.lockMode != 2 || settings.increment != 5 {
+			t.Errorf("Expected lock mode 2 and increment 5, got %+v (%v)", settings, known)
+		}
+	}
+	if remaining := provider.ReplayRemaining(); len(remaining) != 0 {
+		t.Errorf("Expected the whole cassette to be replayed, %d interactions left", len(remaining))
+	}
+}
@@ -0,0 +1,160 @@
+// Package gpagorm provides streaming reads in batches
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// defaultIterateBatchSize is the batch size Iterate reads with
+const defaultIterateBatchSize = 500
+
+// cursorSeq numbers server-side cursors so concurrent streams do not collide
+var cursorSeq atomic.Uint64
+
+// FindInBatches streams the entities matching opts to fn in batches of at
+// most batchSize, without loading the full result. On Postgres with the
+// "server_side_cursors" option, rows are read through DECLARE/FETCH so
+// the server holds the result set; otherwise rows are streamed from the
+// driver. Returning an error from fn stops the stream. When ctx is
+// cancelled the error is a timeout whose BatchProgress reports the rows
+// already handed to fn.
+func (r *Repository[T]) FindInBatches(ctx context.Context, batchSize int, fn func(batch []*T) error, opts ...gpa.QueryOption) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationFindInBatches, Options: opts}, func(ctx context.Context) error {
+		return r.findInBatches(ctx, batchSize, fn, opts...)
+	})
+}
+
+// Iterate calls fn for each entity matching opts, streaming rows in
+// batches. Returning an error from fn stops the iteration.
+func (r *Repository[T]) Iterate(ctx context.Context, fn func(entity *T) error, opts ...gpa.QueryOption) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationIterate, Options: opts}, func(ctx context.Context) error {
+		return r.findInBatches(ctx, defaultIterateBatchSize, func(batch []*T) error {
+			for _, entity := range batch {
+				if err := fn(entity); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, opts...)
+	})
+}
+
+// findInBatches implements FindInBatches
+func (r *Repository[T]) findInBatches(ctx context.Context, batchSize int, fn func(batch []*T) error, opts ...gpa.QueryOption) error {
+	if batchSize <= 0 {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "batch size must be positive")
+	}
+	if err := r.authorizeRead(ctx, opts); err != nil {
+		return err
+	}
+
+	query := r.buildQuery(ctx, opts...)
+	if r.provider != nil && r.provider.serverSideCursors() && dialectName(query) == "postgres" {
+		return r.cursorBatches(query, batchSize, fn)
+	}
+	return r.streamBatches(query, batchSize, fn)
+}
+
+// streamBatches reads rows one at a time from the driver
+func (r *Repository[T]) streamBatches(query *gorm.DB, batchSize int, fn func(batch []*T) error) error {
+	var zero T
+	rows, err := query.Model(&zero).Rows()
+	if err != nil {
+		return convertGormError(err)
+	}
+	defer rows.Close()
+
+	ctx := query.Statement.Context
+	completed := 0
+	flush := func(batch []*T) error {
+		if err := ctx.Err(); err != nil {
+			return partialProgress(OperationFindInBatches, completed, err)
+		}
+		if err := fn(batch); err != nil {
+			return partialProgress(OperationFindInBatches, completed, err)
+		}
+		completed += len(batch)
+		return nil
+	}
+
+	batch := make([]*T, 0, batchSize)
+	for rows.Next() {
+		entity := new(T)
+		if err := query.ScanRows(rows, entity); err != nil {
+			return convertGormError(partialProgress(OperationFindInBatches, completed, err))
+		}
+		batch = append(batch, entity)
+		if len(batch) == batchSize {
+			if err := flush(batch); err != nil {
+				return err
+			}
+			batch = make([]*T, 0, batchSize)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return convertGormError(partialProgress(OperationFindInBatches, completed, err))
+	}
+	if len(batch) > 0 {
+		return flush(batch)
+	}
+	return nil
+}
+
+// cursorBatches reads the result through a Postgres server-side cursor,
+// which must live inside a transaction
+func (r *Repository[T]) cursorBatches(query *gorm.DB, batchSize int, fn func(batch []*T) error) error {
+	var zero []*T
+	stmt := query.Session(&gorm.Session{DryRun: true}).Find(&zero).Statement
+	if stmt.Error != nil {
+		return convertGormError(stmt.Error)
+	}
+	sql, vars := stmt.SQL.String(), stmt.Vars
+
+	run := func(tx *gorm.DB) error {
+		cursor := fmt.Sprintf("gpagorm_cursor_%d", cursorSeq.Add(1))
+		// The statement already uses $n placeholders, so bypass GORM's ? expansion
+		if _, err := tx.Statement.ConnPool.ExecContext(tx.Statement.Context, "DECLARE "+cursor+" NO SCROLL CURSOR FOR "+sql, vars...); err != nil {
+			return convertGormError(err)
+		}
+		defer tx.Exec("CLOSE " + cursor)
+
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM %s", batchSize, cursor)
+		completed := 0
+		for {
+			var batch []*T
+			if err := tx.Raw(fetch).Scan(&batch).Error; err != nil {
+				return convertGormError(partialProgress(OperationFindInBatches, completed, err))
+			}
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := fn(batch); err != nil {
+				return partialProgress(OperationFindInBatches, completed, err)
+			}
+			completed += len(batch)
+			if len(batch) < batchSize {
+				return nil
+			}
+		}
+	}
+
+	base := query.Session(&gorm.Session{NewDB: true})
+	if inTransaction(base) {
+		return run(base)
+	}
+	if err := base.Transaction(run); err != nil {
+		return convertGormError(err)
+	}
+	return nil
+}
+
+// serverSideCursors reports whether the "server_side_cursors" option is set
+func (p *Provider) serverSideCursors() bool {
+	enabled, _ := gormOptions(p.currentConfig())["server_side_cursors"].(bool)
+	return enabled
+}
@@ -0,0 +1,65 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestFindInBatches(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	users := make([]*TestUser, 0, 25)
+	for i := 0; i < 25; i++ {
+		users = append(users, &TestUser{Name: fmt.Sprintf("user%02d", i), Email: fmt.Sprintf("user%02d@example.com", i), Age: i})
+	}
+	if err := repo.CreateBatch(ctx, users); err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+
+	var sizes []int
+	total := 0
+	err := repo.FindInBatches(ctx, 10, func(batch []*TestUser) error {
+		sizes = append(sizes, len(batch))
+		total += len(batch)
+		return nil
+	}, gpa.OrderBy("age", gpa.OrderAsc))
+	if err != nil {
+		t.Fatalf("Failed to read batches: %v", err)
+	}
+	if fmt.Sprint(sizes) != "[10 10 5]" || total != 25 {
+		t.Errorf("Expected batches of 10, 10 and 5, got %v", sizes)
+	}
+
+	count := 0
+	err = repo.Iterate(ctx, func(user *TestUser) error {
+		count++
+		return nil
+	}, gpa.Where("age", gpa.OpGreaterThanOrEqual, 20))
+	if err != nil || count != 5 {
+		t.Errorf("Expected to iterate 5 users, got %d %v", count, err)
+	}
+
+	stop := errors.New("stop")
+	seen := 0
+	err = repo.Iterate(ctx, func(user *TestUser) error {
+		seen++
+		if seen == 3 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || seen != 3 {
+		t.Errorf("Expected iteration to stop after 3 users, got %d %v", seen, err)
+	}
+
+	if err := repo.FindInBatches(ctx, 0, func([]*TestUser) error { return nil }); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid batch size error, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Package gpagorm provides range (BETWEEN) conditions
+package gpagorm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lemmego/gpa"
+)
+
+// Range is the value of a gpa.OpBetween or gpa.OpNotBetween condition.
+// Both bounds are inclusive, as in SQL.
+type Range struct {
+	From interface{}
+	To   interface{}
+}
+
+// Between filters on field lying within [from, to]:
+//
+//	repo.Query(ctx, gpagorm.Between("created_at", start, end))
+//
+// The same filter can be written as gpa.Where(field, gpa.OpBetween, value)
+// with a Range or a two-element slice as value.
+func Between(field string, from, to interface{}) gpa.QueryOption {
+	return gpa.Where(field, gpa.OpBetween, Range{From: from, To: to})
+}
+
+// rangeBounds returns the bounds of a BETWEEN value: a Range, a *Range or
+// a slice or array of exactly two elements
+func rangeBounds(value interface{}) (interface{}, interface{}, error) {
+	switch v := value.(type) {
+	case Range:
+		return v.From, v.To, nil
+	case *Range:
+		if v != nil {
+			return v.From, v.To, nil
+		}
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.IsValid() && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Len() == 2 {
+		return rv.Index(0).Interface(), rv.Index(1).Interface(), nil
+	}
+	return nil, nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("BETWEEN needs a Range or two values, got %v", value))
+}
+
+// betweenSQL returns the range predicate on expr for op
+func betweenSQL(expr string, op gpa.Operator, value interface{}) (string, []interface{}, error) {
+	from, to, err := rangeBounds(value)
+	if err != nil {
+		return "", nil, err
+	}
+	return expr + " " + string(op) + " ? AND ?", []interface{}{from, to}, nil
+}
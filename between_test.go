@@ -0,0 +1,65 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestBetween(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&testSale{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[testSale](provider.db, provider)
+	ctx := context.Background()
+
+	for _, s := range []*testSale{
+		{Region: "EU", Country: "DE", Amount: 10},
+		{Region: "EU", Country: "FR", Amount: 5},
+		{Region: "NA", Country: "US", Amount: 7},
+		{Region: "NA", Country: "CA", Amount: 1},
+		{Region: "APAC", Country: "JP", Amount: 30},
+	} {
+		if err := repo.Create(ctx, s); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	count := func(opts ...gpa.QueryOption) int64 {
+		t.Helper()
+		n, err := repo.Count(ctx, opts...)
+		if err != nil {
+			t.Fatalf("Count failed: %v", err)
+		}
+		return n
+	}
+	if n := count(Between("amount", 5, 10)); n != 3 {
+		t.Errorf("Expected 3 sales in [5, 10], got %d", n)
+	}
+	if n := count(gpa.Where("amount", gpa.OpBetween, []int{1, 5})); n != 2 {
+		t.Errorf("Expected 2 sales in [1, 5], got %d", n)
+	}
+	if n := count(gpa.Where("amount", gpa.OpNotBetween, Range{From: 5, To: 10})); n != 2 {
+		t.Errorf("Expected 2 sales outside [5, 10], got %d", n)
+	}
+
+	regions, err := Project[testRegionCount](ctx, repo, gpa.Select("region", "SUM(amount) AS total"), gpa.GroupBy("region"),
+		gpa.Having("SUM(amount)", gpa.OpBetween, [2]int{8, 20}))
+	if err != nil || len(regions) != 2 {
+		t.Errorf("Expected 2 regions with totals in [8, 20], got %+v (%v)", regions, err)
+	}
+
+	if err := repo.DeleteByCondition(ctx, gpa.WhereCondition("amount", gpa.OpBetween, Range{From: 0, To: 5})); err != nil {
+		t.Fatalf("DeleteByCondition failed: %v", err)
+	}
+	if n := count(); n != 3 {
+		t.Errorf("Expected 3 sales left, got %d", n)
+	}
+
+	if _, err := repo.Count(ctx, gpa.Where("amount", gpa.OpBetween, []int{1})); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for a single bound, got %v", err)
+	}
+}
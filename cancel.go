@@ -0,0 +1,133 @@
+// Package gpagorm provides server-side cancellation of long statements
+package gpagorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// defaultCancelDeadline is how long a cancelled Postgres statement may run
+// after the cancel request before the connection is closed
+const defaultCancelDeadline = 5 * time.Second
+
+// openPostgres opens a Postgres dialector whose connections send a cancel
+// request (the equivalent of pg_cancel_backend) when a statement's context
+// is done, instead of only abandoning the socket. Configured with
+// "server_cancel" (default true) and "server_cancel_deadline". New
+// connections first run the "session_init" statements.
+func openPostgres(config gpa.Config, dsn string) (gorm.Dialector, error) {
+	sessionInit, err := sessionInitStatements(config)
+	if err != nil {
+		return nil, err
+	}
+	if enabled, ok := gormOptions(config)["server_cancel"].(bool); ok && !enabled {
+		if len(sessionInit) == 0 {
+			return postgres.Open(dsn), nil
+		}
+		connector, err := postgresConnector(config, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return postgres.New(postgres.Config{DSN: dsn, Conn: openSessionDB(connector, sessionInit)}), nil
+	}
+
+	connector, err := postgresConnector(config, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return postgres.New(postgres.Config{Conn: openSessionDB(connector, sessionInit)}), nil
+}
+
+// postgresConnector returns a pgx connector for dsn that sends cancel
+// requests unless "server_cancel" is off
+func postgresConnector(config gpa.Config, dsn string) (driver.Connector, error) {
+	gormOpts := gormOptions(config)
+	if enabled, ok := gormOpts["server_cancel"].(bool); ok && !enabled {
+		connector, err := driverConnector("pgx", dsn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid postgres dsn: %w", err)
+		}
+		return connector, nil
+	}
+
+	deadline := defaultCancelDeadline
+	switch d := gormOpts["server_cancel_deadline"].(type) {
+	case time.Duration:
+		deadline = d
+	case string:
+		parsed, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server_cancel_deadline: %w", err)
+		}
+		deadline = parsed
+	}
+
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres dsn: %w", err)
+	}
+	connConfig.BuildContextWatcherHandler = cancelRequestHandler(deadline)
+	return stdlib.GetConnector(*connConfig), nil
+}
+
+// cancelRequestHandler builds context watchers that send a cancel request
+// as soon as the context is done and close the socket after deadline
+func cancelRequestHandler(deadline time.Duration) func(*pgconn.PgConn) ctxwatch.Handler {
+	return func(conn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{Conn: conn, DeadlineDelay: deadline}
+	}
+}
+
+// PartialProgressError reports how far a batch operation got before it
+// was interrupted. Work up to Completed is not rolled back.
+type PartialProgressError struct {
+	Operation Operation
+	Completed int
+	Err       error
+}
+
+// Error implements the error interface
+func (e *PartialProgressError) Error() string {
+	return fmt.Sprintf("%s interrupted after %d rows: %v", e.Operation, e.Completed, e.Err)
+}
+
+// Unwrap returns the interrupting error
+func (e *PartialProgressError) Unwrap() error {
+	return e.Err
+}
+
+// BatchProgress returns the number of rows a batch operation completed
+// before err interrupted it
+func BatchProgress(err error) (int, bool) {
+	var progress *PartialProgressError
+	if errors.As(err, &progress) {
+		return progress.Completed, true
+	}
+	return 0, false
+}
+
+// partialProgress wraps a cancellation in a timeout error carrying the
+// progress of op. Other errors are returned unchanged.
+func partialProgress(op Operation, completed int, err error) error {
+	if !isCancellation(err) {
+		return err
+	}
+	return gpa.NewErrorWithCause(gpa.ErrorTypeTimeout, "operation cancelled",
+		&PartialProgressError{Operation: op, Completed: completed, Err: err})
+}
+
+// isCancellation reports whether err comes from a done context
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
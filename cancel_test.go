@@ -0,0 +1,85 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/postgres"
+)
+
+func TestOpenPostgresServerCancel(t *testing.T) {
+	dsn := "host=localhost user=gpa dbname=gpa sslmode=disable"
+
+	dialector, err := openPostgres(gpa.Config{Driver: "postgres"}, dsn)
+	if err != nil {
+		t.Fatalf("Failed to open dialector: %v", err)
+	}
+	if pg, ok := dialector.(*postgres.Dialector); !ok || pg.Conn == nil {
+		t.Errorf("Expected a dialector over a pgx connection pool, got %#v", dialector)
+	}
+
+	handler := cancelRequestHandler(time.Second)(nil)
+	if h, ok := handler.(*pgconn.CancelRequestContextWatcherHandler); !ok || h.DeadlineDelay != time.Second {
+		t.Errorf("Expected a cancel request handler, got %#v", handler)
+	}
+
+	disabled := gpa.Config{Driver: "postgres", Options: map[string]interface{}{"gorm": map[string]interface{}{"server_cancel": false}}}
+	dialector, err = openPostgres(disabled, dsn)
+	if err != nil {
+		t.Fatalf("Failed to open dialector: %v", err)
+	}
+	if pg := dialector.(*postgres.Dialector); pg.Conn != nil || pg.DSN != dsn {
+		t.Errorf("Expected the plain DSN dialector when server_cancel is off")
+	}
+
+	invalid := gpa.Config{Driver: "postgres", Options: map[string]interface{}{"gorm": map[string]interface{}{"server_cancel_deadline": "soon"}}}
+	if _, err := openPostgres(invalid, dsn); err == nil {
+		t.Errorf("Expected an error for an invalid server_cancel_deadline")
+	}
+}
+
+func TestCancellationErrors(t *testing.T) {
+	if err := convertGormError(context.Canceled); !gpa.IsErrorType(err, gpa.ErrorTypeTimeout) {
+		t.Errorf("Expected a timeout error for a cancelled context, got %v", err)
+	}
+	if err := convertGormError(fmt.Errorf("query: %w", context.DeadlineExceeded)); !gpa.IsErrorType(err, gpa.ErrorTypeTimeout) {
+		t.Errorf("Expected a timeout error for an exceeded deadline, got %v", err)
+	}
+}
+
+func TestFindInBatchesCancelledProgress(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	users := make([]*TestUser, 0, 30)
+	for i := 0; i < 30; i++ {
+		users = append(users, &TestUser{Name: fmt.Sprintf("user%02d", i), Email: fmt.Sprintf("user%02d@example.com", i), Age: i})
+	}
+	if err := repo.CreateBatch(context.Background(), users); err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	batches := 0
+	err := repo.FindInBatches(ctx, 10, func(batch []*TestUser) error {
+		batches++
+		cancel()
+		return nil
+	})
+	if !gpa.IsErrorType(err, gpa.ErrorTypeTimeout) {
+		t.Fatalf("Expected a timeout error, got %v", err)
+	}
+	if completed, ok := BatchProgress(err); !ok || completed != 10 || batches != 1 {
+		t.Errorf("Expected progress of 10 rows after one batch, got %d %v (%d batches)", completed, ok, batches)
+	}
+
+	if _, ok := BatchProgress(gpa.NewError(gpa.ErrorTypeDatabase, "boom")); ok {
+		t.Errorf("Expected no progress on unrelated errors")
+	}
+}
@@ -0,0 +1,159 @@
+// Package gpagorm provides per-field canonicalization of written values
+package gpagorm
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Canonicalizer rewrites a string field value into its canonical form
+type Canonicalizer func(string) string
+
+// Built-in canonicalizers
+var (
+	// TrimSpace removes leading and trailing white space
+	TrimSpace Canonicalizer = strings.TrimSpace
+	// Lowercase maps letters to lower case
+	Lowercase Canonicalizer = strings.ToLower
+	// NormalizeUnicode composes characters to Unicode NFC, so visually
+	// equal strings compare equal
+	NormalizeUnicode Canonicalizer = norm.NFC.String
+	// NormalizeEmail trims, NFC-normalizes and lower-cases an address
+	NormalizeEmail Canonicalizer = func(s string) string {
+		return strings.ToLower(norm.NFC.String(strings.TrimSpace(s)))
+	}
+)
+
+// RegisterCanonicalizer registers fns, applied in order, for field of the
+// repository's entity:
+//
+//	repo.RegisterCanonicalizer("email", gpagorm.NormalizeEmail)
+//	repo.RegisterCanonicalizer("name", gpagorm.TrimSpace, gpagorm.NormalizeUnicode)
+//
+// Create, CreateBatch, Update, UpdatePartial and the other writes rewrite
+// the field before policies, allowed values and validation hooks see the
+// entity. The field must be a string or *string column; registering again
+// replaces its canonicalizers. Like scopes, canonicalizers are kept on the
+// provider, so every repository of the entity shares them.
+func (r *Repository[T]) RegisterCanonicalizer(field string, fns ...Canonicalizer) error {
+	s, err := r.schema()
+	if err != nil {
+		return err
+	}
+	f := s.LookUpField(field)
+	if f == nil || f.DBName == "" {
+		return &FieldValidationError{Field: field, Reason: "field is not a column of " + s.Name}
+	}
+	if t := f.FieldType; t.Kind() != reflect.String && (t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.String) {
+		return &FieldValidationError{Field: field, Reason: "only string fields can be canonicalized"}
+	}
+	if r.provider == nil {
+		return nil
+	}
+
+	r.provider.mu.Lock()
+	defer r.provider.mu.Unlock()
+	key := reflect.TypeOf((*T)(nil)).Elem()
+	if r.provider.canonicalizers == nil {
+		r.provider.canonicalizers = make(map[reflect.Type]map[string][]Canonicalizer)
+	}
+	if r.provider.canonicalizers[key] == nil {
+		r.provider.canonicalizers[key] = make(map[string][]Canonicalizer)
+	}
+	r.provider.canonicalizers[key][f.DBName] = fns
+	return nil
+}
+
+// fieldCanonicalizers returns the canonicalizers registered for T by column
+func (r *Repository[T]) fieldCanonicalizers() map[string][]Canonicalizer {
+	if r.provider == nil {
+		return nil
+	}
+	r.provider.mu.RLock()
+	defer r.provider.mu.RUnlock()
+	return r.provider.canonicalizers[reflect.TypeOf((*T)(nil)).Elem()]
+}
+
+// canonicalize rewrites the registered fields of entities in place
+func (r *Repository[T]) canonicalize(ctx context.Context, entities ...*T) error {
+	fields := r.fieldCanonicalizers()
+	if len(fields) == 0 {
+		return nil
+	}
+	s, err := r.schema()
+	if err != nil {
+		return err
+	}
+
+	for _, entity := range entities {
+		rv := reflect.ValueOf(entity).Elem()
+		for column, fns := range fields {
+			f := s.LookUpField(column)
+			if f == nil {
+				continue
+			}
+			v := f.ReflectValueOf(ctx, rv)
+			if v.Kind() == reflect.Pointer {
+				if v.IsNil() {
+					continue
+				}
+				v = v.Elem()
+			}
+			v.SetString(applyCanonicalizers(v.String(), fns))
+		}
+	}
+	return nil
+}
+
+// canonicalizeUpdates returns updates, an UpdatePartial map keyed by
+// column or field name, with the registered fields rewritten. The caller's
+// map is left unchanged.
+func (r *Repository[T]) canonicalizeUpdates(updates map[string]interface{}) (map[string]interface{}, error) {
+	fields := r.fieldCanonicalizers()
+	if len(fields) == 0 {
+		return updates, nil
+	}
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	canonical := maps.Clone(updates)
+	for key, value := range updates {
+		f := s.LookUpField(key)
+		if f == nil || value == nil {
+			continue
+		}
+		fns, ok := fields[f.DBName]
+		if !ok {
+			continue
+		}
+		v := reflect.ValueOf(value)
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				continue
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.String {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "canonicalized field "+key+" needs a string value")
+		}
+		rewritten := reflect.New(v.Type()).Elem()
+		rewritten.SetString(applyCanonicalizers(v.String(), fns))
+		canonical[key] = rewritten.Interface()
+	}
+	return canonical, nil
+}
+
+// applyCanonicalizers runs fns over s in order
+func applyCanonicalizers(s string, fns []Canonicalizer) string {
+	for _, fn := range fns {
+		s = fn(s)
+	}
+	return s
+}
@@ -0,0 +1,86 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+type testCanonicalAccount struct {
+	ID       uint
+	Email    string
+	Nickname *string
+}
+
+// Validate rejects addresses that were not canonicalized first
+func (a *testCanonicalAccount) Validate(ctx context.Context) error {
+	if a.Email != strings.ToLower(strings.TrimSpace(a.Email)) {
+		return errors.New("email is not canonical")
+	}
+	return nil
+}
+
+func TestCanonicalizers(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&testCanonicalAccount{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[testCanonicalAccount](provider.db, provider)
+	ctx := context.Background()
+
+	if err := repo.RegisterCanonicalizer("email", NormalizeEmail); err != nil {
+		t.Fatalf("RegisterCanonicalizer failed: %v", err)
+	}
+	if err := repo.RegisterCanonicalizer("Nickname", TrimSpace, NormalizeUnicode); err != nil {
+		t.Fatalf("RegisterCanonicalizer failed: %v", err)
+	}
+	if err := repo.RegisterCanonicalizer("id", TrimSpace); err == nil {
+		t.Error("Expected an error for a non-string field")
+	}
+	if err := repo.RegisterCanonicalizer("missing", TrimSpace); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+
+	// "e" followed by a combining acute accent composes to "é"
+	nickname := "  Jose\u0301 "
+	account := &testCanonicalAccount{Email: "  Ann@Example.COM ", Nickname: &nickname}
+	if err := repo.Create(ctx, account); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if account.Email != "ann@example.com" || *account.Nickname != "Jos\u00e9" {
+		t.Errorf("Expected canonical values, got %q and %q", account.Email, *account.Nickname)
+	}
+
+	batch := []*testCanonicalAccount{{Email: "BOB@example.com"}, {Email: " cid@example.com"}}
+	if err := repo.CreateBatch(ctx, batch); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if batch[0].Email != "bob@example.com" || batch[1].Email != "cid@example.com" {
+		t.Errorf("Expected canonical batch emails, got %q and %q", batch[0].Email, batch[1].Email)
+	}
+
+	account.Email = "ANN@example.com"
+	if err := repo.Update(ctx, account); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	updates := map[string]interface{}{"email": " Dee@Example.com"}
+	if err := repo.UpdatePartial(ctx, batch[0].ID, updates); err != nil {
+		t.Fatalf("UpdatePartial failed: %v", err)
+	}
+	if updates["email"] != " Dee@Example.com" {
+		t.Errorf("Expected the caller's map to be left unchanged, got %v", updates)
+	}
+	stored, err := repo.FindByID(ctx, batch[0].ID)
+	if err != nil || stored.Email != "dee@example.com" {
+		t.Errorf("Expected the stored email to be canonical, got %+v (%v)", stored, err)
+	}
+
+	if err := repo.UpdatePartial(ctx, batch[0].ID, map[string]interface{}{"email": 42}); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for a non-string update, got %v", err)
+	}
+}
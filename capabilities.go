@@ -0,0 +1,180 @@
+// Package gpagorm provides dialect capability inspection
+package gpagorm
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// UpsertSyntax is the statement form a dialect uses for upserts
+type UpsertSyntax string
+
+const (
+	// UpsertOnConflict is INSERT ... ON CONFLICT (Postgres, SQLite)
+	UpsertOnConflict UpsertSyntax = "ON CONFLICT"
+	// UpsertOnDuplicateKey is INSERT ... ON DUPLICATE KEY UPDATE (MySQL)
+	UpsertOnDuplicateKey UpsertSyntax = "ON DUPLICATE KEY UPDATE"
+	// UpsertMerge is MERGE (SQL Server)
+	UpsertMerge UpsertSyntax = "MERGE"
+)
+
+// Capabilities describes the SQL features of the connected database, so
+// code built on the provider can branch on features instead of drivers
+type Capabilities struct {
+	Dialect           string       `json:"dialect"`
+	ServerVersion     string       `json:"server_version,omitempty"`
+	Returning         bool         `json:"returning"` // RETURNING, or OUTPUT on SQL Server
+	Upsert            UpsertSyntax `json:"upsert"`
+	ILike             bool         `json:"ilike"`
+	SkipLocked        bool         `json:"skip_locked"`
+	MaterializedViews bool         `json:"materialized_views"`
+	FullText          bool         `json:"full_text"`
+	RowValues         bool         `json:"row_values"` // (a, b) > (?, ?)
+	Rollup            bool         `json:"rollup"`
+	Cube              bool         `json:"cube"`
+	NullsOrdering     bool         `json:"nulls_ordering"` // NULLS FIRST/LAST
+	WindowFunctions   bool         `json:"window_functions"`
+	RecursiveCTE      bool         `json:"recursive_cte"`
+	Savepoints        bool         `json:"savepoints"`
+	AdvisoryLocks     bool         `json:"advisory_locks"` // Native locks for WithAdvisoryLock
+}
+
+// Capabilities returns the capabilities of the connected database. The
+// server version is read once, on first use; if it cannot be read, recent
+// versions are assumed.
+func (p *Provider) Capabilities() Capabilities {
+	p.capabilitiesOnce.Do(func() {
+		p.capabilities = capabilitiesFor(dialectName(p.db), p.serverVersion())
+	})
+	return p.capabilities
+}
+
+// serverVersion reads the database server version, or "" on failure
+func (p *Provider) serverVersion() string {
+	var query string
+	switch dialectName(p.db) {
+	case "postgres":
+		query = "SHOW server_version"
+	case "mysql":
+		query = "SELECT VERSION()"
+	case "sqlite":
+		query = "SELECT sqlite_version()"
+	case "sqlserver":
+		query = "SELECT CAST(SERVERPROPERTY('ProductVersion') AS VARCHAR(64))"
+	default:
+		return ""
+	}
+	var version string
+	if err := p.db.WithContext(context.Background()).Raw(query).Scan(&version).Error; err != nil {
+		return ""
+	}
+	return version
+}
+
+// capabilitiesFor returns the capabilities of a dialect at a server
+// version; an empty version means a recent one
+func capabilitiesFor(dialect, version string) Capabilities {
+	atLeast := func(min string) bool {
+		return version == "" || versionAtLeast(version, min)
+	}
+	caps := Capabilities{Dialect: dialect, ServerVersion: version}
+	switch dialect {
+	case "postgres":
+		caps.Returning = true
+		caps.Upsert = UpsertOnConflict
+		caps.ILike = true
+		caps.SkipLocked = true
+		caps.MaterializedViews = true
+		caps.FullText = true
+		caps.RowValues = true
+		caps.Rollup = true
+		caps.Cube = true
+		caps.NullsOrdering = true
+		caps.WindowFunctions = true
+		caps.RecursiveCTE = true
+		caps.Savepoints = true
+		caps.AdvisoryLocks = true
+	case "mysql":
+		mariaDB := strings.Contains(version, "MariaDB")
+		caps.Upsert = UpsertOnDuplicateKey
+		caps.FullText = true
+		caps.RowValues = true
+		caps.Rollup = true
+		caps.Savepoints = true
+		caps.AdvisoryLocks = true
+		if mariaDB {
+			caps.Returning = atLeast("10.5")
+			caps.SkipLocked = atLeast("10.6")
+			caps.WindowFunctions = atLeast("10.2")
+			caps.RecursiveCTE = atLeast("10.2")
+		} else {
+			caps.SkipLocked = atLeast("8.0")
+			caps.WindowFunctions = atLeast("8.0")
+			caps.RecursiveCTE = atLeast("8.0")
+		}
+	case "sqlite":
+		caps.Returning = atLeast("3.35")
+		caps.Upsert = UpsertOnConflict
+		caps.FullText = true // FTS5
+		caps.RowValues = atLeast("3.15")
+		caps.NullsOrdering = atLeast("3.30")
+		caps.WindowFunctions = atLeast("3.25")
+		caps.RecursiveCTE = true
+		caps.Savepoints = true
+	case "sqlserver":
+		caps.Returning = true
+		caps.Upsert = UpsertMerge
+		caps.FullText = true
+		caps.Rollup = true
+		caps.Cube = true
+		caps.WindowFunctions = true
+		caps.RecursiveCTE = true
+		caps.Savepoints = true
+	}
+	return caps
+}
+
+// versionAtLeast compares the leading dotted numbers of version with min,
+// e.g. "8.0.36-log" or "10.11.2-MariaDB" against "8.0"
+func versionAtLeast(version, min string) bool {
+	if i := strings.Index(version, "-MariaDB"); i >= 0 {
+		// MariaDB may report "5.5.5-10.11.2-MariaDB" for compatibility
+		version = version[:i]
+		if j := strings.LastIndex(version, "-"); j >= 0 {
+			version = version[j+1:]
+		}
+	}
+	have := versionParts(version)
+	want := versionParts(min)
+	for i, w := range want {
+		h := 0
+		if i < len(have) {
+			h = have[i]
+		}
+		if h != w {
+			return h > w
+		}
+	}
+	return true
+}
+
+// versionParts returns the leading numeric components of a version string
+func versionParts(version string) []int {
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		end := 0
+		for end < len(field) && field[end] >= '0' && field[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+		n, _ := strconv.Atoi(field[:end])
+		parts = append(parts, n)
+		if end < len(field) {
+			break
+		}
+	}
+	return parts
+}
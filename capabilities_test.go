@@ -0,0 +1,54 @@
+package gpagorm
+
+import "testing"
+
+func TestCapabilities(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	caps := provider.Capabilities()
+	if caps.Dialect != "sqlite" || caps.ServerVersion == "" {
+		t.Fatalf("Unexpected dialect or version: %+v", caps)
+	}
+	if !caps.Returning || !caps.RowValues || !caps.NullsOrdering || caps.Upsert != UpsertOnConflict {
+		t.Errorf("Expected a recent SQLite's features, got %+v", caps)
+	}
+	if caps.SkipLocked || caps.ILike || caps.AdvisoryLocks {
+		t.Errorf("Expected no SKIP LOCKED, ILIKE or advisory locks on SQLite, got %+v", caps)
+	}
+}
+
+func TestCapabilitiesForVersions(t *testing.T) {
+	mysql57 := capabilitiesFor("mysql", "5.7.44-log")
+	if mysql57.SkipLocked || mysql57.WindowFunctions || mysql57.Returning {
+		t.Errorf("Expected MySQL 5.7 without SKIP LOCKED, windows or RETURNING, got %+v", mysql57)
+	}
+	if mysql8 := capabilitiesFor("mysql", "8.0.36"); !mysql8.SkipLocked || !mysql8.RecursiveCTE {
+		t.Errorf("Expected MySQL 8 features, got %+v", mysql8)
+	}
+	if mariaDB := capabilitiesFor("mysql", "5.5.5-10.11.2-MariaDB"); !mariaDB.Returning || !mariaDB.SkipLocked {
+		t.Errorf("Expected MariaDB 10.11 features, got %+v", mariaDB)
+	}
+	if pg := capabilitiesFor("postgres", "16.2 (Debian 16.2-1)"); !pg.MaterializedViews || !pg.Cube || pg.Upsert != UpsertOnConflict {
+		t.Errorf("Unexpected Postgres capabilities: %+v", pg)
+	}
+	if ms := capabilitiesFor("sqlserver", "16.0.1000.6"); ms.Upsert != UpsertMerge || ms.RowValues {
+		t.Errorf("Unexpected SQL Server capabilities: %+v", ms)
+	}
+
+	tests := []struct {
+		version, min string
+		want         bool
+	}{
+		{"3.46.0", "3.35", true},
+		{"3.34.1", "3.35", false},
+		{"10.5.0-MariaDB", "10.5", true},
+		{"8.0.36-0ubuntu0.22.04.1", "8.0", true},
+		{"16.2 (Debian 16.2-1)", "9.5", true},
+	}
+	for _, tt := range tests {
+		if got := versionAtLeast(tt.version, tt.min); got != tt.want {
+			t.Errorf("versionAtLeast(%q, %q) = %v, want %v", tt.version, tt.min, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package gpagorm provides post-commit change notifications
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ChangeKind is how a write changed the rows of an entity
+type ChangeKind string
+
+const (
+	ChangeCreated ChangeKind = "created"
+	ChangeUpdated ChangeKind = "updated"
+	ChangeDeleted ChangeKind = "deleted"
+)
+
+// EntityChange describes committed writes to rows of one entity type
+type EntityChange struct {
+	Entity string        // Name of the entity type, e.g. "User"
+	Kind   ChangeKind    // How the rows changed
+	IDs    []interface{} // Primary keys of the changed rows
+}
+
+// changeListener receives the committed changes of one entity type
+type changeListener func(ctx context.Context, change EntityChange)
+
+type changeSetKey struct{}
+
+// changeSet collects the changes of a unit of work until it commits
+type changeSet struct {
+	mu      sync.Mutex
+	changes []entityTypeChange
+}
+
+// entityTypeChange is a recorded change with its entity type
+type entityTypeChange struct {
+	typ    reflect.Type
+	change EntityChange
+}
+
+// add records change for entity type typ
+func (c *changeSet) add(typ reflect.Type, change EntityChange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes = append(c.changes, entityTypeChange{typ: typ, change: change})
+}
+
+// merge appends the changes of other, e.g. a released savepoint
+func (c *changeSet) merge(other *changeSet) {
+	other.mu.Lock()
+	changes := other.changes
+	other.mu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes = append(c.changes, changes...)
+}
+
+// OnEntityChanged calls fn with the primary keys of the rows of entity
+// type T that writes through repositories of p created, updated or
+// deleted, once those writes have committed. Writes rolled back, by a
+// transaction or a savepoint, never reach fn, so external caches can be
+// invalidated without racing the commit:
+//
+//	gpagorm.OnEntityChanged[User](provider, func(ctx context.Context, pks []any) {
+//		for _, pk := range pks {
+//			cache.Delete(ctx, fmt.Sprintf("user:%v", pk))
+//		}
+//	})
+//
+// fn runs synchronously on the goroutine that committed, after the
+// operation's result is known and before it returns to the caller. Writes
+// through repositories over transactions they do not manage, and raw SQL,
+// are not seen.
+func OnEntityChanged[T any](p *Provider, fn func(ctx context.Context, pks []any)) {
+	p.addChangeListener(reflect.TypeOf((*T)(nil)).Elem(), func(ctx context.Context, change EntityChange) {
+		fn(ctx, change.IDs)
+	})
+}
+
+// addChangeListener registers fn for the committed changes of typ
+func (p *Provider) addChangeListener(typ reflect.Type, fn changeListener) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.changeListeners == nil {
+		p.changeListeners = make(map[reflect.Type][]changeListener)
+	}
+	p.changeListeners[typ] = append(p.changeListeners[typ], fn)
+}
+
+// hasChangeListeners reports whether changes need to be tracked
+func (p *Provider) hasChangeListeners() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.changeListeners) > 0
+}
+
+// dispatchChanges hands the committed changes of set to the listeners of
+// their entity types, in the order they were made
+func (p *Provider) dispatchChanges(ctx context.Context, set *changeSet) {
+	set.mu.Lock()
+	changes := set.changes
+	set.changes = nil
+	set.mu.Unlock()
+
+	for _, c := range changes {
+		p.mu.RLock()
+		listeners := p.changeListeners[c.typ]
+		p.mu.RUnlock()
+		for _, listener := range listeners {
+			listener(ctx, c.change)
+		}
+	}
+}
+
+// trackChanges returns ctx carrying a change set for an operation that
+// is not part of a transaction, so its changes are dispatched once it
+// has committed. It returns a nil set when the changes are tracked
+// already, by an enclosing operation or the repository's transaction,
+// and when nobody listens. Changes made through a repository over a
+// transaction it does not manage are not tracked, as their commit cannot
+// be observed.
+func (r *Repository[T]) trackChanges(ctx context.Context) (context.Context, *changeSet) {
+	if r.changes != nil || !r.provider.hasChangeListeners() || inTransaction(r.db) {
+		return ctx, nil
+	}
+	if _, ok := ctx.Value(changeSetKey{}).(*changeSet); ok {
+		return ctx, nil
+	}
+	set := &changeSet{}
+	return context.WithValue(ctx, changeSetKey{}, set), set
+}
+
+// changeSet returns the change set the repository's writes go to, or nil
+func (r *Repository[T]) changeSet(ctx context.Context) *changeSet {
+	if r.changes != nil {
+		return r.changes
+	}
+	set, _ := ctx.Value(changeSetKey{}).(*changeSet)
+	return set
+}
+
+// recordChange notes that the rows with ids changed
+func (r *Repository[T]) recordChange(ctx context.Context, kind ChangeKind, ids []interface{}) {
+	set := r.changeSet(ctx)
+	if set == nil || len(ids) == 0 {
+		return
+	}
+	set.add(reflect.TypeOf((*T)(nil)).Elem(), EntityChange{Entity: entityTypeName[T](), Kind: kind, IDs: ids})
+}
+
+// recordEntityChange notes that entities changed
+func (r *Repository[T]) recordEntityChange(ctx context.Context, kind ChangeKind, entities ...*T) {
+	if r.changeSet(ctx) == nil {
+		return
+	}
+	r.recordChange(ctx, kind, r.primaryKeys(ctx, entities))
+}
+
+// primaryKeys returns the primary key values of entities
+func (r *Repository[T]) primaryKeys(ctx context.Context, entities []*T) []interface{} {
+	s, err := r.schema()
+	if err != nil || s.PrioritizedPrimaryField == nil {
+		return nil
+	}
+	ids := make([]interface{}, 0, len(entities))
+	for _, entity := range entities {
+		if id, zero := s.PrioritizedPrimaryField.ValueOf(ctx, reflect.ValueOf(entity).Elem()); !zero {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// pluckPrimaryKeys returns the primary keys of the rows query matches,
+// typed like the primary key field
+func pluckPrimaryKeys(query *gorm.DB, pk *schema.Field) ([]interface{}, error) {
+	values := reflect.New(reflect.SliceOf(pk.FieldType))
+	if err := query.Pluck(pk.DBName, values.Interface()).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]interface{}, values.Elem().Len())
+	for i := range ids {
+		ids[i] = values.Elem().Index(i).Interface()
+	}
+	return ids, nil
+}
@@ -0,0 +1,144 @@
+package gpagorm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestOnEntityChanged(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	var invalidated []any
+	OnEntityChanged[TestUser](provider, func(ctx context.Context, pks []any) {
+		invalidated = append(invalidated, pks...)
+	})
+
+	alice := &TestUser{Name: "Alice", Email: "alice@example.com"}
+	if err := repo.Create(ctx, alice); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	alice.Name = "Alice B."
+	if err := repo.Update(ctx, alice); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// Rolled back writes, of a transaction or a savepoint, are not reported
+	failed := errors.New("failed")
+	err := repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		if err := tx.Delete(ctx, alice.ID); err != nil {
+			return err
+		}
+		if len(invalidated) != 2 {
+			t.Error("Expected no invalidation before the commit")
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Expected the transaction to roll back, got %v", err)
+	}
+	err = repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		outer := tx.(*Transaction[TestUser])
+		if err := outer.UpdatePartial(ctx, alice.ID, map[string]interface{}{"name": "Alice C."}); err != nil {
+			return err
+		}
+		nestedErr := outer.RunNested(ctx, func(nested gpa.Transaction[TestUser]) error {
+			if err := nested.Create(ctx, &TestUser{Name: "Ghost", Email: "ghost@example.com"}); err != nil {
+				return err
+			}
+			return failed
+		})
+		if !errors.Is(nestedErr, failed) {
+			t.Errorf("Expected the savepoint to roll back, got %v", nestedErr)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	if got, want := fmt.Sprint(invalidated), fmt.Sprint([]any{alice.ID, alice.ID, alice.ID}); got != want {
+		t.Errorf("Expected invalidations %s, got %s", want, got)
+	}
+}
+
+func TestOnEntityChangedThroughDerivedRepository(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	var invalidated []any
+	OnEntityChanged[TestUser](provider, func(ctx context.Context, pks []any) {
+		invalidated = append(invalidated, pks...)
+	})
+
+	bob := &TestUser{Name: "Bob", Email: "bob@example.com"}
+	err := repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		return tx.(*Transaction[TestUser]).WithTable("test_users").WithComment("job:import").Create(ctx, bob)
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if got, want := fmt.Sprint(invalidated), fmt.Sprint([]any{bob.ID}); got != want {
+		t.Errorf("Expected invalidations %s, got %s", want, got)
+	}
+}
+
+func TestOnEntityChangedForBulkWrites(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	var invalidated [][]any
+	OnEntityChanged[TestUser](provider, func(ctx context.Context, pks []any) {
+		invalidated = append(invalidated, pks)
+	})
+	expect := func(want ...any) {
+		t.Helper()
+		if got := fmt.Sprint(invalidated); got != fmt.Sprint([][]any{want}) {
+			t.Errorf("Expected invalidations %v, got %s", want, got)
+		}
+		invalidated = nil
+	}
+
+	alice := &TestUser{Name: "Alice", Email: "alice@example.com"}
+	if err := repo.Create(ctx, alice); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	invalidated = nil
+
+	// Only the rows actually inserted are reported
+	bob := &TestUser{Name: "Bob", Email: "bob@example.com"}
+	if _, err := repo.CreateIgnoreDuplicates(ctx, []*TestUser{{Name: "Alice", Email: "alice@example.com"}, bob}); err != nil {
+		t.Fatalf("CreateIgnoreDuplicates failed: %v", err)
+	}
+	expect(bob.ID)
+
+	if _, err := repo.UpdateReturning(ctx, bob.ID, map[string]interface{}{"age": 30}); err != nil {
+		t.Fatalf("UpdateReturning failed: %v", err)
+	}
+	expect(bob.ID)
+
+	var dump bytes.Buffer
+	if err := repo.DumpEntities(ctx, &dump); err != nil {
+		t.Fatalf("DumpEntities failed: %v", err)
+	}
+	if _, err := repo.DeleteReturning(ctx, gpa.BasicCondition{FieldName: "id", Op: gpa.OpEqual, Val: bob.ID}); err != nil {
+		t.Fatalf("DeleteReturning failed: %v", err)
+	}
+	expect(bob.ID)
+
+	if _, err := repo.LoadEntities(ctx, &dump, ConflictSkip); err != nil {
+		t.Fatalf("LoadEntities failed: %v", err)
+	}
+	expect(bob.ID)
+}
@@ -0,0 +1,224 @@
+// Package gpagorm provides declarative data integrity checks
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// defaultCheckSamples is how many violating rows a CheckResult keeps
+const defaultCheckSamples = 10
+
+// Check is an invariant written as a query returning the rows that break
+// it, such as orders whose customer no longer exists. A healthy database
+// returns no rows.
+type Check struct {
+	Name string
+	SQL  string
+	Args []interface{}
+}
+
+// CheckResult reports one check of a run
+type CheckResult struct {
+	Name       string                   `json:"name"`
+	Violations int64                    `json:"violations"`        // Rows the query returned
+	Samples    []map[string]interface{} `json:"samples,omitempty"` // First violating rows
+	Duration   time.Duration            `json:"duration"`
+	Error      string                   `json:"error,omitempty"`
+}
+
+// Passed reports whether the check ran and found no violations
+func (r CheckResult) Passed() bool {
+	return r.Error == "" && r.Violations == 0
+}
+
+// ChecksReport is the outcome of RunChecks, one result per check in
+// registration order
+type ChecksReport struct {
+	Results []CheckResult `json:"results"`
+}
+
+// Passed reports whether every check ran and found no violations
+func (r *ChecksReport) Passed() bool {
+	for _, result := range r.Results {
+		if !result.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Violations returns the total number of violating rows
+func (r *ChecksReport) Violations() int64 {
+	var total int64
+	for _, result := range r.Results {
+		total += result.Violations
+	}
+	return total
+}
+
+// Failed returns the results of the checks that found violations or
+// could not run
+func (r *ChecksReport) Failed() []CheckResult {
+	var failed []CheckResult
+	for _, result := range r.Results {
+		if !result.Passed() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// ChecksRunner runs registered integrity checks against a database, for
+// data-quality jobs that run next to the application:
+//
+//	checks := provider.ChecksRunner()
+//	checks.AddCheck("orphaned orders",
+//	    "SELECT o.id FROM orders o LEFT JOIN customers c ON c.id = o.customer_id WHERE c.id IS NULL")
+//	checks.AddCheck("negative balances", "SELECT id, balance FROM accounts WHERE balance < ?", 0)
+//	report, err := checks.RunChecks(ctx)
+type ChecksRunner struct {
+	db       *gorm.DB
+	provider *Provider
+
+	mu       sync.Mutex
+	checks   []Check
+	samples  int
+	onResult func(CheckResult)
+}
+
+// NewChecksRunner creates a checks runner on db, which may be a
+// transaction
+func NewChecksRunner(db *gorm.DB, provider *Provider) *ChecksRunner {
+	return &ChecksRunner{db: db, provider: provider, samples: defaultCheckSamples}
+}
+
+// ChecksRunner returns a checks runner bound to the provider's connection
+func (p *Provider) ChecksRunner() *ChecksRunner {
+	return NewChecksRunner(p.db, p)
+}
+
+// AddCheck registers a check named name whose query, sql with args bound,
+// returns the rows violating it. The SQL is run as is, so it must not be
+// built from user input.
+func (c *ChecksRunner) AddCheck(name, sql string, args ...interface{}) error {
+	return c.Register(Check{Name: name, SQL: sql, Args: args})
+}
+
+// Register adds check. Names must be unique within the runner.
+func (c *ChecksRunner) Register(check Check) error {
+	if strings.TrimSpace(check.Name) == "" {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "check needs a name")
+	}
+	if strings.TrimSpace(check.SQL) == "" {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "check "+check.Name+" needs a query")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, existing := range c.checks {
+		if existing.Name == check.Name {
+			return gpa.NewError(gpa.ErrorTypeDuplicate, "check "+check.Name+" is already registered")
+		}
+	}
+	c.checks = append(c.checks, check)
+	return nil
+}
+
+// SetSampleSize sets how many violating rows each result keeps, 10 by
+// default. Zero keeps none.
+func (c *ChecksRunner) SetSampleSize(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.samples = max(n, 0)
+}
+
+// OnResult sets a function receiving each check's result as it finishes,
+// e.g. to export violation counts as metrics
+func (c *ChecksRunner) OnResult(fn func(CheckResult)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onResult = fn
+}
+
+// RunChecks runs every registered check in registration order. Violations
+// are reported in the results, not as an error; a check whose query fails
+// is recorded and the remaining checks still run, and the returned error
+// joins those failures.
+func (c *ChecksRunner) RunChecks(ctx context.Context) (*ChecksReport, error) {
+	c.mu.Lock()
+	checks := append([]Check(nil), c.checks...)
+	samples, onResult := c.samples, c.onResult
+	c.mu.Unlock()
+
+	db := sessionDB(ctx, c.db, c.provider)
+	report := &ChecksReport{}
+	var errs []error
+	for _, check := range checks {
+		if err := ctx.Err(); err != nil {
+			return report, convertGormError(err)
+		}
+		start := time.Now()
+		result, err := runCheck(db, check, samples)
+		result.Duration = time.Since(start)
+		if err != nil {
+			result.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", check.Name, err))
+		}
+		report.Results = append(report.Results, result)
+		if onResult != nil {
+			onResult(result)
+		}
+	}
+
+	if len(errs) > 0 {
+		return report, gpa.NewErrorWithCause(gpa.ErrorTypeDatabase, "integrity checks failed to run", errors.Join(errs...))
+	}
+	return report, nil
+}
+
+// runCheck counts the rows of check's query, keeping the first samples
+func runCheck(db *gorm.DB, check Check, samples int) (CheckResult, error) {
+	result := CheckResult{Name: check.Name}
+	rows, err := db.Raw(check.SQL, check.Args...).Rows()
+	if err != nil {
+		return result, convertGormError(err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return result, convertGormError(err)
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		result.Violations++
+		if len(result.Samples) >= samples {
+			continue
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return result, convertGormError(err)
+		}
+		sample := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				// Drivers reuse byte buffers between rows
+				values[i] = string(b)
+			}
+			sample[column] = values[i]
+		}
+		result.Samples = append(result.Samples, sample)
+	}
+	return result, convertGormError(rows.Err())
+}
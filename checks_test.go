@@ -0,0 +1,77 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestRunChecks(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	for i, age := range []int{30, -1, -5} {
+		if err := repo.Create(ctx, &TestUser{Name: fmt.Sprintf("User%d", i), Email: fmt.Sprintf("user%d@example.com", i), Age: age}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	checks := provider.ChecksRunner()
+	if err := checks.AddCheck("negative ages", "SELECT id, name FROM test_users WHERE age < ? ORDER BY id", 0); err != nil {
+		t.Fatalf("AddCheck failed: %v", err)
+	}
+	if err := checks.AddCheck("missing emails", "SELECT id FROM test_users WHERE email = ''"); err != nil {
+		t.Fatalf("AddCheck failed: %v", err)
+	}
+	if err := checks.AddCheck("negative ages", "SELECT 1"); !gpa.IsErrorType(err, gpa.ErrorTypeDuplicate) {
+		t.Errorf("Expected a duplicate error, got %v", err)
+	}
+	if err := checks.AddCheck("", "SELECT 1"); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for an unnamed check, got %v", err)
+	}
+
+	var observed []string
+	checks.OnResult(func(result CheckResult) {
+		observed = append(observed, fmt.Sprintf("%s=%d", result.Name, result.Violations))
+	})
+	checks.SetSampleSize(1)
+
+	report, err := checks.RunChecks(ctx)
+	if err != nil {
+		t.Fatalf("RunChecks failed: %v", err)
+	}
+	if report.Passed() || report.Violations() != 2 || len(report.Failed()) != 1 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	negative := report.Results[0]
+	if negative.Violations != 2 || len(negative.Samples) != 1 || negative.Samples[0]["name"] != "User1" {
+		t.Errorf("Unexpected result %+v", negative)
+	}
+	if !report.Results[1].Passed() {
+		t.Errorf("Expected the email check to pass, got %+v", report.Results[1])
+	}
+	if fmt.Sprint(observed) != "[negative ages=2 missing emails=0]" {
+		t.Errorf("Unexpected observed results %v", observed)
+	}
+}
+
+func TestRunChecksQueryError(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	checks := provider.ChecksRunner()
+	checks.AddCheck("broken", "SELECT id FROM missing_table")
+	checks.AddCheck("fine", "SELECT id FROM test_users")
+
+	report, err := checks.RunChecks(context.Background())
+	if !gpa.IsErrorType(err, gpa.ErrorTypeDatabase) {
+		t.Errorf("Expected a database error, got %v", err)
+	}
+	if len(report.Results) != 2 || report.Results[0].Error == "" || !report.Results[1].Passed() {
+		t.Errorf("Expected the remaining checks to run, got %+v", report.Results)
+	}
+}
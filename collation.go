@@ -0,0 +1,132 @@
+// Package gpagorm provides per-query collation controls
+package gpagorm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// CollateNoCase requests case-insensitive comparison and sorting on every dialect
+const CollateNoCase = "NOCASE"
+
+// collateKey stores the active CollateOption on a query's settings
+const collateKey = "gpagorm:collate"
+
+var collationPattern = regexp.MustCompile(`^[A-Za-z0-9_.@-]+$`)
+
+// CollateOption applies a collation to the WHERE and ORDER BY expressions
+// of a query. It carries no state for gpa.Query; buildQuery reads it.
+type CollateOption struct {
+	Collation string
+	Fields    []string // Restrict to these fields; empty means all string fields
+}
+
+// Apply implements gpa.QueryOption
+func (o CollateOption) Apply(query *gpa.Query) {}
+
+// Collate applies collation to the query's comparisons and ordering, e.g.
+// Collate("und-x-icu") on Postgres or Collate(CollateNoCase) anywhere.
+// Without fields it applies to every string column of the entity.
+func Collate(collation string, fields ...string) gpa.QueryOption {
+	return CollateOption{Collation: collation, Fields: fields}
+}
+
+// collationFromOptions returns the last CollateOption in opts
+func collationFromOptions(opts []gpa.QueryOption) *CollateOption {
+	var found *CollateOption
+	for _, opt := range opts {
+		if collate, ok := opt.(CollateOption); ok {
+			found = &collate
+		}
+	}
+	return found
+}
+
+// collateExpr returns the expression to compare or sort by for field
+// (already quoted as expr) under the query's collation. lower reports that
+// the dialect emulates the collation by lower-casing both sides.
+func (r *Repository[T]) collateExpr(db *gorm.DB, field, expr string) (string, bool) {
+	value, ok := db.Get(collateKey)
+	if !ok {
+		return expr, false
+	}
+	collate := value.(*CollateOption)
+	if !r.collates(collate, field) {
+		return expr, false
+	}
+
+	if !collationPattern.MatchString(collate.Collation) {
+		db.AddError(gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("invalid collation: %s", collate.Collation)))
+		return expr, false
+	}
+
+	noCase := strings.EqualFold(collate.Collation, CollateNoCase)
+	switch dialectName(db) {
+	case "postgres":
+		if noCase {
+			return "LOWER(" + expr + ")", true
+		}
+		return expr + ` COLLATE "` + collate.Collation + `"`, false
+	case "mysql":
+		if noCase {
+			return expr + " COLLATE utf8mb4_general_ci", false
+		}
+	case "sqlserver":
+		if noCase {
+			return expr + " COLLATE Latin1_General_CI_AS", false
+		}
+	case "sqlite":
+		if noCase {
+			return expr + " COLLATE NOCASE", false
+		}
+	}
+	return expr + " COLLATE " + collate.Collation, false
+}
+
+// collates reports whether the collation applies to field
+func (r *Repository[T]) collates(collate *CollateOption, field string) bool {
+	if len(collate.Fields) > 0 {
+		for _, name := range collate.Fields {
+			if name == field {
+				return true
+			}
+		}
+		return false
+	}
+
+	s, err := r.schema()
+	if err != nil {
+		return false
+	}
+	f := s.LookUpField(field[strings.LastIndex(field, ".")+1:])
+	return f != nil && f.DataType == schema.String
+}
+
+// lowerValue lower-cases string values, including those in slices
+func lowerValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.ToLower(v)
+	case []string:
+		lowered := make([]string, len(v))
+		for i, s := range v {
+			lowered[i] = strings.ToLower(s)
+		}
+		return lowered
+	case []interface{}:
+		lowered := make([]interface{}, len(v))
+		for i, item := range v {
+			lowered[i] = lowerValue(item)
+		}
+		return lowered
+	case Range:
+		return Range{From: lowerValue(v.From), To: lowerValue(v.To)}
+	default:
+		return value
+	}
+}
@@ -0,0 +1,78 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestCollateNoCase(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	for _, name := range []string{"alice", "Bob", "carol"} {
+		if err := repo.Create(ctx, &TestUser{Name: name, Email: name + "@example.com", Age: 30}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	users, err := repo.Query(ctx, gpa.Where("name", gpa.OpEqual, "ALICE"))
+	if err != nil || len(users) != 0 {
+		t.Fatalf("Expected case-sensitive match to find nothing, got %d %v", len(users), err)
+	}
+	users, err = repo.Query(ctx, gpa.Where("name", gpa.OpEqual, "ALICE"), Collate(CollateNoCase))
+	if err != nil || len(users) != 1 {
+		t.Fatalf("Expected case-insensitive match, got %d %v", len(users), err)
+	}
+
+	users, err = repo.Query(ctx, gpa.OrderBy("name", gpa.OrderAsc), Collate(CollateNoCase))
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	var names []string
+	for _, user := range users {
+		names = append(names, user.Name)
+	}
+	if strings.Join(names, ",") != "alice,Bob,carol" {
+		t.Errorf("Expected case-insensitive ordering, got %v", names)
+	}
+
+	// Non-string columns are left without a collation
+	var found []*TestUser
+	stmt := repo.buildQuery(ctx, gpa.Where("age", gpa.OpEqual, 30), Collate(CollateNoCase)).
+		Session(&gorm.Session{DryRun: true}).Find(&found).Statement
+	if strings.Contains(stmt.SQL.String(), "COLLATE") {
+		t.Errorf("Expected no collation on a numeric column, got %s", stmt.SQL.String())
+	}
+}
+
+func TestCollatePostgres(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry-run postgres: %v", err)
+	}
+	repo := NewRepository[TestUser](db, nil)
+	ctx := context.Background()
+
+	var users []*TestUser
+	sql := repo.buildQuery(ctx, gpa.Where("name", gpa.OpEqual, "Ann"), gpa.OrderBy("name", gpa.OrderAsc), Collate("und-x-icu")).
+		Find(&users).Statement.SQL.String()
+	if !strings.Contains(sql, `"name" COLLATE "und-x-icu" = $1`) || !strings.Contains(sql, `ORDER BY "name" COLLATE "und-x-icu" ASC`) {
+		t.Errorf("Unexpected SQL: %s", sql)
+	}
+
+	stmt := repo.buildQuery(ctx, gpa.Where("name", gpa.OpEqual, "Ann"), Collate(CollateNoCase)).Find(&users).Statement
+	if !strings.Contains(stmt.SQL.String(), `LOWER("name") = $1`) || stmt.Vars[0] != "ann" {
+		t.Errorf("Expected LOWER emulation, got %s %v", stmt.SQL.String(), stmt.Vars)
+	}
+
+	if err := repo.buildQuery(ctx, gpa.Where("name", gpa.OpEqual, "Ann"), Collate(`x"; DROP`)).Find(&users).Error; err == nil {
+		t.Error("Expected invalid collation to be rejected")
+	}
+}
@@ -0,0 +1,200 @@
+// Package gpagorm provides typed column references for refactoring-safe queries
+package gpagorm
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm/schema"
+)
+
+// columnSchemas caches parsed schemas for column resolution
+var columnSchemas sync.Map
+
+// Column is a typed reference to a column holding values of type V. Its
+// conditions only accept V, so a renamed field or a mismatched value fails
+// to compile instead of producing a bad query.
+type Column[V any] struct {
+	name string
+}
+
+// NewColumn returns a column reference by database name, for generated code
+func NewColumn[V any](name string) Column[V] {
+	return Column[V]{name: name}
+}
+
+// Col resolves the column of the T field returned by field:
+//
+//	age := gpagorm.Col(func(u *User) *int { return &u.Age })
+//
+// It panics if field does not return a mapped field of its argument.
+func Col[T any, V any](field func(*T) *V) Column[V] {
+	var zero T
+	ptr := field(&zero)
+	offset := uintptr(unsafe.Pointer(ptr)) - uintptr(unsafe.Pointer(&zero))
+	valueType := reflect.TypeOf((*V)(nil)).Elem()
+
+	s := columnSchema[T]()
+	for _, f := range s.Fields {
+		if f.DBName == "" || f.FieldType != valueType {
+			continue
+		}
+		if fieldOffset(s.ModelType, f.StructField.Index) == offset {
+			return Column[V]{name: f.DBName}
+		}
+	}
+	panic(fmt.Sprintf("gpagorm: Col accessor does not return a column field of %s", s.ModelType))
+}
+
+// Columns fills the Column fields of C from T's fields with the same names,
+// checking that their value types match:
+//
+//	type userColumns struct {
+//	    Age  gpagorm.Column[int]
+//	    Name gpagorm.Column[string]
+//	}
+//	var q = gpagorm.Columns[User, userColumns]()
+//
+// It panics when a field is missing from T or has another type, so typos
+// surface at startup.
+func Columns[T any, C any]() C {
+	var cols C
+	s := columnSchema[T]()
+	rv := reflect.ValueOf(&cols).Elem()
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("gpagorm: Columns needs a struct of columns, got %s", rv.Type()))
+	}
+	for i := 0; i < rv.NumField(); i++ {
+		name := rv.Type().Field(i).Name
+		binder, ok := rv.Field(i).Addr().Interface().(columnBinder)
+		if !ok {
+			continue
+		}
+		f := s.LookUpField(name)
+		if f == nil || f.DBName == "" {
+			panic(fmt.Sprintf("gpagorm: %s has no column field %s", s.ModelType, name))
+		}
+		if !binder.bind(f.DBName, f.FieldType) {
+			panic(fmt.Sprintf("gpagorm: %s.%s is %s, not the column's type", s.ModelType, name, f.FieldType))
+		}
+	}
+	return cols
+}
+
+// columnBinder is implemented by *Column[V] so Columns can fill them
+type columnBinder interface {
+	bind(name string, typ reflect.Type) bool
+}
+
+// bind sets the column name if typ holds V
+func (c *Column[V]) bind(name string, typ reflect.Type) bool {
+	if typ != reflect.TypeOf((*V)(nil)).Elem() {
+		return false
+	}
+	c.name = name
+	return true
+}
+
+// columnSchema parses T with the default naming strategy
+func columnSchema[T any]() *schema.Schema {
+	var zero T
+	s, err := schema.Parse(&zero, &columnSchemas, schema.NamingStrategy{})
+	if err != nil {
+		panic(fmt.Sprintf("gpagorm: cannot resolve columns of %T: %v", zero, err))
+	}
+	return s
+}
+
+// fieldOffset returns the offset of the field at index within t, following
+// embedded structs
+func fieldOffset(t reflect.Type, index []int) uintptr {
+	var offset uintptr
+	for _, i := range index {
+		if t.Kind() != reflect.Struct {
+			// Embedded pointers do not live inside the struct
+			return ^uintptr(0)
+		}
+		f := t.Field(i)
+		offset += f.Offset
+		t = f.Type
+	}
+	return offset
+}
+
+// Name returns the column's database name
+func (c Column[V]) Name() string {
+	return c.name
+}
+
+// Cond returns a condition on the column, for use in composite conditions
+func (c Column[V]) Cond(op gpa.Operator, value V) gpa.Condition {
+	return gpa.WhereCondition(c.name, op, value)
+}
+
+// Eq matches rows where the column equals value
+func (c Column[V]) Eq(value V) gpa.QueryOption {
+	return gpa.Where(c.name, gpa.OpEqual, value)
+}
+
+// Ne matches rows where the column differs from value
+func (c Column[V]) Ne(value V) gpa.QueryOption {
+	return gpa.Where(c.name, gpa.OpNotEqual, value)
+}
+
+// Gt matches rows where the column is greater than value
+func (c Column[V]) Gt(value V) gpa.QueryOption {
+	return gpa.Where(c.name, gpa.OpGreaterThan, value)
+}
+
+// Gte matches rows where the column is at least value
+func (c Column[V]) Gte(value V) gpa.QueryOption {
+	return gpa.Where(c.name, gpa.OpGreaterThanOrEqual, value)
+}
+
+// Lt matches rows where the column is less than value
+func (c Column[V]) Lt(value V) gpa.QueryOption {
+	return gpa.Where(c.name, gpa.OpLessThan, value)
+}
+
+// Lte matches rows where the column is at most value
+func (c Column[V]) Lte(value V) gpa.QueryOption {
+	return gpa.Where(c.name, gpa.OpLessThanOrEqual, value)
+}
+
+// Like matches rows where the column matches a LIKE pattern
+func (c Column[V]) Like(pattern string) gpa.QueryOption {
+	return gpa.Where(c.name, gpa.OpLike, pattern)
+}
+
+// In matches rows where the column is one of values
+func (c Column[V]) In(values ...V) gpa.QueryOption {
+	return gpa.Where(c.name, gpa.OpIn, values)
+}
+
+// NotIn matches rows where the column is none of values
+func (c Column[V]) NotIn(values ...V) gpa.QueryOption {
+	return gpa.Where(c.name, gpa.OpNotIn, values)
+}
+
+// IsNull matches rows where the column is NULL
+func (c Column[V]) IsNull() gpa.QueryOption {
+	return gpa.WhereNull(c.name)
+}
+
+// IsNotNull matches rows where the column is not NULL
+func (c Column[V]) IsNotNull() gpa.QueryOption {
+	return gpa.WhereNotNull(c.name)
+}
+
+// Asc orders by the column ascending
+func (c Column[V]) Asc() gpa.QueryOption {
+	return gpa.OrderBy(c.name, gpa.OrderAsc)
+}
+
+// Desc orders by the column descending
+func (c Column[V]) Desc() gpa.QueryOption {
+	return gpa.OrderBy(c.name, gpa.OrderDesc)
+}
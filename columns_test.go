@@ -0,0 +1,77 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+)
+
+type testUserColumns struct {
+	ID    Column[uint]
+	Name  Column[string]
+	Email Column[string]
+	Age   Column[int]
+}
+
+func TestTypedColumns(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	for _, user := range []*TestUser{
+		{Name: "Alice", Email: "alice@example.com", Age: 35},
+		{Name: "Adam", Email: "adam@example.com", Age: 25},
+		{Name: "Bob", Email: "bob@example.com", Age: 40},
+	} {
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	q := Columns[TestUser, testUserColumns]()
+	if q.Age.Name() != "age" || q.Email.Name() != "email" {
+		t.Fatalf("Unexpected column names: %+v", q)
+	}
+	users, err := repo.Query(ctx, q.Age.Gt(30), q.Name.Like("A%"))
+	if err != nil || len(users) != 1 || users[0].Name != "Alice" {
+		t.Errorf("Expected Alice, got %v %v", users, err)
+	}
+
+	users, err = repo.Query(ctx, q.Name.In("Adam", "Bob"), q.Age.Desc())
+	if err != nil || len(users) != 2 || users[0].Name != "Bob" {
+		t.Errorf("Expected Bob then Adam, got %v %v", users, err)
+	}
+
+	age := Col(func(u *TestUser) *int { return &u.Age })
+	email := Col(func(u *TestUser) *string { return &u.Email })
+	if age.Name() != "age" || email.Name() != "email" {
+		t.Errorf("Unexpected resolved columns %s %s", age.Name(), email.Name())
+	}
+	count, err := repo.Count(ctx, age.Lte(35), email.Ne("x"))
+	if err != nil || count != 2 {
+		t.Errorf("Expected 2 users, got %d %v", count, err)
+	}
+}
+
+func TestColumnsPanicsOnMismatch(t *testing.T) {
+	type wrongType struct {
+		Age Column[string]
+	}
+	type missing struct {
+		Nickname Column[string]
+	}
+	for name, fn := range map[string]func(){
+		"type":    func() { Columns[TestUser, wrongType]() },
+		"missing": func() { Columns[TestUser, missing]() },
+		"col":     func() { Col(func(u *TestUser) *int { return new(int) }) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected %s mismatch to panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -0,0 +1,113 @@
+// Package gpagorm provides SQL comments annotating a repository's statements
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type statementCommentKey struct{}
+
+// WithComment returns a copy of the repository whose statements carry
+// comment as a leading SQL comment, e.g. /* job:nightly-billing */ SELECT
+// ..., so the same query shape issued by different jobs can be told apart
+// in server-side slow logs and pg_stat_activity. The comment must not
+// contain "/*", "*/" or line breaks.
+func (r *Repository[T]) WithComment(comment string) *Repository[T] {
+	if strings.Contains(comment, "/*") || strings.Contains(comment, "*/") || strings.ContainsAny(comment, "\r\n") {
+		db := r.db.Session(&gorm.Session{})
+		db.AddError(&FieldValidationError{
+			Field:  comment,
+			Reason: "comment must not contain comment delimiters or line breaks",
+		})
+		return &Repository[T]{db: db, provider: r.provider}
+	}
+
+	repo := r.clone()
+	repo.comment = comment
+	return repo
+}
+
+// withStatementComment returns ctx annotating the statements run with it
+func withStatementComment(ctx context.Context, comment string) context.Context {
+	if comment == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, statementCommentKey{}, comment)
+}
+
+// statementComment returns the comment set with withStatementComment
+func statementComment(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	comment, _ := ctx.Value(statementCommentKey{}).(string)
+	return comment
+}
+
+// sqlComment writes a comment before a statement's leading clause
+type sqlComment string
+
+// Build implements clause.Expression
+func (c sqlComment) Build(builder clause.Builder) {
+	builder.WriteString("/* " + string(c) + " */")
+}
+
+// registerStatementComments prefixes the statements of operations run
+// with a repository comment. Built statements get the comment before their
+// leading clause; raw SQL is prefixed as written.
+func registerStatementComments(db *gorm.DB) error {
+	annotate := func(leading string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			comment := statementComment(db.Statement.Context)
+			if comment == "" {
+				return
+			}
+			prefix := "/* " + comment + " */ "
+			if db.Statement.SQL.Len() > 0 {
+				sql := db.Statement.SQL.String()
+				if !strings.HasPrefix(sql, prefix) {
+					db.Statement.SQL.Reset()
+					db.Statement.SQL.WriteString(prefix + sql)
+				}
+				return
+			}
+			if leading == "" {
+				return
+			}
+			c := db.Statement.Clauses[leading]
+			c.Name = leading
+			c.BeforeExpression = sqlComment(comment)
+			db.Statement.Clauses[leading] = c
+		}
+	}
+
+	// Dialects that build a leading clause themselves, such as SQLite's
+	// INSERT, skip its BeforeExpression; write the comment for them
+	for _, name := range []string{"INSERT", "SELECT", "UPDATE", "DELETE"} {
+		if build, ok := db.ClauseBuilders[name]; ok {
+			db.ClauseBuilders[name] = func(c clause.Clause, builder clause.Builder) {
+				if comment, ok := c.BeforeExpression.(sqlComment); ok {
+					comment.Build(builder)
+					builder.WriteByte(' ')
+					c.BeforeExpression = nil
+				}
+				build(c, builder)
+			}
+		}
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("*").Register("gpagorm:statement_comment", annotate("INSERT")),
+		callbacks.Query().Before("*").Register("gpagorm:statement_comment", annotate("SELECT")),
+		callbacks.Update().Before("*").Register("gpagorm:statement_comment", annotate("UPDATE")),
+		callbacks.Delete().Before("*").Register("gpagorm:statement_comment", annotate("DELETE")),
+		callbacks.Row().Before("*").Register("gpagorm:statement_comment", annotate("SELECT")),
+		callbacks.Raw().Before("*").Register("gpagorm:statement_comment", annotate("")),
+	)
+}
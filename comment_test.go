@@ -0,0 +1,80 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+func TestWithComment(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var statements []string
+	record := func(db *gorm.DB) {
+		mu.Lock()
+		defer mu.Unlock()
+		statements = append(statements, db.Statement.SQL.String())
+	}
+	callbacks := provider.db.Callback()
+	callbacks.Create().After("*").Register("test:record", record)
+	callbacks.Query().After("*").Register("test:record", record)
+	callbacks.Update().After("*").Register("test:record", record)
+	callbacks.Delete().After("*").Register("test:record", record)
+	callbacks.Raw().After("*").Register("test:record", record)
+
+	repo := NewRepository[TestUser](provider.db, provider).WithComment("job:nightly-billing")
+	user := &TestUser{Name: "Alice", Email: "alice@example.com"}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := repo.Query(ctx, gpa.Where("name", gpa.OpEqual, "Alice")); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if _, err := repo.Count(ctx); err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	err := repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		return tx.UpdatePartial(ctx, user.ID, map[string]interface{}{"name": "Alice B."})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if _, err := repo.RawExec(ctx, "UPDATE test_users SET age = ?", []interface{}{30}); err != nil {
+		t.Fatalf("RawExec failed: %v", err)
+	}
+	if err := repo.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// Statements of other repositories are left alone
+	if _, err := NewRepository[TestUser](provider.db, provider).Count(ctx); err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+
+	mu.Lock()
+	recorded := statements
+	mu.Unlock()
+	if len(recorded) < 7 {
+		t.Fatalf("Expected at least 7 statements, got %q", recorded)
+	}
+	for _, sql := range recorded[:len(recorded)-1] {
+		if !strings.HasPrefix(sql, "/* job:nightly-billing */ ") {
+			t.Errorf("Expected the comment on %q", sql)
+		}
+	}
+	if last := recorded[len(recorded)-1]; strings.Contains(last, "/*") {
+		t.Errorf("Expected no comment on %q", last)
+	}
+
+	invalid := NewRepository[TestUser](provider.db, provider).WithComment("x */ DROP TABLE test_users; /*")
+	if _, err := invalid.Count(ctx); err == nil {
+		t.Error("Expected a comment closing itself to be rejected")
+	}
+}
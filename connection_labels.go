@@ -0,0 +1,154 @@
+// Package gpagorm provides connection labels for database-side attribution
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/lemmego/gpa"
+)
+
+// connectionLabelPattern matches label names and values, keeping them
+// free of the separators the drivers' DSN formats use
+var connectionLabelPattern = regexp.MustCompile(`^[A-Za-z0-9_ .@/+-]*$`)
+
+type connectionLabelKey struct{}
+
+// WithConnectionLabel returns a context whose repository operations are
+// labelled for the database: on Postgres, application_name is set to the
+// provider's application name followed by label for the duration of each
+// operation, so pg_stat_activity shows which code path holds a connection.
+// Other drivers fix their connection attributes at connect time and
+// ignore the label.
+func WithConnectionLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, connectionLabelKey{}, label)
+}
+
+// connectionLabelFromContext returns the label set with WithConnectionLabel
+func connectionLabelFromContext(ctx context.Context) string {
+	label, _ := ctx.Value(connectionLabelKey{}).(string)
+	return label
+}
+
+// connectionLabels returns the "connection_labels" option and the
+// application name: the "application_name" option, or the service,
+// version and instance labels joined with "/"
+func connectionLabels(config gpa.Config) (string, map[string]string, error) {
+	gormOpts := gormOptions(config)
+	labels := map[string]string{}
+	switch v := gormOpts["connection_labels"].(type) {
+	case nil:
+	case map[string]string:
+		for name, value := range v {
+			labels[name] = value
+		}
+	case map[string]interface{}:
+		for name, value := range v {
+			s, ok := value.(string)
+			if !ok {
+				return "", nil, fmt.Errorf("invalid connection label %s: %v", name, value)
+			}
+			labels[name] = s
+		}
+	default:
+		return "", nil, fmt.Errorf("invalid connection_labels: %v", v)
+	}
+	for name, value := range labels {
+		if name == "" || !connectionLabelPattern.MatchString(name) || !connectionLabelPattern.MatchString(value) {
+			return "", nil, fmt.Errorf("invalid connection label %s: %q", name, value)
+		}
+	}
+
+	name, ok := gormOpts["application_name"].(string)
+	if !ok {
+		var parts []string
+		for _, label := range []string{"service", "version", "instance"} {
+			if labels[label] != "" {
+				parts = append(parts, labels[label])
+			}
+		}
+		name = strings.Join(parts, "/")
+	}
+	if !connectionLabelPattern.MatchString(name) {
+		return "", nil, fmt.Errorf("invalid application_name: %q", name)
+	}
+	return name, labels, nil
+}
+
+// withConnectionLabels adds the application name and labels to dsn in the
+// driver's format, unless dsn already sets them
+func withConnectionLabels(config gpa.Config, dsn string) (string, error) {
+	name, labels, err := connectionLabels(config)
+	if err != nil || (name == "" && len(labels) == 0) {
+		return dsn, err
+	}
+
+	switch normalizeDriver(config.Driver) {
+	case "postgres":
+		if name == "" || strings.Contains(dsn, "application_name=") {
+			return dsn, nil
+		}
+		if strings.Contains(dsn, "://") {
+			return setURLParam(dsn, "application_name", name)
+		}
+		return dsn + " application_name='" + name + "'", nil
+	case "mysql":
+		cfg, err := mysqldriver.ParseDSN(dsn)
+		if err != nil {
+			return "", fmt.Errorf("invalid mysql dsn: %w", err)
+		}
+		if cfg.ConnectionAttributes != "" {
+			return dsn, nil
+		}
+		// Shown in performance_schema.session_connect_attrs
+		names := make([]string, 0, len(labels))
+		for label := range labels {
+			names = append(names, label)
+		}
+		sort.Strings(names)
+		var attributes []string
+		if name != "" {
+			attributes = append(attributes, "program_name:"+name)
+		}
+		for _, label := range names {
+			attributes = append(attributes, label+":"+labels[label])
+		}
+		cfg.ConnectionAttributes = strings.Join(attributes, ",")
+		return cfg.FormatDSN(), nil
+	case "sqlserver":
+		if name == "" || strings.Contains(strings.ToLower(dsn), "app name=") {
+			return dsn, nil
+		}
+		if strings.Contains(dsn, "://") {
+			return setURLParam(dsn, "app name", name)
+		}
+		return strings.TrimSuffix(dsn, ";") + ";app name=" + name, nil
+	}
+	return dsn, nil
+}
+
+// setURLParam sets a query parameter of a URL DSN
+func setURLParam(dsn, name, value string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("invalid dsn: %w", err)
+	}
+	query := u.Query()
+	query.Set(name, value)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// operationApplicationName is the application_name for an operation
+// labelled with WithConnectionLabel
+func operationApplicationName(name, label string) string {
+	if name == "" {
+		return label
+	}
+	return name + " " + label
+}
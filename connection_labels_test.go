@@ -0,0 +1,84 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestConnectionLabelsDSN(t *testing.T) {
+	labels := map[string]interface{}{"gorm": map[string]interface{}{
+		"connection_labels": map[string]interface{}{"service": "orders", "version": "1.4.2", "instance": "pod-7"},
+	}}
+
+	pg := gpa.Config{Driver: "postgres", Host: "db", Port: 5432, Username: "app", Database: "orders", Options: labels}
+	dsn, err := withConnectionLabels(pg, buildPostgresDSN(pg))
+	if err != nil || dsn != "host=db port=5432 user=app password= dbname=orders sslmode=disable application_name='orders/1.4.2/pod-7'" {
+		t.Errorf("Unexpected Postgres DSN: %s, %v", dsn, err)
+	}
+	dsn, _ = withConnectionLabels(pg, "postgres://app@db/orders?sslmode=disable")
+	if dsn != "postgres://app@db/orders?application_name=orders%2F1.4.2%2Fpod-7&sslmode=disable" {
+		t.Errorf("Unexpected Postgres URL: %s", dsn)
+	}
+	if dsn, _ = withConnectionLabels(pg, "postgres://db/orders?application_name=custom"); dsn != "postgres://db/orders?application_name=custom" {
+		t.Errorf("Expected an explicit application_name to win, got %s", dsn)
+	}
+
+	my := gpa.Config{Driver: "mysql", Host: "db", Port: 3306, Username: "app", Database: "orders", Options: labels}
+	dsn, err = withConnectionLabels(my, buildMySQLDSN(my))
+	if err != nil {
+		t.Fatalf("withConnectionLabels failed: %v", err)
+	}
+	if want := "connectionAttributes=program_name%3Aorders%2F1.4.2%2Fpod-7%2Cinstance%3Apod-7%2Cservice%3Aorders%2Cversion%3A1.4.2"; !strings.Contains(dsn, want) {
+		t.Errorf("Expected MySQL connection attributes %s, got %s", want, dsn)
+	}
+
+	ms := gpa.Config{Driver: "sqlserver", Host: "db", Port: 1433, Username: "sa", Database: "orders", Options: labels}
+	dsn, _ = withConnectionLabels(ms, buildSQLServerDSN(ms))
+	if dsn != "sqlserver://sa:@db:1433?app+name=orders%2F1.4.2%2Fpod-7&database=orders" {
+		t.Errorf("Unexpected SQL Server DSN: %s", dsn)
+	}
+
+	named := gpa.Config{Driver: "sqlserver", Options: map[string]interface{}{"gorm": map[string]interface{}{"application_name": "billing"}}}
+	if dsn, _ = withConnectionLabels(named, "server=db;database=orders"); dsn != "server=db;database=orders;app name=billing" {
+		t.Errorf("Unexpected SQL Server DSN: %s", dsn)
+	}
+}
+
+func TestConnectionLabelsInvalid(t *testing.T) {
+	_, err := NewProvider(gpa.Config{Driver: "sqlite", Database: ":memory:", Options: map[string]interface{}{"gorm": map[string]interface{}{
+		"connection_labels": map[string]string{"service": "orders' OR 1=1"},
+	}}})
+	if err == nil {
+		t.Error("Expected a label with a quote to be rejected")
+	}
+	_, err = NewProvider(gpa.Config{Driver: "sqlite", Database: ":memory:", Options: map[string]interface{}{"gorm": map[string]interface{}{
+		"application_name": "a,b",
+	}}})
+	if err == nil {
+		t.Error("Expected an application name with a comma to be rejected")
+	}
+}
+
+func TestConnectionLabelPerOperation(t *testing.T) {
+	cassette := &Cassette{Dialect: "postgres", ServerVersion: "16.2", Interactions: []Interaction{
+		{Query: "BEGIN"},
+		{Query: "SELECT set_config($1, $2, true)", Args: []interface{}{"application_name", "orders/1.4.2 checkout"},
+			Columns: []string{"set_config"}, Rows: [][]interface{}{{"orders/1.4.2 checkout"}}},
+		{Query: `SELECT count(*) FROM "test_users"`, Columns: []string{"count"}, Rows: [][]interface{}{{3}}},
+		{Query: "COMMIT"},
+	}}
+	provider, err := NewReplayProvider(cassette)
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+	provider.applicationName = "orders/1.4.2"
+
+	ctx := WithConnectionLabel(context.Background(), "checkout")
+	count, err := NewRepository[TestUser](provider.db, provider).Count(ctx)
+	if err != nil || count != 3 {
+		t.Fatalf("Count = %d, %v", count, err)
+	}
+}
@@ -0,0 +1,79 @@
+// Package gpagorm provides request-scoped values, such as the acting user,
+// tenant and trace ID, readable from hooks and GORM callbacks
+package gpagorm
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type actorKey struct{}
+
+type tenantKey struct{}
+
+type traceIDKey struct{}
+
+// WithActor returns a context naming the user or service on whose behalf
+// repository operations run with it
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set with WithActor
+func ActorFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// WithTenant returns a context naming the tenant repository operations run
+// with it act for
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set with WithTenant
+func TenantFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// WithTraceID returns a context whose repository operations belong to the
+// trace id
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFromContext returns the trace ID set with WithTraceID
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// StatementContext returns the context of the repository operation that
+// runs db's statement. Every repository operation, including migrations
+// and DDL, runs its statements with the context it was called with, so
+// GORM callbacks and GORM model hooks such as BeforeCreate(tx *gorm.DB)
+// can read the values set on it:
+//
+//	func (o *Order) BeforeCreate(tx *gorm.DB) error {
+//	    o.CreatedBy = gpagorm.ActorFromContext(gpagorm.StatementContext(tx))
+//	    return nil
+//	}
+//
+// It returns context.Background() when db has no context.
+func StatementContext(db *gorm.DB) context.Context {
+	if db == nil || db.Statement == nil || db.Statement.Context == nil {
+		return context.Background()
+	}
+	return db.Statement.Context
+}
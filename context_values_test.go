@@ -0,0 +1,74 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type testAuditedNote struct {
+	ID        uint
+	Body      string
+	CreatedBy string
+	Tenant    string
+}
+
+// BeforeCreate stamps the note from the operation's context
+func (n *testAuditedNote) BeforeCreate(tx *gorm.DB) error {
+	ctx := StatementContext(tx)
+	n.CreatedBy = ActorFromContext(ctx)
+	n.Tenant = TenantFromContext(ctx)
+	return nil
+}
+
+func TestContextValues(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[testAuditedNote](provider.db, provider)
+
+	var traces []string
+	err := provider.db.Callback().Create().Before("gorm:create").Register("test:trace", func(db *gorm.DB) {
+		traces = append(traces, TraceIDFromContext(StatementContext(db)))
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	ctx := WithTraceID(WithTenant(WithActor(context.Background(), "ann"), "acme"), "trace-1")
+	if err := repo.MigrateTable(ctx); err != nil {
+		t.Fatalf("MigrateTable failed: %v", err)
+	}
+	note := &testAuditedNote{Body: "hello"}
+	if err := repo.Create(ctx, note); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if note.CreatedBy != "ann" || note.Tenant != "acme" {
+		t.Errorf("Expected the hook to read the actor and tenant, got %+v", note)
+	}
+	if len(traces) != 1 || traces[0] != "trace-1" {
+		t.Errorf("Expected the callback to read the trace ID, got %v", traces)
+	}
+
+	if got := ActorFromContext(StatementContext(&gorm.DB{})); got != "" {
+		t.Errorf("Expected no actor without a statement, got %q", got)
+	}
+}
+
+func TestMigrationsHonorContext(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[testAuditedNote](provider.db, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := repo.MigrateTable(ctx); err == nil {
+		t.Error("Expected MigrateTable to fail with a cancelled context")
+	}
+	if err := repo.CreateTable(ctx); err == nil {
+		t.Error("Expected CreateTable to fail with a cancelled context")
+	}
+	if provider.db.Migrator().HasTable(&testAuditedNote{}) {
+		t.Error("Expected no table to be created with a cancelled context")
+	}
+}
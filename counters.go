@@ -0,0 +1,148 @@
+// Package gpagorm provides database-backed counters and rate limiting
+package gpagorm
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// counterRow is a row of the counters table; one row per key and window
+type counterRow struct {
+	Name        string     `gorm:"primaryKey;size:255"`
+	WindowStart time.Time  `gorm:"primaryKey"`
+	Hits        int64      `gorm:"not null;default:0"`
+	ExpiresAt   *time.Time `gorm:"index"`
+}
+
+// TableName returns the counters table name
+func (counterRow) TableName() string { return "gpagorm_counters" }
+
+// Counters provides fixed-window counters stored in the gpagorm_counters
+// table, usable as a rate limiter or quota shared by all app instances.
+// Like a repository, it runs inside the transaction it was created with.
+type Counters struct {
+	db       *gorm.DB
+	provider *Provider
+}
+
+// NewCounters creates counters on db, which may be a transaction
+func NewCounters(db *gorm.DB, provider *Provider) *Counters {
+	return &Counters{db: db, provider: provider}
+}
+
+// Counters returns counters bound to the provider's connection
+func (p *Provider) Counters() *Counters {
+	return NewCounters(p.db, p)
+}
+
+// Migrate creates the counters table if it does not exist
+func (c *Counters) Migrate(ctx context.Context) error {
+	return convertGormError(c.db.WithContext(ctx).AutoMigrate(&counterRow{}))
+}
+
+// IncrementCounter atomically increments key in the current window and
+// returns the new count. A zero window counts forever.
+func (c *Counters) IncrementCounter(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return c.IncrementCounterBy(ctx, key, window, 1)
+}
+
+// IncrementCounterBy atomically adds delta to key in the current window
+// and returns the new count. The count is the one the upsert wrote: read
+// with RETURNING on Postgres and SQLite, passed through LAST_INSERT_ID on
+// MySQL, and read back in the transaction holding the row lock elsewhere.
+func (c *Counters) IncrementCounterBy(ctx context.Context, key string, window time.Duration, delta int64) (int64, error) {
+	db := sessionDB(ctx, c.db, c.provider)
+	row := counterRow{Name: key, WindowStart: windowStart(db, window), Hits: delta}
+	if window > 0 {
+		expiresAt := row.WindowStart.Add(window)
+		row.ExpiresAt = &expiresAt
+	}
+	upsert := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}, {Name: "window_start"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"hits": gorm.Expr("gpagorm_counters.hits + ?", delta)}),
+	}
+
+	switch dialectName(db) {
+	case "postgres", "sqlite":
+		err := db.Clauses(upsert, clause.Returning{Columns: []clause.Column{{Name: "hits"}}}).Create(&row).Error
+		if err != nil {
+			return 0, convertGormError(err)
+		}
+		return row.Hits, nil
+	case "mysql":
+		// LAST_INSERT_ID(expr) reports the updated count as the insert ID;
+		// a single affected row is a new row holding delta
+		upsert.DoUpdates = clause.Assignments(map[string]interface{}{"hits": gorm.Expr("LAST_INSERT_ID(gpagorm_counters.hits + ?)", delta)})
+		inserted := gorm.WithResult()
+		created := db.Clauses(upsert, inserted).Create(&row)
+		if created.Error != nil {
+			return 0, convertGormError(created.Error)
+		}
+		if created.RowsAffected == 1 {
+			return delta, nil
+		}
+		hits, err := inserted.Result.LastInsertId()
+		return hits, convertGormError(err)
+	}
+
+	var hits int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(upsert).Create(&row).Error; err != nil {
+			return err
+		}
+		var err error
+		hits, err = c.count(tx, key, row.WindowStart)
+		return err
+	})
+	return hits, convertGormError(err)
+}
+
+// GetCount returns the count of key in the current window
+func (c *Counters) GetCount(ctx context.Context, key string, window time.Duration) (int64, error) {
+	db := sessionDB(ctx, c.db, c.provider)
+	return c.count(db, key, windowStart(db, window))
+}
+
+// Allow increments key and reports whether the count is within limit for
+// the current window
+func (c *Counters) Allow(ctx context.Context, key string, window time.Duration, limit int64) (bool, error) {
+	count, err := c.IncrementCounter(ctx, key, window)
+	if err != nil {
+		return false, err
+	}
+	return count <= limit, nil
+}
+
+// ResetCounter deletes every window of key
+func (c *Counters) ResetCounter(ctx context.Context, key string) error {
+	err := sessionDB(ctx, c.db, c.provider).Where("name = ?", key).Delete(&counterRow{}).Error
+	return convertGormError(err)
+}
+
+// PurgeExpired deletes counters whose window has ended and returns how many were removed
+func (c *Counters) PurgeExpired(ctx context.Context) (int64, error) {
+	db := sessionDB(ctx, c.db, c.provider)
+	result := db.Where("expires_at < ?", db.NowFunc()).Delete(&counterRow{})
+	return result.RowsAffected, convertGormError(result.Error)
+}
+
+// count reads the stored count of key for the window starting at start
+func (c *Counters) count(db *gorm.DB, key string, start time.Time) (int64, error) {
+	var hits []int64
+	err := db.Model(&counterRow{}).Where("name = ? AND window_start = ?", key, start).Pluck("hits", &hits).Error
+	if err != nil || len(hits) == 0 {
+		return 0, convertGormError(err)
+	}
+	return hits[0], nil
+}
+
+// windowStart returns the start of the fixed window containing now
+func windowStart(db *gorm.DB, window time.Duration) time.Time {
+	if window <= 0 {
+		return time.Unix(0, 0).UTC()
+	}
+	return db.NowFunc().UTC().Truncate(window)
+}
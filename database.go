@@ -0,0 +1,154 @@
+// Package gpagorm provides providers bound to sibling databases
+package gpagorm
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// databaseNamePattern matches the database names WithDatabase accepts for
+// drivers that address them by name
+var databaseNamePattern = regexp.MustCompile(`^[A-Za-z0-9_$-]+$`)
+
+// pgDatabaseParam matches the dbname setting of a keyword/value Postgres DSN
+var pgDatabaseParam = regexp.MustCompile(`(^|\s)dbname=\S*`)
+
+// sharedPools are the connection pools a derived provider borrows
+type sharedPools struct {
+	primary     gorm.ConnPool
+	replicas    []gorm.ConnPool
+	replicaDSNs []string
+	tablePrefix string
+}
+
+// WithDatabase returns a provider bound to the database name on the same
+// server, for applications that shard data across sibling databases. It
+// inherits the configuration and registered interceptors, policies, scopes,
+// profiles and canonicalizers of p.
+//
+// On MySQL the derived provider shares p's connection pools and qualifies
+// table names with the database, and its Close leaves the pools open.
+// Postgres and SQL Server connections are bound to one database, so the
+// derived provider opens pools of its own, as it does on SQLite, where
+// name is the database file; those must be closed when no longer needed.
+func (p *Provider) WithDatabase(name string) (*Provider, error) {
+	if name == "" {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "database name is required")
+	}
+	config, err := withDatabaseConfig(p.currentConfig(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	var derived *Provider
+	if dialectName(p.db) == "mysql" {
+		shared := &sharedPools{primary: p.db.ConnPool, tablePrefix: name + "."}
+		p.replicaMu.RLock()
+		for i, replica := range p.replicas {
+			shared.replicas = append(shared.replicas, replica.ConnPool)
+			shared.replicaDSNs = append(shared.replicaDSNs, p.replicaDSNs[i])
+		}
+		p.replicaMu.RUnlock()
+		derived, err = newProvider(config, shared)
+	} else {
+		derived, err = NewProvider(config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	derived.interceptors = slices.Clone(p.interceptors)
+	derived.policies = maps.Clone(p.policies)
+	derived.scopes = cloneRegistry(p.scopes)
+	derived.profiles = cloneRegistry(p.profiles)
+	derived.canonicalizers = cloneRegistry(p.canonicalizers)
+	derived.allowedValues = cloneRegistry(p.allowedValues)
+	derived.sessionVarsResolver = p.sessionVarsResolver
+	p.mu.RUnlock()
+	return derived, nil
+}
+
+// cloneRegistry copies a per-type registry and its inner maps, so
+// registrations on a derived provider do not reach the parent
+func cloneRegistry[K, N comparable, V any](registry map[K]map[N]V) map[K]map[N]V {
+	if registry == nil {
+		return nil
+	}
+	clone := make(map[K]map[N]V, len(registry))
+	for key, entries := range registry {
+		clone[key] = maps.Clone(entries)
+	}
+	return clone
+}
+
+// withDatabaseConfig returns config pointed at the database name
+func withDatabaseConfig(config gpa.Config, name string) (gpa.Config, error) {
+	driver := normalizeDriver(config.Driver)
+	if driver != "sqlite" && !databaseNamePattern.MatchString(name) {
+		return config, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("invalid database name: %q", name))
+	}
+	config.Database = name
+	if config.ConnectionURL == "" {
+		return config, nil
+	}
+
+	switch driver {
+	case "postgres":
+		if u, err := url.Parse(config.ConnectionURL); err == nil && u.Scheme != "" {
+			u.Path = "/" + name
+			config.ConnectionURL = u.String()
+		} else if pgDatabaseParam.MatchString(config.ConnectionURL) {
+			config.ConnectionURL = pgDatabaseParam.ReplaceAllString(config.ConnectionURL, "${1}dbname="+name)
+		} else {
+			config.ConnectionURL = strings.TrimSpace(config.ConnectionURL + " dbname=" + name)
+		}
+	case "sqlserver":
+		u, err := url.Parse(config.ConnectionURL)
+		if err != nil {
+			return config, gpa.NewErrorWithCause(gpa.ErrorTypeInvalidArgument, "invalid connection URL", err)
+		}
+		query := u.Query()
+		query.Set("database", name)
+		u.RawQuery = query.Encode()
+		config.ConnectionURL = u.String()
+	}
+	return config, nil
+}
+
+// sharedDialector returns a dialector over the pool of another provider
+func sharedDialector(config gpa.Config, pool gorm.ConnPool) (gorm.Dialector, error) {
+	if normalizeDriver(config.Driver) != "mysql" {
+		return nil, fmt.Errorf("cannot share connections of driver %s", config.Driver)
+	}
+	return mysql.New(mysql.Config{Conn: pool}), nil
+}
+
+// openSharedReplica opens a replica over the pool of another provider
+func (p *Provider) openSharedReplica(config gpa.Config, pool gorm.ConnPool, gormConfig *gorm.Config) (*gorm.DB, error) {
+	dialector, err := sharedDialector(config, pool)
+	if err != nil {
+		return nil, err
+	}
+	replica, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to replica: %w", err)
+	}
+	if err := p.totals.register(replica); err != nil {
+		return nil, err
+	}
+	if p.queryStats != nil {
+		if err := p.queryStats.register(replica); err != nil {
+			return nil, err
+		}
+	}
+	return replica, nil
+}
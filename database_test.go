@@ -0,0 +1,131 @@
+package gpagorm
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestWithDatabaseSharedPool(t *testing.T) {
+	cassette := &Cassette{Dialect: "mysql", ServerVersion: "8.0.36", Interactions: []Interaction{
+		{Query: "SELECT VERSION()", Columns: []string{"VERSION()"}, Rows: [][]interface{}{{"8.0.36"}}},
+		{Query: "SELECT * FROM `tenant_2`.`test_users` WHERE `test_users`.`id` = ? ORDER BY `test_users`.`id` LIMIT ?",
+			Args: []interface{}{float64(7), float64(1)}, Columns: []string{"id", "name", "email", "age"},
+			Rows: [][]interface{}{{7, "Ada", "ada@example.com", 36}}},
+	}}
+	provider, err := NewReplayProvider(cassette)
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+	provider.config.Options = map[string]interface{}{"gorm": map[string]interface{}{"log_level": "silent"}}
+
+	tenant, err := provider.WithDatabase("tenant_2")
+	if err != nil {
+		t.Fatalf("WithDatabase failed: %v", err)
+	}
+	if tenant.db.ConnPool != provider.db.ConnPool {
+		t.Error("Expected the derived provider to share the pool")
+	}
+
+	user, err := NewRepository[TestUser](tenant.db, tenant).FindByID(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if user.Name != "Ada" {
+		t.Errorf("Expected Ada, got %+v", user)
+	}
+
+	if err := tenant.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := tenant.Configure(tenant.currentConfig()); err == nil {
+		t.Error("Expected a provider sharing connections to refuse reconfiguration")
+	}
+	if _, err := provider.WithDatabase("tenant`; DROP DATABASE app"); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected an invalid database name to be rejected, got %v", err)
+	}
+}
+
+func TestWithDatabaseSeparatePool(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	provider.AddInterceptor(func(ctx context.Context, op OperationInfo, next func(context.Context) error) error {
+		return next(ctx)
+	})
+
+	archive, err := provider.WithDatabase(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatalf("WithDatabase failed: %v", err)
+	}
+	defer archive.Close()
+	if len(archive.interceptors) != 1 {
+		t.Errorf("Expected interceptors to be inherited, got %d", len(archive.interceptors))
+	}
+
+	if err := archive.db.AutoMigrate(&TestUser{}); err != nil {
+		t.Fatalf("Failed to migrate archive: %v", err)
+	}
+	ctx := context.Background()
+	if err := NewRepository[TestUser](archive.db, archive).Create(ctx, &TestUser{Name: "Old", Email: "old@example.com"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	count, err := NewRepository[TestUser](provider.db, provider).Count(ctx)
+	if err != nil || count != 0 {
+		t.Errorf("Expected the original database to be untouched, got %d, %v", count, err)
+	}
+
+	config, err := withDatabaseConfig(gpa.Config{Driver: "postgres", ConnectionURL: "host=db user=app dbname=main sslmode=disable"}, "reports")
+	if err != nil || config.ConnectionURL != "host=db user=app dbname=reports sslmode=disable" {
+		t.Errorf("Unexpected Postgres DSN: %s, %v", config.ConnectionURL, err)
+	}
+	config, _ = withDatabaseConfig(gpa.Config{Driver: "postgres", ConnectionURL: "postgres://app@db:5432/main?sslmode=disable"}, "reports")
+	if config.ConnectionURL != "postgres://app@db:5432/reports?sslmode=disable" {
+		t.Errorf("Unexpected Postgres URL: %s", config.ConnectionURL)
+	}
+	config, _ = withDatabaseConfig(gpa.Config{Driver: "mssql", ConnectionURL: "sqlserver://sa:pw@db:1433?database=main"}, "reports")
+	if config.ConnectionURL != "sqlserver://sa:pw@db:1433?database=reports" {
+		t.Errorf("Unexpected SQL Server URL: %s", config.ConnectionURL)
+	}
+}
+
+func TestWithDatabaseRegistriesAreIndependent(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	parent := NewRepository[TestUser](provider.db, provider)
+	parent.RegisterScope("adults", gpa.Where("age", gpa.OpGreaterThanOrEqual, 18))
+	if err := parent.RegisterProfile("public", Profile{Include: []string{"name"}}); err != nil {
+		t.Fatalf("RegisterProfile failed: %v", err)
+	}
+	if err := parent.RegisterCanonicalizer("email", TrimSpace); err != nil {
+		t.Fatalf("RegisterCanonicalizer failed: %v", err)
+	}
+
+	archive, err := provider.WithDatabase(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatalf("WithDatabase failed: %v", err)
+	}
+	defer archive.Close()
+	derived := NewRepository[TestUser](archive.db, archive)
+	if derived.scope("adults") == nil {
+		t.Error("Expected scopes to be inherited")
+	}
+
+	derived.RegisterScope("seniors", gpa.Where("age", gpa.OpGreaterThanOrEqual, 65))
+	if err := derived.RegisterProfile("admin", Profile{Include: []string{"name", "email"}}); err != nil {
+		t.Fatalf("RegisterProfile failed: %v", err)
+	}
+	if err := derived.RegisterCanonicalizer("name", TrimSpace); err != nil {
+		t.Fatalf("RegisterCanonicalizer failed: %v", err)
+	}
+	if parent.scope("seniors") != nil {
+		t.Error("Expected a scope registered on the derived provider to stay off the parent")
+	}
+	if _, ok := parent.profile("admin"); ok {
+		t.Error("Expected a profile registered on the derived provider to stay off the parent")
+	}
+	if _, ok := parent.fieldCanonicalizers()["name"]; ok {
+		t.Error("Expected a canonicalizer registered on the derived provider to stay off the parent")
+	}
+}
@@ -0,0 +1,108 @@
+// Package gpagorm provides batch creates that stop short of the deadline
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultDeadlineMargin is the time left before the deadline at which
+// CreateBatchBeforeDeadline stops unless "deadline_margin" is set
+const defaultDeadlineMargin = 50 * time.Millisecond
+
+// PartialBatchResult reports how far CreateBatchBeforeDeadline got
+type PartialBatchResult struct {
+	Inserted   int  // Entities inserted, entities[:Inserted]
+	ResumeFrom int  // Resume token: index of the first entity not inserted
+	Complete   bool // Whether every entity was inserted
+	Chunks     int  // INSERT statements issued
+}
+
+// CreateBatchBeforeDeadline inserts entities in chunks, each committed on
+// its own with the session variables of ctx, sizing the chunks by the observed insert times so they finish
+// before ctx's deadline. When too little time is left for another chunk, it
+// stops without an error and reports where to resume, so request handlers
+// with a latency budget can import in several calls:
+//
+//	res, err := repo.CreateBatchBeforeDeadline(ctx, rows[offset:])
+//	offset += res.ResumeFrom
+//
+// The "deadline_margin" option sets the time kept in reserve before the
+// deadline. If a chunk is cancelled anyway, the result is returned with
+// an error from which BatchProgress reads the rows inserted.
+func (r *Repository[T]) CreateBatchBeforeDeadline(ctx context.Context, entities []*T) (result *PartialBatchResult, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationCreateBatchBeforeDeadline, Entity: entities}, func(ctx context.Context) error {
+		var err error
+		result, err = r.createBatchBeforeDeadline(ctx, entities)
+		return err
+	})
+	return result, err
+}
+
+// createBatchBeforeDeadline implements CreateBatchBeforeDeadline
+func (r *Repository[T]) createBatchBeforeDeadline(ctx context.Context, entities []*T) (*PartialBatchResult, error) {
+	margin, err := r.provider.deadlineMargin()
+	if err != nil {
+		return nil, err
+	}
+	deadline, hasDeadline := ctx.Deadline()
+
+	result := &PartialBatchResult{}
+	// Slowest chunk and slowest time per row seen so far; a chunk is
+	// assumed to take at least the slowest one, which covers round trips
+	var slowest, perRow time.Duration
+	for result.Inserted < len(entities) {
+		size := min(createBatchSize, len(entities)-result.Inserted)
+		if hasDeadline {
+			budget := time.Until(deadline) - margin
+			if budget <= slowest {
+				break
+			}
+			if perRow > 0 {
+				size = min(size, int(budget/perRow))
+			}
+			if size < 1 {
+				break
+			}
+		}
+
+		chunk := entities[result.Inserted : result.Inserted+size]
+		started := time.Now()
+		err := r.withSessionVars(ctx, func(ctx context.Context) error {
+			_, err := r.createBatchResult(ctx, chunk)
+			return err
+		})
+		if err != nil {
+			result.ResumeFrom = result.Inserted
+			return result, partialProgress(OperationCreateBatchBeforeDeadline, result.Inserted, err)
+		}
+		elapsed := time.Since(started)
+		slowest = max(slowest, elapsed)
+		perRow = max(perRow, elapsed/time.Duration(size))
+		result.Inserted += size
+		result.Chunks++
+	}
+
+	result.ResumeFrom = result.Inserted
+	result.Complete = result.Inserted == len(entities)
+	return result, nil
+}
+
+// deadlineMargin returns the "deadline_margin" option
+func (p *Provider) deadlineMargin() (time.Duration, error) {
+	if p == nil {
+		return defaultDeadlineMargin, nil
+	}
+	switch margin := gormOptions(p.currentConfig())["deadline_margin"].(type) {
+	case time.Duration:
+		return margin, nil
+	case string:
+		parsed, err := time.ParseDuration(margin)
+		if err != nil {
+			return 0, fmt.Errorf("invalid deadline_margin: %w", err)
+		}
+		return parsed, nil
+	}
+	return defaultDeadlineMargin, nil
+}
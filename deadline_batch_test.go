@@ -0,0 +1,117 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func deadlineTestUsers(n int) []*TestUser {
+	users := make([]*TestUser, n)
+	for i := range users {
+		users[i] = &TestUser{Name: "user", Email: fmt.Sprintf("user%d@example.com", i)}
+	}
+	return users
+}
+
+func TestCreateBatchBeforeDeadline(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	provider.config.Options = map[string]interface{}{
+		"gorm": map[string]interface{}{"deadline_margin": "20ms"},
+	}
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	// Without a deadline every entity is inserted
+	result, err := repo.CreateBatchBeforeDeadline(context.Background(), deadlineTestUsers(250))
+	if err != nil {
+		t.Fatalf("CreateBatchBeforeDeadline failed: %v", err)
+	}
+	if !result.Complete || result.Inserted != 250 || result.ResumeFrom != 250 || result.Chunks != 3 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	// Slow inserts run out of time part way
+	if err := provider.db.Callback().Create().Before("gorm:create").Register("test:slow", func(*gorm.DB) {
+		time.Sleep(30 * time.Millisecond)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	users := deadlineTestUsers(5000)[250:]
+	result, err = repo.CreateBatchBeforeDeadline(ctx, users)
+	if err != nil {
+		t.Fatalf("Expected to stop before the deadline, got %v", err)
+	}
+	if result.Complete || result.Inserted == 0 || result.ResumeFrom != result.Inserted {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if ctx.Err() != nil {
+		t.Error("Expected to return before the deadline")
+	}
+	count, _ := repo.Count(context.Background())
+	if count != int64(250+result.Inserted) {
+		t.Errorf("Expected %d rows, got %d", 250+result.Inserted, count)
+	}
+	if users[result.ResumeFrom].ID != 0 || users[result.ResumeFrom-1].ID == 0 {
+		t.Error("Expected the resume token to point at the first entity not inserted")
+	}
+
+	// No time left at all
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	result, err = repo.CreateBatchBeforeDeadline(ctx, deadlineTestUsers(10))
+	if err != nil || result.Inserted != 0 || result.Complete {
+		t.Errorf("Expected nothing inserted, got %+v (%v)", result, err)
+	}
+}
+
+func TestCreateBatchBeforeDeadlineCommitsEachChunk(t *testing.T) {
+	users := deadlineTestUsers(createBatchSize + 1)
+	var placeholders []string
+	var args []interface{}
+	var ids [][]interface{}
+	for i, user := range users[:createBatchSize] {
+		placeholders = append(placeholders, fmt.Sprintf("($%d,$%d,$%d)", 3*i+1, 3*i+2, 3*i+3))
+		args = append(args, user.Name, user.Email, 0)
+		ids = append(ids, []interface{}{i + 1})
+	}
+	setVars := Interaction{Query: "SELECT set_config($1, $2, true)", Args: []interface{}{"app.tenant_id", "acme"}}
+	cassette := &Cassette{Dialect: "postgres", ServerVersion: "16.2", Interactions: []Interaction{
+		{Query: "BEGIN"},
+		setVars,
+		{
+			Query:   `INSERT INTO "test_users" ("name","email","age") VALUES ` + strings.Join(placeholders, ",") + ` RETURNING "id"`,
+			Args:    args,
+			Columns: []string{"id"},
+			Rows:    ids,
+		},
+		{Query: "COMMIT"},
+		{Query: "BEGIN"},
+		setVars,
+		{Query: "ROLLBACK"},
+	}}
+	provider, err := NewReplayProvider(cassette)
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+	ctx := WithSessionVars(context.Background(), map[string]string{"app.tenant_id": "acme"})
+
+	// The second chunk is not recorded and fails; the first one stays
+	// committed in its own session variable transaction
+	result, err := NewRepository[TestUser](provider.db, provider).CreateBatchBeforeDeadline(ctx, users)
+	if err == nil {
+		t.Fatal("Expected the second chunk to fail")
+	}
+	if result.Inserted != createBatchSize || result.Chunks != 1 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if remaining := provider.ReplayRemaining(); len(remaining) != 0 {
+		t.Errorf("Expected every recorded statement to run, remaining %v", remaining)
+	}
+}
@@ -0,0 +1,274 @@
+// Package gpagorm provides a fixed-point decimal type for money columns
+package gpagorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Decimal is an arbitrary-precision fixed-point number for money and other
+// exact quantities. It is stored as NUMERIC(p,s) / DECIMAL(p,s) using the
+// field's `precision` and `scale` GORM tags (20 and 4 when not tagged),
+// scanned without passing through float64, and bound to statements as its
+// exact string form.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+const (
+	// maxDecimalScale bounds the digits after the point and the exponent
+	// of parsed decimals, so input such as "1e2000000000" cannot exhaust
+	// memory; it is within what NUMERIC columns hold
+	maxDecimalScale = 1000
+	// defaultDecimalPrecision and defaultDecimalScale size the columns of
+	// fields without precision and scale tags; the scale keeps cents and
+	// sub-cent rates
+	defaultDecimalPrecision = 20
+	defaultDecimalScale     = 4
+)
+
+// NewDecimal returns unscaled * 10^-scale, e.g. NewDecimal(1999, 2) is 19.99
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	if scale < 0 {
+		return Decimal{unscaled: new(big.Int).Mul(big.NewInt(unscaled), pow10(-scale))}
+	}
+	return Decimal{unscaled: big.NewInt(unscaled), scale: scale}
+}
+
+// ParseDecimal parses a decimal string such as "-12.3400" or "1e-3". The
+// exponent and the resulting scale may not exceed 1000 in magnitude.
+func ParseDecimal(s string) (Decimal, error) {
+	str := strings.TrimSpace(s)
+	if str == "" {
+		return Decimal{}, fmt.Errorf("invalid decimal: %q", s)
+	}
+
+	exp := int64(0)
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		if _, err := fmt.Sscan(str[i+1:], &exp); err != nil {
+			return Decimal{}, fmt.Errorf("invalid decimal: %q", s)
+		}
+		if exp > maxDecimalScale || exp < -maxDecimalScale {
+			return Decimal{}, fmt.Errorf("decimal exponent out of range: %q", s)
+		}
+		str = str[:i]
+	}
+
+	scale := int64(0)
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		scale = int64(len(str) - i - 1)
+		str = str[:i] + str[i+1:]
+	}
+
+	unscaled, ok := new(big.Int).SetString(str, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal: %q", s)
+	}
+
+	scale -= exp
+	if scale > maxDecimalScale || scale < -maxDecimalScale {
+		return Decimal{}, fmt.Errorf("decimal scale out of range: %q", s)
+	}
+	if scale < 0 {
+		unscaled.Mul(unscaled, pow10(int32(-scale)))
+		scale = 0
+	}
+	return Decimal{unscaled: unscaled, scale: int32(scale)}, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics on invalid input
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// pow10 returns 10^n
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// int returns the unscaled value, treating the zero Decimal as 0
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// rescale returns the unscaled value expressed at a larger scale
+func (d Decimal) rescale(scale int32) *big.Int {
+	return new(big.Int).Mul(d.int(), pow10(scale-d.scale))
+}
+
+// Scale returns the number of digits after the decimal point
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// String returns the exact decimal representation
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	sign := ""
+	if d.int().Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale == 0 {
+		return sign + digits
+	}
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	point := len(digits) - int(d.scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// Add returns d + other
+func (d Decimal) Add(other Decimal) Decimal {
+	scale := max(d.scale, other.scale)
+	return Decimal{unscaled: new(big.Int).Add(d.rescale(scale), other.rescale(scale)), scale: scale}
+}
+
+// Sub returns d - other
+func (d Decimal) Sub(other Decimal) Decimal {
+	scale := max(d.scale, other.scale)
+	return Decimal{unscaled: new(big.Int).Sub(d.rescale(scale), other.rescale(scale)), scale: scale}
+}
+
+// Mul returns d * other without rounding
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{unscaled: new(big.Int).Mul(d.int(), other.int()), scale: d.scale + other.scale}
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{unscaled: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Round rounds d half away from zero to the given number of decimal places
+func (d Decimal) Round(scale int32) Decimal {
+	if scale >= d.scale {
+		return Decimal{unscaled: d.rescale(scale), scale: scale}
+	}
+	divisor := pow10(d.scale - scale)
+	quotient, remainder := new(big.Int).QuoRem(d.int(), divisor, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(divisor) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(d.int().Sign())))
+	}
+	return Decimal{unscaled: quotient, scale: scale}
+}
+
+// Cmp compares d and other, returning -1, 0 or +1
+func (d Decimal) Cmp(other Decimal) int {
+	scale := max(d.scale, other.scale)
+	return d.rescale(scale).Cmp(other.rescale(scale))
+}
+
+// Equal reports whether d and other are numerically equal
+func (d Decimal) Equal(other Decimal) bool {
+	return d.Cmp(other) == 0
+}
+
+// IsZero reports whether d is zero
+func (d Decimal) IsZero() bool {
+	return d.int().Sign() == 0
+}
+
+// Scan implements sql.Scanner without converting through float64
+func (d *Decimal) Scan(value interface{}) error {
+	var str string
+	switch v := value.(type) {
+	case nil:
+		*d = Decimal{}
+		return nil
+	case []byte:
+		str = string(v)
+	case string:
+		str = v
+	case int64:
+		*d = NewDecimal(v, 0)
+		return nil
+	case float64:
+		// Some drivers (SQLite) return REAL; use the shortest exact repr
+		str = fmt.Sprintf("%v", v)
+	default:
+		return fmt.Errorf("cannot scan %T into Decimal", value)
+	}
+
+	parsed, err := ParseDecimal(str)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, binding the exact string form
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// MarshalJSON encodes the decimal as a JSON string to preserve precision
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts both JSON strings and numbers
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	str := strings.Trim(string(data), `"`)
+	if str == "null" {
+		*d = Decimal{}
+		return nil
+	}
+	parsed, err := ParseDecimal(str)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// GormDataType returns the generic GORM data type
+func (Decimal) GormDataType() string {
+	return "decimal"
+}
+
+// GormDBDataType returns the dialect column type honoring precision/scale.
+// An untagged scale defaults to 4 rather than 0, which would round away
+// the cents of money columns.
+func (Decimal) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	precision, scale := field.Precision, field.Scale
+	if precision == 0 {
+		precision = defaultDecimalPrecision
+	}
+	if _, tagged := field.TagSettings["SCALE"]; !tagged {
+		scale = min(defaultDecimalScale, precision)
+	}
+	switch dialectName(db) {
+	case "postgres":
+		return fmt.Sprintf("NUMERIC(%d,%d)", precision, scale)
+	case "sqlite":
+		return "NUMERIC"
+	default:
+		return fmt.Sprintf("DECIMAL(%d,%d)", precision, scale)
+	}
+}
+
+// GormValue binds the decimal as a string literal-compatible parameter
+func (d Decimal) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	if dialectName(db) == "sqlite" {
+		// SQLite compares TEXT and NUMERIC by type; cast for numeric semantics
+		return clause.Expr{SQL: "CAST(? AS NUMERIC)", Vars: []interface{}{d.String()}}
+	}
+	return clause.Expr{SQL: "?", Vars: []interface{}{d.String()}}
+}
@@ -0,0 +1,125 @@
+package gpagorm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type TestInvoice struct {
+	ID    uint    `gorm:"primaryKey"`
+	Total Decimal `gorm:"precision:12;scale:2"`
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	a := MustParseDecimal("0.10")
+	b := MustParseDecimal("0.20")
+
+	if sum := a.Add(b); sum.String() != "0.30" {
+		t.Errorf("Expected 0.30, got %s", sum)
+	}
+	if diff := a.Sub(b); diff.String() != "-0.10" {
+		t.Errorf("Expected -0.10, got %s", diff)
+	}
+	if product := MustParseDecimal("19.99").Mul(NewDecimal(3, 0)); product.String() != "59.97" {
+		t.Errorf("Expected 59.97, got %s", product)
+	}
+	if rounded := MustParseDecimal("2.345").Round(2); rounded.String() != "2.35" {
+		t.Errorf("Expected 2.35, got %s", rounded)
+	}
+	if rounded := MustParseDecimal("-2.345").Round(2); rounded.String() != "-2.35" {
+		t.Errorf("Expected -2.35, got %s", rounded)
+	}
+	if !MustParseDecimal("1.50").Equal(MustParseDecimal("1.5")) {
+		t.Error("Expected 1.50 to equal 1.5")
+	}
+	if d := MustParseDecimal("1.5e-3"); d.String() != "0.0015" {
+		t.Errorf("Expected 0.0015, got %s", d)
+	}
+	if _, err := ParseDecimal("12.3.4"); err == nil {
+		t.Error("Expected error for malformed decimal")
+	}
+	for _, s := range []string{"1e2000000000", "1e-3000000000", "1e1001", "0." + strings.Repeat("0", 1000) + "1"} {
+		if _, err := ParseDecimal(s); err == nil {
+			t.Errorf("Expected %.20s to be out of range", s)
+		}
+	}
+	if d := MustParseDecimal("1e1000"); len(d.String()) != 1001 {
+		t.Errorf("Expected 1e1000 to be parsed, got %d digits", len(d.String()))
+	}
+
+	data, _ := json.Marshal(MustParseDecimal("10.05"))
+	if string(data) != `"10.05"` {
+		t.Errorf("Expected JSON string, got %s", data)
+	}
+}
+
+func TestDecimalPersistence(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	if err := provider.db.AutoMigrate(&TestInvoice{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[TestInvoice](provider.db, provider)
+	ctx := context.Background()
+
+	invoice := &TestInvoice{Total: MustParseDecimal("19.99")}
+	if err := repo.Create(ctx, invoice); err != nil {
+		t.Fatalf("Failed to create invoice: %v", err)
+	}
+	if err := repo.Create(ctx, &TestInvoice{Total: MustParseDecimal("5.00")}); err != nil {
+		t.Fatalf("Failed to create invoice: %v", err)
+	}
+
+	found, err := repo.FindByID(ctx, invoice.ID)
+	if err != nil {
+		t.Fatalf("Failed to find invoice: %v", err)
+	}
+	if !found.Total.Equal(invoice.Total) {
+		t.Errorf("Expected total %s, got %s", invoice.Total, found.Total)
+	}
+
+	count, err := repo.Count(ctx, gpa.Where("total", gpa.OpGreaterThan, MustParseDecimal("10")))
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 invoice above 10, got %d", count)
+	}
+}
+
+func TestDecimalColumnType(t *testing.T) {
+	type price struct {
+		ID     uint
+		Amount Decimal
+		Units  Decimal `gorm:"precision:10;scale:0"`
+		Rate   Decimal `gorm:"precision:8;scale:6"`
+		Ratio  Decimal `gorm:"precision:3"`
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry-run postgres: %v", err)
+	}
+	s, err := schema.Parse(&price{}, &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	for field, want := range map[string]string{
+		"Amount": "NUMERIC(20,4)",
+		"Units":  "NUMERIC(10,0)",
+		"Rate":   "NUMERIC(8,6)",
+		"Ratio":  "NUMERIC(3,3)",
+	} {
+		if got := (Decimal{}).GormDBDataType(db, s.LookUpField(field)); got != want {
+			t.Errorf("%s: expected %s, got %s", field, want, got)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Package gpagorm provides schema drift detection between models and the live database
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DriftReport lists the differences between model definitions and the live schema
+type DriftReport struct {
+	Tables []TableDrift `json:"tables"`
+}
+
+// HasDrift reports whether any table differs from its model
+func (r DriftReport) HasDrift() bool {
+	return len(r.Tables) > 0
+}
+
+// TableDrift lists the differences for a single table
+type TableDrift struct {
+	Table              string           `json:"table"`
+	Missing            bool             `json:"missing,omitempty"`
+	MissingColumns     []string         `json:"missing_columns,omitempty"`
+	ExtraColumns       []string         `json:"extra_columns,omitempty"`
+	ColumnMismatches   []ColumnMismatch `json:"column_mismatches,omitempty"`
+	MissingIndexes     []string         `json:"missing_indexes,omitempty"`
+	MissingForeignKeys []string         `json:"missing_foreign_keys,omitempty"`
+}
+
+// hasDrift reports whether the table has any difference
+func (t TableDrift) hasDrift() bool {
+	return t.Missing || len(t.MissingColumns) > 0 || len(t.ExtraColumns) > 0 ||
+		len(t.ColumnMismatches) > 0 || len(t.MissingIndexes) > 0 || len(t.MissingForeignKeys) > 0
+}
+
+// DriftKind identifies which column attribute differs
+type DriftKind string
+
+const (
+	DriftType     DriftKind = "type"
+	DriftNullable DriftKind = "nullable"
+	DriftDefault  DriftKind = "default"
+)
+
+// ColumnMismatch describes a column whose definition differs from the model
+type ColumnMismatch struct {
+	Column   string    `json:"column"`
+	Kind     DriftKind `json:"kind"`
+	Expected string    `json:"expected"`
+	Actual   string    `json:"actual"`
+}
+
+// DetectDrift compares the live schema with the given models and reports
+// missing or extra columns, type, nullability and default mismatches, and
+// missing indexes and foreign keys. It never modifies the database.
+func (p *Provider) DetectDrift(ctx context.Context, models ...interface{}) (DriftReport, error) {
+	db := p.db.WithContext(ctx)
+	var report DriftReport
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return report, convertGormError(err)
+		}
+
+		drift, err := detectTableDrift(db, model, stmt.Schema, stmt.Table)
+		if err != nil {
+			return report, err
+		}
+		if drift.hasDrift() {
+			report.Tables = append(report.Tables, drift)
+		}
+	}
+
+	return report, nil
+}
+
+// detectTableDrift compares one model with its table
+func detectTableDrift(db *gorm.DB, model interface{}, sch *schema.Schema, table string) (TableDrift, error) {
+	migrator := db.Migrator()
+	drift := TableDrift{Table: table}
+
+	if !migrator.HasTable(model) {
+		drift.Missing = true
+		return drift, nil
+	}
+
+	columnTypes, err := migrator.ColumnTypes(model)
+	if err != nil {
+		return drift, convertGormError(err)
+	}
+	live := make(map[string]gorm.ColumnType, len(columnTypes))
+	for _, columnType := range columnTypes {
+		live[columnType.Name()] = columnType
+	}
+
+	for _, dbName := range sch.DBNames {
+		field := sch.FieldsByDBName[dbName]
+		if field.IgnoreMigration {
+			continue
+		}
+		columnType, ok := live[dbName]
+		if !ok {
+			drift.MissingColumns = append(drift.MissingColumns, dbName)
+			continue
+		}
+		drift.ColumnMismatches = append(drift.ColumnMismatches, compareColumn(db, field, columnType)...)
+	}
+
+	for _, columnType := range columnTypes {
+		if _, ok := sch.FieldsByDBName[columnType.Name()]; !ok {
+			drift.ExtraColumns = append(drift.ExtraColumns, columnType.Name())
+		}
+	}
+
+	for _, idx := range sch.ParseIndexes() {
+		if !migrator.HasIndex(model, idx.Name) {
+			drift.MissingIndexes = append(drift.MissingIndexes, idx.Name)
+		}
+	}
+
+	if !db.DisableForeignKeyConstraintWhenMigrating && !db.IgnoreRelationshipsWhenMigrating {
+		for _, rel := range sch.Relationships.Relations {
+			if rel.Field.IgnoreMigration {
+				continue
+			}
+			if constraint := rel.ParseConstraint(); constraint != nil &&
+				constraint.Schema == sch && !migrator.HasConstraint(model, constraint.Name) {
+				drift.MissingForeignKeys = append(drift.MissingForeignKeys, constraint.Name)
+			}
+		}
+	}
+
+	return drift, nil
+}
+
+// compareColumn applies the same type, nullability and default checks
+// GORM's AutoMigrate uses to decide whether a column needs altering
+func compareColumn(db *gorm.DB, field *schema.Field, columnType gorm.ColumnType) []ColumnMismatch {
+	var mismatches []ColumnMismatch
+	migrator := db.Migrator()
+
+	if !field.PrimaryKey {
+		expected := strings.TrimSpace(strings.ToLower(migrator.FullDataTypeOf(field).SQL))
+		actual := strings.ToLower(columnType.DatabaseTypeName())
+		if !strings.HasPrefix(expected, actual) && !hasTypeAlias(migrator, expected, actual) {
+			mismatches = append(mismatches, ColumnMismatch{
+				Column:   field.DBName,
+				Kind:     DriftType,
+				Expected: strings.ToLower(db.Dialector.DataTypeOf(field)),
+				Actual:   actual,
+			})
+		}
+	}
+
+	if nullable, ok := columnType.Nullable(); ok && !field.PrimaryKey && nullable == field.NotNull {
+		mismatches = append(mismatches, ColumnMismatch{
+			Column:   field.DBName,
+			Kind:     DriftNullable,
+			Expected: strconv.FormatBool(!field.NotNull),
+			Actual:   strconv.FormatBool(nullable),
+		})
+	}
+
+	if !field.PrimaryKey {
+		expectedSet := field.HasDefaultValue && (field.DefaultValueInterface != nil || !strings.EqualFold(field.DefaultValue, "NULL"))
+		actual, actualSet := columnType.DefaultValue()
+		expected := field.DefaultValue
+		if expectedSet && field.DefaultValueInterface != nil && expected == "" {
+			expected = fmt.Sprint(field.DefaultValueInterface)
+		}
+		if expectedSet != actualSet || (expectedSet && !sameDefault(field, expected, actual)) {
+			mismatches = append(mismatches, ColumnMismatch{
+				Column:   field.DBName,
+				Kind:     DriftDefault,
+				Expected: expected,
+				Actual:   actual,
+			})
+		}
+	}
+
+	return mismatches
+}
+
+// hasTypeAlias reports whether the live type is an alias of the expected one
+func hasTypeAlias(migrator gorm.Migrator, expected, actual string) bool {
+	for _, alias := range migrator.GetTypeAliases(actual) {
+		if strings.HasPrefix(expected, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameDefault compares default values ignoring quoting and casts
+func sameDefault(field *schema.Field, expected, actual string) bool {
+	normalize := func(value string) string {
+		if i := strings.Index(value, "::"); i >= 0 {
+			value = value[:i]
+		}
+		value = strings.Trim(strings.TrimSpace(value), "()'\"")
+		return strings.TrimSuffix(value, "()")
+	}
+	expected, actual = normalize(expected), normalize(actual)
+
+	if field.GORMDataType == schema.Bool {
+		v1, err1 := strconv.ParseBool(expected)
+		v2, err2 := strconv.ParseBool(actual)
+		if err1 == nil && err2 == nil {
+			return v1 == v2
+		}
+	}
+	return strings.EqualFold(expected, actual)
+}
@@ -0,0 +1,80 @@
+package gpagorm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+type TestDriftItem struct {
+	ID     uint   `gorm:"primaryKey"`
+	Name   string `gorm:"not null"`
+	SKU    string `gorm:"index"`
+	Status string `gorm:"default:'active'"`
+}
+
+func (TestDriftItem) TableName() string { return "drift_items" }
+
+func TestDetectDrift(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	report, err := provider.DetectDrift(ctx, &TestUser{}, &TestDriftItem{})
+	if err != nil {
+		t.Fatalf("Failed to detect drift: %v", err)
+	}
+	if len(report.Tables) != 1 || report.Tables[0].Table != "drift_items" || !report.Tables[0].Missing {
+		t.Fatalf("Expected only drift_items to be reported missing, got %+v", report)
+	}
+
+	// Create the table by hand with drifted columns
+	if err := provider.db.Exec("CREATE TABLE drift_items (id integer PRIMARY KEY, name text, status text DEFAULT 'pending', legacy text)").Error; err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	report, err = provider.DetectDrift(ctx, &TestDriftItem{})
+	if err != nil {
+		t.Fatalf("Failed to detect drift: %v", err)
+	}
+	if !report.HasDrift() {
+		t.Fatal("Expected drift to be reported")
+	}
+	drift := report.Tables[0]
+	if len(drift.MissingColumns) != 1 || drift.MissingColumns[0] != "sku" {
+		t.Errorf("Expected sku to be missing, got %v", drift.MissingColumns)
+	}
+	if len(drift.ExtraColumns) != 1 || drift.ExtraColumns[0] != "legacy" {
+		t.Errorf("Expected legacy to be extra, got %v", drift.ExtraColumns)
+	}
+	if len(drift.MissingIndexes) != 1 || drift.MissingIndexes[0] != "idx_drift_items_sku" {
+		t.Errorf("Expected sku index to be missing, got %v", drift.MissingIndexes)
+	}
+
+	kinds := map[string]DriftKind{}
+	for _, mismatch := range drift.ColumnMismatches {
+		kinds[mismatch.Column] = mismatch.Kind
+	}
+	if kinds["name"] != DriftNullable || kinds["status"] != DriftDefault {
+		t.Errorf("Expected nullable and default mismatches, got %+v", drift.ColumnMismatches)
+	}
+
+	if _, err := json.Marshal(report); err != nil {
+		t.Errorf("Expected report to be JSON serializable: %v", err)
+	}
+
+	// A freshly migrated table matches its model
+	if err := provider.db.Exec("DROP TABLE drift_items").Error; err != nil {
+		t.Fatalf("Failed to drop table: %v", err)
+	}
+	if err := provider.db.AutoMigrate(&TestDriftItem{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	report, err = provider.DetectDrift(ctx, &TestDriftItem{})
+	if err != nil {
+		t.Fatalf("Failed to detect drift: %v", err)
+	}
+	if report.HasDrift() {
+		t.Errorf("Expected no drift after migration, got %+v", report)
+	}
+}
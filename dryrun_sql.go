@@ -0,0 +1,92 @@
+// Package gpagorm provides per-dialect SQL rendering for golden tests
+package gpagorm
+
+import (
+	"context"
+	"sync"
+
+	"github.com/glebarez/sqlite"
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DryRunDialects are the dialects DryRunSQL renders for
+var DryRunDialects = []string{"postgres", "mysql", "sqlite", "sqlserver"}
+
+// RenderedSQL is a query as rendered for one dialect
+type RenderedSQL struct {
+	SQL       string        // Statement with the dialect's placeholders
+	Vars      []interface{} // Bound arguments
+	Explained string        // Statement with arguments inlined, for reading and goldens
+	Err       error         // Set when the options are invalid on the dialect
+}
+
+var dryRunDBs struct {
+	once sync.Once
+	dbs  map[string]*gorm.DB
+	err  error
+}
+
+// dryRunDB returns a connection-less DryRun session for dialect
+func dryRunDB(dialect string) (*gorm.DB, error) {
+	dryRunDBs.once.Do(func() {
+		dialectors := map[string]gorm.Dialector{
+			"postgres":  postgres.New(postgres.Config{DSN: "host=localhost dbname=gpagorm"}),
+			"mysql":     mysql.New(mysql.Config{DSN: "gpagorm@tcp(localhost)/gpagorm", SkipInitializeWithVersion: true}),
+			"sqlite":    sqlite.Open(":memory:"),
+			"sqlserver": sqlserver.Open("sqlserver://localhost?database=gpagorm"),
+		}
+		dryRunDBs.dbs = make(map[string]*gorm.DB, len(dialectors))
+		for name, dialector := range dialectors {
+			db, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard})
+			if err != nil {
+				dryRunDBs.err = gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to open dry-run "+name, err)
+				return
+			}
+			dryRunDBs.dbs[name] = db
+		}
+	})
+	if dryRunDBs.err != nil {
+		return nil, dryRunDBs.err
+	}
+	db, ok := dryRunDBs.dbs[dialect]
+	if !ok {
+		return nil, gpa.NewError(gpa.ErrorTypeUnsupported, "no dry-run dialect "+dialect)
+	}
+	return db, nil
+}
+
+// DryRunSQL renders the SELECT that Query would run for opts on every
+// dialect in DryRunDialects, without a database. Pinning the result in
+// golden tests catches changes in the SQL the adapter generates:
+//
+//	rendered, err := gpagorm.DryRunSQL[User](gpa.Where("age", gpa.OpGreaterThan, 30), gpa.Limit(5))
+//	got := rendered["postgres"].SQL // SELECT * FROM "users" WHERE age > $1 LIMIT $2
+func DryRunSQL[T any](opts ...gpa.QueryOption) (map[string]RenderedSQL, error) {
+	rendered := make(map[string]RenderedSQL, len(DryRunDialects))
+	for _, dialect := range DryRunDialects {
+		db, err := dryRunDB(dialect)
+		if err != nil {
+			return nil, err
+		}
+		repo := NewRepository[T](db, nil)
+
+		var entities []*T
+		stmt := repo.buildQuery(context.Background(), opts...).Find(&entities)
+		if stmt.Error != nil {
+			rendered[dialect] = RenderedSQL{Err: convertGormError(stmt.Error)}
+			continue
+		}
+		sql := stmt.Statement.SQL.String()
+		rendered[dialect] = RenderedSQL{
+			SQL:       sql,
+			Vars:      stmt.Statement.Vars,
+			Explained: db.Dialector.Explain(sql, stmt.Statement.Vars...),
+		}
+	}
+	return rendered, nil
+}
@@ -0,0 +1,49 @@
+package gpagorm
+
+import (
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestDryRunSQL(t *testing.T) {
+	rendered, err := DryRunSQL[TestUser](
+		gpa.Where("age", gpa.OpGreaterThan, 30),
+		OrderByNulls("name", gpa.OrderAsc, NullsLast),
+		gpa.Limit(5))
+	if err != nil {
+		t.Fatalf("DryRunSQL failed: %v", err)
+	}
+
+	golden := map[string]string{
+		"postgres":  `SELECT * FROM "test_users" WHERE "age" > $1 ORDER BY "name" ASC NULLS LAST LIMIT $2`,
+		"mysql":     "SELECT * FROM `test_users` WHERE `age` > ? ORDER BY `name` IS NULL,`name` ASC LIMIT ?",
+		"sqlite":    "SELECT * FROM `test_users` WHERE `age` > ? ORDER BY `name` ASC NULLS LAST LIMIT 5",
+		"sqlserver": `SELECT * FROM "test_users" WHERE "age" > @p1 ORDER BY CASE WHEN "name" IS NULL THEN 1 ELSE 0 END,"name" ASC OFFSET 0 ROW FETCH NEXT 5 ROWS ONLY`,
+	}
+	for dialect, want := range golden {
+		got := rendered[dialect]
+		if got.Err != nil {
+			t.Errorf("%s: unexpected error %v", dialect, got.Err)
+			continue
+		}
+		if got.SQL != want {
+			t.Errorf("%s:\n got %s\nwant %s", dialect, got.SQL, want)
+		}
+		if len(got.Vars) == 0 || got.Vars[0] != 30 {
+			t.Errorf("%s: expected age bound first, got %v", dialect, got.Vars)
+		}
+	}
+	if explained := rendered["mysql"].Explained; explained != "SELECT * FROM `test_users` WHERE `age` > 30 ORDER BY `name` IS NULL,`name` ASC LIMIT 5" {
+		t.Errorf("Unexpected explained SQL: %s", explained)
+	}
+
+	// Options invalid on a dialect report per dialect
+	rendered, err = DryRunSQL[TestUser](GroupByCube("name"))
+	if err != nil {
+		t.Fatalf("DryRunSQL failed: %v", err)
+	}
+	if rendered["sqlite"].Err == nil || rendered["postgres"].Err != nil {
+		t.Errorf("Expected only the UNION fallback to need selected fields, got %+v", rendered)
+	}
+}
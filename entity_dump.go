@@ -0,0 +1,272 @@
+// Package gpagorm provides logical export and import of entities as JSON
+package gpagorm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// entityDumpFormat identifies the JSON layout written by DumpEntities
+const entityDumpFormat = "gpagorm.entities/v1"
+
+// dumpBatchSize is how many rows DumpEntities reads at a time
+const dumpBatchSize = 500
+
+// ConflictPolicy decides what LoadEntities does with rows whose primary or
+// unique key already exists
+type ConflictPolicy string
+
+const (
+	// ConflictFail aborts the load on the first conflicting row
+	ConflictFail ConflictPolicy = "fail"
+	// ConflictSkip keeps the existing row
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing row with the loaded one
+	ConflictOverwrite ConflictPolicy = "overwrite"
+)
+
+// DumpEntities writes the entities matching opts to w as a JSON document:
+//
+//	{"format": "gpagorm.entities/v1", "entity": "User", "table": "users", "entities": [...]}
+//
+// Rows are read in batches and written as they arrive. Related rows are
+// included for relations requested with gpa.Preload.
+func (r *Repository[T]) DumpEntities(ctx context.Context, w io.Writer, opts ...gpa.QueryOption) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationDumpEntities, Options: opts}, func(ctx context.Context) error {
+		return r.dumpEntities(ctx, w, opts...)
+	})
+}
+
+// dumpEntities implements DumpEntities
+func (r *Repository[T]) dumpEntities(ctx context.Context, w io.Writer, opts ...gpa.QueryOption) error {
+	if err := r.authorizeRead(ctx, opts); err != nil {
+		return err
+	}
+	s, err := r.schema()
+	if err != nil {
+		return err
+	}
+
+	header, err := json.Marshal(map[string]string{"format": entityDumpFormat, "entity": s.Name, "table": s.Table})
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to encode dump header", err)
+	}
+	// Splice the entities array into the header object
+	if _, err := fmt.Fprintf(w, "%s,\"entities\":[", header[:len(header)-1]); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to write dump", err)
+	}
+
+	written := 0
+	var batch []*T
+	result := r.buildQuery(ctx, opts...).FindInBatches(&batch, dumpBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, entity := range batch {
+			data, err := json.Marshal(entity)
+			if err != nil {
+				return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to encode entity", err)
+			}
+			sep := ",\n"
+			if written == 0 {
+				sep = "\n"
+			}
+			if _, err := w.Write(append([]byte(sep), data...)); err != nil {
+				return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to write dump", err)
+			}
+			written++
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return convertGormError(partialProgress(OperationDumpEntities, written, result.Error))
+	}
+
+	closing := "]}\n"
+	if written > 0 {
+		closing = "\n" + closing
+	}
+	if _, err := io.WriteString(w, closing); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to write dump", err)
+	}
+	return nil
+}
+
+// LoadEntities reads a document written by DumpEntities for the same
+// entity and inserts its rows in one transaction, keeping their primary
+// keys. policy decides what happens to rows that already exist. Policies
+// and validation hooks apply as for CreateBatch. It returns the number of
+// rows inserted or overwritten; skipped rows are not counted.
+func (r *Repository[T]) LoadEntities(ctx context.Context, rd io.Reader, policy ConflictPolicy) (loaded int64, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationLoadEntities}, func(ctx context.Context) error {
+		var err error
+		loaded, err = r.loadEntities(ctx, rd, policy)
+		return err
+	})
+	return loaded, err
+}
+
+// loadEntities implements LoadEntities
+func (r *Repository[T]) loadEntities(ctx context.Context, rd io.Reader, policy ConflictPolicy) (int64, error) {
+	var conflict []clause.Expression
+	switch policy {
+	case ConflictFail, "":
+	case ConflictSkip:
+		conflict = append(conflict, clause.OnConflict{DoNothing: true})
+	case ConflictOverwrite:
+		conflict = append(conflict, clause.OnConflict{UpdateAll: true})
+	default:
+		return 0, gpa.NewError(gpa.ErrorTypeInvalidArgument, "unknown conflict policy: "+string(policy))
+	}
+	if err := r.checkWritable(); err != nil {
+		return 0, err
+	}
+	s, err := r.schema()
+	if err != nil {
+		return 0, err
+	}
+
+	var loaded int64
+	err = r.session(ctx).Transaction(func(tx *gorm.DB) error {
+		// Drivers disagree on affected rows for skipped conflicts, so count them
+		var zero T
+		var before int64
+		if policy == ConflictSkip {
+			if err := tx.Model(&zero).Unscoped().Count(&before).Error; err != nil {
+				return err
+			}
+		}
+
+		insert := tx.Clauses(conflict...)
+		flush := func(batch []*T) error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := r.prepareCreate(ctx, batch); err != nil {
+				return err
+			}
+			loaded += int64(len(batch))
+			switch policy {
+			case ConflictSkip:
+				inserted, err := insertIgnoringDuplicates(ctx, tx, s, batch)
+				if err != nil {
+					return err
+				}
+				r.recordEntityChange(ctx, ChangeCreated, inserted...)
+			case ConflictOverwrite:
+				if err := insert.Create(batch).Error; err != nil {
+					return err
+				}
+				// Inserted and overwritten rows cannot be told apart
+				r.recordEntityChange(ctx, ChangeUpdated, batch...)
+			default:
+				if err := insert.Create(batch).Error; err != nil {
+					return err
+				}
+				r.recordEntityChange(ctx, ChangeCreated, batch...)
+			}
+			return nil
+		}
+
+		var batch []*T
+		err := decodeEntityDump(rd, s.Name, func(dec *json.Decoder) error {
+			entity := new(T)
+			if err := dec.Decode(entity); err != nil {
+				return gpa.NewErrorWithCause(gpa.ErrorTypeInvalidArgument, "invalid entity in dump", err)
+			}
+			batch = append(batch, entity)
+			if len(batch) < 100 {
+				return nil
+			}
+			err := flush(batch)
+			batch = nil
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if err := flush(batch); err != nil {
+			return err
+		}
+		if policy == ConflictSkip {
+			var after int64
+			if err := tx.Model(&zero).Unscoped().Count(&after).Error; err != nil {
+				return err
+			}
+			loaded = after - before
+		}
+		return resetSequence(tx, s.Table, s.PrioritizedPrimaryField)
+	})
+	if err != nil {
+		return 0, convertGormError(err)
+	}
+	return loaded, nil
+}
+
+// decodeEntityDump walks a dump document, checking its header against
+// entity and calling next with the decoder positioned at each entity
+func decodeEntityDump(rd io.Reader, entity string, next func(dec *json.Decoder) error) error {
+	invalid := func(msg string) error {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "invalid entity dump: "+msg)
+	}
+	dec := json.NewDecoder(rd)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return invalid("expected an object")
+	}
+
+	format, name, found := "", "", false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return invalid(err.Error())
+		}
+		switch key, _ := tok.(string); key {
+		case "format":
+			err = dec.Decode(&format)
+		case "entity":
+			err = dec.Decode(&name)
+		case "entities":
+			if format != entityDumpFormat {
+				return invalid("unsupported format " + format)
+			}
+			if name != entity {
+				return invalid(fmt.Sprintf("dump holds %s, not %s", name, entity))
+			}
+			found = true
+			if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+				return invalid("expected an entities array")
+			}
+			for dec.More() {
+				if err := next(dec); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return invalid(err.Error())
+		}
+	}
+	if !found {
+		return invalid("no entities")
+	}
+	return nil
+}
+
+// resetSequence moves a Postgres serial sequence past rows inserted with
+// explicit keys, so later inserts do not collide with them
+func resetSequence(db *gorm.DB, table string, pk *schema.Field) error {
+	if dialectName(db) != "postgres" || pk == nil || !pk.AutoIncrement {
+		return nil
+	}
+	column := clause.Column{Name: pk.DBName}
+	return db.Exec("SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX(?), 1)) FROM ?",
+		table, pk.DBName, column, clause.Table{Name: table}).Error
+}
@@ -0,0 +1,96 @@
+package gpagorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestDumpAndLoadEntities(t *testing.T) {
+	source, cleanupSource := setupTestProvider(t)
+	defer cleanupSource()
+	target, cleanupTarget := setupTestProvider(t)
+	defer cleanupTarget()
+	ctx := context.Background()
+
+	from := NewRepository[TestUser](source.db, source)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		if err := from.Create(ctx, &TestUser{Name: name, Email: name + "@example.com", Age: len(name)}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := from.DumpEntities(ctx, &buf, gpa.Where("age", gpa.OpGreaterThan, 3)); err != nil {
+		t.Fatalf("Failed to dump: %v", err)
+	}
+	var doc struct {
+		Format   string     `json:"format"`
+		Entity   string     `json:"entity"`
+		Table    string     `json:"table"`
+		Entities []TestUser `json:"entities"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Dump is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if doc.Format != entityDumpFormat || doc.Entity != "TestUser" || doc.Table != "test_users" || len(doc.Entities) != 2 {
+		t.Fatalf("Unexpected dump: %s", buf.String())
+	}
+
+	to := NewRepository[TestUser](target.db, target)
+	if err := to.Create(ctx, &TestUser{Name: "existing", Email: "alice@example.com"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	dump := buf.String()
+	if _, err := to.LoadEntities(ctx, strings.NewReader(dump), ConflictFail); err == nil {
+		t.Error("Expected a conflict to fail the load")
+	}
+	if count, _ := to.Count(ctx); count != 1 {
+		t.Errorf("Expected a failed load to roll back, got %d users", count)
+	}
+
+	loaded, err := to.LoadEntities(ctx, strings.NewReader(dump), ConflictSkip)
+	if err != nil || loaded != 1 {
+		t.Fatalf("Expected 1 row loaded when skipping conflicts, got %d %v", loaded, err)
+	}
+	carol, err := to.FindByID(ctx, 3)
+	if err != nil || carol.Name != "carol" {
+		t.Errorf("Expected carol to keep her ID, got %v %v", carol, err)
+	}
+
+	loaded, err = to.LoadEntities(ctx, strings.NewReader(dump), ConflictOverwrite)
+	if err != nil || loaded != 2 {
+		t.Fatalf("Expected 2 rows written when overwriting, got %d %v", loaded, err)
+	}
+	alice, err := to.FindByID(ctx, 1)
+	if err != nil || alice.Name != "alice" {
+		t.Errorf("Expected alice to overwrite the existing row, got %v %v", alice, err)
+	}
+}
+
+func TestLoadEntitiesRejectsOtherDumps(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	cases := []string{
+		`{"format":"gpagorm.entities/v1","entity":"Order","entities":[]}`,
+		`{"format":"other","entity":"TestUser","entities":[]}`,
+		`{"format":"gpagorm.entities/v1","entity":"TestUser"}`,
+		`[]`,
+	}
+	for _, doc := range cases {
+		if _, err := repo.LoadEntities(ctx, strings.NewReader(doc), ConflictFail); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+			t.Errorf("Expected %s to be rejected, got %v", doc, err)
+		}
+	}
+	if _, err := repo.LoadEntities(ctx, strings.NewReader(`{}`), "merge"); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected an unknown policy to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+// Package gpagorm provides data subject erasure and anonymization
+package gpagorm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// erasureChunkSize bounds the keys in a single IN list
+const erasureChunkSize = 500
+
+// MaskFunc returns the replacement for a column value
+type MaskFunc func(value interface{}) interface{}
+
+// Nullify replaces values with NULL
+func Nullify() MaskFunc {
+	return func(interface{}) interface{} { return nil }
+}
+
+// Replace replaces values with a constant, e.g. "[erased]"
+func Replace(v interface{}) MaskFunc {
+	return func(interface{}) interface{} { return v }
+}
+
+// HashValue replaces values with the hex SHA-256 of salt and the value, so
+// equal inputs still match each other after masking. NULL stays NULL.
+func HashValue(salt string) MaskFunc {
+	return func(value interface{}) interface{} {
+		if value == nil {
+			return nil
+		}
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		sum := sha256.Sum256([]byte(salt + fmt.Sprint(value)))
+		return hex.EncodeToString(sum[:])
+	}
+}
+
+// ErasureAction is what Erase does with a step's rows
+type ErasureAction string
+
+const (
+	// EraseDelete deletes the rows
+	EraseDelete ErasureAction = "delete"
+	// EraseAnonymize rewrites the step's Columns and keeps the rows
+	EraseAnonymize ErasureAction = "anonymize"
+)
+
+// ErasureStep selects the rows of one table that belong to the subject.
+// A step without Parent matches Match against the subject key; otherwise
+// it matches Match against the Key values of the parent step's rows.
+type ErasureStep struct {
+	Table   string
+	Match   string              // Column identifying the subject or parent
+	Parent  string              // Table of an earlier step
+	Key     string              // Column children match against and rows are updated by; default "id"
+	Action  ErasureAction       // Defaults to EraseDelete
+	Columns map[string]MaskFunc // Column policies for EraseAnonymize
+}
+
+// ErasurePlan declares the graph of tables holding a subject's data
+type ErasurePlan struct {
+	Steps []ErasureStep
+}
+
+// ErasureStepReport records what Erase did to one table
+type ErasureStepReport struct {
+	Table  string        `json:"table"`
+	Action ErasureAction `json:"action"`
+	Rows   int64         `json:"rows"`
+}
+
+// ErasureReport records an erasure for audit
+type ErasureReport struct {
+	Subject  string              `json:"subject"`
+	Started  time.Time           `json:"started"`
+	Finished time.Time           `json:"finished"`
+	Steps    []ErasureStepReport `json:"steps"`
+}
+
+// validate checks identifiers and the step graph
+func (plan ErasurePlan) validate() error {
+	seen := map[string]bool{}
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		if step.Key == "" {
+			step.Key = "id"
+		}
+		if step.Action == "" {
+			step.Action = EraseDelete
+		}
+		for _, name := range []string{step.Table, step.Match, step.Key} {
+			if !isValidFieldName(name) {
+				return gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("invalid identifier %q in erasure step %d", name, i))
+			}
+		}
+		if step.Parent != "" && !seen[step.Parent] {
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "erasure step "+step.Table+" refers to unknown or later parent "+step.Parent)
+		}
+		if seen[step.Table] {
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "duplicate erasure step for "+step.Table)
+		}
+		switch step.Action {
+		case EraseDelete:
+		case EraseAnonymize:
+			if len(step.Columns) == 0 {
+				return gpa.NewError(gpa.ErrorTypeInvalidArgument, "anonymize step "+step.Table+" has no columns")
+			}
+			for column := range step.Columns {
+				if !isValidFieldName(column) {
+					return gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("invalid column %q in erasure step %s", column, step.Table))
+				}
+			}
+		default:
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "unknown erasure action "+string(step.Action))
+		}
+		seen[step.Table] = true
+	}
+	return nil
+}
+
+// Erase removes a data subject's rows following plan, in one transaction.
+// All rows are located first, then steps are applied in reverse order so
+// dependent rows go before the rows they reference. Anonymized rows are
+// rewritten one at a time by their Key column, which must be unique.
+func (p *Provider) Erase(ctx context.Context, subjectKey interface{}, plan ErasurePlan) (ErasureReport, error) {
+	report := ErasureReport{Subject: fmt.Sprint(subjectKey), Started: time.Now()}
+	plan.Steps = append([]ErasureStep(nil), plan.Steps...)
+	if err := plan.validate(); err != nil {
+		return report, err
+	}
+
+	err := sessionDB(ctx, p.db, p).Transaction(func(tx *gorm.DB) error {
+		// Locate each step's rows by the keys of its parent
+		matches := make([][]interface{}, len(plan.Steps))
+		keys := map[string][]interface{}{}
+		for i, step := range plan.Steps {
+			matches[i] = []interface{}{subjectKey}
+			if step.Parent != "" {
+				matches[i] = keys[step.Parent]
+			}
+			for _, chunk := range chunkValues(matches[i], erasureChunkSize) {
+				var found []interface{}
+				err := tx.Table(step.Table).Where(clause.IN{Column: clause.Column{Name: step.Match}, Values: chunk}).
+					Pluck(step.Key, &found).Error
+				if err != nil {
+					return fmt.Errorf("%s: %w", step.Table, err)
+				}
+				keys[step.Table] = append(keys[step.Table], found...)
+			}
+		}
+
+		report.Steps = make([]ErasureStepReport, len(plan.Steps))
+		for i := len(plan.Steps) - 1; i >= 0; i-- {
+			step := plan.Steps[i]
+			stepReport := ErasureStepReport{Table: step.Table, Action: step.Action}
+			var err error
+			if step.Action == EraseDelete {
+				stepReport.Rows, err = eraseRows(tx, step, matches[i])
+			} else {
+				stepReport.Rows, err = anonymizeRows(tx, step, keys[step.Table])
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", step.Table, err)
+			}
+			report.Steps[i] = stepReport
+		}
+		return nil
+	})
+	report.Finished = time.Now()
+	if err != nil {
+		return report, convertGormError(err)
+	}
+	return report, nil
+}
+
+// eraseRows deletes the rows of step matching values
+func eraseRows(tx *gorm.DB, step ErasureStep, values []interface{}) (int64, error) {
+	var deleted int64
+	for _, chunk := range chunkValues(values, erasureChunkSize) {
+		result := tx.Exec("DELETE FROM ? WHERE ?", clause.Table{Name: step.Table},
+			clause.IN{Column: clause.Column{Name: step.Match}, Values: chunk})
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+	return deleted, nil
+}
+
+// anonymizeRows rewrites the policy columns of the rows with the given keys
+func anonymizeRows(tx *gorm.DB, step ErasureStep, keys []interface{}) (int64, error) {
+	var updated int64
+	for _, chunk := range chunkValues(keys, erasureChunkSize) {
+		var rows []map[string]interface{}
+		err := tx.Table(step.Table).Select(maskedColumns(step.Key, step.Columns)).
+			Where(clause.IN{Column: clause.Column{Name: step.Key}, Values: chunk}).Find(&rows).Error
+		if err != nil {
+			return updated, err
+		}
+		n, err := maskRows(tx, step.Table, step.Key, step.Columns, rows)
+		updated += n
+		if err != nil {
+			return updated, err
+		}
+	}
+	return updated, nil
+}
+
+// maskedColumns lists key followed by the masked columns
+func maskedColumns(key string, masks map[string]MaskFunc) []string {
+	columns := []string{key}
+	for column := range masks {
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// maskRows writes the masked values of each row back by its key
+func maskRows(tx *gorm.DB, table, key string, masks map[string]MaskFunc, rows []map[string]interface{}) (int64, error) {
+	var updated int64
+	for _, row := range rows {
+		values := make(map[string]interface{}, len(masks))
+		for column, mask := range masks {
+			values[column] = mask(row[column])
+		}
+		result := tx.Table(table).Where(clause.Eq{Column: clause.Column{Name: key}, Value: row[key]}).Updates(values)
+		if result.Error != nil {
+			return updated, result.Error
+		}
+		updated += result.RowsAffected
+	}
+	return updated, nil
+}
+
+// chunkValues splits values into slices of at most size
+func chunkValues(values []interface{}, size int) [][]interface{} {
+	var chunks [][]interface{}
+	for len(values) > size {
+		chunks = append(chunks, values[:size])
+		values = values[size:]
+	}
+	if len(values) > 0 {
+		chunks = append(chunks, values)
+	}
+	return chunks
+}
@@ -0,0 +1,86 @@
+package gpagorm
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestErase(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	sqlDB, _ := provider.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	for _, stmt := range []string{
+		"CREATE TABLE erase_orders (id INTEGER PRIMARY KEY, user_id INTEGER, address TEXT)",
+		"CREATE TABLE erase_items (id INTEGER PRIMARY KEY, order_id INTEGER, sku TEXT)",
+		"INSERT INTO test_users (id, name, email, age) VALUES (1, 'Alice', 'alice@example.com', 30), (2, 'Bob', 'bob@example.com', 40)",
+		"INSERT INTO erase_orders (id, user_id, address) VALUES (10, 1, '1 Main St'), (11, 1, '2 Side St'), (12, 2, '3 Other St')",
+		"INSERT INTO erase_items (id, order_id, sku) VALUES (100, 10, 'a'), (101, 11, 'b'), (102, 12, 'c')",
+	} {
+		if err := provider.db.Exec(stmt).Error; err != nil {
+			t.Fatalf("Failed to set up: %v", err)
+		}
+	}
+
+	plan := ErasurePlan{Steps: []ErasureStep{
+		{Table: "test_users", Match: "id", Action: EraseAnonymize, Columns: map[string]MaskFunc{
+			"name":  Replace("[erased]"),
+			"email": HashValue("pepper"),
+		}},
+		{Table: "erase_orders", Match: "user_id", Parent: "test_users", Action: EraseAnonymize, Columns: map[string]MaskFunc{
+			"address": Nullify(),
+		}},
+		{Table: "erase_items", Match: "order_id", Parent: "erase_orders"},
+	}}
+	report, err := provider.Erase(ctx, 1, plan)
+	if err != nil {
+		t.Fatalf("Failed to erase: %v", err)
+	}
+	if report.Subject != "1" || len(report.Steps) != 3 || report.Steps[0].Rows != 1 || report.Steps[1].Rows != 2 || report.Steps[2].Rows != 2 {
+		t.Errorf("Unexpected erasure report: %+v", report)
+	}
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	alice, _ := repo.FindByID(ctx, 1)
+	if alice.Name != "[erased]" || len(alice.Email) != 64 || alice.Age != 30 {
+		t.Errorf("Expected alice to be anonymized, got %+v", alice)
+	}
+	bob, _ := repo.FindByID(ctx, 2)
+	if bob.Name != "Bob" || bob.Email != "bob@example.com" {
+		t.Errorf("Expected bob to be untouched, got %+v", bob)
+	}
+
+	var addresses []sql.NullString
+	provider.db.Table("erase_orders").Order("id").Pluck("address", &addresses)
+	if len(addresses) != 3 || addresses[0].Valid || addresses[1].Valid || !addresses[2].Valid {
+		t.Errorf("Expected only alice's addresses to be cleared, got %v", addresses)
+	}
+	var items int64
+	provider.db.Table("erase_items").Count(&items)
+	if items != 1 {
+		t.Errorf("Expected only bob's item to remain, got %d", items)
+	}
+}
+
+func TestErasePlanValidation(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	plans := []ErasurePlan{
+		{Steps: []ErasureStep{{Table: "users; DROP TABLE x", Match: "id"}}},
+		{Steps: []ErasureStep{{Table: "orders", Match: "user_id", Parent: "users"}}},
+		{Steps: []ErasureStep{{Table: "users", Match: "id", Action: EraseAnonymize}}},
+		{Steps: []ErasureStep{{Table: "users", Match: "id", Action: "shred"}}},
+	}
+	for i, plan := range plans {
+		if _, err := provider.Erase(ctx, 1, plan); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+			t.Errorf("Expected plan %d to be rejected, got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package gpagorm provides operation context on database errors
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// maxErrorSQLLength bounds the statement kept on an OperationError
+const maxErrorSQLLength = 256
+
+type operationCtxKey struct{}
+
+// OperationError describes the statement behind a database error. It is
+// the cause of the gpa.GPAError returned by repository operations, so logs
+// show which query failed without verbose SQL logging. The SQL is
+// normalized: literals and bind values are replaced with ?, so it carries
+// no row data.
+type OperationError struct {
+	Entity      string    // Model name, e.g. "User"
+	Table       string    // Table name, e.g. "users"
+	Operation   Operation // Repository operation, empty outside one
+	SQL         string    // Normalized statement, truncated
+	Fingerprint string    // QueryFingerprint of the full normalized statement
+	Err         error
+}
+
+// Error implements the error interface
+func (e *OperationError) Error() string {
+	target := e.Table
+	if e.Entity != "" {
+		target = e.Entity + " on " + e.Table
+	}
+	if e.Operation != "" {
+		target = string(e.Operation) + " " + target
+	}
+	return fmt.Sprintf("%s [%s]: %v", target, e.SQL, e.Err)
+}
+
+// Unwrap returns the database error
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// OperationContext returns the statement context attached to err, if it
+// came from a database statement
+//
+//	if op, ok := gpagorm.OperationContext(err); ok {
+//	    log.Printf("%s %s failed (%s): %s", op.Operation, op.Table, op.Fingerprint, op.SQL)
+//	}
+func OperationContext(err error) (*OperationError, bool) {
+	var opErr *OperationError
+	if errors.As(err, &opErr) {
+		return opErr, true
+	}
+	return nil, false
+}
+
+// withOperation marks ctx as running the repository operation op
+func withOperation(ctx context.Context, op Operation) context.Context {
+	return context.WithValue(ctx, operationCtxKey{}, op)
+}
+
+// operationFromContext returns the repository operation running with ctx
+func operationFromContext(ctx context.Context) Operation {
+	if ctx == nil {
+		return ""
+	}
+	op, _ := ctx.Value(operationCtxKey{}).(Operation)
+	return op
+}
+
+// registerErrorContext wraps statement errors of db in an OperationError
+func registerErrorContext(db *gorm.DB) error {
+	wrap := func(db *gorm.DB) {
+		if db.Error == nil || db.Statement == nil {
+			return
+		}
+		if _, ok := OperationContext(db.Error); ok {
+			return
+		}
+		// Errors raised while building the statement, such as invalid
+		// query options, are returned as they are
+		sql := db.Statement.SQL.String()
+		if sql == "" {
+			return
+		}
+		normalized := NormalizeQuery(sql)
+		opErr := &OperationError{
+			Table:       db.Statement.Table,
+			Operation:   operationFromContext(db.Statement.Context),
+			SQL:         truncateSQL(normalized),
+			Fingerprint: QueryFingerprint(normalized),
+			Err:         db.Error,
+		}
+		if db.Statement.Schema != nil {
+			opErr.Entity = db.Statement.Schema.Name
+		}
+		db.Error = opErr
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().After("*").Register("gpagorm:error_context", wrap),
+		callbacks.Query().After("*").Register("gpagorm:error_context", wrap),
+		callbacks.Update().After("*").Register("gpagorm:error_context", wrap),
+		callbacks.Delete().After("*").Register("gpagorm:error_context", wrap),
+		callbacks.Row().After("*").Register("gpagorm:error_context", wrap),
+		callbacks.Raw().After("*").Register("gpagorm:error_context", wrap),
+	)
+}
+
+// truncateSQL shortens sql to maxErrorSQLLength runes
+func truncateSQL(sql string) string {
+	runes := []rune(sql)
+	if len(runes) <= maxErrorSQLLength {
+		return sql
+	}
+	return string(runes[:maxErrorSQLLength]) + "..."
+}
@@ -0,0 +1,65 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestOperationContext(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	_, err := repo.FindByID(ctx, 42)
+	if !gpa.IsNotFound(err) {
+		t.Fatalf("Expected not found, got %v", err)
+	}
+	op, ok := OperationContext(err)
+	if !ok {
+		t.Fatalf("Expected operation context on %v", err)
+	}
+	if op.Operation != OperationFindByID || op.Entity != "TestUser" || op.Table != "test_users" {
+		t.Errorf("Unexpected operation context: %+v", op)
+	}
+	if !strings.Contains(op.SQL, "FROM `test_users`") || strings.Contains(op.SQL, "42") {
+		t.Errorf("Expected normalized SQL, got %q", op.SQL)
+	}
+	if op.Fingerprint != QueryFingerprint(NormalizeQuery(op.SQL)) {
+		t.Errorf("Unexpected fingerprint %q", op.Fingerprint)
+	}
+
+	if err := repo.Create(ctx, &TestUser{Name: "Alice", Email: "alice@example.com"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	err = repo.Create(ctx, &TestUser{Name: "Alice", Email: "alice@example.com"})
+	if err == nil {
+		t.Fatal("Expected duplicate email to fail")
+	}
+	op, ok = OperationContext(err)
+	if !ok || op.Operation != OperationCreate || !strings.HasPrefix(op.SQL, "INSERT INTO") {
+		t.Errorf("Unexpected operation context %+v for %v", op, err)
+	}
+	if strings.Contains(op.SQL, "alice@example.com") {
+		t.Errorf("Expected values to be stripped, got %q", op.SQL)
+	}
+
+	// Statements outside a repository operation carry no operation
+	err = convertGormError(provider.db.Exec("SELECT * FROM missing_table").Error)
+	if op, ok := OperationContext(err); !ok || op.Operation != "" || op.SQL != "SELECT * FROM missing_table" {
+		t.Errorf("Unexpected operation context %+v for %v", op, err)
+	}
+}
+
+func TestTruncateSQL(t *testing.T) {
+	long := strings.Repeat("x", maxErrorSQLLength+10)
+	if got := truncateSQL(long); len(got) != maxErrorSQLLength+3 || !strings.HasSuffix(got, "...") {
+		t.Errorf("Unexpected truncation %q", got)
+	}
+	if got := truncateSQL("SELECT 1"); got != "SELECT 1" {
+		t.Errorf("Expected short SQL unchanged, got %q", got)
+	}
+}
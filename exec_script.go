@@ -0,0 +1,248 @@
+// Package gpagorm provides execution of multi-statement SQL scripts
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// ExecScript executes a SQL script of several statements, such as a vendor
+// supplied setup file. The script is split the way the database's own
+// client would: quoted strings and comments are respected, as are dollar
+// quoted bodies on Postgres, DELIMITER commands on MySQL and GO batch
+// separators on SQL Server. Statements run in one transaction unless the
+// database cannot roll back DDL (MySQL), the script manages transactions
+// itself, or a statement cannot run inside one (e.g. VACUUM or CREATE INDEX
+// CONCURRENTLY); a failure then leaves the earlier statements applied.
+func (p *Provider) ExecScript(ctx context.Context, script string) error {
+	r := NewRepository[struct{}](p.db, p)
+	return r.intercept(ctx, OperationInfo{Operation: OperationExecScript, SQL: script}, func(ctx context.Context) error {
+		db := r.session(ctx)
+		dialect := dialectName(db)
+		statements := splitScript(script, dialect)
+		if !transactionalScript(statements, dialect) {
+			return execStatements(db, statements)
+		}
+		return db.Transaction(func(tx *gorm.DB) error {
+			return execStatements(tx, statements)
+		})
+	})
+}
+
+// execStatements runs statements in order, stopping at the first failure
+func execStatements(db *gorm.DB, statements []string) error {
+	for i, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			first, _, _ := strings.Cut(statement, "\n")
+			return gpa.NewErrorWithCause(gpa.ErrorTypeDatabase,
+				fmt.Sprintf("script statement %d failed: %s", i+1, first), convertGormError(err))
+		}
+	}
+	return nil
+}
+
+// nonTransactional matches statements that cannot run inside a transaction
+// block, or that manage transactions themselves
+var nonTransactional = regexp.MustCompile(`(?i)^\s*(BEGIN\s*(TRAN|TRANSACTION|WORK)?\s*;?\s*$|START\s+TRANSACTION|COMMIT|ROLLBACK|VACUUM|CREATE\s+DATABASE|DROP\s+DATABASE|ALTER\s+DATABASE|ALTER\s+SYSTEM|BACKUP|RESTORE|CREATE\s+(UNIQUE\s+)?INDEX\s+CONCURRENTLY|DROP\s+INDEX\s+CONCURRENTLY|REINDEX\s+.*CONCURRENTLY)`)
+
+// transactionalScript reports whether statements can run in one transaction
+func transactionalScript(statements []string, dialect string) bool {
+	if dialect == "mysql" || len(statements) < 2 {
+		return false
+	}
+	for _, statement := range statements {
+		if nonTransactional.MatchString(stripLeadingComments(statement)) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripLeadingComments removes the comments before a statement's first token
+func stripLeadingComments(statement string) string {
+	for {
+		statement = strings.TrimSpace(statement)
+		switch {
+		case strings.HasPrefix(statement, "--") || strings.HasPrefix(statement, "#"):
+			_, rest, _ := strings.Cut(statement, "\n")
+			statement = rest
+		case strings.HasPrefix(statement, "/*"):
+			_, rest, ok := strings.Cut(statement, "*/")
+			if !ok {
+				return ""
+			}
+			statement = rest
+		default:
+			return statement
+		}
+	}
+}
+
+// splitScript splits script into statements for dialect
+func splitScript(script, dialect string) []string {
+	if dialect == "sqlserver" {
+		return splitBatches(script)
+	}
+
+	var statements []string
+	var current strings.Builder
+	hasCode := false
+	flush := func() {
+		if hasCode {
+			statements = append(statements, strings.TrimSpace(current.String()))
+		}
+		current.Reset()
+		hasCode = false
+	}
+
+	delimiter := ";"
+	trigger := false // SQLite trigger bodies contain semicolons up to END
+	for i := 0; i < len(script); {
+		rest := script[i:]
+		c := script[i]
+		switch {
+		case dialect == "mysql" && !hasCode && hasWordPrefix(rest, "DELIMITER"):
+			line, _, _ := strings.Cut(rest[len("DELIMITER"):], "\n")
+			if d := strings.TrimSpace(line); d != "" {
+				delimiter = d
+			}
+			i += len("DELIMITER") + len(line)
+			current.Reset()
+		case c == '\'' || c == '"' || (c == '`' && dialect == "mysql"):
+			end := quotedEnd(script, i, dialect == "mysql" && c != '`')
+			current.WriteString(script[i:end])
+			hasCode = true
+			i = end
+		case strings.HasPrefix(rest, "--") || (c == '#' && dialect == "mysql"):
+			line, _, _ := strings.Cut(rest, "\n")
+			current.WriteString(line)
+			i += len(line)
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += 4
+			}
+			current.WriteString(rest[:end])
+			i += end
+		case c == '$' && dialect == "postgres" && (i == 0 || !isIdentByte(script[i-1])):
+			tag := dollarTag(rest)
+			if tag == "" {
+				current.WriteByte(c)
+				i++
+				continue
+			}
+			end := strings.Index(rest[len(tag):], tag)
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += 2 * len(tag)
+			}
+			current.WriteString(rest[:end])
+			hasCode = true
+			i += end
+		case strings.HasPrefix(rest, delimiter):
+			if trigger && !endsWithWord(current.String(), "END") {
+				current.WriteString(delimiter)
+			} else {
+				flush()
+				trigger = false
+			}
+			i += len(delimiter)
+		default:
+			if !hasCode && dialect == "sqlite" && sqliteTrigger.MatchString(rest) {
+				trigger = true
+			}
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				hasCode = true
+			}
+			current.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return statements
+}
+
+// sqliteTrigger matches the start of a CREATE TRIGGER statement
+var sqliteTrigger = regexp.MustCompile(`(?i)^CREATE\s+(TEMP\s+|TEMPORARY\s+)?TRIGGER\b`)
+
+// batchSeparator matches a SQL Server GO line
+var batchSeparator = regexp.MustCompile(`(?im)^[ \t]*GO[ \t]*;?[ \t]*$`)
+
+// splitBatches splits a SQL Server script on its GO lines. Each batch is
+// sent whole, as sqlcmd does, so procedure bodies stay intact.
+func splitBatches(script string) []string {
+	var batches []string
+	for _, batch := range batchSeparator.Split(script, -1) {
+		if stripLeadingComments(batch) != "" {
+			batches = append(batches, strings.TrimSpace(batch))
+		}
+	}
+	return batches
+}
+
+// quotedEnd returns the index after the quoted string starting at i.
+// Doubled quotes are escapes, and so are backslashes when backslash is set.
+func quotedEnd(script string, i int, backslash bool) int {
+	quote := script[i]
+	for j := i + 1; j < len(script); j++ {
+		switch script[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(script) && script[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(script)
+}
+
+// dollarTag returns the Postgres dollar quote tag s starts with, e.g. "$$"
+// or "$body$", or "" when it starts with none
+func dollarTag(s string) string {
+	for j := 1; j < len(s); j++ {
+		switch {
+		case s[j] == '$':
+			return s[:j+1]
+		case !isIdentByte(s[j]) || (j == 1 && s[j] >= '0' && s[j] <= '9'):
+			return ""
+		}
+	}
+	return ""
+}
+
+// isIdentByte reports whether b can be part of an unquoted identifier
+func isIdentByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// hasWordPrefix reports whether s starts with word, case-insensitively,
+// followed by whitespace or the end of s
+func hasWordPrefix(s, word string) bool {
+	if len(s) < len(word) || !strings.EqualFold(s[:len(word)], word) {
+		return false
+	}
+	return len(s) == len(word) || s[len(word)] == ' ' || s[len(word)] == '\t'
+}
+
+// endsWithWord reports whether s ends with word as a whole word,
+// case-insensitively and ignoring trailing whitespace
+func endsWithWord(s, word string) bool {
+	s = strings.TrimRight(s, " \t\r\n")
+	if len(s) < len(word) || !strings.EqualFold(s[len(s)-len(word):], word) {
+		return false
+	}
+	return len(s) == len(word) || !isIdentByte(s[len(s)-len(word)-1])
+}
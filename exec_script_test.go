@@ -0,0 +1,123 @@
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSplitScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		script  string
+		want    []string
+	}{
+		{
+			name:    "quotes and comments",
+			dialect: "sqlite",
+			script:  "-- setup\nINSERT INTO t VALUES ('a;b', \"c;d\"); /* x; */ SELECT 1;\n-- trailing only\n",
+			want:    []string{"-- setup\nINSERT INTO t VALUES ('a;b', \"c;d\")", "/* x; */ SELECT 1"},
+		},
+		{
+			name:    "doubled quotes",
+			dialect: "postgres",
+			script:  "SELECT 'it''s; fine'; SELECT 2",
+			want:    []string{"SELECT 'it''s; fine'", "SELECT 2"},
+		},
+		{
+			name:    "dollar quoted body",
+			dialect: "postgres",
+			script: "CREATE FUNCTION f() RETURNS int AS $fn$ BEGIN RETURN 1; END; $fn$ LANGUAGE plpgsql;\n" +
+				"DO $$ BEGIN PERFORM 1; END $$;\nSELECT $1",
+			want: []string{
+				"CREATE FUNCTION f() RETURNS int AS $fn$ BEGIN RETURN 1; END; $fn$ LANGUAGE plpgsql",
+				"DO $$ BEGIN PERFORM 1; END $$",
+				"SELECT $1",
+			},
+		},
+		{
+			name:    "mysql delimiter",
+			dialect: "mysql",
+			script: "DROP PROCEDURE IF EXISTS p;\nDELIMITER //\nCREATE PROCEDURE p() BEGIN SELECT 1; SELECT 'x\\';'; END //\n" +
+				"DELIMITER ;\n# comment;\nSELECT `a;b` FROM t;",
+			want: []string{
+				"DROP PROCEDURE IF EXISTS p",
+				"CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 'x\\';'; END",
+				"# comment;\nSELECT `a;b` FROM t",
+			},
+		},
+		{
+			name:    "sqlite trigger",
+			dialect: "sqlite",
+			script:  "CREATE TRIGGER tr AFTER INSERT ON t BEGIN UPDATE c SET n = n + 1; DELETE FROM q; END;\nSELECT 1;",
+			want: []string{
+				"CREATE TRIGGER tr AFTER INSERT ON t BEGIN UPDATE c SET n = n + 1; DELETE FROM q; END",
+				"SELECT 1",
+			},
+		},
+		{
+			name:    "sqlserver batches",
+			dialect: "sqlserver",
+			script:  "CREATE TABLE t (id int);\nGO\nCREATE PROCEDURE p AS BEGIN SELECT 1; SELECT 2; END\ngo\n",
+			want:    []string{"CREATE TABLE t (id int);", "CREATE PROCEDURE p AS BEGIN SELECT 1; SELECT 2; END"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitScript(tt.script, tt.dialect); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitScript() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransactionalScript(t *testing.T) {
+	if transactionalScript([]string{"CREATE TABLE a (id int)", "CREATE TABLE b (id int)"}, "mysql") {
+		t.Error("Expected MySQL scripts not to run in a transaction")
+	}
+	if !transactionalScript([]string{"CREATE TABLE a (id int)", "CREATE INDEX i ON a (id)"}, "postgres") {
+		t.Error("Expected a plain Postgres script to run in a transaction")
+	}
+	if transactionalScript([]string{"CREATE TABLE a (id int)", "-- build\nCREATE INDEX CONCURRENTLY i ON a (id)"}, "postgres") {
+		t.Error("Expected CREATE INDEX CONCURRENTLY to disable the transaction")
+	}
+	if transactionalScript([]string{"BEGIN", "CREATE TABLE a (id int)", "COMMIT"}, "sqlite") {
+		t.Error("Expected a script managing its own transaction not to be wrapped")
+	}
+}
+
+func TestExecScript(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	script := `
+-- vendor setup
+CREATE TABLE counters (name TEXT PRIMARY KEY, n INTEGER NOT NULL);
+INSERT INTO counters VALUES ('users', 0);
+CREATE TRIGGER count_users AFTER INSERT ON test_users BEGIN
+    UPDATE counters SET n = n + 1 WHERE name = 'users';
+END;
+INSERT INTO test_users (name, email, age) VALUES ('Semi;colon', 'a@example.com', 1);
+`
+	if err := provider.ExecScript(ctx, script); err != nil {
+		t.Fatalf("ExecScript failed: %v", err)
+	}
+	var n int
+	provider.db.Raw("SELECT n FROM counters WHERE name = 'users'").Scan(&n)
+	if n != 1 {
+		t.Errorf("Expected the trigger to count 1 user, got %d", n)
+	}
+
+	// A failing statement rolls the script back
+	err := provider.ExecScript(ctx, "INSERT INTO counters VALUES ('orders', 0);\nINSERT INTO missing VALUES (1);")
+	if err == nil {
+		t.Fatal("Expected the script to fail")
+	}
+	var count int64
+	provider.db.Raw("SELECT COUNT(*) FROM counters").Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected the failed script to be rolled back, got %d counters", count)
+	}
+}
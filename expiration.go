@@ -0,0 +1,233 @@
+// Package gpagorm provides entity expiration with a background sweeper
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// defaultSweepChunkSize is how many expired rows a sweep removes per statement
+const defaultSweepChunkSize = 1000
+
+// SweepStat reports the sweeper's work on one table
+type SweepStat struct {
+	Table        string        `json:"table"`
+	Runs         int64         `json:"runs"`
+	Deleted      int64         `json:"deleted"`
+	Archived     int64         `json:"archived"`
+	LastRun      time.Time     `json:"last_run"`
+	LastDuration time.Duration `json:"last_duration"`
+	LastError    string        `json:"last_error,omitempty"`
+}
+
+// expirationSpec is a table registered with RegisterExpiration
+type expirationSpec struct {
+	table   string
+	pk      string
+	column  string
+	after   time.Duration // Rows expire this long after column; zero means column is the expiry
+	archive string        // Table expired rows are copied to before deletion
+
+	mu   sync.Mutex
+	stat SweepStat
+}
+
+// parseTTLTag parses a `ttl:"after=720h,archive=sessions_archive"` struct tag.
+// An empty tag marks the field as the expiry time itself.
+func parseTTLTag(spec *expirationSpec, tag string) error {
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		switch strings.TrimSpace(key) {
+		case "after":
+			d, err := time.ParseDuration(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("invalid ttl after: %w", err)
+			}
+			spec.after = d
+		case "archive":
+			spec.archive = strings.TrimSpace(value)
+		default:
+			return fmt.Errorf("unknown ttl setting %q", key)
+		}
+	}
+	return nil
+}
+
+// RegisterExpiration registers models for the sweeper. Each model marks
+// its TTL column with a `ttl` struct tag on a time field: an empty tag
+// means the field holds the expiry time, `ttl:"after=720h"` expires rows
+// that long after the field's time, and `archive=table` copies expired
+// rows into an existing table with the same columns before deleting them.
+func (p *Provider) RegisterExpiration(models ...interface{}) error {
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: p.db}
+		if err := stmt.Parse(model); err != nil {
+			return convertGormError(err)
+		}
+		spec := &expirationSpec{table: stmt.Schema.Table}
+		if pk := stmt.Schema.PrioritizedPrimaryField; pk != nil {
+			spec.pk = pk.DBName
+		} else {
+			return gpa.NewError(gpa.ErrorTypeUnsupported, "expiration requires a single primary key on "+spec.table)
+		}
+		for _, field := range stmt.Schema.Fields {
+			tag, ok := field.Tag.Lookup("ttl")
+			if !ok || field.DBName == "" {
+				continue
+			}
+			if field.DataType != schema.Time {
+				return gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("ttl field %s.%s is not a time", spec.table, field.Name))
+			}
+			if err := parseTTLTag(spec, tag); err != nil {
+				return gpa.NewErrorWithCause(gpa.ErrorTypeInvalidArgument, "invalid ttl tag on "+spec.table, err)
+			}
+			spec.column = field.DBName
+			break
+		}
+		if spec.column == "" {
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "no ttl field on "+spec.table)
+		}
+		spec.stat.Table = spec.table
+
+		p.mu.Lock()
+		p.expirations = append(p.expirations, spec)
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+// SweepExpired deletes, or archives and deletes, the expired rows of every
+// registered table once, in chunks. It returns the rows removed per table.
+func (p *Provider) SweepExpired(ctx context.Context) (map[string]int64, error) {
+	p.mu.RLock()
+	specs := append([]*expirationSpec(nil), p.expirations...)
+	p.mu.RUnlock()
+
+	removed := make(map[string]int64, len(specs))
+	var errs []error
+	for _, spec := range specs {
+		n, err := p.sweep(ctx, spec)
+		removed[spec.table] = n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", spec.table, err))
+		}
+	}
+	if len(errs) > 0 {
+		return removed, gpa.NewErrorWithCause(gpa.ErrorTypeDatabase, "expiration sweep failed", errors.Join(errs...))
+	}
+	return removed, nil
+}
+
+// StartSweeper sweeps expired rows every interval until ctx is done.
+// Failures are logged and retried on the next tick.
+func (p *Provider) StartSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := p.SweepExpired(ctx); err != nil && ctx.Err() == nil {
+					slog.Default().LogAttrs(context.Background(), slog.LevelWarn, "gpagorm: expiration sweep failed",
+						slog.String("error", err.Error()))
+				}
+			}
+		}
+	}()
+}
+
+// SweeperStats returns the sweeper's counters per registered table
+func (p *Provider) SweeperStats() []SweepStat {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	stats := make([]SweepStat, 0, len(p.expirations))
+	for _, spec := range p.expirations {
+		spec.mu.Lock()
+		stats = append(stats, spec.stat)
+		spec.mu.Unlock()
+	}
+	return stats
+}
+
+// sweep removes the expired rows of one table and records its stats
+func (p *Provider) sweep(ctx context.Context, spec *expirationSpec) (int64, error) {
+	start := time.Now()
+	db := p.db.WithContext(ctx)
+	cutoff := db.NowFunc().Add(-spec.after)
+	chunk := p.sweepChunkSize()
+
+	var removed, archived int64
+	var err error
+	for {
+		var ids []interface{}
+		err = db.Table(spec.table).
+			Where(clause.Lte{Column: clause.Column{Name: spec.column}, Value: cutoff}).
+			Order(clause.OrderByColumn{Column: clause.Column{Name: spec.pk}}).
+			Limit(chunk).
+			Pluck(spec.pk, &ids).Error
+		if err != nil || len(ids) == 0 {
+			break
+		}
+
+		var deletedChunk, archivedChunk int64
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if spec.archive != "" {
+				result := tx.Exec("INSERT INTO ? SELECT * FROM ? WHERE ? IN ?",
+					clause.Table{Name: spec.archive}, clause.Table{Name: spec.table}, clause.Column{Name: spec.pk}, ids)
+				if result.Error != nil {
+					return result.Error
+				}
+				archivedChunk = result.RowsAffected
+			}
+			result := tx.Exec("DELETE FROM ? WHERE ? IN ?", clause.Table{Name: spec.table}, clause.Column{Name: spec.pk}, ids)
+			deletedChunk = result.RowsAffected
+			return result.Error
+		})
+		if err != nil {
+			break
+		}
+		removed += deletedChunk
+		archived += archivedChunk
+		if len(ids) < chunk {
+			break
+		}
+	}
+
+	spec.mu.Lock()
+	spec.stat.Runs++
+	spec.stat.Deleted += removed
+	spec.stat.Archived += archived
+	spec.stat.LastRun = start
+	spec.stat.LastDuration = time.Since(start)
+	spec.stat.LastError = ""
+	if err != nil {
+		spec.stat.LastError = err.Error()
+	}
+	spec.mu.Unlock()
+
+	return removed, convertGormError(err)
+}
+
+// sweepChunkSize reads the "sweep_chunk_size" option
+func (p *Provider) sweepChunkSize() int {
+	if size, ok := gormOptions(p.currentConfig())["sweep_chunk_size"].(int); ok && size > 0 {
+		return size
+	}
+	return defaultSweepChunkSize
+}
@@ -0,0 +1,122 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+type TestExpiringSession struct {
+	ID        uint `gorm:"primaryKey"`
+	Token     string
+	ExpiresAt time.Time `ttl:""`
+}
+
+type TestExpiringEvent struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	CreatedAt time.Time `ttl:"after=1h,archive=test_expiring_events_archive"`
+}
+
+func TestSweepExpired(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	sqlDB, _ := provider.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	provider.config.Options = map[string]interface{}{"gorm": map[string]interface{}{"sweep_chunk_size": 2}}
+	ctx := context.Background()
+
+	if err := provider.db.AutoMigrate(&TestExpiringSession{}, &TestExpiringEvent{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := provider.db.Exec("CREATE TABLE test_expiring_events_archive (id INTEGER, name TEXT, created_at DATETIME)").Error; err != nil {
+		t.Fatalf("Failed to create archive table: %v", err)
+	}
+	if err := provider.RegisterExpiration(&TestExpiringSession{}, &TestExpiringEvent{}); err != nil {
+		t.Fatalf("Failed to register expiration: %v", err)
+	}
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		provider.db.Create(&TestExpiringSession{Token: fmt.Sprintf("old%d", i), ExpiresAt: now.Add(-time.Minute)})
+	}
+	provider.db.Create(&TestExpiringSession{Token: "live", ExpiresAt: now.Add(time.Hour)})
+	provider.db.Create(&TestExpiringEvent{Name: "stale", CreatedAt: now.Add(-2 * time.Hour)})
+	provider.db.Create(&TestExpiringEvent{Name: "fresh", CreatedAt: now})
+
+	removed, err := provider.SweepExpired(ctx)
+	if err != nil {
+		t.Fatalf("Failed to sweep: %v", err)
+	}
+	if removed["test_expiring_sessions"] != 5 || removed["test_expiring_events"] != 1 {
+		t.Errorf("Unexpected sweep result: %v", removed)
+	}
+
+	var sessions []TestExpiringSession
+	provider.db.Find(&sessions)
+	if len(sessions) != 1 || sessions[0].Token != "live" {
+		t.Errorf("Expected only the live session to remain, got %v", sessions)
+	}
+	var archived []string
+	provider.db.Table("test_expiring_events_archive").Pluck("name", &archived)
+	if len(archived) != 1 || archived[0] != "stale" {
+		t.Errorf("Expected the stale event to be archived, got %v", archived)
+	}
+
+	stats := provider.SweeperStats()
+	if len(stats) != 2 || stats[0].Deleted != 5 || stats[0].Runs != 1 || stats[1].Archived != 1 {
+		t.Errorf("Unexpected sweeper stats: %+v", stats)
+	}
+}
+
+func TestStartSweeper(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	sqlDB, _ := provider.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := provider.db.AutoMigrate(&TestExpiringSession{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := provider.RegisterExpiration(&TestExpiringSession{}); err != nil {
+		t.Fatalf("Failed to register expiration: %v", err)
+	}
+	provider.db.Create(&TestExpiringSession{Token: "old", ExpiresAt: time.Now().Add(-time.Minute)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	provider.StartSweeper(ctx, 10*time.Millisecond)
+	waitFor(t, func() bool {
+		var count int64
+		provider.db.Model(&TestExpiringSession{}).Count(&count)
+		return count == 0
+	})
+}
+
+func TestRegisterExpirationErrors(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	if err := provider.RegisterExpiration(&TestUser{}); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected an error for a model without a ttl field, got %v", err)
+	}
+
+	type badTTL struct {
+		ID   uint
+		Name string `ttl:""`
+	}
+	if err := provider.RegisterExpiration(&badTTL{}); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected an error for a non-time ttl field, got %v", err)
+	}
+
+	type badTag struct {
+		ID        uint
+		ExpiresAt time.Time `ttl:"after=soon"`
+	}
+	if err := provider.RegisterExpiration(&badTag{}); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected an error for an invalid ttl tag, got %v", err)
+	}
+}
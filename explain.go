@@ -0,0 +1,111 @@
+// Package gpagorm provides query plan inspection
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// Explain returns the database's query plan for a query built from opts,
+// one line per plan node
+func (r *Repository[T]) Explain(ctx context.Context, opts ...gpa.QueryOption) ([]string, error) {
+	var entities []*T
+	stmt := r.buildQuery(ctx, opts...).Session(&gorm.Session{DryRun: true}).Find(&entities)
+	if stmt.Error != nil {
+		return nil, convertGormError(stmt.Error)
+	}
+
+	plan, err := explainStatement(r.readSession(ctx), stmt.Statement.SQL.String(), stmt.Statement.Vars)
+	if err != nil {
+		return nil, convertGormError(err)
+	}
+	return plan, nil
+}
+
+// explainStatement runs the dialect's EXPLAIN for sql on db
+func explainStatement(db *gorm.DB, sql string, vars []interface{}) ([]string, error) {
+	var rows []map[string]interface{}
+	dialect := dialectName(db)
+
+	switch dialect {
+	case "sqlite":
+		if err := db.Raw("EXPLAIN QUERY PLAN "+sql, vars...).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		plan := make([]string, 0, len(rows))
+		for _, row := range rows {
+			plan = append(plan, fmt.Sprint(row["detail"]))
+		}
+		return plan, nil
+	case "postgres":
+		if err := db.Raw("EXPLAIN "+sql, vars...).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		plan := make([]string, 0, len(rows))
+		for _, row := range rows {
+			plan = append(plan, fmt.Sprint(row["QUERY PLAN"]))
+		}
+		return plan, nil
+	case "mysql":
+		if err := db.Raw("EXPLAIN "+sql, vars...).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		plan := make([]string, 0, len(rows))
+		for _, row := range rows {
+			plan = append(plan, formatPlanRow(row))
+		}
+		return plan, nil
+	default:
+		return nil, gpa.NewError(gpa.ErrorTypeUnsupported, fmt.Sprintf("explain is not supported for %s", dialect))
+	}
+}
+
+// formatPlanRow renders a tabular plan row as sorted key=value pairs
+func formatPlanRow(row map[string]interface{}) string {
+	keys := make([]string, 0, len(row))
+	for key, value := range row {
+		if value != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := row[key]
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		parts = append(parts, fmt.Sprintf("%s=%v", key, value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// planScansTable reports whether plan reads table with a full sequential scan
+func planScansTable(dialect string, plan []string, table string) bool {
+	for _, line := range plan {
+		switch dialect {
+		case "sqlite":
+			// "SCAN users" or "SCAN TABLE users"; index scans mention USING
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[0] == "SCAN" && !strings.Contains(line, "USING") &&
+				(fields[1] == table || (fields[1] == "TABLE" && len(fields) > 2 && fields[2] == table)) {
+				return true
+			}
+		case "postgres":
+			if strings.Contains(line, "Seq Scan on "+table+" ") || strings.HasSuffix(line, "Seq Scan on "+table) {
+				return true
+			}
+		case "mysql":
+			if strings.Contains(" "+line+" ", " table="+table+" ") && strings.Contains(" "+line+" ", " type=ALL ") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+// Package gpagorm provides fault injection for resilience tests
+package gpagorm
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// FaultKind is the kind of fault a rule injects
+type FaultKind string
+
+const (
+	// FaultLatency delays the operation, then runs it
+	FaultLatency FaultKind = "latency"
+	// FaultTransient fails the operation with a connection error
+	FaultTransient FaultKind = "transient"
+	// FaultSerialization fails the operation with a serialization failure,
+	// as a conflicting serializable transaction would
+	FaultSerialization FaultKind = "serialization"
+)
+
+// FaultRule injects a fault into matching repository operations
+type FaultRule struct {
+	Kind        FaultKind
+	Operations  []Operation   // Operations to match; empty matches all
+	Entities    []string      // Entity type names to match; empty matches all
+	Probability float64       // Chance of injecting per matching operation, 0 to 1
+	Latency     time.Duration // Delay for FaultLatency
+	Err         error         // Error to return instead of the kind's default
+	Limit       int           // Maximum injections; 0 means no limit
+}
+
+// FaultInjection configures WithFaultInjection
+type FaultInjection struct {
+	Seed  int64 // Seed of the random source, so runs are reproducible
+	Rules []FaultRule
+}
+
+// FaultInjector injects the faults of its rules into repository operations.
+// It is meant for tests of retry and timeout handling, never production.
+type FaultInjector struct {
+	mu       sync.Mutex
+	rand     *rand.Rand
+	rules    []FaultRule
+	counts   []int
+	disabled bool
+}
+
+// WithFaultInjection installs a fault injector as an interceptor of every
+// repository operation of the provider and returns it:
+//
+//	faults := provider.WithFaultInjection(gpagorm.FaultInjection{Seed: 1, Rules: []gpagorm.FaultRule{
+//	    {Kind: gpagorm.FaultSerialization, Operations: []gpagorm.Operation{gpagorm.OperationUpdate}, Probability: 0.5},
+//	    {Kind: gpagorm.FaultLatency, Latency: 200 * time.Millisecond, Probability: 1, Limit: 3},
+//	}})
+//	defer faults.Disable()
+//
+// With a fixed seed and a fixed sequence of operations the same operations
+// fail on every run.
+func (p *Provider) WithFaultInjection(config FaultInjection) *FaultInjector {
+	f := &FaultInjector{
+		rand:   rand.New(rand.NewSource(config.Seed)),
+		rules:  config.Rules,
+		counts: make([]int, len(config.Rules)),
+	}
+	p.AddInterceptor(f.intercept)
+	return f
+}
+
+// Disable stops injecting faults
+func (f *FaultInjector) Disable() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.disabled = true
+}
+
+// Injected returns how many faults each rule has injected, in rule order
+func (f *FaultInjector) Injected() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int(nil), f.counts...)
+}
+
+// intercept is the injector's Interceptor
+func (f *FaultInjector) intercept(ctx context.Context, op OperationInfo, next func(ctx context.Context) error) error {
+	latency, err := f.pick(op)
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return gpa.NewErrorWithCause(gpa.ErrorTypeTimeout, "operation cancelled", ctx.Err())
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return next(ctx)
+}
+
+// pick draws the faults for op: the total latency of the latency rules
+// that fire and the error of the first error rule that fires
+func (f *FaultInjector) pick(op OperationInfo) (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.disabled {
+		return 0, nil
+	}
+
+	var latency time.Duration
+	for i, rule := range f.rules {
+		if !rule.matches(op) || (rule.Limit > 0 && f.counts[i] >= rule.Limit) {
+			continue
+		}
+		if f.rand.Float64() >= rule.Probability {
+			continue
+		}
+		f.counts[i]++
+		if rule.Kind == FaultLatency {
+			latency += rule.Latency
+			continue
+		}
+		return latency, rule.err(op)
+	}
+	return latency, nil
+}
+
+// matches reports whether the rule applies to op
+func (rule FaultRule) matches(op OperationInfo) bool {
+	return (len(rule.Operations) == 0 || containsValue(rule.Operations, op.Operation)) &&
+		(len(rule.Entities) == 0 || containsValue(rule.Entities, op.EntityType))
+}
+
+// err returns the error the rule injects into op
+func (rule FaultRule) err(op OperationInfo) error {
+	if rule.Err != nil {
+		return rule.Err
+	}
+	switch rule.Kind {
+	case FaultSerialization:
+		return gpa.NewError(gpa.ErrorTypeSerialization, "injected fault: could not serialize access during "+string(op.Operation))
+	default:
+		return gpa.NewError(gpa.ErrorTypeConnection, "injected fault: connection reset during "+string(op.Operation))
+	}
+}
+
+// containsValue reports whether values holds v
+func containsValue[V comparable](values []V, v V) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestFaultInjection(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	faults := provider.WithFaultInjection(FaultInjection{Seed: 7, Rules: []FaultRule{
+		{Kind: FaultSerialization, Operations: []Operation{OperationCreate}, Probability: 1, Limit: 2},
+		{Kind: FaultTransient, Entities: []string{"testAuditEntry"}, Probability: 1},
+		{Kind: FaultLatency, Operations: []Operation{OperationCount}, Latency: 20 * time.Millisecond, Probability: 1},
+	}})
+
+	// The first two creates fail, the third goes through
+	user := &TestUser{Name: "Ann", Email: "ann@example.com"}
+	for i := 0; i < 2; i++ {
+		if err := repo.Create(ctx, user); !gpa.IsErrorType(err, gpa.ErrorTypeSerialization) {
+			t.Errorf("Expected injected serialization failure, got %v", err)
+		}
+	}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Expected create to succeed after the limit, got %v", err)
+	}
+
+	start := time.Now()
+	if n, err := repo.Count(ctx); err != nil || n != 1 {
+		t.Errorf("Expected delayed count of 1, got %d %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected injected latency, took %v", elapsed)
+	}
+	timeout, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	if _, err := repo.Count(timeout); !gpa.IsErrorType(err, gpa.ErrorTypeTimeout) {
+		t.Errorf("Expected the deadline to cut the latency short, got %v", err)
+	}
+
+	audit := NewRepository[testAuditEntry](provider.db, provider)
+	if _, err := audit.FindAll(ctx); !gpa.IsErrorType(err, gpa.ErrorTypeConnection) {
+		t.Errorf("Expected injected connection error, got %v", err)
+	}
+	if got := faults.Injected(); got[0] != 2 || got[1] != 1 || got[2] != 2 {
+		t.Errorf("Unexpected injection counts %v", got)
+	}
+
+	faults.Disable()
+	if _, err := repo.FindAll(ctx); err != nil {
+		t.Errorf("Expected no faults once disabled, got %v", err)
+	}
+}
+
+func TestFaultInjectionDeterministic(t *testing.T) {
+	run := func() []bool {
+		provider, cleanup := setupTestProvider(t)
+		defer cleanup()
+		sentinel := errors.New("flaky")
+		provider.WithFaultInjection(FaultInjection{Seed: 42, Rules: []FaultRule{
+			{Kind: FaultTransient, Probability: 0.5, Err: sentinel},
+		}})
+		repo := NewRepository[TestUser](provider.db, provider)
+		var failures []bool
+		for i := 0; i < 20; i++ {
+			_, err := repo.Count(context.Background())
+			if err != nil && err != sentinel {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			failures = append(failures, err != nil)
+		}
+		return failures
+	}
+	first, second := run(), run()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected the same failures for the same seed, got %v and %v", first, second)
+		}
+	}
+}
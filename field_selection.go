@@ -0,0 +1,178 @@
+// Package gpagorm provides column and relation selection from GraphQL fields
+package gpagorm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// PreloadSelectOption preloads a relation reading only some of its columns.
+// It carries no state for gpa.Query; buildQuery applies it.
+type PreloadSelectOption struct {
+	Relation string // Relation path, e.g. "Posts.Comments"
+	Columns  []string
+}
+
+// Apply implements gpa.QueryOption
+func (o PreloadSelectOption) Apply(query *gpa.Query) {}
+
+// PreloadSelect preloads relation selecting only columns. The columns must
+// include the keys that link the relation to its parent.
+func PreloadSelect(relation string, columns ...string) gpa.QueryOption {
+	return PreloadSelectOption{Relation: relation, Columns: columns}
+}
+
+// applyPreloadSelects applies the PreloadSelectOptions in opts
+func applyPreloadSelects(db *gorm.DB, opts []gpa.QueryOption) *gorm.DB {
+	for _, opt := range opts {
+		if o, ok := opt.(PreloadSelectOption); ok {
+			columns := o.Columns
+			db = db.Preload(o.Relation, func(tx *gorm.DB) *gorm.DB { return tx.Select(columns) })
+		}
+	}
+	return db
+}
+
+// selectionNode is an entity in a field selection with the columns and
+// relations requested of it
+type selectionNode struct {
+	schema    *schema.Schema
+	columns   map[string]bool
+	relations map[string]*selectionNode
+}
+
+// SelectFields converts the fields requested by a GraphQL query into the
+// options that fetch only them: gpa.Select for the entity's columns and a
+// PreloadSelect per relation. Nested fields are dotted paths:
+//
+//	opts, err := repo.SelectFields("id", "name", "posts.title", "posts.author.name")
+//	users, err := repo.Query(ctx, append(opts, gpa.Limit(10))...)
+//
+// Path segments match a field's Go name, column or JSON name, ignoring
+// case and underscores. Meta fields such as __typename are skipped. Primary keys and the
+// keys joining each relation are always selected so preloads can stitch
+// rows together. Unknown fields are invalid argument errors.
+func (r *Repository[T]) SelectFields(paths ...string) ([]gpa.QueryOption, error) {
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+	root := newSelectionNode(s)
+	for _, path := range paths {
+		if err := root.add(path); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []gpa.QueryOption{gpa.Select(root.columnList()...)}
+	var walk func(prefix string, node *selectionNode)
+	walk = func(prefix string, node *selectionNode) {
+		names := make([]string, 0, len(node.relations))
+		for name := range node.relations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := node.relations[name]
+			opts = append(opts, PreloadSelect(prefix+name, child.columnList()...))
+			walk(prefix+name+".", child)
+		}
+	}
+	walk("", root)
+	return opts, nil
+}
+
+// newSelectionNode returns a node selecting the primary keys of s
+func newSelectionNode(s *schema.Schema) *selectionNode {
+	node := &selectionNode{schema: s, columns: map[string]bool{}, relations: map[string]*selectionNode{}}
+	for _, f := range s.PrimaryFields {
+		node.columns[f.DBName] = true
+	}
+	return node
+}
+
+// add selects the field at the dotted path below node
+func (node *selectionNode) add(path string) error {
+	segment, rest, nested := strings.Cut(path, ".")
+	if strings.HasPrefix(segment, "__") {
+		return nil
+	}
+	field := lookUpSelectionField(node.schema, segment)
+	if field == nil {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("%s has no field %s", node.schema.Name, segment))
+	}
+
+	if rel, ok := node.schema.Relationships.Relations[field.Name]; ok {
+		child, ok := node.relations[rel.Name]
+		if !ok {
+			child = newSelectionNode(rel.FieldSchema)
+			node.relations[rel.Name] = child
+			// Select the keys joining parent and child rows on both sides
+			for _, ref := range rel.References {
+				for _, f := range []*schema.Field{ref.PrimaryKey, ref.ForeignKey} {
+					if f == nil {
+						continue
+					}
+					if f.Schema == node.schema {
+						node.columns[f.DBName] = true
+					}
+					if f.Schema == rel.FieldSchema {
+						child.columns[f.DBName] = true
+					}
+				}
+			}
+		}
+		if !nested {
+			return nil
+		}
+		return child.add(rest)
+	}
+
+	if field.DBName == "" {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("%s.%s is not a column", node.schema.Name, field.Name))
+	}
+	if nested {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("%s.%s has no fields", node.schema.Name, field.Name))
+	}
+	node.columns[field.DBName] = true
+	return nil
+}
+
+// lookUpSelectionField finds the field of s named name by Go name, column
+// or JSON name, ignoring case and underscores so camelCase GraphQL names
+// match snake_case columns
+func lookUpSelectionField(s *schema.Schema, name string) *schema.Field {
+	if f := s.LookUpField(name); f != nil {
+		return f
+	}
+	if name = foldFieldName(name); name == "" {
+		return nil
+	}
+	for _, f := range s.Fields {
+		if foldFieldName(f.Name) == name || foldFieldName(f.DBName) == name || foldFieldName(jsonName(f)) == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// foldFieldName lowercases name and drops its underscores
+func foldFieldName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// columnList returns the selected columns in schema order
+func (node *selectionNode) columnList() []string {
+	columns := make([]string, 0, len(node.columns))
+	for _, name := range node.schema.DBNames {
+		if node.columns[name] {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
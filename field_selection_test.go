@@ -0,0 +1,76 @@
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+type testBlogAuthor struct {
+	ID    uint
+	Name  string
+	Email string
+	Posts []testBlogPost `gorm:"foreignKey:AuthorID"`
+}
+
+type testBlogPost struct {
+	ID         uint
+	AuthorID   uint
+	CategoryID uint
+	Title      string
+	Body       string
+	Category   testBlogCategory
+}
+
+type testBlogCategory struct {
+	ID    uint
+	Label string `json:"label_text"`
+	Notes string
+}
+
+func TestSelectFields(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	provider.db.AutoMigrate(&testBlogAuthor{}, &testBlogPost{}, &testBlogCategory{})
+
+	category := testBlogCategory{Label: "go", Notes: "internal"}
+	provider.db.Create(&category)
+	provider.db.Create(&testBlogAuthor{Name: "Ann", Email: "ann@example.com", Posts: []testBlogPost{
+		{Title: "Generics", Body: "long text", CategoryID: category.ID},
+	}})
+
+	repo := NewRepository[testBlogAuthor](provider.db, provider)
+	opts, err := repo.SelectFields("name", "__typename", "posts.title", "posts.category.labelText")
+	if err != nil {
+		t.Fatalf("Failed to map fields: %v", err)
+	}
+	want := []gpa.QueryOption{
+		gpa.Select("id", "name"),
+		PreloadSelect("Posts", "id", "author_id", "category_id", "title"),
+		PreloadSelect("Posts.Category", "id", "label"),
+	}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("Expected %#v, got %#v", want, opts)
+	}
+
+	authors, err := repo.Query(ctx, opts...)
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if len(authors) != 1 || authors[0].Name != "Ann" || authors[0].Email != "" || len(authors[0].Posts) != 1 {
+		t.Fatalf("Expected Ann with one post and no email, got %+v", authors)
+	}
+	post := authors[0].Posts[0]
+	if post.Title != "Generics" || post.Body != "" || post.Category.Label != "go" || post.Category.Notes != "" {
+		t.Errorf("Expected only the requested post and category fields, got %+v", post)
+	}
+
+	for _, path := range []string{"password", "name.first", "posts.comments"} {
+		if _, err := repo.SelectFields(path); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+			t.Errorf("Expected invalid argument for %s, got %v", path, err)
+		}
+	}
+}
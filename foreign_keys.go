@@ -0,0 +1,152 @@
+// Package gpagorm provides control over foreign key creation in migrations
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// ForeignKeyMigrator lets a model override the "disable_foreign_keys"
+// option: MigrateForeignKeys returns whether migrating the model creates
+// the foreign keys on its table.
+type ForeignKeyMigrator interface {
+	MigrateForeignKeys() bool
+}
+
+// withoutForeignKeys returns db configured to skip foreign keys while
+// migrating. The config is copied so db itself is unaffected.
+func withoutForeignKeys(db *gorm.DB) *gorm.DB {
+	if db.DisableForeignKeyConstraintWhenMigrating {
+		return db
+	}
+	config := *db.Config
+	config.DisableForeignKeyConstraintWhenMigrating = true
+	tx := db.Session(&gorm.Session{})
+	tx.Config = &config
+	return tx
+}
+
+// autoMigrate runs AutoMigrate on models. Without ForeignKeyMigrator
+// overrides it follows the "disable_foreign_keys" option. With overrides,
+// every table is migrated without foreign keys first and the enabled models
+// then get the constraints on their own tables, so an override never leaks
+// to the related models GORM migrates along the way. Models migrated
+// without their foreign keys are remembered for CreateForeignKeys.
+func (p *Provider) autoMigrate(db *gorm.DB, models ...interface{}) error {
+	defaultEnabled := !db.DisableForeignKeyConstraintWhenMigrating
+	var enabled, skipped []interface{}
+	overridden := false
+	for _, model := range models {
+		on := defaultEnabled
+		if m, ok := model.(ForeignKeyMigrator); ok {
+			on = m.MigrateForeignKeys()
+			overridden = true
+		}
+		if on {
+			enabled = append(enabled, model)
+		} else {
+			skipped = append(skipped, model)
+		}
+	}
+
+	if !overridden {
+		if err := db.AutoMigrate(models...); err != nil {
+			return err
+		}
+	} else {
+		if err := withoutForeignKeys(db).AutoMigrate(models...); err != nil {
+			return err
+		}
+		for _, model := range enabled {
+			if _, err := createForeignKeys(db, model, true); err != nil {
+				return err
+			}
+		}
+	}
+	if p != nil && len(skipped) > 0 {
+		p.deferForeignKeys(skipped)
+	}
+	return nil
+}
+
+// deferForeignKeys remembers models whose foreign keys were not created
+func (p *Provider) deferForeignKeys(models []interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, model := range models {
+		typ := reflect.Indirect(reflect.ValueOf(model)).Type()
+		if p.deferredForeignKeys == nil {
+			p.deferredForeignKeys = map[reflect.Type]interface{}{}
+		}
+		if _, ok := p.deferredForeignKeys[typ]; !ok {
+			p.deferredForeignKeys[typ] = model
+			p.deferredForeignKeyOrder = append(p.deferredForeignKeyOrder, typ)
+		}
+	}
+}
+
+// CreateForeignKeys creates the missing foreign key constraints of models,
+// as a separate step after every table exists. Without models it creates
+// those skipped by earlier migrations run with "disable_foreign_keys" or a
+// ForeignKeyMigrator override. It returns the names of the constraints it
+// created.
+func (p *Provider) CreateForeignKeys(ctx context.Context, models ...interface{}) ([]string, error) {
+	deferred := len(models) == 0
+	if deferred {
+		p.mu.RLock()
+		for _, typ := range p.deferredForeignKeyOrder {
+			models = append(models, p.deferredForeignKeys[typ])
+		}
+		p.mu.RUnlock()
+	}
+
+	db := p.db.WithContext(ctx)
+	var created []string
+	for _, model := range models {
+		names, err := createForeignKeys(db, model, false)
+		created = append(created, names...)
+		if err != nil {
+			return created, convertGormError(err)
+		}
+	}
+
+	if deferred {
+		p.mu.Lock()
+		p.deferredForeignKeys = nil
+		p.deferredForeignKeyOrder = nil
+		p.mu.Unlock()
+	}
+	return created, nil
+}
+
+// createForeignKeys creates the missing foreign key constraints of model's
+// relations, only those on its own table when ownOnly is set
+func createForeignKeys(db *gorm.DB, model interface{}, ownOnly bool) ([]string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	migrator := db.Migrator()
+	var created []string
+	for _, rel := range stmt.Schema.Relationships.Relations {
+		if rel.Field.IgnoreMigration {
+			continue
+		}
+		constraint := rel.ParseConstraint()
+		if constraint == nil || (ownOnly && constraint.Schema != stmt.Schema) {
+			continue
+		}
+		// Has-one and has-many constraints live on the related table
+		owner := reflect.New(constraint.Schema.ModelType).Interface()
+		if migrator.HasConstraint(owner, constraint.Name) {
+			continue
+		}
+		if err := migrator.CreateConstraint(owner, constraint.Name); err != nil {
+			return created, err
+		}
+		created = append(created, constraint.Name)
+	}
+	return created, nil
+}
@@ -0,0 +1,87 @@
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+type fkAuthor struct {
+	ID   uint
+	Name string
+}
+
+type fkBook struct {
+	ID       uint
+	AuthorID uint
+	Author   fkAuthor
+}
+
+type fkReview struct {
+	ID     uint
+	BookID uint
+	Book   fkBook
+}
+
+// MigrateForeignKeys keeps review constraints despite the provider option
+func (fkReview) MigrateForeignKeys() bool { return true }
+
+func TestForeignKeysDeferred(t *testing.T) {
+	provider, err := NewProvider(gpa.Config{
+		Driver:   "sqlite",
+		Database: ":memory:",
+		Options: map[string]interface{}{
+			"gorm": map[string]interface{}{"log_level": "silent", "disable_foreign_keys": true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	defer provider.Close()
+	ctx := context.Background()
+
+	if err := provider.Migrate(&fkAuthor{}, &fkBook{}, &fkReview{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	migrator := provider.db.Migrator()
+	if migrator.HasConstraint(&fkBook{}, "Author") {
+		t.Error("Expected book foreign key to be skipped")
+	}
+	if !migrator.HasConstraint(&fkReview{}, "Book") {
+		t.Error("Expected the model override to create the review foreign key")
+	}
+
+	created, err := provider.CreateForeignKeys(ctx)
+	if err != nil {
+		t.Fatalf("Failed to create foreign keys: %v", err)
+	}
+	if !reflect.DeepEqual(created, []string{"fk_fk_books_author"}) {
+		t.Errorf("Expected the deferred book foreign key, got %v", created)
+	}
+	if !migrator.HasConstraint(&fkBook{}, "Author") {
+		t.Error("Expected book foreign key to exist")
+	}
+
+	// Nothing is left to create
+	if created, err := provider.CreateForeignKeys(ctx, &fkBook{}, &fkReview{}); err != nil || len(created) != 0 {
+		t.Errorf("Expected no new foreign keys, got %v %v", created, err)
+	}
+}
+
+func TestForeignKeysDefault(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	repo := NewRepository[fkBook](provider.db, provider)
+	if err := repo.MigrateTable(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if !provider.db.Migrator().HasConstraint(&fkBook{}, "Author") {
+		t.Error("Expected foreign keys to be created by default")
+	}
+	if len(provider.deferredForeignKeys) != 0 {
+		t.Errorf("Expected nothing deferred, got %v", provider.deferredForeignKeys)
+	}
+}
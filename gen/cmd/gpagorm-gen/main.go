@@ -0,0 +1,35 @@
+// Command gpagorm-gen writes typed query helpers for the models of a
+// package. Typical use is a go:generate line next to the models:
+//
+//	//go:generate go run github.com/lemmego/gpagorm/gen/cmd/gpagorm-gen
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lemmego/gpagorm/gen"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "package directory holding the models")
+	models := flag.String("models", "", "comma-separated model structs (default: structs marked //gpagorm:model)")
+	out := flag.String("out", "gpagorm_gen.go", "output file, relative to -dir")
+	flag.Parse()
+
+	cfg := gen.Config{Dir: *dir}
+	if *models != "" {
+		cfg.Models = strings.Split(*models, ",")
+	}
+	path := *out
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(*dir, path)
+	}
+	if err := gen.WriteFile(cfg, path); err != nil {
+		fmt.Fprintln(os.Stderr, "gpagorm-gen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,509 @@
+// Package gen generates typed query helpers for gpagorm models.
+//
+// For each model struct it emits column name constants, a struct of typed
+// gpagorm.Column references and, for projections declared on the model,
+// a projection struct with a query function. Because the helpers are plain
+// Go, renaming or retyping a model field breaks the build instead of a
+// query at runtime.
+//
+// Models are the structs named in Config.Models, or the structs marked
+// with a //gpagorm:model comment. Projections are declared in the model's
+// doc comment:
+//
+//	//gpagorm:model
+//	//gpagorm:projection UserSummary ID Name
+//	type User struct { ... }
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gorm.io/gorm/schema"
+)
+
+const (
+	modelDirective      = "//gpagorm:model"
+	projectionDirective = "//gpagorm:projection"
+	gpagormImport       = "github.com/lemmego/gpagorm"
+)
+
+// Config selects the models to generate helpers for
+type Config struct {
+	Dir    string   // Package directory holding the models
+	Models []string // Struct names; empty means the structs marked //gpagorm:model
+}
+
+// Model is a struct the generator emits helpers for
+type Model struct {
+	Name        string
+	Fields      []Field
+	Projections []Projection
+}
+
+// Field is a model field mapped to a column
+type Field struct {
+	Name   string // Go field name
+	Column string // Database column name
+	Type   string // Go type as written in the package
+}
+
+// Projection is a named subset of a model's fields
+type Projection struct {
+	Name   string
+	Fields []Field
+}
+
+// Generate parses the package in cfg.Dir and returns the formatted source
+// of its helpers, to be written into the same package
+func Generate(cfg Config) ([]byte, error) {
+	pkg, err := parsePackage(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	models, err := pkg.models(cfg.Models)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	data := map[string]interface{}{
+		"Package": pkg.name,
+		"Imports": pkg.importList(models),
+		"Models":  models,
+	}
+	if err := fileTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gen: formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+// structDecl is a struct type declared in the package
+type structDecl struct {
+	name string
+	typ  *ast.StructType
+	doc  []string
+	file *ast.File
+}
+
+// goPackage is the parsed package the models live in
+type goPackage struct {
+	name    string
+	fset    *token.FileSet
+	structs map[string]*structDecl
+	order   []string
+	imports map[string]string // Import paths used by field types, by name
+}
+
+// parsePackage parses the non-test, non-generated Go files in dir
+func parsePackage(dir string) (*goPackage, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	pkg := &goPackage{fset: token.NewFileSet(), structs: map[string]*structDecl{}, imports: map[string]string{}}
+	for _, filename := range files {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(pkg.fset, filename, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if ast.IsGenerated(file) {
+			continue
+		}
+		if pkg.name == "" {
+			pkg.name = file.Name.Name
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok || ts.TypeParams != nil {
+					continue
+				}
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				pkg.structs[ts.Name.Name] = &structDecl{name: ts.Name.Name, typ: st, doc: commentLines(doc), file: file}
+				pkg.order = append(pkg.order, ts.Name.Name)
+			}
+		}
+	}
+	if pkg.name == "" {
+		return nil, fmt.Errorf("gen: no Go files in %s", dir)
+	}
+	return pkg, nil
+}
+
+// commentLines returns the raw lines of a comment group
+func commentLines(doc *ast.CommentGroup) []string {
+	if doc == nil {
+		return nil
+	}
+	lines := make([]string, 0, len(doc.List))
+	for _, c := range doc.List {
+		lines = append(lines, c.Text)
+	}
+	return lines
+}
+
+// models resolves the requested models, or the marked ones when names is empty
+func (pkg *goPackage) models(names []string) ([]Model, error) {
+	if len(names) == 0 {
+		for _, name := range pkg.order {
+			for _, line := range pkg.structs[name].doc {
+				if strings.TrimSpace(line) == modelDirective {
+					names = append(names, name)
+					break
+				}
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("gen: no structs marked %s in package %s", modelDirective, pkg.name)
+		}
+	}
+
+	models := make([]Model, 0, len(names))
+	for _, name := range names {
+		decl, ok := pkg.structs[name]
+		if !ok {
+			return nil, fmt.Errorf("gen: struct %s not found in package %s", name, pkg.name)
+		}
+		model := Model{Name: name}
+		if err := pkg.collectFields(&model.Fields, decl.typ, decl.file, ""); err != nil {
+			return nil, fmt.Errorf("gen: %s: %w", name, err)
+		}
+		projections, err := parseProjections(decl.doc, model.Fields)
+		if err != nil {
+			return nil, fmt.Errorf("gen: %s: %w", name, err)
+		}
+		model.Projections = projections
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+// relationSettings are gorm tag settings that mark association fields
+var relationSettings = []string{"FOREIGNKEY", "REFERENCES", "MANY2MANY", "POLYMORPHIC", "JOINFOREIGNKEY"}
+
+// collectFields appends the column fields of st, expanding embedded structs
+func (pkg *goPackage) collectFields(fields *[]Field, st *ast.StructType, file *ast.File, prefix string) error {
+	naming := schema.NamingStrategy{}
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			raw, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return err
+			}
+			tag = reflect.StructTag(raw)
+		}
+		gormTag := tag.Get("gorm")
+		if gormTag == "-" || strings.HasPrefix(gormTag, "-;") {
+			continue
+		}
+		settings := schema.ParseTagSetting(gormTag, ";")
+		if _, ok := settings["-"]; ok {
+			continue
+		}
+		if hasAny(settings, relationSettings) {
+			continue
+		}
+
+		// Embedded structs contribute their fields, as in gorm's schema
+		_, embedded := settings["EMBEDDED"]
+		if len(f.Names) == 0 || embedded {
+			embeddedPrefix := prefix + settings["EMBEDDEDPREFIX"]
+			if sel, ok := f.Type.(*ast.SelectorExpr); ok && exprString(sel.X) == importName(file, "gorm.io/gorm") && sel.Sel.Name == "Model" {
+				pkg.imports["time"] = "time"
+				pkg.imports["gorm"] = "gorm.io/gorm"
+				*fields = append(*fields,
+					Field{Name: "ID", Column: embeddedPrefix + "id", Type: "uint"},
+					Field{Name: "CreatedAt", Column: embeddedPrefix + "created_at", Type: "time.Time"},
+					Field{Name: "UpdatedAt", Column: embeddedPrefix + "updated_at", Type: "time.Time"},
+					Field{Name: "DeletedAt", Column: embeddedPrefix + "deleted_at", Type: "gorm.DeletedAt"},
+				)
+				continue
+			}
+			if decl := pkg.localStruct(f.Type); decl != nil {
+				if err := pkg.collectFields(fields, decl.typ, decl.file, embeddedPrefix); err != nil {
+					return err
+				}
+				continue
+			}
+			if len(f.Names) == 0 {
+				// Embedded types from other packages are not expanded
+				continue
+			}
+		}
+
+		if pkg.isRelation(f.Type, settings) {
+			continue
+		}
+		typ := exprString(f.Type)
+		if err := pkg.useImports(f.Type, file); err != nil {
+			return err
+		}
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+			column := settings["COLUMN"]
+			if column == "" {
+				column = naming.ColumnName("", name.Name)
+			}
+			*fields = append(*fields, Field{Name: name.Name, Column: prefix + column, Type: typ})
+		}
+	}
+	return nil
+}
+
+// localStruct returns the package struct expr names, through a pointer
+func (pkg *goPackage) localStruct(expr ast.Expr) *structDecl {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return pkg.structs[ident.Name]
+	}
+	return nil
+}
+
+// isRelation reports whether a field of type expr is an association rather
+// than a column: other models of the package, and slices of them
+func (pkg *goPackage) isRelation(expr ast.Expr, settings map[string]string) bool {
+	if settings["SERIALIZER"] != "" || settings["TYPE"] != "" {
+		return false
+	}
+	if arr, ok := expr.(*ast.ArrayType); ok {
+		if ident, ok := arr.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return false
+		}
+		expr = arr.Elt
+	}
+	return pkg.localStruct(expr) != nil
+}
+
+// useImports records the imports referenced by a field type
+func (pkg *goPackage) useImports(expr ast.Expr, file *ast.File) error {
+	var err error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		importPath := importPathOf(file, ident.Name)
+		if importPath == "" {
+			err = fmt.Errorf("unknown package %s in field type %s", ident.Name, exprString(expr))
+			return false
+		}
+		if existing, ok := pkg.imports[ident.Name]; ok && existing != importPath {
+			err = fmt.Errorf("package name %s refers to both %s and %s", ident.Name, existing, importPath)
+			return false
+		}
+		pkg.imports[ident.Name] = importPath
+		return false
+	})
+	return err
+}
+
+// importList returns the import specs the generated file needs, standard
+// library first
+func (pkg *goPackage) importList(models []Model) [][]string {
+	specs := []string{strconv.Quote(gpagormImport)}
+	for _, m := range models {
+		if len(m.Projections) > 0 {
+			specs = append(specs, strconv.Quote("context"), strconv.Quote("github.com/lemmego/gpa"))
+			break
+		}
+	}
+	for name, importPath := range pkg.imports {
+		spec := strconv.Quote(importPath)
+		if name != defaultImportName(importPath) {
+			spec = name + " " + spec
+		}
+		specs = append(specs, spec)
+	}
+	sort.Strings(specs)
+
+	var std, others []string
+	for _, spec := range specs {
+		importPath, _ := strconv.Unquote(spec[strings.IndexByte(spec, '"'):])
+		if strings.Contains(strings.Split(importPath, "/")[0], ".") {
+			others = append(others, spec)
+		} else {
+			std = append(std, spec)
+		}
+	}
+	return [][]string{std, others}
+}
+
+// importPathOf returns the path of the import named name in file
+func importPathOf(file *ast.File, name string) string {
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name != nil {
+			if spec.Name.Name == name {
+				return importPath
+			}
+		} else if defaultImportName(importPath) == name {
+			return importPath
+		}
+	}
+	return ""
+}
+
+// importName returns the name importPath is imported under in file
+func importName(file *ast.File, importPath string) string {
+	for _, spec := range file.Imports {
+		if p, _ := strconv.Unquote(spec.Path.Value); p == importPath {
+			if spec.Name != nil {
+				return spec.Name.Name
+			}
+			return defaultImportName(importPath)
+		}
+	}
+	return ""
+}
+
+// majorVersion matches the /vN suffix of module paths
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// defaultImportName guesses the package name of an import path
+func defaultImportName(importPath string) string {
+	base := path.Base(importPath)
+	if majorVersion.MatchString(base) {
+		base = path.Base(path.Dir(importPath))
+	}
+	return strings.TrimPrefix(strings.TrimSuffix(base, ".go"), "go-")
+}
+
+// parseProjections reads the projection directives of a model's doc comment
+func parseProjections(doc []string, fields []Field) ([]Projection, error) {
+	byName := map[string]Field{}
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	var projections []Projection
+	for _, line := range doc {
+		if !strings.HasPrefix(line, projectionDirective+" ") {
+			continue
+		}
+		words := strings.Fields(strings.TrimPrefix(line, projectionDirective))
+		if len(words) < 2 || !token.IsIdentifier(words[0]) {
+			return nil, fmt.Errorf("malformed projection %q, want %s Name Field...", line, projectionDirective)
+		}
+		projection := Projection{Name: words[0]}
+		for _, name := range words[1:] {
+			f, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("projection %s: no column field %s", projection.Name, name)
+			}
+			projection.Fields = append(projection.Fields, f)
+		}
+		projections = append(projections, projection)
+	}
+	return projections, nil
+}
+
+// hasAny reports whether settings holds any of keys
+func hasAny(settings map[string]string, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := settings[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// exprString renders a type expression as source
+func exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	format.Node(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
+
+// WriteFile generates the helpers for cfg and writes them to path
+func WriteFile(cfg Config, path string) error {
+	src, err := Generate(cfg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, src, 0o644)
+}
+
+var fileTemplate = template.Must(template.New("file").Parse(`// Code generated by gpagorm-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range $i, $group := .Imports}}{{if and $i (index $.Imports 0)}}
+{{end}}
+{{- range $group}}
+	{{.}}
+{{- end}}
+{{- end}}
+)
+{{range $m := .Models}}
+// Column names of {{$m.Name}}
+const (
+{{- range $m.Fields}}
+	{{$m.Name}}Column{{.Name}} = {{printf "%q" .Column}}
+{{- end}}
+)
+
+// {{$m.Name}}Columns holds typed column references of {{$m.Name}}
+type {{$m.Name}}Columns struct {
+{{- range $m.Fields}}
+	{{.Name}} gpagorm.Column[{{.Type}}]
+{{- end}}
+}
+
+// {{$m.Name}}Cols are the typed columns of {{$m.Name}}
+var {{$m.Name}}Cols = {{$m.Name}}Columns{
+{{- range $m.Fields}}
+	{{.Name}}: gpagorm.NewColumn[{{.Type}}]({{$m.Name}}Column{{.Name}}),
+{{- end}}
+}
+{{range $p := $m.Projections}}
+// {{$p.Name}} is a projection of {{$m.Name}}
+type {{$p.Name}} struct {
+{{- range $p.Fields}}
+	{{.Name}} {{.Type}} ` + "`" + `gorm:"column:{{.Column}}"` + "`" + `
+{{- end}}
+}
+
+// Query{{$p.Name}} selects the {{$p.Name}} columns of the {{$m.Name}} rows matching opts
+func Query{{$p.Name}}(ctx context.Context, repo *gpagorm.Repository[{{$m.Name}}], opts ...gpa.QueryOption) ([]{{$p.Name}}, error) {
+	return gpagorm.Project[{{$p.Name}}](ctx, repo, opts...)
+}
+{{end}}
+{{- end}}`))
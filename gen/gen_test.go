@@ -0,0 +1,104 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGeneratedExampleUpToDate(t *testing.T) {
+	src, err := Generate(Config{Dir: "internal/example"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	existing, err := os.ReadFile("internal/example/gpagorm_gen.go")
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	if string(src) != string(existing) {
+		t.Errorf("internal/example/gpagorm_gen.go is stale; run go generate ./gen/...")
+	}
+}
+
+func writeModels(t *testing.T, src string) string {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "models.go"), []byte(src), 0o644); err != nil {
+		t.Fatalf("Failed to write models: %v", err)
+	}
+	return dir
+}
+
+func TestGenerateNamedModels(t *testing.T) {
+	dir := writeModels(t, `package shop
+
+import (
+	"database/sql"
+	dec "github.com/shopspring/decimal"
+)
+
+type Address struct {
+	City string
+}
+
+type Order struct {
+	ID       int64
+	Total    dec.Decimal
+	Note     sql.NullString `+"`gorm:\"column:remark\"`"+`
+	Shipping Address        `+"`gorm:\"embedded;embeddedPrefix:ship_\"`"+`
+	Items    []Item
+	Ignored  string `+"`gorm:\"-\"`"+`
+}
+
+type Item struct {
+	ID uint
+}
+`)
+
+	src, err := Generate(Config{Dir: dir, Models: []string{"Order"}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	// Compare with alignment collapsed
+	out := strings.Join(strings.Fields(string(src)), " ")
+	for _, want := range []string{
+		"package shop",
+		`"database/sql"`,
+		`dec "github.com/shopspring/decimal"`,
+		`OrderColumnNote = "remark"`,
+		`OrderColumnCity = "ship_city"`,
+		"Total gpagorm.Column[dec.Decimal]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected generated code to contain %q:\n%s", want, src)
+		}
+	}
+	for _, unwanted := range []string{"Items", "Ignored", "ItemColumns", `"context"`} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("Expected generated code not to contain %q", unwanted)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	dir := writeModels(t, `package shop
+
+//gpagorm:model
+//gpagorm:projection Brief ID Missing
+type Order struct {
+	ID int64
+}
+`)
+
+	if _, err := Generate(Config{Dir: dir}); err == nil || !strings.Contains(err.Error(), "no column field Missing") {
+		t.Errorf("Expected unknown projection field error, got %v", err)
+	}
+	if _, err := Generate(Config{Dir: dir, Models: []string{"Customer"}}); err == nil {
+		t.Error("Expected error for unknown model")
+	}
+
+	empty := writeModels(t, "package shop\n\ntype Order struct{ ID int64 }\n")
+	if _, err := Generate(Config{Dir: empty}); err == nil {
+		t.Error("Expected error when no models are marked")
+	}
+}
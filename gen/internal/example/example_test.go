@@ -0,0 +1,49 @@
+package example
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/lemmego/gpagorm"
+	"gorm.io/gorm"
+)
+
+func TestGeneratedHelpers(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&User{}, &Post{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	ctx := context.Background()
+	repo := gpagorm.NewRepository[User](db, nil)
+	for _, u := range []*User{
+		{Name: "Alice", Email: "alice@example.com", Age: 34},
+		{Name: "Bob", Email: "bob@example.com", Age: 25},
+		{Name: "Amy", Email: "amy@example.com", Age: 41},
+	} {
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	q := UserCols
+	users, err := repo.Query(ctx, q.Age.Gt(30), q.Name.Like("A%"), q.Age.Desc())
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(users) != 2 || users[0].Name != "Amy" {
+		t.Errorf("Expected Amy then Alice, got %+v", users)
+	}
+
+	summaries, err := QueryUserSummary(ctx, repo, q.Email.Eq("bob@example.com"))
+	if err != nil {
+		t.Fatalf("QueryUserSummary failed: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].Name != "Bob" || summaries[0].ID == 0 {
+		t.Errorf("Unexpected summaries: %+v", summaries)
+	}
+}
@@ -0,0 +1,86 @@
+// Code generated by gpagorm-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"github.com/lemmego/gpagorm"
+	"gorm.io/gorm"
+)
+
+// Column names of User
+const (
+	UserColumnID        = "id"
+	UserColumnName      = "name"
+	UserColumnEmail     = "email_address"
+	UserColumnAge       = "age"
+	UserColumnNickname  = "nickname"
+	UserColumnCreatedAt = "created_at"
+)
+
+// UserColumns holds typed column references of User
+type UserColumns struct {
+	ID        gpagorm.Column[uint]
+	Name      gpagorm.Column[string]
+	Email     gpagorm.Column[string]
+	Age       gpagorm.Column[int]
+	Nickname  gpagorm.Column[*string]
+	CreatedAt gpagorm.Column[time.Time]
+}
+
+// UserCols are the typed columns of User
+var UserCols = UserColumns{
+	ID:        gpagorm.NewColumn[uint](UserColumnID),
+	Name:      gpagorm.NewColumn[string](UserColumnName),
+	Email:     gpagorm.NewColumn[string](UserColumnEmail),
+	Age:       gpagorm.NewColumn[int](UserColumnAge),
+	Nickname:  gpagorm.NewColumn[*string](UserColumnNickname),
+	CreatedAt: gpagorm.NewColumn[time.Time](UserColumnCreatedAt),
+}
+
+// UserSummary is a projection of User
+type UserSummary struct {
+	ID   uint   `gorm:"column:id"`
+	Name string `gorm:"column:name"`
+}
+
+// QueryUserSummary selects the UserSummary columns of the User rows matching opts
+func QueryUserSummary(ctx context.Context, repo *gpagorm.Repository[User], opts ...gpa.QueryOption) ([]UserSummary, error) {
+	return gpagorm.Project[UserSummary](ctx, repo, opts...)
+}
+
+// Column names of Post
+const (
+	PostColumnID        = "id"
+	PostColumnCreatedAt = "created_at"
+	PostColumnUpdatedAt = "updated_at"
+	PostColumnDeletedAt = "deleted_at"
+	PostColumnAuthorID  = "author_id"
+	PostColumnTitle     = "title"
+	PostColumnBody      = "body"
+)
+
+// PostColumns holds typed column references of Post
+type PostColumns struct {
+	ID        gpagorm.Column[uint]
+	CreatedAt gpagorm.Column[time.Time]
+	UpdatedAt gpagorm.Column[time.Time]
+	DeletedAt gpagorm.Column[gorm.DeletedAt]
+	AuthorID  gpagorm.Column[uint]
+	Title     gpagorm.Column[string]
+	Body      gpagorm.Column[[]byte]
+}
+
+// PostCols are the typed columns of Post
+var PostCols = PostColumns{
+	ID:        gpagorm.NewColumn[uint](PostColumnID),
+	CreatedAt: gpagorm.NewColumn[time.Time](PostColumnCreatedAt),
+	UpdatedAt: gpagorm.NewColumn[time.Time](PostColumnUpdatedAt),
+	DeletedAt: gpagorm.NewColumn[gorm.DeletedAt](PostColumnDeletedAt),
+	AuthorID:  gpagorm.NewColumn[uint](PostColumnAuthorID),
+	Title:     gpagorm.NewColumn[string](PostColumnTitle),
+	Body:      gpagorm.NewColumn[[]byte](PostColumnBody),
+}
@@ -0,0 +1,38 @@
+// Package example holds models used to exercise the generator; its
+// gpagorm_gen.go is generated and checked by the gen tests
+package example
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:generate go run ../../cmd/gpagorm-gen
+
+// User is a model with a projection
+//
+//gpagorm:model
+//gpagorm:projection UserSummary ID Name
+type User struct {
+	ID        uint
+	Name      string
+	Email     string `gorm:"column:email_address;uniqueIndex"`
+	Age       int
+	Nickname  *string
+	CreatedAt time.Time
+	Posts     []Post `gorm:"foreignKey:AuthorID"`
+	secret    string
+}
+
+// Post embeds gorm.Model and belongs to a User
+//
+//gpagorm:model
+type Post struct {
+	gorm.Model
+	AuthorID uint
+	Author   *User
+	Title    string
+	Body     []byte
+	Draft    bool `gorm:"-"`
+}
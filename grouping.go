@@ -0,0 +1,188 @@
+// Package gpagorm provides GROUP BY ROLLUP and CUBE subtotals
+package gpagorm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// GroupingKind selects the grouping sets a GroupingOption produces
+type GroupingKind string
+
+const (
+	// GroupingRollup groups by each prefix of the fields, then overall
+	GroupingRollup GroupingKind = "ROLLUP"
+	// GroupingCube groups by every subset of the fields
+	GroupingCube GroupingKind = "CUBE"
+)
+
+// groupingAlias names the derived table of the UNION fallback
+const groupingAlias = "gpagorm_grouping"
+
+// GroupingOption groups by ROLLUP or CUBE of its fields, after any
+// gpa.GroupBy fields. It carries no state for gpa.Query; buildQuery reads it.
+type GroupingOption struct {
+	Kind   GroupingKind
+	Fields []string
+}
+
+// Apply implements gpa.QueryOption
+func (o GroupingOption) Apply(query *gpa.Query) {}
+
+// GroupByRollup adds subtotal rows for each prefix of fields and a grand
+// total, with NULL in the rolled-up columns:
+//
+//	totals, err := gpagorm.Project[RegionTotal](ctx, repo,
+//	    gpa.Select("region", "country", "SUM(amount) AS total"),
+//	    gpagorm.GroupByRollup("region", "country"))
+//
+// Postgres, SQL Server and MySQL group natively. Elsewhere the query runs
+// as a UNION ALL of one grouped query per level, which needs gpa.Select.
+func GroupByRollup(fields ...string) gpa.QueryOption {
+	return GroupingOption{Kind: GroupingRollup, Fields: fields}
+}
+
+// GroupByCube adds subtotal rows for every combination of fields, like
+// GroupByRollup. MySQL and SQLite use the UNION ALL fallback.
+func GroupByCube(fields ...string) gpa.QueryOption {
+	return GroupingOption{Kind: GroupingCube, Fields: fields}
+}
+
+// groupingFromOptions returns the last GroupingOption in opts
+func groupingFromOptions(opts []gpa.QueryOption) *GroupingOption {
+	var found *GroupingOption
+	for _, opt := range opts {
+		if grouping, ok := opt.(GroupingOption); ok {
+			found = &grouping
+		}
+	}
+	return found
+}
+
+// validate checks the grouping fields
+func (o *GroupingOption) validate() error {
+	if len(o.Fields) == 0 {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "grouping needs at least one field")
+	}
+	for _, field := range o.Fields {
+		if !isValidFieldName(field) {
+			return &FieldValidationError{
+				Field:  field,
+				Reason: "field name contains invalid characters or doesn't follow naming rules",
+			}
+		}
+	}
+	return nil
+}
+
+// nativeGrouping returns the GROUP BY expression for the dialect, or ""
+// when it lacks the grouping
+func (o *GroupingOption) nativeGrouping(db *gorm.DB) string {
+	fields := make([]string, len(o.Fields))
+	for i, field := range o.Fields {
+		fields[i] = quoteField(db, field)
+	}
+	list := strings.Join(fields, ", ")
+
+	switch dialectName(db) {
+	case "postgres", "sqlserver":
+		return string(o.Kind) + " (" + list + ")"
+	case "mysql":
+		if o.Kind == GroupingRollup {
+			return list + " WITH ROLLUP"
+		}
+	}
+	return ""
+}
+
+// groupingSets lists the field sets to group by, largest first
+func (o *GroupingOption) groupingSets() [][]string {
+	var sets [][]string
+	if o.Kind == GroupingRollup {
+		for n := len(o.Fields); n >= 0; n-- {
+			sets = append(sets, o.Fields[:n])
+		}
+		return sets
+	}
+	for size := len(o.Fields); size >= 0; size-- {
+		for mask := (1 << len(o.Fields)) - 1; mask >= 0; mask-- {
+			var set []string
+			for i, field := range o.Fields {
+				if mask&(1<<i) != 0 {
+					set = append(set, field)
+				}
+			}
+			if len(set) == size {
+				sets = append(sets, set)
+			}
+		}
+	}
+	return sets
+}
+
+// buildGroupingUnion emulates a grouping with UNION ALL of one grouped
+// query per grouping set, selecting NULL for the fields a set leaves out.
+// Ordering, limit and offset apply to the combined rows.
+func (r *Repository[T]) buildGroupingUnion(ctx context.Context, grouping *GroupingOption, opts []gpa.QueryOption) *gorm.DB {
+	query := &gpa.Query{}
+	var base []gpa.QueryOption
+	for _, opt := range opts {
+		opt.Apply(query)
+		switch opt.(type) {
+		case gpa.FieldsOption, gpa.OrderOption, SortOption, gpa.LimitOption, gpa.OffsetOption, GroupingOption:
+		default:
+			base = append(base, opt)
+		}
+	}
+
+	db := r.replicaSession(ctx)
+	if len(query.Fields) == 0 {
+		db.AddError(gpa.NewError(gpa.ErrorTypeInvalidArgument, "grouping on "+dialectName(db)+" needs the selected fields, see gpa.Select"))
+		return db
+	}
+
+	var arms []string
+	var vars []interface{}
+	for _, set := range grouping.groupingSets() {
+		grouped := map[string]bool{}
+		for _, field := range set {
+			grouped[field] = true
+		}
+		fields := make([]string, len(query.Fields))
+		for i, field := range query.Fields {
+			fields[i] = field
+			for _, g := range grouping.Fields {
+				if !grouped[g] && (field == g || strings.HasSuffix(field, "."+g)) {
+					fields[i] = "NULL AS " + quoteField(db, g[strings.LastIndex(g, ".")+1:])
+				}
+			}
+		}
+
+		armOpts := append(append([]gpa.QueryOption{}, base...), gpa.Select(fields...))
+		if len(set) > 0 {
+			armOpts = append(armOpts, gpa.GroupBy(set...))
+		}
+		var zero T
+		var rows []map[string]interface{}
+		stmt := r.buildQuery(ctx, armOpts...).Model(&zero).Session(&gorm.Session{DryRun: true}).Find(&rows)
+		if stmt.Error != nil {
+			db.AddError(stmt.Error)
+			return db
+		}
+		arms = append(arms, stmt.Statement.SQL.String())
+		vars = append(vars, stmt.Statement.Vars...)
+	}
+
+	db = db.Table("("+strings.Join(arms, " UNION ALL ")+") AS "+groupingAlias, vars...).Unscoped()
+	db = r.applyOrdering(db, opts)
+	if query.Limit != nil {
+		db = db.Limit(*query.Limit)
+	}
+	if query.Offset != nil {
+		db = db.Offset(*query.Offset)
+	}
+	return db
+}
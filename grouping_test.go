@@ -0,0 +1,103 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type testSale struct {
+	ID      uint
+	Region  string
+	Country string
+	Amount  int
+}
+
+type testSaleTotal struct {
+	Region  *string
+	Country *string
+	Total   int
+}
+
+func (s testSaleTotal) String() string {
+	deref := func(v *string) string {
+		if v == nil {
+			return "-"
+		}
+		return *v
+	}
+	return fmt.Sprintf("%s/%s=%d", deref(s.Region), deref(s.Country), s.Total)
+}
+
+func TestGroupByRollupFallback(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&testSale{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[testSale](provider.db, provider)
+	ctx := context.Background()
+
+	for _, s := range []*testSale{
+		{Region: "EU", Country: "DE", Amount: 10},
+		{Region: "EU", Country: "FR", Amount: 5},
+		{Region: "EU", Country: "FR", Amount: 1},
+		{Region: "NA", Country: "US", Amount: 7},
+	} {
+		if err := repo.Create(ctx, s); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	totals, err := Project[testSaleTotal](ctx, repo,
+		gpa.Select("region", "country", "SUM(amount) AS total"),
+		GroupByRollup("region", "country"),
+		gpa.OrderBy("region", gpa.OrderAsc), gpa.OrderBy("country", gpa.OrderAsc))
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+	var got []string
+	for _, total := range totals {
+		got = append(got, total.String())
+	}
+	want := "-/-=23,EU/-=16,EU/DE=10,EU/FR=6,NA/-=7,NA/US=7"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Expected %s, got %s", want, strings.Join(got, ","))
+	}
+
+	totals, err = Project[testSaleTotal](ctx, repo,
+		gpa.Select("region", "country", "SUM(amount) AS total"),
+		GroupByCube("region", "country"),
+		gpa.Where("amount", gpa.OpGreaterThan, 1))
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+	// 3 country rows, 2 regions, 3 countries across regions, 1 grand total
+	if len(totals) != 9 {
+		t.Errorf("Expected 9 cube rows, got %v", totals)
+	}
+
+	if _, err := Project[testSaleTotal](ctx, repo, GroupByRollup("region")); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument without selected fields, got %v", err)
+	}
+}
+
+func TestGroupByRollupPostgres(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry-run postgres: %v", err)
+	}
+	repo := NewRepository[testSale](db, nil)
+
+	var sales []*testSale
+	sql := repo.buildQuery(context.Background(), gpa.Select("region", "SUM(amount) AS total"), gpa.GroupBy("channel"), GroupByCube("region", "country")).
+		Find(&sales).Statement.SQL.String()
+	if !strings.Contains(sql, `GROUP BY "channel",CUBE ("region", "country")`) {
+		t.Errorf("Unexpected SQL: %s", sql)
+	}
+}
@@ -0,0 +1,45 @@
+// Package gpagorm provides HAVING conditions on aggregate expressions
+package gpagorm
+
+import (
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// aggregatePattern matches an aggregate over a column or *, e.g.
+// "COUNT(*)", "sum(amount)" or "COUNT(DISTINCT users.email)"
+var aggregatePattern = regexp.MustCompile(`(?i)^\s*(COUNT|SUM|AVG|MIN|MAX)\s*\(\s*(DISTINCT\s+)?(\*|[A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)?)\s*\)\s*$`)
+
+// havingExpr returns the SQL for the operand of a HAVING condition: an
+// aggregate, or a field or select alias. Anything else is rejected, so the
+// operand never carries arbitrary SQL; values are always bound.
+func havingExpr(db *gorm.DB, field string) (string, error) {
+	invalid := &FieldValidationError{
+		Field:  field,
+		Reason: "having field must be a column, an alias or an aggregate of a column",
+	}
+	if m := aggregatePattern.FindStringSubmatch(field); m != nil {
+		arg := m[3]
+		if arg == "*" {
+			if m[2] != "" {
+				return "", invalid
+			}
+		} else {
+			var err error
+			if arg, err = fieldExpr(db, arg); err != nil {
+				return "", err
+			}
+		}
+		distinct := ""
+		if m[2] != "" {
+			distinct = "DISTINCT "
+		}
+		return strings.ToUpper(m[1]) + "(" + distinct + arg + ")", nil
+	}
+	if !isValidFieldName(field) {
+		return "", invalid
+	}
+	return fieldExpr(db, field)
+}
@@ -0,0 +1,65 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+type testRegionCount struct {
+	Region string
+	Orders int
+	Total  int
+}
+
+func TestHavingAggregates(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&testSale{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[testSale](provider.db, provider)
+	ctx := context.Background()
+
+	for _, s := range []*testSale{
+		{Region: "EU", Country: "DE", Amount: 10},
+		{Region: "EU", Country: "FR", Amount: 5},
+		{Region: "NA", Country: "US", Amount: 7},
+		{Region: "NA", Country: "CA", Amount: 1},
+		{Region: "APAC", Country: "JP", Amount: 30},
+	} {
+		if err := repo.Create(ctx, s); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	selectTotals := gpa.Select("region", "COUNT(*) AS orders", "SUM(amount) AS total")
+	regions, err := Project[testRegionCount](ctx, repo, selectTotals, gpa.GroupBy("region"),
+		gpa.Having("COUNT(*)", gpa.OpGreaterThan, 1),
+		gpa.Having("sum(amount)", gpa.OpGreaterThanOrEqual, 10),
+		gpa.OrderBy("region", gpa.OrderAsc))
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+	if len(regions) != 1 || regions[0] != (testRegionCount{Region: "EU", Orders: 2, Total: 15}) {
+		t.Errorf("Expected only EU, got %+v", regions)
+	}
+
+	// Select aliases can be compared too
+	regions, err = Project[testRegionCount](ctx, repo, selectTotals, gpa.GroupBy("region"),
+		gpa.Having("total", gpa.OpLessThan, 20), gpa.Having("COUNT(DISTINCT country)", gpa.OpEqual, 2))
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+	if len(regions) != 2 {
+		t.Errorf("Expected EU and NA, got %+v", regions)
+	}
+
+	for _, field := range []string{"COUNT(*) > 0 OR 1", "SUM(DISTINCT *)", "LENGTH(region)"} {
+		_, err := Project[testRegionCount](ctx, repo, selectTotals, gpa.GroupBy("region"), gpa.Having(field, gpa.OpGreaterThan, 1))
+		if err == nil {
+			t.Errorf("Expected %q to be rejected", field)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package gpagorm provides configurable database health checks
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HealthCheckTarget selects which connections Health probes
+type HealthCheckTarget string
+
+const (
+	// HealthCheckPrimary requires the primary connection to be healthy
+	HealthCheckPrimary HealthCheckTarget = "primary"
+	// HealthCheckAny succeeds when the primary or any replica is healthy
+	HealthCheckAny HealthCheckTarget = "any"
+)
+
+// defaultHealthCheckTimeout is used when no timeout is configured
+const defaultHealthCheckTimeout = 5 * time.Second
+
+// HealthCheckConfig controls how Health probes the database.
+// Configured through Options["gorm"] with the keys "health_check_query",
+// "health_check_timeout" and "health_check_target".
+type HealthCheckConfig struct {
+	Query   string            // Statement to run, e.g. "SELECT 1"; empty uses a driver ping
+	Timeout time.Duration     // Maximum time for a single probe
+	Target  HealthCheckTarget // Which connections must answer
+}
+
+// parseHealthCheckConfig reads the health check settings from the gorm options
+func parseHealthCheckConfig(gormOpts map[string]interface{}) (HealthCheckConfig, error) {
+	cfg := HealthCheckConfig{
+		Timeout: defaultHealthCheckTimeout,
+		Target:  HealthCheckPrimary,
+	}
+
+	if query, ok := gormOpts["health_check_query"].(string); ok {
+		cfg.Query = query
+	}
+
+	switch timeout := gormOpts["health_check_timeout"].(type) {
+	case time.Duration:
+		cfg.Timeout = timeout
+	case string:
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid health_check_timeout: %w", err)
+		}
+		cfg.Timeout = d
+	}
+
+	if target, ok := gormOpts["health_check_target"].(string); ok {
+		switch HealthCheckTarget(target) {
+		case HealthCheckPrimary, HealthCheckAny:
+			cfg.Target = HealthCheckTarget(target)
+		default:
+			return cfg, fmt.Errorf("invalid health_check_target: %s", target)
+		}
+	}
+
+	return cfg, nil
+}
+
+// SetHealthCheck replaces the health check configuration
+func (p *Provider) SetHealthCheck(cfg HealthCheckConfig) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultHealthCheckTimeout
+	}
+	if cfg.Target == "" {
+		cfg.Target = HealthCheckPrimary
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.healthCheck = cfg
+}
+
+// currentHealthCheck returns the health check configuration
+func (p *Provider) currentHealthCheck() HealthCheckConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.healthCheck
+}
+
+// Health checks the database connection health
+func (p *Provider) Health() error {
+	err := p.probe(p.db)
+	if err == nil || p.currentHealthCheck().Target != HealthCheckAny {
+		return err
+	}
+
+	errs := []error{fmt.Errorf("primary: %w", err)}
+	for i, replica := range p.replicaDBs() {
+		replicaErr := p.probe(replica)
+		if replicaErr == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("replica %d: %w", i, replicaErr))
+	}
+	return errors.Join(errs...)
+}
+
+// probe runs the configured health check against a single connection
+func (p *Provider) probe(db *gorm.DB) error {
+	check := p.currentHealthCheck()
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if check.Query != "" {
+		rows, err := db.WithContext(ctx).Raw(check.Query).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+		}
+		return rows.Err()
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	}
+	return sqlDB.PingContext(ctx)
+}
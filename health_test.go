@@ -0,0 +1,112 @@
+package gpagorm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestHealthCheckQuery(t *testing.T) {
+	config := gpa.Config{
+		Driver:   "sqlite",
+		Database: ":memory:",
+		Options: map[string]interface{}{
+			"gorm": map[string]interface{}{
+				"health_check_query":   "SELECT 1",
+				"health_check_timeout": "2s",
+			},
+		},
+	}
+
+	provider, err := NewProvider(config)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	defer provider.Close()
+
+	if provider.healthCheck.Timeout != 2*time.Second {
+		t.Errorf("Expected timeout 2s, got %v", provider.healthCheck.Timeout)
+	}
+
+	if err := provider.Health(); err != nil {
+		t.Errorf("Health check failed: %v", err)
+	}
+
+	provider.SetHealthCheck(HealthCheckConfig{Query: "SELECT * FROM missing_table"})
+	if err := provider.Health(); err == nil {
+		t.Error("Expected health check to fail for invalid query")
+	}
+}
+
+func TestHealthCheckAnyReplica(t *testing.T) {
+	config := gpa.Config{
+		Driver:   "sqlite",
+		Database: ":memory:",
+		Options: map[string]interface{}{
+			"gorm": map[string]interface{}{
+				"replicas":            []string{":memory:"},
+				"health_check_target": "any",
+			},
+		},
+	}
+
+	provider, err := NewProvider(config)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	defer provider.Close()
+
+	if len(provider.replicas) != 1 {
+		t.Fatalf("Expected 1 replica, got %d", len(provider.replicas))
+	}
+
+	// Only the replica can answer this query
+	if err := provider.replicas[0].Exec("CREATE TABLE replica_only (id INTEGER)").Error; err != nil {
+		t.Fatalf("Failed to create replica table: %v", err)
+	}
+	provider.SetHealthCheck(HealthCheckConfig{Query: "SELECT * FROM replica_only", Target: HealthCheckAny})
+	if err := provider.Health(); err != nil {
+		t.Errorf("Expected healthy replica to satisfy check, got %v", err)
+	}
+
+	provider.SetHealthCheck(HealthCheckConfig{Query: "SELECT * FROM replica_only", Target: HealthCheckPrimary})
+	if err := provider.Health(); err == nil {
+		t.Error("Expected primary-only health check to fail")
+	}
+}
+
+func TestHealthCheckInvalidTarget(t *testing.T) {
+	config := gpa.Config{
+		Driver:   "sqlite",
+		Database: ":memory:",
+		Options: map[string]interface{}{
+			"gorm": map[string]interface{}{
+				"health_check_target": "somewhere",
+			},
+		},
+	}
+
+	if _, err := NewProvider(config); err == nil {
+		t.Error("Expected error for invalid health check target")
+	}
+}
+
+func TestSetHealthCheckConcurrentHealth(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			provider.SetHealthCheck(HealthCheckConfig{Query: "SELECT 1", Target: HealthCheckAny})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if err := provider.Health(); err != nil {
+			t.Errorf("Health check failed: %v", err)
+		}
+	}
+	<-done
+}
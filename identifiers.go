@@ -0,0 +1,181 @@
+// Package gpagorm provides identifier validation, quoting and the strict
+// field allow-list of query options
+package gpagorm
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// strictFieldsKey stores the fieldAllowList of a strict repository's
+// query on its settings
+const strictFieldsKey = "gpagorm:strict_fields"
+
+var (
+	// selectAliasPattern matches the alias of a select expression, e.g.
+	// "SUM(amount) AS total"
+	selectAliasPattern = regexp.MustCompile(`(?i)\s+AS\s+([A-Za-z_][A-Za-z0-9_]*)\s*$`)
+	// joinTablePattern matches a joined table, optionally qualified and
+	// followed by an alias, e.g. "analytics.sessions s"
+	joinTablePattern = regexp.MustCompile(`(?i)^([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*)(?:\s+(?:AS\s+)?([A-Za-z_][A-Za-z0-9_]*))?$`)
+)
+
+// fieldAllowList holds the identifiers the options of a strict
+// repository's query may name
+type fieldAllowList struct {
+	schema  *schema.Schema
+	own     map[string]bool // Names the entity's columns may be qualified with
+	joined  map[string]bool // Joined tables and aliases, whose columns are not checked
+	aliases map[string]bool // Select aliases
+}
+
+// WithStrictFields returns a copy of the repository whose query options
+// may only name columns of T, by column or Go field name, optionally
+// qualified by the entity's table; select aliases; and columns of joined
+// tables qualified by the table or its alias. Names are resolved to their
+// column and quoted for the dialect, so conditions, having, ordering and
+// grouping built from API input cannot reach other columns:
+//
+//	users, err := repo.WithStrictFields().Query(ctx, gpa.OrderBy(req.Sort, gpa.OrderAsc))
+//
+// Anything else fails with a FieldValidationError. Without strict fields,
+// names are only checked to be plain identifiers, and quoted.
+func (r *Repository[T]) WithStrictFields() *Repository[T] {
+	repo := r.clone()
+	repo.strictFields = true
+	return repo
+}
+
+// fieldAllowList returns the allow-list of a strict query made of query
+// and opts
+func (r *Repository[T]) fieldAllowList(query *gpa.Query, opts []gpa.QueryOption) (*fieldAllowList, error) {
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+	list := &fieldAllowList{
+		schema:  s,
+		own:     map[string]bool{s.Table: true},
+		joined:  map[string]bool{},
+		aliases: map[string]bool{},
+	}
+	for _, table := range []string{r.table, r.view} {
+		if table != "" {
+			list.own[table] = true
+			list.own[table[strings.LastIndex(table, ".")+1:]] = true
+		}
+	}
+	for _, field := range query.Fields {
+		if m := selectAliasPattern.FindStringSubmatch(field); m != nil {
+			list.aliases[m[1]] = true
+		}
+	}
+	for _, bucket := range timeBucketsFromOptions(opts) {
+		list.aliases[bucket.alias()] = true
+	}
+	for _, join := range query.Joins {
+		if m := joinTablePattern.FindStringSubmatch(join.Table); m != nil {
+			list.joined[m[1]] = true
+			list.joined[m[1][strings.LastIndex(m[1], ".")+1:]] = true
+			if m[2] != "" {
+				list.joined[m[2]] = true
+			}
+		}
+		if join.Alias != "" {
+			list.joined[join.Alias] = true
+		}
+	}
+	return list, nil
+}
+
+// resolve returns field as a quoted column, or an error when the
+// allow-list does not hold it
+func (l *fieldAllowList) resolve(db *gorm.DB, field string) (string, error) {
+	qualifier, name := "", field
+	if i := strings.LastIndex(field, "."); i >= 0 {
+		qualifier, name = field[:i], field[i+1:]
+	}
+
+	switch {
+	case qualifier == "" && l.aliases[name]:
+		// Aliases are left as written, as quoting would make them case sensitive
+		return name, nil
+	case qualifier == "" || l.own[qualifier]:
+		if f := l.schema.LookUpField(name); f != nil && f.DBName != "" {
+			if qualifier == "" {
+				return db.Statement.Quote(f.DBName), nil
+			}
+			return db.Statement.Quote(qualifier + "." + f.DBName), nil
+		}
+	case l.joined[qualifier]:
+		return db.Statement.Quote(field), nil
+	}
+	return "", &FieldValidationError{
+		Field:  field,
+		Reason: "field is not a column of " + l.schema.Name + " or a select alias",
+	}
+}
+
+// fieldExpr validates a field name from query options and returns it as
+// written into SQL: resolved and quoted by the allow-list of a strict
+// repository, otherwise quoted by quoteField
+func fieldExpr(db *gorm.DB, field string) (string, error) {
+	if !isValidFieldName(field) {
+		return "", &FieldValidationError{
+			Field:  field,
+			Reason: "field name contains invalid characters or doesn't follow naming rules",
+		}
+	}
+	if value, ok := db.Get(strictFieldsKey); ok {
+		return value.(*fieldAllowList).resolve(db, field)
+	}
+	return quoteField(db, field), nil
+}
+
+// orderDirection validates the direction of an ordering, defaulting to
+// ascending
+func orderDirection(direction gpa.OrderDirection) (string, error) {
+	switch d := strings.ToUpper(string(direction)); d {
+	case "":
+		return string(gpa.OrderAsc), nil
+	case string(gpa.OrderAsc), string(gpa.OrderDesc):
+		return d, nil
+	default:
+		return "", gpa.NewError(gpa.ErrorTypeInvalidArgument, "invalid order direction: "+string(direction))
+	}
+}
+
+// joinSQL validates a join's type, table and alias and returns the join
+// clause. The ON condition is SQL written by the caller and is not checked.
+func joinSQL(db *gorm.DB, join gpa.JoinClause) (string, error) {
+	switch join.Type {
+	case gpa.JoinInner, gpa.JoinLeft, gpa.JoinRight, gpa.JoinFull:
+	default:
+		return "", gpa.NewError(gpa.ErrorTypeInvalidArgument, "invalid join type: "+string(join.Type))
+	}
+	if !joinTablePattern.MatchString(join.Table) {
+		return "", &FieldValidationError{
+			Field:  join.Table,
+			Reason: "join table contains invalid characters or doesn't follow naming rules",
+		}
+	}
+	if join.Alias != "" && (!isValidFieldName(join.Alias) || strings.Contains(join.Alias, ".")) {
+		return "", &FieldValidationError{
+			Field:  join.Alias,
+			Reason: "join alias must be a plain identifier",
+		}
+	}
+
+	sql := string(join.Type) + " JOIN " + quoteTable(db, join.Table)
+	if join.Alias != "" {
+		sql += " AS " + join.Alias
+	}
+	if join.Condition != "" {
+		sql += " ON " + join.Condition
+	}
+	return sql, nil
+}
@@ -0,0 +1,86 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+func TestQueryIdentifierValidation(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		opt  gpa.QueryOption
+	}{
+		{"order field", gpa.OrderBy("name; DROP TABLE test_users", gpa.OrderAsc)},
+		{"order direction", gpa.OrderBy("name", "ASC; DROP TABLE test_users")},
+		{"group", gpa.GroupBy("age) UNION SELECT password FROM secrets --")},
+		{"join type", gpa.Join("CROSS JOIN secrets;", "orders", "orders.user_id = test_users.id")},
+		{"join table", gpa.Join(gpa.JoinInner, "orders; DROP TABLE test_users", "orders.user_id = test_users.id")},
+		{"join alias", gpa.Join(gpa.JoinInner, "orders", "o.user_id = test_users.id", "o;")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := repo.Query(ctx, tt.opt); err == nil {
+				t.Error("Expected the option to be rejected")
+			}
+		})
+	}
+
+	if users, err := repo.Query(ctx, gpa.OrderBy("name", "desc")); err != nil {
+		t.Errorf("Expected a lower-case direction to be accepted, got %v (%v)", users, err)
+	}
+}
+
+func TestWithStrictFields(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	db := provider.db.Session(&gorm.Session{DryRun: true})
+	repo := NewRepository[TestUser](db, provider).WithStrictFields()
+	ctx := context.Background()
+
+	sql := func(opts ...gpa.QueryOption) (string, error) {
+		var users []*TestUser
+		stmt := repo.buildQuery(ctx, opts...).Find(&users).Statement
+		return stmt.SQL.String(), stmt.Error
+	}
+
+	// Go field names resolve to their quoted column
+	got, err := sql(gpa.Where("Name", gpa.OpEqual, "Ann"), gpa.OrderBy("test_users.age", gpa.OrderDesc))
+	if want := "SELECT * FROM `test_users` WHERE `name` = ? ORDER BY `test_users`.`age` DESC"; err != nil || got != want {
+		t.Errorf("Expected %q, got %q (%v)", want, got, err)
+	}
+
+	// Select aliases and columns of joined tables are accepted
+	got, err = sql(gpa.Select("age", "COUNT(*) AS users"), gpa.Join(gpa.JoinLeft, "orders o", "o.user_id = test_users.id"),
+		gpa.GroupBy("age"), gpa.Having("users", gpa.OpGreaterThan, 1), gpa.Where("o.total", gpa.OpGreaterThan, 10))
+	if want := "SELECT `age`,COUNT(*) AS users FROM `test_users` LEFT JOIN orders o ON o.user_id = test_users.id WHERE `o`.`total` > ? GROUP BY `age` HAVING users > ?"; err != nil || got != want {
+		t.Errorf("Expected %q, got %q (%v)", want, got, err)
+	}
+
+	for _, opt := range []gpa.QueryOption{
+		gpa.Where("password", gpa.OpEqual, "x"),
+		gpa.OrderBy("secrets.token", gpa.OrderAsc),
+		gpa.GroupBy("password"),
+		gpa.Having("SUM(salary)", gpa.OpGreaterThan, 1),
+		WhereTuple([]string{"age", "salary"}, gpa.OpGreaterThan, []interface{}{1, 2}),
+		JSONExtract("metadata", "$.plan", gpa.OpEqual, "pro"),
+	} {
+		var fieldErr *FieldValidationError
+		if _, err := sql(opt); !errors.As(err, &fieldErr) {
+			t.Errorf("Expected a field validation error for %+v, got %v", opt, err)
+		}
+	}
+
+	// The strict mode carries over to derived repositories
+	if !repo.WithComment("job").WithTable("test_users").strictFields {
+		t.Error("Expected derived repositories to keep strict fields")
+	}
+}
@@ -0,0 +1,209 @@
+// Package gpagorm provides a development-mode missing-index advisor
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultIndexAdvisorMinRows is the table size below which scans are ignored
+const defaultIndexAdvisorMinRows = 1000
+
+// IndexSuggestion describes a query that scanned a large table sequentially
+type IndexSuggestion struct {
+	Table     string   // Scanned table
+	Columns   []string // Candidate index columns from WHERE, then ORDER BY
+	Rows      int64    // Table size when the scan was observed
+	Query     string   // Normalized statement
+	Plan      []string // Plan that showed the scan
+	Statement string   // Suggested CREATE INDEX statement
+}
+
+// indexAdvisor explains each distinct query once and records sequential scans
+type indexAdvisor struct {
+	minRows int64
+
+	mu          sync.Mutex
+	seen        map[string]bool
+	suggestions []IndexSuggestion
+}
+
+// newIndexAdvisor reads the advisor settings from the gorm options.
+// Configured with "index_advisor" and "index_advisor_min_rows".
+func newIndexAdvisor(gormOpts map[string]interface{}) *indexAdvisor {
+	if enabled, ok := gormOpts["index_advisor"].(bool); !ok || !enabled {
+		return nil
+	}
+	advisor := &indexAdvisor{minRows: defaultIndexAdvisorMinRows, seen: make(map[string]bool)}
+	switch minRows := gormOpts["index_advisor_min_rows"].(type) {
+	case int:
+		advisor.minRows = int64(minRows)
+	case int64:
+		advisor.minRows = minRows
+	}
+	return advisor
+}
+
+// register installs the advisor after GORM's query callback
+func (a *indexAdvisor) register(db *gorm.DB) error {
+	return db.Callback().Query().After("gorm:query").Register("gpagorm:index_advisor", a.analyze)
+}
+
+// analyze explains a finished query the first time its shape is seen
+func (a *indexAdvisor) analyze(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || db.DryRun || stmt.Table == "" || stmt.SQL.Len() == 0 {
+		return
+	}
+
+	query := NormalizeQuery(stmt.SQL.String())
+	fingerprint := QueryFingerprint(query)
+	a.mu.Lock()
+	if a.seen[fingerprint] {
+		a.mu.Unlock()
+		return
+	}
+	a.seen[fingerprint] = true
+	a.mu.Unlock()
+
+	// Reuse the statement's connection so transactions and in-memory databases see the same data
+	session := db.Session(&gorm.Session{NewDB: true, Context: stmt.Context})
+	dialect := dialectName(db)
+	plan, err := explainStatement(session, stmt.SQL.String(), stmt.Vars)
+	if err != nil || !planScansTable(dialect, plan, stmt.Table) {
+		return
+	}
+
+	var rows int64
+	if err := session.Raw("SELECT COUNT(*) FROM " + stmt.Quote(stmt.Table)).Scan(&rows).Error; err != nil || rows < a.minRows {
+		return
+	}
+
+	suggestion := IndexSuggestion{
+		Table:   stmt.Table,
+		Columns: candidateIndexColumns(stmt),
+		Rows:    rows,
+		Query:   query,
+		Plan:    plan,
+	}
+	if len(suggestion.Columns) > 0 {
+		suggestion.Statement = fmt.Sprintf("CREATE INDEX idx_%s_%s ON %s (%s)",
+			stmt.Table, strings.Join(suggestion.Columns, "_"), stmt.Table, strings.Join(suggestion.Columns, ", "))
+	}
+
+	a.mu.Lock()
+	a.suggestions = append(a.suggestions, suggestion)
+	a.mu.Unlock()
+
+	slog.Default().LogAttrs(context.Background(), slog.LevelWarn, "gpagorm: sequential scan on large table",
+		slog.String("table", suggestion.Table),
+		slog.Int64("rows", suggestion.Rows),
+		slog.String("query", suggestion.Query),
+		slog.String("suggestion", suggestion.Statement),
+	)
+}
+
+// IndexSuggestions returns the sequential scans found by the index advisor.
+// The advisor is enabled with the "index_advisor" gorm option and is meant
+// for development, since it runs EXPLAIN for every new query shape.
+func (p *Provider) IndexSuggestions() []IndexSuggestion {
+	if p.indexAdvisor == nil {
+		return nil
+	}
+	p.indexAdvisor.mu.Lock()
+	defer p.indexAdvisor.mu.Unlock()
+	return append([]IndexSuggestion(nil), p.indexAdvisor.suggestions...)
+}
+
+var predicateColumnPattern = regexp.MustCompile(`(?i)([A-Za-z_][\w."` + "`" + `\[\]]*)\s*(?:=|!=|<>|<=|>=|<|>|\s(?:NOT\s+)?IN\b|\s(?:NOT\s+)?LIKE\b|\sBETWEEN\b|\sIS\b)`)
+
+// candidateIndexColumns collects the WHERE columns, then the ORDER BY columns
+func candidateIndexColumns(stmt *gorm.Statement) []string {
+	var columns []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		name = strings.Trim(name[strings.LastIndex(name, ".")+1:], "\"`[]")
+		if name == "" || seen[strings.ToLower(name)] {
+			return
+		}
+		seen[strings.ToLower(name)] = true
+		columns = append(columns, name)
+	}
+
+	if where, ok := stmt.Clauses["WHERE"].Expression.(clause.Where); ok {
+		for _, expr := range where.Exprs {
+			collectExprColumns(expr, add)
+		}
+	}
+	if orderBy, ok := stmt.Clauses["ORDER BY"].Expression.(clause.OrderBy); ok {
+		for _, column := range orderBy.Columns {
+			if fields := strings.Fields(column.Column.Name); len(fields) > 0 {
+				add(fields[0])
+			}
+		}
+	}
+	return columns
+}
+
+// collectExprColumns walks a WHERE expression and reports referenced columns
+func collectExprColumns(expr clause.Expression, add func(string)) {
+	switch e := expr.(type) {
+	case clause.Expr:
+		for _, match := range predicateColumnPattern.FindAllStringSubmatch(e.SQL, -1) {
+			switch strings.ToUpper(match[1]) {
+			case "AND", "OR", "NOT":
+				continue
+			}
+			add(match[1])
+		}
+	case clause.NamedExpr:
+		collectExprColumns(clause.Expr{SQL: e.SQL}, add)
+	case clause.Eq:
+		add(columnName(e.Column))
+	case clause.Neq:
+		add(columnName(e.Column))
+	case clause.Gt:
+		add(columnName(e.Column))
+	case clause.Gte:
+		add(columnName(e.Column))
+	case clause.Lt:
+		add(columnName(e.Column))
+	case clause.Lte:
+		add(columnName(e.Column))
+	case clause.IN:
+		add(columnName(e.Column))
+	case clause.Like:
+		add(columnName(e.Column))
+	case clause.AndConditions:
+		for _, sub := range e.Exprs {
+			collectExprColumns(sub, add)
+		}
+	case clause.OrConditions:
+		for _, sub := range e.Exprs {
+			collectExprColumns(sub, add)
+		}
+	case clause.NotConditions:
+		for _, sub := range e.Exprs {
+			collectExprColumns(sub, add)
+		}
+	}
+}
+
+// columnName returns the name of a clause column reference
+func columnName(column interface{}) string {
+	switch c := column.(type) {
+	case clause.Column:
+		return c.Name
+	case string:
+		return c
+	default:
+		return ""
+	}
+}
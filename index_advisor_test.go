@@ -0,0 +1,81 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestRepositoryExplain(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	plan, err := repo.Explain(context.Background(), gpa.Where("email", gpa.OpEqual, "a@example.com"))
+	if err != nil {
+		t.Fatalf("Failed to explain: %v", err)
+	}
+	if len(plan) == 0 || !strings.Contains(strings.Join(plan, "\n"), "idx_test_users_email") {
+		t.Errorf("Expected plan to use the email index, got %v", plan)
+	}
+}
+
+func TestIndexAdvisor(t *testing.T) {
+	provider, err := NewProvider(gpa.Config{
+		Driver:   "sqlite",
+		Database: ":memory:",
+		Options: map[string]interface{}{
+			"gorm": map[string]interface{}{
+				"log_level":              "silent",
+				"index_advisor":          true,
+				"index_advisor_min_rows": 10,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	defer provider.Close()
+
+	if err := provider.db.AutoMigrate(&TestUser{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+	users := make([]*TestUser, 0, 20)
+	for i := 0; i < 20; i++ {
+		users = append(users, &TestUser{Name: fmt.Sprintf("user%d", i), Email: fmt.Sprintf("user%d@example.com", i), Age: i})
+	}
+	if err := repo.CreateBatch(ctx, users); err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+
+	if _, err := repo.Query(ctx, gpa.Where("email", gpa.OpEqual, "user1@example.com")); err != nil {
+		t.Fatalf("Failed to query by email: %v", err)
+	}
+	if len(provider.IndexSuggestions()) != 0 {
+		t.Fatalf("Expected no suggestions for an indexed lookup, got %+v", provider.IndexSuggestions())
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := repo.Query(ctx, gpa.Where("age", gpa.OpGreaterThan, 5+i), gpa.OrderBy("name", gpa.OrderDesc)); err != nil {
+			t.Fatalf("Failed to query by age: %v", err)
+		}
+	}
+
+	suggestions := provider.IndexSuggestions()
+	if len(suggestions) != 1 {
+		t.Fatalf("Expected one suggestion, got %+v", suggestions)
+	}
+	s := suggestions[0]
+	if s.Table != "test_users" || s.Rows != 20 || strings.Join(s.Columns, ",") != "age,name" {
+		t.Errorf("Unexpected suggestion: %+v", s)
+	}
+	if s.Statement != "CREATE INDEX idx_test_users_age_name ON test_users (age, name)" {
+		t.Errorf("Unexpected statement: %s", s.Statement)
+	}
+}
@@ -0,0 +1,249 @@
+// Package gpagorm provides index creation by name and column set, and
+// listing of a table's indexes
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// indexFieldPattern matches an index field: a column or Go field name,
+// an optional prefix length and an optional direction, e.g. "title(20) DESC"
+var indexFieldPattern = regexp.MustCompile(`(?i)^([A-Za-z_][A-Za-z0-9_]*)(?:\((\d+)\))?(?:\s+(ASC|DESC))?$`)
+
+// IndexSpec describes an index for CreateIndexSpec
+type IndexSpec struct {
+	Name string // Index name; idx_<table>_<columns> when empty
+	// Columns, by column or Go field name, in index order. Each may be
+	// followed by a prefix length, on MySQL, and a direction:
+	// "title(20)", "created_at DESC"
+	Fields []string
+	// Columns stored in the index without being keyed on, so queries
+	// reading only them are answered from the index. Postgres and SQL
+	// Server only.
+	Include []string
+	Unique  bool
+}
+
+// indexColumn is a parsed index field
+type indexColumn struct {
+	name   string
+	length int // Prefix length, 0 for the whole column
+	desc   bool
+}
+
+// IndexExistsError reports that the index CreateIndexSpec was asked for,
+// or an equivalent one, already exists. It is the cause of the
+// gpa.ErrorTypeDuplicate error returned.
+type IndexExistsError struct {
+	Requested string        // Name of the requested index
+	Existing  gpa.IndexInfo // The index found in the database
+}
+
+// Error implements the error interface
+func (e *IndexExistsError) Error() string {
+	columns := strings.Join(e.Existing.Fields, ", ")
+	if e.Existing.Name == e.Requested {
+		return fmt.Sprintf("index %s on (%s) already exists", e.Requested, columns)
+	}
+	return fmt.Sprintf("index %s is equivalent to existing index %s on (%s)", e.Requested, e.Existing.Name, columns)
+}
+
+// CreateIndexSpec creates the index spec describes. It fails with a
+// gpa.ErrorTypeDuplicate error, caused by an IndexExistsError naming the
+// existing index, when an index of that name exists or when an index on
+// the same columns in the same order does, whatever its name, such as one
+// declared in struct tags. A unique index is equivalent to a requested
+// non-unique one, but not the reverse; directions, prefix lengths and
+// included columns are not compared.
+//
+//	err := repo.CreateIndexSpec(ctx, gpagorm.IndexSpec{
+//	    Name:    "orders_by_customer",
+//	    Fields:  []string{"customer_id", "created_at DESC"},
+//	    Include: []string{"total"},
+//	})
+//	var exists *gpagorm.IndexExistsError
+//	if errors.As(err, &exists) {
+//	    log.Printf("using existing index %s", exists.Existing.Name)
+//	}
+//
+// With a name and no fields, the index of that name declared in struct
+// tags is created.
+func (r *Repository[T]) CreateIndexSpec(ctx context.Context, spec IndexSpec) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationCreateIndex, Entity: spec}, func(ctx context.Context) error {
+		return r.createIndexSpec(ctx, spec)
+	})
+}
+
+// createIndexSpec implements CreateIndexSpec
+func (r *Repository[T]) createIndexSpec(ctx context.Context, spec IndexSpec) error {
+	s, err := r.schema()
+	if err != nil {
+		return err
+	}
+	var declared *schema.Index
+	if len(spec.Fields) == 0 {
+		if declared = s.LookIndex(spec.Name); declared == nil {
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "index needs fields or the name of an index declared on "+s.Name)
+		}
+		spec.Name = declared.Name
+		for _, option := range declared.Fields {
+			spec.Fields = append(spec.Fields, option.DBName)
+		}
+		spec.Unique = declared.Class == "UNIQUE"
+	}
+	keys, err := indexColumns(s, spec.Fields)
+	if err != nil {
+		return err
+	}
+	include, err := indexColumns(s, spec.Include)
+	if err != nil {
+		return err
+	}
+	columns := make([]string, len(keys))
+	for i, key := range keys {
+		columns[i] = key.name
+	}
+	if spec.Name == "" {
+		spec.Name = "idx_" + s.Table + "_" + strings.Join(columns, "_")
+	}
+	if !isValidFieldName(spec.Name) || strings.Contains(spec.Name, ".") {
+		return &FieldValidationError{Field: spec.Name, Reason: "index name must be a plain identifier"}
+	}
+
+	table := s.Table
+	if r.table != "" {
+		table = r.table
+	}
+	var zero T
+	err = r.provider.runDDL(ctx, r.db, func(db *gorm.DB) error {
+		migrator := withQualifiedTable(db, table).Migrator()
+		existing, err := migrator.GetIndexes(&zero)
+		if err != nil {
+			return err
+		}
+		for _, index := range existing {
+			info := indexInfo(index)
+			if info.Name == spec.Name || (slices.Equal(info.Fields, columns) && (info.IsUnique || !spec.Unique)) {
+				return gpa.NewErrorWithCause(gpa.ErrorTypeDuplicate, "index already exists: "+spec.Name,
+					&IndexExistsError{Requested: spec.Name, Existing: info})
+			}
+		}
+
+		if declared != nil {
+			return migrator.CreateIndex(&zero, declared.Name)
+		}
+		sql, err := createIndexSQL(db, table, spec.Name, keys, include, spec.Unique)
+		if err != nil {
+			return err
+		}
+		return db.Exec(sql).Error
+	})
+	return convertGormError(err)
+}
+
+// createIndexSQL returns the CREATE INDEX statement of an index on table
+// keyed on keys and including include
+func createIndexSQL(db *gorm.DB, table, name string, keys, include []indexColumn, unique bool) (string, error) {
+	dialect := dialectName(db)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = db.Statement.Quote(key.name)
+		if key.length > 0 {
+			if dialect != "mysql" {
+				return "", gpa.NewError(gpa.ErrorTypeUnsupported, "index prefix lengths are not supported on "+dialect)
+			}
+			parts[i] += "(" + strconv.Itoa(key.length) + ")"
+		}
+		if key.desc {
+			parts[i] += " DESC"
+		}
+	}
+
+	sql := "CREATE INDEX "
+	if unique {
+		sql = "CREATE UNIQUE INDEX "
+	}
+	sql += db.Statement.Quote(name) + " ON " + db.Statement.Quote(table) + " (" + strings.Join(parts, ", ") + ")"
+	if len(include) > 0 {
+		if dialect != "postgres" && dialect != "sqlserver" {
+			return "", gpa.NewError(gpa.ErrorTypeUnsupported, "included index columns are not supported on "+dialect)
+		}
+		quoted := make([]string, len(include))
+		for i, column := range include {
+			if column.length > 0 || column.desc {
+				return "", &FieldValidationError{Field: column.name, Reason: "included columns take no length or direction"}
+			}
+			quoted[i] = db.Statement.Quote(column.name)
+		}
+		sql += " INCLUDE (" + strings.Join(quoted, ", ") + ")"
+	}
+	return sql, nil
+}
+
+// Indexes returns the indexes of the table of T, or of the WithTable
+// override, as they exist in the database, including the primary key's
+// where the database reports it
+func (r *Repository[T]) Indexes(ctx context.Context) ([]gpa.IndexInfo, error) {
+	db := r.db.WithContext(ctx)
+	if r.table != "" {
+		db = withQualifiedTable(db, r.table)
+	}
+	var zero T
+	indexes, err := db.Migrator().GetIndexes(&zero)
+	if err != nil {
+		return nil, convertGormError(err)
+	}
+	infos := make([]gpa.IndexInfo, 0, len(indexes))
+	for _, index := range indexes {
+		infos = append(infos, indexInfo(index))
+	}
+	return infos, nil
+}
+
+// indexInfo describes a database index
+func indexInfo(index gorm.Index) gpa.IndexInfo {
+	info := gpa.IndexInfo{Name: index.Name(), Fields: index.Columns(), Type: gpa.IndexTypeStandard}
+	info.IsUnique, _ = index.Unique()
+	primary, _ := index.PrimaryKey()
+	switch {
+	case primary:
+		info.IsUnique = true
+		info.Type = gpa.IndexTypePrimary
+	case info.IsUnique:
+		info.Type = gpa.IndexTypeUnique
+	case len(info.Fields) > 1:
+		info.Type = gpa.IndexTypeComposite
+	}
+	return info
+}
+
+// indexColumns parses index fields and resolves them to columns of s
+func indexColumns(s *schema.Schema, fields []string) ([]indexColumn, error) {
+	columns := make([]indexColumn, len(fields))
+	for i, spec := range fields {
+		m := indexFieldPattern.FindStringSubmatch(strings.TrimSpace(spec))
+		if m == nil {
+			return nil, &FieldValidationError{Field: spec, Reason: "index field must be a column with an optional prefix length and direction"}
+		}
+		field := s.LookUpField(m[1])
+		if field == nil || field.DBName == "" {
+			return nil, &FieldValidationError{Field: m[1], Reason: "field is not a column of " + s.Name}
+		}
+		columns[i] = indexColumn{name: field.DBName, desc: strings.EqualFold(m[3], "DESC")}
+		if m[2] != "" {
+			if columns[i].length, _ = strconv.Atoi(m[2]); columns[i].length <= 0 {
+				return nil, &FieldValidationError{Field: spec, Reason: "index prefix length must be positive"}
+			}
+		}
+	}
+	return columns, nil
+}
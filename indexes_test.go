@@ -0,0 +1,202 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+)
+
+type testIndexedOrder struct {
+	ID         uint
+	CustomerID uint   `gorm:"index:orders_by_customer"`
+	Status     string `gorm:"index:orders_by_status,class:UNIQUE"`
+	Total      int
+}
+
+func TestCreateIndexSpec(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[testIndexedOrder](provider.db, provider)
+	ctx := context.Background()
+	if err := repo.CreateTable(ctx); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	// The generated name of the plain API
+	if err := repo.CreateIndex(ctx, []string{"Total"}, false); err != nil {
+		t.Fatalf("CreateIndex failed: %v", err)
+	}
+	if err := repo.CreateIndex(ctx, []string{"total"}, false); !gpa.IsErrorType(err, gpa.ErrorTypeDuplicate) {
+		t.Errorf("Expected a duplicate index error, got %v", err)
+	}
+
+	// An index on the columns of a tag-declared index exists under its name
+	err := repo.CreateIndexSpec(ctx, IndexSpec{Name: "customer_lookup", Fields: []string{"customer_id"}})
+	var exists *IndexExistsError
+	if !errors.As(err, &exists) || exists.Existing.Name != "orders_by_customer" {
+		t.Errorf("Expected the tag-declared index to be reported, got %v", err)
+	}
+
+	if err := repo.CreateIndexSpec(ctx, IndexSpec{Name: "orders_by_total_customer", Fields: []string{"total", "customer_id"}}); err != nil {
+		t.Fatalf("CreateIndexSpec failed: %v", err)
+	}
+	// A name in use fails, whatever its columns
+	err = repo.CreateIndexSpec(ctx, IndexSpec{Name: "orders_by_total_customer", Fields: []string{"status"}})
+	if !errors.As(err, &exists) || exists.Existing.Name != "orders_by_total_customer" {
+		t.Errorf("Expected the index of that name to be reported, got %v", err)
+	}
+	// A unique index is not served by a non-unique one on the same columns
+	if err := repo.CreateIndexSpec(ctx, IndexSpec{Name: "orders_total_unique", Fields: []string{"total", "customer_id"}, Unique: true}); err != nil {
+		t.Errorf("Expected a unique index to be created, got %v", err)
+	}
+
+	// A tag-declared index dropped and created again by name
+	if err := repo.DropIndex(ctx, "orders_by_status"); err != nil {
+		t.Fatalf("DropIndex failed: %v", err)
+	}
+	if err := repo.CreateIndexSpec(ctx, IndexSpec{Name: "orders_by_status"}); err != nil {
+		t.Errorf("Expected the declared index to be created, got %v", err)
+	}
+
+	indexes, err := repo.Indexes(ctx)
+	if err != nil {
+		t.Fatalf("Indexes failed: %v", err)
+	}
+	found := map[string]gpa.IndexInfo{}
+	for _, index := range indexes {
+		found[index.Name] = index
+	}
+	if index := found["orders_by_status"]; !index.IsUnique || index.Type != gpa.IndexTypeUnique {
+		t.Errorf("Expected orders_by_status to be unique, got %+v", index)
+	}
+	if index := found["orders_by_total_customer"]; index.Type != gpa.IndexTypeComposite || len(index.Fields) != 2 {
+		t.Errorf("Expected a composite index, got %+v", index)
+	}
+	if info, err := repo.GetTableInfo(ctx); err != nil || len(info.Indexes) != len(indexes) {
+		t.Errorf("Expected the table info to list the indexes, got %+v (%v)", info.Indexes, err)
+	}
+
+	for _, spec := range []IndexSpec{
+		{Fields: []string{"missing"}},
+		{Name: "bad; name", Fields: []string{"total"}},
+		{Name: "undeclared"},
+	} {
+		if err := repo.CreateIndexSpec(ctx, spec); err == nil {
+			t.Errorf("Expected %+v to be rejected", spec)
+		}
+	}
+}
+
+func TestCreateIndexFieldSpecs(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[testIndexedOrder](provider.db, provider)
+	ctx := context.Background()
+	if err := repo.CreateTable(ctx); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	if err := repo.CreateIndex(ctx, []string{"CustomerID", "total desc"}, false); err != nil {
+		t.Fatalf("CreateIndex failed: %v", err)
+	}
+	if indexes, err := repo.Indexes(ctx); err != nil || !slices.ContainsFunc(indexes, func(index gpa.IndexInfo) bool {
+		return index.Name == "idx_test_indexed_orders_customer_id_total"
+	}) {
+		t.Errorf("Expected the index named after its columns, got %+v (%v)", indexes, err)
+	}
+	if err := repo.CreateIndex(ctx, []string{"total(10)"}, false); !gpa.IsErrorType(err, gpa.ErrorTypeUnsupported) {
+		t.Errorf("Expected prefix lengths to be unsupported on SQLite, got %v", err)
+	}
+	err := repo.CreateIndexSpec(ctx, IndexSpec{Fields: []string{"total"}, Include: []string{"status"}})
+	if !gpa.IsErrorType(err, gpa.ErrorTypeUnsupported) {
+		t.Errorf("Expected included columns to be unsupported on SQLite, got %v", err)
+	}
+	for _, field := range []string{"total DESCENDING", "total(0)", "total; DROP TABLE x"} {
+		if err := repo.CreateIndex(ctx, []string{field}, false); err == nil {
+			t.Errorf("Expected %q to be rejected", field)
+		}
+	}
+}
+
+func TestCreateIndexSQL(t *testing.T) {
+	dialectors := map[string]gorm.Dialector{
+		"postgres":  postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}),
+		"mysql":     mysql.New(mysql.Config{DSN: "user@tcp(localhost)/test", SkipInitializeWithVersion: true}),
+		"sqlserver": sqlserver.Open("sqlserver://localhost?database=test"),
+	}
+	keys := []indexColumn{{name: "customer_id"}, {name: "created_at", desc: true}}
+	include := []indexColumn{{name: "total"}}
+	tests := []struct {
+		dialect       string
+		keys, include []indexColumn
+		want          string
+	}{
+		{"postgres", keys, include, `CREATE INDEX "orders_recent" ON "orders" ("customer_id", "created_at" DESC) INCLUDE ("total")`},
+		{"sqlserver", keys, include, `CREATE INDEX "orders_recent" ON "orders" ("customer_id", "created_at" DESC) INCLUDE ("total")`},
+		{"mysql", []indexColumn{{name: "title", length: 20}, {name: "created_at", desc: true}}, nil, "CREATE INDEX `orders_recent` ON `orders` (`title`(20), `created_at` DESC)"},
+		{"mysql", keys, include, ""},
+		{"postgres", []indexColumn{{name: "title", length: 20}}, nil, ""},
+	}
+	for _, tt := range tests {
+		db, err := gorm.Open(dialectors[tt.dialect], &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+		if err != nil {
+			t.Fatalf("Failed to open dry-run %s: %v", tt.dialect, err)
+		}
+		sql, err := createIndexSQL(db, "orders", "orders_recent", tt.keys, tt.include, false)
+		if tt.want == "" {
+			if !gpa.IsErrorType(err, gpa.ErrorTypeUnsupported) {
+				t.Errorf("%s: expected an unsupported error, got %q (%v)", tt.dialect, sql, err)
+			}
+			continue
+		}
+		if err != nil || sql != tt.want {
+			t.Errorf("%s: expected %s, got %s (%v)", tt.dialect, tt.want, sql, err)
+		}
+	}
+}
+
+func TestIndexesOfTableOverride(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	archive := NewRepository[testIndexedOrder](provider.db, provider).WithTable("archived_orders")
+	if err := archive.CreateTable(ctx); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	if err := archive.CreateIndexSpec(ctx, IndexSpec{Name: "archived_by_total", Fields: []string{"total"}}); err != nil {
+		t.Fatalf("CreateIndexSpec failed: %v", err)
+	}
+	if err := archive.DropIndex(ctx, "orders_by_status"); err != nil {
+		t.Fatalf("DropIndex failed: %v", err)
+	}
+	if err := archive.CreateIndexSpec(ctx, IndexSpec{Name: "orders_by_status"}); err != nil {
+		t.Errorf("Expected the declared index to be created on the override table, got %v", err)
+	}
+	if err := archive.CreateIndexSpec(ctx, IndexSpec{Fields: []string{"customer_id"}}); !gpa.IsErrorType(err, gpa.ErrorTypeDuplicate) {
+		t.Errorf("Expected the existing index of the override table to be found, got %v", err)
+	}
+
+	indexes, err := archive.Indexes(ctx)
+	names := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		names = append(names, index.Name)
+	}
+	slices.Sort(names)
+	if want := []string{"archived_by_total", "orders_by_customer", "orders_by_status"}; err != nil || !slices.Equal(names, want) {
+		t.Errorf("Expected indexes %v, got %v (%v)", want, names, err)
+	}
+
+	// Without the index list, the table info falls back to the schema
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if info, err := archive.GetTableInfo(cancelled); err != nil || len(info.Columns) != 4 || len(info.Indexes) != 0 {
+		t.Errorf("Expected the schema-derived table info, got %+v (%v)", info, err)
+	}
+}
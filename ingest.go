@@ -0,0 +1,175 @@
+// Package gpagorm provides duplicate-safe ingestion helpers
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// CreateIgnoreDuplicates inserts entities, silently skipping rows that
+// violate a unique constraint (ON CONFLICT DO NOTHING on Postgres and
+// SQLite, a no-op ON DUPLICATE KEY UPDATE on MySQL). It returns the number
+// of rows actually inserted. A batch in which some rows conflict is rolled
+// back to a savepoint and inserted one row at a time, so inserted rows get
+// their keys and skipped rows keep theirs.
+func (r *Repository[T]) CreateIgnoreDuplicates(ctx context.Context, entities []*T) (inserted int64, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationCreateBatch, Entity: entities}, func(ctx context.Context) error {
+		var err error
+		inserted, err = r.createIgnoreDuplicates(ctx, entities)
+		return err
+	})
+	return inserted, err
+}
+
+// createIgnoreDuplicates implements CreateIgnoreDuplicates
+func (r *Repository[T]) createIgnoreDuplicates(ctx context.Context, entities []*T) (int64, error) {
+	if len(entities) == 0 {
+		return 0, nil
+	}
+	if err := r.prepareCreate(ctx, entities); err != nil {
+		return 0, err
+	}
+
+	s, err := r.schema()
+	if err != nil {
+		return 0, err
+	}
+
+	var inserted []*T
+	err = r.session(ctx).Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(entities); start += createBatchSize {
+			batch := entities[start:min(start+createBatchSize, len(entities))]
+			written, err := insertIgnoringDuplicates(ctx, tx, s, batch)
+			if err != nil {
+				return err
+			}
+			inserted = append(inserted, written...)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, convertGormError(err)
+	}
+
+	r.finishCreate(ctx, inserted)
+	return int64(len(inserted)), nil
+}
+
+// ignoreConflicts is ON CONFLICT DO NOTHING for a multi-row insert with
+// RETURNING. GORM counts the rows returned for a clause.OnConflict DO
+// NOTHING by slice position, passing over entities whose primary key was
+// set beforehand as if they had been inserted; wrapped, every returned row
+// is counted once.
+type ignoreConflicts struct {
+	clause.OnConflict
+}
+
+// MergeClause keeps the wrapper as the clause's expression
+func (c ignoreConflicts) MergeClause(cl *clause.Clause) {
+	cl.Expression = c
+}
+
+// insertIgnoringDuplicates inserts batch with ON CONFLICT DO NOTHING and
+// returns the rows it inserted. The keys read back by a multi-row insert
+// are filled in by position, so when some rows were skipped the insert is
+// rolled back to a savepoint and repeated one row at a time.
+func insertIgnoringDuplicates[T any](ctx context.Context, tx *gorm.DB, s *schema.Schema, batch []*T) ([]*T, error) {
+	var pk []*schema.Field
+	if s.PrioritizedPrimaryField != nil {
+		pk = append(pk, s.PrioritizedPrimaryField)
+	}
+	onConflict := clause.OnConflict{DoNothing: true}
+
+	var ignore clause.Expression = onConflict
+	switch dialectName(tx) {
+	case "postgres", "sqlite":
+		ignore = ignoreConflicts{onConflict}
+	}
+
+	name := fmt.Sprintf("gpagorm_sp_%d", savepointSeq.Add(1))
+	if err := tx.SavePoint(name).Error; err != nil {
+		return nil, err
+	}
+	saved := saveFields(ctx, pk, batch)
+	result := tx.Clauses(ignore).Create(batch)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == int64(len(batch)) {
+		return batch, releaseSavepoint(tx, name)
+	}
+
+	if err := tx.RollbackTo(name).Error; err != nil {
+		return nil, err
+	}
+	restoreFields(ctx, pk, batch, saved)
+	var inserted []*T
+	for _, entity := range batch {
+		result := tx.Clauses(onConflict).Create(entity)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			inserted = append(inserted, entity)
+		}
+	}
+	return inserted, releaseSavepoint(tx, name)
+}
+
+// InsertMissing inserts the entities whose conflictColumns values are not
+// yet present and returns the newly inserted ones. Rows are inserted one
+// statement at a time inside a single transaction so each row's outcome
+// is known; existing rows are left untouched. Empty conflictColumns falls
+// back to any unique constraint.
+func (r *Repository[T]) InsertMissing(ctx context.Context, entities []*T, conflictColumns []string) (inserted []*T, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationCreateBatch, Entity: entities}, func(ctx context.Context) error {
+		var err error
+		inserted, err = r.insertMissing(ctx, entities, conflictColumns)
+		return err
+	})
+	return inserted, err
+}
+
+// insertMissing implements InsertMissing
+func (r *Repository[T]) insertMissing(ctx context.Context, entities []*T, conflictColumns []string) ([]*T, error) {
+	if len(entities) == 0 {
+		return nil, nil
+	}
+
+	onConflict := clause.OnConflict{DoNothing: true}
+	for _, column := range conflictColumns {
+		if err := validateFieldName(column); err != nil {
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeInvalidArgument, "invalid conflict column", err)
+		}
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column})
+	}
+
+	if err := r.prepareCreate(ctx, entities); err != nil {
+		return nil, err
+	}
+
+	var inserted []*T
+	err := r.session(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, entity := range entities {
+			result := tx.Clauses(onConflict).Create(entity)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				inserted = append(inserted, entity)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, convertGormError(err)
+	}
+
+	r.finishCreate(ctx, inserted)
+	return inserted, nil
+}
@@ -0,0 +1,130 @@
+// Package gpagorm provides an interceptor chain around repository operations
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/lemmego/gpa"
+)
+
+// Operation identifies a repository operation passed to interceptors
+type Operation string
+
+const (
+	OperationCreate                    Operation = "Create"
+	OperationCreateBatch               Operation = "CreateBatch"
+	OperationFindByID                  Operation = "FindByID"
+	OperationFindAll                   Operation = "FindAll"
+	OperationUpdate                    Operation = "Update"
+	OperationUpdatePartial             Operation = "UpdatePartial"
+	OperationDelete                    Operation = "Delete"
+	OperationDeleteByCondition         Operation = "DeleteByCondition"
+	OperationQuery                     Operation = "Query"
+	OperationQueryOne                  Operation = "QueryOne"
+	OperationCount                     Operation = "Count"
+	OperationExists                    Operation = "Exists"
+	OperationTransaction               Operation = "Transaction"
+	OperationRawQuery                  Operation = "RawQuery"
+	OperationRawExec                   Operation = "RawExec"
+	OperationCreateTable               Operation = "CreateTable"
+	OperationDropTable                 Operation = "DropTable"
+	OperationCreateIndex               Operation = "CreateIndex"
+	OperationDropIndex                 Operation = "DropIndex"
+	OperationMigrateTable              Operation = "MigrateTable"
+	OperationSync                      Operation = "Sync"
+	OperationFindInBatches             Operation = "FindInBatches"
+	OperationIterate                   Operation = "Iterate"
+	OperationProcessInParallel         Operation = "ProcessInParallel"
+	OperationDumpEntities              Operation = "DumpEntities"
+	OperationLoadEntities              Operation = "LoadEntities"
+	OperationProject                   Operation = "Project"
+	OperationQueryAsMaps               Operation = "QueryAsMaps"
+	OperationExecScript                Operation = "ExecScript"
+	OperationUpsert                    Operation = "Upsert"
+	OperationAllocateIDs               Operation = "AllocateIDs"
+	OperationQuerySnapshotPage         Operation = "QuerySnapshotPage"
+	OperationFindDescendants           Operation = "FindDescendants"
+	OperationFindAncestors             Operation = "FindAncestors"
+	OperationQueryWithWindow           Operation = "QueryWithWindow"
+	OperationCreateBatchBeforeDeadline Operation = "CreateBatchBeforeDeadline"
+)
+
+// OperationInfo describes the repository operation being intercepted
+type OperationInfo struct {
+	Operation  Operation         // The repository method being executed
+	EntityType string            // Name of the entity type, e.g. "User"
+	Entity     interface{}       // Entity, entity slice or update map, if any
+	ID         interface{}       // Primary key for ID-based operations
+	Condition  gpa.Condition     // Condition for DeleteByCondition
+	Options    []gpa.QueryOption // Query options for read operations
+	SQL        string            // Statement for raw SQL operations
+}
+
+// Interceptor wraps a repository operation. It must call next to continue
+// the chain; returning without calling next short-circuits the operation.
+type Interceptor func(ctx context.Context, op OperationInfo, next func(ctx context.Context) error) error
+
+// AddInterceptor appends an interceptor applied to every repository
+// operation of this provider. Interceptors run in registration order,
+// the first registered being the outermost.
+func (p *Provider) AddInterceptor(interceptor Interceptor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.interceptors = append(p.interceptors, interceptor)
+}
+
+// intercept runs fn through the provider's interceptor chain and then
+// dispatches the changes it committed
+func (r *Repository[T]) intercept(ctx context.Context, op OperationInfo, fn func(ctx context.Context) error) error {
+	ctx = withOperation(ctx, op.Operation)
+	ctx = withStatementComment(ctx, r.comment)
+	ctx, changes := r.trackChanges(ctx)
+	err := r.runInterceptors(ctx, op, fn)
+	if err == nil && changes != nil {
+		r.provider.dispatchChanges(ctx, changes)
+	}
+	return err
+}
+
+// runInterceptors runs fn through the provider's interceptor chain
+func (r *Repository[T]) runInterceptors(ctx context.Context, op OperationInfo, fn func(ctx context.Context) error) error {
+	if r.provider == nil {
+		return fn(ctx)
+	}
+
+	// Session variables are applied closest to the operation itself,
+	// unless it opens its own transaction and applies them there
+	if !ownsSessionVars[op.Operation] {
+		inner := fn
+		fn = func(ctx context.Context) error {
+			return r.withSessionVars(ctx, inner)
+		}
+	}
+
+	r.provider.mu.RLock()
+	interceptors := r.provider.interceptors
+	r.provider.mu.RUnlock()
+	if len(interceptors) == 0 {
+		return fn(ctx)
+	}
+
+	op.EntityType = entityTypeName[T]()
+	next := fn
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := interceptors[i], next
+		next = func(ctx context.Context) error {
+			return interceptor(ctx, op, inner)
+		}
+	}
+	return next(ctx)
+}
+
+// entityTypeName returns the name of type T
+func entityTypeName[T any]() string {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
@@ -0,0 +1,81 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestInterceptorChainOrder(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	var calls []string
+	provider.AddInterceptor(func(ctx context.Context, op OperationInfo, next func(ctx context.Context) error) error {
+		calls = append(calls, "outer:"+string(op.Operation))
+		return next(ctx)
+	})
+	provider.AddInterceptor(func(ctx context.Context, op OperationInfo, next func(ctx context.Context) error) error {
+		calls = append(calls, "inner:"+op.EntityType)
+		return next(ctx)
+	})
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	user := &TestUser{Name: "John Doe", Email: "john@example.com", Age: 30}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	expected := []string{"outer:Create", "inner:TestUser"}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("Expected call %d to be '%s', got '%s'", i, expected[i], calls[i])
+		}
+	}
+
+	found, err := repo.FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to find user: %v", err)
+	}
+	if found.Name != user.Name {
+		t.Errorf("Expected name '%s', got '%s'", user.Name, found.Name)
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	errBlocked := errors.New("blocked")
+	provider.AddInterceptor(func(ctx context.Context, op OperationInfo, next func(ctx context.Context) error) error {
+		if op.Operation == OperationDelete {
+			return errBlocked
+		}
+		return next(ctx)
+	})
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	user := &TestUser{Name: "John Doe", Email: "john@example.com", Age: 30}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if err := repo.Delete(ctx, user.ID); !errors.Is(err, errBlocked) {
+		t.Errorf("Expected blocked error, got %v", err)
+	}
+
+	exists, err := repo.Exists(ctx)
+	if err != nil {
+		t.Fatalf("Failed to check existence: %v", err)
+	}
+	if !exists {
+		t.Error("Expected user to still exist")
+	}
+}
@@ -0,0 +1,182 @@
+// Package gpagorm provides JSON path conditions
+package gpagorm
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// jsonPathPattern matches the JSON paths JSONExtract accepts: $ followed
+// by object keys and array indexes, e.g. $.plan.limits[0]
+var jsonPathPattern = regexp.MustCompile(`^\$(\.[A-Za-z_][A-Za-z0-9_]*|\[[0-9]+\])*$`)
+
+// jsonPathSegment matches one key or index of a JSON path
+var jsonPathSegment = regexp.MustCompile(`\.([A-Za-z_][A-Za-z0-9_]*)|\[([0-9]+)\]`)
+
+// JSONCondition compares the value at Path inside the JSON column Column
+type JSONCondition struct {
+	Column string
+	Path   string
+	Op     gpa.Operator
+	Val    interface{}
+}
+
+// Field returns the column and path
+func (c JSONCondition) Field() string { return c.Column + strings.TrimPrefix(c.Path, "$") }
+
+// Operator returns the comparison operator
+func (c JSONCondition) Operator() gpa.Operator { return c.Op }
+
+// Value returns the compared value
+func (c JSONCondition) Value() interface{} { return c.Val }
+
+// String describes the condition
+func (c JSONCondition) String() string {
+	return fmt.Sprintf("%s %s %s %v", c.Column, c.Path, c.Op, c.Val)
+}
+
+// JSONExtract filters on the value at path inside a JSON column:
+//
+//	repo.Query(ctx, gpagorm.JSONExtract("metadata", "$.plan", gpa.OpEqual, "pro"))
+//	repo.Query(ctx, gpagorm.JSONExtract("metadata", "$.seats", gpa.OpGreaterThan, 10))
+//
+// Paths are object keys and array indexes, as in "$.limits[0].name". The
+// value is extracted with ->/->> on Postgres, JSON_EXTRACT on MySQL,
+// json_extract on SQLite and JSON_VALUE on SQL Server, and compared as a
+// number or boolean when the given value is one, otherwise as text.
+func JSONExtract(column, path string, op gpa.Operator, value interface{}) gpa.QueryOption {
+	return gpa.ConditionOption{Condition: JSONCondition{Column: column, Path: path, Op: op, Val: value}}
+}
+
+// jsonSQL returns the predicate of c on db
+func jsonSQL(db *gorm.DB, c JSONCondition) (string, []interface{}, error) {
+	column, err := fieldExpr(db, c.Column)
+	if err != nil {
+		return "", nil, err
+	}
+	if !jsonPathPattern.MatchString(c.Path) {
+		return "", nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "invalid JSON path: "+c.Path)
+	}
+
+	expr, err := jsonExtractExpr(db, column, c.Path, jsonValueKind(c.Val))
+	if err != nil {
+		return "", nil, err
+	}
+	value := c.Val
+	if b, ok := value.(bool); ok && (dialectName(db) == "mysql" || dialectName(db) == "sqlserver") {
+		// Both return JSON booleans as the text true or false
+		value = fmt.Sprint(b)
+	}
+
+	switch c.Op {
+	case gpa.OpEqual:
+		return expr + " = ?", []interface{}{value}, nil
+	case gpa.OpNotEqual:
+		return expr + " != ?", []interface{}{value}, nil
+	case gpa.OpGreaterThan:
+		return expr + " > ?", []interface{}{value}, nil
+	case gpa.OpGreaterThanOrEqual:
+		return expr + " >= ?", []interface{}{value}, nil
+	case gpa.OpLessThan:
+		return expr + " < ?", []interface{}{value}, nil
+	case gpa.OpLessThanOrEqual:
+		return expr + " <= ?", []interface{}{value}, nil
+	case gpa.OpLike:
+		return expr + " LIKE ?", []interface{}{value}, nil
+	case gpa.OpNotLike:
+		return expr + " NOT LIKE ?", []interface{}{value}, nil
+	case gpa.OpIn:
+		return expr + " IN ?", []interface{}{value}, nil
+	case gpa.OpNotIn:
+		return expr + " NOT IN ?", []interface{}{value}, nil
+	case gpa.OpIsNull:
+		return expr + " IS NULL", nil, nil
+	case gpa.OpIsNotNull:
+		return expr + " IS NOT NULL", nil, nil
+	case gpa.OpBetween, gpa.OpNotBetween:
+		return betweenSQL(expr, c.Op, value)
+	default:
+		return "", nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "unsupported JSON operator: "+string(c.Op))
+	}
+}
+
+// jsonExtractExpr returns the SQL extracting path from the quoted column
+// as a scalar of kind: "numeric", "boolean" or "" for text
+func jsonExtractExpr(db *gorm.DB, column, path, kind string) (string, error) {
+	switch dialectName(db) {
+	case "postgres":
+		segments := jsonPathSegment.FindAllStringSubmatch(path, -1)
+		if len(segments) == 0 {
+			return "", gpa.NewError(gpa.ErrorTypeInvalidArgument, "JSON path must name a key or index on postgres")
+		}
+		var b strings.Builder
+		b.WriteString(column)
+		for i, segment := range segments {
+			if i == len(segments)-1 {
+				b.WriteString(" ->> ")
+			} else {
+				b.WriteString(" -> ")
+			}
+			if segment[1] != "" {
+				b.WriteString("'" + segment[1] + "'")
+			} else {
+				b.WriteString(segment[2])
+			}
+		}
+		if kind == "" {
+			return b.String(), nil
+		}
+		return "(" + b.String() + ")::" + kind, nil
+	case "mysql":
+		return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '%s'))", column, path), nil
+	case "sqlite":
+		return fmt.Sprintf("json_extract(%s, '%s')", column, path), nil
+	case "sqlserver":
+		if kind == "numeric" {
+			return fmt.Sprintf("CAST(JSON_VALUE(%s, '%s') AS FLOAT)", column, path), nil
+		}
+		return fmt.Sprintf("JSON_VALUE(%s, '%s')", column, path), nil
+	default:
+		return "", gpa.NewError(gpa.ErrorTypeUnsupported, "JSON queries are not supported on "+dialectName(db))
+	}
+}
+
+// jsonValueKind returns how a compared value types the extracted JSON
+// value: "numeric", "boolean" or "" for text. Slices and ranges are typed
+// by their first element.
+func jsonValueKind(value interface{}) string {
+	switch v := value.(type) {
+	case Range:
+		return jsonValueKind(v.From)
+	case *Range:
+		if v != nil {
+			return jsonValueKind(v.From)
+		}
+		return ""
+	}
+
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		return ""
+	}
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+		if rv.Len() == 0 {
+			return ""
+		}
+		return jsonValueKind(rv.Index(0).Interface())
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "numeric"
+	case reflect.Bool:
+		return "boolean"
+	}
+	return ""
+}
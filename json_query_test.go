@@ -0,0 +1,103 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+)
+
+type testAccount struct {
+	ID       uint
+	Name     string
+	Metadata string
+}
+
+func TestJSONExtract(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&testAccount{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[testAccount](provider.db, provider)
+	ctx := context.Background()
+
+	for _, a := range []*testAccount{
+		{Name: "acme", Metadata: `{"plan": "pro", "seats": 25, "trial": false, "owners": ["ann"]}`},
+		{Name: "globex", Metadata: `{"plan": "free", "seats": 3, "trial": true, "owners": ["bob", "cid"]}`},
+		{Name: "initech", Metadata: `{"plan": "pro", "seats": 8}`},
+	} {
+		if err := repo.Create(ctx, a); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	count := func(opts ...gpa.QueryOption) int64 {
+		t.Helper()
+		n, err := repo.Count(ctx, opts...)
+		if err != nil {
+			t.Fatalf("Count failed: %v", err)
+		}
+		return n
+	}
+	if n := count(JSONExtract("metadata", "$.plan", gpa.OpEqual, "pro")); n != 2 {
+		t.Errorf("Expected 2 pro accounts, got %d", n)
+	}
+	if n := count(JSONExtract("metadata", "$.seats", gpa.OpGreaterThan, 5)); n != 2 {
+		t.Errorf("Expected 2 accounts over 5 seats, got %d", n)
+	}
+	if n := count(JSONExtract("metadata", "$.trial", gpa.OpEqual, true)); n != 1 {
+		t.Errorf("Expected 1 trial account, got %d", n)
+	}
+	if n := count(JSONExtract("metadata", "$.owners[1]", gpa.OpEqual, "cid")); n != 1 {
+		t.Errorf("Expected 1 account with a second owner cid, got %d", n)
+	}
+	if n := count(JSONExtract("metadata", "$.trial", gpa.OpIsNull, nil)); n != 1 {
+		t.Errorf("Expected 1 account without a trial flag, got %d", n)
+	}
+	if n := count(JSONExtract("metadata", "$.seats", gpa.OpBetween, []int{5, 10})); n != 1 {
+		t.Errorf("Expected 1 account with 5 to 10 seats, got %d", n)
+	}
+
+	if _, err := repo.Count(ctx, JSONExtract("metadata", "$.plan'); DROP TABLE x; --", gpa.OpEqual, "pro")); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for a malformed path, got %v", err)
+	}
+}
+
+func TestJSONExtractDialects(t *testing.T) {
+	dialectors := map[string]gorm.Dialector{
+		"postgres":  postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}),
+		"mysql":     mysql.New(mysql.Config{DSN: "user@tcp(localhost)/test", SkipInitializeWithVersion: true}),
+		"sqlserver": sqlserver.Open("sqlserver://localhost?database=test"),
+	}
+	tests := []struct {
+		dialect string
+		path    string
+		value   interface{}
+		want    string
+	}{
+		{"postgres", "$.plan", "pro", `"metadata" ->> 'plan' = 'pro'`},
+		{"postgres", "$.limits.seats", 10, `("metadata" -> 'limits' ->> 'seats')::numeric = 10`},
+		{"postgres", "$.owners[0]", true, `("metadata" -> 'owners' ->> 0)::boolean = true`},
+		{"mysql", "$.plan", "pro", "JSON_UNQUOTE(JSON_EXTRACT(`metadata`, '$.plan')) = 'pro'"},
+		{"mysql", "$.trial", true, "JSON_UNQUOTE(JSON_EXTRACT(`metadata`, '$.trial')) = 'true'"},
+		{"sqlserver", "$.seats", 10, `CAST(JSON_VALUE("metadata", '$.seats') AS FLOAT) = 10`},
+	}
+	for _, tt := range tests {
+		db, err := gorm.Open(dialectors[tt.dialect], &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+		if err != nil {
+			t.Fatalf("Failed to open dry-run %s: %v", tt.dialect, err)
+		}
+		repo := NewRepository[testAccount](db, nil)
+		var rows []*testAccount
+		stmt := repo.buildQuery(context.Background(), JSONExtract("metadata", tt.path, gpa.OpEqual, tt.value)).Find(&rows).Statement
+		if sql := db.Dialector.Explain(stmt.SQL.String(), stmt.Vars...); !strings.Contains(sql, tt.want) {
+			t.Errorf("%s %s: expected %s in %s", tt.dialect, tt.path, tt.want, sql)
+		}
+	}
+}
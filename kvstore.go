@@ -0,0 +1,134 @@
+// Package gpagorm provides a small key-value store on top of the provider
+package gpagorm
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// kvRow is a row of the key-value table
+type kvRow struct {
+	Name      string     `gorm:"primaryKey;size:255"`
+	Value     string     `gorm:"type:text"`
+	ExpiresAt *time.Time `gorm:"index"`
+}
+
+// TableName returns the key-value table name
+func (kvRow) TableName() string { return "gpagorm_kv" }
+
+// KVStore is a scratch key-value store kept in the gpagorm_kv table, for
+// settings and feature flags that do not warrant a separate cache server.
+// Expired keys are invisible to reads and removed by PurgeExpired or the
+// StartCleanup job.
+type KVStore struct {
+	db       *gorm.DB
+	provider *Provider
+}
+
+// NewKVStore creates a key-value store on db, which may be a transaction
+func NewKVStore(db *gorm.DB, provider *Provider) *KVStore {
+	return &KVStore{db: db, provider: provider}
+}
+
+// KVStore returns a key-value store bound to the provider's connection
+func (p *Provider) KVStore() *KVStore {
+	return NewKVStore(p.db, p)
+}
+
+// Migrate creates the key-value table if it does not exist
+func (s *KVStore) Migrate(ctx context.Context) error {
+	return convertGormError(s.db.WithContext(ctx).AutoMigrate(&kvRow{}))
+}
+
+// Get returns the value of key, or a not found error if it is missing or expired
+func (s *KVStore) Get(ctx context.Context, key string) (string, error) {
+	db := sessionDB(ctx, s.db, s.provider)
+	var row kvRow
+	err := s.live(db).Where("name = ?", key).Take(&row).Error
+	if err != nil {
+		return "", convertGormError(err)
+	}
+	return row.Value, nil
+}
+
+// Set stores value under key; a positive ttl makes it expire
+func (s *KVStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	db := sessionDB(ctx, s.db, s.provider)
+	row := kvRow{Name: key, Value: value, ExpiresAt: expiry(db, ttl)}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "expires_at"}),
+	}).Create(&row).Error
+	return convertGormError(err)
+}
+
+// SetNX stores value only if key is absent or expired, reporting whether it was set
+func (s *KVStore) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	db := sessionDB(ctx, s.db, s.provider)
+	if err := db.Where("name = ? AND expires_at < ?", key, db.NowFunc()).Delete(&kvRow{}).Error; err != nil {
+		return false, convertGormError(err)
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&kvRow{Name: key, Value: value, ExpiresAt: expiry(db, ttl)})
+	if result.Error != nil {
+		return false, convertGormError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Delete removes key
+func (s *KVStore) Delete(ctx context.Context, key string) error {
+	err := sessionDB(ctx, s.db, s.provider).Where("name = ?", key).Delete(&kvRow{}).Error
+	return convertGormError(err)
+}
+
+// Expire sets the time to live of an existing key; a non-positive ttl
+// removes the expiry. It reports whether the key existed.
+func (s *KVStore) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	db := sessionDB(ctx, s.db, s.provider)
+	result := s.live(db.Model(&kvRow{})).Where("name = ?", key).Update("expires_at", expiry(db, ttl))
+	if result.Error != nil {
+		return false, convertGormError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// PurgeExpired deletes expired keys and returns how many were removed
+func (s *KVStore) PurgeExpired(ctx context.Context) (int64, error) {
+	db := sessionDB(ctx, s.db, s.provider)
+	result := db.Where("expires_at < ?", db.NowFunc()).Delete(&kvRow{})
+	return result.RowsAffected, convertGormError(result.Error)
+}
+
+// StartCleanup purges expired keys every interval until ctx is done
+func (s *KVStore) StartCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.PurgeExpired(ctx)
+			}
+		}
+	}()
+}
+
+// live restricts db to keys that have not expired
+func (s *KVStore) live(db *gorm.DB) *gorm.DB {
+	return db.Where("expires_at IS NULL OR expires_at >= ?", db.NowFunc())
+}
+
+// expiry returns the expiry time for ttl, or nil for no expiry
+func expiry(db *gorm.DB, ttl time.Duration) *time.Time {
+	if ttl <= 0 {
+		return nil
+	}
+	expiresAt := db.NowFunc().Add(ttl)
+	return &expiresAt
+}
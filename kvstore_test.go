@@ -0,0 +1,69 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestKVStore(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	kv := provider.KVStore()
+	if err := kv.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	if _, err := kv.Get(ctx, "missing"); !gpa.IsNotFound(err) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	if err := kv.Set(ctx, "feature:beta", "on", 0); err != nil {
+		t.Fatalf("Failed to set: %v", err)
+	}
+	if err := kv.Set(ctx, "feature:beta", "off", 0); err != nil {
+		t.Fatalf("Failed to overwrite: %v", err)
+	}
+	if value, err := kv.Get(ctx, "feature:beta"); err != nil || value != "off" {
+		t.Errorf("Expected overwritten value, got %q %v", value, err)
+	}
+
+	if ok, err := kv.SetNX(ctx, "feature:beta", "on", 0); err != nil || ok {
+		t.Errorf("Expected SetNX on an existing key to fail, got %v %v", ok, err)
+	}
+	if ok, err := kv.SetNX(ctx, "job:lock", "worker-1", time.Hour); err != nil || !ok {
+		t.Errorf("Expected SetNX on a new key to succeed, got %v %v", ok, err)
+	}
+
+	if ok, err := kv.Expire(ctx, "job:lock", 0); err != nil || !ok {
+		t.Errorf("Expected Expire to persist an existing key, got %v %v", ok, err)
+	}
+	if ok, err := kv.Expire(ctx, "missing", time.Minute); err != nil || ok {
+		t.Errorf("Expected Expire on a missing key to report false, got %v %v", ok, err)
+	}
+
+	// Backdate the lock; it becomes invisible and can be taken again
+	provider.db.Model(&kvRow{}).Where("name = ?", "job:lock").Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := kv.Get(ctx, "job:lock"); !gpa.IsNotFound(err) {
+		t.Errorf("Expected expired key to be hidden, got %v", err)
+	}
+	if ok, err := kv.SetNX(ctx, "job:lock", "worker-2", time.Hour); err != nil || !ok {
+		t.Errorf("Expected SetNX over an expired key to succeed, got %v %v", ok, err)
+	}
+
+	provider.db.Model(&kvRow{}).Where("name = ?", "job:lock").Update("expires_at", time.Now().Add(-time.Minute))
+	if purged, err := kv.PurgeExpired(ctx); err != nil || purged != 1 {
+		t.Errorf("Expected one purged key, got %d %v", purged, err)
+	}
+
+	if err := kv.Delete(ctx, "feature:beta"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if _, err := kv.Get(ctx, "feature:beta"); !gpa.IsNotFound(err) {
+		t.Errorf("Expected deleted key to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+// Package gpagorm provides database-backed leader election
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// LeaderElector elects a single leader among processes sharing a database.
+// Leadership is a lease row in the gpagorm_locks table that the leader
+// renews every ttl/3; if it stops renewing, another candidate takes over
+// once the lease expires.
+type LeaderElector struct {
+	provider *Provider
+	name     string
+	ttl      time.Duration
+	id       string
+
+	onAcquire func(ctx context.Context)
+	onLose    func()
+
+	mu     sync.Mutex
+	leader bool
+	cancel context.CancelFunc
+}
+
+// NewLeaderElector creates an elector for the named role. It fails when
+// ttl is too short to renew the lease every ttl/3.
+func NewLeaderElector(provider *Provider, name string, ttl time.Duration) (*LeaderElector, error) {
+	if ttl/3 <= 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("invalid leader ttl %v: the lease is renewed every ttl/3", ttl))
+	}
+	return &LeaderElector{
+		provider: provider,
+		name:     "leader:" + name,
+		ttl:      ttl,
+		id:       NewUUID().String(),
+	}, nil
+}
+
+// OnAcquire sets the callback run in its own goroutine when leadership is
+// gained; its context is cancelled when leadership is lost
+func (e *LeaderElector) OnAcquire(fn func(ctx context.Context)) *LeaderElector {
+	e.onAcquire = fn
+	return e
+}
+
+// OnLose sets the callback run when leadership is lost or released
+func (e *LeaderElector) OnLose(fn func()) *LeaderElector {
+	e.onLose = fn
+	return e
+}
+
+// ID returns the candidate identifier stored in the lease
+func (e *LeaderElector) ID() string {
+	return e.id
+}
+
+// IsLeader reports whether this elector currently holds the lease
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Run campaigns for leadership until ctx is done, then releases the lease
+func (e *LeaderElector) Run(ctx context.Context) error {
+	if err := ensureLockTable(e.provider.db.WithContext(ctx)); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		e.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			if e.IsLeader() {
+				e.lose()
+				releaseLease(e.provider.db, e.name, e.id)
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick renews the lease when leading, or tries to acquire it otherwise
+func (e *LeaderElector) tick(ctx context.Context) {
+	db := e.provider.db.WithContext(ctx)
+	if e.IsLeader() {
+		if renewed, err := renewLease(db, e.name, e.id, e.ttl); err != nil || !renewed {
+			e.lose()
+		}
+		return
+	}
+
+	if acquired, err := acquireLease(db, e.name, e.id, e.ttl); err == nil && acquired {
+		e.acquire(ctx)
+	}
+}
+
+// acquire marks this elector as leader and starts the acquire callback
+func (e *LeaderElector) acquire(ctx context.Context) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	e.mu.Lock()
+	e.leader = true
+	e.cancel = cancel
+	e.mu.Unlock()
+
+	if e.onAcquire != nil {
+		go e.onAcquire(leaderCtx)
+	}
+}
+
+// lose clears leadership and notifies the callbacks
+func (e *LeaderElector) lose() {
+	e.mu.Lock()
+	e.leader = false
+	cancel := e.cancel
+	e.cancel = nil
+	e.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	if e.onLose != nil {
+		e.onLose()
+	}
+}
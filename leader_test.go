@@ -0,0 +1,82 @@
+package gpagorm
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestLeaderElector(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	// Keep a single connection so the in-memory database is shared
+	sqlDB, _ := provider.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	var acquired, lost atomic.Int32
+	first, err := NewLeaderElector(provider, "scheduler", 150*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewLeaderElector failed: %v", err)
+	}
+	first.
+		OnAcquire(func(ctx context.Context) {
+			acquired.Add(1)
+			<-ctx.Done()
+		}).
+		OnLose(func() { lost.Add(1) })
+	second, err := NewLeaderElector(provider, "scheduler", 150*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewLeaderElector failed: %v", err)
+	}
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	firstDone := make(chan struct{})
+	go func() {
+		first.Run(firstCtx)
+		close(firstDone)
+	}()
+	waitFor(t, first.IsLeader)
+
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	defer stopSecond()
+	go second.Run(secondCtx)
+
+	time.Sleep(200 * time.Millisecond)
+	if second.IsLeader() {
+		t.Fatal("Expected only one leader")
+	}
+	if acquired.Load() != 1 {
+		t.Errorf("Expected acquire callback once, got %d", acquired.Load())
+	}
+
+	stopFirst()
+	<-firstDone
+	if first.IsLeader() || lost.Load() != 1 {
+		t.Errorf("Expected first elector to give up leadership, lost=%d", lost.Load())
+	}
+	waitFor(t, second.IsLeader)
+}
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLeaderElectorRejectsShortTTL(t *testing.T) {
+	for _, ttl := range []time.Duration{-time.Second, 0, 2 * time.Nanosecond} {
+		if _, err := NewLeaderElector(nil, "scheduler", ttl); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+			t.Errorf("Expected an invalid argument error for ttl %v, got %v", ttl, err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Package gpagorm provides lock timeouts for migrations and other schema
+// operations
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type lockTimeoutKey struct{}
+
+// WithLockTimeout returns a context whose schema operations (MigrateTable,
+// CreateTable, DropTable, CreateIndex and DropIndex) stop waiting for table
+// locks after d, overriding the "lock_timeout" option. A DDL statement
+// queued behind a long transaction then fails instead of blocking every
+// query queued behind it in turn.
+func WithLockTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, lockTimeoutKey{}, d)
+}
+
+// lockTimeout returns how long schema operations run with ctx may wait for
+// locks: WithLockTimeout, else the "lock_timeout" option, shortened to the
+// time left before ctx's deadline. Zero means no limit.
+func (p *Provider) lockTimeout(ctx context.Context) (time.Duration, error) {
+	timeout, ok := ctx.Value(lockTimeoutKey{}).(time.Duration)
+	if !ok && p != nil {
+		switch d := gormOptions(p.currentConfig())["lock_timeout"].(type) {
+		case nil:
+		case time.Duration:
+			timeout = d
+		case string:
+			parsed, err := time.ParseDuration(d)
+			if err != nil {
+				return 0, fmt.Errorf("invalid lock_timeout: %w", err)
+			}
+			timeout = parsed
+		default:
+			return 0, fmt.Errorf("invalid lock_timeout: %v", d)
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline); timeout <= 0 || left < timeout {
+			timeout = max(left, time.Millisecond)
+		}
+	}
+	return timeout, nil
+}
+
+// lockTimeoutStatements returns the statements setting and resetting the
+// session lock timeout on dialect, or empty strings where locks are not
+// waited on per session (SQLite waits on its busy timeout)
+func lockTimeoutStatements(dialect string, timeout time.Duration) (string, string) {
+	switch dialect {
+	case "postgres":
+		return fmt.Sprintf("SET lock_timeout = %d", timeout.Milliseconds()), "RESET lock_timeout"
+	case "mysql":
+		// lock_wait_timeout is in whole seconds
+		seconds := int64((timeout + time.Second - 1) / time.Second)
+		return fmt.Sprintf("SET SESSION lock_wait_timeout = %d", seconds), "SET SESSION lock_wait_timeout = DEFAULT"
+	case "sqlserver":
+		return fmt.Sprintf("SET LOCK_TIMEOUT %d", timeout.Milliseconds()), "SET LOCK_TIMEOUT -1"
+	default:
+		return "", ""
+	}
+}
+
+// runDDL runs fn with db bound to ctx and the lock timeout applied. The
+// timeout is set on a connection held for fn and reset before it returns
+// to the pool; inside a transaction it is set on the transaction.
+func (p *Provider) runDDL(ctx context.Context, db *gorm.DB, fn func(db *gorm.DB) error) error {
+	db = db.WithContext(ctx)
+	timeout, err := p.lockTimeout(ctx)
+	if err != nil {
+		return err
+	}
+	set, reset := lockTimeoutStatements(dialectName(db), timeout)
+	if timeout <= 0 || set == "" {
+		return fn(db)
+	}
+
+	run := func(conn *gorm.DB) error {
+		if err := conn.Exec(set).Error; err != nil {
+			return err
+		}
+		// Reset even when ctx is done, so the connection goes back clean
+		defer conn.WithContext(context.WithoutCancel(ctx)).Exec(reset)
+		return fn(conn)
+	}
+	if inTransaction(db) {
+		return run(db)
+	}
+	return db.Connection(run)
+}
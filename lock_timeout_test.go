@@ -0,0 +1,79 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestLockTimeout(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	if timeout, err := provider.lockTimeout(context.Background()); err != nil || timeout != 0 {
+		t.Errorf("Expected no lock timeout by default, got %s (%v)", timeout, err)
+	}
+
+	provider.setConfig(gpa.Config{Driver: "sqlite", Database: ":memory:", Options: map[string]interface{}{"gorm": map[string]interface{}{"lock_timeout": "3s"}}})
+	if timeout, err := provider.lockTimeout(context.Background()); err != nil || timeout != 3*time.Second {
+		t.Errorf("Expected the configured lock timeout, got %s (%v)", timeout, err)
+	}
+	ctx := WithLockTimeout(context.Background(), 500*time.Millisecond)
+	if timeout, err := provider.lockTimeout(ctx); err != nil || timeout != 500*time.Millisecond {
+		t.Errorf("Expected the context lock timeout, got %s (%v)", timeout, err)
+	}
+
+	// A nearer deadline shortens the timeout
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if timeout, err := provider.lockTimeout(ctx); err != nil || timeout <= 0 || timeout > time.Second {
+		t.Errorf("Expected the deadline to bound the lock timeout, got %s (%v)", timeout, err)
+	}
+
+	provider.setConfig(gpa.Config{Driver: "sqlite", Database: ":memory:", Options: map[string]interface{}{"gorm": map[string]interface{}{"lock_timeout": "soon"}}})
+	repo := NewRepository[TestUser](provider.db, provider)
+	if err := repo.MigrateTable(context.Background()); err == nil {
+		t.Error("Expected an invalid lock_timeout to fail the migration")
+	}
+}
+
+func TestLockTimeoutStatements(t *testing.T) {
+	tests := []struct {
+		dialect    string
+		set, reset string
+	}{
+		{"postgres", "SET lock_timeout = 1500", "RESET lock_timeout"},
+		{"mysql", "SET SESSION lock_wait_timeout = 2", "SET SESSION lock_wait_timeout = DEFAULT"},
+		{"sqlserver", "SET LOCK_TIMEOUT 1500", "SET LOCK_TIMEOUT -1"},
+		{"sqlite", "", ""},
+	}
+	for _, tt := range tests {
+		set, reset := lockTimeoutStatements(tt.dialect, 1500*time.Millisecond)
+		if set != tt.set || reset != tt.reset {
+			t.Errorf("%s: expected %q and %q, got %q and %q", tt.dialect, tt.set, tt.reset, set, reset)
+		}
+	}
+}
+
+func TestSchemaOperationsWithLockTimeout(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[testAuditedNote](provider.db, provider)
+
+	ctx, cancel := context.WithTimeout(WithLockTimeout(context.Background(), time.Second), time.Minute)
+	defer cancel()
+	if err := repo.CreateTable(ctx); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	if err := repo.CreateTable(ctx); !gpa.IsErrorType(err, gpa.ErrorTypeDuplicate) {
+		t.Errorf("Expected a duplicate table error, got %v", err)
+	}
+	if err := repo.MigrateTable(ctx); err != nil {
+		t.Errorf("MigrateTable failed: %v", err)
+	}
+	if err := repo.DropTable(ctx); err != nil {
+		t.Errorf("DropTable failed: %v", err)
+	}
+}
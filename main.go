@@ -3,18 +3,19 @@ package gpagorm
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/glebarez/sqlite"
+	"net/url"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lemmego/gpa"
 	"gorm.io/driver/mysql"
-	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlserver"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 )
 
@@ -24,70 +25,293 @@ import (
 
 // Provider implements gpa.Provider and gpa.SQLProvider using GORM
 type Provider struct {
-	db     *gorm.DB
-	config gpa.Config
+	db             *gorm.DB
+	replicas       []*gorm.DB
+	config         gpa.Config
+	healthCheck    HealthCheckConfig
+	mu             sync.RWMutex
+	interceptors   []Interceptor
+	policies       map[reflect.Type]interface{}
+	scopes         map[reflect.Type]map[string]ScopeFunc
+	profiles       map[reflect.Type]map[string][]profileField
+	canonicalizers map[reflect.Type]map[string][]Canonicalizer
+	readBindings   map[reflect.Type]readBinding
+
+	changeListeners map[reflect.Type][]changeListener
+
+	allowedValues map[string]map[string][]string // table -> column -> values
+	timeLocation  *time.Location
+	sqlMode       string // Configured MySQL sql_mode, see sqlModeSet
+	sqlModeSet    bool
+	queryStats    *queryStatsCollector
+	totals        statementTotals
+	indexAdvisor  *indexAdvisor
+
+	replicaDSNs     []string
+	replicaMu       sync.RWMutex // Guards replicas, replicaDSNs and replicaReadPool
+	replicaReadPool *replicaPool
+	reloadMu        sync.Mutex // Serializes Configure
+	connector       *reloadableConnector
+	logger          *reloadableLogger
+	expirations     []*expirationSpec
+
+	capabilities     Capabilities
+	capabilitiesOnce sync.Once
+
+	sessionVarsResolver SessionVarsResolver
+
+	deferredForeignKeys     map[reflect.Type]interface{}
+	deferredForeignKeyOrder []reflect.Type
+
+	autoIncrement mysqlAutoIncrement // Read on first use, guarded by mu
+
+	shared          bool   // Pools belong to the provider WithDatabase was called on
+	applicationName string // Name the database shows for this provider's connections
 }
 
 // NewProvider creates a new GORM provider instance
 func NewProvider(config gpa.Config) (*Provider, error) {
-	provider := &Provider{config: config}
+	return newProvider(config, nil)
+}
+
+// newProvider creates a provider, over the pools of shared when set
+func newProvider(config gpa.Config, shared *sharedPools) (*Provider, error) {
+	provider := &Provider{config: config, shared: shared != nil}
+	gormOpts := gormOptions(config)
+
 	// Configure GORM
+	provider.logger = newReloadableLogger(logLevelOption(gormOpts))
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: provider.logger,
 		NamingStrategy: schema.NamingStrategy{
 			SingularTable: false,
 		},
 	}
 
-	// Apply custom configurations from options
-	if options, ok := config.Options["gorm"]; ok {
-		if gormOpts, ok := options.(map[string]interface{}); ok {
-			if logLevel, ok := gormOpts["log_level"].(string); ok {
-				switch logLevel {
-				case "silent":
-					gormConfig.Logger = logger.Default.LogMode(logger.Silent)
-				case "error":
-					gormConfig.Logger = logger.Default.LogMode(logger.Error)
-				case "warn":
-					gormConfig.Logger = logger.Default.LogMode(logger.Warn)
-				case "info":
-					gormConfig.Logger = logger.Default.LogMode(logger.Info)
-				}
+	if singularTable, ok := gormOpts["singular_table"].(bool); ok {
+		gormConfig.NamingStrategy = schema.NamingStrategy{
+			SingularTable: singularTable,
+		}
+	}
+
+	if shared != nil {
+		naming := gormConfig.NamingStrategy.(schema.NamingStrategy)
+		naming.TablePrefix = shared.tablePrefix
+		gormConfig.NamingStrategy = naming
+	}
+
+	if disable, ok := gormOpts["disable_foreign_keys"].(bool); ok {
+		gormConfig.DisableForeignKeyConstraintWhenMigrating = disable
+	}
+
+	loc, err := configureTimeZone(gormConfig, gormOpts)
+	if err != nil {
+		return nil, err
+	}
+	provider.timeLocation = loc
+	if provider.sqlMode, provider.sqlModeSet, err = sqlModeOption(gormOpts); err != nil {
+		return nil, err
+	}
+
+	if provider.applicationName, _, err = connectionLabels(config); err != nil {
+		return nil, err
+	}
+
+	healthCheck, err := parseHealthCheckConfig(gormOpts)
+	if err != nil {
+		return nil, err
+	}
+	provider.healthCheck = healthCheck
+
+	// Initialize database connection
+	var dialector gorm.Dialector
+	if shared != nil {
+		dialector, err = sharedDialector(config, shared.primary)
+	} else {
+		dialector, provider.connector, err = openPrimary(config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if shared == nil {
+		if err := configurePool(db, config); err != nil {
+			return nil, err
+		}
+	}
+	provider.db = db
+	if err := registerOutputInserted(db); err != nil {
+		provider.Close()
+		return nil, err
+	}
+	if err := registerErrorContext(db); err != nil {
+		provider.Close()
+		return nil, err
+	}
+	if err := registerStatementComments(db); err != nil {
+		provider.Close()
+		return nil, err
+	}
+	if err := provider.totals.register(db); err != nil {
+		provider.Close()
+		return nil, err
+	}
+
+	if enabled, ok := gormOpts["query_stats"].(bool); ok && enabled {
+		provider.queryStats = newQueryStatsCollector()
+		if err := provider.queryStats.configure(gormOpts); err != nil {
+			provider.Close()
+			return nil, err
+		}
+		if err := provider.queryStats.register(db); err != nil {
+			provider.Close()
+			return nil, err
+		}
+	}
+
+	if loc != nil {
+		if normalize, ok := gormOpts["normalize_time_on_write"].(bool); !ok || normalize {
+			if err := registerTimeNormalization(db, loc); err != nil {
+				provider.Close()
+				return nil, err
 			}
+		}
+		if err := provider.verifyTimeZone(gormOpts); err != nil {
+			provider.Close()
+			return nil, err
+		}
+	}
 
-			if singularTable, ok := gormOpts["singular_table"].(bool); ok {
-				gormConfig.NamingStrategy = schema.NamingStrategy{
-					SingularTable: singularTable,
-				}
+	if err := provider.verifySQLMode(gormOpts); err != nil {
+		provider.Close()
+		return nil, err
+	}
+
+	if models, ok := gormOpts["validate_schema"].([]interface{}); ok {
+		if err := provider.ValidateSchema(context.Background(), models...); err != nil {
+			provider.Close()
+			return nil, err
+		}
+	}
+
+	if advisor := newIndexAdvisor(gormOpts); advisor != nil {
+		if err := advisor.register(db); err != nil {
+			provider.Close()
+			return nil, err
+		}
+		provider.indexAdvisor = advisor
+	}
+
+	// Open read replicas with the same driver and pool settings
+	if shared != nil {
+		for i, pool := range shared.replicas {
+			replica, err := provider.openSharedReplica(config, pool, gormConfig)
+			if err != nil {
+				provider.Close()
+				return nil, err
+			}
+			provider.replicas = append(provider.replicas, replica)
+			provider.replicaDSNs = append(provider.replicaDSNs, shared.replicaDSNs[i])
+		}
+	} else if dsns, ok := gormOpts["replicas"].([]string); ok {
+		for _, dsn := range dsns {
+			replica, err := provider.openReplica(config, dsn, gormConfig)
+			if err != nil {
+				provider.Close()
+				return nil, err
 			}
+			provider.replicas = append(provider.replicas, replica)
+			provider.replicaDSNs = append(provider.replicaDSNs, dsn)
 		}
 	}
 
-	// Initialize database connection
-	var dialector gorm.Dialector
+	return provider, nil
+}
+
+// gormOptions returns the "gorm" section of the config options
+func gormOptions(config gpa.Config) map[string]interface{} {
+	if options, ok := config.Options["gorm"]; ok {
+		if gormOpts, ok := options.(map[string]interface{}); ok {
+			return gormOpts
+		}
+	}
+	return map[string]interface{}{}
+}
+
+// openDialector creates a dialector for the configured driver.
+// A non-empty dsn overrides the DSN built from the config.
+func openDialector(config gpa.Config, dsn string) (gorm.Dialector, error) {
+	sessionInit, err := sessionInitStatements(config)
+	if err != nil {
+		return nil, err
+	}
 
 	switch strings.ToLower(config.Driver) {
 	case "postgres", "postgresql":
-		dialector = postgres.Open(buildPostgresDSN(config))
+		if dsn == "" {
+			dsn = buildPostgresDSN(config)
+		}
+		if dsn, err = withConnectionLabels(config, dsn); err != nil {
+			return nil, err
+		}
+		return openPostgres(config, dsn)
 	case "mysql":
-		dialector = mysql.Open(buildMySQLDSN(config))
+		if dsn == "" {
+			dsn = buildMySQLDSN(config)
+		}
+		if dsn, err = withConnectionLabels(config, dsn); err != nil {
+			return nil, err
+		}
+		if sessionInit, err = mysqlSessionInit(config, sessionInit); err != nil {
+			return nil, err
+		}
+		if len(sessionInit) == 0 {
+			return mysql.Open(dsn), nil
+		}
+		connector, err := driverConnector("mysql", dsn)
+		if err != nil {
+			return nil, err
+		}
+		return mysql.New(mysql.Config{DSN: dsn, Conn: openSessionDB(connector, sessionInit)}), nil
 	case "sqlite", "sqlite3":
-		dialector = sqlite.Open(config.Database)
+		registerSQLiteBuiltins()
+		if dsn == "" {
+			dsn = config.Database
+		}
+		if len(sessionInit) == 0 {
+			return sqlite.Open(dsn), nil
+		}
+		connector, err := driverConnector(sqlite.DriverName, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return &sqlite.Dialector{DSN: dsn, Conn: openSessionDB(connector, sessionInit)}, nil
 	case "sqlserver", "mssql":
-		dialector = sqlserver.Open(buildSQLServerDSN(config))
+		if dsn == "" {
+			dsn = buildSQLServerDSN(config)
+		}
+		if dsn, err = withConnectionLabels(config, dsn); err != nil {
+			return nil, err
+		}
+		connector, err := driverConnector("sqlserver", dsn)
+		if err != nil {
+			return nil, err
+		}
+		return sqlserver.New(sqlserver.Config{DSN: dsn, Conn: openSessionDB(uniqueIdentifierConnector{connector}, sessionInit)}), nil
 	default:
 		return nil, fmt.Errorf("unsupported driver: %s", config.Driver)
 	}
+}
 
-	db, err := gorm.Open(dialector, gormConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
-	}
-
-	// Configure connection pool
+// configurePool applies the connection pool settings to db
+func configurePool(db *gorm.DB, config gpa.Config) error {
 	sqlDB, err := db.DB()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get underlying sql.DB: %w", err)
+		return fmt.Errorf("failed to get underlying sql.DB: %w", err)
 	}
 
 	if config.MaxOpenConns > 0 {
@@ -102,37 +326,30 @@ func NewProvider(config gpa.Config) (*Provider, error) {
 	if config.ConnMaxIdleTime > 0 {
 		sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
 	}
-
-	provider.db = db
-	return provider, nil
-}
-
-// Configure applies configuration to the provider
-func (p *Provider) Configure(config gpa.Config) error {
-	p.config = config
 	return nil
 }
 
-// Health checks the database connection health
-func (p *Provider) Health() error {
-	sqlDB, err := p.db.DB()
-	if err != nil {
-		return fmt.Errorf("failed to get underlying sql.DB: %w", err)
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	return sqlDB.PingContext(ctx)
-}
-
-// Close closes the database connection
+// Close closes the database connection and any replica connections. A
+// provider returned by WithDatabase that shares its parent's pools leaves
+// them open.
 func (p *Provider) Close() error {
-	sqlDB, err := p.db.DB()
-	if err != nil {
-		return err
+	if p.shared {
+		return nil
+	}
+	var errs []error
+	for _, replica := range p.replicaDBs() {
+		if sqlDB, err := replica.DB(); err == nil {
+			errs = append(errs, sqlDB.Close())
+		}
 	}
-	return sqlDB.Close()
+	if p.db != nil {
+		sqlDB, err := p.db.DB()
+		if err != nil {
+			return err
+		}
+		errs = append(errs, sqlDB.Close())
+	}
+	return errors.Join(errs...)
 }
 
 // SupportedFeatures returns the list of supported features
@@ -184,35 +401,26 @@ func (p *Provider) BeginTx(ctx context.Context, opts *gpa.TxOptions) (interface{
 		return p.db.WithContext(ctx).Begin(), nil
 	}
 
-	// Convert GPA isolation level to sql.IsolationLevel
-	sqlOpts := &sql.TxOptions{
-		ReadOnly: opts.ReadOnly,
-	}
-
-	switch opts.IsolationLevel {
-	case gpa.IsolationReadUncommitted:
-		sqlOpts.Isolation = sql.LevelReadUncommitted
-	case gpa.IsolationReadCommitted:
-		sqlOpts.Isolation = sql.LevelReadCommitted
-	case gpa.IsolationRepeatableRead:
-		sqlOpts.Isolation = sql.LevelRepeatableRead
-	case gpa.IsolationSerializable:
-		sqlOpts.Isolation = sql.LevelSerializable
-	default:
-		sqlOpts.Isolation = sql.LevelDefault
-	}
-
 	sqlDB, err := p.db.DB()
 	if err != nil {
 		return nil, err
 	}
 
-	return sqlDB.BeginTx(ctx, sqlOpts)
+	return sqlDB.BeginTx(ctx, toSQLTxOptions(opts))
 }
 
-// Migrate runs database migrations
+// Migrate runs database migrations.
+// With the "safe_migrations" option only additive changes are applied.
+// The "lock_timeout" option bounds how long DDL waits for table locks.
 func (p *Provider) Migrate(models ...interface{}) error {
-	return p.db.AutoMigrate(models...)
+	if p.safeMigrations() {
+		report, err := p.MigrateSafe(models...)
+		logSkippedChanges(report)
+		return err
+	}
+	return p.runDDL(context.Background(), p.db, func(db *gorm.DB) error {
+		return p.autoMigrate(db, models...)
+	})
 }
 
 // RawQuery executes raw SQL and returns results
@@ -261,6 +469,10 @@ func buildPostgresDSN(config gpa.Config) string {
 		dsn += " sslmode=disable"
 	}
 
+	if tz := timeZoneOption(gormOptions(config)); tz != "" {
+		dsn += " TimeZone=" + tz
+	}
+
 	return dsn
 }
 
@@ -270,8 +482,13 @@ func buildMySQLDSN(config gpa.Config) string {
 		return config.ConnectionURL
 	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		config.Username, config.Password, config.Host, config.Port, config.Database)
+	loc := "Local"
+	if tz := timeZoneOption(gormOptions(config)); tz != "" {
+		loc = url.QueryEscape(tz)
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=%s",
+		config.Username, config.Password, config.Host, config.Port, config.Database, loc)
 
 	if config.SSL.Enabled {
 		dsn += "&tls=" + config.SSL.Mode
@@ -290,6 +507,14 @@ func buildSQLServerDSN(config gpa.Config) string {
 		config.Username, config.Password, config.Host, config.Port, config.Database)
 }
 
+// dialectName returns the GORM dialect name of db, e.g. "postgres"
+func dialectName(db *gorm.DB) string {
+	if db == nil || db.Dialector == nil {
+		return ""
+	}
+	return db.Dialector.Name()
+}
+
 // SupportedDrivers returns the list of supported database drivers
 func SupportedDrivers() []string {
 	return []string{"postgres", "postgresql", "mysql", "sqlite", "sqlite3", "sqlserver", "mssql"}
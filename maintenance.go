@@ -0,0 +1,199 @@
+// Package gpagorm provides database maintenance tasks
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaintenanceTask names a maintenance operation
+type MaintenanceTask string
+
+const (
+	// MaintenanceAnalyze refreshes planner statistics
+	MaintenanceAnalyze MaintenanceTask = "analyze"
+	// MaintenanceVacuum reclaims space (VACUUM, OPTIMIZE TABLE)
+	MaintenanceVacuum MaintenanceTask = "vacuum"
+	// MaintenanceReindex rebuilds indexes
+	MaintenanceReindex MaintenanceTask = "reindex"
+)
+
+// MaintenanceSpec selects the maintenance to run. Empty Tables means every
+// table in the database.
+type MaintenanceSpec struct {
+	Tables  []string
+	Analyze bool
+	Vacuum  bool
+	Reindex bool
+}
+
+// MaintenanceResult reports one maintenance statement
+type MaintenanceResult struct {
+	Table    string          `json:"table,omitempty"`
+	Task     MaintenanceTask `json:"task"`
+	SQL      string          `json:"sql,omitempty"`
+	Duration time.Duration   `json:"duration"`
+	Skipped  string          `json:"skipped,omitempty"` // Reason the task is not available on the dialect
+	Error    string          `json:"error,omitempty"`
+}
+
+// maintenanceStatement is one statement to run, with the table left as ?
+type maintenanceStatement struct {
+	task  MaintenanceTask
+	table bool // Whether the statement takes the table
+	sql   string
+}
+
+// maintenanceStatements returns the statements for spec on dialect, or a
+// reason for each task the dialect cannot run
+func maintenanceStatements(dialect string, spec MaintenanceSpec) ([]maintenanceStatement, map[MaintenanceTask]string) {
+	var stmts []maintenanceStatement
+	skipped := map[MaintenanceTask]string{}
+	switch dialect {
+	case "postgres":
+		switch {
+		case spec.Vacuum && spec.Analyze:
+			stmts = append(stmts, maintenanceStatement{MaintenanceVacuum, true, "VACUUM (ANALYZE) ?"})
+		case spec.Vacuum:
+			stmts = append(stmts, maintenanceStatement{MaintenanceVacuum, true, "VACUUM ?"})
+		case spec.Analyze:
+			stmts = append(stmts, maintenanceStatement{MaintenanceAnalyze, true, "ANALYZE ?"})
+		}
+		if spec.Reindex {
+			stmts = append(stmts, maintenanceStatement{MaintenanceReindex, true, "REINDEX TABLE ?"})
+		}
+	case "mysql":
+		if spec.Analyze {
+			stmts = append(stmts, maintenanceStatement{MaintenanceAnalyze, true, "ANALYZE TABLE ?"})
+		}
+		// OPTIMIZE TABLE rebuilds InnoDB tables and their indexes together
+		if spec.Vacuum || spec.Reindex {
+			stmts = append(stmts, maintenanceStatement{MaintenanceVacuum, true, "OPTIMIZE TABLE ?"})
+		}
+	case "sqlite":
+		if spec.Analyze {
+			stmts = append(stmts, maintenanceStatement{MaintenanceAnalyze, true, "ANALYZE ?"})
+		}
+		if spec.Reindex {
+			stmts = append(stmts, maintenanceStatement{MaintenanceReindex, true, "REINDEX ?"})
+		}
+		// SQLite only vacuums the whole database
+		if spec.Vacuum {
+			stmts = append(stmts, maintenanceStatement{MaintenanceVacuum, false, "VACUUM"})
+		}
+	case "sqlserver":
+		if spec.Analyze {
+			stmts = append(stmts, maintenanceStatement{MaintenanceAnalyze, true, "UPDATE STATISTICS ?"})
+		}
+		if spec.Reindex {
+			stmts = append(stmts, maintenanceStatement{MaintenanceReindex, true, "ALTER INDEX ALL ON ? REBUILD"})
+		}
+		if spec.Vacuum {
+			skipped[MaintenanceVacuum] = "sqlserver has no vacuum; use reindex"
+		}
+	default:
+		for task, enabled := range map[MaintenanceTask]bool{MaintenanceAnalyze: spec.Analyze, MaintenanceVacuum: spec.Vacuum, MaintenanceReindex: spec.Reindex} {
+			if enabled {
+				skipped[task] = "not supported on " + dialect
+			}
+		}
+	}
+	return stmts, skipped
+}
+
+// Maintain runs the maintenance in spec with the provider's connection:
+// VACUUM/ANALYZE/REINDEX on Postgres, ANALYZE/OPTIMIZE TABLE on MySQL,
+// ANALYZE/REINDEX/VACUUM on SQLite and UPDATE STATISTICS/index rebuilds
+// on SQL Server. Statements run outside any transaction, one table at a
+// time; a failure is recorded and the remaining statements still run.
+func (p *Provider) Maintain(ctx context.Context, spec MaintenanceSpec) ([]MaintenanceResult, error) {
+	db := p.db.WithContext(ctx)
+	dialect := dialectName(db)
+
+	tables := spec.Tables
+	if len(tables) == 0 {
+		all, err := db.Migrator().GetTables()
+		if err != nil {
+			return nil, convertGormError(err)
+		}
+		for _, table := range all {
+			// SQLite lists its own bookkeeping tables such as sqlite_sequence
+			if !strings.HasPrefix(table, "sqlite_") {
+				tables = append(tables, table)
+			}
+		}
+	}
+
+	stmts, skipped := maintenanceStatements(dialect, spec)
+	var results []MaintenanceResult
+	for _, task := range []MaintenanceTask{MaintenanceAnalyze, MaintenanceVacuum, MaintenanceReindex} {
+		if reason, ok := skipped[task]; ok {
+			results = append(results, MaintenanceResult{Task: task, Skipped: reason})
+		}
+	}
+
+	var errs []error
+	for _, stmt := range stmts {
+		targets := tables
+		if !stmt.table {
+			targets = []string{""}
+		}
+		for _, table := range targets {
+			if err := ctx.Err(); err != nil {
+				return results, convertGormError(err)
+			}
+			result := MaintenanceResult{Table: table, Task: stmt.task}
+			var vars []interface{}
+			if stmt.table {
+				vars = append(vars, clause.Table{Name: table})
+			}
+			result.SQL = db.ToSQL(func(tx *gorm.DB) *gorm.DB { return tx.Exec(stmt.sql, vars...) })
+
+			start := time.Now()
+			err := db.Exec(stmt.sql, vars...).Error
+			result.Duration = time.Since(start)
+			if err != nil {
+				result.Error = err.Error()
+				errs = append(errs, fmt.Errorf("%s %s: %w", stmt.task, table, err))
+			}
+			results = append(results, result)
+		}
+	}
+
+	if len(errs) > 0 {
+		return results, gpa.NewErrorWithCause(gpa.ErrorTypeDatabase, "maintenance failed", errors.Join(errs...))
+	}
+	return results, nil
+}
+
+// ScheduleMaintenance runs Maintain every interval until ctx is done.
+// onDone, if set, receives each run's results; failures are also logged.
+func (p *Provider) ScheduleMaintenance(ctx context.Context, interval time.Duration, spec MaintenanceSpec, onDone func([]MaintenanceResult, error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				results, err := p.Maintain(ctx, spec)
+				if err != nil && ctx.Err() == nil {
+					slog.Default().LogAttrs(context.Background(), slog.LevelWarn, "gpagorm: scheduled maintenance failed",
+						slog.String("error", err.Error()))
+				}
+				if onDone != nil {
+					onDone(results, err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,78 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintain(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	sqlDB, _ := provider.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	results, err := provider.Maintain(ctx, MaintenanceSpec{Analyze: true, Vacuum: true, Reindex: true})
+	if err != nil {
+		t.Fatalf("Failed to run maintenance: %v", err)
+	}
+	var sqls []string
+	for _, result := range results {
+		sqls = append(sqls, result.SQL)
+	}
+	got := strings.Join(sqls, "; ")
+	if got != "ANALYZE `test_users`; REINDEX `test_users`; VACUUM" {
+		t.Errorf("Unexpected maintenance statements: %s", got)
+	}
+
+	results, err = provider.Maintain(ctx, MaintenanceSpec{Tables: []string{"missing_table"}, Analyze: true})
+	if err == nil || len(results) != 1 || results[0].Error == "" {
+		t.Errorf("Expected the failure to be reported, got %+v %v", results, err)
+	}
+}
+
+func TestMaintenanceStatements(t *testing.T) {
+	spec := MaintenanceSpec{Analyze: true, Vacuum: true, Reindex: true}
+
+	pg, _ := maintenanceStatements("postgres", spec)
+	if len(pg) != 2 || pg[0].sql != "VACUUM (ANALYZE) ?" || pg[1].sql != "REINDEX TABLE ?" {
+		t.Errorf("Unexpected postgres statements: %+v", pg)
+	}
+	my, _ := maintenanceStatements("mysql", spec)
+	if len(my) != 2 || my[1].sql != "OPTIMIZE TABLE ?" {
+		t.Errorf("Unexpected mysql statements: %+v", my)
+	}
+	_, skipped := maintenanceStatements("sqlserver", spec)
+	if _, ok := skipped[MaintenanceVacuum]; !ok {
+		t.Error("Expected vacuum to be skipped on sqlserver")
+	}
+}
+
+func TestScheduleMaintenance(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	sqlDB, _ := provider.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan []MaintenanceResult, 1)
+	provider.ScheduleMaintenance(ctx, 10*time.Millisecond, MaintenanceSpec{Analyze: true}, func(results []MaintenanceResult, err error) {
+		if err == nil {
+			select {
+			case done <- results:
+			default:
+			}
+		}
+	})
+	select {
+	case results := <-done:
+		if len(results) != 1 || results[0].Task != MaintenanceAnalyze {
+			t.Errorf("Unexpected scheduled results: %+v", results)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Scheduled maintenance did not run")
+	}
+}
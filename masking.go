@@ -0,0 +1,153 @@
+// Package gpagorm provides data masking for non-production copies
+package gpagorm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultMaskingChunkSize is how many rows a masking run updates per transaction
+const defaultMaskingChunkSize = 1000
+
+// FakeEmail replaces values with a stable fake address at domain
+// ("example.invalid" when empty), so equal inputs map to the same address
+// and unique constraints keep holding. NULL stays NULL.
+func FakeEmail(domain string) MaskFunc {
+	if domain == "" {
+		domain = "example.invalid"
+	}
+	return func(value interface{}) interface{} {
+		if value == nil {
+			return nil
+		}
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		sum := sha256.Sum256([]byte(fmt.Sprint(value)))
+		return "user-" + hex.EncodeToString(sum[:8]) + "@" + domain
+	}
+}
+
+// MaskingRule masks columns of one table. Rows are walked in Key order
+// (default "id"), which must be unique.
+type MaskingRule struct {
+	Table   string
+	Key     string
+	Columns map[string]MaskFunc
+	Where   gpa.Condition // Optional filter on the rows to mask
+}
+
+// MaskingResult reports a masking run over one table
+type MaskingResult struct {
+	Table    string        `json:"table"`
+	Rows     int64         `json:"rows"`
+	Duration time.Duration `json:"duration"`
+}
+
+// MaskingRunner applies masking rules to sanitize a production snapshot,
+// updating rows in chunks so no single transaction grows too large
+type MaskingRunner struct {
+	db        *gorm.DB
+	provider  *Provider
+	rules     []MaskingRule
+	ChunkSize int
+}
+
+// NewMaskingRunner creates a masking runner for rules on db
+func NewMaskingRunner(db *gorm.DB, provider *Provider, rules ...MaskingRule) *MaskingRunner {
+	return &MaskingRunner{db: db, provider: provider, rules: rules, ChunkSize: defaultMaskingChunkSize}
+}
+
+// Run applies every rule in order. Each chunk commits on its own, so an
+// interrupted run can simply be started again.
+func (m *MaskingRunner) Run(ctx context.Context) ([]MaskingResult, error) {
+	for i := range m.rules {
+		if err := m.rules[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []MaskingResult
+	for _, rule := range m.rules {
+		start := time.Now()
+		rows, err := m.maskTable(ctx, rule)
+		results = append(results, MaskingResult{Table: rule.Table, Rows: rows, Duration: time.Since(start)})
+		if err != nil {
+			return results, convertGormError(fmt.Errorf("%s: %w", rule.Table, err))
+		}
+	}
+	return results, nil
+}
+
+// validate checks the rule's identifiers
+func (rule *MaskingRule) validate() error {
+	if rule.Key == "" {
+		rule.Key = "id"
+	}
+	if len(rule.Columns) == 0 {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "masking rule for "+rule.Table+" has no columns")
+	}
+	names := []string{rule.Table, rule.Key}
+	for column := range rule.Columns {
+		names = append(names, column)
+	}
+	for _, name := range names {
+		if !isValidFieldName(name) {
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("invalid identifier %q in masking rule", name))
+		}
+	}
+	return nil
+}
+
+// maskTable walks the table by key and masks each chunk in a transaction
+func (m *MaskingRunner) maskTable(ctx context.Context, rule MaskingRule) (int64, error) {
+	chunk := m.ChunkSize
+	if chunk <= 0 {
+		chunk = defaultMaskingChunkSize
+	}
+	db := sessionDB(ctx, m.db, m.provider)
+	key := clause.Column{Name: rule.Key}
+
+	var masked int64
+	var last interface{}
+	for {
+		query := db.Table(rule.Table).Select(maskedColumns(rule.Key, rule.Columns)).
+			Order(clause.OrderByColumn{Column: key}).Limit(chunk)
+		if rule.Where != nil {
+			// Condition building does not depend on the entity type
+			query = NewRepository[struct{}](m.db, m.provider).applyCondition(query, rule.Where)
+		}
+		if last != nil {
+			query = query.Where(clause.Gt{Column: key, Value: last})
+		}
+		var rows []map[string]interface{}
+		if err := query.Find(&rows).Error; err != nil {
+			return masked, err
+		}
+		if len(rows) == 0 {
+			return masked, nil
+		}
+
+		var n int64
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			n, err = maskRows(tx, rule.Table, rule.Key, rule.Columns, rows)
+			return err
+		})
+		if err != nil {
+			return masked, err
+		}
+		masked += n
+		if len(rows) < chunk {
+			return masked, nil
+		}
+		last = rows[len(rows)-1][rule.Key]
+	}
+}
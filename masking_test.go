@@ -0,0 +1,71 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestMaskingRunner(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	sqlDB, _ := provider.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	users := make([]*TestUser, 0, 25)
+	for i := 0; i < 25; i++ {
+		users = append(users, &TestUser{Name: fmt.Sprintf("user%02d", i), Email: fmt.Sprintf("user%02d@corp.com", i), Age: i})
+	}
+	if err := repo.CreateBatch(ctx, users); err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+
+	runner := NewMaskingRunner(provider.db, provider, MaskingRule{
+		Table: "test_users",
+		Columns: map[string]MaskFunc{
+			"email": FakeEmail(""),
+			"name":  HashValue("salt"),
+		},
+		Where: gpa.BasicCondition{FieldName: "age", Op: gpa.OpGreaterThanOrEqual, Val: 5},
+	})
+	runner.ChunkSize = 7
+	results, err := runner.Run(ctx)
+	if err != nil {
+		t.Fatalf("Failed to mask: %v", err)
+	}
+	if len(results) != 1 || results[0].Rows != 20 {
+		t.Errorf("Expected 20 masked rows, got %+v", results)
+	}
+
+	all, _ := repo.FindAll(ctx, gpa.OrderBy("age", gpa.OrderAsc))
+	emails := map[string]bool{}
+	for _, user := range all {
+		if user.Age < 5 {
+			if !strings.HasSuffix(user.Email, "@corp.com") {
+				t.Errorf("Expected filtered out user %d to be untouched, got %s", user.Age, user.Email)
+			}
+			continue
+		}
+		if !strings.HasSuffix(user.Email, "@example.invalid") || len(user.Name) != 64 {
+			t.Errorf("Expected user %d to be masked, got %s %s", user.Age, user.Name, user.Email)
+		}
+		emails[user.Email] = true
+	}
+	if len(emails) != 20 {
+		t.Errorf("Expected fake emails to stay unique, got %d distinct", len(emails))
+	}
+
+	if FakeEmail("x.test")("a@b.c") != FakeEmail("x.test")("a@b.c") {
+		t.Error("Expected fake emails to be stable")
+	}
+
+	bad := NewMaskingRunner(provider.db, provider, MaskingRule{Table: "test_users"})
+	if _, err := bad.Run(ctx); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected a rule without columns to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+// Package gpagorm provides batched membership checks
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ExistsByIDs reports for every given ID whether an entity with that primary
+// key exists, using a single SELECT ... WHERE pk IN (?) query. The returned
+// map is keyed by the IDs exactly as passed in.
+func (r *Repository[T]) ExistsByIDs(ctx context.Context, ids []interface{}) (present map[interface{}]bool, err error) {
+	opts := []gpa.QueryOption{gpa.WhereIn("id", ids)}
+	err = r.intercept(ctx, OperationInfo{Operation: OperationExists, Options: opts}, func(ctx context.Context) error {
+		var err error
+		present, err = r.existsByIDs(ctx, ids)
+		return err
+	})
+	return present, err
+}
+
+// existsByIDs implements ExistsByIDs
+func (r *Repository[T]) existsByIDs(ctx context.Context, ids []interface{}) (map[interface{}]bool, error) {
+	present := make(map[interface{}]bool, len(ids))
+	if len(ids) == 0 {
+		return present, nil
+	}
+
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return nil, gpa.NewError(gpa.ErrorTypeUnsupported, "entity has no single primary key")
+	}
+
+	if err := r.authorizeRead(ctx, []gpa.QueryOption{gpa.WhereIn(pk.DBName, ids)}); err != nil {
+		return nil, err
+	}
+
+	var found []interface{}
+	var zero T
+	result := r.replicaSession(ctx).Model(&zero).Where(pk.DBName+" IN ?", ids).Pluck(pk.DBName, &found)
+	if result.Error != nil {
+		return nil, convertGormError(result.Error)
+	}
+
+	// Drivers may return keys with a different Go type than the caller
+	// used (int64 vs int), so match on the formatted value
+	foundKeys := make(map[string]bool, len(found))
+	for _, id := range found {
+		foundKeys[idKey(id)] = true
+	}
+	for _, id := range ids {
+		present[id] = foundKeys[idKey(id)]
+	}
+	return present, nil
+}
+
+// idKey normalizes a primary key value for comparison
+func idKey(id interface{}) string {
+	if b, ok := id.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(id)
+}
+
+// schema parses the GORM schema of entity type T
+func (r *Repository[T]) schema() (*schema.Schema, error) {
+	var zero T
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(&zero); err != nil {
+		return nil, convertGormError(err)
+	}
+	return stmt.Schema, nil
+}
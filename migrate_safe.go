@@ -0,0 +1,123 @@
+// Package gpagorm provides a non-destructive migration mode
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+// MigrationReport lists what a safe migration applied and what it skipped
+type MigrationReport struct {
+	Applied []string        `json:"applied,omitempty"`
+	Skipped []SkippedChange `json:"skipped,omitempty"`
+}
+
+// SkippedChange is a change AutoMigrate would make that safe mode refused
+type SkippedChange struct {
+	Table  string `json:"table"`
+	Column string `json:"column,omitempty"`
+	Change string `json:"change"`
+	Reason string `json:"reason"`
+}
+
+// MigrateSafe migrates models applying only additive changes: new tables,
+// new nullable (or defaulted) columns and new indexes. Type, nullability and
+// default changes, NOT NULL columns without a default, and new foreign keys
+// are reported as skipped instead of applied.
+func (p *Provider) MigrateSafe(models ...interface{}) (report MigrationReport, err error) {
+	err = p.runDDL(context.Background(), p.db, func(db *gorm.DB) error {
+		report, err = migrateSafe(db, models...)
+		return err
+	})
+	return report, err
+}
+
+// safeMigrations reports whether the "safe_migrations" gorm option is set
+func (p *Provider) safeMigrations() bool {
+	enabled, _ := gormOptions(p.currentConfig())["safe_migrations"].(bool)
+	return enabled
+}
+
+// migrateSafe implements MigrateSafe on db
+func migrateSafe(db *gorm.DB, models ...interface{}) (MigrationReport, error) {
+	var report MigrationReport
+	migrator := db.Migrator()
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return report, convertGormError(err)
+		}
+
+		drift, err := detectTableDrift(db, model, stmt.Schema, stmt.Table)
+		if err != nil {
+			return report, err
+		}
+
+		if drift.Missing {
+			if err := migrator.CreateTable(model); err != nil {
+				return report, convertGormError(err)
+			}
+			report.Applied = append(report.Applied, "create table "+stmt.Table)
+			continue
+		}
+
+		for _, column := range drift.MissingColumns {
+			field := stmt.Schema.FieldsByDBName[column]
+			if field.NotNull && !field.HasDefaultValue {
+				report.Skipped = append(report.Skipped, SkippedChange{
+					Table:  stmt.Table,
+					Column: column,
+					Change: "add column",
+					Reason: "NOT NULL column without a default would fail on existing rows",
+				})
+				continue
+			}
+			if err := migrator.AddColumn(model, column); err != nil {
+				return report, convertGormError(err)
+			}
+			report.Applied = append(report.Applied, fmt.Sprintf("add column %s.%s", stmt.Table, column))
+		}
+
+		for _, index := range drift.MissingIndexes {
+			if err := migrator.CreateIndex(model, index); err != nil {
+				return report, convertGormError(err)
+			}
+			report.Applied = append(report.Applied, "create index "+index)
+		}
+
+		for _, mismatch := range drift.ColumnMismatches {
+			report.Skipped = append(report.Skipped, SkippedChange{
+				Table:  stmt.Table,
+				Column: mismatch.Column,
+				Change: fmt.Sprintf("alter %s from %q to %q", mismatch.Kind, mismatch.Actual, mismatch.Expected),
+				Reason: "altering an existing column may narrow or rewrite data",
+			})
+		}
+
+		for _, constraint := range drift.MissingForeignKeys {
+			report.Skipped = append(report.Skipped, SkippedChange{
+				Table:  stmt.Table,
+				Change: "add foreign key " + constraint,
+				Reason: "new constraints may reject existing rows",
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// logSkippedChanges warns about each change a safe migration skipped
+func logSkippedChanges(report MigrationReport) {
+	for _, skipped := range report.Skipped {
+		slog.Default().LogAttrs(context.Background(), slog.LevelWarn, "gpagorm: safe migration skipped change",
+			slog.String("table", skipped.Table),
+			slog.String("column", skipped.Column),
+			slog.String("change", skipped.Change),
+			slog.String("reason", skipped.Reason),
+		)
+	}
+}
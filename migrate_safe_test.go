@@ -0,0 +1,80 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestMigrateSafe(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	if err := provider.db.Exec("CREATE TABLE drift_items (id integer PRIMARY KEY, status text DEFAULT 'pending', legacy text)").Error; err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	report, err := provider.MigrateSafe(&TestDriftItem{})
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	migrator := provider.db.Migrator()
+	if !migrator.HasColumn(&TestDriftItem{}, "sku") || !migrator.HasIndex(&TestDriftItem{}, "idx_drift_items_sku") {
+		t.Error("Expected nullable sku column and its index to be added")
+	}
+	if migrator.HasColumn(&TestDriftItem{}, "name") {
+		t.Error("Expected NOT NULL name column without default to be skipped")
+	}
+	if !migrator.HasColumn(&TestDriftItem{}, "legacy") {
+		t.Error("Expected extra column to be kept")
+	}
+
+	skipped := map[string]bool{}
+	for _, change := range report.Skipped {
+		skipped[change.Column] = true
+	}
+	if !skipped["name"] || !skipped["status"] || len(report.Skipped) != 2 {
+		t.Errorf("Expected name and status changes to be skipped, got %+v", report.Skipped)
+	}
+	if len(report.Applied) != 2 {
+		t.Errorf("Expected two applied changes, got %v", report.Applied)
+	}
+
+	if _, err := provider.MigrateSafe(&TestDevice{}); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if !migrator.HasTable(&TestDevice{}) {
+		t.Error("Expected missing table to be created")
+	}
+}
+
+func TestMigrateSafeOption(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	if err := provider.db.Exec("CREATE TABLE drift_items (id integer PRIMARY KEY, status text)").Error; err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	provider.Configure(gpa.Config{
+		Driver:   "sqlite",
+		Database: ":memory:",
+		Options:  map[string]interface{}{"gorm": map[string]interface{}{"safe_migrations": true}},
+	})
+
+	if err := provider.Migrate(&TestDriftItem{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if provider.db.Migrator().HasColumn(&TestDriftItem{}, "name") {
+		t.Error("Expected Migrate to skip the NOT NULL column in safe mode")
+	}
+
+	repo := NewRepository[TestDriftItem](provider.db, provider)
+	if err := repo.MigrateTable(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate table: %v", err)
+	}
+	if provider.db.Migrator().HasColumn(&TestDriftItem{}, "name") {
+		t.Error("Expected MigrateTable to skip the NOT NULL column in safe mode")
+	}
+}
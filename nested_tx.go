@@ -0,0 +1,75 @@
+// Package gpagorm provides savepoint-scoped nested transactions
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// savepointSeq numbers the savepoints created by RunNested
+var savepointSeq atomic.Uint64
+
+// RunNested runs fn inside a savepoint of the transaction. If fn returns an
+// error or panics, only its changes are rolled back and the surrounding
+// transaction can carry on; otherwise the savepoint is released. Calls may
+// nest.
+//
+//	err := repo.Transaction(ctx, func(tx gpa.Transaction[Order]) error {
+//	    // ...
+//	    if err := tx.(*gpagorm.Transaction[Order]).RunNested(ctx, applyDiscount); err != nil {
+//	        log.Printf("discount skipped: %v", err)
+//	    }
+//	    return nil
+//	})
+func (t *Transaction[T]) RunNested(ctx context.Context, fn gpa.TransactionFunc[T]) (err error) {
+	name := fmt.Sprintf("gpagorm_sp_%d", savepointSeq.Add(1))
+	tx := t.db.WithContext(ctx)
+	if err := tx.SavePoint(name).Error; err != nil {
+		return convertGormError(err)
+	}
+
+	nested := &Transaction[T]{Repository: t.Repository.clone()}
+	nested.db = tx
+	if t.changes != nil {
+		// Changes rolled back with the savepoint are not reported
+		nested.changes = &changeSet{}
+	}
+
+	done := false
+	defer func() {
+		if !done {
+			// fn panicked; undo its changes before the panic unwinds
+			tx.RollbackTo(name)
+		}
+	}()
+	err = fn(nested)
+	done = true
+
+	if err != nil {
+		if rbErr := tx.RollbackTo(name).Error; rbErr != nil {
+			return errors.Join(err, convertGormError(rbErr))
+		}
+		return err
+	}
+	if err := releaseSavepoint(tx, name); err != nil {
+		return convertGormError(err)
+	}
+	if nested.changes != nil {
+		t.changes.merge(nested.changes)
+	}
+	return nil
+}
+
+// releaseSavepoint discards a savepoint that is no longer needed; SQL
+// Server has no release and frees savepoints with the transaction
+func releaseSavepoint(tx *gorm.DB, name string) error {
+	if dialectName(tx) == "sqlserver" {
+		return nil
+	}
+	return tx.Exec("RELEASE SAVEPOINT " + name).Error
+}
@@ -0,0 +1,85 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestRunNested(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+	failed := errors.New("nested failed")
+
+	err := repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		outer := tx.(*Transaction[TestUser])
+		if err := outer.Create(ctx, &TestUser{Name: "Alice", Email: "alice@example.com"}); err != nil {
+			return err
+		}
+
+		// A failed nested step is undone without aborting the transaction
+		err := outer.RunNested(ctx, func(nested gpa.Transaction[TestUser]) error {
+			if err := nested.Create(ctx, &TestUser{Name: "Bob", Email: "bob@example.com"}); err != nil {
+				return err
+			}
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Errorf("Expected nested error, got %v", err)
+		}
+
+		// Nested savepoints commit with the transaction
+		return outer.RunNested(ctx, func(nested gpa.Transaction[TestUser]) error {
+			if err := nested.Create(ctx, &TestUser{Name: "Carol", Email: "carol@example.com"}); err != nil {
+				return err
+			}
+			return nested.(*Transaction[TestUser]).RunNested(ctx, func(inner gpa.Transaction[TestUser]) error {
+				return inner.Create(ctx, &TestUser{Name: "Dave", Email: "dave@example.com"})
+			})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	users, err := repo.Query(ctx, gpa.OrderBy("name", gpa.OrderAsc))
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var names []string
+	for _, u := range users {
+		names = append(names, u.Name)
+	}
+	if len(names) != 3 || names[0] != "Alice" || names[1] != "Carol" || names[2] != "Dave" {
+		t.Errorf("Expected Alice, Carol and Dave, got %v", names)
+	}
+}
+
+func TestRunNestedPanic(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	err := repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		outer := tx.(*Transaction[TestUser])
+		func() {
+			defer func() { recover() }()
+			outer.RunNested(ctx, func(nested gpa.Transaction[TestUser]) error {
+				nested.Create(ctx, &TestUser{Name: "Bob", Email: "bob@example.com"})
+				panic("boom")
+			})
+		}()
+		return outer.Create(ctx, &TestUser{Name: "Alice", Email: "alice@example.com"})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if count, _ := repo.Count(ctx); count != 1 {
+		t.Errorf("Expected only Alice after the panicking step, got %d users", count)
+	}
+}
@@ -0,0 +1,393 @@
+// Package gpagorm provides an OData-style query string dialect
+package gpagorm
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ParseOData compiles the OData system query options in values into query
+// options for the repository's entity:
+//
+//	$filter=status eq 'active' and (age ge 18 or contains(name, 'ann'))
+//	$orderby=createdAt desc,name
+//	$top=20&$skip=40&$select=id,name
+//
+// $filter supports eq, ne, gt, ge, lt, le, in, and, or, not, parentheses,
+// the contains, startswith and endswith functions, and tolower, toupper
+// and length on the left of a comparison. Literals are 'strings' (with ''
+// escaping), numbers, true, false, null and ISO 8601 dates. Property names
+// are resolved against the entity like SelectFields names, so only columns
+// can be filtered; values are always bound, never inlined. Parameters
+// without a $ prefix are ignored; unsupported $ options are errors.
+func (r *Repository[T]) ParseOData(values url.Values) ([]gpa.QueryOption, error) {
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+	var opts []gpa.QueryOption
+	for name := range values {
+		if !strings.HasPrefix(name, "$") {
+			continue
+		}
+		switch name {
+		case "$filter", "$orderby", "$top", "$skip", "$select":
+		default:
+			return nil, gpa.NewError(gpa.ErrorTypeUnsupported, "unsupported OData option "+name)
+		}
+	}
+
+	if filter := values.Get("$filter"); filter != "" {
+		p := &odataParser{schema: s}
+		if err := p.tokenize(filter); err != nil {
+			return nil, err
+		}
+		sql, args, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.tokens) {
+			return nil, p.errorf("unexpected %q", p.tokens[p.pos])
+		}
+		opts = append(opts, RawCondition(sql, args...))
+	}
+
+	if orderby := values.Get("$orderby"); orderby != "" {
+		for _, item := range strings.Split(orderby, ",") {
+			words := strings.Fields(item)
+			if len(words) == 0 || len(words) > 2 {
+				return nil, odataError("$orderby", "malformed item %q", item)
+			}
+			column, err := odataColumn(s, words[0])
+			if err != nil {
+				return nil, err
+			}
+			direction := gpa.OrderAsc
+			if len(words) == 2 {
+				switch strings.ToLower(words[1]) {
+				case "asc":
+				case "desc":
+					direction = gpa.OrderDesc
+				default:
+					return nil, odataError("$orderby", "unknown direction %q", words[1])
+				}
+			}
+			opts = append(opts, gpa.OrderBy(column, direction))
+		}
+	}
+
+	for _, option := range []string{"$top", "$skip"} {
+		raw := values.Get(option)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return nil, odataError(option, "%q is not a non-negative integer", raw)
+		}
+		if option == "$top" {
+			opts = append(opts, gpa.Limit(n))
+		} else {
+			opts = append(opts, gpa.Offset(n))
+		}
+	}
+
+	if sel := values.Get("$select"); sel != "" && sel != "*" {
+		var columns []string
+		for _, name := range strings.Split(sel, ",") {
+			column, err := odataColumn(s, strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, column)
+		}
+		opts = append(opts, gpa.Select(columns...))
+	}
+	return opts, nil
+}
+
+// odataError returns an invalid argument error for an OData option
+func odataError(option, format string, args ...interface{}) error {
+	return gpa.NewError(gpa.ErrorTypeInvalidArgument, option+": "+fmt.Sprintf(format, args...))
+}
+
+// odataColumn resolves an OData property name to a column of s
+func odataColumn(s *schema.Schema, name string) (string, error) {
+	field := lookUpSelectionField(s, name)
+	if field == nil || field.DBName == "" {
+		return "", gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("%s has no property %s", s.Name, name))
+	}
+	return field.DBName, nil
+}
+
+// odataToken matches the tokens of a $filter expression
+var odataToken = regexp.MustCompile(`\s*(?:('(?:[^']|'')*')|([(),])|([^\s(),']+))`)
+
+// odataComparisons maps OData comparison operators to SQL
+var odataComparisons = map[string]string{"eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<="}
+
+// odataLikes maps OData string predicates to LIKE pattern wrappers
+var odataLikes = map[string][2]string{"contains": {"%", "%"}, "startswith": {"", "%"}, "endswith": {"%", ""}}
+
+// odataValueFunctions maps OData functions of a property to SQL
+var odataValueFunctions = map[string]string{"tolower": "LOWER(?)", "toupper": "UPPER(?)", "length": "LENGTH(?)"}
+
+// odataLikeEscaper escapes LIKE wildcards with '!', which every supported
+// dialect accepts as an ESCAPE character without further quoting
+var odataLikeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_", "[", "![")
+
+// odataParser is a recursive descent parser for $filter
+type odataParser struct {
+	schema *schema.Schema
+	tokens []string
+	pos    int
+}
+
+// tokenize splits filter into tokens
+func (p *odataParser) tokenize(filter string) error {
+	rest := strings.TrimSpace(filter)
+	for rest != "" {
+		m := odataToken.FindStringIndex(rest)
+		if m == nil || m[0] != 0 {
+			return p.errorf("cannot parse %q", rest)
+		}
+		p.tokens = append(p.tokens, strings.TrimSpace(rest[:m[1]]))
+		rest = strings.TrimSpace(rest[m[1]:])
+	}
+	if len(p.tokens) == 0 {
+		return p.errorf("empty expression")
+	}
+	return nil
+}
+
+// errorf returns a $filter syntax error
+func (p *odataParser) errorf(format string, args ...interface{}) error {
+	return odataError("$filter", format, args...)
+}
+
+// peek returns the next token lowercased, or ""
+func (p *odataParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return strings.ToLower(p.tokens[p.pos])
+}
+
+// next consumes and returns the next token
+func (p *odataParser) next() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", p.errorf("unexpected end of expression")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+// expect consumes token or fails
+func (p *odataParser) expect(token string) error {
+	got, err := p.next()
+	if err != nil {
+		return err
+	}
+	if got != token {
+		return p.errorf("expected %q, got %q", token, got)
+	}
+	return nil
+}
+
+// parseOr parses and-expressions joined by or
+func (p *odataParser) parseOr() (string, []interface{}, error) {
+	return p.parseJoined("or", p.parseAnd)
+}
+
+// parseAnd parses unary expressions joined by and
+func (p *odataParser) parseAnd() (string, []interface{}, error) {
+	return p.parseJoined("and", p.parseNot)
+}
+
+// parseJoined parses operands of parse joined by keyword
+func (p *odataParser) parseJoined(keyword string, parse func() (string, []interface{}, error)) (string, []interface{}, error) {
+	sql, args, err := parse()
+	if err != nil {
+		return "", nil, err
+	}
+	for p.peek() == keyword {
+		p.pos++
+		right, rightArgs, err := parse()
+		if err != nil {
+			return "", nil, err
+		}
+		sql += " " + strings.ToUpper(keyword) + " " + right
+		args = append(args, rightArgs...)
+	}
+	return sql, args, nil
+}
+
+// parseNot parses an optionally negated primary expression
+func (p *odataParser) parseNot() (string, []interface{}, error) {
+	if p.peek() == "not" {
+		p.pos++
+		sql, args, err := p.parseNot()
+		return "NOT " + sql, args, err
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses a parenthesized expression, a string predicate or a
+// comparison
+func (p *odataParser) parsePrimary() (string, []interface{}, error) {
+	token := p.peek()
+	if token == "(" {
+		p.pos++
+		sql, args, err := p.parseOr()
+		if err != nil {
+			return "", nil, err
+		}
+		return "(" + sql + ")", args, p.expect(")")
+	}
+	if wrap, ok := odataLikes[token]; ok {
+		p.pos++
+		column, err := p.parseCall()
+		if err != nil {
+			return "", nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return "", nil, err
+		}
+		value, err := p.parseLiteral()
+		if err != nil {
+			return "", nil, err
+		}
+		text, ok := value.(string)
+		if !ok {
+			return "", nil, p.errorf("%s needs a string", token)
+		}
+		pattern := wrap[0] + odataLikeEscaper.Replace(text) + wrap[1]
+		return "? LIKE ? ESCAPE '!'", []interface{}{column, pattern}, p.expect(")")
+	}
+	return p.parseComparison()
+}
+
+// parseCall parses "(" property after a function name
+func (p *odataParser) parseCall() (clause.Column, error) {
+	if err := p.expect("("); err != nil {
+		return clause.Column{}, err
+	}
+	return p.parseProperty()
+}
+
+// parseProperty parses a property name into its column
+func (p *odataParser) parseProperty() (clause.Column, error) {
+	name, err := p.next()
+	if err != nil {
+		return clause.Column{}, err
+	}
+	column, err := odataColumn(p.schema, name)
+	if err != nil {
+		return clause.Column{}, err
+	}
+	return clause.Column{Name: column}, nil
+}
+
+// parseComparison parses "operand op literal" and "operand in (literals)"
+func (p *odataParser) parseComparison() (string, []interface{}, error) {
+	left := "?"
+	var column clause.Column
+	var err error
+	if fn, ok := odataValueFunctions[p.peek()]; ok {
+		p.pos++
+		if column, err = p.parseCall(); err != nil {
+			return "", nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return "", nil, err
+		}
+		left = fn
+	} else if column, err = p.parseProperty(); err != nil {
+		return "", nil, err
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return "", nil, err
+	}
+	op = strings.ToLower(op)
+	if op == "in" {
+		if err := p.expect("("); err != nil {
+			return "", nil, err
+		}
+		var list []interface{}
+		for {
+			value, err := p.parseLiteral()
+			if err != nil {
+				return "", nil, err
+			}
+			list = append(list, value)
+			if p.peek() != "," {
+				break
+			}
+			p.pos++
+		}
+		return left + " IN ?", []interface{}{column, list}, p.expect(")")
+	}
+
+	sqlOp, ok := odataComparisons[op]
+	if !ok {
+		return "", nil, p.errorf("unknown operator %q", op)
+	}
+	value, err := p.parseLiteral()
+	if err != nil {
+		return "", nil, err
+	}
+	if value == nil {
+		switch op {
+		case "eq":
+			return left + " IS NULL", []interface{}{column}, nil
+		case "ne":
+			return left + " IS NOT NULL", []interface{}{column}, nil
+		}
+		return "", nil, p.errorf("null can only be compared with eq or ne")
+	}
+	return left + " " + sqlOp + " ?", []interface{}{column, value}, nil
+}
+
+// odataDateLayouts are the accepted layouts of unquoted date literals
+var odataDateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+// parseLiteral parses a string, number, boolean, null or date literal
+func (p *odataParser) parseLiteral() (interface{}, error) {
+	token, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(token, "'") {
+		return strings.ReplaceAll(token[1:len(token)-1], "''", "'"), nil
+	}
+	switch strings.ToLower(token) {
+	case "null":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(token, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(token, 64); err == nil {
+		return f, nil
+	}
+	for _, layout := range odataDateLayouts {
+		if t, err := time.Parse(layout, token); err == nil {
+			return t, nil
+		}
+	}
+	return nil, p.errorf("invalid literal %q", token)
+}
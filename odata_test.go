@@ -0,0 +1,84 @@
+package gpagorm
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestParseOData(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	for _, u := range []TestUser{
+		{Name: "Ann", Email: "ann@example.com", Age: 31},
+		{Name: "Bob", Email: "bob@example.com", Age: 17},
+		{Name: "Cid", Email: "cid_1@example.org", Age: 45},
+		{Name: "O'Hara", Email: "ohara@example.com", Age: 52},
+	} {
+		repo.Create(ctx, &u)
+	}
+
+	tests := []struct {
+		query string
+		names []string
+	}{
+		{"$filter=age ge 18 and age lt 50&$orderby=name desc", []string{"Cid", "Ann"}},
+		{"$filter=not (age gt 18) or endswith(email, '.org')&$orderby=age", []string{"Bob", "Cid"}},
+		{"$filter=name in ('Ann', 'O''Hara')&$orderby=Name", []string{"Ann", "O'Hara"}},
+		{"$filter=contains(email, '_1')", []string{"Cid"}},
+		{"$filter=tolower(name) eq 'bob'", []string{"Bob"}},
+		{"$filter=name ne null&$orderby=age desc&$top=2&$skip=1&$select=id,name", []string{"Cid", "Ann"}},
+		{"page=3&$orderby=age", []string{"Bob", "Ann", "Cid", "O'Hara"}},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		opts, err := repo.ParseOData(values)
+		if err != nil {
+			t.Errorf("%s: failed to parse: %v", tt.query, err)
+			continue
+		}
+		users, err := repo.Query(ctx, opts...)
+		if err != nil {
+			t.Errorf("%s: failed to query: %v", tt.query, err)
+			continue
+		}
+		var names []string
+		for _, u := range users {
+			names = append(names, u.Name)
+		}
+		if len(names) != len(tt.names) {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.names, names)
+			continue
+		}
+		for i := range names {
+			if names[i] != tt.names[i] {
+				t.Errorf("%s: expected %v, got %v", tt.query, tt.names, names)
+				break
+			}
+		}
+	}
+
+	for _, query := range []string{
+		"$filter=password eq 'x'",
+		"$filter=age eq",
+		"$filter=age gt null",
+		"$filter=(age eq 1",
+		"$filter=age eq 1 age",
+		"$orderby=age sideways",
+		"$top=-1",
+		"$select=id,secret",
+	} {
+		values, _ := url.ParseQuery(query)
+		if _, err := repo.ParseOData(values); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+			t.Errorf("%s: expected invalid argument, got %v", query, err)
+		}
+	}
+	if _, err := repo.ParseOData(url.Values{"$expand": {"posts"}}); !gpa.IsErrorType(err, gpa.ErrorTypeUnsupported) {
+		t.Errorf("Expected $expand to be unsupported, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+// Package gpagorm provides ordering by expressions, NULL placement and value priority
+package gpagorm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NullsOrder places NULLs before or after the other values
+type NullsOrder string
+
+const (
+	// NullsFirst sorts NULLs before every other value
+	NullsFirst NullsOrder = "FIRST"
+	// NullsLast sorts NULLs after every other value
+	NullsLast NullsOrder = "LAST"
+)
+
+// SortOption orders by a field or expression beyond what gpa.OrderBy
+// expresses. It carries no state for gpa.Query; buildQuery reads it, in
+// order with gpa.OrderBy options.
+type SortOption struct {
+	Field     string             // Column to order by, validated like other fields
+	Expr      string             // Raw SQL expression, used when Field is empty
+	Args      []interface{}      // Bound to the placeholders of Expr
+	Direction gpa.OrderDirection // Defaults to gpa.OrderAsc
+	Nulls     NullsOrder         // Optional NULL placement
+	Values    []interface{}      // Order by position of the field's value in Values
+}
+
+// Apply implements gpa.QueryOption
+func (o SortOption) Apply(query *gpa.Query) {}
+
+// OrderByExpr orders by a SQL expression with bound arguments:
+//
+//	gpagorm.OrderByExpr("LOWER(name)", gpa.OrderAsc)
+//	gpagorm.OrderByExpr("CASE WHEN status = ? THEN 0 ELSE 1 END", gpa.OrderAsc, "urgent")
+//
+// The expression is written into the query as is, so it must not be built
+// from user input; pass values as args instead.
+func OrderByExpr(expr string, direction gpa.OrderDirection, args ...interface{}) gpa.QueryOption {
+	return SortOption{Expr: expr, Args: args, Direction: direction}
+}
+
+// OrderByNulls orders by field with NULLs first or last. Postgres and
+// SQLite use NULLS FIRST/LAST; MySQL and SQL Server sort on a NULL test
+// first.
+func OrderByNulls(field string, direction gpa.OrderDirection, nulls NullsOrder) gpa.QueryOption {
+	return SortOption{Field: field, Direction: direction, Nulls: nulls}
+}
+
+// OrderByValues orders rows by the position of field's value in values,
+// with other values after them, e.g. a custom status priority:
+//
+//	gpagorm.OrderByValues("status", "urgent", "open", "closed")
+func OrderByValues(field string, values ...interface{}) gpa.QueryOption {
+	return SortOption{Field: field, Values: values}
+}
+
+// applyOrdering adds the gpa.OrderBy and SortOption orderings of opts
+func (r *Repository[T]) applyOrdering(db *gorm.DB, opts []gpa.QueryOption) *gorm.DB {
+	var orders []clause.Expr
+	bound := false
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case gpa.OrderOption:
+			field, err := fieldExpr(db, o.Order.Field)
+			if err != nil {
+				db.AddError(err)
+				return db
+			}
+			direction, err := orderDirection(o.Order.Direction)
+			if err != nil {
+				db.AddError(err)
+				return db
+			}
+			expr, _ := r.collateExpr(db, o.Order.Field, field)
+			orders = append(orders, clause.Expr{SQL: expr + " " + direction})
+		case SortOption:
+			exprs, err := r.sortExprs(db, o)
+			if err != nil {
+				db.AddError(err)
+				return db
+			}
+			for _, expr := range exprs {
+				bound = bound || len(expr.Vars) > 0
+			}
+			orders = append(orders, exprs...)
+		}
+	}
+
+	if !bound {
+		// Plain columns merge with orderings added later, e.g. by batching
+		for _, order := range orders {
+			db = db.Order(order.SQL)
+		}
+		return db
+	}
+	exprs := make([]clause.Expression, len(orders))
+	for i, order := range orders {
+		exprs[i] = order
+	}
+	return db.Clauses(clause.OrderBy{Expression: clause.CommaExpression{Exprs: exprs}})
+}
+
+// sortExprs returns the ORDER BY items of o for the dialect
+func (r *Repository[T]) sortExprs(db *gorm.DB, o SortOption) ([]clause.Expr, error) {
+	direction, err := orderDirection(o.Direction)
+	if err != nil {
+		return nil, err
+	}
+
+	expr := o.Expr
+	if o.Field != "" {
+		if expr, err = fieldExpr(db, o.Field); err != nil {
+			return nil, err
+		}
+	} else if strings.TrimSpace(expr) == "" {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "ordering needs a field or an expression")
+	}
+
+	if len(o.Values) > 0 {
+		// CASE works everywhere, unlike FIELD() or array_position()
+		var sql strings.Builder
+		sql.WriteString("CASE " + expr)
+		for i := range o.Values {
+			fmt.Fprintf(&sql, " WHEN ? THEN %d", i)
+		}
+		fmt.Fprintf(&sql, " ELSE %d END %s", len(o.Values), direction)
+		return []clause.Expr{{SQL: sql.String(), Vars: append(append([]interface{}{}, o.Args...), o.Values...)}}, nil
+	}
+
+	if o.Field != "" {
+		expr, _ = r.collateExpr(db, o.Field, expr)
+	}
+	order := clause.Expr{SQL: expr + " " + direction, Vars: o.Args}
+	switch o.Nulls {
+	case "":
+		return []clause.Expr{order}, nil
+	case NullsFirst, NullsLast:
+	default:
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "invalid NULLs order: "+string(o.Nulls))
+	}
+
+	switch dialectName(db) {
+	case "mysql", "sqlserver":
+		// NULL tests sort 0 before 1
+		nullTest := "CASE WHEN " + expr + " IS NULL THEN 1 ELSE 0 END"
+		if dialectName(db) == "mysql" {
+			nullTest = expr + " IS NULL"
+		}
+		if o.Nulls == NullsFirst {
+			nullTest += " DESC"
+		}
+		return []clause.Expr{{SQL: nullTest, Vars: o.Args}, order}, nil
+	default:
+		order.SQL += " NULLS " + string(o.Nulls)
+		return []clause.Expr{order}, nil
+	}
+}
@@ -0,0 +1,91 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+type testTicket struct {
+	ID       uint
+	Title    string
+	Status   string
+	Assignee *string
+}
+
+func ticketTitles(tickets []*testTicket) string {
+	titles := make([]string, len(tickets))
+	for i, ticket := range tickets {
+		titles[i] = ticket.Title
+	}
+	return strings.Join(titles, ",")
+}
+
+func TestOrdering(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&testTicket{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[testTicket](provider.db, provider)
+	ctx := context.Background()
+
+	ann := "ann"
+	for _, ticket := range []*testTicket{
+		{Title: "b", Status: "closed", Assignee: &ann},
+		{Title: "A", Status: "open"},
+		{Title: "c", Status: "urgent", Assignee: &ann},
+		{Title: "D", Status: "open", Assignee: &ann},
+	} {
+		if err := repo.Create(ctx, ticket); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts []gpa.QueryOption
+		want string
+	}{
+		{"expression", []gpa.QueryOption{OrderByExpr("LOWER(title)", gpa.OrderDesc)}, "D,c,b,A"},
+		{"expression with args", []gpa.QueryOption{OrderByExpr("CASE WHEN status = ? THEN 0 ELSE 1 END", gpa.OrderAsc, "open"), gpa.OrderBy("title", gpa.OrderAsc)}, "A,D,b,c"},
+		{"nulls last", []gpa.QueryOption{OrderByNulls("assignee", gpa.OrderAsc, NullsLast), gpa.OrderBy("id", gpa.OrderAsc)}, "b,c,D,A"},
+		{"nulls first", []gpa.QueryOption{OrderByNulls("assignee", gpa.OrderDesc, NullsFirst), gpa.OrderBy("id", gpa.OrderAsc)}, "A,b,c,D"},
+		{"value priority", []gpa.QueryOption{OrderByValues("status", "urgent", "open"), gpa.OrderBy("title", gpa.OrderDesc)}, "c,D,A,b"},
+	}
+	for _, tt := range tests {
+		tickets, err := repo.Query(ctx, tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: Query failed: %v", tt.name, err)
+		}
+		if got := ticketTitles(tickets); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+
+	if _, err := repo.Query(ctx, OrderByNulls("assignee; DROP", gpa.OrderAsc, NullsLast)); err == nil {
+		t.Error("Expected invalid field to be rejected")
+	}
+	if _, err := repo.Query(ctx, OrderByExpr("title", "SIDEWAYS")); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid direction to be rejected, got %v", err)
+	}
+}
+
+func TestOrderByNullsMySQL(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user@tcp(localhost)/test", SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry-run mysql: %v", err)
+	}
+	repo := NewRepository[testTicket](db, nil)
+
+	var tickets []*testTicket
+	sql := repo.buildQuery(context.Background(), OrderByNulls("assignee", gpa.OrderAsc, NullsLast)).
+		Find(&tickets).Statement.SQL.String()
+	if !strings.Contains(sql, "ORDER BY `assignee` IS NULL,`assignee` ASC") {
+		t.Errorf("Unexpected SQL: %s", sql)
+	}
+}
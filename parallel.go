@@ -0,0 +1,137 @@
+// Package gpagorm provides parallel chunked processing over primary key ranges
+package gpagorm
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// keyRange is a closed primary key range [from, to]
+type keyRange struct {
+	from, to int64
+}
+
+// ProcessInParallel splits the primary key space into ranges of chunkSize
+// keys and hands the entities in each range that match opts to fn, with at
+// most workers chunks in flight. Chunks run in no particular order, and
+// sparse keys give smaller chunks. The first error stops the remaining
+// chunks; when ctx is cancelled BatchProgress reports the rows processed.
+// The entity must have a single integer primary key.
+func (r *Repository[T]) ProcessInParallel(ctx context.Context, workers int, chunkSize int, fn func(batch []*T) error, opts ...gpa.QueryOption) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationProcessInParallel, Options: opts}, func(ctx context.Context) error {
+		return r.processInParallel(ctx, workers, chunkSize, fn, opts...)
+	})
+}
+
+// processInParallel implements ProcessInParallel
+func (r *Repository[T]) processInParallel(ctx context.Context, workers int, chunkSize int, fn func(batch []*T) error, opts ...gpa.QueryOption) error {
+	if workers <= 0 || chunkSize <= 0 {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "workers and chunk size must be positive")
+	}
+	if err := r.authorizeRead(ctx, opts); err != nil {
+		return err
+	}
+
+	s, err := r.schema()
+	if err != nil {
+		return err
+	}
+	pk := s.PrioritizedPrimaryField
+	if pk == nil || (pk.DataType != schema.Int && pk.DataType != schema.Uint) {
+		return gpa.NewError(gpa.ErrorTypeUnsupported, "parallel processing requires a single integer primary key")
+	}
+
+	var lo, hi sql.NullInt64
+	var zero T
+	column := clause.Column{Table: clause.CurrentTable, Name: pk.DBName}
+	row := r.replicaSession(ctx).Model(&zero).Select("MIN(?), MAX(?)", column, column).Row()
+	if err := row.Scan(&lo, &hi); err != nil {
+		return convertGormError(err)
+	}
+	if !lo.Valid {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ranges := make(chan keyRange)
+	go func() {
+		defer close(ranges)
+		// Bounds are inclusive and the distance to hi is checked before
+		// adding, as unsigned so it cannot overflow, so keys near the ends
+		// of int64 neither wrap nor end the loop early
+		step := int64(chunkSize)
+		for from := lo.Int64; ; from += step {
+			last := uint64(hi.Int64)-uint64(from) < uint64(step)
+			to := hi.Int64
+			if !last {
+				to = from + step - 1
+			}
+			select {
+			case ranges <- keyRange{from: from, to: to}:
+			case <-ctx.Done():
+				return
+			}
+			if last {
+				return
+			}
+		}
+	}()
+
+	var (
+		wg        sync.WaitGroup
+		once      sync.Once
+		firstErr  error
+		completed atomic.Int64
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for kr := range ranges {
+				var batch []*T
+				err := r.buildQuery(ctx, opts...).
+					Where(clause.Gte{Column: column, Value: kr.from}).
+					Where(clause.Lte{Column: column, Value: kr.to}).
+					Order(clause.OrderByColumn{Column: column}).
+					Find(&batch).Error
+				if err != nil {
+					fail(convertGormError(err))
+					return
+				}
+				if len(batch) == 0 {
+					continue
+				}
+				if err := fn(batch); err != nil {
+					fail(err)
+					return
+				}
+				completed.Add(int64(len(batch)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// cancel only fires on failure, so a done ctx without one is the caller's
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr == nil {
+		return nil
+	}
+	return partialProgress(OperationProcessInParallel, int(completed.Load()), firstErr)
+}
@@ -0,0 +1,154 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestProcessInParallel(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	sqlDB, _ := provider.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	users := make([]*TestUser, 0, 100)
+	for i := 0; i < 100; i++ {
+		users = append(users, &TestUser{Name: fmt.Sprintf("user%03d", i), Email: fmt.Sprintf("user%03d@example.com", i), Age: i})
+	}
+	if err := repo.CreateBatch(ctx, users); err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+
+	var (
+		mu       sync.Mutex
+		seen     = map[uint]bool{}
+		inFlight atomic.Int32
+		peak     atomic.Int32
+	)
+	err := repo.ProcessInParallel(ctx, 4, 10, func(batch []*TestUser) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		if len(batch) > 10 {
+			t.Errorf("Expected chunks of at most 10, got %d", len(batch))
+		}
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, user := range batch {
+			if seen[user.ID] {
+				t.Errorf("User %d processed twice", user.ID)
+			}
+			seen[user.ID] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to process users: %v", err)
+	}
+	if len(seen) != 100 {
+		t.Errorf("Expected 100 users processed, got %d", len(seen))
+	}
+	if peak.Load() > 4 {
+		t.Errorf("Expected at most 4 chunks in flight, got %d", peak.Load())
+	}
+
+	var filtered atomic.Int32
+	err = repo.ProcessInParallel(ctx, 3, 7, func(batch []*TestUser) error {
+		filtered.Add(int32(len(batch)))
+		return nil
+	}, gpa.Where("age", gpa.OpGreaterThanOrEqual, 50))
+	if err != nil || filtered.Load() != 50 {
+		t.Errorf("Expected 50 filtered users, got %d %v", filtered.Load(), err)
+	}
+
+	boom := errors.New("boom")
+	err = repo.ProcessInParallel(ctx, 2, 10, func(batch []*TestUser) error { return boom })
+	if !errors.Is(err, boom) {
+		t.Errorf("Expected the callback error, got %v", err)
+	}
+
+	if err := repo.ProcessInParallel(ctx, 0, 10, func([]*TestUser) error { return nil }); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid worker count error, got %v", err)
+	}
+}
+
+func TestProcessInParallelCancelled(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	sqlDB, _ := provider.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	users := make([]*TestUser, 0, 50)
+	for i := 0; i < 50; i++ {
+		users = append(users, &TestUser{Name: fmt.Sprintf("user%02d", i), Email: fmt.Sprintf("user%02d@example.com", i)})
+	}
+	if err := repo.CreateBatch(context.Background(), users); err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := repo.ProcessInParallel(ctx, 1, 10, func(batch []*TestUser) error {
+		cancel()
+		return nil
+	})
+	if !gpa.IsErrorType(err, gpa.ErrorTypeTimeout) {
+		t.Fatalf("Expected a timeout error, got %v", err)
+	}
+	if completed, ok := BatchProgress(err); !ok || completed != 10 {
+		t.Errorf("Expected progress of 10 rows, got %d %v", completed, ok)
+	}
+}
+
+// TestSignedKey has an int64 key spanning the whole key space
+type TestSignedKey struct {
+	ID   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name string
+}
+
+func TestProcessInParallelNearMaxKey(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&TestSignedKey{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	repo := NewRepository[TestSignedKey](provider.db, provider)
+	keys := []int64{math.MaxInt64 - 25, math.MaxInt64 - 1, math.MaxInt64}
+	for _, id := range keys {
+		if err := repo.Create(ctx, &TestSignedKey{ID: id, Name: "near max"}); err != nil {
+			t.Fatalf("Failed to create row: %v", err)
+		}
+	}
+
+	var seen atomic.Int32
+	err := repo.ProcessInParallel(ctx, 2, 10, func(batch []*TestSignedKey) error {
+		seen.Add(int32(len(batch)))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to process rows: %v", err)
+	}
+	if int(seen.Load()) != len(keys) {
+		t.Errorf("Expected %d rows processed, got %d", len(keys), seen.Load())
+	}
+}
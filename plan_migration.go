@@ -0,0 +1,122 @@
+// Package gpagorm provides migration plan previews
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// PlannedStatement is a DDL statement AutoMigrate would execute
+type PlannedStatement struct {
+	Table       string `json:"table"`
+	Change      string `json:"change"`      // e.g. "create table", "add column email"
+	SQL         string `json:"sql"`         // Statement with arguments inlined
+	Destructive bool   `json:"destructive"` // Alters existing data or columns
+}
+
+// PlanMigration returns the DDL AutoMigrate would run for models without
+// applying it. The live schema is read to find differences, and each
+// change is rendered through a DryRun session.
+func (p *Provider) PlanMigration(ctx context.Context, models ...interface{}) ([]PlannedStatement, error) {
+	db := p.db.WithContext(ctx)
+	var plan []PlannedStatement
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, convertGormError(err)
+		}
+
+		drift, err := detectTableDrift(db, model, stmt.Schema, stmt.Table)
+		if err != nil {
+			return nil, err
+		}
+
+		add := func(change string, destructive bool, run func(m gorm.Migrator) error) error {
+			recorder := &sqlRecorder{}
+			dry := db.Session(&gorm.Session{DryRun: true, Logger: recorder})
+			previewed, err := previewDDL(func() error { return run(dry.Migrator()) })
+			if err != nil {
+				return convertGormError(fmt.Errorf("planning %s on %s: %w", change, stmt.Table, err))
+			}
+			if !previewed {
+				// Some migrators (e.g. SQLite table rebuilds) read back state that DryRun never wrote
+				recorder.statements = []string{fmt.Sprintf("-- %s cannot be previewed on %s", change, dialectName(db))}
+			}
+			for _, sql := range recorder.statements {
+				plan = append(plan, PlannedStatement{Table: stmt.Table, Change: change, SQL: sql, Destructive: destructive})
+			}
+			return nil
+		}
+
+		if drift.Missing {
+			if err := add("create table", false, func(m gorm.Migrator) error { return m.CreateTable(model) }); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		for _, column := range drift.MissingColumns {
+			if err := add("add column "+column, false, func(m gorm.Migrator) error { return m.AddColumn(model, column) }); err != nil {
+				return nil, err
+			}
+		}
+
+		altered := map[string]bool{}
+		for _, mismatch := range drift.ColumnMismatches {
+			if altered[mismatch.Column] {
+				continue
+			}
+			altered[mismatch.Column] = true
+			column := mismatch.Column
+			if err := add("alter column "+column, true, func(m gorm.Migrator) error { return m.AlterColumn(model, column) }); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, constraint := range drift.MissingForeignKeys {
+			if err := add("add foreign key "+constraint, false, func(m gorm.Migrator) error { return m.CreateConstraint(model, constraint) }); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, index := range drift.MissingIndexes {
+			if err := add("create index "+index, false, func(m gorm.Migrator) error { return m.CreateIndex(model, index) }); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// previewDDL runs a DryRun migrator call, reporting false if it could not complete
+func previewDDL(run func() error) (previewed bool, err error) {
+	defer func() {
+		if recover() != nil {
+			previewed, err = false, nil
+		}
+	}()
+	return true, run()
+}
+
+// sqlRecorder is a GORM logger that records statements instead of printing them
+type sqlRecorder struct {
+	statements []string
+}
+
+func (r *sqlRecorder) LogMode(logger.LogLevel) logger.Interface      { return r }
+func (r *sqlRecorder) Info(context.Context, string, ...interface{})  {}
+func (r *sqlRecorder) Warn(context.Context, string, ...interface{})  {}
+func (r *sqlRecorder) Error(context.Context, string, ...interface{}) {}
+
+// Trace records the statement that was built
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if sql, _ := fc(); sql != "" {
+		r.statements = append(r.statements, sql)
+	}
+}
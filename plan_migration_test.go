@@ -0,0 +1,63 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPlanMigration(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	plan, err := provider.PlanMigration(ctx, &TestUser{}, &TestDriftItem{})
+	if err != nil {
+		t.Fatalf("Failed to plan migration: %v", err)
+	}
+	if len(plan) == 0 || plan[0].Change != "create table" || !strings.HasPrefix(plan[0].SQL, "CREATE TABLE `drift_items`") {
+		t.Fatalf("Expected a create table statement for drift_items, got %+v", plan)
+	}
+	if provider.db.Migrator().HasTable(&TestDriftItem{}) {
+		t.Fatal("Expected planning not to create the table")
+	}
+
+	if err := provider.db.Exec("CREATE TABLE drift_items (id integer PRIMARY KEY, name text NOT NULL, status text DEFAULT 'active')").Error; err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	plan, err = provider.PlanMigration(ctx, &TestDriftItem{})
+	if err != nil {
+		t.Fatalf("Failed to plan migration: %v", err)
+	}
+
+	var changes []string
+	for _, statement := range plan {
+		changes = append(changes, statement.Change)
+		if statement.Destructive {
+			t.Errorf("Expected only additive changes, got %+v", statement)
+		}
+	}
+	if strings.Join(changes, ",") != "add column sku,create index idx_drift_items_sku" {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+	if provider.db.Migrator().HasColumn(&TestDriftItem{}, "sku") {
+		t.Error("Expected planning not to add the column")
+	}
+
+	if err := provider.db.Exec("DROP TABLE drift_items").Error; err != nil {
+		t.Fatalf("Failed to drop table: %v", err)
+	}
+	if err := provider.db.Exec("CREATE TABLE drift_items (id integer PRIMARY KEY, name text NOT NULL, sku text, status text DEFAULT 'pending')").Error; err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if err := provider.db.Exec("CREATE INDEX idx_drift_items_sku ON drift_items (sku)").Error; err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	plan, err = provider.PlanMigration(ctx, &TestDriftItem{})
+	if err != nil {
+		t.Fatalf("Failed to plan migration: %v", err)
+	}
+	if len(plan) != 1 || plan[0].Change != "alter column status" || !plan[0].Destructive {
+		t.Errorf("Expected a destructive alter of status, got %+v", plan)
+	}
+}
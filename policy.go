@@ -0,0 +1,110 @@
+// Package gpagorm provides per-entity authorization policies
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/lemmego/gpa"
+)
+
+// Policy authorizes repository operations for entity type T.
+// Returning a non-nil error denies the operation; the repository
+// reports it as a gpa.ErrorTypePermission error wrapping the cause.
+type Policy[T any] interface {
+	// CanCreate is called for every entity passed to Create or CreateBatch
+	CanCreate(ctx context.Context, entity *T) error
+	// CanRead is called with the query options of read operations
+	CanRead(ctx context.Context, opts []gpa.QueryOption) error
+	// CanUpdate is called with the stored entity for Update and UpdatePartial
+	CanUpdate(ctx context.Context, entity *T) error
+	// CanDelete is called with every entity about to be deleted
+	CanDelete(ctx context.Context, entity *T) error
+}
+
+// AllowAllPolicy permits every operation. Embed it in a policy to
+// override only the checks that matter.
+type AllowAllPolicy[T any] struct{}
+
+// CanCreate permits the create
+func (AllowAllPolicy[T]) CanCreate(ctx context.Context, entity *T) error { return nil }
+
+// CanRead permits the read
+func (AllowAllPolicy[T]) CanRead(ctx context.Context, opts []gpa.QueryOption) error { return nil }
+
+// CanUpdate permits the update
+func (AllowAllPolicy[T]) CanUpdate(ctx context.Context, entity *T) error { return nil }
+
+// CanDelete permits the delete
+func (AllowAllPolicy[T]) CanDelete(ctx context.Context, entity *T) error { return nil }
+
+// RegisterPolicy registers the authorization policy for entity type T on
+// the provider, replacing any previous policy. Passing nil removes it.
+func RegisterPolicy[T any](p *Provider, policy Policy[T]) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := reflect.TypeOf((*T)(nil)).Elem()
+	if policy == nil {
+		delete(p.policies, key)
+		return
+	}
+	if p.policies == nil {
+		p.policies = make(map[reflect.Type]interface{})
+	}
+	p.policies[key] = policy
+}
+
+// policy returns the registered policy for T, or nil
+func (r *Repository[T]) policy() Policy[T] {
+	if r.provider == nil {
+		return nil
+	}
+	r.provider.mu.RLock()
+	defer r.provider.mu.RUnlock()
+
+	if policy, ok := r.provider.policies[reflect.TypeOf((*T)(nil)).Elem()]; ok {
+		return policy.(Policy[T])
+	}
+	return nil
+}
+
+// permissionError wraps a policy denial as a GPA permission error
+func permissionError(action string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return gpa.NewErrorWithCause(gpa.ErrorTypePermission, action+" not permitted", err)
+}
+
+// authorizeCreate checks the create policy for entity
+func (r *Repository[T]) authorizeCreate(ctx context.Context, entity *T) error {
+	if policy := r.policy(); policy != nil {
+		return permissionError("create", policy.CanCreate(ctx, entity))
+	}
+	return nil
+}
+
+// authorizeRead checks the read policy for opts
+func (r *Repository[T]) authorizeRead(ctx context.Context, opts []gpa.QueryOption) error {
+	if policy := r.policy(); policy != nil {
+		return permissionError("read", policy.CanRead(ctx, opts))
+	}
+	return nil
+}
+
+// authorizeUpdate checks the update policy for entity
+func (r *Repository[T]) authorizeUpdate(ctx context.Context, entity *T) error {
+	if policy := r.policy(); policy != nil {
+		return permissionError("update", policy.CanUpdate(ctx, entity))
+	}
+	return nil
+}
+
+// authorizeDelete checks the delete policy for entity
+func (r *Repository[T]) authorizeDelete(ctx context.Context, entity *T) error {
+	if policy := r.policy(); policy != nil {
+		return permissionError("delete", policy.CanDelete(ctx, entity))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+type minorsPolicy struct {
+	AllowAllPolicy[TestUser]
+}
+
+func (minorsPolicy) CanCreate(ctx context.Context, user *TestUser) error {
+	if user.Age < 18 {
+		return errors.New("minors cannot be registered")
+	}
+	return nil
+}
+
+func (minorsPolicy) CanDelete(ctx context.Context, user *TestUser) error {
+	if user.Name == "Admin" {
+		return errors.New("admin cannot be deleted")
+	}
+	return nil
+}
+
+func TestPolicyEnforcement(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	RegisterPolicy[TestUser](provider, minorsPolicy{})
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	err := repo.Create(ctx, &TestUser{Name: "Kid", Email: "kid@example.com", Age: 12})
+	if !gpa.IsErrorType(err, gpa.ErrorTypePermission) {
+		t.Errorf("Expected permission error, got %v", err)
+	}
+
+	admin := &TestUser{Name: "Admin", Email: "admin@example.com", Age: 40}
+	if err := repo.Create(ctx, admin); err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+
+	err = repo.Delete(ctx, admin.ID)
+	if !gpa.IsErrorType(err, gpa.ErrorTypePermission) {
+		t.Errorf("Expected permission error on delete, got %v", err)
+	}
+
+	err = repo.DeleteByCondition(ctx, gpa.WhereCondition("age", gpa.OpGreaterThan, 30))
+	if !gpa.IsErrorType(err, gpa.ErrorTypePermission) {
+		t.Errorf("Expected permission error on conditional delete, got %v", err)
+	}
+
+	RegisterPolicy[TestUser](provider, nil)
+	if err := repo.Delete(ctx, admin.ID); err != nil {
+		t.Errorf("Expected delete to succeed without policy, got %v", err)
+	}
+}
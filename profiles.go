@@ -0,0 +1,183 @@
+// Package gpagorm provides serialization profiles for shaping API output
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm/schema"
+)
+
+// Profile selects and names the fields of an entity in QueryAsMaps output.
+// Fields are given by Go name or column name.
+type Profile struct {
+	Include []string          // Fields to output; empty means every column field
+	Exclude []string          // Fields to leave out of Include
+	Rename  map[string]string // Output key by field; others use their JSON name
+}
+
+// profileField is a field of a compiled profile
+type profileField struct {
+	field *schema.Field
+	key   string
+}
+
+// RegisterProfile registers a serialization profile for the repository's
+// entity under name, replacing any previous profile of that name:
+//
+//	repo.RegisterProfile("public", gpagorm.Profile{
+//	    Include: []string{"ID", "Name", "CreatedAt"},
+//	    Rename:  map[string]string{"CreatedAt": "joined"},
+//	})
+//
+// Profiles are kept on the provider, so every repository of the entity
+// shares them. Unknown fields are reported as invalid argument errors.
+func (r *Repository[T]) RegisterProfile(name string, profile Profile) error {
+	s, err := r.schema()
+	if err != nil {
+		return err
+	}
+	fields, err := compileProfile(s, profile)
+	if err != nil {
+		return err
+	}
+	if r.provider == nil {
+		return nil
+	}
+
+	r.provider.mu.Lock()
+	defer r.provider.mu.Unlock()
+	key := reflect.TypeOf((*T)(nil)).Elem()
+	if r.provider.profiles == nil {
+		r.provider.profiles = make(map[reflect.Type]map[string][]profileField)
+	}
+	if r.provider.profiles[key] == nil {
+		r.provider.profiles[key] = make(map[string][]profileField)
+	}
+	r.provider.profiles[key][name] = fields
+	return nil
+}
+
+// compileProfile resolves the output fields of profile on s
+func compileProfile(s *schema.Schema, profile Profile) ([]profileField, error) {
+	lookup := func(name string) (*schema.Field, error) {
+		if f := s.LookUpField(name); f != nil {
+			return f, nil
+		}
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("%s has no field %s", s.Name, name))
+	}
+
+	var fields []*schema.Field
+	if len(profile.Include) == 0 {
+		for _, f := range s.Fields {
+			if f.DBName != "" && f.Readable && jsonName(f) != "-" {
+				fields = append(fields, f)
+			}
+		}
+	} else {
+		for _, name := range profile.Include {
+			f, err := lookup(name)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, f)
+		}
+	}
+
+	excluded := map[*schema.Field]bool{}
+	for _, name := range profile.Exclude {
+		f, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		excluded[f] = true
+	}
+	renamed := map[*schema.Field]string{}
+	for name, key := range profile.Rename {
+		f, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		renamed[f] = key
+	}
+
+	compiled := make([]profileField, 0, len(fields))
+	for _, f := range fields {
+		if excluded[f] {
+			continue
+		}
+		key, ok := renamed[f]
+		if !ok {
+			if key = jsonName(f); key == "" || key == "-" {
+				key = f.Name
+			}
+		}
+		compiled = append(compiled, profileField{field: f, key: key})
+	}
+	return compiled, nil
+}
+
+// jsonName returns the name in a field's json tag
+func jsonName(f *schema.Field) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// profile returns the profile registered for T under name, if any
+func (r *Repository[T]) profile(name string) ([]profileField, bool) {
+	if r.provider == nil {
+		return nil, false
+	}
+	r.provider.mu.RLock()
+	defer r.provider.mu.RUnlock()
+	fields, ok := r.provider.profiles[reflect.TypeOf((*T)(nil)).Elem()][name]
+	return fields, ok
+}
+
+// QueryAsMaps queries the entities matching opts and returns them as maps
+// shaped by the named profile, ready to marshal as an API response. The
+// empty name uses the default profile of every column field under its JSON
+// name. Relation fields are output when included and preloaded.
+func (r *Repository[T]) QueryAsMaps(ctx context.Context, profile string, opts ...gpa.QueryOption) (rows []map[string]interface{}, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationQueryAsMaps, Options: opts}, func(ctx context.Context) error {
+		var err error
+		rows, err = r.queryAsMaps(ctx, profile, opts...)
+		return err
+	})
+	return rows, err
+}
+
+// queryAsMaps implements QueryAsMaps
+func (r *Repository[T]) queryAsMaps(ctx context.Context, profile string, opts ...gpa.QueryOption) ([]map[string]interface{}, error) {
+	fields, ok := r.profile(profile)
+	if !ok {
+		if profile != "" {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "unknown serialization profile: "+profile)
+		}
+		s, err := r.schema()
+		if err != nil {
+			return nil, err
+		}
+		if fields, err = compileProfile(s, Profile{}); err != nil {
+			return nil, err
+		}
+	}
+
+	entities, err := r.query(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]interface{}, len(entities))
+	for i, entity := range entities {
+		rv := reflect.ValueOf(entity).Elem()
+		row := make(map[string]interface{}, len(fields))
+		for _, f := range fields {
+			row[f.key] = f.field.ReflectValueOf(ctx, rv).Interface()
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
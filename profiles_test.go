@@ -0,0 +1,51 @@
+package gpagorm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestQueryAsMaps(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	repo.Create(ctx, &TestUser{Name: "Ann", Email: "ann@example.com", Age: 31})
+	repo.Create(ctx, &TestUser{Name: "Bob", Email: "bob@example.com", Age: 25})
+
+	if err := repo.RegisterProfile("public", Profile{
+		Include: []string{"ID", "name", "Age"},
+		Exclude: []string{"Age"},
+		Rename:  map[string]string{"name": "display_name"},
+	}); err != nil {
+		t.Fatalf("Failed to register profile: %v", err)
+	}
+	if err := repo.RegisterProfile("broken", Profile{Exclude: []string{"Password"}}); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for an unknown field, got %v", err)
+	}
+
+	rows, err := repo.QueryAsMaps(ctx, "public", gpa.OrderBy("name", gpa.OrderAsc))
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	data, _ := json.Marshal(rows)
+	if got, want := string(data), `[{"ID":1,"display_name":"Ann"},{"ID":2,"display_name":"Bob"}]`; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	rows, err = repo.QueryAsMaps(ctx, "", gpa.Where("age", gpa.OpGreaterThan, 30))
+	if err != nil {
+		t.Fatalf("Failed to query with the default profile: %v", err)
+	}
+	if len(rows) != 1 || len(rows[0]) != 4 || rows[0]["Email"] != "ann@example.com" {
+		t.Errorf("Expected every column of Ann, got %v", rows)
+	}
+
+	if _, err := repo.QueryAsMaps(ctx, "admin"); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for an unknown profile, got %v", err)
+	}
+}
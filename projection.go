@@ -0,0 +1,49 @@
+// Package gpagorm provides queries that scan into projection structs
+package gpagorm
+
+import (
+	"context"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// Project queries the entities of r matching opts and scans only the
+// columns of P into it. P is a struct whose fields map to columns of T's
+// table the same way T's fields do, e.g. a summary with a few of T's fields.
+// With gpa.Select the selected expressions are scanned instead, which suits
+// aggregates such as "SUM(amount) AS total".
+func Project[P any, T any](ctx context.Context, r *Repository[T], opts ...gpa.QueryOption) (rows []P, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationProject, Options: opts}, func(ctx context.Context) error {
+		var err error
+		rows, err = project[P](ctx, r, opts...)
+		return err
+	})
+	return rows, err
+}
+
+// project implements Project
+func project[P any, T any](ctx context.Context, r *Repository[T], opts ...gpa.QueryOption) ([]P, error) {
+	if err := r.authorizeRead(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	var zero P
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(&zero); err != nil {
+		return nil, convertGormError(err)
+	}
+	columns := append([]string(nil), stmt.Schema.DBNames...)
+
+	var entity T
+	rows := []P{}
+	query := r.buildQuery(ctx, opts...).Model(&entity)
+	if len(query.Statement.Selects) == 0 {
+		query = query.Select(columns)
+	}
+	result := query.Find(&rows)
+	if err := convertGormError(result.Error); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
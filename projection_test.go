@@ -0,0 +1,37 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+type testUserContact struct {
+	Name  string
+	Email string
+}
+
+func TestProject(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	for _, u := range []*TestUser{
+		{Name: "Alice", Email: "alice@example.com", Age: 30},
+		{Name: "Bob", Email: "bob@example.com", Age: 20},
+	} {
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	contacts, err := Project[testUserContact](ctx, repo, gpa.Where("age", gpa.OpGreaterThan, 25))
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+	if len(contacts) != 1 || contacts[0] != (testUserContact{Name: "Alice", Email: "alice@example.com"}) {
+		t.Errorf("Unexpected projection: %+v", contacts)
+	}
+}
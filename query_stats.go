@@ -0,0 +1,391 @@
+// Package gpagorm provides per-statement metrics grouped by query fingerprint
+package gpagorm
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// QueryStatsBuckets are the upper bounds of the latency histogram buckets.
+// A final implicit bucket counts everything slower than the last bound.
+var QueryStatsBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// QueryStat holds the aggregated metrics of one normalized statement
+type QueryStat struct {
+	Fingerprint string        // Hash of the normalized statement
+	Query       string        // Statement with literals replaced by ?
+	Calls       int64         // Number of executions
+	Errors      int64         // Executions that returned an error
+	Rows        int64         // Total rows affected or returned
+	TotalTime   time.Duration // Sum of execution times
+	MinTime     time.Duration // Fastest execution
+	MaxTime     time.Duration // Slowest execution
+	Histogram   []int64       // Counts per QueryStatsBuckets entry, plus overflow
+}
+
+// MeanTime returns the average execution time
+func (s QueryStat) MeanTime() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.TotalTime / time.Duration(s.Calls)
+}
+
+// observe adds one execution to the stat
+func (s *QueryStat) observe(elapsed time.Duration, rows int64, err error) {
+	if s.Calls == 0 || elapsed < s.MinTime {
+		s.MinTime = elapsed
+	}
+	if s.Histogram == nil {
+		s.Histogram = make([]int64, len(QueryStatsBuckets)+1)
+	}
+	s.Calls++
+	if err != nil {
+		s.Errors++
+	}
+	if rows > 0 {
+		s.Rows += rows
+	}
+	s.TotalTime += elapsed
+	if elapsed > s.MaxTime {
+		s.MaxTime = elapsed
+	}
+
+	bucket := sort.Search(len(QueryStatsBuckets), func(i int) bool {
+		return elapsed <= QueryStatsBuckets[i]
+	})
+	s.Histogram[bucket]++
+}
+
+// OperationStat holds the aggregated metrics of one labeled series: the
+// statements of an operation kind on an entity's table. Labels dropped by
+// the "query_stats_labels" option are empty, and entities past the
+// cardinality limits are reported as "other".
+type OperationStat struct {
+	Entity    string // Model name, e.g. "User"
+	Table     string // Table name, e.g. "users"
+	Operation string // create, query, update, delete, row or raw
+	QueryStat        // Metrics of the series; Fingerprint and Query are empty
+}
+
+// OtherLabel replaces entity and table labels beyond the cardinality limits
+const OtherLabel = "other"
+
+// defaultMaxSeries bounds the labeled series unless "query_stats_max_series"
+// is set
+const defaultMaxSeries = 500
+
+// operationKey identifies a labeled series
+type operationKey struct {
+	entity, table, operation string
+}
+
+// queryStatsCollector aggregates statement metrics across connections
+type queryStatsCollector struct {
+	mu         sync.Mutex
+	stats      map[string]*QueryStat
+	operations map[operationKey]*OperationStat
+
+	labels    map[string]bool // Labels kept on operation series
+	tables    map[string]bool // Tables labeled by name; nil means all
+	maxSeries int
+}
+
+const queryStatsStartKey = "gpagorm:query_stats_start"
+
+// newQueryStatsCollector creates an empty collector
+func newQueryStatsCollector() *queryStatsCollector {
+	return &queryStatsCollector{
+		stats:      make(map[string]*QueryStat),
+		operations: make(map[operationKey]*OperationStat),
+		labels:     map[string]bool{"table": true, "operation": true},
+		maxSeries:  defaultMaxSeries,
+	}
+}
+
+// configure applies the label options:
+//
+//   - "query_stats_labels": the labels to keep of "entity", "table" and
+//     "operation"; the others are aggregated away
+//   - "query_stats_tables": tables labeled by name, the rest as "other"
+//   - "query_stats_max_series": the number of series after which new
+//     entities and tables are labeled "other"
+func (c *queryStatsCollector) configure(gormOpts map[string]interface{}) error {
+	if labels, ok := gormOpts["query_stats_labels"].([]string); ok {
+		c.labels = map[string]bool{}
+		for _, label := range labels {
+			switch label {
+			case "entity", "table", "operation":
+				c.labels[label] = true
+			default:
+				return fmt.Errorf("invalid query_stats_labels entry %q: want entity, table or operation", label)
+			}
+		}
+	}
+	if tables, ok := gormOpts["query_stats_tables"].([]string); ok {
+		c.tables = map[string]bool{}
+		for _, table := range tables {
+			c.tables[table] = true
+		}
+	}
+	if max, ok := gormOpts["query_stats_max_series"].(int); ok {
+		if max <= 0 {
+			return fmt.Errorf("query_stats_max_series must be positive, got %d", max)
+		}
+		c.maxSeries = max
+	}
+	return nil
+}
+
+// register installs the timing callbacks on every GORM processor of db
+func (c *queryStatsCollector) register(db *gorm.DB) error {
+	start := func(db *gorm.DB) {
+		db.InstanceSet(queryStatsStartKey, time.Now())
+	}
+	finish := func(operation string) func(db *gorm.DB) {
+		return func(db *gorm.DB) {
+			if db.DryRun {
+				return
+			}
+			started, ok := db.InstanceGet(queryStatsStartKey)
+			if !ok {
+				return
+			}
+			key := operationKey{table: db.Statement.Table, operation: operation}
+			if db.Statement.Schema != nil {
+				key.entity = db.Statement.Schema.Name
+			}
+			c.record(db.Statement.SQL.String(), key, time.Since(started.(time.Time)), db.RowsAffected, db.Error)
+		}
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("*").Register("gpagorm:query_stats_start", start),
+		callbacks.Create().After("*").Register("gpagorm:query_stats_finish", finish("create")),
+		callbacks.Query().Before("*").Register("gpagorm:query_stats_start", start),
+		callbacks.Query().After("*").Register("gpagorm:query_stats_finish", finish("query")),
+		callbacks.Update().Before("*").Register("gpagorm:query_stats_start", start),
+		callbacks.Update().After("*").Register("gpagorm:query_stats_finish", finish("update")),
+		callbacks.Delete().Before("*").Register("gpagorm:query_stats_start", start),
+		callbacks.Delete().After("*").Register("gpagorm:query_stats_finish", finish("delete")),
+		callbacks.Row().Before("*").Register("gpagorm:query_stats_start", start),
+		callbacks.Row().After("*").Register("gpagorm:query_stats_finish", finish("row")),
+		callbacks.Raw().Before("*").Register("gpagorm:query_stats_start", start),
+		callbacks.Raw().After("*").Register("gpagorm:query_stats_finish", finish("raw")),
+	)
+}
+
+// record adds one execution to the statistics of its fingerprint and of
+// its labeled series
+func (c *queryStatsCollector) record(sql string, key operationKey, elapsed time.Duration, rows int64, err error) {
+	if sql == "" {
+		return
+	}
+	normalized := NormalizeQuery(sql)
+	fingerprint := QueryFingerprint(normalized)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stat, ok := c.stats[fingerprint]
+	if !ok {
+		stat = &QueryStat{Fingerprint: fingerprint, Query: normalized}
+		c.stats[fingerprint] = stat
+	}
+	stat.observe(elapsed, rows, err)
+
+	key = c.limitLabels(key)
+	series, ok := c.operations[key]
+	if !ok {
+		series = &OperationStat{Entity: key.entity, Table: key.table, Operation: key.operation}
+		c.operations[key] = series
+	}
+	series.observe(elapsed, rows, err)
+}
+
+// limitLabels drops the labels that are not kept and folds entities and
+// tables beyond the cardinality limits into OtherLabel
+func (c *queryStatsCollector) limitLabels(key operationKey) operationKey {
+	if c.tables != nil && key.table != "" && !c.tables[key.table] {
+		key.entity, key.table = OtherLabel, OtherLabel
+	}
+	if !c.labels["entity"] {
+		key.entity = ""
+	}
+	if !c.labels["table"] {
+		key.table = ""
+	}
+	if !c.labels["operation"] {
+		key.operation = ""
+	}
+	if _, ok := c.operations[key]; !ok && len(c.operations) >= c.maxSeries {
+		if key.entity != "" {
+			key.entity = OtherLabel
+		}
+		if key.table != "" {
+			key.table = OtherLabel
+		}
+	}
+	return key
+}
+
+// snapshot returns a copy of the statistics ordered by total time
+func (c *queryStatsCollector) snapshot() []QueryStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := make([]QueryStat, 0, len(c.stats))
+	for _, stat := range c.stats {
+		copied := *stat
+		copied.Histogram = append([]int64(nil), stat.Histogram...)
+		stats = append(stats, copied)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].TotalTime != stats[j].TotalTime {
+			return stats[i].TotalTime > stats[j].TotalTime
+		}
+		return stats[i].Fingerprint < stats[j].Fingerprint
+	})
+	return stats
+}
+
+// operationSnapshot returns a copy of the labeled series ordered by total
+// time
+func (c *queryStatsCollector) operationSnapshot() []OperationStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := make([]OperationStat, 0, len(c.operations))
+	for _, stat := range c.operations {
+		copied := *stat
+		copied.Histogram = append([]int64(nil), stat.Histogram...)
+		stats = append(stats, copied)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].TotalTime != stats[j].TotalTime {
+			return stats[i].TotalTime > stats[j].TotalTime
+		}
+		a, b := stats[i], stats[j]
+		return a.Entity+"\x00"+a.Table+"\x00"+a.Operation < b.Entity+"\x00"+b.Table+"\x00"+b.Operation
+	})
+	return stats
+}
+
+// reset clears all collected statistics
+func (c *queryStatsCollector) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats = make(map[string]*QueryStat)
+	c.operations = make(map[operationKey]*OperationStat)
+}
+
+// QueryStats returns the collected statement metrics, slowest in total first.
+// Collection is enabled with the "query_stats" gorm option; nil is returned otherwise.
+func (p *Provider) QueryStats() []QueryStat {
+	if p.queryStats == nil {
+		return nil
+	}
+	return p.queryStats.snapshot()
+}
+
+// OperationStats returns the collected metrics labeled by entity, table and
+// operation, slowest in total first, for dashboards of per-aggregate
+// latency. Labels are limited by the "query_stats_labels",
+// "query_stats_tables" and "query_stats_max_series" gorm options so
+// exported series stay bounded. nil is returned unless "query_stats" is set.
+func (p *Provider) OperationStats() []OperationStat {
+	if p.queryStats == nil {
+		return nil
+	}
+	return p.queryStats.operationSnapshot()
+}
+
+// ResetQueryStats clears the collected statement metrics
+func (p *Provider) ResetQueryStats() {
+	if p.queryStats != nil {
+		p.queryStats.reset()
+	}
+}
+
+var inListPattern = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)+\s*\)`)
+
+// NormalizeQuery replaces literals and bind placeholders with ?, collapses
+// IN lists and whitespace, so statements of the same shape compare equal
+func NormalizeQuery(sql string) string {
+	var b strings.Builder
+	runes := []rune(sql)
+	space := false
+	prevWord := false
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			prevWord = false
+			continue
+		case r == '\'':
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			r = '?'
+		case r == '$' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			// Postgres $1 placeholders
+			for i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
+				i++
+			}
+			r = '?'
+		case r == '@' && i+2 < len(runes) && runes[i+1] == 'p' && unicode.IsDigit(runes[i+2]):
+			// SQL Server @p1 placeholders
+			for i++; i+1 < len(runes) && unicode.IsDigit(runes[i+1]); i++ {
+			}
+			r = '?'
+		case unicode.IsDigit(r) && !prevWord:
+			for i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.') {
+				i++
+			}
+			r = '?'
+		}
+
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+		prevWord = r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	return inListPattern.ReplaceAllString(b.String(), "(...)")
+}
+
+// QueryFingerprint hashes a normalized statement
+func QueryFingerprint(normalized string) string {
+	h := fnv.New64a()
+	h.Write([]byte(normalized))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
@@ -0,0 +1,157 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"SELECT * FROM users WHERE id = 42", "SELECT * FROM users WHERE id = ?"},
+		{"SELECT * FROM users WHERE name = 'O''Brien'", "SELECT * FROM users WHERE name = ?"},
+		{"SELECT *\n  FROM users WHERE id IN (1, 2, 3)", "SELECT * FROM users WHERE id IN (...)"},
+		{"SELECT * FROM t1 WHERE a = $1 AND b = $2", "SELECT * FROM t1 WHERE a = ? AND b = ?"},
+		{"UPDATE t SET a = @p1 WHERE b = 1.5", "UPDATE t SET a = ? WHERE b = ?"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeQuery(tt.in); got != tt.want {
+			t.Errorf("NormalizeQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if QueryFingerprint(NormalizeQuery("SELECT 1")) != QueryFingerprint(NormalizeQuery("SELECT  2")) {
+		t.Error("Expected statements of the same shape to share a fingerprint")
+	}
+}
+
+func TestQueryStats(t *testing.T) {
+	provider, err := NewProvider(gpa.Config{
+		Driver:   "sqlite",
+		Database: ":memory:",
+		Options: map[string]interface{}{
+			"gorm": map[string]interface{}{"log_level": "silent", "query_stats": true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	defer provider.Close()
+
+	if err := provider.db.AutoMigrate(&TestUser{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	provider.ResetQueryStats()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+	for i, name := range []string{"Ann", "Bob", "Cid"} {
+		user := &TestUser{Name: name, Email: name + "@example.com", Age: 20 + i}
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	for _, id := range []int{1, 2, 99} {
+		provider.db.Exec(fmt.Sprintf("UPDATE test_users SET age = age + 1 WHERE id = %d", id))
+	}
+
+	stats := provider.QueryStats()
+	var inserts, updates *QueryStat
+	for i := range stats {
+		switch {
+		case strings.HasPrefix(stats[i].Query, "INSERT"):
+			inserts = &stats[i]
+		case strings.HasPrefix(stats[i].Query, "UPDATE"):
+			updates = &stats[i]
+		}
+	}
+	if inserts == nil || inserts.Calls != 3 {
+		t.Fatalf("Expected 3 calls for the insert fingerprint, got %+v", inserts)
+	}
+	if updates == nil || updates.Calls != 3 || updates.Rows != 2 {
+		t.Fatalf("Expected 3 calls and 2 rows for the update fingerprint, got %+v", updates)
+	}
+
+	var total int64
+	for _, n := range inserts.Histogram {
+		total += n
+	}
+	if total != inserts.Calls {
+		t.Errorf("Expected histogram to account for %d calls, got %d", inserts.Calls, total)
+	}
+
+	provider.ResetQueryStats()
+	if len(provider.QueryStats()) != 0 {
+		t.Error("Expected stats to be empty after reset")
+	}
+}
+
+func TestOperationStats(t *testing.T) {
+	open := func(options map[string]interface{}) *Provider {
+		options["log_level"] = "silent"
+		options["query_stats"] = true
+		provider, err := NewProvider(gpa.Config{Driver: "sqlite", Database: ":memory:", Options: map[string]interface{}{"gorm": options}})
+		if err != nil {
+			t.Fatalf("Failed to create provider: %v", err)
+		}
+		if err := provider.db.AutoMigrate(&TestUser{}, &testAuditEntry{}); err != nil {
+			t.Fatalf("Failed to migrate: %v", err)
+		}
+		provider.ResetQueryStats()
+		return provider
+	}
+	run := func(provider *Provider) map[string]int64 {
+		ctx := context.Background()
+		users := NewRepository[TestUser](provider.db, provider)
+		audit := NewRepository[testAuditEntry](provider.db, provider)
+		users.Create(ctx, &TestUser{Name: "Ann", Email: "ann@example.com"})
+		users.FindAll(ctx)
+		users.FindAll(ctx)
+		audit.Create(ctx, &testAuditEntry{Action: "login"})
+		calls := map[string]int64{}
+		for _, stat := range provider.OperationStats() {
+			calls[stat.Entity+"|"+stat.Table+"|"+stat.Operation] += stat.Calls
+		}
+		return calls
+	}
+
+	provider := open(map[string]interface{}{})
+	defer provider.Close()
+	calls := run(provider)
+	for series, want := range map[string]int64{"|test_users|create": 1, "|test_users|query": 2, "|test_audit_entries|create": 1} {
+		if calls[series] != want {
+			t.Errorf("Expected %d calls of %s, got %v", want, series, calls)
+		}
+	}
+
+	limited := open(map[string]interface{}{
+		"query_stats_labels": []string{"entity", "operation"},
+		"query_stats_tables": []string{"test_users"},
+	})
+	defer limited.Close()
+	calls = run(limited)
+	for series, want := range map[string]int64{"TestUser||query": 2, "other||create": 1, "TestUser||create": 1} {
+		if calls[series] != want {
+			t.Errorf("Expected %d calls of %s, got %v", want, series, calls)
+		}
+	}
+
+	capped := open(map[string]interface{}{"query_stats_max_series": 1})
+	defer capped.Close()
+	if calls = run(capped); calls["|test_users|create"] != 1 || calls["|other|query"] != 2 || calls["|other|create"] != 1 {
+		t.Errorf("Expected series past the limit to be folded into other, got %v", calls)
+	}
+
+	if _, err := NewProvider(gpa.Config{Driver: "sqlite", Database: ":memory:", Options: map[string]interface{}{
+		"gorm": map[string]interface{}{"query_stats": true, "query_stats_labels": []string{"user_id"}},
+	}}); err == nil {
+		t.Error("Expected an unknown label to be rejected")
+	}
+}
@@ -0,0 +1,55 @@
+// Package gpagorm provides raw SQL predicates inside query options
+package gpagorm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lemmego/gpa"
+)
+
+// RawSQLCondition is a SQL predicate with bound arguments. It is both a
+// gpa.Condition and a gpa.QueryOption, so it can be passed to Query
+// directly or used wherever a condition is expected.
+type RawSQLCondition struct {
+	SQL  string
+	Args []interface{}
+}
+
+// RawCondition adds a parameterized SQL predicate to a query while keeping
+// the other options, e.g. ordering, limits and preloads:
+//
+//	repo.Query(ctx, gpagorm.RawCondition("jsonb_path_exists(data, ?)", path), gpa.Limit(10))
+//
+// The SQL is written into the query as is, so it must not be built from
+// user input; pass values as args instead.
+func RawCondition(sql string, args ...interface{}) RawSQLCondition {
+	return RawSQLCondition{SQL: sql, Args: args}
+}
+
+// Apply implements gpa.QueryOption
+func (c RawSQLCondition) Apply(query *gpa.Query) {
+	query.Conditions = append(query.Conditions, c)
+}
+
+// Field returns "", as the predicate is not tied to one field
+func (c RawSQLCondition) Field() string { return "" }
+
+// Operator returns "", as the predicate carries its own operators
+func (c RawSQLCondition) Operator() gpa.Operator { return "" }
+
+// Value returns the bound arguments
+func (c RawSQLCondition) Value() interface{} { return c.Args }
+
+// String returns the SQL with its arguments
+func (c RawSQLCondition) String() string {
+	return fmt.Sprintf("%s %v", c.SQL, c.Args)
+}
+
+// validate rejects an empty predicate
+func (c RawSQLCondition) validate() error {
+	if strings.TrimSpace(c.SQL) == "" {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "raw condition needs SQL")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestRawCondition(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	for _, u := range []*TestUser{
+		{Name: "Alice", Email: "alice@example.com", Age: 30},
+		{Name: "alan", Email: "alan@example.com", Age: 40},
+		{Name: "Bob", Email: "bob@example.com", Age: 50},
+	} {
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	// Other options still apply around the raw predicate
+	users, err := repo.Query(ctx,
+		RawCondition("LOWER(SUBSTR(name, 1, 2)) = ? OR age > ?", "al", 45),
+		gpa.Where("age", gpa.OpLessThan, 45),
+		gpa.OrderBy("age", gpa.OrderDesc), gpa.Limit(1))
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(users) != 1 || users[0].Name != "alan" {
+		t.Errorf("Expected alan, got %+v", users)
+	}
+
+	if _, err := repo.Query(ctx, RawCondition(" ")); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for empty SQL, got %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+// Package gpagorm provides typed raw SQL queries
+package gpagorm
+
+import (
+	"context"
+)
+
+// Query runs raw SQL on p and scans the rows, in order, into R. R is any
+// struct whose fields map to the result columns the way entity fields map
+// to table columns, e.g. a report row; a scalar R such as int64 or string
+// scans single-column results. Unlike Provider.RawQuery, column types are
+// kept and no maps are allocated. The query joins the context's
+// transaction and runs through the provider's interceptors as a raw query.
+func Query[R any](ctx context.Context, p *Provider, sql string, args ...interface{}) (rows []R, err error) {
+	r := NewRepository[R](p.db, p)
+	err = r.intercept(ctx, OperationInfo{Operation: OperationRawQuery, SQL: sql}, func(ctx context.Context) error {
+		rows = []R{}
+		return convertGormError(r.session(ctx).Raw(sql, args...).Scan(&rows).Error)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,61 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	users := []*TestUser{
+		{Name: "Alice", Email: "alice@example.com", Age: 30},
+		{Name: "Bob", Email: "bob@example.com", Age: 25},
+		{Name: "Carol", Email: "carol@example.com", Age: 30},
+	}
+	if err := repo.CreateBatch(ctx, users); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+
+	var ops []Operation
+	provider.AddInterceptor(func(ctx context.Context, op OperationInfo, next func(context.Context) error) error {
+		ops = append(ops, op.Operation)
+		return next(ctx)
+	})
+
+	type ageCount struct {
+		Age   int
+		Total int64
+	}
+	counts, err := Query[ageCount](ctx, provider,
+		"SELECT age, COUNT(*) AS total FROM test_users GROUP BY age ORDER BY age DESC")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(counts) != 2 || counts[0] != (ageCount{30, 2}) || counts[1] != (ageCount{25, 1}) {
+		t.Errorf("Unexpected rows: %+v", counts)
+	}
+	if len(ops) != 1 || ops[0] != OperationRawQuery {
+		t.Errorf("Expected one raw query operation, got %v", ops)
+	}
+
+	names, err := Query[string](ctx, provider, "SELECT name FROM test_users WHERE age = ? ORDER BY name DESC", 30)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "Carol" || names[1] != "Alice" {
+		t.Errorf("Expected [Carol Alice], got %v", names)
+	}
+
+	empty, err := Query[ageCount](ctx, provider, "SELECT age, 1 AS total FROM test_users WHERE age > ?", 100)
+	if err != nil || empty == nil || len(empty) != 0 {
+		t.Errorf("Expected an empty non-nil result, got %v, %v", empty, err)
+	}
+
+	if _, err := Query[string](ctx, provider, "SELECT nope FROM missing"); err == nil {
+		t.Error("Expected an error for invalid SQL")
+	}
+}
@@ -0,0 +1,112 @@
+// Package gpagorm provides streaming of raw SQL results
+package gpagorm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Rows is a cursor over the result of a raw query. Next advances to the
+// next row, which Scan or ScanMap then read; Err reports the error that
+// ended the iteration, if any. Close must be called once done, and may be
+// called more than once.
+type Rows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	ScanMap(dest map[string]interface{}) error
+	Columns() ([]string, error)
+	Err() error
+	Close() error
+}
+
+// RawQueryRows runs raw SQL and returns a cursor over its rows, so tooling
+// can stream arbitrary queries instead of loading them through RawQuery.
+// The rows hold a connection until closed, and with session variables the
+// transaction they are set in, committed on Close. Interceptors see the
+// query being opened, not its iteration.
+func (p *Provider) RawQueryRows(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	r := NewRepository[struct{}](p.db, p)
+	ctx, finish, err := r.cursorSessionTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rows *sql.Rows
+	err = r.intercept(ctx, OperationInfo{Operation: OperationRawQuery, SQL: query}, func(ctx context.Context) error {
+		var err error
+		rows, err = r.session(ctx).Raw(query, args...).Rows()
+		return convertGormError(err)
+	})
+	if err != nil {
+		if finish != nil {
+			return nil, finish(err)
+		}
+		return nil, err
+	}
+	return &rawRows{rows: rows, finish: finish}, nil
+}
+
+// rawRows implements Rows over *sql.Rows, converting driver errors
+type rawRows struct {
+	rows    *sql.Rows
+	columns []string
+	finish  func(err error) error // Ends the session variable transaction, if any
+}
+
+// Next prepares the next row, returning false when there are no more
+func (r *rawRows) Next() bool {
+	return r.rows.Next()
+}
+
+// Scan copies the columns of the current row into dest
+func (r *rawRows) Scan(dest ...interface{}) error {
+	return convertGormError(r.rows.Scan(dest...))
+}
+
+// ScanMap stores the columns of the current row into dest by column name
+func (r *rawRows) ScanMap(dest map[string]interface{}) error {
+	columns, err := r.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := r.Scan(pointers...); err != nil {
+		return err
+	}
+	for i, column := range columns {
+		dest[column] = values[i]
+	}
+	return nil
+}
+
+// Columns returns the column names of the result
+func (r *rawRows) Columns() ([]string, error) {
+	if r.columns == nil {
+		columns, err := r.rows.Columns()
+		if err != nil {
+			return nil, convertGormError(err)
+		}
+		r.columns = columns
+	}
+	return r.columns, nil
+}
+
+// Err returns the error, if any, that ended the iteration
+func (r *rawRows) Err() error {
+	return convertGormError(r.rows.Err())
+}
+
+// Close releases the rows and their connection
+func (r *rawRows) Close() error {
+	err := convertGormError(r.rows.Close())
+	if finish := r.finish; finish != nil {
+		r.finish = nil
+		if finishErr := finish(nil); err == nil {
+			err = finishErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestRawQueryRows(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	for i := 0; i < 5; i++ {
+		user := &TestUser{Name: fmt.Sprintf("user%d", i), Email: fmt.Sprintf("user%d@example.com", i), Age: 20 + i}
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	rows, err := provider.RawQueryRows(ctx, "SELECT name, age FROM test_users WHERE age >= ? ORDER BY age", 22)
+	if err != nil {
+		t.Fatalf("RawQueryRows failed: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil || len(columns) != 2 || columns[0] != "name" || columns[1] != "age" {
+		t.Errorf("Unexpected columns %v, %v", columns, err)
+	}
+
+	var names []string
+	for rows.Next() {
+		var name string
+		var age int
+		if err := rows.Scan(&name, &age); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		names = append(names, fmt.Sprintf("%s:%d", name, age))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Iteration failed: %v", err)
+	}
+	if fmt.Sprint(names) != "[user2:22 user3:23 user4:24]" {
+		t.Errorf("Unexpected rows: %v", names)
+	}
+	if err := rows.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+
+	rows, err = provider.RawQueryRows(ctx, "SELECT name, age FROM test_users WHERE age = ?", 20)
+	if err != nil {
+		t.Fatalf("RawQueryRows failed: %v", err)
+	}
+	defer rows.Close()
+	row := map[string]interface{}{}
+	if !rows.Next() {
+		t.Fatal("Expected a row")
+	}
+	if err := rows.ScanMap(row); err != nil {
+		t.Fatalf("ScanMap failed: %v", err)
+	}
+	if fmt.Sprint(row["name"]) != "user0" || fmt.Sprint(row["age"]) != "20" {
+		t.Errorf("Unexpected row: %v", row)
+	}
+
+	if _, err := provider.RawQueryRows(ctx, "SELECT * FROM missing"); err == nil {
+		t.Error("Expected an error for a missing table")
+	}
+}
+
+func TestRawQueryRowsSessionVars(t *testing.T) {
+	cassette := &Cassette{Dialect: "postgres", ServerVersion: "16.2", Interactions: []Interaction{
+		{Query: "BEGIN"},
+		{Query: "SELECT set_config($1, $2, true)", Args: []interface{}{"app.tenant_id", "acme"}},
+		{Query: "SELECT name FROM documents", Columns: []string{"name"}, Rows: [][]interface{}{{"a"}, {"b"}}},
+		{Query: "COMMIT"},
+	}}
+	provider, err := NewReplayProvider(cassette)
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+	ctx := WithSessionVars(context.Background(), map[string]string{"app.tenant_id": "acme"})
+
+	// The transaction holding the variables ends with the cursor, not
+	// with the call opening it
+	rows, err := provider.RawQueryRows(ctx, "SELECT name FROM documents")
+	if err != nil {
+		t.Fatalf("RawQueryRows failed: %v", err)
+	}
+	if remaining := provider.ReplayRemaining(); len(remaining) != 1 || remaining[0].Query != "COMMIT" {
+		t.Errorf("Expected the transaction to stay open while the rows are, remaining %v", remaining)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	if err := rows.Close(); err != nil || fmt.Sprint(names) != "[a b]" {
+		t.Errorf("Expected both rows, got %v (%v)", names, err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Errorf("Expected a second Close to succeed, got %v", err)
+	}
+	if remaining := provider.ReplayRemaining(); len(remaining) != 0 {
+		t.Errorf("Expected every recorded statement to run, remaining %v", remaining)
+	}
+}
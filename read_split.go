@@ -0,0 +1,91 @@
+// Package gpagorm provides per-entity routing of reads to a read model
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// readBinding is where the reads of an entity type go, see BindReads
+type readBinding struct {
+	provider *Provider // Provider reads go to, nil for the repository's own
+	table    string    // Table or view read from, empty for the entity's
+}
+
+// BindReads routes the reads of entity type T made through repositories
+// of p (FindByID, FindAll, Query, QueryOne, Count, Exists and the other
+// read-only operations) to reads, and to table there when it is set, while
+// writes stay on p. A denormalized read model can so back the queries of
+// an entity without changing call sites:
+//
+//	gpagorm.BindReads[Order](primary, readModel, "reporting.order_rows")
+//
+// With a nil reads, the reads go to table on p. Reads inside a
+// transaction, and the lookups writes make for hooks and policies, stay
+// on p so they see the transaction's own changes. Passing a nil reads and
+// an empty table removes the binding.
+func BindReads[T any](p *Provider, reads *Provider, table string) error {
+	if table != "" && !isValidTableName(table) {
+		return &FieldValidationError{
+			Field:  table,
+			Reason: "table name contains invalid characters or doesn't follow naming rules",
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := reflect.TypeOf((*T)(nil)).Elem()
+	if reads == nil && table == "" {
+		delete(p.readBindings, key)
+		return nil
+	}
+	if p.readBindings == nil {
+		p.readBindings = make(map[reflect.Type]readBinding)
+	}
+	p.readBindings[key] = readBinding{provider: reads, table: table}
+	return nil
+}
+
+// readBinding returns the read binding registered for T, if any
+func (r *Repository[T]) readBinding() (readBinding, bool) {
+	if r.provider == nil {
+		return readBinding{}, false
+	}
+	r.provider.mu.RLock()
+	defer r.provider.mu.RUnlock()
+
+	binding, ok := r.provider.readBindings[reflect.TypeOf((*T)(nil)).Elem()]
+	return binding, ok
+}
+
+// boundReadSession returns the session for a read bound with BindReads,
+// or false when the read stays on the repository's own session
+func (r *Repository[T]) boundReadSession(ctx context.Context) (*gorm.DB, bool) {
+	binding, ok := r.readBinding()
+	if !ok || inTransaction(sessionDB(ctx, r.db, r.provider)) {
+		return nil, false
+	}
+
+	provider := binding.provider
+	if provider == nil {
+		provider = r.provider
+	}
+	db := sessionDB(ctx, provider.db, provider)
+
+	switch {
+	case binding.table != "":
+		db = withQualifiedTable(db, binding.table)
+	case r.view != "":
+		db = withQualifiedTable(db, r.view)
+	case r.table != "":
+		db = withQualifiedTable(db, r.table)
+	}
+
+	if pool := provider.readPool(); pool != nil && !inTransaction(db) {
+		db.Statement.ConnPool = pool
+	}
+	return db, true
+}
@@ -0,0 +1,78 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestBindReads(t *testing.T) {
+	primary, cleanup := setupTestProvider(t)
+	defer cleanup()
+	readModel, cleanupReads := setupTestProvider(t)
+	defer cleanupReads()
+	ctx := context.Background()
+
+	if err := readModel.db.Create(&TestUser{Name: "Projected", Email: "projected@example.com"}).Error; err != nil {
+		t.Fatalf("Seeding the read model failed: %v", err)
+	}
+	if err := BindReads[TestUser](primary, readModel, ""); err != nil {
+		t.Fatalf("BindReads failed: %v", err)
+	}
+
+	repo := NewRepository[TestUser](primary.db, primary)
+	if err := repo.Create(ctx, &TestUser{Name: "Written", Email: "written@example.com"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := repo.Create(ctx, &TestUser{Name: "Second", Email: "second@example.com"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	users, err := repo.Query(ctx, gpa.OrderBy("id", gpa.OrderAsc))
+	if err != nil || len(users) != 1 || users[0].Name != "Projected" {
+		t.Errorf("Expected reads from the read model, got %+v (%v)", users, err)
+	}
+	if count, _ := repo.Count(ctx); count != 1 {
+		t.Errorf("Expected 1 row in the read model, got %d", count)
+	}
+
+	// Writes and reads inside a transaction stay on the primary
+	var written int64
+	primary.db.Model(&TestUser{}).Count(&written)
+	if written != 2 {
+		t.Errorf("Expected 2 rows written to the primary, got %d", written)
+	}
+	err = repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		count, err := tx.Count(ctx)
+		if count != 2 {
+			t.Errorf("Expected the transaction to read the primary, got %d rows", count)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	// A table on the same provider
+	if err := primary.db.Exec("CREATE VIEW test_user_names AS SELECT * FROM test_users WHERE name = 'Second'").Error; err != nil {
+		t.Fatalf("Creating the view failed: %v", err)
+	}
+	if err := BindReads[TestUser](primary, nil, "test_user_names"); err != nil {
+		t.Fatalf("BindReads failed: %v", err)
+	}
+	if user, err := repo.QueryOne(ctx); err != nil || user.Name != "Second" {
+		t.Errorf("Expected reads from the view, got %+v (%v)", user, err)
+	}
+
+	if err := BindReads[TestUser](primary, nil, ""); err != nil {
+		t.Fatalf("Removing the binding failed: %v", err)
+	}
+	if count, _ := repo.Count(ctx); count != 2 {
+		t.Errorf("Expected reads back on the primary, got %d rows", count)
+	}
+
+	if err := BindReads[TestUser](primary, nil, "users; DROP TABLE x"); err == nil {
+		t.Error("Expected an invalid table name to be rejected")
+	}
+}
@@ -0,0 +1,676 @@
+// Package gpagorm provides recording and replay of SQL traffic for tests
+package gpagorm
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Cassette holds the SQL statements of a recorded run with their results
+type Cassette struct {
+	Dialect       string        `json:"dialect"`
+	ServerVersion string        `json:"server_version,omitempty"`
+	Interactions  []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded statement. Values are stored in a JSON-safe
+// form: times as {"$time": RFC 3339} and bytes as {"$bytes": base64}.
+type Interaction struct {
+	Query        string          `json:"query"`
+	Args         []interface{}   `json:"args,omitempty"`
+	Columns      []string        `json:"columns,omitempty"` // Set for queries returning rows
+	Rows         [][]interface{} `json:"rows,omitempty"`
+	RowsAffected int64           `json:"rows_affected,omitempty"`
+	LastInsertID int64           `json:"last_insert_id,omitempty"`
+	Err          string          `json:"error,omitempty"`
+}
+
+// LoadCassette reads a cassette written by Cassette.Save
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var c Cassette
+	if err := decoder.Decode(&c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path as indented JSON
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Recorder captures the statements a provider runs, see StartRecording
+type Recorder struct {
+	provider *Provider
+	pool     gorm.ConnPool
+	mu       sync.Mutex
+	cassette Cassette
+	serve    *rowServer
+}
+
+// StartRecording captures every statement the provider's primary
+// connection runs, with its arguments and results, until Stop. The
+// cassette can be saved and served without a database by
+// NewReplayProvider, for fast tests of service layers:
+//
+//	rec := provider.StartRecording()
+//	runScenario(provider)
+//	rec.Stop().Save("testdata/scenario.json")
+//
+// Recording is meant for tests; it holds every result in memory.
+func (p *Provider) StartRecording() *Recorder {
+	caps := p.Capabilities()
+	rec := &Recorder{
+		provider: p,
+		pool:     p.db.ConnPool,
+		cassette: Cassette{Dialect: caps.Dialect, ServerVersion: caps.ServerVersion},
+		serve:    newRowServer(),
+	}
+	recording := &recordingPool{pool: rec.pool, rec: rec}
+	p.db.ConnPool = recording
+	p.db.Statement.ConnPool = recording
+	return rec
+}
+
+// Stop restores the provider's connection and returns the cassette
+func (rec *Recorder) Stop() *Cassette {
+	rec.provider.db.ConnPool = rec.pool
+	rec.provider.db.Statement.ConnPool = rec.pool
+	rec.serve.close()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	c := rec.cassette
+	c.Interactions = append([]Interaction(nil), rec.cassette.Interactions...)
+	return &c
+}
+
+// add appends an interaction
+func (rec *Recorder) add(interaction Interaction) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.cassette.Interactions = append(rec.cassette.Interactions, interaction)
+}
+
+// recordingPool runs statements on pool and records them
+type recordingPool struct {
+	pool gorm.ConnPool
+	rec  *Recorder
+}
+
+// PrepareContext implements gorm.ConnPool
+func (p *recordingPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.pool.PrepareContext(ctx, query)
+}
+
+// ExecContext implements gorm.ConnPool
+func (p *recordingPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	interaction := Interaction{Query: query, Args: encodeArgs(args)}
+	result, err := p.pool.ExecContext(ctx, query, args...)
+	if err != nil {
+		interaction.Err = err.Error()
+	} else {
+		interaction.RowsAffected, _ = result.RowsAffected()
+		interaction.LastInsertID, _ = result.LastInsertId()
+	}
+	p.rec.add(interaction)
+	return result, err
+}
+
+// QueryContext implements gorm.ConnPool. The rows are read in full,
+// recorded and then served from the recording.
+func (p *recordingPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	interaction := p.query(ctx, query, args)
+	return p.rec.serve.query(ctx, interaction)
+}
+
+// QueryRowContext implements gorm.ConnPool
+func (p *recordingPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	interaction := p.query(ctx, query, args)
+	return p.rec.serve.queryRow(ctx, interaction)
+}
+
+// query runs and records a statement returning rows
+func (p *recordingPool) query(ctx context.Context, query string, args []interface{}) Interaction {
+	interaction := Interaction{Query: query, Args: encodeArgs(args)}
+	rows, err := p.pool.QueryContext(ctx, query, args...)
+	if err == nil {
+		interaction.Columns, interaction.Rows, err = readRows(rows)
+	}
+	if err != nil {
+		interaction.Err = err.Error()
+	}
+	p.rec.add(interaction)
+	return interaction
+}
+
+// BeginTx implements gorm.ConnPoolBeginner
+func (p *recordingPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var tx gorm.ConnPool
+	var err error
+	switch beginner := p.pool.(type) {
+	case gorm.TxBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	case gorm.ConnPoolBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+	if err != nil {
+		return nil, err
+	}
+	committer, ok := tx.(gorm.TxCommitter)
+	if !ok {
+		return nil, gorm.ErrInvalidTransaction
+	}
+	p.rec.add(Interaction{Query: "BEGIN"})
+	return &recordingTx{pool: &recordingPool{pool: tx, rec: p.rec}, tx: committer}, nil
+}
+
+// GetDBConn implements gorm.GetDBConnector
+func (p *recordingPool) GetDBConn() (*sql.DB, error) {
+	if db, ok := p.pool.(*sql.DB); ok {
+		return db, nil
+	}
+	if connector, ok := p.pool.(gorm.GetDBConnector); ok {
+		return connector.GetDBConn()
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// recordingTx is a recorded transaction. Like replayTx it does not begin
+// transactions itself.
+type recordingTx struct {
+	pool *recordingPool
+	tx   gorm.TxCommitter
+}
+
+// PrepareContext implements gorm.ConnPool
+func (tx *recordingTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return tx.pool.PrepareContext(ctx, query)
+}
+
+// ExecContext implements gorm.ConnPool
+func (tx *recordingTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.pool.ExecContext(ctx, query, args...)
+}
+
+// QueryContext implements gorm.ConnPool
+func (tx *recordingTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.pool.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext implements gorm.ConnPool
+func (tx *recordingTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.pool.QueryRowContext(ctx, query, args...)
+}
+
+// Commit implements gorm.TxCommitter
+func (tx *recordingTx) Commit() error {
+	tx.pool.rec.add(Interaction{Query: "COMMIT"})
+	return tx.tx.Commit()
+}
+
+// Rollback implements gorm.TxCommitter
+func (tx *recordingTx) Rollback() error {
+	tx.pool.rec.add(Interaction{Query: "ROLLBACK"})
+	return tx.tx.Rollback()
+}
+
+// readRows reads and closes rows, encoding their values
+func readRows(rows *sql.Rows) ([]string, [][]interface{}, error) {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	data := [][]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, nil, err
+		}
+		for i, v := range values {
+			values[i] = encodeValue(v)
+		}
+		data = append(data, values)
+	}
+	return columns, data, rows.Err()
+}
+
+// encodeArgs converts statement arguments to their recorded form
+func encodeArgs(args []interface{}) []interface{} {
+	encoded := make([]interface{}, len(args))
+	for i, arg := range args {
+		if named, ok := arg.(sql.NamedArg); ok {
+			arg = named.Value
+		}
+		if valuer, ok := arg.(driver.Valuer); ok {
+			if v, err := valuer.Value(); err == nil {
+				arg = v
+			}
+		}
+		if v, err := driver.DefaultParameterConverter.ConvertValue(arg); err == nil {
+			arg = v
+		} else {
+			arg = fmt.Sprint(arg)
+		}
+		encoded[i] = encodeValue(arg)
+	}
+	return encoded
+}
+
+// encodeValue converts a driver value to its JSON-safe recorded form
+func encodeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		return map[string]interface{}{"$time": v.Format(time.RFC3339Nano)}
+	case []byte:
+		return map[string]interface{}{"$bytes": base64.StdEncoding.EncodeToString(v)}
+	}
+	return v
+}
+
+// decodeValue converts a recorded value back to a driver value
+func decodeValue(v interface{}) (driver.Value, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if s, ok := v["$time"].(string); ok {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+		if s, ok := v["$bytes"].(string); ok {
+			return base64.StdEncoding.DecodeString(s)
+		}
+		return nil, fmt.Errorf("unknown recorded value %v", v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	case int:
+		return int64(v), nil
+	}
+	return v, nil
+}
+
+// NewReplayProvider returns a provider that serves the statements of a
+// cassette instead of querying a database. Statements must be issued as
+// recorded: each one is answered by the first unused interaction with the
+// same SQL and arguments, where time arguments are not compared since they
+// usually differ between runs. Unmatched statements fail with an error
+// naming the SQL. Recorded errors are replayed as plain errors.
+func NewReplayProvider(c *Cassette) (*Provider, error) {
+	pool := &replayPool{cassette: c, used: make([]bool, len(c.Interactions)), serve: newRowServer()}
+
+	var dialector gorm.Dialector
+	switch c.Dialect {
+	case "postgres":
+		dialector = postgres.New(postgres.Config{Conn: pool})
+	case "mysql":
+		dialector = mysql.New(mysql.Config{Conn: pool, ServerVersion: c.ServerVersion, SkipInitializeWithVersion: true})
+	case "sqlite":
+		dialector = sqlite.Dialector{Conn: pool}
+	case "sqlserver":
+		dialector = sqlserver.New(sqlserver.Config{Conn: pool})
+	default:
+		return nil, fmt.Errorf("unsupported cassette dialect: %q", c.Dialect)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard, DisableAutomaticPing: true})
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &Provider{db: db, config: gpa.Config{Driver: c.Dialect}}
+	provider.capabilitiesOnce.Do(func() {
+		provider.capabilities = capabilitiesFor(c.Dialect, c.ServerVersion)
+	})
+	return provider, nil
+}
+
+// replayPool answers statements from a cassette
+type replayPool struct {
+	cassette *Cassette
+	mu       sync.Mutex
+	used     []bool
+	serve    *rowServer
+}
+
+// next returns the first unused interaction matching query and args
+func (p *replayPool) next(query string, args []interface{}) (Interaction, error) {
+	// Dialector initialization, not part of the recording
+	if query == "select sqlite_version()" && p.cassette.Dialect == "sqlite" {
+		return Interaction{Query: query, Columns: []string{"version"}, Rows: [][]interface{}{{p.cassette.ServerVersion}}}, nil
+	}
+
+	encoded, _ := json.Marshal(maskTimes(encodeArgs(args)))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, interaction := range p.cassette.Interactions {
+		if p.used[i] || interaction.Query != query {
+			continue
+		}
+		if recorded, _ := json.Marshal(maskTimes(interaction.Args)); !bytes.Equal(recorded, encoded) {
+			continue
+		}
+		p.used[i] = true
+		return interaction, nil
+	}
+	return Interaction{}, fmt.Errorf("gpagorm: no recorded interaction for %s %s", query, encoded)
+}
+
+// maskTimes replaces recorded times so they match any time
+func maskTimes(values []interface{}) []interface{} {
+	masked := make([]interface{}, len(values))
+	for i, v := range values {
+		if m, ok := v.(map[string]interface{}); ok {
+			if _, ok := m["$time"]; ok {
+				v = "$time"
+			}
+		}
+		masked[i] = v
+	}
+	return masked
+}
+
+// ReplayRemaining returns the interactions of a replay provider's cassette
+// that have not been replayed, so tests can assert the whole recording was
+// used. It returns nil for other providers.
+func (p *Provider) ReplayRemaining() []Interaction {
+	if pool, ok := p.db.ConnPool.(*replayPool); ok {
+		return pool.remaining()
+	}
+	return nil
+}
+
+// remaining returns the interactions that were not replayed
+func (p *replayPool) remaining() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var remaining []Interaction
+	for i, interaction := range p.cassette.Interactions {
+		if !p.used[i] {
+			remaining = append(remaining, interaction)
+		}
+	}
+	return remaining
+}
+
+// PrepareContext implements gorm.ConnPool
+func (p *replayPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("gpagorm: prepared statements cannot be replayed")
+}
+
+// ExecContext implements gorm.ConnPool
+func (p *replayPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	interaction, err := p.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if interaction.Err != "" {
+		return nil, errors.New(interaction.Err)
+	}
+	return replayResult{interaction}, nil
+}
+
+// QueryContext implements gorm.ConnPool
+func (p *replayPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	interaction, err := p.next(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return p.serve.query(ctx, interaction)
+}
+
+// QueryRowContext implements gorm.ConnPool
+func (p *replayPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	interaction, err := p.next(query, args)
+	if err != nil {
+		interaction = Interaction{Query: query, Err: err.Error()}
+	}
+	return p.serve.queryRow(ctx, interaction)
+}
+
+// BeginTx implements gorm.ConnPoolBeginner
+func (p *replayPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	if _, err := p.next("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return &replayTx{pool: p}, nil
+}
+
+// GetDBConn implements gorm.GetDBConnector with the database serving rows
+func (p *replayPool) GetDBConn() (*sql.DB, error) {
+	return p.serve.db, nil
+}
+
+// replayTx is a replayed transaction. It does not begin transactions
+// itself, so statements inside it are not wrapped in another one.
+type replayTx struct {
+	pool *replayPool
+}
+
+// PrepareContext implements gorm.ConnPool
+func (tx *replayTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return tx.pool.PrepareContext(ctx, query)
+}
+
+// ExecContext implements gorm.ConnPool
+func (tx *replayTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.pool.ExecContext(ctx, query, args...)
+}
+
+// QueryContext implements gorm.ConnPool
+func (tx *replayTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.pool.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext implements gorm.ConnPool
+func (tx *replayTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.pool.QueryRowContext(ctx, query, args...)
+}
+
+// Commit implements gorm.TxCommitter
+func (tx *replayTx) Commit() error {
+	_, err := tx.pool.next("COMMIT", nil)
+	return err
+}
+
+// Rollback implements gorm.TxCommitter
+func (tx *replayTx) Rollback() error {
+	_, err := tx.pool.next("ROLLBACK", nil)
+	return err
+}
+
+// replayResult is the sql.Result of a recorded statement
+type replayResult struct {
+	interaction Interaction
+}
+
+// LastInsertId implements sql.Result
+func (r replayResult) LastInsertId() (int64, error) { return r.interaction.LastInsertID, nil }
+
+// RowsAffected implements sql.Result
+func (r replayResult) RowsAffected() (int64, error) { return r.interaction.RowsAffected, nil }
+
+// rowServer turns recorded rows into *sql.Rows through an in-process
+// driver, as sql.Rows cannot be built directly
+type rowServer struct {
+	db      *sql.DB
+	mu      sync.Mutex
+	pending map[string]Interaction
+	seq     int
+}
+
+// newRowServer opens a row server
+func newRowServer() *rowServer {
+	s := &rowServer{pending: map[string]Interaction{}}
+	s.db = sql.OpenDB(rowConnector{s})
+	return s
+}
+
+// close closes the row server's database
+func (s *rowServer) close() {
+	s.db.Close()
+}
+
+// token registers interaction and returns the query that serves it
+func (s *rowServer) token(interaction Interaction) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	token := strconv.Itoa(s.seq)
+	s.pending[token] = interaction
+	return token
+}
+
+// take removes and returns the interaction registered under token
+func (s *rowServer) take(token string) (Interaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	interaction, ok := s.pending[token]
+	delete(s.pending, token)
+	return interaction, ok
+}
+
+// query serves the rows of interaction
+func (s *rowServer) query(ctx context.Context, interaction Interaction) (*sql.Rows, error) {
+	if interaction.Err != "" {
+		return nil, errors.New(interaction.Err)
+	}
+	return s.db.QueryContext(ctx, s.token(interaction))
+}
+
+// queryRow serves the first row of interaction
+func (s *rowServer) queryRow(ctx context.Context, interaction Interaction) *sql.Row {
+	return s.db.QueryRowContext(ctx, s.token(interaction))
+}
+
+// rowConnector connects to a row server
+type rowConnector struct {
+	server *rowServer
+}
+
+// Connect implements driver.Connector
+func (c rowConnector) Connect(context.Context) (driver.Conn, error) { return rowConn(c), nil }
+
+// Driver implements driver.Connector
+func (c rowConnector) Driver() driver.Driver { return rowDriver{} }
+
+// rowDriver is the driver of row servers, only reachable through a connector
+type rowDriver struct{}
+
+// Open implements driver.Driver
+func (rowDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("gpagorm: row server needs a connector")
+}
+
+// rowConn is a connection to a row server
+type rowConn struct {
+	server *rowServer
+}
+
+// Prepare implements driver.Conn
+func (c rowConn) Prepare(query string) (driver.Stmt, error) { return rowStmt{c.server, query}, nil }
+
+// Close implements driver.Conn
+func (c rowConn) Close() error { return nil }
+
+// Begin implements driver.Conn
+func (c rowConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("gpagorm: row server has no transactions")
+}
+
+// rowStmt is a statement on a row server; its query is a token
+type rowStmt struct {
+	server *rowServer
+	token  string
+}
+
+// Close implements driver.Stmt
+func (s rowStmt) Close() error { return nil }
+
+// NumInput implements driver.Stmt
+func (s rowStmt) NumInput() int { return 0 }
+
+// Exec implements driver.Stmt
+func (s rowStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("gpagorm: row server cannot execute statements")
+}
+
+// Query implements driver.Stmt
+func (s rowStmt) Query([]driver.Value) (driver.Rows, error) {
+	interaction, ok := s.server.take(s.token)
+	if !ok {
+		return nil, fmt.Errorf("gpagorm: unknown row server token %q", s.token)
+	}
+	if interaction.Err != "" {
+		return nil, errors.New(interaction.Err)
+	}
+	return &recordedRows{interaction: interaction}, nil
+}
+
+// recordedRows iterates the rows of an interaction
+type recordedRows struct {
+	interaction Interaction
+	pos         int
+}
+
+// Columns implements driver.Rows
+func (r *recordedRows) Columns() []string { return r.interaction.Columns }
+
+// Close implements driver.Rows
+func (r *recordedRows) Close() error { return nil }
+
+// Next implements driver.Rows
+func (r *recordedRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.interaction.Rows) {
+		return io.EOF
+	}
+	row := r.interaction.Rows[r.pos]
+	r.pos++
+	for i := range dest {
+		if i >= len(row) {
+			dest[i] = nil
+			continue
+		}
+		v, err := decodeValue(row[i])
+		if err != nil {
+			return err
+		}
+		dest[i] = v
+	}
+	return nil
+}
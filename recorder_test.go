@@ -0,0 +1,84 @@
+package gpagorm
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// recordedScenario is a service-layer flow run against a live and a
+// replayed provider
+func recordedScenario(t *testing.T, provider *Provider) (*TestUser, int64) {
+	t.Helper()
+	ctx := context.Background()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	if err := repo.Create(ctx, &TestUser{Name: "Ann", Email: "ann@example.com", Age: 30}); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+	err := provider.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Model(&TestUser{}).Where("email = ?", "ann@example.com").Update("age", 31).Error
+	})
+	if err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	user, err := repo.QueryOne(ctx, gpa.Where("email", gpa.OpEqual, "ann@example.com"))
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	count, err := repo.Count(ctx)
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	return user, count
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	rec := provider.StartRecording()
+	recorded, recordedCount := recordedScenario(t, provider)
+	cassette := rec.Stop()
+	if len(cassette.Interactions) == 0 || cassette.Dialect != "sqlite" {
+		t.Fatalf("Expected a sqlite recording, got %+v", cassette)
+	}
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	loaded, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+
+	replay, err := NewReplayProvider(loaded)
+	if err != nil {
+		t.Fatalf("Failed to open replay provider: %v", err)
+	}
+	defer replay.Close()
+
+	time.Sleep(time.Millisecond) // timestamps differ from the recording
+	replayed, replayedCount := recordedScenario(t, replay)
+	if replayed.ID != recorded.ID || replayed.Age != 31 || replayed.Name != "Ann" {
+		t.Errorf("Expected %+v, got %+v", recorded, replayed)
+	}
+	if replayedCount != recordedCount {
+		t.Errorf("Expected count %d, got %d", recordedCount, replayedCount)
+	}
+	if remaining := replay.ReplayRemaining(); len(remaining) != 0 {
+		t.Errorf("Expected the whole cassette to be replayed, %d left", len(remaining))
+	}
+
+	repo := NewRepository[TestUser](replay.db, replay)
+	_, err = repo.FindByID(context.Background(), 99)
+	if err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("Expected unrecorded statements to fail, got %v", err)
+	}
+}
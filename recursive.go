@@ -0,0 +1,174 @@
+// Package gpagorm provides recursive CTE traversal of tree-shaped entities
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// defaultParentKey is the column referencing an entity's parent unless
+// ParentKey names another
+const defaultParentKey = "parent_id"
+
+// ParentKeyOption names the column referencing an entity's parent for
+// FindDescendants and FindAncestors. It carries no state for gpa.Query.
+type ParentKeyOption struct {
+	Field string
+}
+
+// Apply implements gpa.QueryOption
+func (o ParentKeyOption) Apply(query *gpa.Query) {}
+
+// ParentKey traverses a tree through field, e.g. "manager_id", instead of
+// "parent_id"
+func ParentKey(field string) gpa.QueryOption {
+	return ParentKeyOption{Field: field}
+}
+
+// MaxDepthOption bounds how many levels FindDescendants and FindAncestors
+// traverse. It carries no state for gpa.Query.
+type MaxDepthOption struct {
+	Depth int
+}
+
+// Apply implements gpa.QueryOption
+func (o MaxDepthOption) Apply(query *gpa.Query) {}
+
+// MaxDepth stops a traversal after depth levels: 1 returns only the
+// children, or only the parent
+func MaxDepth(depth int) gpa.QueryOption {
+	return MaxDepthOption{Depth: depth}
+}
+
+// FindDescendants returns the entities below the entity with id in its
+// tree, at any depth, that match opts. The tree is walked with a
+// recursive CTE over the "parent_id" column, or the column named by
+// ParentKey:
+//
+//	// Every subcategory of "Books", however deeply nested
+//	categories, err := categoryRepo.FindDescendants(ctx, booksID, gpa.OrderBy("name", gpa.OrderAsc))
+//	// Direct and second-level reports
+//	staff, err := employeeRepo.FindDescendants(ctx, ceoID, gpagorm.ParentKey("manager_id"), gpagorm.MaxDepth(2))
+//
+// The entity itself is not included. opts filter the returned entities,
+// not the walk: a descendant whose parent does not match is still
+// returned. Supported on Postgres, MySQL 8 and SQLite.
+func (r *Repository[T]) FindDescendants(ctx context.Context, id interface{}, opts ...gpa.QueryOption) (entities []*T, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationFindDescendants, ID: id, Options: opts}, func(ctx context.Context) error {
+		var err error
+		entities, err = r.findTree(ctx, id, false, opts)
+		return err
+	})
+	return entities, err
+}
+
+// FindAncestors returns the entities above the entity with id in its
+// tree, up to the root, that match opts, for breadcrumbs and inherited
+// settings. It walks the tree like FindDescendants, in the other
+// direction; the entity itself is not included.
+func (r *Repository[T]) FindAncestors(ctx context.Context, id interface{}, opts ...gpa.QueryOption) (entities []*T, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationFindAncestors, ID: id, Options: opts}, func(ctx context.Context) error {
+		var err error
+		entities, err = r.findTree(ctx, id, true, opts)
+		return err
+	})
+	return entities, err
+}
+
+// findTree implements FindDescendants and FindAncestors
+func (r *Repository[T]) findTree(ctx context.Context, id interface{}, ancestors bool, opts []gpa.QueryOption) ([]*T, error) {
+	if err := r.authorizeRead(ctx, opts); err != nil {
+		return nil, err
+	}
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	parentKey, maxDepth := defaultParentKey, 0
+	var filters []gpa.QueryOption
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case ParentKeyOption:
+			parentKey = o.Field
+		case MaxDepthOption:
+			if o.Depth <= 0 {
+				return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "max depth must be positive")
+			}
+			maxDepth = o.Depth
+		default:
+			filters = append(filters, opt)
+		}
+	}
+	if s.PrioritizedPrimaryField == nil {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, s.Name+" has no primary key to walk a tree by")
+	}
+	parent := s.LookUpField(parentKey)
+	if parent == nil || parent.DBName == "" {
+		return nil, &FieldValidationError{Field: parentKey, Reason: "field is not a column of " + s.Name}
+	}
+
+	table := s.Table
+	if r.view != "" {
+		table = r.view
+	} else if r.table != "" {
+		table = r.table
+	}
+	query := r.buildQuery(ctx, filters...)
+	cte, err := treeCTE(query, table, s.PrioritizedPrimaryField.DBName, parent.DBName, ancestors, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	key := query.Statement.Quote(table + "." + s.PrioritizedPrimaryField.DBName)
+
+	var entities []*T
+	if err := convertGormError(query.Where(key+" IN ("+cte+")", id).Find(&entities).Error); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// treeCTE returns a subquery selecting the keys of the descendants, or
+// ancestors, of the row whose key is its single argument. Without a max
+// depth the recursion is a UNION of keys alone, so a cycle in the data
+// ends the walk instead of looping.
+func treeCTE(db *gorm.DB, table, key, parent string, ancestors bool, maxDepth int) (string, error) {
+	switch dialect := dialectName(db); dialect {
+	case "postgres", "mysql", "sqlite":
+	default:
+		return "", gpa.NewError(gpa.ErrorTypeUnsupported, "recursive tree queries are not supported on "+dialect)
+	}
+
+	q := db.Statement.Quote
+	cteName, node, depth := q("gpagorm_tree"), q("node_id"), q("depth")
+
+	// Descendants start from the rows whose parent is the node and follow
+	// parent keys down; ancestors start from the node's parent and follow
+	// them up
+	anchorSelect, anchorWhere := q(key), q(parent)+" = ?"
+	stepSelect, stepJoin := "t."+q(key), "t."+q(parent)
+	var stepWhere []string
+	if ancestors {
+		anchorSelect, anchorWhere = q(parent), q(key)+" = ? AND "+q(parent)+" IS NOT NULL"
+		stepSelect, stepJoin = "t."+q(parent), "t."+q(key)
+		stepWhere = append(stepWhere, "t."+q(parent)+" IS NOT NULL")
+	}
+	columns := node
+	if maxDepth > 0 {
+		columns += ", " + depth
+		anchorSelect += ", 1"
+		stepSelect += ", " + cteName + "." + depth + " + 1"
+		stepWhere = append(stepWhere, fmt.Sprintf("%s.%s < %d", cteName, depth, maxDepth))
+	}
+
+	step := fmt.Sprintf("SELECT %s FROM %s t JOIN %s ON %s = %s.%s", stepSelect, q(table), cteName, stepJoin, cteName, node)
+	if len(stepWhere) > 0 {
+		step += " WHERE " + strings.Join(stepWhere, " AND ")
+	}
+	return fmt.Sprintf("WITH RECURSIVE %s (%s) AS (SELECT %s FROM %s WHERE %s UNION %s) SELECT %s FROM %s",
+		cteName, columns, anchorSelect, q(table), anchorWhere, step, node, cteName), nil
+}
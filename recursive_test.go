@@ -0,0 +1,129 @@
+package gpagorm
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+type testCategory struct {
+	ID       uint
+	Name     string
+	ParentID *uint
+}
+
+type testEmployee struct {
+	ID        uint
+	Name      string
+	ManagerID *uint
+}
+
+func treeNames[T any](entities []*T, name func(*T) string) string {
+	names := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		names = append(names, name(entity))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestFindDescendantsAndAncestors(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&testCategory{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[testCategory](provider.db, provider)
+	ctx := context.Background()
+
+	// books > fiction > fantasy > epic, books > history; music stands alone
+	add := func(name string, parent *testCategory) *testCategory {
+		category := &testCategory{Name: name}
+		if parent != nil {
+			category.ParentID = &parent.ID
+		}
+		if err := repo.Create(ctx, category); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		return category
+	}
+	books := add("books", nil)
+	fiction := add("fiction", books)
+	fantasy := add("fantasy", fiction)
+	epic := add("epic", fantasy)
+	add("history", books)
+	add("music", nil)
+	name := func(c *testCategory) string { return c.Name }
+
+	descendants, err := repo.FindDescendants(ctx, books.ID)
+	if got := treeNames(descendants, name); err != nil || got != "epic,fantasy,fiction,history" {
+		t.Errorf("Expected every descendant, got %q (%v)", got, err)
+	}
+	descendants, err = repo.FindDescendants(ctx, books.ID, MaxDepth(2), gpa.Where("name", gpa.OpNotEqual, "history"))
+	if got := treeNames(descendants, name); err != nil || got != "fantasy,fiction" {
+		t.Errorf("Expected two filtered levels, got %q (%v)", got, err)
+	}
+
+	ancestors, err := repo.FindAncestors(ctx, epic.ID)
+	if got := treeNames(ancestors, name); err != nil || got != "books,fantasy,fiction" {
+		t.Errorf("Expected every ancestor, got %q (%v)", got, err)
+	}
+	ancestors, err = repo.FindAncestors(ctx, epic.ID, MaxDepth(1))
+	if got := treeNames(ancestors, name); err != nil || got != "fantasy" {
+		t.Errorf("Expected the parent, got %q (%v)", got, err)
+	}
+	if ancestors, err := repo.FindAncestors(ctx, books.ID); err != nil || len(ancestors) != 0 {
+		t.Errorf("Expected a root to have no ancestors, got %v (%v)", ancestors, err)
+	}
+
+	// A cycle ends the walk
+	provider.db.Model(&testCategory{}).Where("id = ?", books.ID).Update("parent_id", epic.ID)
+	if descendants, err := repo.FindDescendants(ctx, fantasy.ID); err != nil || len(descendants) != 5 {
+		t.Errorf("Expected the cycle to be walked once, got %d (%v)", len(descendants), err)
+	}
+
+	if _, err := repo.FindDescendants(ctx, books.ID, ParentKey("owner_id")); err == nil {
+		t.Error("Expected an unknown parent key to be rejected")
+	}
+	if _, err := repo.FindDescendants(ctx, books.ID, MaxDepth(0)); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for a zero depth, got %v", err)
+	}
+}
+
+func TestFindDescendantsParentKey(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&testEmployee{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	ctx := context.Background()
+
+	ceo := &testEmployee{Name: "ceo"}
+	provider.db.Create(ceo)
+	cto := &testEmployee{Name: "cto", ManagerID: &ceo.ID}
+	provider.db.Create(cto)
+	provider.db.Create(&testEmployee{Name: "dev", ManagerID: &cto.ID})
+
+	repo := NewRepository[testEmployee](provider.db, provider)
+	staff, err := repo.FindDescendants(ctx, ceo.ID, ParentKey("ManagerID"))
+	if got := treeNames(staff, func(e *testEmployee) string { return e.Name }); err != nil || got != "cto,dev" {
+		t.Errorf("Expected the reports, got %q (%v)", got, err)
+	}
+
+	dry := NewRepository[testEmployee](provider.db.Session(&gorm.Session{DryRun: true}), provider)
+	var rows []*testEmployee
+	query := dry.buildQuery(ctx)
+	cte, _ := treeCTE(query, "test_employees", "id", "manager_id", true, 3)
+	sql := query.Where("id IN ("+cte+")", 1).Find(&rows).Statement.SQL.String()
+	want := "SELECT * FROM `test_employees` WHERE id IN (WITH RECURSIVE `gpagorm_tree` (`node_id`, `depth`) AS (" +
+		"SELECT `manager_id`, 1 FROM `test_employees` WHERE `id` = ? AND `manager_id` IS NOT NULL UNION " +
+		"SELECT t.`manager_id`, `gpagorm_tree`.`depth` + 1 FROM `test_employees` t JOIN `gpagorm_tree` ON t.`id` = `gpagorm_tree`.`node_id` " +
+		"WHERE t.`manager_id` IS NOT NULL AND `gpagorm_tree`.`depth` < 3) SELECT `node_id` FROM `gpagorm_tree`)"
+	if sql != want {
+		t.Errorf("Expected %s, got %s", want, sql)
+	}
+}
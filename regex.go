@@ -0,0 +1,27 @@
+// Package gpagorm provides regular expression conditions
+package gpagorm
+
+import (
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// regexSQL returns the predicate matching field against a pattern bound
+// as its single argument, for gpa.OpRegex:
+//
+//	repo.Query(ctx, gpa.Where("slug", gpa.OpRegex, "^[a-z0-9-]+$"))
+//
+// Postgres matches with ~ (POSIX syntax), MySQL with REGEXP (ICU syntax)
+// and SQLite with REGEXP, which the adapter implements with Go's regexp
+// package. SQL Server has no regex operator and returns an unsupported
+// error.
+func regexSQL(db *gorm.DB, field string) (string, error) {
+	switch dialect := dialectName(db); dialect {
+	case "postgres":
+		return field + " ~ ?", nil
+	case "mysql", "sqlite":
+		return field + " REGEXP ?", nil
+	default:
+		return "", gpa.NewError(gpa.ErrorTypeUnsupported, "regular expressions are not supported on "+dialect)
+	}
+}
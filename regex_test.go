@@ -0,0 +1,43 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+)
+
+func TestRegexCondition(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	for _, email := range []string{"ann@example.com", "bob-2@example.org", "CID@example.com"} {
+		if err := repo.Create(ctx, &TestUser{Name: email, Email: email}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	users, err := repo.Query(ctx, gpa.Where("email", gpa.OpRegex, `^[a-z]+@example\.com$`))
+	if err != nil || len(users) != 1 || users[0].Email != "ann@example.com" {
+		t.Errorf("Expected ann only, got %+v (%v)", users, err)
+	}
+	if n, err := repo.Count(ctx, gpa.Where("email", gpa.OpRegex, `\d`)); err != nil || n != 1 {
+		t.Errorf("Expected 1 email with a digit, got %d (%v)", n, err)
+	}
+}
+
+func TestRegexConditionSQLServer(t *testing.T) {
+	db, err := gorm.Open(sqlserver.Open("sqlserver://localhost?database=test"), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry-run sqlserver: %v", err)
+	}
+	var users []*TestUser
+	err = NewRepository[TestUser](db, nil).buildQuery(context.Background(), gpa.Where("email", gpa.OpRegex, "^a")).Find(&users).Error
+	if !gpa.IsErrorType(err, gpa.ErrorTypeUnsupported) {
+		t.Errorf("Expected unsupported on SQL Server, got %v", err)
+	}
+}
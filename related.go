@@ -0,0 +1,74 @@
+// Package gpagorm provides batched loading of associations GORM does not model
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// relatedChunkSize bounds the keys of one IN query, below the parameter
+// limits of every supported driver
+const relatedChunkSize = 1000
+
+// LoadRelated loads the children of parents in one IN query per 1000 keys
+// instead of one query per parent, for associations that are not modeled
+// as GORM relations, e.g. across repositories or databases. key returns a
+// parent's key, foreignKey is the child column holding it, and set receives
+// each parent with its children (nil when it has none). opts further filter
+// and order the children.
+//
+//	err := gpagorm.LoadRelated(ctx, orders, auditRepo, "order_id",
+//	    func(o *Order) uint { return o.ID },
+//	    func(o *Order, entries []*AuditEntry) { o.Audit = entries })
+func LoadRelated[A any, B any, K comparable](ctx context.Context, parents []*A, childRepo *Repository[B], foreignKey string, key func(*A) K, set func(*A, []*B), opts ...gpa.QueryOption) error {
+	var zero B
+	stmt := &gorm.Statement{DB: childRepo.db}
+	if err := stmt.Parse(&zero); err != nil {
+		return convertGormError(err)
+	}
+	field := stmt.Schema.LookUpField(foreignKey)
+	if field == nil || field.DBName == "" {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("%s has no column %s", stmt.Schema.Name, foreignKey))
+	}
+	keyType := reflect.TypeOf((*K)(nil)).Elem()
+
+	var keys []interface{}
+	seen := map[K]bool{}
+	for _, parent := range parents {
+		if k := key(parent); !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+
+	children := map[K][]*B{}
+	for start := 0; start < len(keys); start += relatedChunkSize {
+		end := min(start+relatedChunkSize, len(keys))
+		chunkOpts := append([]gpa.QueryOption{gpa.Where(field.DBName, gpa.OpIn, keys[start:end])}, opts...)
+		batch, err := childRepo.Query(ctx, chunkOpts...)
+		if err != nil {
+			return err
+		}
+		for _, child := range batch {
+			value, _ := field.ValueOf(ctx, reflect.ValueOf(child).Elem())
+			rv := reflect.Indirect(reflect.ValueOf(value))
+			if !rv.IsValid() {
+				continue // NULL foreign key
+			}
+			if !rv.Type().ConvertibleTo(keyType) {
+				return gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("column %s of %s holds %T, not %s", field.DBName, stmt.Schema.Name, value, keyType))
+			}
+			k := rv.Convert(keyType).Interface().(K)
+			children[k] = append(children[k], child)
+		}
+	}
+
+	for _, parent := range parents {
+		set(parent, children[key(parent)])
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+type testAuditEntry struct {
+	ID     uint `gorm:"primaryKey"`
+	UserID *uint
+	Action string
+}
+
+func TestLoadRelated(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	provider.db.AutoMigrate(&testAuditEntry{})
+
+	users := []*TestUser{
+		{Name: "Ann", Email: "ann@example.com"},
+		{Name: "Bob", Email: "bob@example.com"},
+		{Name: "Cid", Email: "cid@example.com"},
+	}
+	provider.db.Create(&users)
+	uid := func(u *TestUser) *uint { id := u.ID; return &id }
+	provider.db.Create(&[]testAuditEntry{
+		{UserID: uid(users[0]), Action: "login"},
+		{UserID: uid(users[1]), Action: "login"},
+		{UserID: uid(users[0]), Action: "logout"},
+		{Action: "system"},
+	})
+
+	audit := map[*TestUser][]*testAuditEntry{users[2]: {{Action: "stale"}}}
+	queries := 0
+	provider.AddInterceptor(func(ctx context.Context, op OperationInfo, next func(context.Context) error) error {
+		if op.Operation == OperationQuery {
+			queries++
+		}
+		return next(ctx)
+	})
+	auditRepo := NewRepository[testAuditEntry](provider.db, provider)
+
+	err := LoadRelated(ctx, users, auditRepo, "user_id",
+		func(u *TestUser) uint { return u.ID },
+		func(u *TestUser, entries []*testAuditEntry) { audit[u] = entries },
+		gpa.OrderBy("id", gpa.OrderDesc))
+	if err != nil {
+		t.Fatalf("Failed to load related: %v", err)
+	}
+	if queries != 1 {
+		t.Errorf("Expected one query, got %d", queries)
+	}
+	if got := audit[users[0]]; len(got) != 2 || got[0].Action != "logout" || got[1].Action != "login" {
+		t.Errorf("Expected Ann's entries newest first, got %v", got)
+	}
+	if got := audit[users[1]]; len(got) != 1 || got[0].Action != "login" {
+		t.Errorf("Expected Bob's entry, got %v", got)
+	}
+	if got, ok := audit[users[2]]; !ok || got != nil {
+		t.Errorf("Expected Cid's entries to be reset, got %v", got)
+	}
+
+	err = LoadRelated(ctx, users, auditRepo, "owner_id",
+		func(u *TestUser) uint { return u.ID },
+		func(u *TestUser, entries []*testAuditEntry) {})
+	if !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for an unknown column, got %v", err)
+	}
+}
@@ -0,0 +1,441 @@
+// Package gpagorm provides configuration reloading for a live provider
+package gpagorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Configure applies config to the live provider:
+//
+//   - pool limits are applied to the primary and every replica
+//   - the "log_level" option takes effect for subsequent statements
+//   - replicas listed in "replicas" are opened or closed to match
+//   - changed connection settings (host, credentials, database, SSL) are
+//     verified with a new connection and used for every connection opened
+//     afterwards; idle connections are closed, busy ones retire on return
+//     or when ConnMaxLifetime expires
+//
+// Changes are applied in that order; on error the remaining ones are not
+// and the stored config is left as it was. Other options take effect
+// where they are read per call. Changing the driver requires a new
+// provider.
+func (p *Provider) Configure(config gpa.Config) error {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	if p.db == nil {
+		p.setConfig(config)
+		return nil
+	}
+	if p.shared {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "provider shares its connections; configure the provider it was derived from")
+	}
+	previous := p.currentConfig()
+	if normalizeDriver(config.Driver) != normalizeDriver(previous.Driver) {
+		return fmt.Errorf("cannot change driver from %s to %s on a live provider", previous.Driver, config.Driver)
+	}
+	gormOpts := gormOptions(config)
+
+	if p.connector != nil {
+		oldDSN, _ := connectionDSN(previous)
+		newDSN, _ := connectionDSN(config)
+		if newDSN != oldDSN {
+			if err := p.rotateConnector(config); err != nil {
+				return err
+			}
+		}
+	}
+	if err := configurePool(p.db, config); err != nil {
+		return err
+	}
+	if err := p.reloadReplicas(config, gormOpts); err != nil {
+		return err
+	}
+	if p.logger != nil {
+		p.logger.set(logLevelOption(gormOpts))
+	}
+	p.setConfig(config)
+	return nil
+}
+
+// setConfig replaces the stored config
+func (p *Provider) setConfig(config gpa.Config) {
+	p.mu.Lock()
+	p.config = config
+	p.mu.Unlock()
+}
+
+// currentConfig returns the stored config
+func (p *Provider) currentConfig() gpa.Config {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config
+}
+
+// rotateConnector verifies the connection settings of config and switches
+// new primary connections to them
+func (p *Provider) rotateConnector(config gpa.Config) error {
+	connector, _, err := openConnector(config)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHealthCheckTimeout)
+	defer cancel()
+	conn, err := connector.Connect(ctx)
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeConnection, "failed to connect with the new settings", err)
+	}
+	conn.Close()
+	p.connector.set(connector)
+
+	// Closing the idle connections makes the pool reconnect with the new
+	// settings
+	sqlDB, err := p.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	}
+	idle := config.MaxIdleConns
+	if idle <= 0 {
+		idle = 2 // database/sql's default
+	}
+	sqlDB.SetMaxIdleConns(0)
+	sqlDB.SetMaxIdleConns(idle)
+	return nil
+}
+
+// reloadReplicas opens the replicas config lists that are not open yet,
+// closes the ones it no longer lists and applies the pool limits to all
+func (p *Provider) reloadReplicas(config gpa.Config, gormOpts map[string]interface{}) error {
+	dsns, _ := gormOpts["replicas"].([]string)
+
+	p.replicaMu.RLock()
+	current := slices.Clone(p.replicaDSNs)
+	p.replicaMu.RUnlock()
+
+	opened := map[string]*gorm.DB{}
+	for _, dsn := range dsns {
+		if slices.Contains(current, dsn) || opened[dsn] != nil {
+			continue
+		}
+		replica, err := p.openReplica(config, dsn, p.db.Config)
+		if err != nil {
+			for _, db := range opened {
+				closeDB(db)
+			}
+			return err
+		}
+		opened[dsn] = replica
+	}
+
+	p.replicaMu.Lock()
+	var replicas []*gorm.DB
+	var kept []string
+	var removed []*gorm.DB
+	for i, dsn := range p.replicaDSNs {
+		if slices.Contains(dsns, dsn) {
+			replicas = append(replicas, p.replicas[i])
+			kept = append(kept, dsn)
+		} else {
+			removed = append(removed, p.replicas[i])
+		}
+	}
+	for _, dsn := range dsns {
+		if replica := opened[dsn]; replica != nil {
+			replicas = append(replicas, replica)
+			kept = append(kept, dsn)
+			delete(opened, dsn)
+		}
+	}
+	p.replicas, p.replicaDSNs = replicas, kept
+	p.replicaReadPool = nil
+	p.replicaMu.Unlock()
+
+	// Closing waits for the statements already running on the replicas
+	for _, replica := range removed {
+		closeDB(replica)
+	}
+	for _, replica := range replicas {
+		if err := configurePool(replica, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openReplica connects to the replica at dsn with the primary's settings
+func (p *Provider) openReplica(config gpa.Config, dsn string, gormConfig *gorm.Config) (*gorm.DB, error) {
+	dialector, err := openDialector(config, dsn)
+	if err != nil {
+		return nil, err
+	}
+	replica, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to replica: %w", err)
+	}
+	if err := configurePool(replica, config); err != nil {
+		closeDB(replica)
+		return nil, err
+	}
+	if err := p.totals.register(replica); err != nil {
+		closeDB(replica)
+		return nil, err
+	}
+	if p.queryStats != nil {
+		if err := p.queryStats.register(replica); err != nil {
+			closeDB(replica)
+			return nil, err
+		}
+	}
+	return replica, nil
+}
+
+// replicaDBs returns the open replicas
+func (p *Provider) replicaDBs() []*gorm.DB {
+	p.replicaMu.RLock()
+	defer p.replicaMu.RUnlock()
+	return slices.Clone(p.replicas)
+}
+
+// closeDB closes the connection pool of db
+func closeDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// normalizeDriver maps driver aliases to one name
+func normalizeDriver(name string) string {
+	switch name = strings.ToLower(name); name {
+	case "postgresql":
+		return "postgres"
+	case "sqlite3":
+		return "sqlite"
+	case "mssql":
+		return "sqlserver"
+	}
+	return name
+}
+
+// connectionDSN returns the DSN the primary connects with
+func connectionDSN(config gpa.Config) (string, error) {
+	switch normalizeDriver(config.Driver) {
+	case "postgres":
+		return buildPostgresDSN(config), nil
+	case "mysql":
+		return buildMySQLDSN(config), nil
+	case "sqlite":
+		return config.Database, nil
+	case "sqlserver":
+		return buildSQLServerDSN(config), nil
+	}
+	return "", fmt.Errorf("unsupported driver: %s", config.Driver)
+}
+
+// openConnector returns the connector for the primary of config, running
+// the "session_init" statements on new connections, and its DSN
+func openConnector(config gpa.Config) (driver.Connector, string, error) {
+	dsn, err := connectionDSN(config)
+	if err != nil {
+		return nil, "", err
+	}
+	if dsn, err = withConnectionLabels(config, dsn); err != nil {
+		return nil, "", err
+	}
+	sessionInit, err := sessionInitStatements(config)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var connector driver.Connector
+	switch normalizeDriver(config.Driver) {
+	case "postgres":
+		connector, err = postgresConnector(config, dsn)
+	case "mysql":
+		if sessionInit, err = mysqlSessionInit(config, sessionInit); err != nil {
+			return nil, "", err
+		}
+		connector, err = driverConnector("mysql", dsn)
+	case "sqlite":
+		registerSQLiteBuiltins()
+		connector, err = driverConnector(sqlite.DriverName, dsn)
+	case "sqlserver":
+		connector, err = driverConnector("sqlserver", dsn)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if len(sessionInit) > 0 {
+		connector = &sessionInitConnector{Connector: connector, statements: sessionInit}
+	}
+	return connector, dsn, nil
+}
+
+// openPrimary opens the dialector of the primary over a connector whose
+// settings Configure can replace
+func openPrimary(config gpa.Config) (gorm.Dialector, *reloadableConnector, error) {
+	connector, dsn, err := openConnector(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	reloadable := &reloadableConnector{}
+	reloadable.set(connector)
+	conn := sql.OpenDB(reloadable)
+
+	switch normalizeDriver(config.Driver) {
+	case "postgres":
+		return postgres.New(postgres.Config{Conn: conn}), reloadable, nil
+	case "mysql":
+		return mysql.New(mysql.Config{DSN: dsn, Conn: conn}), reloadable, nil
+	case "sqlite":
+		return &sqlite.Dialector{DSN: dsn, Conn: conn}, reloadable, nil
+	default:
+		return sqlserver.New(sqlserver.Config{DSN: dsn, Conn: conn}), reloadable, nil
+	}
+}
+
+// reloadableConnector opens connections with the connector last set
+type reloadableConnector struct {
+	current atomic.Pointer[driver.Connector]
+}
+
+// set replaces the connector new connections are opened with
+func (c *reloadableConnector) set(connector driver.Connector) {
+	c.current.Store(&connector)
+}
+
+// Connect implements driver.Connector
+func (c *reloadableConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return (*c.current.Load()).Connect(ctx)
+}
+
+// Driver implements driver.Connector
+func (c *reloadableConnector) Driver() driver.Driver {
+	return (*c.current.Load()).Driver()
+}
+
+// logLevelOption returns the "log_level" option, info by default
+func logLevelOption(gormOpts map[string]interface{}) logger.LogLevel {
+	switch gormOpts["log_level"] {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "warn":
+		return logger.Warn
+	}
+	return logger.Info
+}
+
+// reloadableLogger is a GORM logger whose level Configure can change
+type reloadableLogger struct {
+	current atomic.Pointer[logger.Interface]
+}
+
+// newReloadableLogger returns the default GORM logger at level
+func newReloadableLogger(level logger.LogLevel) *reloadableLogger {
+	l := &reloadableLogger{}
+	l.set(level)
+	return l
+}
+
+// set changes the level of subsequent log calls
+func (l *reloadableLogger) set(level logger.LogLevel) {
+	// The settings of logger.Default, whose writer cannot be replaced
+	current := logger.New(callerWriter{log.New(os.Stdout, "\r\n", log.LstdFlags)}, logger.Config{
+		SlowThreshold: 200 * time.Millisecond,
+		LogLevel:      level,
+		Colorful:      true,
+	})
+	l.current.Store(&current)
+}
+
+func (l *reloadableLogger) load() logger.Interface {
+	return *l.current.Load()
+}
+
+// LogMode implements logger.Interface; the returned logger no longer
+// follows reloads
+func (l *reloadableLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return l.load().LogMode(level)
+}
+
+// Info implements logger.Interface
+func (l *reloadableLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.load().Info(ctx, msg, args...)
+}
+
+// Warn implements logger.Interface
+func (l *reloadableLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.load().Warn(ctx, msg, args...)
+}
+
+// Error implements logger.Interface
+func (l *reloadableLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.load().Error(ctx, msg, args...)
+}
+
+// Trace implements logger.Interface
+func (l *reloadableLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.load().Trace(ctx, begin, fc, err)
+}
+
+// reloadSourceFile is the path of this file, which GORM reports as the
+// caller of every logged statement since reloadableLogger is not in GORM
+var reloadSourceFile = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return file
+}()
+
+// callerWriter replaces the caller GORM logs with the first frame outside
+// GORM and this file
+type callerWriter struct {
+	logger.Writer
+}
+
+// Printf implements logger.Writer
+func (w callerWriter) Printf(format string, args ...interface{}) {
+	if len(args) > 0 {
+		if file, ok := args[0].(string); ok && strings.HasPrefix(file, reloadSourceFile+":") {
+			args[0] = callerFile()
+		}
+	}
+	w.Writer.Printf(format, args...)
+}
+
+// callerFile returns the file and line of the first caller outside GORM
+// and this file
+func callerFile() string {
+	pcs := [32]uintptr{}
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if frame.File != reloadSourceFile && !strings.HasPrefix(frame.Function, "gorm.io/") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
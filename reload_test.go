@@ -0,0 +1,135 @@
+package gpagorm
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm/logger"
+)
+
+func TestConfigureReload(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.db"), filepath.Join(dir, "second.db")
+	replicaA, replicaB := filepath.Join(dir, "replica_a.db"), filepath.Join(dir, "replica_b.db")
+
+	// Give the second database a table the first one lacks
+	db, err := sql.Open(sqlite.DriverName, second)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE only_second (id INTEGER)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	db.Close()
+
+	config := gpa.Config{
+		Driver:       "sqlite",
+		Database:     first,
+		MaxOpenConns: 1,
+		Options: map[string]interface{}{"gorm": map[string]interface{}{
+			"log_level": "info",
+			"replicas":  []string{replicaA},
+		}},
+	}
+	provider, err := NewProvider(config)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	defer provider.Close()
+	if err := provider.db.Exec("SELECT 1").Error; err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	oldReplica := provider.replicaDBs()[0]
+
+	config.Database = second
+	config.MaxOpenConns = 4
+	config.Options = map[string]interface{}{"gorm": map[string]interface{}{
+		"log_level": "silent",
+		"replicas":  []string{replicaB},
+	}}
+	if err := provider.Configure(config); err != nil {
+		t.Fatalf("Failed to reload configuration: %v", err)
+	}
+
+	if err := provider.db.Exec("SELECT * FROM only_second").Error; err != nil {
+		t.Errorf("Expected new connections to use the second database: %v", err)
+	}
+	sqlDB, _ := provider.db.DB()
+	if max := sqlDB.Stats().MaxOpenConnections; max != 4 {
+		t.Errorf("Expected the pool limit to be raised to 4, got %d", max)
+	}
+	level := reflect.ValueOf(provider.logger.load()).Elem().FieldByName("LogLevel").Int()
+	if logger.LogLevel(level) != logger.Silent {
+		t.Errorf("Expected the silent log level, got %d", level)
+	}
+	if replicas := provider.replicaDBs(); len(replicas) != 1 || provider.replicaDSNs[0] != replicaB {
+		t.Errorf("Expected only the new replica, got %v", provider.replicaDSNs)
+	}
+	oldSQLDB, _ := oldReplica.DB()
+	if err := oldSQLDB.Ping(); err == nil {
+		t.Error("Expected the removed replica to be closed")
+	}
+	if provider.currentConfig().Database != second {
+		t.Error("Expected the new configuration to be stored")
+	}
+}
+
+func TestConfigureReloadErrors(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	config := provider.currentConfig()
+	config.Driver = "postgres"
+	if err := provider.Configure(config); err == nil {
+		t.Error("Expected changing the driver to fail")
+	}
+
+	config = provider.currentConfig()
+	config.Database = filepath.Join(t.TempDir(), "missing", "app.db")
+	if err := provider.Configure(config); !gpa.IsErrorType(err, gpa.ErrorTypeConnection) {
+		t.Errorf("Expected a connection error for unusable settings, got %v", err)
+	}
+	if provider.currentConfig().Database != ":memory:" {
+		t.Error("Expected the previous configuration to be kept")
+	}
+	var count int64
+	if err := provider.db.Model(&TestUser{}).Count(&count).Error; err != nil {
+		t.Errorf("Expected the provider to keep working: %v", err)
+	}
+}
+
+func TestConfigureConcurrentOptionReads(t *testing.T) {
+	config := gpa.Config{Driver: "sqlite", Database: ":memory:"}
+	provider, err := NewProvider(config)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	defer provider.Close()
+
+	// Run with -race: option readers must not race Configure
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			provider.replicaReads()
+			provider.safeMigrations()
+			provider.serverSideCursors()
+			provider.sweepChunkSize()
+			provider.transactionPanicsAsErrors()
+			provider.deadlineMargin()
+			provider.lockTimeout(context.Background())
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		config.Options = map[string]interface{}{"gorm": map[string]interface{}{"replica_reads": i%2 == 0}}
+		if err := provider.Configure(config); err != nil {
+			t.Fatalf("Failed to reload configuration: %v", err)
+		}
+	}
+	<-done
+}
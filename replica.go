@@ -0,0 +1,169 @@
+// Package gpagorm provides replica reads with statement-level failover
+package gpagorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// replicaPool routes read statements across the replicas. A statement
+// that fails because its connection went away is retried once on the next
+// replica, or the primary when there is only one, while ctx allows.
+type replicaPool struct {
+	primary  gorm.ConnPool
+	replicas []gorm.ConnPool
+	next     atomic.Uint64
+}
+
+// replicaReads reports whether the "replica_reads" option is set
+func (p *Provider) replicaReads() bool {
+	enabled, _ := gormOptions(p.currentConfig())["replica_reads"].(bool)
+	return enabled
+}
+
+// readPool returns the pool reads are routed through, or nil when reads
+// stay on the primary
+func (p *Provider) readPool() *replicaPool {
+	if !p.replicaReads() {
+		return nil
+	}
+	p.replicaMu.RLock()
+	pool, empty := p.replicaReadPool, len(p.replicas) == 0
+	p.replicaMu.RUnlock()
+	if pool != nil || empty {
+		return pool
+	}
+
+	p.replicaMu.Lock()
+	defer p.replicaMu.Unlock()
+	if p.replicaReadPool == nil && len(p.replicas) > 0 {
+		pool := &replicaPool{primary: p.db.ConnPool}
+		for _, replica := range p.replicas {
+			pool.replicas = append(pool.replicas, replica.ConnPool)
+		}
+		p.replicaReadPool = pool
+	}
+	return p.replicaReadPool
+}
+
+// replicaSession returns the read session routed to the replicas when
+// replica reads are enabled, or to the read model bound with BindReads.
+// Reads inside a transaction stay on it.
+func (r *Repository[T]) replicaSession(ctx context.Context) *gorm.DB {
+	if db, ok := r.boundReadSession(ctx); ok {
+		return db
+	}
+	db := r.readSession(ctx)
+	if r.provider == nil || inTransaction(db) {
+		return db
+	}
+	if pool := r.provider.readPool(); pool != nil {
+		db.Statement.ConnPool = pool
+	}
+	return db
+}
+
+// targets returns the connection to try first and its fallback
+func (p *replicaPool) targets() (gorm.ConnPool, gorm.ConnPool) {
+	n := p.next.Add(1) - 1
+	first := p.replicas[n%uint64(len(p.replicas))]
+	if len(p.replicas) == 1 {
+		return first, p.primary
+	}
+	return first, p.replicas[(n+1)%uint64(len(p.replicas))]
+}
+
+// retry reports whether a failed read should be attempted again
+func (p *replicaPool) retry(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || !isConnectionLost(err) {
+		return false
+	}
+	slog.Default().LogAttrs(context.Background(), slog.LevelWarn, "gpagorm: retrying read after replica connection failure",
+		slog.String("error", err.Error()))
+	return true
+}
+
+// PrepareContext implements gorm.ConnPool
+func (p *replicaPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	first, fallback := p.targets()
+	stmt, err := first.PrepareContext(ctx, query)
+	if p.retry(ctx, err) {
+		return fallback.PrepareContext(ctx, query)
+	}
+	return stmt, err
+}
+
+// ExecContext implements gorm.ConnPool. Writes always go to the primary.
+func (p *replicaPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.primary.ExecContext(ctx, query, args...)
+}
+
+// QueryContext implements gorm.ConnPool
+func (p *replicaPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	first, fallback := p.targets()
+	rows, err := first.QueryContext(ctx, query, args...)
+	if p.retry(ctx, err) {
+		return fallback.QueryContext(ctx, query, args...)
+	}
+	return rows, err
+}
+
+// QueryRowContext implements gorm.ConnPool
+func (p *replicaPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	first, fallback := p.targets()
+	row := first.QueryRowContext(ctx, query, args...)
+	if p.retry(ctx, row.Err()) {
+		return fallback.QueryRowContext(ctx, query, args...)
+	}
+	return row
+}
+
+// BeginTx implements gorm.ConnPoolBeginner so read transactions, such as
+// server-side cursors, run on a replica
+func (p *replicaPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	first, fallback := p.targets()
+	tx, err := beginOn(ctx, first, opts)
+	if p.retry(ctx, err) {
+		return beginOn(ctx, fallback, opts)
+	}
+	return tx, err
+}
+
+// beginOn starts a transaction on pool
+func beginOn(ctx context.Context, pool gorm.ConnPool, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	switch beginner := pool.(type) {
+	case gorm.TxBeginner:
+		return beginner.BeginTx(ctx, opts)
+	case gorm.ConnPoolBeginner:
+		return beginner.BeginTx(ctx, opts)
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+}
+
+// isConnectionLost reports whether err means the connection was reset or
+// the server went away, so the statement never ran to completion
+func isConnectionLost(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysqldriver.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	// database/sql does not export its closed-pool error
+	return strings.Contains(err.Error(), "sql: database is closed")
+}
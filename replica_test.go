@@ -0,0 +1,132 @@
+package gpagorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+func setupReplicaProvider(t *testing.T) *Provider {
+	config := gpa.Config{
+		Driver:   "sqlite",
+		Database: ":memory:",
+		Options: map[string]interface{}{
+			"gorm": map[string]interface{}{
+				"replicas":      []string{":memory:", ":memory:"},
+				"replica_reads": true,
+				"log_level":     "silent",
+			},
+		},
+	}
+	provider, err := NewProvider(config)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	for i, db := range append([]*gorm.DB{provider.db}, provider.replicas...) {
+		sqlDB, _ := db.DB()
+		sqlDB.SetMaxOpenConns(1)
+		if err := db.AutoMigrate(&TestUser{}); err != nil {
+			t.Fatalf("Failed to migrate database %d: %v", i, err)
+		}
+		name := "primary"
+		if i > 0 {
+			name = fmt.Sprintf("replica%d", i-1)
+		}
+		if err := db.Create(&TestUser{ID: 1, Name: name, Email: name + "@example.com"}).Error; err != nil {
+			t.Fatalf("Failed to seed database %d: %v", i, err)
+		}
+	}
+	return provider
+}
+
+func TestReplicaReads(t *testing.T) {
+	provider := setupReplicaProvider(t)
+	defer provider.Close()
+	ctx := context.Background()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		user, err := repo.FindByID(ctx, 1)
+		if err != nil {
+			t.Fatalf("Failed to read user: %v", err)
+		}
+		seen[user.Name] = true
+	}
+	if !seen["replica0"] || !seen["replica1"] || seen["primary"] {
+		t.Errorf("Expected reads to alternate between replicas, got %v", seen)
+	}
+
+	if err := repo.Create(ctx, &TestUser{Name: "written", Email: "written@example.com"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	var count int64
+	provider.db.Model(&TestUser{}).Where("name = ?", "written").Count(&count)
+	if count != 1 {
+		t.Errorf("Expected writes to go to the primary")
+	}
+
+	err := repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		user, err := tx.FindByID(ctx, 1)
+		if err != nil {
+			return err
+		}
+		if user.Name != "primary" {
+			t.Errorf("Expected reads in a transaction to use the primary, got %s", user.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+}
+
+func TestReplicaReadFailover(t *testing.T) {
+	provider := setupReplicaProvider(t)
+	defer provider.Close()
+	ctx := context.Background()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	// Take the first replica away
+	sqlDB, _ := provider.replicas[0].DB()
+	sqlDB.Close()
+
+	for i := 0; i < 4; i++ {
+		user, err := repo.FindByID(ctx, 1)
+		if err != nil {
+			t.Fatalf("Expected the read to fail over, got %v", err)
+		}
+		if user.Name != "replica1" {
+			t.Errorf("Expected the surviving replica to answer, got %s", user.Name)
+		}
+	}
+	users, err := repo.FindAll(ctx)
+	if err != nil || len(users) != 1 {
+		t.Errorf("Expected FindAll to fail over, got %d %v", len(users), err)
+	}
+
+	// A cancelled read is not retried
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := repo.FindByID(cancelled, 1); err == nil {
+		t.Error("Expected a cancelled read to fail")
+	}
+}
+
+func TestIsConnectionLost(t *testing.T) {
+	lost := []error{driver.ErrBadConn, io.ErrUnexpectedEOF, fmt.Errorf("read: %w", io.EOF)}
+	for _, err := range lost {
+		if !isConnectionLost(err) {
+			t.Errorf("Expected %v to count as a lost connection", err)
+		}
+	}
+	if isConnectionLost(errors.New("no such table: users")) {
+		t.Error("Expected query errors not to be retried")
+	}
+}
@@ -34,26 +34,46 @@ func (r *SQLResult) LastInsertId() (int64, error) {
 type Repository[T any] struct {
 	db       *gorm.DB
 	provider *Provider
+	table    string // Qualified table override, see WithTable
+	view     string // View used for reads, see WithView
+	readOnly bool   // Reject writes, set for views without a write table
+	comment  string // SQL comment on every statement, see WithComment
+
+	strictFields bool // Only accept fields of T, see WithStrictFields
+
+	changes *changeSet // Changes of the enclosing transaction, see trackChanges
 }
 
-// convertGormError converts GORM errors to GPA errors
+// convertGormError converts GORM errors to GPA errors. Statement errors
+// keep their OperationError as the cause, see OperationContext.
 func convertGormError(err error) error {
 	if err == nil {
 		return nil
 	}
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return gpa.NewError(gpa.ErrorTypeNotFound, "record not found")
+		return withOperationCause(gpa.NewError(gpa.ErrorTypeNotFound, "record not found"), err)
 	}
 	if errors.Is(err, gorm.ErrDuplicatedKey) {
-		return gpa.NewError(gpa.ErrorTypeDuplicate, "duplicate key")
+		return withOperationCause(gpa.NewError(gpa.ErrorTypeDuplicate, "duplicate key"), err)
 	}
 	// If it's already a GPA error, return it as is
 	if gpaErr, ok := err.(gpa.GPAError); ok {
 		return gpaErr
 	}
+	if isCancellation(err) {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeTimeout, "operation cancelled", err)
+	}
 	return gpa.NewErrorWithCause(gpa.ErrorTypeDatabase, "database error", err)
 }
 
+// withOperationCause sets the OperationError of err as the cause of gpaErr
+func withOperationCause(gpaErr gpa.GPAError, err error) gpa.GPAError {
+	if opErr, ok := OperationContext(err); ok {
+		gpaErr.Cause = opErr
+	}
+	return gpaErr
+}
+
 // NewRepository creates a new generic GORM repository for type T.
 // Example: userRepo := NewRepository[User](db, provider)
 func NewRepository[T any](db *gorm.DB, provider *Provider) *Repository[T] {
@@ -63,12 +83,39 @@ func NewRepository[T any](db *gorm.DB, provider *Provider) *Repository[T] {
 	}
 }
 
+// clone returns a copy of the repository sharing its session, for the
+// With* methods and transaction repositories to adjust
+func (r *Repository[T]) clone() *Repository[T] {
+	repo := *r
+	return &repo
+}
+
 // =====================================
 // RepositoryG[T] Implementation
 // =====================================
 
 // Create inserts a new entity with compile-time type safety.
 func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationCreate, Entity: entity}, func(ctx context.Context) error {
+		return r.create(ctx, entity)
+	})
+}
+
+// create implements Create
+func (r *Repository[T]) create(ctx context.Context, entity *T) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	if err := r.canonicalize(ctx, entity); err != nil {
+		return err
+	}
+	if err := r.authorizeCreate(ctx, entity); err != nil {
+		return err
+	}
+	if err := r.checkAllowedValues(ctx, entity); err != nil {
+		return err
+	}
+
 	// Execute validation hook
 	if hook, ok := any(entity).(gpa.ValidationHook); ok {
 		if err := hook.Validate(ctx); err != nil {
@@ -83,10 +130,11 @@ func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
 		}
 	}
 
-	result := r.db.WithContext(ctx).Create(entity)
+	result := withReturning(ctx, r.session(ctx)).Create(entity)
 	if result.Error != nil {
 		return convertGormError(result.Error)
 	}
+	r.recordEntityChange(ctx, ChangeCreated, entity)
 
 	// Execute after create hook
 	if hook, ok := any(entity).(gpa.AfterCreateHook); ok {
@@ -101,46 +149,35 @@ func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
 
 // CreateBatch inserts multiple entities with compile-time type safety.
 func (r *Repository[T]) CreateBatch(ctx context.Context, entities []*T) error {
-	// Execute validation hooks for all entities
-	for _, entity := range entities {
-		if hook, ok := any(entity).(gpa.ValidationHook); ok {
-			if err := hook.Validate(ctx); err != nil {
-				return gpa.NewErrorWithCause(gpa.ErrorTypeValidation, "validation failed", err)
-			}
-		}
-	}
+	return r.intercept(ctx, OperationInfo{Operation: OperationCreateBatch, Entity: entities}, func(ctx context.Context) error {
+		return r.createBatch(ctx, entities)
+	})
+}
 
-	// Execute before create hooks for all entities
-	for _, entity := range entities {
-		if hook, ok := any(entity).(gpa.BeforeCreateHook); ok {
-			if err := hook.BeforeCreate(ctx); err != nil {
-				return gpa.NewErrorWithCause(gpa.ErrorTypeValidation, "before create hook failed", err)
-			}
-		}
-	}
+// createBatch implements CreateBatch
+func (r *Repository[T]) createBatch(ctx context.Context, entities []*T) error {
+	_, err := r.createBatchResult(ctx, entities)
+	return err
+}
 
-	result := r.db.WithContext(ctx).CreateInBatches(entities, 100)
-	if result.Error != nil {
-		return convertGormError(result.Error)
-	}
+// FindByID retrieves a single entity by ID with compile-time type safety.
+func (r *Repository[T]) FindByID(ctx context.Context, id interface{}) (entity *T, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationFindByID, ID: id}, func(ctx context.Context) error {
+		var err error
+		entity, err = r.findByID(ctx, id)
+		return err
+	})
+	return entity, err
+}
 
-	// Execute after create hooks for all entities
-	for _, entity := range entities {
-		if hook, ok := any(entity).(gpa.AfterCreateHook); ok {
-			if err := hook.AfterCreate(ctx); err != nil {
-				// Log error but don't fail the operation
-				LogAfterCreateError(ctx, entity, err)
-			}
-		}
+// findByID implements FindByID
+func (r *Repository[T]) findByID(ctx context.Context, id interface{}) (*T, error) {
+	if err := r.authorizeRead(ctx, []gpa.QueryOption{gpa.Where("id", gpa.OpEqual, id)}); err != nil {
+		return nil, err
 	}
 
-	return nil
-}
-
-// FindByID retrieves a single entity by ID with compile-time type safety.
-func (r *Repository[T]) FindByID(ctx context.Context, id interface{}) (*T, error) {
 	var entity T
-	result := r.db.WithContext(ctx).First(&entity, id)
+	result := r.replicaSession(ctx).First(&entity, id)
 	if err := convertGormError(result.Error); err != nil {
 		return nil, err
 	}
@@ -157,10 +194,24 @@ func (r *Repository[T]) FindByID(ctx context.Context, id interface{}) (*T, error
 }
 
 // FindAll retrieves all entities with compile-time type safety.
-func (r *Repository[T]) FindAll(ctx context.Context, opts ...gpa.QueryOption) ([]*T, error) {
-	query := r.buildQuery(opts...)
+func (r *Repository[T]) FindAll(ctx context.Context, opts ...gpa.QueryOption) (entities []*T, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationFindAll, Options: opts}, func(ctx context.Context) error {
+		var err error
+		entities, err = r.findAll(ctx, opts...)
+		return err
+	})
+	return entities, err
+}
+
+// findAll implements FindAll
+func (r *Repository[T]) findAll(ctx context.Context, opts ...gpa.QueryOption) ([]*T, error) {
+	if err := r.authorizeRead(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	query := r.buildQuery(ctx, opts...)
 	var entities []*T
-	result := query.WithContext(ctx).Find(&entities)
+	result := query.Find(&entities)
 	if err := convertGormError(result.Error); err != nil {
 		return nil, err
 	}
@@ -169,6 +220,26 @@ func (r *Repository[T]) FindAll(ctx context.Context, opts ...gpa.QueryOption) ([
 
 // Update modifies an existing entity with compile-time type safety.
 func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationUpdate, Entity: entity}, func(ctx context.Context) error {
+		return r.update(ctx, entity)
+	})
+}
+
+// update implements Update
+func (r *Repository[T]) update(ctx context.Context, entity *T) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	if err := r.canonicalize(ctx, entity); err != nil {
+		return err
+	}
+	if err := r.authorizeUpdate(ctx, entity); err != nil {
+		return err
+	}
+	if err := r.checkAllowedValues(ctx, entity); err != nil {
+		return err
+	}
+
 	// Execute validation hook
 	if hook, ok := any(entity).(gpa.ValidationHook); ok {
 		if err := hook.Validate(ctx); err != nil {
@@ -183,10 +254,11 @@ func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
 		}
 	}
 
-	result := r.db.WithContext(ctx).Save(entity)
+	result := r.session(ctx).Save(entity)
 	if result.Error != nil {
 		return convertGormError(result.Error)
 	}
+	r.recordEntityChange(ctx, ChangeUpdated, entity)
 
 	// Execute after update hook
 	if hook, ok := any(entity).(gpa.AfterUpdateHook); ok {
@@ -201,8 +273,37 @@ func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
 
 // UpdatePartial modifies specific fields of an entity.
 func (r *Repository[T]) UpdatePartial(ctx context.Context, id interface{}, updates map[string]interface{}) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationUpdatePartial, ID: id, Entity: updates}, func(ctx context.Context) error {
+		return r.updatePartial(ctx, id, updates)
+	})
+}
+
+// updatePartial implements UpdatePartial
+func (r *Repository[T]) updatePartial(ctx context.Context, id interface{}, updates map[string]interface{}) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	updates, err := r.canonicalizeUpdates(updates)
+	if err != nil {
+		return err
+	}
+	if err := r.checkAllowedUpdates(updates); err != nil {
+		return err
+	}
 	var entity T
-	result := r.db.WithContext(ctx).Model(&entity).Where("id = ?", id).Updates(updates)
+
+	// Load the stored entity so the policy can inspect it
+	if r.policy() != nil {
+		var current T
+		if err := r.readSession(ctx).First(&current, id).Error; err != nil {
+			return convertGormError(err)
+		}
+		if err := r.authorizeUpdate(ctx, &current); err != nil {
+			return err
+		}
+	}
+
+	result := r.session(ctx).Model(&entity).Where("id = ?", id).Updates(updates)
 	if result.Error != nil {
 		return convertGormError(result.Error)
 	}
@@ -212,19 +313,34 @@ func (r *Repository[T]) UpdatePartial(ctx context.Context, id interface{}, updat
 			Message: "entity not found",
 		}
 	}
+	r.recordChange(ctx, ChangeUpdated, []interface{}{id})
 	return nil
 }
 
 // Delete removes an entity by ID with compile-time type safety.
 func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationDelete, ID: id}, func(ctx context.Context) error {
+		return r.delete(ctx, id)
+	})
+}
+
+// delete implements Delete
+func (r *Repository[T]) delete(ctx context.Context, id interface{}) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
 	var entity T
 
 	// First, fetch the entity to run hooks on it
-	result := r.db.WithContext(ctx).First(&entity, id)
+	result := r.readSession(ctx).First(&entity, id)
 	if result.Error != nil {
 		return convertGormError(result.Error)
 	}
 
+	if err := r.authorizeDelete(ctx, &entity); err != nil {
+		return err
+	}
+
 	// Execute before delete hook
 	if hook, ok := any(&entity).(gpa.BeforeDeleteHook); ok {
 		if err := hook.BeforeDelete(ctx); err != nil {
@@ -232,7 +348,7 @@ func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
 		}
 	}
 
-	result = r.db.WithContext(ctx).Delete(&entity, id)
+	result = r.session(ctx).Delete(&entity, id)
 	if result.Error != nil {
 		return convertGormError(result.Error)
 	}
@@ -242,6 +358,7 @@ func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
 			Message: "entity not found",
 		}
 	}
+	r.recordChange(ctx, ChangeDeleted, []interface{}{id})
 
 	// Execute after delete hook
 	if hook, ok := any(&entity).(gpa.AfterDeleteHook); ok {
@@ -256,18 +373,72 @@ func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
 
 // DeleteByCondition removes entities matching a condition.
 func (r *Repository[T]) DeleteByCondition(ctx context.Context, condition gpa.Condition) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationDeleteByCondition, Condition: condition}, func(ctx context.Context) error {
+		return r.deleteByCondition(ctx, condition)
+	})
+}
+
+// deleteByCondition implements DeleteByCondition
+func (r *Repository[T]) deleteByCondition(ctx context.Context, condition gpa.Condition) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
 	var entity T
-	query := r.db.WithContext(ctx).Model(&entity)
+
+	// Check every matching entity against the policy before deleting
+	if r.policy() != nil {
+		var matches []*T
+		result := r.applyCondition(r.readSession(ctx).Model(&entity), condition).Find(&matches)
+		if result.Error != nil {
+			return convertGormError(result.Error)
+		}
+		for _, match := range matches {
+			if err := r.authorizeDelete(ctx, match); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Listeners are told the keys of the rows matching when the delete starts
+	var ids []interface{}
+	if r.changeSet(ctx) != nil {
+		if s, err := r.schema(); err == nil && s.PrioritizedPrimaryField != nil {
+			query := r.applyCondition(r.session(ctx).Model(&entity), condition)
+			if ids, err = pluckPrimaryKeys(query, s.PrioritizedPrimaryField); err != nil {
+				return convertGormError(err)
+			}
+		}
+	}
+
+	query := r.session(ctx).Model(&entity)
 	query = r.applyCondition(query, condition)
 	result := query.Delete(&entity)
-	return convertGormError(result.Error)
+	if result.Error != nil {
+		return convertGormError(result.Error)
+	}
+	r.recordChange(ctx, ChangeDeleted, ids)
+	return nil
 }
 
 // Query retrieves entities based on query options with compile-time type safety.
-func (r *Repository[T]) Query(ctx context.Context, opts ...gpa.QueryOption) ([]*T, error) {
-	query := r.buildQuery(opts...)
+func (r *Repository[T]) Query(ctx context.Context, opts ...gpa.QueryOption) (entities []*T, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationQuery, Options: opts}, func(ctx context.Context) error {
+		var err error
+		entities, err = r.query(ctx, opts...)
+		return err
+	})
+	return entities, err
+}
+
+// query implements Query
+func (r *Repository[T]) query(ctx context.Context, opts ...gpa.QueryOption) ([]*T, error) {
+	if err := r.authorizeRead(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	query := r.buildQuery(ctx, opts...)
 	var entities []*T
-	result := query.WithContext(ctx).Find(&entities)
+	result := query.Find(&entities)
 	if err := convertGormError(result.Error); err != nil {
 		return nil, err
 	}
@@ -275,10 +446,24 @@ func (r *Repository[T]) Query(ctx context.Context, opts ...gpa.QueryOption) ([]*
 }
 
 // QueryOne retrieves a single entity based on query options.
-func (r *Repository[T]) QueryOne(ctx context.Context, opts ...gpa.QueryOption) (*T, error) {
-	query := r.buildQuery(opts...)
+func (r *Repository[T]) QueryOne(ctx context.Context, opts ...gpa.QueryOption) (entity *T, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationQueryOne, Options: opts}, func(ctx context.Context) error {
+		var err error
+		entity, err = r.queryOne(ctx, opts...)
+		return err
+	})
+	return entity, err
+}
+
+// queryOne implements QueryOne
+func (r *Repository[T]) queryOne(ctx context.Context, opts ...gpa.QueryOption) (*T, error) {
+	if err := r.authorizeRead(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	query := r.buildQuery(ctx, opts...)
 	var entity T
-	result := query.WithContext(ctx).First(&entity)
+	result := query.First(&entity)
 	if err := convertGormError(result.Error); err != nil {
 		return nil, err
 	}
@@ -286,37 +471,81 @@ func (r *Repository[T]) QueryOne(ctx context.Context, opts ...gpa.QueryOption) (
 }
 
 // Count returns the number of entities matching query options.
-func (r *Repository[T]) Count(ctx context.Context, opts ...gpa.QueryOption) (int64, error) {
-	query := r.buildQuery(opts...)
+func (r *Repository[T]) Count(ctx context.Context, opts ...gpa.QueryOption) (count int64, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationCount, Options: opts}, func(ctx context.Context) error {
+		var err error
+		count, err = r.count(ctx, opts...)
+		return err
+	})
+	return count, err
+}
+
+// count implements Count
+func (r *Repository[T]) count(ctx context.Context, opts ...gpa.QueryOption) (int64, error) {
+	if err := r.authorizeRead(ctx, opts); err != nil {
+		return 0, err
+	}
+
+	query := r.buildQuery(ctx, opts...)
 	var count int64
 	var entity T
-	result := query.WithContext(ctx).Model(&entity).Count(&count)
+	result := query.Model(&entity).Count(&count)
 	return count, convertGormError(result.Error)
 }
 
 // Exists checks if any entity matches the query options.
-func (r *Repository[T]) Exists(ctx context.Context, opts ...gpa.QueryOption) (bool, error) {
-	count, err := r.Count(ctx, opts...)
-	return count > 0, err
+func (r *Repository[T]) Exists(ctx context.Context, opts ...gpa.QueryOption) (exists bool, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationExists, Options: opts}, func(ctx context.Context) error {
+		count, err := r.count(ctx, opts...)
+		exists = count > 0
+		return err
+	})
+	return exists, err
 }
 
 // Transaction executes a function within a transaction with type safety.
 func (r *Repository[T]) Transaction(ctx context.Context, fn gpa.TransactionFunc[T]) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		txRepo := &Transaction[T]{
-			Repository: &Repository[T]{
-				db:       tx,
-				provider: r.provider,
-			},
-		}
-		return fn(txRepo)
+	return r.intercept(ctx, OperationInfo{Operation: OperationTransaction}, func(ctx context.Context) error {
+		return r.transaction(ctx, nil, fn)
+	})
+}
+
+// transaction implements Transaction and TransactionWithOptions. A panic
+// in fn rolls the transaction back and is raised again as a
+// TransactionPanic, or returned as an error with the
+// "transaction_panics_as_errors" option.
+func (r *Repository[T]) transaction(ctx context.Context, opts *gpa.TxOptions, fn gpa.TransactionFunc[T]) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			recoverTransactionPanic(value, entityTypeName[T](), r.provider.transactionPanicsAsErrors(), &err)
+		}
+	}()
+	return runTransaction(ctx, r.provider, r.session(ctx), opts, func(ctx context.Context, tx *gorm.DB) error {
+		repo := r.clone()
+		repo.db = tx
+		repo.changes = r.changeSet(ctx)
+		return fn(&Transaction[T]{Repository: repo})
 	})
 }
 
 // RawQuery executes a raw SQL query with compile-time type safety.
-func (r *Repository[T]) RawQuery(ctx context.Context, query string, args []interface{}) ([]*T, error) {
+func (r *Repository[T]) RawQuery(ctx context.Context, query string, args []interface{}) (entities []*T, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationRawQuery, SQL: query}, func(ctx context.Context) error {
+		var err error
+		entities, err = r.rawQuery(ctx, query, args)
+		return err
+	})
+	return entities, err
+}
+
+// rawQuery implements RawQuery
+func (r *Repository[T]) rawQuery(ctx context.Context, query string, args []interface{}) ([]*T, error) {
+	if err := r.authorizeRead(ctx, nil); err != nil {
+		return nil, err
+	}
+
 	var entities []*T
-	result := r.db.WithContext(ctx).Raw(query, args...).Scan(&entities)
+	result := r.session(ctx).Raw(query, args...).Scan(&entities)
 	if err := convertGormError(result.Error); err != nil {
 		return nil, err
 	}
@@ -324,8 +553,18 @@ func (r *Repository[T]) RawQuery(ctx context.Context, query string, args []inter
 }
 
 // RawExec executes a raw SQL statement.
-func (r *Repository[T]) RawExec(ctx context.Context, query string, args []interface{}) (gpa.Result, error) {
-	result := r.db.WithContext(ctx).Exec(query, args...)
+func (r *Repository[T]) RawExec(ctx context.Context, query string, args []interface{}) (result gpa.Result, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationRawExec, SQL: query}, func(ctx context.Context) error {
+		var err error
+		result, err = r.rawExec(ctx, query, args)
+		return err
+	})
+	return result, err
+}
+
+// rawExec implements RawExec
+func (r *Repository[T]) rawExec(ctx context.Context, query string, args []interface{}) (gpa.Result, error) {
+	result := r.session(ctx).Exec(query, args...)
 	if result.Error != nil {
 		return nil, convertGormError(result.Error)
 	}
@@ -411,8 +650,22 @@ func (r *Repository[T]) FindWithRelations(ctx context.Context, relations []strin
 }
 
 // FindByIDWithRelations retrieves an entity by ID with preloaded relationships.
-func (r *Repository[T]) FindByIDWithRelations(ctx context.Context, id interface{}, relations []string) (*T, error) {
-	db := r.db.WithContext(ctx)
+func (r *Repository[T]) FindByIDWithRelations(ctx context.Context, id interface{}, relations []string) (entity *T, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationFindByID, ID: id}, func(ctx context.Context) error {
+		var err error
+		entity, err = r.findByIDWithRelations(ctx, id, relations)
+		return err
+	})
+	return entity, err
+}
+
+// findByIDWithRelations implements FindByIDWithRelations
+func (r *Repository[T]) findByIDWithRelations(ctx context.Context, id interface{}, relations []string) (*T, error) {
+	if err := r.authorizeRead(ctx, []gpa.QueryOption{gpa.Where("id", gpa.OpEqual, id), gpa.Preload(relations...)}); err != nil {
+		return nil, err
+	}
+
+	db := r.replicaSession(ctx)
 
 	// Apply preloads
 	for _, relation := range relations {
@@ -429,60 +682,67 @@ func (r *Repository[T]) FindByIDWithRelations(ctx context.Context, id interface{
 
 // CreateTable creates a new table for entity type T.
 func (r *Repository[T]) CreateTable(ctx context.Context) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationCreateTable}, func(ctx context.Context) error {
+		return r.createTable(ctx)
+	})
+}
+
+// createTable implements CreateTable
+func (r *Repository[T]) createTable(ctx context.Context) error {
 	var zero T
-	migrator := r.db.Migrator()
-	if migrator.HasTable(&zero) {
-		return gpa.GPAError{
-			Type:    gpa.ErrorTypeDuplicate,
-			Message: "table already exists",
+	err := r.provider.runDDL(ctx, r.db, func(db *gorm.DB) error {
+		migrator := db.Migrator()
+		if migrator.HasTable(&zero) {
+			return gpa.GPAError{
+				Type:    gpa.ErrorTypeDuplicate,
+				Message: "table already exists",
+			}
 		}
-	}
-	err := migrator.CreateTable(&zero)
+		return migrator.CreateTable(&zero)
+	})
 	return convertGormError(err)
 }
 
 // DropTable drops the table for entity type T.
 func (r *Repository[T]) DropTable(ctx context.Context) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationDropTable}, func(ctx context.Context) error {
+		return r.dropTable(ctx)
+	})
+}
+
+// dropTable implements DropTable
+func (r *Repository[T]) dropTable(ctx context.Context) error {
 	var zero T
-	migrator := r.db.Migrator()
-	err := migrator.DropTable(&zero)
+	err := r.provider.runDDL(ctx, r.db, func(db *gorm.DB) error {
+		return db.Migrator().DropTable(&zero)
+	})
 	return convertGormError(err)
 }
 
-// CreateIndex creates an index on the specified fields.
+// CreateIndex creates an index on the specified fields, named
+// idx_<table>_<columns>. Fields may carry a direction and, on MySQL, a
+// prefix length, as in "created_at DESC" or "title(20)". See
+// CreateIndexSpec for explicit names, included columns and how existing
+// indexes are detected.
 func (r *Repository[T]) CreateIndex(ctx context.Context, fields []string, unique bool) error {
-	var zero T
-	migrator := r.db.Migrator()
-
-	// Generate index name
-	stmt := &gorm.Statement{DB: r.db}
-	err := stmt.Parse(&zero)
-	if err != nil {
-		return convertGormError(err)
-	}
-
-	indexName := "idx_" + stmt.Schema.Table + "_" + fields[0]
-	for _, field := range fields[1:] {
-		indexName += "_" + field
-	}
-
-	// Check if index already exists
-	if migrator.HasIndex(&zero, indexName) {
-		return gpa.GPAError{
-			Type:    gpa.ErrorTypeDuplicate,
-			Message: "index already exists: " + indexName,
-		}
-	}
-
-	err = migrator.CreateIndex(&zero, indexName)
-	return convertGormError(err)
+	return r.intercept(ctx, OperationInfo{Operation: OperationCreateIndex}, func(ctx context.Context) error {
+		return r.createIndexSpec(ctx, IndexSpec{Fields: fields, Unique: unique})
+	})
 }
 
 // DropIndex removes an index.
 func (r *Repository[T]) DropIndex(ctx context.Context, indexName string) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationDropIndex}, func(ctx context.Context) error {
+		return r.dropIndex(ctx, indexName)
+	})
+}
+
+// dropIndex implements DropIndex
+func (r *Repository[T]) dropIndex(ctx context.Context, indexName string) error {
 	var zero T
-	migrator := r.db.Migrator()
-	err := migrator.DropIndex(&zero, indexName)
+	err := r.provider.runDDL(ctx, r.db, func(db *gorm.DB) error {
+		return db.Migrator().DropIndex(&zero, indexName)
+	})
 	return convertGormError(err)
 }
 
@@ -492,15 +752,31 @@ func (r *Repository[T]) DropIndex(ctx context.Context, indexName string) error {
 
 // MigrateTable migrates the table schema for entity type T.
 func (r *Repository[T]) MigrateTable(ctx context.Context) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationMigrateTable}, func(ctx context.Context) error {
+		return r.migrateTable(ctx)
+	})
+}
+
+// migrateTable implements MigrateTable
+func (r *Repository[T]) migrateTable(ctx context.Context) error {
 	var zero T
-	err := r.db.AutoMigrate(&zero)
+	if r.provider != nil && r.provider.safeMigrations() {
+		return r.provider.runDDL(ctx, r.db, func(db *gorm.DB) error {
+			report, err := migrateSafe(db, &zero)
+			logSkippedChanges(report)
+			return err
+		})
+	}
+	err := r.provider.runDDL(ctx, r.db, func(db *gorm.DB) error {
+		return r.provider.autoMigrate(db, &zero)
+	})
 	return convertGormError(err)
 }
 
 // GetMigrationStatus returns the current migration status for entity type T.
 func (r *Repository[T]) GetMigrationStatus(ctx context.Context) (gpa.MigrationStatus, error) {
 	var zero T
-	migrator := r.db.Migrator()
+	migrator := r.db.WithContext(ctx).Migrator()
 
 	status := gpa.MigrationStatus{
 		TableExists:     migrator.HasTable(&zero),
@@ -556,6 +832,10 @@ func (r *Repository[T]) GetTableInfo(ctx context.Context) (gpa.TableInfo, error)
 		info.Columns = append(info.Columns, columnInfo)
 	}
 
+	// Drivers without index introspection still get the schema's info
+	if indexes, err := r.Indexes(ctx); err == nil {
+		info.Indexes = indexes
+	}
 	return info, nil
 }
 
@@ -592,8 +872,62 @@ func (t *Transaction[T]) RollbackToSavepoint(name string) error {
 // Helper Methods
 // =====================================
 
+// prepareCreate authorizes entities and runs their validation and
+// before create hooks, stopping at the first failure
+func (r *Repository[T]) prepareCreate(ctx context.Context, entities []*T) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	if err := r.canonicalize(ctx, entities...); err != nil {
+		return err
+	}
+	for _, entity := range entities {
+		if err := r.authorizeCreate(ctx, entity); err != nil {
+			return err
+		}
+		if err := r.checkAllowedValues(ctx, entity); err != nil {
+			return err
+		}
+	}
+
+	// Execute validation hooks for all entities
+	for _, entity := range entities {
+		if hook, ok := any(entity).(gpa.ValidationHook); ok {
+			if err := hook.Validate(ctx); err != nil {
+				return gpa.NewErrorWithCause(gpa.ErrorTypeValidation, "validation failed", err)
+			}
+		}
+	}
+
+	// Execute before create hooks for all entities
+	for _, entity := range entities {
+		if hook, ok := any(entity).(gpa.BeforeCreateHook); ok {
+			if err := hook.BeforeCreate(ctx); err != nil {
+				return gpa.NewErrorWithCause(gpa.ErrorTypeValidation, "before create hook failed", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// finishCreate records the inserted entities as changed and runs their
+// after create hooks
+func (r *Repository[T]) finishCreate(ctx context.Context, entities []*T) {
+	r.recordEntityChange(ctx, ChangeCreated, entities...)
+	for _, entity := range entities {
+		if hook, ok := any(entity).(gpa.AfterCreateHook); ok {
+			if err := hook.AfterCreate(ctx); err != nil {
+				// Log error but don't fail the operation
+				LogAfterCreateError(ctx, entity, err)
+			}
+		}
+	}
+}
+
 // buildQuery builds a GORM query from GPA query options
-func (r *Repository[T]) buildQuery(opts ...gpa.QueryOption) *gorm.DB {
+func (r *Repository[T]) buildQuery(ctx context.Context, opts ...gpa.QueryOption) *gorm.DB {
+	opts, scopeErr := r.expandScopes(opts, 0)
 	query := &gpa.Query{}
 
 	// Apply all options
@@ -601,22 +935,46 @@ func (r *Repository[T]) buildQuery(opts ...gpa.QueryOption) *gorm.DB {
 		opt.Apply(query)
 	}
 
-	db := r.db
+	db := r.replicaSession(ctx)
+	if scopeErr != nil {
+		db.AddError(scopeErr)
+		return db
+	}
+	grouping := groupingFromOptions(opts)
+	if grouping != nil {
+		if err := grouping.validate(); err != nil {
+			db.AddError(err)
+			return db
+		}
+		if grouping.nativeGrouping(db) == "" {
+			return r.buildGroupingUnion(ctx, grouping, opts)
+		}
+	}
+	if collate := collationFromOptions(opts); collate != nil {
+		db = db.Set(collateKey, collate)
+	}
+	if r.strictFields {
+		list, err := r.fieldAllowList(query, opts)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		db = db.Set(strictFieldsKey, list)
+	}
 
 	// Apply conditions
 	for _, condition := range query.Conditions {
 		db = r.applyCondition(db, condition)
 	}
 
-	// Apply field selection
-	if len(query.Fields) > 0 {
-		db = db.Select(query.Fields)
+	// Apply field selection, with the starts of any time buckets
+	db, bucketFields := applyTimeBuckets(db, timeBucketsFromOptions(opts))
+	if fields := append(append([]string(nil), query.Fields...), bucketFields...); len(fields) > 0 {
+		db = db.Select(fields)
 	}
 
 	// Apply ordering
-	for _, order := range query.Orders {
-		db = db.Order(order.Field + " " + string(order.Direction))
-	}
+	db = r.applyOrdering(db, opts)
 
 	// Apply limit
 	if query.Limit != nil {
@@ -630,12 +988,10 @@ func (r *Repository[T]) buildQuery(opts ...gpa.QueryOption) *gorm.DB {
 
 	// Apply joins
 	for _, join := range query.Joins {
-		joinClause := string(join.Type) + " JOIN " + join.Table
-		if join.Alias != "" {
-			joinClause += " AS " + join.Alias
-		}
-		if join.Condition != "" {
-			joinClause += " ON " + join.Condition
+		joinClause, err := joinSQL(db, join)
+		if err != nil {
+			db.AddError(err)
+			return db
 		}
 		db = db.Joins(joinClause)
 	}
@@ -644,12 +1000,20 @@ func (r *Repository[T]) buildQuery(opts ...gpa.QueryOption) *gorm.DB {
 	for _, preload := range query.Preloads {
 		db = db.Preload(preload)
 	}
+	db = applyPreloadSelects(db, opts)
+	db = applyTableHints(db, opts)
 
 	// Apply grouping
-	if len(query.Groups) > 0 {
-		for _, group := range query.Groups {
-			db = db.Group(group)
+	for _, group := range query.Groups {
+		expr, err := fieldExpr(db, group)
+		if err != nil {
+			db.AddError(err)
+			return db
 		}
+		db = db.Group(expr)
+	}
+	if grouping != nil {
+		db = db.Group(grouping.nativeGrouping(db))
 	}
 
 	// Apply having conditions
@@ -670,14 +1034,9 @@ func (r *Repository[T]) applyCondition(db *gorm.DB, condition gpa.Condition) *go
 	// Basic implementation - can be enhanced later
 	switch cond := condition.(type) {
 	case gpa.BasicCondition:
-		field := cond.Field()
-
 		// Validate field name to prevent SQL injection
-		if !isValidFieldName(field) {
-			err := &FieldValidationError{
-				Field:  field,
-				Reason: "field name contains invalid characters or doesn't follow naming rules",
-			}
+		field, err := fieldExpr(db, cond.Field())
+		if err != nil {
 			// Store error on DB instance
 			db.AddError(err)
 			return db
@@ -686,6 +1045,14 @@ func (r *Repository[T]) applyCondition(db *gorm.DB, condition gpa.Condition) *go
 		operator := cond.Operator()
 		value := cond.Value()
 
+		// Patterns are not lower-cased, as that would change their meaning
+		if operator != gpa.OpIsNull && operator != gpa.OpIsNotNull && operator != gpa.OpRegex {
+			var lower bool
+			if field, lower = r.collateExpr(db, cond.Field(), field); lower {
+				value = lowerValue(value)
+			}
+		}
+
 		switch operator {
 		case gpa.OpEqual:
 			return db.Where(field+" = ?", value)
@@ -711,29 +1078,70 @@ func (r *Repository[T]) applyCondition(db *gorm.DB, condition gpa.Condition) *go
 			return db.Where(field + " IS NULL")
 		case gpa.OpIsNotNull:
 			return db.Where(field + " IS NOT NULL")
+		case gpa.OpBetween, gpa.OpNotBetween:
+			sql, args, err := betweenSQL(field, operator, value)
+			if err != nil {
+				db.AddError(err)
+				return db
+			}
+			return db.Where(sql, args...)
+		case gpa.OpRegex:
+			sql, err := regexSQL(db, field)
+			if err != nil {
+				db.AddError(err)
+				return db
+			}
+			return db.Where(sql, value)
+		case OpArrayContains, OpArrayOverlaps, OpArrayAny:
+			sql, args, err := arraySQL(db, field, operator, value)
+			if err != nil {
+				db.AddError(err)
+				return db
+			}
+			return db.Where(sql, args...)
 		default:
 			return db.Where(field+" = ?", value)
 		}
+	case TupleCondition:
+		sql, args, err := tupleSQL(db, cond)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		return db.Where(sql, args...)
+	case JSONCondition:
+		sql, args, err := jsonSQL(db, cond)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		return db.Where(sql, args...)
+	case SubQueryCondition:
+		sql, args, err := subQuerySQL(db, cond)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		return db.Where(sql, args...)
+	case RawSQLCondition:
+		if err := cond.validate(); err != nil {
+			db.AddError(err)
+			return db
+		}
+		return db.Where(cond.SQL, cond.Args...)
 	default:
 		// For complex conditions, return the query unchanged for now
 		return db
 	}
 }
 
-// applyHaving applies a having condition
+// applyHaving applies a having condition. Its field may be an aggregate
+// such as "COUNT(*)" or "SUM(amount)", or a select alias.
 func (r *Repository[T]) applyHaving(db *gorm.DB, condition gpa.Condition) *gorm.DB {
-	// Basic implementation - similar to applyCondition but for HAVING clause
 	switch cond := condition.(type) {
 	case gpa.BasicCondition:
-		field := cond.Field()
-
-		// Validate field name to prevent SQL injection
-		if !isValidFieldName(field) {
-			err := &FieldValidationError{
-				Field:  field,
-				Reason: "field name contains invalid characters or doesn't follow naming rules",
-			}
-			// Store error on DB instance
+		field, err := havingExpr(db, cond.Field())
+		if err != nil {
 			db.AddError(err)
 			return db
 		}
@@ -754,6 +1162,21 @@ func (r *Repository[T]) applyHaving(db *gorm.DB, condition gpa.Condition) *gorm.
 			return db.Having(field+" < ?", value)
 		case gpa.OpLessThanOrEqual:
 			return db.Having(field+" <= ?", value)
+		case gpa.OpIn:
+			return db.Having(field+" IN ?", value)
+		case gpa.OpNotIn:
+			return db.Having(field+" NOT IN ?", value)
+		case gpa.OpIsNull:
+			return db.Having(field + " IS NULL")
+		case gpa.OpIsNotNull:
+			return db.Having(field + " IS NOT NULL")
+		case gpa.OpBetween, gpa.OpNotBetween:
+			sql, args, err := betweenSQL(field, operator, value)
+			if err != nil {
+				db.AddError(err)
+				return db
+			}
+			return db.Having(sql, args...)
 		default:
 			return db.Having(field+" = ?", value)
 		}
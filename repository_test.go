@@ -722,4 +722,136 @@ func TestSQLResult(t *testing.T) {
 	if id != 123 {
 		t.Errorf("Expected last insert ID 123, got %d", id)
 	}
-}
\ No newline at end of file
+}
+
+func TestRepositoryExistsByIDs(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	users := []*TestUser{
+		{Name: "User 1", Email: "user1@example.com"},
+		{Name: "User 2", Email: "user2@example.com"},
+	}
+	if err := repo.CreateBatch(ctx, users); err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+
+	present, err := repo.ExistsByIDs(ctx, []interface{}{users[0].ID, 9999, int(users[1].ID)})
+	if err != nil {
+		t.Fatalf("Failed to check IDs: %v", err)
+	}
+
+	if !present[users[0].ID] {
+		t.Errorf("Expected ID %d to exist", users[0].ID)
+	}
+	if present[9999] {
+		t.Error("Expected ID 9999 to be missing")
+	}
+	if !present[int(users[1].ID)] {
+		t.Errorf("Expected ID %d to exist", users[1].ID)
+	}
+	if len(present) != 3 {
+		t.Errorf("Expected 3 entries, got %d", len(present))
+	}
+}
+
+func TestRepositoryCreateIgnoreDuplicates(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &TestUser{Name: "Existing", Email: "dup@example.com"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	inserted, err := repo.CreateIgnoreDuplicates(ctx, []*TestUser{
+		{Name: "Duplicate", Email: "dup@example.com"},
+		{Name: "New", Email: "new@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create ignoring duplicates: %v", err)
+	}
+	if inserted != 1 {
+		t.Errorf("Expected 1 inserted row, got %d", inserted)
+	}
+
+	count, _ := repo.Count(ctx)
+	if count != 2 {
+		t.Errorf("Expected 2 users, got %d", count)
+	}
+}
+
+// hookedUser records the AfterCreate calls it receives
+type hookedUser struct {
+	ID           uint   `gorm:"primaryKey"`
+	Email        string `gorm:"uniqueIndex"`
+	afterCreates int    `gorm:"-"`
+}
+
+func (u *hookedUser) AfterCreate(ctx context.Context) error {
+	u.afterCreates++
+	return nil
+}
+
+func TestRepositoryCreateIgnoreDuplicatesAfterCreate(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&hookedUser{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	repo := NewRepository[hookedUser](provider.db, provider)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &hookedUser{Email: "dup@example.com"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	duplicate := &hookedUser{Email: "dup@example.com"}
+	fresh := &hookedUser{Email: "new@example.com"}
+	if _, err := repo.CreateIgnoreDuplicates(ctx, []*hookedUser{duplicate, fresh}); err != nil {
+		t.Fatalf("Failed to create ignoring duplicates: %v", err)
+	}
+	if duplicate.afterCreates != 0 || fresh.afterCreates != 1 {
+		t.Errorf("Expected AfterCreate only for the inserted user, got %d and %d", duplicate.afterCreates, fresh.afterCreates)
+	}
+	if duplicate.ID != 0 || fresh.ID == 0 {
+		t.Errorf("Expected only the inserted user to get an ID, got %d and %d", duplicate.ID, fresh.ID)
+	}
+}
+
+func TestRepositoryInsertMissing(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &TestUser{Name: "Existing", Email: "dup@example.com"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	fresh := &TestUser{Name: "New", Email: "new@example.com"}
+	inserted, err := repo.InsertMissing(ctx, []*TestUser{
+		{Name: "Duplicate", Email: "dup@example.com"},
+		fresh,
+	}, []string{"email"})
+	if err != nil {
+		t.Fatalf("Failed to insert missing: %v", err)
+	}
+	if len(inserted) != 1 || inserted[0] != fresh {
+		t.Fatalf("Expected only the new user to be reported, got %v", inserted)
+	}
+	if fresh.ID == 0 {
+		t.Error("Expected inserted user to have ID set")
+	}
+
+	_, err = repo.InsertMissing(ctx, []*TestUser{fresh}, []string{"email; DROP TABLE"})
+	if !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument error, got %v", err)
+	}
+}
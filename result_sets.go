@@ -0,0 +1,143 @@
+// Package gpagorm provides statements returning multiple result sets
+package gpagorm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// ResultSets iterates the result sets of a statement that returns several,
+// such as a SQL Server procedure or a batch of SELECTs. The first set is
+// current on return from QueryMulti; NextResultSet advances to the next.
+// Each set is read with its own target, through Scan or Rows.
+type ResultSets struct {
+	db     *gorm.DB
+	rows   *sql.Rows
+	cursor *rawRows
+	finish func(err error) error // Ends the session variable transaction, if any
+}
+
+// QueryMulti runs a statement that may return several result sets. The
+// result sets hold a connection until closed, and with session variables
+// the transaction they are set in, committed on Close.
+func (p *Provider) QueryMulti(ctx context.Context, query string, args ...interface{}) (*ResultSets, error) {
+	r := NewRepository[struct{}](p.db, p)
+	ctx, finish, err := r.cursorSessionTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var sets *ResultSets
+	err = r.intercept(ctx, OperationInfo{Operation: OperationRawQuery, SQL: query}, func(ctx context.Context) error {
+		db := r.session(ctx)
+		rows, err := db.Raw(query, args...).Rows()
+		if err != nil {
+			return convertGormError(err)
+		}
+		sets = &ResultSets{db: db, rows: rows, finish: finish}
+		return nil
+	})
+	if err != nil {
+		if finish != nil {
+			return nil, finish(err)
+		}
+		return nil, err
+	}
+	return sets, nil
+}
+
+// Scan reads the rest of the current result set into dest: a pointer to a
+// slice of structs, maps or scalars receives every row, any other pointer
+// the next row only. Columns map to struct fields as for entities.
+func (s *ResultSets) Scan(dest interface{}) error {
+	isSlice := reflect.Indirect(reflect.ValueOf(dest)).Kind() == reflect.Slice
+	if !s.rows.Next() {
+		if err := s.rows.Err(); err != nil {
+			return convertGormError(err)
+		}
+		if isSlice {
+			slice := reflect.ValueOf(dest).Elem()
+			slice.Set(reflect.MakeSlice(slice.Type(), 0, 0))
+		}
+		return nil
+	}
+	// ScanRows continues from the current row, through every remaining row
+	// for slices
+	if err := s.db.ScanRows(s.rows, dest); err != nil {
+		return convertGormError(err)
+	}
+	return nil
+}
+
+// Rows returns a cursor over the current result set. It ends with the set;
+// NextResultSet moves on to the next one.
+func (s *ResultSets) Rows() Rows {
+	if s.cursor == nil {
+		s.cursor = &rawRows{rows: s.rows}
+	}
+	return resultSetRows{s.cursor}
+}
+
+// NextResultSet advances to the next result set, skipping any unread rows
+// of the current one. It returns false once there are no more sets; Err
+// then reports whether that was due to an error.
+func (s *ResultSets) NextResultSet() bool {
+	if s.cursor != nil {
+		s.cursor.columns = nil
+	}
+	return s.rows.NextResultSet()
+}
+
+// Err returns the error, if any, met while reading the result sets
+func (s *ResultSets) Err() error {
+	return convertGormError(s.rows.Err())
+}
+
+// Close releases the result sets and their connection
+func (s *ResultSets) Close() error {
+	err := convertGormError(s.rows.Close())
+	if finish := s.finish; finish != nil {
+		s.finish = nil
+		if finishErr := finish(nil); err == nil {
+			err = finishErr
+		}
+	}
+	return err
+}
+
+// resultSetRows is the cursor of one result set; closing it is left to
+// ResultSets so the following sets stay readable
+type resultSetRows struct {
+	*rawRows
+}
+
+// Close does nothing; the rows are released by ResultSets.Close
+func (resultSetRows) Close() error {
+	return nil
+}
+
+// ScanResultSets runs a statement and scans its result sets, in order,
+// into dests, one target per set as for ResultSets.Scan. It fails if the
+// statement returns fewer sets than dests.
+func (p *Provider) ScanResultSets(ctx context.Context, dests []interface{}, query string, args ...interface{}) error {
+	sets, err := p.QueryMulti(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer sets.Close()
+	for i, dest := range dests {
+		if i > 0 && !sets.NextResultSet() {
+			if err := sets.Err(); err != nil {
+				return err
+			}
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "statement returned fewer result sets than targets")
+		}
+		if err := sets.Scan(dest); err != nil {
+			return err
+		}
+	}
+	return sets.Err()
+}
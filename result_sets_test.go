@@ -0,0 +1,175 @@
+package gpagorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// multiResultDriver answers every query with fixed result sets, standing in
+// for a SQL Server procedure
+type multiResultDriver struct{ sets []multiResultSet }
+
+type multiResultSet struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (d multiResultDriver) Connect(context.Context) (driver.Conn, error) {
+	return multiResultConn(d), nil
+}
+func (d multiResultDriver) Driver() driver.Driver { return nil }
+
+type multiResultConn multiResultDriver
+
+func (c multiResultConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c multiResultConn) Close() error                        { return nil }
+func (c multiResultConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (c multiResultConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if query == "select sqlite_version()" {
+		return &multiResultRows{sets: []multiResultSet{{[]string{"v"}, [][]driver.Value{{"3.45.0"}}}}}, nil
+	}
+	return &multiResultRows{sets: c.sets}, nil
+}
+
+type multiResultRows struct {
+	sets []multiResultSet
+	set  int
+	row  int
+}
+
+func (r *multiResultRows) Columns() []string { return r.sets[r.set].columns }
+func (r *multiResultRows) Close() error      { return nil }
+func (r *multiResultRows) HasNextResultSet() bool {
+	return r.set+1 < len(r.sets)
+}
+func (r *multiResultRows) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		return io.EOF
+	}
+	r.set, r.row = r.set+1, 0
+	return nil
+}
+func (r *multiResultRows) Next(dest []driver.Value) error {
+	rows := r.sets[r.set].rows
+	if r.row >= len(rows) {
+		return io.EOF
+	}
+	copy(dest, rows[r.row])
+	r.row++
+	return nil
+}
+
+func TestQueryMulti(t *testing.T) {
+	connector := multiResultDriver{sets: []multiResultSet{
+		{[]string{"id", "name"}, [][]driver.Value{{int64(1), "Alice"}, {int64(2), "Bob"}}},
+		{[]string{"total"}, [][]driver.Value{{int64(2)}}},
+		{[]string{"order_id", "amount"}, nil},
+	}}
+	db, err := gorm.Open(sqlite.Dialector{Conn: sql.OpenDB(connector)}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	provider := &Provider{db: db}
+	ctx := context.Background()
+
+	type customer struct {
+		ID   int
+		Name string
+	}
+	type order struct {
+		OrderID int
+		Amount  float64
+	}
+	var customers []customer
+	var total int64
+	orders := []order{{1, 2}}
+	if err := provider.ScanResultSets(ctx, []interface{}{&customers, &total, &orders}, "EXEC customer_report"); err != nil {
+		t.Fatalf("ScanResultSets failed: %v", err)
+	}
+	if len(customers) != 2 || customers[1] != (customer{2, "Bob"}) {
+		t.Errorf("Unexpected customers: %+v", customers)
+	}
+	if total != 2 {
+		t.Errorf("Expected total 2, got %d", total)
+	}
+	if orders == nil || len(orders) != 0 {
+		t.Errorf("Expected no orders, got %+v", orders)
+	}
+
+	extra := []interface{}{&customers, &total, &orders, &total}
+	if err := provider.ScanResultSets(ctx, extra, "EXEC customer_report"); err == nil {
+		t.Error("Expected an error for more targets than result sets")
+	}
+
+	// Iterating a set through its cursor
+	sets, err := provider.QueryMulti(ctx, "EXEC customer_report")
+	if err != nil {
+		t.Fatalf("QueryMulti failed: %v", err)
+	}
+	defer sets.Close()
+	rows := sets.Rows()
+	var names []string
+	for rows.Next() {
+		row := map[string]interface{}{}
+		if err := rows.ScanMap(row); err != nil {
+			t.Fatalf("ScanMap failed: %v", err)
+		}
+		names = append(names, row["name"].(string))
+	}
+	if len(names) != 2 {
+		t.Errorf("Expected two names, got %v", names)
+	}
+	if !sets.NextResultSet() {
+		t.Fatal("Expected a second result set")
+	}
+	if columns, _ := sets.Rows().Columns(); len(columns) != 1 || columns[0] != "total" {
+		t.Errorf("Expected the second set's columns, got %v", columns)
+	}
+	sets.NextResultSet()
+	if sets.NextResultSet() {
+		t.Error("Expected no fourth result set")
+	}
+	if err := sets.Err(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestQueryMultiSessionVars(t *testing.T) {
+	cassette := &Cassette{Dialect: "postgres", ServerVersion: "16.2", Interactions: []Interaction{
+		{Query: "BEGIN"},
+		{Query: "SELECT set_config($1, $2, true)", Args: []interface{}{"app.tenant_id", "acme"}},
+		{Query: "SELECT id FROM documents", Columns: []string{"id"}, Rows: [][]interface{}{{1}}},
+		{Query: "COMMIT"},
+	}}
+	provider, err := NewReplayProvider(cassette)
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+	ctx := WithSessionVars(context.Background(), map[string]string{"app.tenant_id": "acme"})
+
+	sets, err := provider.QueryMulti(ctx, "SELECT id FROM documents")
+	if err != nil {
+		t.Fatalf("QueryMulti failed: %v", err)
+	}
+	var ids []int
+	if err := sets.Scan(&ids); err != nil || len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("Expected the id, got %v (%v)", ids, err)
+	}
+	if remaining := provider.ReplayRemaining(); len(remaining) != 1 || remaining[0].Query != "COMMIT" {
+		t.Errorf("Expected the commit to wait for Close, remaining %v", remaining)
+	}
+	if err := sets.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if remaining := provider.ReplayRemaining(); len(remaining) != 0 {
+		t.Errorf("Expected every recorded statement to run, remaining %v", remaining)
+	}
+}
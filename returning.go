@@ -0,0 +1,237 @@
+// Package gpagorm provides writes that return the rows they wrote
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+)
+
+// returningKey marks a context whose create reads back the inserted row
+type returningKey struct{}
+
+// CreateReturning inserts entity and refreshes every column from the row
+// as stored, including database defaults, computed columns and values set
+// by triggers, within the same statement: RETURNING * on Postgres and
+// SQLite, OUTPUT INSERTED.* on SQL Server.
+func (r *Repository[T]) CreateReturning(ctx context.Context, entity *T) error {
+	if err := r.checkReturning(); err != nil {
+		return err
+	}
+	return r.intercept(ctx, OperationInfo{Operation: OperationCreate, Entity: entity}, func(ctx context.Context) error {
+		return r.create(context.WithValue(ctx, returningKey{}, true), entity)
+	})
+}
+
+// UpdateReturning applies updates to the entity with id and returns it as
+// stored afterwards, read by the UPDATE itself: RETURNING * or, on SQL
+// Server, OUTPUT INSERTED.*.
+func (r *Repository[T]) UpdateReturning(ctx context.Context, id interface{}, updates map[string]interface{}) (entity *T, err error) {
+	if err := r.checkReturning(); err != nil {
+		return nil, err
+	}
+	err = r.intercept(ctx, OperationInfo{Operation: OperationUpdatePartial, ID: id, Entity: updates}, func(ctx context.Context) error {
+		var err error
+		entity, err = r.updateReturning(ctx, id, updates)
+		return err
+	})
+	return entity, err
+}
+
+// updateReturning implements UpdateReturning
+func (r *Repository[T]) updateReturning(ctx context.Context, id interface{}, updates map[string]interface{}) (*T, error) {
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	if err := r.checkAllowedUpdates(updates); err != nil {
+		return nil, err
+	}
+	if r.policy() != nil {
+		var current T
+		if err := r.readSession(ctx).First(&current, id).Error; err != nil {
+			return nil, convertGormError(err)
+		}
+		if err := r.authorizeUpdate(ctx, &current); err != nil {
+			return nil, err
+		}
+	}
+
+	entity := new(T)
+	result := r.session(ctx).Model(entity).Clauses(clause.Returning{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return nil, convertGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, gpa.GPAError{
+			Type:    gpa.ErrorTypeNotFound,
+			Message: "entity not found",
+		}
+	}
+	r.recordChange(ctx, ChangeUpdated, []interface{}{id})
+	return entity, nil
+}
+
+// DeleteReturning removes the entities matching condition and returns them
+// as they were, read by the DELETE itself: RETURNING * or, on SQL Server,
+// OUTPUT DELETED.*.
+func (r *Repository[T]) DeleteReturning(ctx context.Context, condition gpa.Condition) (entities []*T, err error) {
+	if err := r.checkReturning(); err != nil {
+		return nil, err
+	}
+	err = r.intercept(ctx, OperationInfo{Operation: OperationDeleteByCondition, Condition: condition}, func(ctx context.Context) error {
+		var err error
+		entities, err = r.deleteReturning(ctx, condition)
+		return err
+	})
+	return entities, err
+}
+
+// deleteReturning implements DeleteReturning
+func (r *Repository[T]) deleteReturning(ctx context.Context, condition gpa.Condition) ([]*T, error) {
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	var entity T
+	if r.policy() != nil {
+		var matches []*T
+		result := r.applyCondition(r.readSession(ctx).Model(&entity), condition).Find(&matches)
+		if result.Error != nil {
+			return nil, convertGormError(result.Error)
+		}
+		for _, match := range matches {
+			if err := r.authorizeDelete(ctx, match); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	entities := []*T{}
+	query := r.applyCondition(r.session(ctx).Model(&entity).Clauses(clause.Returning{}), condition)
+	if err := convertGormError(query.Delete(&entities).Error); err != nil {
+		return nil, err
+	}
+	r.recordEntityChange(ctx, ChangeDeleted, entities...)
+	return entities, nil
+}
+
+// checkReturning fails when the database cannot return written rows
+func (r *Repository[T]) checkReturning() error {
+	var supported bool
+	if r.provider != nil {
+		supported = r.provider.Capabilities().Returning
+	} else {
+		supported = capabilitiesFor(dialectName(r.db), "").Returning
+	}
+	if !supported {
+		return gpa.NewError(gpa.ErrorTypeUnsupported, "returning written rows is not supported by "+dialectName(r.db))
+	}
+	return nil
+}
+
+// withReturning adds a RETURNING clause to db when ctx asks for it
+func withReturning(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if returning, _ := ctx.Value(returningKey{}).(bool); returning {
+		return db.Clauses(clause.Returning{})
+	}
+	return db
+}
+
+// registerOutputInserted makes creates with a RETURNING clause emit OUTPUT
+// INSERTED.* on SQL Server, whose driver only outputs the columns with
+// database defaults. Other creates go to the driver unchanged.
+func registerOutputInserted(db *gorm.DB) error {
+	if dialectName(db) != "sqlserver" {
+		return nil
+	}
+	create := db.Callback().Create().Get("gorm:create")
+	return db.Callback().Create().Replace("gorm:create", func(db *gorm.DB) {
+		_, returning := db.Statement.Clauses["RETURNING"]
+		_, upsert := db.Statement.Clauses["ON CONFLICT"]
+		if !returning || upsert || db.Statement.SQL.Len() > 0 || db.Statement.Schema == nil {
+			create(db)
+			return
+		}
+		createOutputInserted(db)
+	})
+}
+
+// createOutputInserted builds and runs INSERT ... OUTPUT INSERTED.* VALUES
+// and scans the output into the created entities
+func createOutputInserted(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	stmt := db.Statement
+	if !stmt.Unscoped {
+		for _, c := range stmt.Schema.CreateClauses {
+			stmt.AddClause(c)
+		}
+	}
+	values := callbacks.ConvertToCreateValues(stmt)
+
+	// Explicit identity values need IDENTITY_INSERT, as in the driver
+	identityInsert := false
+	if field := stmt.Schema.PrioritizedPrimaryField; field != nil && field.AutoIncrement {
+		rv := stmt.ReflectValue
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			if rv.Len() > 0 {
+				rv = reflect.Indirect(rv.Index(0))
+			}
+		}
+		if rv.Kind() == reflect.Struct {
+			_, zero := field.ValueOf(stmt.Context, rv)
+			identityInsert = !zero
+		}
+	}
+	if identityInsert {
+		stmt.WriteString("SET IDENTITY_INSERT ")
+		stmt.WriteQuoted(stmt.Table)
+		stmt.WriteString(" ON;")
+	}
+
+	stmt.AddClauseIfNotExists(clause.Insert{})
+	stmt.Build("INSERT")
+	if len(values.Columns) == 0 {
+		stmt.WriteString(" OUTPUT INSERTED.* DEFAULT VALUES;")
+	} else {
+		stmt.WriteString(" (")
+		for i, column := range values.Columns {
+			if i > 0 {
+				stmt.WriteByte(',')
+			}
+			stmt.WriteQuoted(column)
+		}
+		stmt.WriteString(") OUTPUT INSERTED.* VALUES ")
+		for i, value := range values.Values {
+			if i > 0 {
+				stmt.WriteByte(',')
+			}
+			stmt.WriteByte('(')
+			stmt.AddVar(stmt, value...)
+			stmt.WriteByte(')')
+		}
+		stmt.WriteString(";")
+	}
+	if identityInsert {
+		stmt.WriteString("SET IDENTITY_INSERT ")
+		stmt.WriteQuoted(stmt.Table)
+		stmt.WriteString(" OFF;")
+	}
+
+	if db.DryRun || db.Error != nil {
+		return
+	}
+	rows, err := stmt.ConnPool.QueryContext(stmt.Context, stmt.SQL.String(), stmt.Vars...)
+	if db.AddError(err) != nil {
+		return
+	}
+	defer rows.Close()
+	gorm.Scan(rows, db, gorm.ScanUpdate)
+	if stmt.Result != nil {
+		stmt.Result.RowsAffected = db.RowsAffected
+	}
+}
@@ -0,0 +1,110 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testStampedUser has a column filled in by the database
+type testStampedUser struct {
+	ID     uint   `gorm:"primaryKey"`
+	Name   string `gorm:"size:255"`
+	Status string `gorm:"size:32;default:'pending'"`
+}
+
+func TestWritesReturning(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	if err := provider.db.AutoMigrate(&testStampedUser{}); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+	repo := NewRepository[testStampedUser](provider.db, provider)
+
+	user := &testStampedUser{Name: "Alice"}
+	if err := repo.CreateReturning(ctx, user); err != nil {
+		t.Fatalf("CreateReturning failed: %v", err)
+	}
+	if user.ID == 0 || user.Status != "pending" {
+		t.Errorf("Expected the stored row to be read back, got %+v", user)
+	}
+
+	updated, err := repo.UpdateReturning(ctx, user.ID, map[string]interface{}{"status": "active"})
+	if err != nil {
+		t.Fatalf("UpdateReturning failed: %v", err)
+	}
+	if updated.ID != user.ID || updated.Name != "Alice" || updated.Status != "active" {
+		t.Errorf("Unexpected updated row: %+v", updated)
+	}
+	if _, err := repo.UpdateReturning(ctx, 999, map[string]interface{}{"status": "x"}); !gpa.IsErrorType(err, gpa.ErrorTypeNotFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+
+	repo.Create(ctx, &testStampedUser{Name: "Bob"})
+	deleted, err := repo.DeleteReturning(ctx, gpa.BasicCondition{FieldName: "status", Op: gpa.OpEqual, Val: "active"})
+	if err != nil {
+		t.Fatalf("DeleteReturning failed: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].Name != "Alice" {
+		t.Errorf("Expected Alice to be deleted, got %+v", deleted)
+	}
+	if count, _ := repo.Count(ctx); count != 1 {
+		t.Errorf("Expected one user left, got %d", count)
+	}
+}
+
+func TestOutputInsertedSQL(t *testing.T) {
+	db, err := gorm.Open(sqlserver.Open("sqlserver://localhost?database=gpagorm"),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := registerOutputInserted(db); err != nil {
+		t.Fatalf("registerOutputInserted failed: %v", err)
+	}
+
+	user := &testStampedUser{Name: "Alice"}
+	stmt := withReturning(context.WithValue(context.Background(), returningKey{}, true), db).Create(user).Statement
+	if got := stmt.SQL.String(); !strings.Contains(got, `("name","status") OUTPUT INSERTED.* VALUES (@p1,@p2);`) {
+		t.Errorf("Expected OUTPUT INSERTED.*, got %s", got)
+	}
+
+	stmt = db.Create(&testStampedUser{Name: "Bob"}).Statement
+	if got := stmt.SQL.String(); strings.Contains(got, "INSERTED.*") {
+		t.Errorf("Expected a plain create without RETURNING, got %s", got)
+	}
+
+	repo := NewRepository[testStampedUser](db, nil)
+	var rows []*testStampedUser
+	stmt = repo.buildQuery(context.Background(), TableHint("NOLOCK", "INDEX(ix_name)")).Find(&rows).Statement
+	if got := stmt.SQL.String(); got != `SELECT * FROM "test_stamped_users" WITH (NOLOCK, INDEX(ix_name))` {
+		t.Errorf("Unexpected hinted query: %s", got)
+	}
+}
+
+func TestTableHintValidation(t *testing.T) {
+	rendered, err := DryRunSQL[TestUser](TableHint("UPDLOCK", "ROWLOCK"))
+	if err != nil {
+		t.Fatalf("DryRunSQL failed: %v", err)
+	}
+	if got := rendered["sqlserver"].SQL; !strings.Contains(got, `FROM "test_users" WITH (UPDLOCK, ROWLOCK)`) {
+		t.Errorf("Expected the hints on SQL Server, got %s", got)
+	}
+	if got := rendered["postgres"].SQL; strings.Contains(got, "WITH") {
+		t.Errorf("Expected hints to be ignored on Postgres, got %s", got)
+	}
+
+	rendered, err = DryRunSQL[TestUser](TableHint("NOLOCK); DROP TABLE users; --"))
+	if err != nil {
+		t.Fatalf("DryRunSQL failed: %v", err)
+	}
+	if rendered["sqlserver"].Err == nil || rendered["postgres"].Err == nil {
+		t.Error("Expected an invalid hint to be rejected on every dialect")
+	}
+}
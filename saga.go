@@ -0,0 +1,319 @@
+// Package gpagorm provides persistent saga state for multi-step workflows
+package gpagorm
+
+import (
+	"context"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SagaStatus is the state of a saga
+type SagaStatus string
+
+const (
+	// SagaRunning sagas are executing their steps
+	SagaRunning SagaStatus = "running"
+	// SagaCompleted sagas finished every step
+	SagaCompleted SagaStatus = "completed"
+	// SagaCompensating sagas are undoing their finished steps
+	SagaCompensating SagaStatus = "compensating"
+	// SagaCompensated sagas have undone every finished step
+	SagaCompensated SagaStatus = "compensated"
+)
+
+// SagaStepStatus is the state of a saga step
+type SagaStepStatus string
+
+const (
+	// SagaStepPending steps have not run yet
+	SagaStepPending SagaStepStatus = "pending"
+	// SagaStepDone steps have run
+	SagaStepDone SagaStepStatus = "done"
+	// SagaStepCompensated steps have run and been undone
+	SagaStepCompensated SagaStepStatus = "compensated"
+)
+
+// sagaRow is a row of the saga table
+type sagaRow struct {
+	ID        string     `gorm:"primaryKey;size:36"`
+	Name      string     `gorm:"size:255;not null;index"`
+	Status    SagaStatus `gorm:"size:32;not null;index"`
+	Payload   string     `gorm:"type:text"`
+	Error     string     `gorm:"type:text"`
+	CreatedAt time.Time
+	UpdatedAt time.Time `gorm:"index"`
+}
+
+// TableName returns the saga table name
+func (sagaRow) TableName() string { return "gpagorm_sagas" }
+
+// sagaStepRow is a row of the saga step table
+type sagaStepRow struct {
+	SagaID    string         `gorm:"primaryKey;size:36"`
+	Name      string         `gorm:"primaryKey;size:255"`
+	Position  int            `gorm:"not null"`
+	Status    SagaStepStatus `gorm:"size:32;not null"`
+	UpdatedAt time.Time
+}
+
+// TableName returns the saga step table name
+func (sagaStepRow) TableName() string { return "gpagorm_saga_steps" }
+
+// Saga is the persisted state of a workflow
+type Saga struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Status    SagaStatus `json:"status"`
+	Payload   string     `json:"payload,omitempty"`
+	Error     string     `json:"error,omitempty"` // Reason given to Abort
+	Steps     []SagaStep `json:"steps"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// SagaStep is the state of one step of a saga
+type SagaStep struct {
+	Name      string         `json:"name"`
+	Status    SagaStepStatus `json:"status"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// NextStep returns the first pending step of a running saga
+func (s *Saga) NextStep() (string, bool) {
+	if s.Status != SagaRunning {
+		return "", false
+	}
+	for _, step := range s.Steps {
+		if step.Status == SagaStepPending {
+			return step.Name, true
+		}
+	}
+	return "", false
+}
+
+// Compensations returns the finished steps still to be undone, last first
+func (s *Saga) Compensations() []string {
+	var steps []string
+	for i := len(s.Steps) - 1; i >= 0; i-- {
+		if s.Steps[i].Status == SagaStepDone {
+			steps = append(steps, s.Steps[i].Name)
+		}
+	}
+	return steps
+}
+
+// SagaStore persists saga state in the gpagorm_sagas and
+// gpagorm_saga_steps tables. Every change runs in a transaction, so the
+// saga and step states always agree.
+type SagaStore struct {
+	db       *gorm.DB
+	provider *Provider
+}
+
+// NewSagaStore creates a saga store on db, which may be a transaction
+func NewSagaStore(db *gorm.DB, provider *Provider) *SagaStore {
+	return &SagaStore{db: db, provider: provider}
+}
+
+// SagaStore returns a saga store bound to the provider's connection
+func (p *Provider) SagaStore() *SagaStore {
+	return NewSagaStore(p.db, p)
+}
+
+// Migrate creates the saga tables if they do not exist
+func (s *SagaStore) Migrate(ctx context.Context) error {
+	return convertGormError(s.db.WithContext(ctx).AutoMigrate(&sagaRow{}, &sagaStepRow{}))
+}
+
+// Create starts a running saga with the given steps, in execution order
+func (s *SagaStore) Create(ctx context.Context, name, payload string, steps ...string) (*Saga, error) {
+	if len(steps) == 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "saga "+name+" needs at least one step")
+	}
+	seen := map[string]bool{}
+	for _, step := range steps {
+		if step == "" || seen[step] {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "saga "+name+" has an empty or duplicate step "+step)
+		}
+		seen[step] = true
+	}
+
+	row := sagaRow{ID: NewUUID().String(), Name: name, Status: SagaRunning, Payload: payload}
+	err := sessionDB(ctx, s.db, s.provider).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		stepRows := make([]sagaStepRow, len(steps))
+		for i, step := range steps {
+			stepRows[i] = sagaStepRow{SagaID: row.ID, Name: step, Position: i, Status: SagaStepPending}
+		}
+		return tx.Create(&stepRows).Error
+	})
+	if err != nil {
+		return nil, convertGormError(err)
+	}
+	return s.Get(ctx, row.ID)
+}
+
+// Get loads a saga with its steps
+func (s *SagaStore) Get(ctx context.Context, id string) (*Saga, error) {
+	sagas, err := s.load(sessionDB(ctx, s.db, s.provider).Where("id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	if len(sagas) == 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeNotFound, "saga not found: "+id)
+	}
+	return sagas[0], nil
+}
+
+// MarkStepDone records that step ran. Once every step is done the saga is
+// completed. Marking a done step again is a no-op; marking a step of a
+// saga that is no longer running fails.
+func (s *SagaStore) MarkStepDone(ctx context.Context, id, step string) error {
+	return s.update(ctx, id, func(tx *gorm.DB, saga *sagaRow) error {
+		if saga.Status != SagaRunning {
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "saga "+id+" is "+string(saga.Status)+", not running")
+		}
+		if err := s.setStep(tx, id, step, SagaStepPending, SagaStepDone); err != nil {
+			return err
+		}
+		return s.settle(tx, saga, SagaStepPending, SagaCompleted)
+	})
+}
+
+// Abort stops a running saga and starts compensating its finished steps,
+// recording reason. A saga without finished steps is compensated at once.
+func (s *SagaStore) Abort(ctx context.Context, id, reason string) error {
+	return s.update(ctx, id, func(tx *gorm.DB, saga *sagaRow) error {
+		switch saga.Status {
+		case SagaCompensating, SagaCompensated:
+			return nil
+		case SagaCompleted:
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "saga "+id+" is already completed")
+		}
+		saga.Status = SagaCompensating
+		saga.Error = reason
+		if err := tx.Model(&sagaRow{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"status": saga.Status, "error": reason, "updated_at": tx.NowFunc()}).Error; err != nil {
+			return err
+		}
+		return s.settle(tx, saga, SagaStepDone, SagaCompensated)
+	})
+}
+
+// MarkCompensated records that a done step was undone, moving a running
+// saga to compensating. Once no done steps remain the saga is compensated.
+func (s *SagaStore) MarkCompensated(ctx context.Context, id, step string) error {
+	return s.update(ctx, id, func(tx *gorm.DB, saga *sagaRow) error {
+		if saga.Status == SagaCompleted || saga.Status == SagaCompensated {
+			return gpa.NewError(gpa.ErrorTypeInvalidArgument, "saga "+id+" is already "+string(saga.Status))
+		}
+		if err := s.setStep(tx, id, step, SagaStepDone, SagaStepCompensated); err != nil {
+			return err
+		}
+		saga.Status = SagaCompensating
+		if err := tx.Model(&sagaRow{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"status": saga.Status, "updated_at": tx.NowFunc()}).Error; err != nil {
+			return err
+		}
+		return s.settle(tx, saga, SagaStepDone, SagaCompensated)
+	})
+}
+
+// LoadPending returns running and compensating sagas that have not changed
+// for idleFor, oldest first, so a recovery worker can resume or compensate
+// sagas whose orchestrator went away. A non-positive limit loads all.
+func (s *SagaStore) LoadPending(ctx context.Context, idleFor time.Duration, limit int) ([]*Saga, error) {
+	db := sessionDB(ctx, s.db, s.provider)
+	query := db.Where("status IN ?", []SagaStatus{SagaRunning, SagaCompensating}).
+		Where("updated_at <= ?", db.NowFunc().Add(-idleFor)).Order("updated_at")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	return s.load(query)
+}
+
+// update runs fn on the locked saga row in a transaction
+func (s *SagaStore) update(ctx context.Context, id string, fn func(tx *gorm.DB, saga *sagaRow) error) error {
+	err := sessionDB(ctx, s.db, s.provider).Transaction(func(tx *gorm.DB) error {
+		var saga sagaRow
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Take(&saga).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return gpa.NewError(gpa.ErrorTypeNotFound, "saga not found: "+id)
+			}
+			return err
+		}
+		return fn(tx, &saga)
+	})
+	return convertGormError(err)
+}
+
+// setStep moves step from one status to another; a step already in the
+// target status is left alone
+func (s *SagaStore) setStep(tx *gorm.DB, id, step string, from, to SagaStepStatus) error {
+	var row sagaStepRow
+	if err := tx.Where("saga_id = ? AND name = ?", id, step).Take(&row).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return gpa.NewError(gpa.ErrorTypeNotFound, "saga "+id+" has no step "+step)
+		}
+		return err
+	}
+	if row.Status == to {
+		return nil
+	}
+	if row.Status != from {
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "step "+step+" of saga "+id+" is "+string(row.Status)+", not "+string(from))
+	}
+	return tx.Model(&sagaStepRow{}).Where("saga_id = ? AND name = ?", id, step).
+		Updates(map[string]interface{}{"status": to, "updated_at": tx.NowFunc()}).Error
+}
+
+// settle moves the saga to final once no steps in status remain
+func (s *SagaStore) settle(tx *gorm.DB, saga *sagaRow, status SagaStepStatus, final SagaStatus) error {
+	var remaining int64
+	if err := tx.Model(&sagaStepRow{}).Where("saga_id = ? AND status = ?", saga.ID, status).Count(&remaining).Error; err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return tx.Model(&sagaRow{}).Where("id = ?", saga.ID).Update("updated_at", tx.NowFunc()).Error
+	}
+	saga.Status = final
+	return tx.Model(&sagaRow{}).Where("id = ?", saga.ID).
+		Updates(map[string]interface{}{"status": final, "updated_at": tx.NowFunc()}).Error
+}
+
+// load returns the sagas selected by query with their steps
+func (s *SagaStore) load(query *gorm.DB) ([]*Saga, error) {
+	var rows []sagaRow
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, convertGormError(err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(rows))
+	sagas := make([]*Saga, len(rows))
+	byID := make(map[string]*Saga, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+		sagas[i] = &Saga{ID: row.ID, Name: row.Name, Status: row.Status, Payload: row.Payload, Error: row.Error,
+			CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt}
+		byID[row.ID] = sagas[i]
+	}
+
+	var steps []sagaStepRow
+	if err := query.Session(&gorm.Session{NewDB: true}).Where("saga_id IN ?", ids).
+		Order("saga_id").Order("position").Find(&steps).Error; err != nil {
+		return nil, convertGormError(err)
+	}
+	for _, step := range steps {
+		saga := byID[step.SagaID]
+		saga.Steps = append(saga.Steps, SagaStep{Name: step.Name, Status: step.Status, UpdatedAt: step.UpdatedAt})
+	}
+	return sagas, nil
+}
@@ -0,0 +1,126 @@
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestSagaStore(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	sagas := provider.SagaStore()
+	if err := sagas.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	if _, err := sagas.Create(ctx, "order", "", "reserve", "reserve"); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for duplicate steps, got %v", err)
+	}
+	if _, err := sagas.Get(ctx, "missing"); !gpa.IsNotFound(err) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	saga, err := sagas.Create(ctx, "order", `{"order":42}`, "reserve", "charge", "ship")
+	if err != nil {
+		t.Fatalf("Failed to create saga: %v", err)
+	}
+	if saga.Status != SagaRunning || len(saga.Steps) != 3 || saga.Payload != `{"order":42}` {
+		t.Fatalf("Unexpected new saga: %+v", saga)
+	}
+	if next, ok := saga.NextStep(); !ok || next != "reserve" {
+		t.Errorf("Expected reserve to run first, got %q %v", next, ok)
+	}
+
+	if err := sagas.MarkStepDone(ctx, saga.ID, "reserve"); err != nil {
+		t.Fatalf("Failed to mark step done: %v", err)
+	}
+	if err := sagas.MarkStepDone(ctx, saga.ID, "reserve"); err != nil {
+		t.Errorf("Expected marking a done step again to be a no-op, got %v", err)
+	}
+	if err := sagas.MarkStepDone(ctx, saga.ID, "refund"); !gpa.IsNotFound(err) {
+		t.Errorf("Expected not found for an unknown step, got %v", err)
+	}
+	if err := sagas.MarkCompensated(ctx, saga.ID, "charge"); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected compensating a pending step to fail, got %v", err)
+	}
+
+	saga, _ = sagas.Get(ctx, saga.ID)
+	if next, ok := saga.NextStep(); !ok || next != "charge" {
+		t.Errorf("Expected charge to run next, got %q %v", next, ok)
+	}
+
+	// Only sagas idle for long enough are picked up for recovery
+	if pending, err := sagas.LoadPending(ctx, time.Hour, 0); err != nil || len(pending) != 0 {
+		t.Errorf("Expected no idle sagas, got %d %v", len(pending), err)
+	}
+	if pending, err := sagas.LoadPending(ctx, 0, 10); err != nil || len(pending) != 1 || pending[0].ID != saga.ID {
+		t.Errorf("Expected the running saga to be pending, got %v %v", pending, err)
+	}
+
+	if err := sagas.MarkStepDone(ctx, saga.ID, "charge"); err != nil {
+		t.Fatalf("Failed to mark step done: %v", err)
+	}
+	if err := sagas.Abort(ctx, saga.ID, "out of stock"); err != nil {
+		t.Fatalf("Failed to abort: %v", err)
+	}
+	if err := sagas.MarkStepDone(ctx, saga.ID, "ship"); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected steps of an aborted saga to be rejected, got %v", err)
+	}
+
+	saga, _ = sagas.Get(ctx, saga.ID)
+	if saga.Status != SagaCompensating || saga.Error != "out of stock" {
+		t.Errorf("Expected compensating saga, got %s %q", saga.Status, saga.Error)
+	}
+	if got := saga.Compensations(); !reflect.DeepEqual(got, []string{"charge", "reserve"}) {
+		t.Errorf("Expected compensations in reverse order, got %v", got)
+	}
+
+	for _, step := range saga.Compensations() {
+		if err := sagas.MarkCompensated(ctx, saga.ID, step); err != nil {
+			t.Fatalf("Failed to compensate %s: %v", step, err)
+		}
+	}
+	saga, _ = sagas.Get(ctx, saga.ID)
+	if saga.Status != SagaCompensated {
+		t.Errorf("Expected compensated saga, got %s", saga.Status)
+	}
+	if pending, err := sagas.LoadPending(ctx, 0, 0); err != nil || len(pending) != 0 {
+		t.Errorf("Expected no pending sagas, got %d %v", len(pending), err)
+	}
+}
+
+func TestSagaStoreCompletesAndAborts(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	sagas := provider.SagaStore()
+	if err := sagas.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	done, _ := sagas.Create(ctx, "signup", "", "account", "email")
+	sagas.MarkStepDone(ctx, done.ID, "account")
+	sagas.MarkStepDone(ctx, done.ID, "email")
+	if saga, _ := sagas.Get(ctx, done.ID); saga.Status != SagaCompleted {
+		t.Errorf("Expected completed saga, got %s", saga.Status)
+	}
+	if err := sagas.Abort(ctx, done.ID, "late"); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected aborting a completed saga to fail, got %v", err)
+	}
+
+	// Nothing ran, so there is nothing to compensate
+	aborted, _ := sagas.Create(ctx, "signup", "", "account", "email")
+	if err := sagas.Abort(ctx, aborted.ID, "invalid email"); err != nil {
+		t.Fatalf("Failed to abort: %v", err)
+	}
+	if saga, _ := sagas.Get(ctx, aborted.ID); saga.Status != SagaCompensated {
+		t.Errorf("Expected saga without done steps to be compensated, got %s", saga.Status)
+	}
+}
@@ -0,0 +1,186 @@
+// Package gpagorm provides startup validation of the schema against models
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// SchemaProblem is a difference between a model and its table that would
+// make queries or writes fail
+type SchemaProblem struct {
+	Table   string `json:"table"`
+	Column  string `json:"column,omitempty"`
+	Index   string `json:"index,omitempty"`
+	Problem string `json:"problem"`
+}
+
+// String formats the problem as "table.column: problem"
+func (p SchemaProblem) String() string {
+	target := p.Table
+	switch {
+	case p.Column != "":
+		target += "." + p.Column
+	case p.Index != "":
+		target += " index " + p.Index
+	}
+	return target + ": " + p.Problem
+}
+
+// SchemaValidationError lists every problem ValidateSchema found
+type SchemaValidationError struct {
+	Problems []SchemaProblem
+}
+
+// Error returns one line per problem
+func (e *SchemaValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		lines[i] = "  " + problem.String()
+	}
+	return fmt.Sprintf("schema validation failed with %d problem(s):\n%s", len(e.Problems), strings.Join(lines, "\n"))
+}
+
+// ValidateSchema confirms that the tables, columns and indexes of models
+// exist and that column types are compatible with their fields, so a
+// service can fail fast at boot instead of on its first query. Unlike
+// DetectDrift it only reports differences that break the models: missing
+// tables, columns and indexes, incompatible types, NOT NULL columns the
+// model may leave empty, and extra NOT NULL columns without a default. The
+// returned error is a *SchemaValidationError listing every problem. Set
+// the "validate_schema" gorm option to a []interface{} of models to run it
+// in NewProvider.
+func (p *Provider) ValidateSchema(ctx context.Context, models ...interface{}) error {
+	db := p.db.WithContext(ctx)
+	var problems []SchemaProblem
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return convertGormError(err)
+		}
+		tableProblems, err := validateTable(db, model, stmt.Schema, stmt.Table)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, tableProblems...)
+	}
+
+	if len(problems) > 0 {
+		return &SchemaValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateTable checks one model against its table
+func validateTable(db *gorm.DB, model interface{}, sch *schema.Schema, table string) ([]SchemaProblem, error) {
+	drift, err := detectTableDrift(db, model, sch, table)
+	if err != nil {
+		return nil, err
+	}
+	if drift.Missing {
+		return []SchemaProblem{{Table: table, Problem: "table does not exist"}}, nil
+	}
+
+	var problems []SchemaProblem
+	for _, column := range drift.MissingColumns {
+		problems = append(problems, SchemaProblem{Table: table, Column: column, Problem: "column does not exist"})
+	}
+	for _, index := range drift.MissingIndexes {
+		problems = append(problems, SchemaProblem{Table: table, Index: index, Problem: "index does not exist"})
+	}
+
+	columnTypes, err := db.Migrator().ColumnTypes(model)
+	if err != nil {
+		return nil, convertGormError(err)
+	}
+	for _, columnType := range columnTypes {
+		name := columnType.Name()
+		nullable, nullableKnown := columnType.Nullable()
+		_, hasDefault := columnType.DefaultValue()
+		field, ok := sch.FieldsByDBName[name]
+		if !ok {
+			if nullableKnown && !nullable && !hasDefault {
+				problems = append(problems, SchemaProblem{Table: table, Column: name,
+					Problem: "column is not in the model but is NOT NULL without a default, so inserts fail"})
+			}
+			continue
+		}
+		if field.IgnoreMigration {
+			continue
+		}
+
+		actual := strings.ToLower(columnType.DatabaseTypeName())
+		if !typeCompatible(field, actual) {
+			problems = append(problems, SchemaProblem{Table: table, Column: name,
+				Problem: fmt.Sprintf("column type %s is not compatible with %s field %s", actual, field.FieldType, field.Name)})
+		}
+		if nullableKnown && !nullable && !hasDefault && !field.NotNull && !field.PrimaryKey && !field.HasDefaultValue && fieldNullable(field) {
+			problems = append(problems, SchemaProblem{Table: table, Column: name,
+				Problem: fmt.Sprintf("column is NOT NULL but field %s can be nil", field.Name)})
+		}
+	}
+	return problems, nil
+}
+
+// fieldNullable reports whether a field can hold NULL
+func fieldNullable(field *schema.Field) bool {
+	return field.FieldType.Kind() == reflect.Ptr || strings.HasPrefix(field.FieldType.Name(), "Null")
+}
+
+// typeFamily classifies a database type name, or returns "" when unknown
+func typeFamily(typeName string) string {
+	switch {
+	case typeName == "bit" || strings.HasPrefix(typeName, "bool"):
+		return "bool"
+	case strings.Contains(typeName, "int") || strings.Contains(typeName, "serial"):
+		return "int"
+	case strings.Contains(typeName, "json"):
+		return "json"
+	case strings.Contains(typeName, "char") || strings.Contains(typeName, "text") || strings.Contains(typeName, "clob") ||
+		typeName == "uuid" || typeName == "uniqueidentifier" || typeName == "enum" || typeName == "set" ||
+		typeName == "string" || typeName == "name" || typeName == "xml":
+		return "string"
+	case strings.Contains(typeName, "real") || strings.Contains(typeName, "float") || strings.Contains(typeName, "double") ||
+		strings.Contains(typeName, "numeric") || strings.Contains(typeName, "decimal") || strings.Contains(typeName, "money"):
+		return "float"
+	case strings.Contains(typeName, "date") || strings.Contains(typeName, "time"):
+		return "time"
+	case strings.Contains(typeName, "blob") || strings.Contains(typeName, "binary") || typeName == "bytea" || typeName == "image":
+		return "bytes"
+	}
+	return ""
+}
+
+// compatibleFamilies lists the column type families each field type can
+// be read from and written to
+var compatibleFamilies = map[schema.DataType][]string{
+	schema.Bool:   {"bool", "int"},
+	schema.Int:    {"int"},
+	schema.Uint:   {"int"},
+	schema.Float:  {"float", "int"},
+	schema.String: {"string", "json"},
+	schema.Time:   {"time"},
+	schema.Bytes:  {"bytes", "json", "string"},
+}
+
+// typeCompatible reports whether a column of the live type can hold the
+// field. Custom field types and unknown column types are not checked.
+func typeCompatible(field *schema.Field, typeName string) bool {
+	families, ok := compatibleFamilies[field.GORMDataType]
+	family := typeFamily(typeName)
+	if !ok || family == "" {
+		return true
+	}
+	for _, f := range families {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+type TestValidatedOrder struct {
+	ID       uint   `gorm:"primaryKey"`
+	Number   string `gorm:"index"`
+	Total    float64
+	Quantity int
+	Note     *string
+}
+
+func (TestValidatedOrder) TableName() string { return "validated_orders" }
+
+func TestValidateSchema(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := provider.ValidateSchema(ctx, &TestUser{}); err != nil {
+		t.Fatalf("Expected migrated schema to validate, got %v", err)
+	}
+
+	err := provider.ValidateSchema(ctx, &TestValidatedOrder{})
+	var validation *SchemaValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("Expected a schema validation error, got %v", err)
+	}
+	if len(validation.Problems) != 1 || validation.Problems[0].Problem != "table does not exist" {
+		t.Fatalf("Expected a missing table, got %+v", validation.Problems)
+	}
+
+	// Create the table by hand with broken columns
+	if err := provider.db.Exec("CREATE TABLE validated_orders (id integer PRIMARY KEY, total text, quantity integer, note text NOT NULL, tenant_id integer NOT NULL)").Error; err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	err = provider.ValidateSchema(ctx, &TestValidatedOrder{})
+	if !errors.As(err, &validation) {
+		t.Fatalf("Expected a schema validation error, got %v", err)
+	}
+	report := err.Error()
+	for _, want := range []string{
+		"validated_orders.number: column does not exist",
+		"validated_orders index idx_validated_orders_number: index does not exist",
+		"validated_orders.total: column type text is not compatible",
+		"validated_orders.note: column is NOT NULL but field Note can be nil",
+		"validated_orders.tenant_id: column is not in the model but is NOT NULL",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "quantity") {
+		t.Errorf("Expected quantity to be compatible, got:\n%s", report)
+	}
+}
+
+func TestValidateSchemaAtStartup(t *testing.T) {
+	_, err := NewProvider(gpa.Config{
+		Driver:   "sqlite",
+		Database: ":memory:",
+		Options: map[string]interface{}{
+			"gorm": map[string]interface{}{
+				"validate_schema": []interface{}{&TestUser{}},
+			},
+		},
+	})
+	var validation *SchemaValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("Expected startup to fail schema validation, got %v", err)
+	}
+	if validation.Problems[0].Table != "test_users" {
+		t.Errorf("Expected test_users to be reported, got %+v", validation.Problems)
+	}
+}
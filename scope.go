@@ -0,0 +1,123 @@
+// Package gpagorm provides named, reusable query scopes
+package gpagorm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/lemmego/gpa"
+)
+
+// maxScopeDepth bounds scopes that refer to other scopes
+const maxScopeDepth = 8
+
+// ScopeFunc builds the options of a parameterized scope from the
+// arguments passed to Scope
+type ScopeFunc func(args ...interface{}) ([]gpa.QueryOption, error)
+
+// ScopeOption applies a scope registered for the queried entity. It
+// carries no state for gpa.Query; buildQuery expands it.
+type ScopeOption struct {
+	Name string
+	Args []interface{}
+}
+
+// Apply implements gpa.QueryOption
+func (o ScopeOption) Apply(query *gpa.Query) {}
+
+// Scope applies the scope registered under name, e.g.
+//
+//	repo.RegisterScope("active", gpa.Where("status", gpa.OpEqual, "active"))
+//	users, err := repo.Query(ctx, gpagorm.Scope("active"), gpa.Limit(10))
+//
+// Arguments are passed to scopes registered with RegisterScopeFunc.
+func Scope(name string, args ...interface{}) gpa.QueryOption {
+	return ScopeOption{Name: name, Args: args}
+}
+
+// RegisterScope registers opts under name for the repository's entity,
+// replacing any previous scope of that name. Scopes are kept on the
+// provider, so every repository of the entity shares them.
+func (r *Repository[T]) RegisterScope(name string, opts ...gpa.QueryOption) {
+	r.RegisterScopeFunc(name, func(args ...interface{}) ([]gpa.QueryOption, error) {
+		if len(args) > 0 {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("scope %s takes no arguments", name))
+		}
+		return opts, nil
+	})
+}
+
+// RegisterScopeFunc registers a parameterized scope for the repository's
+// entity:
+//
+//	repo.RegisterScopeFunc("olderThan", func(args ...interface{}) ([]gpa.QueryOption, error) {
+//	    return []gpa.QueryOption{gpa.Where("age", gpa.OpGreaterThan, args[0])}, nil
+//	})
+//	users, err := repo.Query(ctx, gpagorm.Scope("olderThan", 30))
+func (r *Repository[T]) RegisterScopeFunc(name string, fn ScopeFunc) {
+	if r.provider == nil {
+		return
+	}
+	r.provider.mu.Lock()
+	defer r.provider.mu.Unlock()
+
+	key := reflect.TypeOf((*T)(nil)).Elem()
+	if r.provider.scopes == nil {
+		r.provider.scopes = make(map[reflect.Type]map[string]ScopeFunc)
+	}
+	if r.provider.scopes[key] == nil {
+		r.provider.scopes[key] = make(map[string]ScopeFunc)
+	}
+	r.provider.scopes[key][name] = fn
+}
+
+// scope returns the scope registered for T under name, or nil
+func (r *Repository[T]) scope(name string) ScopeFunc {
+	if r.provider == nil {
+		return nil
+	}
+	r.provider.mu.RLock()
+	defer r.provider.mu.RUnlock()
+	return r.provider.scopes[reflect.TypeOf((*T)(nil)).Elem()][name]
+}
+
+// expandScopes replaces the scope options in opts with the options they
+// stand for, in place, so later options still override earlier ones
+func (r *Repository[T]) expandScopes(opts []gpa.QueryOption, depth int) ([]gpa.QueryOption, error) {
+	var expanded []gpa.QueryOption
+	for i, opt := range opts {
+		scope, ok := opt.(ScopeOption)
+		if !ok {
+			if expanded != nil {
+				expanded = append(expanded, opt)
+			}
+			continue
+		}
+		if depth >= maxScopeDepth {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "scopes nested too deeply at "+scope.Name)
+		}
+		if expanded == nil {
+			expanded = append([]gpa.QueryOption{}, opts[:i]...)
+		}
+
+		fn := r.scope(scope.Name)
+		if fn == nil {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "unknown scope: "+scope.Name)
+		}
+		scoped, err := fn(scope.Args...)
+		if err != nil {
+			if _, ok := err.(gpa.GPAError); ok {
+				return nil, err
+			}
+			return nil, gpa.NewErrorWithCause(gpa.ErrorTypeInvalidArgument, "scope "+scope.Name+" failed", err)
+		}
+		if scoped, err = r.expandScopes(scoped, depth+1); err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, scoped...)
+	}
+	if expanded == nil {
+		return opts, nil
+	}
+	return expanded, nil
+}
@@ -0,0 +1,60 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestScopes(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	for _, u := range []*TestUser{
+		{Name: "Alice", Email: "alice@example.com", Age: 30},
+		{Name: "Bob", Email: "bob@example.com", Age: 17},
+		{Name: "Carol", Email: "carol@example.com", Age: 45},
+	} {
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	repo.RegisterScope("adult", gpa.Where("age", gpa.OpGreaterThanOrEqual, 18))
+	repo.RegisterScopeFunc("olderThan", func(args ...interface{}) ([]gpa.QueryOption, error) {
+		return []gpa.QueryOption{Scope("adult"), gpa.Where("age", gpa.OpGreaterThan, args[0])}, nil
+	})
+
+	// Scopes are shared by repositories of the same entity
+	other := NewRepository[TestUser](provider.db, provider)
+	count, err := other.Count(ctx, Scope("adult"))
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 adults, got %d", count)
+	}
+
+	users, err := repo.Query(ctx, Scope("olderThan", 40), gpa.OrderBy("name", gpa.OrderAsc))
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(users) != 1 || users[0].Name != "Carol" {
+		t.Errorf("Expected Carol, got %+v", users)
+	}
+
+	if _, err := repo.Query(ctx, Scope("missing")); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for unknown scope, got %v", err)
+	}
+	if _, err := repo.Query(ctx, Scope("adult", 1)); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for unexpected scope arguments, got %v", err)
+	}
+
+	repo.RegisterScope("loop", Scope("loop"))
+	if _, err := repo.Query(ctx, Scope("loop")); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for recursive scope, got %v", err)
+	}
+}
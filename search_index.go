@@ -0,0 +1,200 @@
+// Package gpagorm provides search index synchronization after commits
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/lemmego/gpa"
+)
+
+// Indexer writes documents of entity type T to a search engine such as
+// Elasticsearch or Meilisearch
+type Indexer[T any] interface {
+	// Index adds or replaces the documents of entities
+	Index(ctx context.Context, entities []*T) error
+	// Delete removes the documents with the given primary keys
+	Delete(ctx context.Context, ids []interface{}) error
+}
+
+// SearchIndexConfig configures a SearchIndex
+type SearchIndexConfig struct {
+	// QueueSize bounds the number of changes waiting to be indexed
+	// (default 1024). Further changes are reported to OnError.
+	QueueSize int
+	// OnError receives the changes that could not be indexed. It is
+	// called from the indexing goroutine and must not block for long.
+	OnError func(change EntityChange, err error)
+}
+
+// SearchIndex keeps a search index in sync with the rows of entity type T.
+// Changes are queued once the writes making them have committed, so
+// rolled back writes never reach the index, and applied in the background
+// in commit order: created and updated rows are read back from the
+// database and indexed in their committed state, deleted rows are removed.
+type SearchIndex[T any] struct {
+	repo    *Repository[T]
+	indexer Indexer[T]
+	config  SearchIndexConfig
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan indexRequest
+	done   chan struct{}
+}
+
+// indexRequest is a queued change, or a flush marker when flushed is set
+type indexRequest struct {
+	ctx     context.Context
+	change  EntityChange
+	flushed chan struct{}
+}
+
+// RegisterIndexer starts keeping indexer in sync with the committed
+// writes to entity type T made through repositories of p. Writes through
+// repositories over transactions they do not manage, and raw SQL, are not
+// seen; Reindex catches up with them. Close stops the indexing.
+func RegisterIndexer[T any](p *Provider, indexer Indexer[T], config SearchIndexConfig) *SearchIndex[T] {
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1024
+	}
+	s := &SearchIndex[T]{
+		repo:    NewRepository[T](p.db, p),
+		indexer: indexer,
+		config:  config,
+		queue:   make(chan indexRequest, config.QueueSize),
+		done:    make(chan struct{}),
+	}
+	p.addChangeListener(reflect.TypeOf((*T)(nil)).Elem(), s.enqueue)
+	go s.run()
+	return s
+}
+
+// enqueue queues a committed change for indexing
+func (s *SearchIndex[T]) enqueue(ctx context.Context, change EntityChange) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- indexRequest{ctx: context.WithoutCancel(ctx), change: change}:
+	default:
+		s.report(change, gpa.NewError(gpa.ErrorTypeDatabase, "search index queue is full"))
+	}
+}
+
+// run applies queued changes until the index is closed
+func (s *SearchIndex[T]) run() {
+	defer close(s.done)
+	for request := range s.queue {
+		if request.flushed != nil {
+			close(request.flushed)
+			continue
+		}
+		if err := s.apply(request.ctx, request.change); err != nil {
+			s.report(request.change, err)
+		}
+	}
+}
+
+// apply writes one change to the index
+func (s *SearchIndex[T]) apply(ctx context.Context, change EntityChange) error {
+	if change.Kind == ChangeDeleted {
+		return s.indexer.Delete(ctx, change.IDs)
+	}
+
+	sch, err := s.repo.schema()
+	if err != nil {
+		return err
+	}
+	if sch.PrioritizedPrimaryField == nil {
+		return gpa.NewError(gpa.ErrorTypeUnsupported, "indexing needs a primary key on "+change.Entity)
+	}
+	var entities []*T
+	if err := s.repo.session(ctx).Where(map[string]interface{}{sch.PrioritizedPrimaryField.DBName: change.IDs}).Find(&entities).Error; err != nil {
+		return convertGormError(err)
+	}
+	if len(entities) > 0 {
+		if err := s.indexer.Index(ctx, entities); err != nil {
+			return err
+		}
+	}
+
+	// Rows deleted since the change committed leave the index too
+	if len(entities) < len(change.IDs) {
+		found := make(map[string]bool, len(entities))
+		for _, id := range s.repo.primaryKeys(ctx, entities) {
+			found[idKey(id)] = true
+		}
+		var missing []interface{}
+		for _, id := range change.IDs {
+			if !found[idKey(id)] {
+				missing = append(missing, id)
+			}
+		}
+		return s.indexer.Delete(ctx, missing)
+	}
+	return nil
+}
+
+// report passes a failed change to OnError
+func (s *SearchIndex[T]) report(change EntityChange, err error) {
+	if s.config.OnError != nil {
+		s.config.OnError(change, err)
+	}
+}
+
+// Reindex reads every row of T in batches of batchSize and indexes them,
+// to backfill a new index or repair one that missed writes. It returns the
+// number of rows indexed.
+func (s *SearchIndex[T]) Reindex(ctx context.Context, batchSize int) (int, error) {
+	indexed := 0
+	err := s.repo.FindInBatches(ctx, batchSize, func(batch []*T) error {
+		if err := s.indexer.Index(ctx, batch); err != nil {
+			return err
+		}
+		indexed += len(batch)
+		return nil
+	})
+	return indexed, err
+}
+
+// Flush waits until the changes queued so far have been indexed, or until
+// ctx ends
+func (s *SearchIndex[T]) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return nil
+	}
+	select {
+	case s.queue <- indexRequest{flushed: flushed}:
+		s.mu.RUnlock()
+	case <-ctx.Done():
+		s.mu.RUnlock()
+		return gpa.NewErrorWithCause(gpa.ErrorTypeTimeout, "operation cancelled", ctx.Err())
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return gpa.NewErrorWithCause(gpa.ErrorTypeTimeout, "operation cancelled", ctx.Err())
+	}
+}
+
+// Close stops accepting changes and waits until the queued ones have been
+// indexed
+func (s *SearchIndex[T]) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
@@ -0,0 +1,118 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+// memoryIndexer is an in-memory Indexer keyed by ID
+type memoryIndexer struct {
+	mu   sync.Mutex
+	docs map[uint]string
+}
+
+func (m *memoryIndexer) Index(ctx context.Context, users []*TestUser) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range users {
+		m.docs[u.ID] = u.Name
+	}
+	return nil
+}
+
+func (m *memoryIndexer) Delete(ctx context.Context, ids []interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.docs, id.(uint))
+	}
+	return nil
+}
+
+func (m *memoryIndexer) snapshot() map[uint]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	docs := make(map[uint]string, len(m.docs))
+	for id, name := range m.docs {
+		docs[id] = name
+	}
+	return docs
+}
+
+func TestSearchIndex(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	// Rows written before the indexer are backfilled
+	if err := repo.Create(ctx, &TestUser{Name: "Early", Email: "early@example.com"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	indexer := &memoryIndexer{docs: map[uint]string{}}
+	index := RegisterIndexer[TestUser](provider, indexer, SearchIndexConfig{
+		OnError: func(change EntityChange, err error) { t.Errorf("Indexing %+v failed: %v", change, err) },
+	})
+	defer index.Close()
+
+	if n, err := index.Reindex(ctx, 10); err != nil || n != 1 {
+		t.Fatalf("Reindex indexed %d rows: %v", n, err)
+	}
+
+	alice := &TestUser{Name: "Alice", Email: "alice@example.com"}
+	if err := repo.Create(ctx, alice); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := repo.UpdatePartial(ctx, alice.ID, map[string]interface{}{"name": "Alice B."}); err != nil {
+		t.Fatalf("UpdatePartial failed: %v", err)
+	}
+	if err := repo.CreateBatch(ctx, []*TestUser{{Name: "Bob", Email: "bob@example.com"}, {Name: "Carol", Email: "carol@example.com"}}); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if err := repo.DeleteByCondition(ctx, gpa.WhereCondition("name", gpa.OpEqual, "Bob")); err != nil {
+		t.Fatalf("DeleteByCondition failed: %v", err)
+	}
+
+	// Writes of a rolled back transaction never reach the index
+	rollback := errors.New("rollback")
+	err := repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		if err := tx.Create(ctx, &TestUser{Name: "Ghost", Email: "ghost@example.com"}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Expected the transaction to roll back, got %v", err)
+	}
+
+	// Committed transactions are indexed after the commit
+	if err := index.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	err = repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		if err := tx.Create(ctx, &TestUser{Name: "Dave", Email: "dave@example.com"}); err != nil {
+			return err
+		}
+		if len(indexer.snapshot()) != 3 {
+			t.Error("Expected nothing indexed before the commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	if err := index.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	got := fmt.Sprint(indexer.snapshot())
+	if want := fmt.Sprint(map[uint]string{1: "Early", 2: "Alice B.", 4: "Carol", 5: "Dave"}); got != want {
+		t.Errorf("Expected index %s, got %s", want, got)
+	}
+}
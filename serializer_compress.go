@@ -0,0 +1,162 @@
+// Package gpagorm provides compressing column serializers for large values
+package gpagorm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultCompressionThreshold is the minimum encoded size, in bytes, at
+// which the compressing serializers compress a value
+const DefaultCompressionThreshold = 256
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+func init() {
+	schema.RegisterSerializer("gzip", CompressSerializer{Algorithm: "gzip"})
+	schema.RegisterSerializer("zstd", CompressSerializer{Algorithm: "zstd"})
+}
+
+// CompressSerializer transparently compresses large string, []byte or
+// JSON-encoded fields. Values smaller than the threshold are stored as-is,
+// and stored values are recognized by their magic bytes, so existing
+// uncompressed rows keep reading correctly. The column should be binary
+// (bytea/blob). Enable it with `gorm:"serializer:gzip"` or
+// `gorm:"serializer:zstd"`, optionally with `compress_threshold:1024`.
+type CompressSerializer struct {
+	Algorithm string // "gzip" or "zstd"
+}
+
+// threshold returns the field's compression threshold
+func (s CompressSerializer) threshold(field *schema.Field) int {
+	if value, ok := field.TagSettings["COMPRESS_THRESHOLD"]; ok {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return DefaultCompressionThreshold
+}
+
+// Scan decompresses the column value into the field
+func (s CompressSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	target := field.ReflectValueOf(ctx, dst)
+	if dbValue == nil {
+		target.Set(reflect.Zero(field.FieldType))
+		return nil
+	}
+
+	var data []byte
+	switch v := dbValue.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("failed to decompress value: %#v", dbValue)
+	}
+
+	data, err := decompress(data)
+	if err != nil {
+		return err
+	}
+
+	switch field.FieldType.Kind() {
+	case reflect.String:
+		target.SetString(string(data))
+	case reflect.Slice:
+		if field.FieldType.Elem().Kind() == reflect.Uint8 {
+			target.SetBytes(data)
+			return nil
+		}
+		fallthrough
+	default:
+		value := reflect.New(field.FieldType)
+		if err := json.Unmarshal(data, value.Interface()); err != nil {
+			return err
+		}
+		target.Set(value.Elem())
+	}
+	return nil
+}
+
+// Value compresses the field value when it exceeds the threshold
+func (s CompressSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var data []byte
+	switch v := fieldValue.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		data = encoded
+	}
+
+	if len(data) < s.threshold(field) {
+		return data, nil
+	}
+
+	switch s.Algorithm {
+	case "zstd":
+		initZstd()
+		return zstdEncoder.EncodeAll(data, nil), nil
+	default:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+// decompress detects the compression format by magic bytes
+func decompress(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case bytes.HasPrefix(data, zstdMagic):
+		initZstd()
+		return zstdDecoder.DecodeAll(data, nil)
+	default:
+		return data, nil
+	}
+}
+
+// initZstd lazily creates the shared zstd encoder and decoder
+func initZstd() {
+	zstdOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil)
+		zstdDecoder, _ = zstd.NewReader(nil)
+	})
+}
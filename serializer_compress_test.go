@@ -0,0 +1,59 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type TestArticle struct {
+	ID      uint              `gorm:"primaryKey"`
+	Body    string            `gorm:"serializer:gzip;type:blob"`
+	Notes   []byte            `gorm:"serializer:zstd;type:blob;compress_threshold:8"`
+	Summary string            `gorm:"serializer:zstd;type:blob"`
+	Meta    map[string]string `gorm:"serializer:gzip;type:blob;compress_threshold:1"`
+}
+
+func TestCompressSerializer(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	if err := provider.db.AutoMigrate(&TestArticle{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[TestArticle](provider.db, provider)
+	ctx := context.Background()
+
+	article := &TestArticle{
+		Body:    strings.Repeat("lorem ipsum ", 500),
+		Notes:   []byte(strings.Repeat("n", 64)),
+		Summary: "short",
+		Meta:    map[string]string{"lang": "en"},
+	}
+	if err := repo.Create(ctx, article); err != nil {
+		t.Fatalf("Failed to create article: %v", err)
+	}
+
+	var sizes struct {
+		Body    int
+		Summary int
+	}
+	provider.db.Raw("SELECT length(body) AS body, length(summary) AS summary FROM test_articles").Scan(&sizes)
+	if sizes.Body >= len(article.Body) {
+		t.Errorf("Expected body to be compressed, stored %d bytes", sizes.Body)
+	}
+	if sizes.Summary != len("short") {
+		t.Errorf("Expected short summary stored uncompressed, got %d bytes", sizes.Summary)
+	}
+
+	found, err := repo.FindByID(ctx, article.ID)
+	if err != nil {
+		t.Fatalf("Failed to find article: %v", err)
+	}
+	if found.Body != article.Body || string(found.Notes) != string(article.Notes) || found.Summary != "short" {
+		t.Error("Expected fields to round-trip through compression")
+	}
+	if found.Meta["lang"] != "en" {
+		t.Errorf("Expected meta to round-trip, got %v", found.Meta)
+	}
+}
@@ -0,0 +1,85 @@
+// Package gpagorm provides protobuf column serializers
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("proto", ProtoSerializer{})
+	schema.RegisterSerializer("protojson", ProtoSerializer{JSON: true})
+}
+
+// ProtoSerializer stores proto.Message fields in a bytea/blob column using
+// the binary wire format, or as text with JSON set for debuggability.
+// Enable it with `gorm:"serializer:proto"` or `gorm:"serializer:protojson"`.
+type ProtoSerializer struct {
+	JSON bool
+}
+
+// Scan decodes the column value into the message field
+func (s ProtoSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	if field.FieldType.Kind() != reflect.Ptr {
+		return fmt.Errorf("proto serializer requires a pointer field, got %s", field.FieldType)
+	}
+
+	target := field.ReflectValueOf(ctx, dst)
+	if dbValue == nil {
+		target.Set(reflect.Zero(field.FieldType))
+		return nil
+	}
+
+	var data []byte
+	switch v := dbValue.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("failed to unmarshal proto value: %#v", dbValue)
+	}
+
+	msgValue := reflect.New(field.FieldType.Elem())
+	msg, ok := msgValue.Interface().(proto.Message)
+	if !ok {
+		return fmt.Errorf("field %s does not implement proto.Message", field.Name)
+	}
+
+	var err error
+	if s.JSON {
+		err = protojson.Unmarshal(data, msg)
+	} else {
+		err = proto.Unmarshal(data, msg)
+	}
+	if err != nil {
+		return err
+	}
+	target.Set(msgValue)
+	return nil
+}
+
+// Value encodes the message field for storage
+func (s ProtoSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	if fieldValue == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(fieldValue); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+
+	msg, ok := fieldValue.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("field %s does not implement proto.Message", field.Name)
+	}
+	if s.JSON {
+		data, err := protojson.Marshal(msg)
+		return string(data), err
+	}
+	return proto.Marshal(msg)
+}
@@ -0,0 +1,59 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type TestEnvelope struct {
+	ID      uint             `gorm:"primaryKey"`
+	Payload *structpb.Struct `gorm:"serializer:proto"`
+	Debug   *structpb.Struct `gorm:"serializer:protojson"`
+}
+
+func TestProtoSerializer(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	if err := provider.db.AutoMigrate(&TestEnvelope{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[TestEnvelope](provider.db, provider)
+	ctx := context.Background()
+
+	payload, _ := structpb.NewStruct(map[string]interface{}{"event": "created", "count": 3})
+	envelope := &TestEnvelope{Payload: payload, Debug: payload}
+	if err := repo.Create(ctx, envelope); err != nil {
+		t.Fatalf("Failed to create envelope: %v", err)
+	}
+	if err := repo.Create(ctx, &TestEnvelope{}); err != nil {
+		t.Fatalf("Failed to create empty envelope: %v", err)
+	}
+
+	found, err := repo.FindByID(ctx, envelope.ID)
+	if err != nil {
+		t.Fatalf("Failed to find envelope: %v", err)
+	}
+	if found.Payload.Fields["event"].GetStringValue() != "created" {
+		t.Errorf("Expected binary payload to round-trip, got %v", found.Payload)
+	}
+	if found.Debug.Fields["count"].GetNumberValue() != 3 {
+		t.Errorf("Expected JSON payload to round-trip, got %v", found.Debug)
+	}
+
+	var debug string
+	provider.db.Raw("SELECT debug FROM test_envelopes WHERE id = ?", envelope.ID).Scan(&debug)
+	if debug == "" || debug[0] != '{' {
+		t.Errorf("Expected JSON text in debug column, got %q", debug)
+	}
+
+	empty, err := repo.FindByID(ctx, envelope.ID+1)
+	if err != nil {
+		t.Fatalf("Failed to find empty envelope: %v", err)
+	}
+	if empty.Payload != nil {
+		t.Errorf("Expected nil payload, got %v", empty.Payload)
+	}
+}
@@ -0,0 +1,118 @@
+// Package gpagorm provides per-connection session initialization
+package gpagorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/lemmego/gpa"
+)
+
+// sessionInitStatements returns the "session_init" option: statements run
+// on every new pooled connection, as a list or a single statement
+func sessionInitStatements(config gpa.Config) ([]string, error) {
+	switch v := gormOptions(config)["session_init"].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		statements := make([]string, len(v))
+		for i, s := range v {
+			statement, ok := s.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid session_init statement: %v", s)
+			}
+			statements[i] = statement
+		}
+		return statements, nil
+	default:
+		return nil, fmt.Errorf("invalid session_init: %v", v)
+	}
+}
+
+// openSessionDB opens a pool over connector whose new connections first run
+// statements, so session settings such as time zones, search paths and SQL
+// modes hold on every connection of the pool
+func openSessionDB(connector driver.Connector, statements []string) *sql.DB {
+	if len(statements) == 0 {
+		return sql.OpenDB(connector)
+	}
+	return sql.OpenDB(&sessionInitConnector{Connector: connector, statements: statements})
+}
+
+// driverConnector returns a connector for dsn on the registered driver
+func driverConnector(driverName, dsn string) (driver.Connector, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	db.Close()
+	if dc, ok := d.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return dsnConnector{driver: d, dsn: dsn}, nil
+}
+
+// dsnConnector opens connections of a driver without a connector of its own
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+// Connect implements driver.Connector
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver implements driver.Connector
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sessionInitConnector runs statements on each connection it opens
+type sessionInitConnector struct {
+	driver.Connector
+	statements []string
+}
+
+// Connect implements driver.Connector
+func (c *sessionInitConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, statement := range c.statements {
+		if err := execOnConn(ctx, conn, statement); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("session_init %q failed: %w", statement, err)
+		}
+	}
+	return conn, nil
+}
+
+// execOnConn executes a statement without arguments on a driver connection
+func execOnConn(ctx context.Context, conn driver.Conn, statement string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, statement, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+	stmt, err := conn.Prepare(statement)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, nil)
+	} else {
+		_, err = stmt.Exec(nil)
+	}
+	return err
+}
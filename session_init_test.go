@@ -0,0 +1,59 @@
+package gpagorm
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestSessionInit(t *testing.T) {
+	config := gpa.Config{
+		Driver:   "sqlite",
+		Database: filepath.Join(t.TempDir(), "session.db"),
+		Options: map[string]interface{}{"gorm": map[string]interface{}{
+			"session_init": []interface{}{"PRAGMA busy_timeout = 1234", "PRAGMA foreign_keys = ON"},
+		}},
+	}
+	provider, err := NewProvider(config)
+	if err != nil {
+		t.Fatalf("NewProvider failed: %v", err)
+	}
+	defer provider.Close()
+
+	// Every pooled connection runs the statements, not only the first
+	ctx := context.Background()
+	sqlDB, _ := provider.db.DB()
+	for i := 0; i < 3; i++ {
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Conn failed: %v", err)
+		}
+		defer conn.Close()
+		var timeout, foreignKeys int
+		if err := conn.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&timeout); err != nil {
+			t.Fatalf("PRAGMA failed: %v", err)
+		}
+		conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys)
+		if timeout != 1234 || foreignKeys != 1 {
+			t.Errorf("Connection %d: expected session settings, got busy_timeout %d, foreign_keys %d", i, timeout, foreignKeys)
+		}
+	}
+}
+
+func TestSessionInitErrors(t *testing.T) {
+	config := gpa.Config{
+		Driver:   "sqlite",
+		Database: ":memory:",
+		Options:  map[string]interface{}{"gorm": map[string]interface{}{"session_init": "SET nonsense"}},
+	}
+	if _, err := NewProvider(config); err == nil {
+		t.Error("Expected a failing session_init statement to fail NewProvider")
+	}
+
+	config.Options = map[string]interface{}{"gorm": map[string]interface{}{"session_init": 42}}
+	if _, err := NewProvider(config); err == nil {
+		t.Error("Expected an invalid session_init option to fail NewProvider")
+	}
+}
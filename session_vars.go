@@ -0,0 +1,207 @@
+// Package gpagorm provides session variable propagation for row-level security
+package gpagorm
+
+import (
+	"context"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+type sessionVarsKey struct{}
+
+type sessionTxKey struct{}
+
+// sessionTx is a transaction opened to scope session variables
+type sessionTx struct {
+	provider *Provider
+	db       *gorm.DB
+}
+
+// SessionVarsResolver extracts session variables from a context, e.g.
+// mapping an authenticated user stored by middleware to "app.current_user_id".
+type SessionVarsResolver func(ctx context.Context) map[string]string
+
+// WithSessionVars returns a context carrying session variables that are
+// applied with SET LOCAL semantics at the start of every repository
+// operation, so Postgres row-level security policies can read them with
+// current_setting(). Variables are merged with those already on ctx.
+func WithSessionVars(ctx context.Context, vars map[string]string) context.Context {
+	merged := make(map[string]string, len(vars))
+	for name, value := range SessionVarsFromContext(ctx) {
+		merged[name] = value
+	}
+	for name, value := range vars {
+		merged[name] = value
+	}
+	return context.WithValue(ctx, sessionVarsKey{}, merged)
+}
+
+// SessionVarsFromContext returns the session variables stored on ctx
+func SessionVarsFromContext(ctx context.Context) map[string]string {
+	vars, _ := ctx.Value(sessionVarsKey{}).(map[string]string)
+	return vars
+}
+
+// SetSessionVarsResolver registers a resolver whose variables are applied
+// in addition to those set with WithSessionVars. Context values win on conflict.
+func (p *Provider) SetSessionVarsResolver(resolver SessionVarsResolver) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sessionVarsResolver = resolver
+}
+
+// sessionVars collects the session variables for ctx
+func (p *Provider) sessionVars(ctx context.Context) map[string]string {
+	p.mu.RLock()
+	resolver := p.sessionVarsResolver
+	p.mu.RUnlock()
+
+	vars := make(map[string]string)
+	if resolver != nil {
+		for name, value := range resolver(ctx) {
+			vars[name] = value
+		}
+	}
+	if label := connectionLabelFromContext(ctx); label != "" {
+		vars["application_name"] = operationApplicationName(p.applicationName, label)
+	}
+	for name, value := range SessionVarsFromContext(ctx) {
+		vars[name] = value
+	}
+	return vars
+}
+
+// session returns the database handle for ctx, preferring a session
+// transaction opened by withSessionVars on the same provider
+func (r *Repository[T]) session(ctx context.Context) *gorm.DB {
+	db := sessionDB(ctx, r.db, r.provider)
+	if r.table != "" {
+		db = withQualifiedTable(db, r.table)
+	}
+	return db
+}
+
+// sessionDB returns the session transaction on ctx for provider, or db
+func sessionDB(ctx context.Context, db *gorm.DB, provider *Provider) *gorm.DB {
+	if tx, ok := ctx.Value(sessionTxKey{}).(*sessionTx); ok && tx.provider == provider && !inTransaction(db) {
+		return tx.db.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// ownsSessionVars lists the operations that are not wrapped in a session
+// variable transaction, because they open their own transactions and
+// apply the variables there: a transaction with its options, and a batch
+// committing each chunk on its own
+var ownsSessionVars = map[Operation]bool{
+	OperationTransaction:               true,
+	OperationCreateBatchBeforeDeadline: true,
+}
+
+// applyTxSessionVars sets the session variables of ctx on tx, a
+// transaction opened by the operation itself
+func (p *Provider) applyTxSessionVars(ctx context.Context, tx *gorm.DB) error {
+	if p == nil || dialectName(tx) != "postgres" {
+		return nil
+	}
+	vars := p.sessionVars(ctx)
+	if len(vars) == 0 {
+		return nil
+	}
+	return applySessionVars(tx, vars)
+}
+
+// cursorSessionTx opens the session variable transaction of ctx ahead of
+// an operation returning a cursor, which must outlive the operation: the
+// transaction is shared through the returned context, and finish commits
+// it once the cursor is closed, or rolls it back when err is set. Without
+// session variables, or inside a transaction, finish is nil.
+func (r *Repository[T]) cursorSessionTx(ctx context.Context) (_ context.Context, finish func(err error) error, _ error) {
+	if r.provider == nil || dialectName(r.db) != "postgres" {
+		return ctx, nil, nil
+	}
+	if _, ok := ctx.Value(sessionTxKey{}).(*sessionTx); ok {
+		return ctx, nil, nil
+	}
+	db := r.session(ctx)
+	vars := r.provider.sessionVars(ctx)
+	if len(vars) == 0 || inTransaction(db) {
+		return ctx, nil, nil
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		return ctx, nil, convertGormError(tx.Error)
+	}
+	if err := applySessionVars(tx, vars); err != nil {
+		tx.Rollback()
+		return ctx, nil, err
+	}
+	shared := tx.Table("").Session(&gorm.Session{})
+	finish = func(err error) error {
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		return convertGormError(tx.Commit().Error)
+	}
+	return context.WithValue(ctx, sessionTxKey{}, &sessionTx{provider: r.provider, db: shared}), finish, nil
+}
+
+// withSessionVars runs fn with the context's session variables applied.
+// Outside a transaction a short transaction is opened so SET LOCAL
+// semantics hold; inside one the variables are set on it directly.
+// Session variables are only supported on Postgres and ignored elsewhere.
+func (r *Repository[T]) withSessionVars(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r.provider == nil || dialectName(r.db) != "postgres" {
+		return fn(ctx)
+	}
+	if _, ok := ctx.Value(sessionTxKey{}).(*sessionTx); ok {
+		return fn(ctx)
+	}
+	vars := r.provider.sessionVars(ctx)
+	if len(vars) == 0 {
+		return fn(ctx)
+	}
+
+	db := r.session(ctx)
+	if inTransaction(db) {
+		if err := applySessionVars(db, vars); err != nil {
+			return err
+		}
+		return fn(ctx)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := applySessionVars(tx, vars); err != nil {
+			return err
+		}
+		// Share the transaction without this repository's table override
+		shared := tx.Table("").Session(&gorm.Session{})
+		return fn(context.WithValue(ctx, sessionTxKey{}, &sessionTx{provider: r.provider, db: shared}))
+	})
+}
+
+// applySessionVars sets each variable local to the current transaction
+func applySessionVars(db *gorm.DB, vars map[string]string) error {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		// set_config(..., true) is the parameterizable form of SET LOCAL
+		if err := db.Exec("SELECT set_config(?, ?, true)", name, vars[name]).Error; err != nil {
+			return convertGormError(err)
+		}
+	}
+	return nil
+}
+
+// inTransaction reports whether db is bound to an open transaction
+func inTransaction(db *gorm.DB) bool {
+	committer, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok && committer != nil
+}
@@ -0,0 +1,36 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSessionVarsPrecedence(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	provider.SetSessionVarsResolver(func(ctx context.Context) map[string]string {
+		return map[string]string{"app.tenant_id": "resolver", "app.role": "reader"}
+	})
+
+	ctx := WithSessionVars(context.Background(), map[string]string{"app.current_user_id": "42"})
+	ctx = WithSessionVars(ctx, map[string]string{"app.tenant_id": "context"})
+
+	vars := provider.sessionVars(ctx)
+	expected := map[string]string{
+		"app.current_user_id": "42",
+		"app.tenant_id":       "context",
+		"app.role":            "reader",
+	}
+	for name, value := range expected {
+		if vars[name] != value {
+			t.Errorf("Expected %s='%s', got '%s'", name, value, vars[name])
+		}
+	}
+
+	// Non-Postgres dialects ignore session variables
+	repo := NewRepository[TestUser](provider.db, provider)
+	if err := repo.Create(ctx, &TestUser{Name: "John", Email: "john@example.com"}); err != nil {
+		t.Errorf("Expected create to ignore session vars on SQLite, got %v", err)
+	}
+}
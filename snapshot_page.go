@@ -0,0 +1,192 @@
+// Package gpagorm provides offset pagination pinned to a snapshot boundary
+package gpagorm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/lemmego/gpa"
+)
+
+// SnapshotPage is one page of a snapshot pagination
+type SnapshotPage[T any] struct {
+	Items     []*T
+	NextToken string // Token of the next page, empty after the last page
+}
+
+// SnapshotFieldOption names the field whose maximum bounds a snapshot
+// pagination. It carries no state for gpa.Query; QuerySnapshotPage reads
+// it.
+type SnapshotFieldOption struct {
+	Field string
+}
+
+// Apply implements gpa.QueryOption
+func (o SnapshotFieldOption) Apply(query *gpa.Query) {}
+
+// SnapshotField bounds a snapshot pagination by field, such as a creation
+// timestamp, instead of the primary key. The field must only grow for new
+// rows.
+func SnapshotField(field string) gpa.QueryOption {
+	return SnapshotFieldOption{Field: field}
+}
+
+// snapshotToken is the decoded state of a snapshot page token
+type snapshotToken struct {
+	Field    string          `json:"f"`
+	Boundary json.RawMessage `json:"b"`
+	Offset   int             `json:"o"`
+}
+
+// QuerySnapshotPage returns a page of at most pageSize entities matching
+// opts. The first page, requested with an empty token, captures the
+// largest primary key among the matching rows; every later page, requested
+// with the previous page's NextToken, only reads rows up to that boundary,
+// so rows inserted while a client pages through the results cannot shift
+// rows between pages:
+//
+//	page, err := repo.QuerySnapshotPage(ctx, "", 50, gpa.Where("status", gpa.OpEqual, "open"))
+//	// ...
+//	page, err = repo.QuerySnapshotPage(ctx, page.NextToken, 50, gpa.Where("status", gpa.OpEqual, "open"))
+//
+// Pass the same options for every page. Rows are ordered by the boundary
+// field unless opts order them; gpa.Limit and gpa.Offset are replaced by
+// the page. SnapshotField bounds the pagination by another field; rows
+// where it is NULL are skipped. Deleted rows still shift later pages.
+func (r *Repository[T]) QuerySnapshotPage(ctx context.Context, token string, pageSize int, opts ...gpa.QueryOption) (page *SnapshotPage[T], err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationQuerySnapshotPage, Options: opts}, func(ctx context.Context) error {
+		var err error
+		page, err = r.querySnapshotPage(ctx, token, pageSize, opts...)
+		return err
+	})
+	return page, err
+}
+
+// querySnapshotPage implements QuerySnapshotPage
+func (r *Repository[T]) querySnapshotPage(ctx context.Context, token string, pageSize int, opts ...gpa.QueryOption) (*SnapshotPage[T], error) {
+	if pageSize <= 0 {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "page size must be positive")
+	}
+	if err := r.authorizeRead(ctx, opts); err != nil {
+		return nil, err
+	}
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	fieldName := ""
+	if s.PrioritizedPrimaryField != nil {
+		fieldName = s.PrioritizedPrimaryField.DBName
+	}
+	var filters []gpa.QueryOption
+	ordered := false
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case SnapshotFieldOption:
+			fieldName = o.Field
+			continue
+		case gpa.LimitOption, gpa.OffsetOption:
+			continue
+		case gpa.OrderOption, SortOption:
+			ordered = true
+		}
+		filters = append(filters, opt)
+	}
+	if fieldName == "" {
+		return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, s.Name+" has no primary key to bound a snapshot; use SnapshotField")
+	}
+	field := s.LookUpField(fieldName)
+	if field == nil {
+		return nil, &FieldValidationError{Field: fieldName, Reason: "field is not a column of " + s.Name}
+	}
+
+	state := snapshotToken{Field: field.DBName}
+	var boundary interface{}
+	if token == "" {
+		entity, err := r.snapshotBoundary(ctx, field.DBName, filters)
+		if err != nil || entity == nil {
+			return &SnapshotPage[T]{}, err
+		}
+		boundary, _ = field.ValueOf(ctx, reflect.ValueOf(entity).Elem())
+		if state.Boundary, err = json.Marshal(boundary); err != nil {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("cannot encode snapshot boundary %v: %v", boundary, err))
+		}
+	} else {
+		if state, err = decodeSnapshotToken(token); err != nil {
+			return nil, err
+		}
+		if state.Field != field.DBName {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "snapshot token was issued for field "+state.Field)
+		}
+		value := reflect.New(field.FieldType)
+		if err := json.Unmarshal(state.Boundary, value.Interface()); err != nil {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "invalid snapshot token")
+		}
+		boundary = value.Elem().Interface()
+	}
+
+	pageOpts := append(filters, gpa.Where(field.DBName, gpa.OpLessThanOrEqual, boundary))
+	if !ordered {
+		pageOpts = append(pageOpts, gpa.OrderBy(field.DBName, gpa.OrderAsc))
+	}
+	// One extra row tells whether another page follows
+	pageOpts = append(pageOpts, gpa.Limit(pageSize+1), gpa.Offset(state.Offset))
+
+	var entities []*T
+	if err := convertGormError(r.buildQuery(ctx, pageOpts...).Find(&entities).Error); err != nil {
+		return nil, err
+	}
+	page := &SnapshotPage[T]{Items: entities}
+	if len(entities) > pageSize {
+		page.Items = entities[:pageSize]
+		state.Offset += pageSize
+		page.NextToken = encodeSnapshotToken(state)
+	}
+	return page, nil
+}
+
+// snapshotBoundary returns the matching entity with the largest non-NULL
+// value of field, or nil when there is none
+func (r *Repository[T]) snapshotBoundary(ctx context.Context, field string, filters []gpa.QueryOption) (*T, error) {
+	var opts []gpa.QueryOption
+	for _, opt := range filters {
+		switch opt.(type) {
+		case gpa.OrderOption, SortOption, gpa.FieldsOption:
+			continue
+		}
+		opts = append(opts, opt)
+	}
+	opts = append(opts,
+		gpa.Where(field, gpa.OpIsNotNull, nil),
+		gpa.OrderBy(field, gpa.OrderDesc),
+		gpa.Limit(1))
+
+	var entities []*T
+	if err := convertGormError(r.buildQuery(ctx, opts...).Find(&entities).Error); err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 {
+		return nil, nil
+	}
+	return entities[0], nil
+}
+
+// encodeSnapshotToken returns the opaque token of state
+func encodeSnapshotToken(state snapshotToken) string {
+	data, _ := json.Marshal(state)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSnapshotToken parses a token returned by encodeSnapshotToken
+func decodeSnapshotToken(token string) (snapshotToken, error) {
+	var state snapshotToken
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(data, &state) != nil || state.Offset < 0 || len(state.Boundary) == 0 {
+		return snapshotToken{}, gpa.NewError(gpa.ErrorTypeInvalidArgument, "invalid snapshot token")
+	}
+	return state, nil
+}
@@ -0,0 +1,121 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestQuerySnapshotPage(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		if err := repo.Create(ctx, &TestUser{Name: fmt.Sprintf("User%d", i), Email: fmt.Sprintf("user%d@example.com", i), Age: 20 + i}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	names := func(page *SnapshotPage[TestUser]) []string {
+		var names []string
+		for _, user := range page.Items {
+			names = append(names, user.Name)
+		}
+		return names
+	}
+
+	page, err := repo.QuerySnapshotPage(ctx, "", 2)
+	if err != nil {
+		t.Fatalf("QuerySnapshotPage failed: %v", err)
+	}
+	if fmt.Sprint(names(page)) != "[User1 User2]" || page.NextToken == "" {
+		t.Fatalf("Unexpected first page %v (token %q)", names(page), page.NextToken)
+	}
+
+	// Rows inserted mid-pagination stay out of the snapshot
+	if err := repo.Create(ctx, &TestUser{Name: "Late", Email: "late@example.com", Age: 40}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	var all []string
+	all = append(all, names(page)...)
+	for page.NextToken != "" {
+		if page, err = repo.QuerySnapshotPage(ctx, page.NextToken, 2); err != nil {
+			t.Fatalf("QuerySnapshotPage failed: %v", err)
+		}
+		all = append(all, names(page)...)
+	}
+	if fmt.Sprint(all) != "[User1 User2 User3 User4 User5]" {
+		t.Errorf("Expected the snapshot rows, got %v", all)
+	}
+
+	// Conditions and ordering carry over to every page
+	opts := []gpa.QueryOption{gpa.Where("age", gpa.OpGreaterThan, 22), gpa.OrderBy("age", gpa.OrderDesc)}
+	page, err = repo.QuerySnapshotPage(ctx, "", 2, opts...)
+	if err != nil || fmt.Sprint(names(page)) != "[Late User5]" {
+		t.Fatalf("Unexpected ordered page %v (%v)", names(page), err)
+	}
+	if page, err = repo.QuerySnapshotPage(ctx, page.NextToken, 2, opts...); err != nil || fmt.Sprint(names(page)) != "[User4 User3]" || page.NextToken != "" {
+		t.Errorf("Unexpected last page %v (token %q, %v)", names(page), page.NextToken, err)
+	}
+
+	// An empty result has no pages
+	page, err = repo.QuerySnapshotPage(ctx, "", 2, gpa.Where("age", gpa.OpGreaterThan, 100))
+	if err != nil || len(page.Items) != 0 || page.NextToken != "" {
+		t.Errorf("Expected an empty page, got %v (%v)", names(page), err)
+	}
+}
+
+func TestQuerySnapshotPageField(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	for _, age := range []int{30, 10, 20} {
+		if err := repo.Create(ctx, &TestUser{Name: fmt.Sprintf("Age%d", age), Email: fmt.Sprintf("age%d@example.com", age), Age: age}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	page, err := repo.QuerySnapshotPage(ctx, "", 2, SnapshotField("age"))
+	if err != nil || len(page.Items) != 2 || page.Items[0].Age != 10 || page.Items[1].Age != 20 {
+		t.Fatalf("Unexpected first page %v (%v)", page, err)
+	}
+	repo.Create(ctx, &TestUser{Name: "Age40", Email: "age40@example.com", Age: 40})
+	if page, err = repo.QuerySnapshotPage(ctx, page.NextToken, 2, SnapshotField("age")); err != nil || len(page.Items) != 1 || page.Items[0].Age != 30 {
+		t.Errorf("Expected the snapshot's last row only, got %v (%v)", page, err)
+	}
+}
+
+func TestQuerySnapshotPageInvalid(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		repo.Create(ctx, &TestUser{Name: fmt.Sprintf("User%d", i), Email: fmt.Sprintf("user%d@example.com", i)})
+	}
+	page, err := repo.QuerySnapshotPage(ctx, "", 1)
+	if err != nil {
+		t.Fatalf("QuerySnapshotPage failed: %v", err)
+	}
+
+	if _, err := repo.QuerySnapshotPage(ctx, "not a token", 1); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for a malformed token, got %v", err)
+	}
+	if _, err := repo.QuerySnapshotPage(ctx, page.NextToken, 1, SnapshotField("age")); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for a token of another field, got %v", err)
+	}
+	if _, err := repo.QuerySnapshotPage(ctx, "", 0); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for page size 0, got %v", err)
+	}
+	if _, err := repo.QuerySnapshotPage(ctx, "", 1, SnapshotField("missing")); err == nil {
+		t.Error("Expected an error for an unknown snapshot field")
+	}
+}
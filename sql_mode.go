@@ -0,0 +1,134 @@
+// Package gpagorm provides MySQL sql_mode configuration and verification
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/lemmego/gpa"
+)
+
+// StrictSQLMode is the sql_mode the "sql_mode" option value "strict"
+// stands for: writes fail instead of truncating values, storing zero
+// dates or dividing by zero
+const StrictSQLMode = "STRICT_ALL_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION"
+
+var sqlModePattern = regexp.MustCompile(`^[A-Za-z_]*(,[A-Za-z_]+)*$`)
+
+// combinedSQLModes are the modes MySQL expands combination modes into
+var combinedSQLModes = map[string][]string{
+	"ANSI":        {"REAL_AS_FLOAT", "PIPES_AS_CONCAT", "ANSI_QUOTES", "IGNORE_SPACE", "ONLY_FULL_GROUP_BY"},
+	"TRADITIONAL": {"STRICT_TRANS_TABLES", "STRICT_ALL_TABLES", "NO_ZERO_IN_DATE", "NO_ZERO_DATE", "ERROR_FOR_DIVISION_BY_ZERO", "NO_ENGINE_SUBSTITUTION"},
+}
+
+// sqlModeOption returns the "sql_mode" option and whether it is set
+func sqlModeOption(gormOpts map[string]interface{}) (string, bool, error) {
+	value, ok := gormOpts["sql_mode"]
+	if !ok {
+		return "", false, nil
+	}
+	mode, ok := value.(string)
+	if !ok {
+		return "", false, fmt.Errorf("invalid sql_mode: %v", value)
+	}
+	if strings.EqualFold(mode, "strict") {
+		mode = StrictSQLMode
+	}
+	mode = strings.ToUpper(strings.ReplaceAll(mode, " ", ""))
+	if !sqlModePattern.MatchString(mode) {
+		return "", false, fmt.Errorf("invalid sql_mode: %q", mode)
+	}
+	return mode, true, nil
+}
+
+// mysqlSessionInit prepends setting the configured sql_mode to the
+// session init statements
+func mysqlSessionInit(config gpa.Config, sessionInit []string) ([]string, error) {
+	mode, ok, err := sqlModeOption(gormOptions(config))
+	if err != nil || !ok {
+		return sessionInit, err
+	}
+	return append([]string{"SET SESSION sql_mode = '" + mode + "'"}, sessionInit...), nil
+}
+
+// sqlModes returns the individual modes of a sql_mode value, with
+// combination modes expanded
+func sqlModes(mode string) []string {
+	var modes []string
+	for _, m := range strings.Split(strings.ToUpper(mode), ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		if expanded, ok := combinedSQLModes[m]; ok {
+			modes = append(modes, expanded...)
+		} else {
+			modes = append(modes, m)
+		}
+	}
+	return modes
+}
+
+// CheckSQLMode compares the MySQL session sql_mode with the configured
+// "sql_mode" option and returns an error naming the configured modes the
+// session lacks and the modes it has beyond them. It returns nil on other
+// databases or without the option.
+func (p *Provider) CheckSQLMode(ctx context.Context) error {
+	if !p.sqlModeSet || dialectName(p.db) != "mysql" {
+		return nil
+	}
+	var actual string
+	if err := p.db.WithContext(ctx).Raw("SELECT @@SESSION.sql_mode").Scan(&actual).Error; err != nil {
+		return convertGormError(err)
+	}
+
+	have := map[string]bool{}
+	for _, m := range sqlModes(actual) {
+		have[m] = true
+	}
+	want := map[string]bool{}
+	var missing, extra []string
+	for _, m := range sqlModes(p.sqlMode) {
+		want[m] = true
+		if !have[m] {
+			missing = append(missing, m)
+		}
+	}
+	for _, m := range sqlModes(actual) {
+		if !want[m] {
+			extra = append(extra, m)
+		}
+	}
+	var diffs []string
+	if len(missing) > 0 {
+		diffs = append(diffs, "missing "+strings.Join(missing, ","))
+	}
+	if len(extra) > 0 {
+		diffs = append(diffs, "unexpected "+strings.Join(extra, ","))
+	}
+	if len(diffs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("database session sql_mode %q differs from configured %q: %s",
+		actual, p.sqlMode, strings.Join(diffs, "; "))
+}
+
+// verifySQLMode runs CheckSQLMode at startup, warning on mismatch or
+// failing when Options["gorm"]["sql_mode_strict"] is true
+func (p *Provider) verifySQLMode(gormOpts map[string]interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHealthCheckTimeout)
+	defer cancel()
+
+	err := p.CheckSQLMode(ctx)
+	if err == nil {
+		return nil
+	}
+	if strict, _ := gormOpts["sql_mode_strict"].(bool); strict {
+		return err
+	}
+	slog.Default().Warn("sql_mode mismatch", slog.String("error", err.Error()))
+	return nil
+}
@@ -0,0 +1,74 @@
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/mysql"
+)
+
+func TestSQLModeOption(t *testing.T) {
+	mode, ok, err := sqlModeOption(map[string]interface{}{"sql_mode": "strict"})
+	if err != nil || !ok || mode != StrictSQLMode {
+		t.Errorf("Expected strict to stand for StrictSQLMode, got %q, %v, %v", mode, ok, err)
+	}
+	mode, ok, err = sqlModeOption(map[string]interface{}{"sql_mode": ""})
+	if err != nil || !ok || mode != "" {
+		t.Errorf("Expected an empty sql_mode to be set, got %q, %v, %v", mode, ok, err)
+	}
+	if _, ok, _ := sqlModeOption(map[string]interface{}{}); ok {
+		t.Error("Expected no sql_mode without the option")
+	}
+	if _, _, err := sqlModeOption(map[string]interface{}{"sql_mode": "STRICT_ALL_TABLES'; DROP TABLE users; --"}); err == nil {
+		t.Error("Expected an invalid sql_mode to be rejected")
+	}
+
+	want := []string{"STRICT_TRANS_TABLES", "STRICT_ALL_TABLES", "NO_ZERO_IN_DATE", "NO_ZERO_DATE",
+		"ERROR_FOR_DIVISION_BY_ZERO", "NO_ENGINE_SUBSTITUTION", "ONLY_FULL_GROUP_BY"}
+	if got := sqlModes("traditional,ONLY_FULL_GROUP_BY"); !reflect.DeepEqual(got, want) {
+		t.Errorf("sqlModes() = %v, want %v", got, want)
+	}
+
+	config := gpa.Config{Driver: "mysql", Host: "localhost", Port: 3306, Database: "app",
+		Options: map[string]interface{}{"gorm": map[string]interface{}{"sql_mode": "strict"}}}
+	dialector, err := openDialector(config, "")
+	if err != nil {
+		t.Fatalf("openDialector failed: %v", err)
+	}
+	if d := dialector.(*mysql.Dialector); d.Conn == nil {
+		t.Error("Expected connections that set sql_mode on connect")
+	}
+}
+
+func TestCheckSQLMode(t *testing.T) {
+	cassette := &Cassette{Dialect: "mysql", ServerVersion: "8.0.36", Interactions: []Interaction{
+		{Query: "SELECT @@SESSION.sql_mode", Columns: []string{"@@SESSION.sql_mode"},
+			Rows: [][]interface{}{{"STRICT_ALL_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION"}}},
+		{Query: "SELECT @@SESSION.sql_mode", Columns: []string{"@@SESSION.sql_mode"},
+			Rows: [][]interface{}{{"ONLY_FULL_GROUP_BY,NO_ENGINE_SUBSTITUTION"}}},
+	}}
+	provider, err := NewReplayProvider(cassette)
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+	provider.sqlMode, provider.sqlModeSet = StrictSQLMode, true
+	ctx := context.Background()
+
+	if err := provider.CheckSQLMode(ctx); err != nil {
+		t.Errorf("Expected matching modes, got %v", err)
+	}
+	err = provider.CheckSQLMode(ctx)
+	if err == nil || !strings.Contains(err.Error(), "missing STRICT_ALL_TABLES") || !strings.Contains(err.Error(), "unexpected ONLY_FULL_GROUP_BY") {
+		t.Errorf("Expected the differing modes to be named, got %v", err)
+	}
+
+	sqliteProvider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	sqliteProvider.sqlMode, sqliteProvider.sqlModeSet = StrictSQLMode, true
+	if err := sqliteProvider.CheckSQLMode(ctx); err != nil {
+		t.Errorf("Expected other databases to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+// Package gpagorm provides backup and restore for SQLite databases
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// backupFilePrefix names the files written by ScheduleBackup
+const backupFilePrefix = "backup-"
+
+// restoreSchema is the schema name a backup is attached under while restoring
+const restoreSchema = "gpagorm_restore"
+
+// requireSQLite rejects SQLite-only operations on other drivers
+func (p *Provider) requireSQLite(op string) error {
+	if dialect := dialectName(p.db); dialect != "sqlite" {
+		return gpa.NewError(gpa.ErrorTypeUnsupported, op+" is only supported on sqlite, not "+dialect)
+	}
+	return nil
+}
+
+// BackupTo writes a consistent copy of the SQLite database to path with
+// VACUUM INTO, which reads a single snapshot and so is safe under write
+// load. The copy is written next to path and renamed into place, so path
+// is never left half written.
+func (p *Provider) BackupTo(ctx context.Context, path string) error {
+	if err := p.requireSQLite("BackupTo"); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to create backup file", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	// VACUUM INTO refuses to overwrite an existing file
+	os.Remove(tmpPath)
+	defer os.Remove(tmpPath)
+
+	if err := p.db.WithContext(ctx).Exec("VACUUM INTO ?", tmpPath).Error; err != nil {
+		return convertGormError(err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeInternal, "failed to move backup into place", err)
+	}
+	return nil
+}
+
+// RestoreFrom replaces the contents of the SQLite database with the backup
+// at path: tables, rows, indexes, views and triggers. The restore runs in
+// one transaction on a single connection, so readers see either the old or
+// the restored data. With ":memory:" databases only that connection's
+// database is restored.
+func (p *Provider) RestoreFrom(ctx context.Context, path string) error {
+	if err := p.requireSQLite("RestoreFrom"); err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return gpa.NewErrorWithCause(gpa.ErrorTypeNotFound, "backup not found", err)
+	}
+
+	err := p.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("ATTACH DATABASE ? AS "+restoreSchema, path).Error; err != nil {
+			return err
+		}
+		defer conn.Exec("DETACH DATABASE " + restoreSchema)
+		return conn.Transaction(restoreTables)
+	})
+	return convertGormError(err)
+}
+
+// sqliteObject is a row of sqlite_master
+type sqliteObject struct {
+	Type string
+	Name string
+	SQL  string `gorm:"column:sql"`
+}
+
+// restoreTables recreates the main schema from the attached backup
+func restoreTables(tx *gorm.DB) error {
+	if err := tx.Exec("PRAGMA defer_foreign_keys = ON").Error; err != nil {
+		return err
+	}
+
+	var current []sqliteObject
+	if err := tx.Raw("SELECT type, name, sql FROM main.sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%'").Scan(&current).Error; err != nil {
+		return err
+	}
+	for _, obj := range current {
+		// Indexes and triggers go with their tables
+		if err := tx.Exec(fmt.Sprintf("DROP %s IF EXISTS main.%s", strings.ToUpper(obj.Type), quoteSQLiteIdent(obj.Name))).Error; err != nil {
+			return err
+		}
+	}
+
+	var objects []sqliteObject
+	if err := tx.Raw("SELECT type, name, sql FROM " + restoreSchema + ".sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 ELSE 2 END").Scan(&objects).Error; err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err := tx.Exec(obj.SQL).Error; err != nil {
+			return fmt.Errorf("recreate %s %s: %w", obj.Type, obj.Name, err)
+		}
+		if obj.Type != "table" {
+			continue
+		}
+		name := quoteSQLiteIdent(obj.Name)
+		if err := tx.Exec(fmt.Sprintf("INSERT INTO main.%s SELECT * FROM %s.%s", name, restoreSchema, name)).Error; err != nil {
+			return fmt.Errorf("restore rows of %s: %w", obj.Name, err)
+		}
+	}
+
+	// Carry AUTOINCREMENT counters over so new IDs do not reuse old ones
+	var hasSequence int64
+	tx.Raw("SELECT COUNT(*) FROM " + restoreSchema + ".sqlite_master WHERE name = 'sqlite_sequence'").Scan(&hasSequence)
+	if hasSequence > 0 {
+		if err := tx.Exec("DELETE FROM main.sqlite_sequence").Error; err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO main.sqlite_sequence SELECT * FROM " + restoreSchema + ".sqlite_sequence").Error
+	}
+	return nil
+}
+
+// quoteSQLiteIdent quotes a SQLite identifier
+func quoteSQLiteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// ScheduleBackup writes a backup into dir every interval until ctx is done,
+// keeping the newest keep files (all of them when keep <= 0). Failures are
+// logged and retried on the next tick.
+func (p *Provider) ScheduleBackup(ctx context.Context, interval time.Duration, dir string, keep int) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := p.backupRotate(ctx, dir, keep); err != nil && ctx.Err() == nil {
+					slog.Default().LogAttrs(context.Background(), slog.LevelWarn, "gpagorm: scheduled backup failed",
+						slog.String("dir", dir), slog.String("error", err.Error()))
+				}
+			}
+		}
+	}()
+}
+
+// backupRotate writes a timestamped backup into dir and prunes old ones
+func (p *Provider) backupRotate(ctx context.Context, dir string, keep int) error {
+	name := backupFilePrefix + time.Now().UTC().Format("20060102T150405.000000000Z") + ".db"
+	if err := p.BackupTo(ctx, filepath.Join(dir, name)); err != nil {
+		return err
+	}
+	if keep <= 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(filepath.Join(dir, backupFilePrefix+"*.db"))
+	if err != nil {
+		return err
+	}
+	// Timestamps sort lexically, oldest first
+	sort.Strings(backups)
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package gpagorm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestSQLiteBackupRestore(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	sqlDB, _ := provider.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	for _, name := range []string{"alice", "bob"} {
+		if err := repo.Create(ctx, &TestUser{Name: name, Email: name + "@example.com"}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "app.db")
+	if err := provider.BackupTo(ctx, path); err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+	// A second backup replaces the first
+	if err := provider.BackupTo(ctx, path); err != nil {
+		t.Fatalf("Failed to overwrite backup: %v", err)
+	}
+
+	backup, err := NewProvider(gpa.Config{Driver: "sqlite", Database: path})
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	count, err := NewRepository[TestUser](backup.db, backup).Count(ctx)
+	backup.Close()
+	if err != nil || count != 2 {
+		t.Fatalf("Expected 2 users in the backup, got %d %v", count, err)
+	}
+
+	// Diverge, then restore
+	if err := repo.Create(ctx, &TestUser{Name: "carol", Email: "carol@example.com"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := provider.db.Exec("CREATE TABLE scratch (id INTEGER)").Error; err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if err := provider.RestoreFrom(ctx, path); err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+
+	users, err := repo.FindAll(ctx, gpa.OrderBy("id", gpa.OrderAsc))
+	if err != nil || len(users) != 2 || users[1].Name != "bob" {
+		t.Fatalf("Expected the backed up users after restore, got %v %v", users, err)
+	}
+	if provider.db.Migrator().HasTable("scratch") {
+		t.Error("Expected tables missing from the backup to be dropped")
+	}
+	if err := repo.Create(ctx, &TestUser{Name: "alice2", Email: "alice@example.com"}); err == nil {
+		t.Error("Expected the unique index to be restored")
+	}
+	user := &TestUser{Name: "dave", Email: "dave@example.com"}
+	if err := repo.Create(ctx, user); err != nil || user.ID != 3 {
+		t.Errorf("Expected the next ID to follow the backup, got %d %v", user.ID, err)
+	}
+
+	if err := provider.RestoreFrom(ctx, filepath.Join(t.TempDir(), "missing.db")); !gpa.IsNotFound(err) {
+		t.Errorf("Expected a not found error for a missing backup, got %v", err)
+	}
+}
+
+func TestScheduleBackup(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	sqlDB, _ := provider.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	provider.ScheduleBackup(ctx, 5*time.Millisecond, dir, 2)
+
+	waitFor(t, func() bool {
+		matches, _ := filepath.Glob(filepath.Join(dir, backupFilePrefix+"*.db"))
+		return len(matches) == 2
+	})
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+
+	entries, _ := os.ReadDir(dir)
+	backups := 0
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == ".db" {
+			backups++
+		}
+	}
+	if backups > 2 {
+		t.Errorf("Expected at most 2 backups to be kept, got %d", backups)
+	}
+}
@@ -0,0 +1,179 @@
+// Package gpagorm provides custom SQL functions for SQLite connections
+package gpagorm
+
+import (
+	"container/list"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	sqlite "github.com/glebarez/go-sqlite"
+)
+
+// SQLiteFunc implements a scalar SQL function for SQLite
+type SQLiteFunc func(args []driver.Value) (driver.Value, error)
+
+// sqliteRegexpCacheSize bounds the compiled patterns kept for REGEXP
+const sqliteRegexpCacheSize = 256
+
+var (
+	sqliteBuiltinsOnce sync.Once
+	sqliteRegexpCache  = newRegexpCache(sqliteRegexpCacheSize)
+)
+
+// regexpCache keeps the most recently used compiled patterns
+type regexpCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // Most recently used first
+	entries map[string]*list.Element
+}
+
+// regexpCacheEntry is a compiled pattern in a regexpCache
+type regexpCacheEntry struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func newRegexpCache(size int) *regexpCache {
+	return &regexpCache{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// compile returns the compiled pattern, compiling and caching it on a miss
+// and evicting the least recently used pattern when the cache is full
+func (c *regexpCache) compile(pattern string) (*regexp.Regexp, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(elem)
+		c.mu.Unlock()
+		return elem.Value.(*regexpCacheEntry).re, nil
+	}
+	c.mu.Unlock()
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*regexpCacheEntry).re, nil
+	}
+	c.entries[pattern] = c.order.PushFront(&regexpCacheEntry{pattern: pattern, re: re})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*regexpCacheEntry).pattern)
+	}
+	return re, nil
+}
+
+// RegisterSQLiteFunction registers a scalar function for every SQLite
+// connection opened afterwards, so it must be called before NewProvider.
+// nArgs of -1 makes the function variadic. Deterministic functions may be
+// used in indexes and CHECK constraints.
+//
+// REGEXP, uuid(), gen_random_uuid() and soundex() are registered
+// automatically, matching the Postgres behaviour repositories rely on.
+func RegisterSQLiteFunction(name string, nArgs int, deterministic bool, fn SQLiteFunc) error {
+	xFunc := func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		return fn(args)
+	}
+	if deterministic {
+		return sqlite.RegisterDeterministicScalarFunction(name, int32(nArgs), xFunc)
+	}
+	return sqlite.RegisterScalarFunction(name, int32(nArgs), xFunc)
+}
+
+// registerSQLiteBuiltins registers the default functions once per process.
+// Names the application registered first are left alone.
+func registerSQLiteBuiltins() {
+	sqliteBuiltinsOnce.Do(func() {
+		RegisterSQLiteFunction("regexp", 2, true, sqliteRegexp)
+		RegisterSQLiteFunction("soundex", 1, true, sqliteSoundex)
+		RegisterSQLiteFunction("uuid", 0, false, sqliteUUID)
+		RegisterSQLiteFunction("gen_random_uuid", 0, false, sqliteUUID)
+	})
+}
+
+// sqliteRegexp implements "X REGEXP Y", which SQLite calls as regexp(Y, X)
+func sqliteRegexp(args []driver.Value) (driver.Value, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	re, err := sqliteRegexpCache.compile(sqliteText(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString(sqliteText(args[1])), nil
+}
+
+// sqliteUUID returns a random version 4 UUID string
+func sqliteUUID(args []driver.Value) (driver.Value, error) {
+	return NewUUID().String(), nil
+}
+
+// sqliteSoundex returns the American Soundex code of its argument
+func sqliteSoundex(args []driver.Value) (driver.Value, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	return Soundex(sqliteText(args[0])), nil
+}
+
+// Soundex returns the four character American Soundex code of s, or "?000"
+// when s contains no letters, matching SQLite's built-in soundex()
+func Soundex(s string) string {
+	codes := map[byte]byte{
+		'B': '1', 'F': '1', 'P': '1', 'V': '1',
+		'C': '2', 'G': '2', 'J': '2', 'K': '2', 'Q': '2', 'S': '2', 'X': '2', 'Z': '2',
+		'D': '3', 'T': '3',
+		'L': '4',
+		'M': '5', 'N': '5',
+		'R': '6',
+	}
+
+	upper := strings.ToUpper(s)
+	start := strings.IndexFunc(upper, func(r rune) bool { return r >= 'A' && r <= 'Z' })
+	if start < 0 {
+		return "?000"
+	}
+
+	result := []byte{upper[start]}
+	last := codes[upper[start]]
+	for i := start + 1; i < len(upper) && len(result) < 4; i++ {
+		c := upper[i]
+		if c < 'A' || c > 'Z' {
+			continue
+		}
+		code, ok := codes[c]
+		switch {
+		case ok && code != last:
+			result = append(result, code)
+			last = code
+		case !ok && c != 'H' && c != 'W':
+			// Vowels separate repeated codes; H and W do not
+			last = 0
+		}
+	}
+	for len(result) < 4 {
+		result = append(result, '0')
+	}
+	return string(result)
+}
+
+// sqliteText converts a function argument to a string
+func sqliteText(value driver.Value) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,102 @@
+package gpagorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// sqliteTestFunctionSeq names test functions uniquely, as registrations
+// last for the process and tests may run more than once
+var sqliteTestFunctionSeq atomic.Int64
+
+func TestSoundex(t *testing.T) {
+	tests := map[string]string{
+		"Robert":   "R163",
+		"Rupert":   "R163",
+		"Tymczak":  "T522",
+		"Pfister":  "P236",
+		"Ashcraft": "A261",
+		"Honeyman": "H555",
+		"Lee":      "L000",
+		"123":      "?000",
+	}
+	for in, want := range tests {
+		if got := Soundex(in); got != want {
+			t.Errorf("Soundex(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSQLiteFunctions(t *testing.T) {
+	reverse := fmt.Sprintf("reverse_text_%d", sqliteTestFunctionSeq.Add(1))
+	if err := RegisterSQLiteFunction(reverse, 1, true, func(args []driver.Value) (driver.Value, error) {
+		runes := []rune(sqliteText(args[0]))
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	}); err != nil {
+		t.Fatalf("Failed to register function: %v", err)
+	}
+
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+	for _, user := range []*TestUser{
+		{Name: "Robert", Email: "robert@example.com", Age: 40},
+		{Name: "Alice", Email: "alice@test.org", Age: 30},
+	} {
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	var names []string
+	provider.db.Raw("SELECT name FROM test_users WHERE email REGEXP ?", `@example\.com$`).Scan(&names)
+	if len(names) != 1 || names[0] != "Robert" {
+		t.Errorf("Expected REGEXP to match Robert, got %v", names)
+	}
+
+	names = nil
+	provider.db.Raw("SELECT name FROM test_users WHERE soundex(name) = soundex(?)", "Rupert").Scan(&names)
+	if len(names) != 1 || names[0] != "Robert" {
+		t.Errorf("Expected soundex to match Robert, got %v", names)
+	}
+
+	var id string
+	provider.db.Raw("SELECT uuid()").Scan(&id)
+	if _, err := ParseUUID(id); err != nil {
+		t.Errorf("Expected uuid() to return a UUID, got %q", id)
+	}
+
+	var reversed string
+	provider.db.Raw("SELECT " + reverse + "('abc')").Scan(&reversed)
+	if reversed != "cba" {
+		t.Errorf("Expected custom function result, got %q", reversed)
+	}
+
+	if err := RegisterSQLiteFunction(reverse, 1, true, nil); err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("Expected duplicate registration to fail, got %v", err)
+	}
+}
+
+func TestSQLiteRegexpCacheIsBounded(t *testing.T) {
+	cache := newRegexpCache(2)
+	for _, pattern := range []string{"a", "b", "a", "c"} {
+		if _, err := cache.compile(pattern); err != nil {
+			t.Fatalf("compile(%q) failed: %v", pattern, err)
+		}
+	}
+	if len(cache.entries) != 2 || cache.entries["b"] != nil || cache.entries["a"] == nil {
+		t.Errorf("Expected the least recently used pattern to be evicted, got %v", cache.entries)
+	}
+	if _, err := cache.compile("("); err == nil || len(cache.entries) != 2 {
+		t.Errorf("Expected an invalid pattern to fail without being cached, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+// Package gpagorm provides cumulative statement counters and snapshots of
+// them for query budgets
+package gpagorm
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const statementTotalsStartKey = "gpagorm:statement_totals_start"
+
+// statementTotals counts every statement a provider runs, on the primary
+// and on replicas
+type statementTotals struct {
+	queries     atomic.Int64
+	errors      atomic.Int64
+	rowsRead    atomic.Int64
+	rowsWritten atomic.Int64
+	dbTime      atomic.Int64 // Nanoseconds
+}
+
+// register installs the counting callbacks on every GORM processor of db
+func (t *statementTotals) register(db *gorm.DB) error {
+	start := func(db *gorm.DB) {
+		db.InstanceSet(statementTotalsStartKey, time.Now())
+	}
+	finish := func(write bool) func(db *gorm.DB) {
+		return func(db *gorm.DB) {
+			if db.DryRun {
+				return
+			}
+			started, ok := db.InstanceGet(statementTotalsStartKey)
+			if !ok || db.Statement.SQL.Len() == 0 {
+				return
+			}
+			t.queries.Add(1)
+			t.dbTime.Add(int64(time.Since(started.(time.Time))))
+			if db.Error != nil {
+				t.errors.Add(1)
+			}
+			if db.RowsAffected > 0 {
+				if write {
+					t.rowsWritten.Add(db.RowsAffected)
+				} else {
+					t.rowsRead.Add(db.RowsAffected)
+				}
+			}
+		}
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("*").Register("gpagorm:statement_totals_start", start),
+		callbacks.Create().After("*").Register("gpagorm:statement_totals_finish", finish(true)),
+		callbacks.Query().Before("*").Register("gpagorm:statement_totals_start", start),
+		callbacks.Query().After("*").Register("gpagorm:statement_totals_finish", finish(false)),
+		callbacks.Update().Before("*").Register("gpagorm:statement_totals_start", start),
+		callbacks.Update().After("*").Register("gpagorm:statement_totals_finish", finish(true)),
+		callbacks.Delete().Before("*").Register("gpagorm:statement_totals_start", start),
+		callbacks.Delete().After("*").Register("gpagorm:statement_totals_finish", finish(true)),
+		callbacks.Row().Before("*").Register("gpagorm:statement_totals_start", start),
+		callbacks.Row().After("*").Register("gpagorm:statement_totals_finish", finish(false)),
+		callbacks.Raw().Before("*").Register("gpagorm:statement_totals_start", start),
+		callbacks.Raw().After("*").Register("gpagorm:statement_totals_finish", finish(true)),
+	)
+}
+
+// StatsSnapshot holds a provider's cumulative statement counters at one
+// point in time. Compare two snapshots with DiffSnapshots.
+type StatsSnapshot struct {
+	Taken       time.Time     `json:"taken"`
+	Queries     int64         `json:"queries"`      // Statements executed
+	Errors      int64         `json:"errors"`       // Statements that returned an error
+	RowsRead    int64         `json:"rows_read"`    // Rows loaded by queries
+	RowsWritten int64         `json:"rows_written"` // Rows affected by inserts, updates, deletes and Exec
+	DBTime      time.Duration `json:"db_time"`      // Time spent running statements
+}
+
+// StatsDiff is the activity between two snapshots
+type StatsDiff struct {
+	Elapsed     time.Duration `json:"elapsed"` // Wall time between the snapshots
+	Queries     int64         `json:"queries"`
+	Errors      int64         `json:"errors"`
+	RowsRead    int64         `json:"rows_read"`
+	RowsWritten int64         `json:"rows_written"`
+	DBTime      time.Duration `json:"db_time"`
+}
+
+// StatsSnapshot returns the statement counters of the provider, counted
+// since it was opened on the primary and replicas alike, so load tests and
+// benchmarks can assert query budgets per scenario:
+//
+//	before := provider.StatsSnapshot()
+//	checkout(ctx)
+//	if diff := gpagorm.DiffSnapshots(before, provider.StatsSnapshot()); diff.Queries > 12 {
+//	    t.Errorf("checkout issued %d queries", diff.Queries)
+//	}
+//
+// Counters are always on, unlike the "query_stats" metrics. Rows read
+// through Rows or Row are not counted, as the driver does not report them.
+func (p *Provider) StatsSnapshot() StatsSnapshot {
+	return StatsSnapshot{
+		Taken:       time.Now(),
+		Queries:     p.totals.queries.Load(),
+		Errors:      p.totals.errors.Load(),
+		RowsRead:    p.totals.rowsRead.Load(),
+		RowsWritten: p.totals.rowsWritten.Load(),
+		DBTime:      time.Duration(p.totals.dbTime.Load()),
+	}
+}
+
+// DiffSnapshots returns the activity from snapshot a to the later
+// snapshot b. Statements running concurrently, such as other tests
+// sharing the provider, are included.
+func DiffSnapshots(a, b StatsSnapshot) StatsDiff {
+	return StatsDiff{
+		Elapsed:     b.Taken.Sub(a.Taken),
+		Queries:     b.Queries - a.Queries,
+		Errors:      b.Errors - a.Errors,
+		RowsRead:    b.RowsRead - a.RowsRead,
+		RowsWritten: b.RowsWritten - a.RowsWritten,
+		DBTime:      b.DBTime - a.DBTime,
+	}
+}
@@ -0,0 +1,50 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+func TestStatsSnapshot(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	before := provider.StatsSnapshot()
+	users := []*TestUser{
+		{Name: "Ann", Email: "ann@example.com", Age: 30},
+		{Name: "Bob", Email: "bob@example.com", Age: 40},
+		{Name: "Cid", Email: "cid@example.com", Age: 50},
+	}
+	if err := repo.CreateBatch(ctx, users); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if _, err := repo.Query(ctx, gpa.Where("age", gpa.OpGreaterThan, 35)); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if err := repo.UpdatePartial(ctx, users[0].ID, map[string]interface{}{"age": 31}); err != nil {
+		t.Fatalf("UpdatePartial failed: %v", err)
+	}
+	repo.Query(ctx, gpa.Where("missing_column", gpa.OpEqual, 1))
+
+	diff := DiffSnapshots(before, provider.StatsSnapshot())
+	got := fmt.Sprintf("queries=%d errors=%d read=%d written=%d", diff.Queries, diff.Errors, diff.RowsRead, diff.RowsWritten)
+	if want := "queries=4 errors=1 read=2 written=4"; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+	if diff.DBTime <= 0 || diff.Elapsed < diff.DBTime {
+		t.Errorf("Expected DB time within the elapsed time, got %s of %s", diff.DBTime, diff.Elapsed)
+	}
+
+	// Dry runs execute nothing
+	before = provider.StatsSnapshot()
+	provider.db.ToSQL(func(tx *gorm.DB) *gorm.DB { return tx.Find(&[]*TestUser{}) })
+	if diff := DiffSnapshots(before, provider.StatsSnapshot()); diff.Queries != 0 {
+		t.Errorf("Expected no queries for a dry run, got %d", diff.Queries)
+	}
+}
@@ -0,0 +1,147 @@
+// Package gpagorm provides subquery conditions built from repositories or
+// raw SQL
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// SubQuery is a SELECT built by a repository or written as raw SQL, for
+// use inside the conditions of another repository's query
+type SubQuery struct {
+	db   *gorm.DB      // Query built by a repository
+	sql  string        // Raw SELECT, when db is nil
+	args []interface{} // Arguments of sql
+}
+
+// SubQuery returns a subquery selecting column from the rows of T that
+// opts match, honouring the repository's table, scopes and read policy:
+//
+//	admins := adminRepo.SubQuery(ctx, "user_id", gpa.Where("active", gpa.OpEqual, true))
+//	posts, err := postRepo.Query(ctx, gpagorm.WhereInSubQuery("author_id", admins))
+//
+// An empty column selects 1, for EXISTS. The subquery is embedded into
+// the outer statement, so both repositories must use the same database.
+func (r *Repository[T]) SubQuery(ctx context.Context, column string, opts ...gpa.QueryOption) *SubQuery {
+	db := r.buildQuery(ctx, opts...)
+	if err := r.authorizeRead(ctx, opts); err != nil {
+		db.AddError(err)
+	}
+	if column == "" {
+		return &SubQuery{db: db.Model(new(T)).Select("1")}
+	}
+	expr, err := fieldExpr(db, column)
+	if err != nil {
+		db.AddError(err)
+		return &SubQuery{db: db}
+	}
+	return &SubQuery{db: db.Model(new(T)).Select(expr)}
+}
+
+// RawSubQuery returns a subquery written as SQL, with values passed as
+// args. Like RawCondition, the SQL is embedded as is, so it must not be
+// built from user input.
+func RawSubQuery(sql string, args ...interface{}) *SubQuery {
+	return &SubQuery{sql: sql, args: args}
+}
+
+// expr returns the parenthesized subquery and its arguments
+func (s *SubQuery) expr() (string, []interface{}, error) {
+	switch {
+	case s == nil || (s.db == nil && strings.TrimSpace(s.sql) == ""):
+		return "", nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "subquery condition needs a subquery")
+	case s.db == nil:
+		return "(" + s.sql + ")", s.args, nil
+	case s.db.Error != nil:
+		return "", nil, s.db.Error
+	default:
+		return "(?)", []interface{}{s.db}, nil
+	}
+}
+
+// SubQueryCondition compares a field with the rows of a SubQuery, as in
+// user_id IN (SELECT id FROM admins WHERE active = true), for Op
+// gpa.OpInSubQuery or gpa.OpNotInSubQuery, or tests whether the subquery
+// has rows, for gpa.OpExists or gpa.OpNotExists, leaving FieldName empty.
+type SubQueryCondition struct {
+	FieldName string
+	Op        gpa.Operator
+	Query     *SubQuery
+}
+
+// Field returns the compared field
+func (c SubQueryCondition) Field() string { return c.FieldName }
+
+// Operator returns the comparison operator
+func (c SubQueryCondition) Operator() gpa.Operator { return c.Op }
+
+// Value returns the subquery
+func (c SubQueryCondition) Value() interface{} { return c.Query }
+
+// String describes the condition
+func (c SubQueryCondition) String() string {
+	if c.FieldName == "" {
+		return fmt.Sprintf("%s (subquery)", c.Op)
+	}
+	return fmt.Sprintf("%s %s (subquery)", c.FieldName, c.Op)
+}
+
+// WhereInSubQuery filters on field being among the rows of sub
+func WhereInSubQuery(field string, sub *SubQuery) gpa.QueryOption {
+	return gpa.ConditionOption{Condition: SubQueryCondition{FieldName: field, Op: gpa.OpInSubQuery, Query: sub}}
+}
+
+// WhereNotInSubQuery filters on field not being among the rows of sub.
+// As with NOT IN, a NULL among the rows matches nothing.
+func WhereNotInSubQuery(field string, sub *SubQuery) gpa.QueryOption {
+	return gpa.ConditionOption{Condition: SubQueryCondition{FieldName: field, Op: gpa.OpNotInSubQuery, Query: sub}}
+}
+
+// WhereExists filters on sub returning rows. Correlate it with the outer
+// query through a condition naming the outer table:
+//
+//	logins := auditRepo.SubQuery(ctx, "",
+//	    gpa.Where("action", gpa.OpEqual, "login"),
+//	    gpagorm.RawCondition("audit_entries.user_id = users.id"))
+//	users, err := userRepo.Query(ctx, gpagorm.WhereExists(logins))
+func WhereExists(sub *SubQuery) gpa.QueryOption {
+	return gpa.ConditionOption{Condition: SubQueryCondition{Op: gpa.OpExists, Query: sub}}
+}
+
+// WhereNotExists filters on sub returning no rows
+func WhereNotExists(sub *SubQuery) gpa.QueryOption {
+	return gpa.ConditionOption{Condition: SubQueryCondition{Op: gpa.OpNotExists, Query: sub}}
+}
+
+// subQuerySQL returns the predicate of c on db
+func subQuerySQL(db *gorm.DB, c SubQueryCondition) (string, []interface{}, error) {
+	sub, args, err := c.Query.expr()
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch c.Op {
+	case gpa.OpExists:
+		return "EXISTS " + sub, args, nil
+	case gpa.OpNotExists:
+		return "NOT EXISTS " + sub, args, nil
+	}
+
+	field, err := fieldExpr(db, c.FieldName)
+	if err != nil {
+		return "", nil, err
+	}
+	switch c.Op {
+	case gpa.OpInSubQuery, gpa.OpIn:
+		return field + " IN " + sub, args, nil
+	case gpa.OpNotInSubQuery, gpa.OpNotIn:
+		return field + " NOT IN " + sub, args, nil
+	default:
+		return "", nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "unsupported subquery operator: "+string(c.Op))
+	}
+}
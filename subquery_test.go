@@ -0,0 +1,114 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestWhereInSubQuery(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	if err := provider.db.AutoMigrate(&testAuditEntry{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	users := []*TestUser{
+		{Name: "Ann", Email: "ann@example.com"},
+		{Name: "Bob", Email: "bob@example.com"},
+		{Name: "Cid", Email: "cid@example.com"},
+	}
+	repo := NewRepository[TestUser](provider.db, provider)
+	if err := repo.CreateBatch(ctx, users); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	uid := func(u *TestUser) *uint { id := u.ID; return &id }
+	audit := NewRepository[testAuditEntry](provider.db, provider)
+	if err := audit.CreateBatch(ctx, []*testAuditEntry{
+		{UserID: uid(users[0]), Action: "login"},
+		{UserID: uid(users[1]), Action: "logout"},
+		{UserID: uid(users[0]), Action: "login"},
+	}); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+
+	logins := audit.SubQuery(ctx, "user_id", gpa.Where("action", gpa.OpEqual, "login"))
+	found, err := repo.Query(ctx, WhereInSubQuery("id", logins))
+	if err != nil || len(found) != 1 || found[0].Name != "Ann" {
+		t.Errorf("Expected Ann, got %+v (%v)", found, err)
+	}
+
+	active := audit.SubQuery(ctx, "user_id")
+	if n, err := repo.Count(ctx, WhereNotInSubQuery("id", active)); err != nil || n != 1 {
+		t.Errorf("Expected 1 user without audit entries, got %d (%v)", n, err)
+	}
+
+	if err := repo.DeleteByCondition(ctx, SubQueryCondition{FieldName: "id", Op: gpa.OpInSubQuery, Query: active}); err != nil {
+		t.Fatalf("DeleteByCondition failed: %v", err)
+	}
+	if left, err := repo.FindAll(ctx); err != nil || len(left) != 1 || left[0].Name != "Cid" {
+		t.Errorf("Expected only Cid left, got %+v (%v)", left, err)
+	}
+
+	if _, err := repo.Query(ctx, WhereInSubQuery("id", audit.SubQuery(ctx, "user_id; DROP TABLE test_users"))); err == nil {
+		t.Error("Expected an invalid subquery column to be rejected")
+	}
+}
+
+func TestWhereExists(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	if err := provider.db.AutoMigrate(&testAuditEntry{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	users := []*TestUser{
+		{Name: "Ann", Email: "ann@example.com"},
+		{Name: "Bob", Email: "bob@example.com"},
+		{Name: "Cid", Email: "cid@example.com"},
+	}
+	repo := NewRepository[TestUser](provider.db, provider)
+	if err := repo.CreateBatch(ctx, users); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	uid := func(u *TestUser) *uint { id := u.ID; return &id }
+	audit := NewRepository[testAuditEntry](provider.db, provider)
+	if err := audit.CreateBatch(ctx, []*testAuditEntry{
+		{UserID: uid(users[0]), Action: "login"},
+		{UserID: uid(users[1]), Action: "logout"},
+	}); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+
+	names := func(opts ...gpa.QueryOption) []string {
+		t.Helper()
+		found, err := repo.Query(ctx, append(opts, gpa.OrderBy("name", gpa.OrderAsc))...)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		var names []string
+		for _, u := range found {
+			names = append(names, u.Name)
+		}
+		return names
+	}
+
+	// Correlated through the outer table
+	logins := audit.SubQuery(ctx, "", gpa.Where("action", gpa.OpEqual, "login"),
+		RawCondition("test_audit_entries.user_id = test_users.id"))
+	if got := names(WhereExists(logins)); len(got) != 1 || got[0] != "Ann" {
+		t.Errorf("Expected Ann, got %v", got)
+	}
+
+	audited := RawSubQuery("SELECT 1 FROM test_audit_entries WHERE test_audit_entries.user_id = test_users.id AND action != ?", "system")
+	if got := names(WhereNotExists(audited)); len(got) != 1 || got[0] != "Cid" {
+		t.Errorf("Expected Cid, got %v", got)
+	}
+
+	if _, err := repo.Query(ctx, WhereExists(RawSubQuery(" "))); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for an empty subquery, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+// Package gpagorm provides table synchronization from a desired state
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// SyncOptions controls how Sync reconciles a table
+type SyncOptions struct {
+	Scope    gpa.Condition // Restricts the rows considered part of the synced set
+	NoDelete bool          // Keep rows that are missing from the desired slice
+	DryRun   bool          // Compute the report without changing anything
+}
+
+// SyncReport summarizes the changes made (or planned) by Sync
+type SyncReport struct {
+	Inserted  int
+	Updated   int
+	Deleted   int
+	Unchanged int
+}
+
+// Sync makes the rows of T (optionally limited by opts.Scope) match desired,
+// identifying rows by keyColumns. Missing rows are inserted, rows whose
+// other columns differ are updated, and rows absent from desired are
+// deleted unless opts.NoDelete is set. All changes run in one transaction.
+// Policies and validation hooks apply; other entity hooks are not run.
+func (r *Repository[T]) Sync(ctx context.Context, desired []*T, keyColumns []string, opts SyncOptions) (report SyncReport, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationSync, Entity: desired, Condition: opts.Scope}, func(ctx context.Context) error {
+		var err error
+		report, err = r.sync(ctx, desired, keyColumns, opts)
+		return err
+	})
+	return report, err
+}
+
+// sync implements Sync
+func (r *Repository[T]) sync(ctx context.Context, desired []*T, keyColumns []string, opts SyncOptions) (SyncReport, error) {
+	var report SyncReport
+
+	if len(keyColumns) == 0 {
+		return report, gpa.NewError(gpa.ErrorTypeInvalidArgument, "sync requires at least one key column")
+	}
+	if !opts.DryRun {
+		if err := r.checkWritable(); err != nil {
+			return report, err
+		}
+	}
+	s, err := r.schema()
+	if err != nil {
+		return report, err
+	}
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return report, gpa.NewError(gpa.ErrorTypeUnsupported, "entity has no single primary key")
+	}
+
+	keyFields := make([]*schema.Field, 0, len(keyColumns))
+	isKey := make(map[string]bool, len(keyColumns))
+	for _, column := range keyColumns {
+		field := s.LookUpField(column)
+		if field == nil || field.DBName == "" {
+			return report, gpa.NewError(gpa.ErrorTypeInvalidArgument, "unknown key column: "+column)
+		}
+		keyFields = append(keyFields, field)
+		isKey[field.DBName] = true
+	}
+
+	// Columns compared to decide whether a row needs an update
+	var compareFields []*schema.Field
+	for _, field := range s.Fields {
+		if field.DBName == "" || field.PrimaryKey || isKey[field.DBName] ||
+			field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 {
+			continue
+		}
+		compareFields = append(compareFields, field)
+	}
+
+	err = r.session(ctx).Transaction(func(tx *gorm.DB) error {
+		var zero T
+		query := tx.Model(&zero)
+		if opts.Scope != nil {
+			query = r.applyCondition(query, opts.Scope)
+		}
+		var existing []*T
+		if err := query.Find(&existing).Error; err != nil {
+			return err
+		}
+
+		existingByKey := make(map[string]*T, len(existing))
+		for _, entity := range existing {
+			existingByKey[syncKey(ctx, keyFields, entity)] = entity
+		}
+
+		var inserts []*T
+		seen := make(map[string]bool, len(desired))
+		for _, entity := range desired {
+			key := syncKey(ctx, keyFields, entity)
+			if seen[key] {
+				return gpa.NewError(gpa.ErrorTypeInvalidArgument, "duplicate key in desired rows: "+key)
+			}
+			seen[key] = true
+
+			current, ok := existingByKey[key]
+			if !ok {
+				inserts = append(inserts, entity)
+				continue
+			}
+
+			changed := changedColumns(ctx, compareFields, current, entity)
+			if len(changed) == 0 {
+				report.Unchanged++
+				continue
+			}
+
+			report.Updated++
+			if opts.DryRun {
+				continue
+			}
+			if err := r.authorizeUpdate(ctx, current); err != nil {
+				return err
+			}
+			if hook, ok := any(entity).(gpa.ValidationHook); ok {
+				if err := hook.Validate(ctx); err != nil {
+					return gpa.NewErrorWithCause(gpa.ErrorTypeValidation, "validation failed", err)
+				}
+			}
+
+			// Adopt the stored primary key and write only the changed columns
+			pkValue, _ := pk.ValueOf(ctx, reflect.ValueOf(current).Elem())
+			if err := pk.Set(ctx, reflect.ValueOf(entity).Elem(), pkValue); err != nil {
+				return err
+			}
+			if err := tx.Model(entity).Select(changed).Updates(entity).Error; err != nil {
+				return err
+			}
+			r.recordChange(ctx, ChangeUpdated, []interface{}{pkValue})
+		}
+
+		report.Inserted = len(inserts)
+		if !opts.DryRun && len(inserts) > 0 {
+			if err := r.prepareCreate(ctx, inserts); err != nil {
+				return err
+			}
+			if err := tx.CreateInBatches(inserts, 100).Error; err != nil {
+				return err
+			}
+			r.recordEntityChange(ctx, ChangeCreated, inserts...)
+		}
+
+		if opts.NoDelete {
+			return nil
+		}
+		for key, entity := range existingByKey {
+			if seen[key] {
+				continue
+			}
+			report.Deleted++
+			if opts.DryRun {
+				continue
+			}
+			if err := r.authorizeDelete(ctx, entity); err != nil {
+				return err
+			}
+			if err := tx.Delete(entity).Error; err != nil {
+				return err
+			}
+			r.recordEntityChange(ctx, ChangeDeleted, entity)
+		}
+		return nil
+	})
+	if err != nil {
+		return SyncReport{}, convertGormError(err)
+	}
+	return report, nil
+}
+
+// syncKey builds the identity of entity from its key fields
+func syncKey[T any](ctx context.Context, keyFields []*schema.Field, entity *T) string {
+	rv := reflect.ValueOf(entity).Elem()
+	parts := make([]string, len(keyFields))
+	for i, field := range keyFields {
+		value, _ := field.ValueOf(ctx, rv)
+		parts[i] = fmt.Sprintf("%v", value)
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+// changedColumns returns the columns whose values differ between entities
+func changedColumns[T any](ctx context.Context, fields []*schema.Field, current, desired *T) []string {
+	currentRV := reflect.ValueOf(current).Elem()
+	desiredRV := reflect.ValueOf(desired).Elem()
+
+	var changed []string
+	for _, field := range fields {
+		a, _ := field.ValueOf(ctx, currentRV)
+		b, _ := field.ValueOf(ctx, desiredRV)
+		if !reflect.DeepEqual(a, b) {
+			changed = append(changed, field.DBName)
+		}
+	}
+	return changed
+}
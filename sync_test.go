@@ -0,0 +1,59 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestRepositorySync(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	err := repo.CreateBatch(ctx, []*TestUser{
+		{Name: "Keep", Email: "keep@example.com", Age: 20},
+		{Name: "Change", Email: "change@example.com", Age: 20},
+		{Name: "Remove", Email: "remove@example.com", Age: 20},
+	})
+	if err != nil {
+		t.Fatalf("Failed to seed users: %v", err)
+	}
+
+	desired := []*TestUser{
+		{Name: "Keep", Email: "keep@example.com", Age: 20},
+		{Name: "Change", Email: "change@example.com", Age: 21},
+		{Name: "Add", Email: "add@example.com", Age: 22},
+	}
+
+	plan, err := repo.Sync(ctx, desired, []string{"email"}, SyncOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if count, _ := repo.Count(ctx); count != 3 {
+		t.Errorf("Expected dry run to leave 3 users, got %d", count)
+	}
+
+	report, err := repo.Sync(ctx, desired, []string{"email"}, SyncOptions{})
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	expected := SyncReport{Inserted: 1, Updated: 1, Deleted: 1, Unchanged: 1}
+	if report != expected || plan != expected {
+		t.Errorf("Expected report %+v, got %+v (plan %+v)", expected, report, plan)
+	}
+
+	changed, err := repo.QueryOne(ctx, gpa.Where("email", gpa.OpEqual, "change@example.com"))
+	if err != nil {
+		t.Fatalf("Failed to load changed user: %v", err)
+	}
+	if changed.Age != 21 {
+		t.Errorf("Expected age 21, got %d", changed.Age)
+	}
+	if exists, _ := repo.Exists(ctx, gpa.Where("email", gpa.OpEqual, "remove@example.com")); exists {
+		t.Error("Expected removed user to be deleted")
+	}
+}
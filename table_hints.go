@@ -0,0 +1,88 @@
+// Package gpagorm provides SQL Server table hints
+package gpagorm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TableHintOption adds SQL Server table hints to a query's table, e.g.
+// WITH (NOLOCK). It carries no state for gpa.Query; buildQuery reads it.
+type TableHintOption struct {
+	Hints []string
+}
+
+// Apply implements gpa.QueryOption
+func (o TableHintOption) Apply(query *gpa.Query) {}
+
+// TableHint adds table hints such as "NOLOCK", "UPDLOCK", "ROWLOCK" or
+// "INDEX(ix_users_email)" to the query's table on SQL Server. Other
+// databases have no table hints and ignore them; use a transaction's
+// isolation level or row locking there instead.
+func TableHint(hints ...string) gpa.QueryOption {
+	return TableHintOption{Hints: hints}
+}
+
+// tableHints are the SQL Server table hints TableHint accepts, besides
+// INDEX(...) and FORCESEEK(...)
+var tableHints = map[string]bool{
+	"NOLOCK": true, "READUNCOMMITTED": true, "READCOMMITTED": true,
+	"READCOMMITTEDLOCK": true, "REPEATABLEREAD": true, "SERIALIZABLE": true,
+	"HOLDLOCK": true, "SNAPSHOT": true, "UPDLOCK": true, "XLOCK": true,
+	"ROWLOCK": true, "PAGLOCK": true, "TABLOCK": true, "TABLOCKX": true,
+	"READPAST": true, "NOWAIT": true, "NOEXPAND": true, "FORCESEEK": true,
+	"FORCESCAN": true, "KEEPIDENTITY": true, "KEEPDEFAULTS": true,
+	"IGNORE_CONSTRAINTS": true, "IGNORE_TRIGGERS": true,
+}
+
+// indexHintPattern matches INDEX(...) and FORCESEEK(...) hints naming
+// indexes or columns
+var indexHintPattern = regexp.MustCompile(`(?i)^(INDEX|FORCESEEK)\s*\([A-Za-z0-9_, ()]+\)$`)
+
+// applyTableHints adds the hints of the TableHintOptions in opts to the
+// query's table on SQL Server
+func applyTableHints(db *gorm.DB, opts []gpa.QueryOption) *gorm.DB {
+	var hints []string
+	for _, opt := range opts {
+		if hint, ok := opt.(TableHintOption); ok {
+			hints = append(hints, hint.Hints...)
+		}
+	}
+	if len(hints) == 0 {
+		return db
+	}
+	for i, hint := range hints {
+		hint = strings.TrimSpace(hint)
+		if !tableHints[strings.ToUpper(hint)] && !indexHintPattern.MatchString(hint) {
+			db.AddError(gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("invalid table hint: %s", hint)))
+			return db
+		}
+		hints[i] = hint
+	}
+	if dialectName(db) != "sqlserver" {
+		return db
+	}
+	return db.Table("? WITH ("+strings.Join(hints, ", ")+")", hintedTable{table: db.Statement.TableExpr})
+}
+
+// hintedTable writes the table a hint applies to: the table override, if
+// any, or the entity's table
+type hintedTable struct {
+	table *clause.Expr
+}
+
+// Build implements clause.Expression
+func (t hintedTable) Build(builder clause.Builder) {
+	if t.table != nil {
+		t.table.Build(builder)
+		return
+	}
+	if stmt, ok := builder.(*gorm.Statement); ok {
+		builder.WriteQuoted(stmt.Table)
+	}
+}
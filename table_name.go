@@ -0,0 +1,53 @@
+// Package gpagorm provides schema-qualified table targeting
+package gpagorm
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// WithTable returns a copy of the repository that reads and writes table
+// instead of the entity's default table. The name may be qualified by
+// schema or database, e.g. "analytics.events" or "otherdb.dbo.users" on
+// SQL Server; each part is quoted separately.
+func (r *Repository[T]) WithTable(table string) *Repository[T] {
+	if !isValidTableName(table) {
+		db := r.db.Session(&gorm.Session{})
+		db.AddError(&FieldValidationError{
+			Field:  table,
+			Reason: "table name contains invalid characters or doesn't follow naming rules",
+		})
+		return &Repository[T]{db: db, provider: r.provider}
+	}
+
+	repo := r.clone()
+	repo.db = withQualifiedTable(r.db, table)
+	repo.table = table
+	return repo
+}
+
+// withQualifiedTable targets table on db. The statement keeps the full
+// qualified name as its table too, because some drivers (SQLite's INSERT,
+// the migrators' table lookups) read the table name instead of the
+// quoted table expression.
+func withQualifiedTable(db *gorm.DB, table string) *gorm.DB {
+	tx := db.Table(table)
+	tx.Statement.Table = table
+	return tx.Session(&gorm.Session{})
+}
+
+// quoteField quotes a validated field reference for the dialect; a
+// qualified one such as "events.user_id" is quoted part by part
+func quoteField(db *gorm.DB, field string) string {
+	return db.Statement.Quote(field)
+}
+
+// quoteTable quotes a qualified table name in a join; anything else, such
+// as a table followed by an alias, is left as written
+func quoteTable(db *gorm.DB, table string) string {
+	if !strings.Contains(table, ".") || !isValidTableName(table) {
+		return table
+	}
+	return db.Statement.Quote(table)
+}
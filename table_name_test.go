@@ -0,0 +1,78 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+func TestRepositoryWithTable(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// An attached database acts as a schema; keep one connection so it stays attached
+	sqlDB, _ := provider.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := provider.db.Exec("ATTACH DATABASE ':memory:' AS archive").Error; err != nil {
+		t.Fatalf("Failed to attach database: %v", err)
+	}
+
+	if err := provider.db.Exec("CREATE TABLE archive.test_users (id integer PRIMARY KEY AUTOINCREMENT, name text, email text, age integer)").Error; err != nil {
+		t.Fatalf("Failed to create qualified table: %v", err)
+	}
+
+	repo := NewRepository[TestUser](provider.db, provider).WithTable("archive.test_users")
+
+	if err := repo.Create(ctx, &TestUser{Name: "Old", Email: "old@example.com", Age: 70}); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+
+	users, err := repo.Query(ctx, gpa.Where("archive.test_users.age", gpa.OpGreaterThan, 60))
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if len(users) != 1 || users[0].Name != "Old" {
+		t.Errorf("Expected archived user, got %+v", users)
+	}
+
+	if count, _ := NewRepository[TestUser](provider.db, provider).Count(ctx); count != 0 {
+		t.Errorf("Expected default table to be untouched, got %d rows", count)
+	}
+
+	err = repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		return tx.Create(ctx, &TestUser{Name: "Older", Email: "older@example.com", Age: 80})
+	})
+	if err != nil {
+		t.Fatalf("Failed to create in transaction: %v", err)
+	}
+	if count, _ := repo.Count(ctx); count != 2 {
+		t.Errorf("Expected transaction to write the qualified table, got %d rows", count)
+	}
+}
+
+func TestRepositoryWithTableQuoting(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	repo := NewRepository[TestUser](provider.db, provider).WithTable("analytics.events")
+	var users []*TestUser
+	stmt := repo.buildQuery(context.Background(),
+		gpa.Where("analytics.events.age", gpa.OpEqual, 1),
+		gpa.Join(gpa.JoinInner, "analytics.sessions", "sessions.user_id = events.id"),
+	).Session(&gorm.Session{DryRun: true}).Find(&users).Statement
+	sql := stmt.SQL.String()
+
+	for _, want := range []string{"FROM `analytics`.`events`", "JOIN `analytics`.`sessions`", "`analytics`.`events`.`age` = ?"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("Expected %q in %s", want, sql)
+		}
+	}
+
+	if err := repo.WithTable("events; DROP TABLE users").Create(context.Background(), &TestUser{}); err == nil {
+		t.Error("Expected invalid table name to be rejected")
+	}
+}
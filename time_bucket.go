@@ -0,0 +1,166 @@
+// Package gpagorm provides time-bucket grouping for time-series rollups
+package gpagorm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// defaultBucketAlias names the bucket column when no alias is set
+const defaultBucketAlias = "bucket"
+
+// TimeBucketOption groups rows by the time bucket of Field and selects the
+// bucket start as Alias. It carries no state for gpa.Query; buildQuery
+// reads it.
+type TimeBucketOption struct {
+	Field string
+	Width time.Duration
+	Alias string // Column of the bucket start, "bucket" when empty
+}
+
+// Apply implements gpa.QueryOption
+func (o TimeBucketOption) Apply(query *gpa.Query) {}
+
+// GroupByTimeBucket groups rows by the width-wide time bucket of field and
+// selects each bucket's start as "bucket", after the fields of gpa.Select:
+//
+//	hourly, err := gpagorm.Project[HourlyCount](ctx, repo,
+//	    gpa.Select("COUNT(*) AS events"),
+//	    gpagorm.GroupByTimeBucket("created_at", time.Hour),
+//	    gpa.OrderBy("bucket", gpa.OrderAsc))
+//
+// Seconds, minutes, hours, days and weeks (starting on Monday) truncate
+// with date_trunc on Postgres, DATE_FORMAT on MySQL, strftime on SQLite
+// and DATEADD on SQL Server. Other widths, such as 15*time.Minute, must be
+// whole seconds and are aligned to the Unix epoch. SQLite returns the
+// bucket as text formatted "2006-01-02 15:04:05"; the other databases
+// return a timestamp.
+func GroupByTimeBucket(field string, width time.Duration) gpa.QueryOption {
+	return TimeBucketOption{Field: field, Width: width}
+}
+
+// timeBucketsFromOptions returns the TimeBucketOptions in opts
+func timeBucketsFromOptions(opts []gpa.QueryOption) []TimeBucketOption {
+	var buckets []TimeBucketOption
+	for _, opt := range opts {
+		if bucket, ok := opt.(TimeBucketOption); ok {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets
+}
+
+// alias returns the column the bucket start is selected as
+func (o TimeBucketOption) alias() string {
+	if o.Alias == "" {
+		return defaultBucketAlias
+	}
+	return o.Alias
+}
+
+// expr returns the SQL expression of the bucket start on db's dialect
+func (o TimeBucketOption) expr(db *gorm.DB) (string, error) {
+	field, err := fieldExpr(db, o.Field)
+	if err != nil {
+		return "", err
+	}
+	if !isValidFieldName(o.alias()) || strings.Contains(o.alias(), ".") {
+		return "", &FieldValidationError{
+			Field:  o.alias(),
+			Reason: "bucket alias must be a plain column name",
+		}
+	}
+	if o.Width < time.Second || o.Width%time.Second != 0 {
+		return "", gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("time bucket width must be a whole number of seconds, got %s", o.Width))
+	}
+
+	unit := bucketUnit(o.Width)
+	seconds := int64(o.Width / time.Second)
+
+	switch dialectName(db) {
+	case "postgres":
+		if unit != "" {
+			return fmt.Sprintf("date_trunc('%s', %s)", unit, field), nil
+		}
+		return fmt.Sprintf("to_timestamp(floor(extract(epoch from %s) / %d) * %d)", field, seconds, seconds), nil
+	case "mysql":
+		switch unit {
+		case "week":
+			return fmt.Sprintf("CAST(DATE_FORMAT(DATE_SUB(%s, INTERVAL WEEKDAY(%s) DAY), '%%Y-%%m-%%d') AS DATETIME)", field, field), nil
+		case "":
+			return fmt.Sprintf("FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(%s) / %d) * %d)", field, seconds, seconds), nil
+		}
+		return fmt.Sprintf("CAST(DATE_FORMAT(%s, '%s') AS DATETIME)", field, mysqlBucketFormats[unit]), nil
+	case "sqlite":
+		switch unit {
+		case "week":
+			return fmt.Sprintf("strftime('%%Y-%%m-%%d 00:00:00', %s, 'weekday 0', '-6 days')", field), nil
+		case "":
+			return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:%%M:%%S', (CAST(strftime('%%s', %s) AS INTEGER) / %d) * %d, 'unixepoch')", field, seconds, seconds), nil
+		}
+		return fmt.Sprintf("strftime('%s', %s)", sqliteBucketFormats[unit], field), nil
+	case "sqlserver":
+		switch unit {
+		case "minute", "hour", "day":
+			return fmt.Sprintf("DATEADD(%s, DATEDIFF(%s, 0, %s), 0)", unit, unit, field), nil
+		case "week":
+			// Day 0, 1900-01-01, was a Monday
+			return fmt.Sprintf("DATEADD(week, DATEDIFF(day, 0, %s) / 7, 0)", field), nil
+		}
+		return fmt.Sprintf("DATEADD(second, (DATEDIFF(second, '2000-01-01', %s) / %d) * %d, '2000-01-01')", field, seconds, seconds), nil
+	default:
+		return "", gpa.NewError(gpa.ErrorTypeUnsupported, "time buckets are not supported on "+dialectName(db))
+	}
+}
+
+// bucketUnit returns the calendar unit width truncates to, or "" for
+// widths aligned to the epoch
+func bucketUnit(width time.Duration) string {
+	switch width {
+	case time.Second:
+		return "second"
+	case time.Minute:
+		return "minute"
+	case time.Hour:
+		return "hour"
+	case 24 * time.Hour:
+		return "day"
+	case 7 * 24 * time.Hour:
+		return "week"
+	}
+	return ""
+}
+
+var mysqlBucketFormats = map[string]string{
+	"second": "%Y-%m-%d %H:%i:%s",
+	"minute": "%Y-%m-%d %H:%i:00",
+	"hour":   "%Y-%m-%d %H:00:00",
+	"day":    "%Y-%m-%d",
+}
+
+var sqliteBucketFormats = map[string]string{
+	"second": "%Y-%m-%d %H:%M:%S",
+	"minute": "%Y-%m-%d %H:%M:00",
+	"hour":   "%Y-%m-%d %H:00:00",
+	"day":    "%Y-%m-%d 00:00:00",
+}
+
+// applyTimeBuckets groups db by the buckets and returns the select
+// expressions naming them
+func applyTimeBuckets(db *gorm.DB, buckets []TimeBucketOption) (*gorm.DB, []string) {
+	var selects []string
+	for _, bucket := range buckets {
+		expr, err := bucket.expr(db)
+		if err != nil {
+			db.AddError(err)
+			return db, nil
+		}
+		db = db.Group(expr)
+		selects = append(selects, expr+" AS "+bucket.alias())
+	}
+	return db, selects
+}
@@ -0,0 +1,107 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+)
+
+type testPageView struct {
+	ID       uint
+	Path     string
+	ViewedAt time.Time
+}
+
+type testBucketCount struct {
+	Bucket string
+	Views  int
+}
+
+func TestGroupByTimeBucket(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&testPageView{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[testPageView](provider.db, provider)
+	ctx := context.Background()
+
+	// Wednesday 2024-05-15
+	base := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+	for _, offset := range []time.Duration{5 * time.Minute, 20 * time.Minute, 50 * time.Minute, 70 * time.Minute, 26 * time.Hour} {
+		if err := repo.Create(ctx, &testPageView{Path: "/", ViewedAt: base.Add(offset)}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	rollup := func(width time.Duration) string {
+		t.Helper()
+		rows, err := Project[testBucketCount](ctx, repo, gpa.Select("COUNT(*) AS views"),
+			GroupByTimeBucket("viewed_at", width), gpa.OrderBy("bucket", gpa.OrderAsc))
+		if err != nil {
+			t.Fatalf("Project failed: %v", err)
+		}
+		var parts []string
+		for _, row := range rows {
+			parts = append(parts, fmt.Sprintf("%s=%d", row.Bucket, row.Views))
+		}
+		return strings.Join(parts, ", ")
+	}
+	if got, want := rollup(time.Hour), "2024-05-15 10:00:00=3, 2024-05-15 11:00:00=1, 2024-05-16 12:00:00=1"; got != want {
+		t.Errorf("Expected hourly buckets %s, got %s", want, got)
+	}
+	if got, want := rollup(15*time.Minute), "2024-05-15 10:00:00=1, 2024-05-15 10:15:00=1, 2024-05-15 10:45:00=1, 2024-05-15 11:00:00=1, 2024-05-16 12:00:00=1"; got != want {
+		t.Errorf("Expected quarter-hour buckets %s, got %s", want, got)
+	}
+	if got, want := rollup(24*time.Hour), "2024-05-15 00:00:00=4, 2024-05-16 00:00:00=1"; got != want {
+		t.Errorf("Expected daily buckets %s, got %s", want, got)
+	}
+	if got, want := rollup(7*24*time.Hour), "2024-05-13 00:00:00=5"; got != want {
+		t.Errorf("Expected weekly buckets %s, got %s", want, got)
+	}
+
+	if _, err := Project[testBucketCount](ctx, repo, GroupByTimeBucket("viewed_at", 1500*time.Millisecond)); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for a fractional width, got %v", err)
+	}
+}
+
+func TestGroupByTimeBucketDialects(t *testing.T) {
+	dialectors := map[string]gorm.Dialector{
+		"postgres":  postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}),
+		"mysql":     mysql.New(mysql.Config{DSN: "user@tcp(localhost)/test", SkipInitializeWithVersion: true}),
+		"sqlserver": sqlserver.Open("sqlserver://localhost?database=test"),
+	}
+	tests := []struct {
+		dialect string
+		width   time.Duration
+		want    string
+	}{
+		{"postgres", time.Hour, `SELECT COUNT(*) AS views,date_trunc('hour', "viewed_at") AS bucket FROM "test_page_views" GROUP BY date_trunc('hour', "viewed_at")`},
+		{"postgres", 15 * time.Minute, `to_timestamp(floor(extract(epoch from "viewed_at") / 900) * 900) AS bucket`},
+		{"mysql", time.Hour, "CAST(DATE_FORMAT(`viewed_at`, '%Y-%m-%d %H:00:00') AS DATETIME) AS bucket"},
+		{"mysql", 7 * 24 * time.Hour, "CAST(DATE_FORMAT(DATE_SUB(`viewed_at`, INTERVAL WEEKDAY(`viewed_at`) DAY), '%Y-%m-%d') AS DATETIME) AS bucket"},
+		{"sqlserver", 24 * time.Hour, `GROUP BY DATEADD(day, DATEDIFF(day, 0, "viewed_at"), 0)`},
+		{"sqlserver", 5 * time.Minute, `DATEADD(second, (DATEDIFF(second, '2000-01-01', "viewed_at") / 300) * 300, '2000-01-01') AS bucket`},
+	}
+	for _, tt := range tests {
+		db, err := gorm.Open(dialectors[tt.dialect], &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+		if err != nil {
+			t.Fatalf("Failed to open dry-run %s: %v", tt.dialect, err)
+		}
+		repo := NewRepository[testPageView](db, nil)
+		var rows []testBucketCount
+		sql := repo.buildQuery(context.Background(), gpa.Select("COUNT(*) AS views"), GroupByTimeBucket("viewed_at", tt.width)).
+			Model(&testPageView{}).Find(&rows).Statement.SQL.String()
+		if !strings.Contains(sql, tt.want) {
+			t.Errorf("%s %s: expected %s in %s", tt.dialect, tt.width, tt.want, sql)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Package gpagorm provides timezone-safe timestamp handling
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// timeZoneOption returns the configured "time_zone" option, if any
+func timeZoneOption(gormOpts map[string]interface{}) string {
+	tz, _ := gormOpts["time_zone"].(string)
+	return tz
+}
+
+// configureTimeZone sets up UTC/zone normalization for writes. It is a
+// no-op unless Options["gorm"]["time_zone"] is set. Auto timestamps use
+// the zone through NowFunc; "normalize_time_on_write" (default true) also
+// converts explicitly set time.Time fields before create and update.
+func configureTimeZone(gormConfig *gorm.Config, gormOpts map[string]interface{}) (*time.Location, error) {
+	tz := timeZoneOption(gormOpts)
+	if tz == "" {
+		return nil, nil
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time_zone: %w", err)
+	}
+	gormConfig.NowFunc = func() time.Time {
+		return time.Now().In(loc)
+	}
+	return loc, nil
+}
+
+// registerTimeNormalization registers callbacks converting time fields to loc
+func registerTimeNormalization(db *gorm.DB, loc *time.Location) error {
+	normalize := func(db *gorm.DB) {
+		if db.Statement.Schema == nil || !db.Statement.ReflectValue.IsValid() {
+			return
+		}
+		rv := reflect.Indirect(db.Statement.ReflectValue)
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < rv.Len(); i++ {
+				normalizeTimeFields(db, reflect.Indirect(rv.Index(i)), loc)
+			}
+		case reflect.Struct:
+			normalizeTimeFields(db, rv, loc)
+		}
+	}
+
+	if err := db.Callback().Create().Before("gorm:create").Register("gpagorm:normalize_time", normalize); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("gpagorm:normalize_time", normalize)
+}
+
+// normalizeTimeFields converts time.Time and *time.Time fields of rv to loc
+func normalizeTimeFields(db *gorm.DB, rv reflect.Value, loc *time.Location) {
+	ctx := db.Statement.Context
+	for _, field := range db.Statement.Schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		value, isZero := field.ValueOf(ctx, rv)
+		if isZero {
+			continue
+		}
+		switch t := value.(type) {
+		case time.Time:
+			if t.Location() != loc {
+				_ = field.Set(ctx, rv, t.In(loc))
+			}
+		case *time.Time:
+			if t != nil && t.Location() != loc {
+				converted := t.In(loc)
+				_ = field.Set(ctx, rv, &converted)
+			}
+		}
+	}
+}
+
+// CheckTimeZone compares the database session time zone with the configured
+// "time_zone" option and returns an error describing any difference.
+// Offsets are compared at the current instant.
+func (p *Provider) CheckTimeZone(ctx context.Context) error {
+	if p.timeLocation == nil {
+		return nil
+	}
+
+	_, expected := time.Now().In(p.timeLocation).Zone()
+	var actual int
+	var label string
+
+	db := p.db.WithContext(ctx)
+	switch dialectName(p.db) {
+	case "postgres":
+		if err := db.Raw("SELECT current_setting('TimeZone')").Scan(&label).Error; err != nil {
+			return convertGormError(err)
+		}
+		if err := db.Raw("SELECT EXTRACT(TIMEZONE FROM now())::int").Scan(&actual).Error; err != nil {
+			return convertGormError(err)
+		}
+	case "mysql":
+		if err := db.Raw("SELECT @@session.time_zone").Scan(&label).Error; err != nil {
+			return convertGormError(err)
+		}
+		if err := db.Raw("SELECT TIMESTAMPDIFF(SECOND, UTC_TIMESTAMP(), NOW())").Scan(&actual).Error; err != nil {
+			return convertGormError(err)
+		}
+	case "sqlserver":
+		if err := db.Raw("SELECT DATEPART(TZOFFSET, SYSDATETIMEOFFSET()) * 60").Scan(&actual).Error; err != nil {
+			return convertGormError(err)
+		}
+		label = fmt.Sprintf("%+d minutes", actual/60)
+	default:
+		// SQLite has no session time zone; values are stored as written
+		return nil
+	}
+
+	if actual != expected {
+		return fmt.Errorf("database session time zone %s (offset %ds) differs from configured %s (offset %ds)",
+			strings.TrimSpace(label), actual, p.timeLocation, expected)
+	}
+	return nil
+}
+
+// verifyTimeZone runs CheckTimeZone at startup, warning on mismatch or
+// failing when Options["gorm"]["time_zone_strict"] is true
+func (p *Provider) verifyTimeZone(gormOpts map[string]interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHealthCheckTimeout)
+	defer cancel()
+
+	err := p.CheckTimeZone(ctx)
+	if err == nil {
+		return nil
+	}
+	if strict, _ := gormOpts["time_zone_strict"].(bool); strict {
+		return err
+	}
+	slog.Default().Warn("time zone mismatch", slog.String("error", err.Error()))
+	return nil
+}
@@ -0,0 +1,93 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+type TestEvent struct {
+	ID         uint `gorm:"primaryKey"`
+	OccurredAt time.Time
+	CreatedAt  time.Time
+}
+
+func TestTimeZoneNormalization(t *testing.T) {
+	config := gpa.Config{
+		Driver:   "sqlite",
+		Database: ":memory:",
+		Options: map[string]interface{}{
+			"gorm": map[string]interface{}{
+				"time_zone": "UTC",
+			},
+		},
+	}
+
+	provider, err := NewProvider(config)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	defer provider.Close()
+
+	if err := provider.db.AutoMigrate(&TestEvent{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	repo := NewRepository[TestEvent](provider.db, provider)
+	tokyo := time.FixedZone("JST", 9*60*60)
+	event := &TestEvent{OccurredAt: time.Date(2024, 1, 1, 9, 0, 0, 0, tokyo)}
+	if err := repo.Create(context.Background(), event); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	if event.OccurredAt.Location() != time.UTC {
+		t.Errorf("Expected OccurredAt in UTC, got %v", event.OccurredAt.Location())
+	}
+	if event.OccurredAt.Hour() != 0 {
+		t.Errorf("Expected hour 0 UTC, got %d", event.OccurredAt.Hour())
+	}
+	if event.CreatedAt.Location() != time.UTC {
+		t.Errorf("Expected CreatedAt in UTC, got %v", event.CreatedAt.Location())
+	}
+}
+
+func TestTimeZoneDSN(t *testing.T) {
+	config := gpa.Config{
+		Host:     "localhost",
+		Port:     3306,
+		Username: "user",
+		Password: "pass",
+		Database: "testdb",
+		Options: map[string]interface{}{
+			"gorm": map[string]interface{}{
+				"time_zone": "America/New_York",
+			},
+		},
+	}
+
+	if dsn := buildMySQLDSN(config); !strings.HasSuffix(dsn, "&loc=America%2FNew_York") {
+		t.Errorf("Expected MySQL DSN to carry loc, got '%s'", dsn)
+	}
+	if dsn := buildPostgresDSN(config); !strings.HasSuffix(dsn, " TimeZone=America/New_York") {
+		t.Errorf("Expected Postgres DSN to carry TimeZone, got '%s'", dsn)
+	}
+}
+
+func TestInvalidTimeZone(t *testing.T) {
+	config := gpa.Config{
+		Driver:   "sqlite",
+		Database: ":memory:",
+		Options: map[string]interface{}{
+			"gorm": map[string]interface{}{
+				"time_zone": "Mars/Olympus",
+			},
+		},
+	}
+
+	if _, err := NewProvider(config); err == nil {
+		t.Error("Expected error for invalid time zone")
+	}
+}
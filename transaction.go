@@ -0,0 +1,109 @@
+// Package gpagorm provides transactions with explicit isolation and access modes
+package gpagorm
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+type deferrableTxKey struct{}
+
+// WithDeferrableTx marks transactions started with ctx as DEFERRABLE.
+// On Postgres a SERIALIZABLE READ ONLY DEFERRABLE transaction waits for a
+// safe snapshot and then never fails with a serialization error, which
+// suits long consistent reports. Other dialects ignore the flag.
+func WithDeferrableTx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deferrableTxKey{}, true)
+}
+
+// isDeferrableTx reports whether ctx requests a deferrable transaction
+func isDeferrableTx(ctx context.Context) bool {
+	deferrable, _ := ctx.Value(deferrableTxKey{}).(bool)
+	return deferrable
+}
+
+// toSQLTxOptions converts GPA transaction options to database/sql options
+func toSQLTxOptions(opts *gpa.TxOptions) *sql.TxOptions {
+	if opts == nil {
+		return nil
+	}
+
+	sqlOpts := &sql.TxOptions{
+		ReadOnly: opts.ReadOnly,
+	}
+
+	switch opts.IsolationLevel {
+	case gpa.IsolationReadUncommitted:
+		sqlOpts.Isolation = sql.LevelReadUncommitted
+	case gpa.IsolationReadCommitted:
+		sqlOpts.Isolation = sql.LevelReadCommitted
+	case gpa.IsolationRepeatableRead:
+		sqlOpts.Isolation = sql.LevelRepeatableRead
+	case gpa.IsolationSerializable:
+		sqlOpts.Isolation = sql.LevelSerializable
+	default:
+		sqlOpts.Isolation = sql.LevelDefault
+	}
+
+	return sqlOpts
+}
+
+// runTransaction runs fn in a transaction on db honoring opts, including
+// the transaction timeout and the deferrable flag carried by ctx. The
+// session variables of provider for ctx are set once the transaction's
+// mode is.
+func runTransaction(ctx context.Context, provider *Provider, db *gorm.DB, opts *gpa.TxOptions, fn func(ctx context.Context, tx *gorm.DB) error) error {
+	if opts != nil && opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	sqlOpts := toSQLTxOptions(opts)
+	var txOpts []*sql.TxOptions
+	if sqlOpts != nil {
+		txOpts = append(txOpts, sqlOpts)
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if isDeferrableTx(ctx) && dialectName(tx) == "postgres" {
+			// Must be the first statement of the transaction
+			mode := "SET TRANSACTION DEFERRABLE"
+			if sqlOpts != nil && sqlOpts.ReadOnly && sqlOpts.Isolation == sql.LevelSerializable {
+				mode = "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE READ ONLY DEFERRABLE"
+			}
+			if err := tx.Exec(mode).Error; err != nil {
+				return convertGormError(err)
+			}
+		}
+		if err := provider.applyTxSessionVars(ctx, tx); err != nil {
+			return err
+		}
+		return fn(ctx, tx)
+	}, txOpts...)
+}
+
+// TransactionWithOptions executes fn within a transaction using the given
+// isolation level, access mode and timeout. A nil opts behaves like Transaction.
+func (r *Repository[T]) TransactionWithOptions(ctx context.Context, opts *gpa.TxOptions, fn gpa.TransactionFunc[T]) error {
+	return r.intercept(ctx, OperationInfo{Operation: OperationTransaction}, func(ctx context.Context) error {
+		return r.transaction(ctx, opts, fn)
+	})
+}
+
+// TransactionWithOptions executes fn within a transaction on the provider's
+// primary connection using the given isolation level, access mode and timeout.
+// Panics in fn are handled as in Repository.Transaction.
+func (p *Provider) TransactionWithOptions(ctx context.Context, opts *gpa.TxOptions, fn func(tx *gorm.DB) error) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			recoverTransactionPanic(value, "", p.transactionPanicsAsErrors(), &err)
+		}
+	}()
+	return runTransaction(ctx, p, p.db, opts, func(ctx context.Context, tx *gorm.DB) error {
+		return fn(tx)
+	})
+}
@@ -0,0 +1,58 @@
+// Package gpagorm provides panic handling for transactions
+package gpagorm
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/lemmego/gpa"
+)
+
+// TransactionPanic is raised again, or returned as the cause of a
+// transaction error, when the function passed to a transaction panics.
+// The transaction has been rolled back and its connection returned to the
+// pool by then.
+type TransactionPanic struct {
+	Entity string      // Entity type of the repository, empty for Provider transactions
+	Value  interface{} // Value passed to panic
+	Stack  []byte      // Stack of the panicking goroutine
+}
+
+// Error implements the error interface
+func (p *TransactionPanic) Error() string {
+	if p.Entity == "" {
+		return fmt.Sprintf("transaction panicked and was rolled back: %v", p.Value)
+	}
+	return fmt.Sprintf("transaction on %s panicked and was rolled back: %v", p.Entity, p.Value)
+}
+
+// Unwrap returns the panic value when it is an error
+func (p *TransactionPanic) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// transactionPanicsAsErrors reports whether the
+// "transaction_panics_as_errors" option is set
+func (p *Provider) transactionPanicsAsErrors() bool {
+	if p == nil {
+		return false
+	}
+	enabled, _ := gormOptions(p.currentConfig())["transaction_panics_as_errors"].(bool)
+	return enabled
+}
+
+// recoverTransactionPanic handles a panic recovered from a transaction of
+// entity: it is raised again as a TransactionPanic, or stored in err as a
+// transaction error when asErrors is set. Nested transactions pass an
+// existing TransactionPanic on unchanged.
+func recoverTransactionPanic(value interface{}, entity string, asErrors bool, err *error) {
+	txPanic, ok := value.(*TransactionPanic)
+	if !ok {
+		txPanic = &TransactionPanic{Entity: entity, Value: value, Stack: debug.Stack()}
+	}
+	if !asErrors {
+		panic(txPanic)
+	}
+	*err = gpa.NewErrorWithCause(gpa.ErrorTypeTransaction, "transaction panicked", txPanic)
+}
@@ -0,0 +1,78 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+func TestTransactionPanic(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	boom := errors.New("boom")
+	func() {
+		defer func() {
+			txPanic, ok := recover().(*TransactionPanic)
+			if !ok {
+				t.Fatalf("Expected a TransactionPanic, got %v", txPanic)
+			}
+			if txPanic.Entity != "TestUser" || !errors.Is(txPanic, boom) || len(txPanic.Stack) == 0 {
+				t.Errorf("Unexpected panic %+v", txPanic)
+			}
+		}()
+		repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+			if err := tx.Create(ctx, &TestUser{Name: "Alice", Email: "alice@example.com"}); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			panic(boom)
+		})
+	}()
+
+	if count, err := repo.Count(ctx); err != nil || count != 0 {
+		t.Errorf("Expected the insert to be rolled back, got %d (%v)", count, err)
+	}
+	sqlDB, _ := provider.db.DB()
+	if inUse := sqlDB.Stats().InUse; inUse != 0 {
+		t.Errorf("Expected the connection back in the pool, %d in use", inUse)
+	}
+}
+
+func TestTransactionPanicsAsErrors(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	provider.config.Options = map[string]interface{}{
+		"gorm": map[string]interface{}{"transaction_panics_as_errors": true},
+	}
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	err := repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		if err := tx.Create(ctx, &TestUser{Name: "Alice", Email: "alice@example.com"}); err != nil {
+			return err
+		}
+		panic("worker failed")
+	})
+	if !gpa.IsTransaction(err) {
+		t.Fatalf("Expected a transaction error, got %v", err)
+	}
+	var txPanic *TransactionPanic
+	if !errors.As(err, &txPanic) || txPanic.Value != "worker failed" {
+		t.Errorf("Expected the panic as cause, got %v", err)
+	}
+	if count, _ := repo.Count(ctx); count != 0 {
+		t.Errorf("Expected the insert to be rolled back, got %d rows", count)
+	}
+
+	err = provider.TransactionWithOptions(ctx, nil, func(tx *gorm.DB) error {
+		panic("provider worker failed")
+	})
+	if !errors.As(err, &txPanic) || txPanic.Entity != "" {
+		t.Errorf("Expected a provider transaction panic, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package gpagorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+func TestRepositoryTransactionWithOptions(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	opts := &gpa.TxOptions{IsolationLevel: gpa.IsolationSerializable, Timeout: time.Second}
+	err := repo.TransactionWithOptions(ctx, opts, func(tx gpa.Transaction[TestUser]) error {
+		return tx.Create(ctx, &TestUser{Name: "John", Email: "john@example.com", Age: 30})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	count, err := repo.Count(ctx)
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 user, got %d", count)
+	}
+}
+
+func TestProviderTransactionWithOptionsRollback(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	ctx := WithDeferrableTx(context.Background())
+	errAbort := errors.New("abort")
+
+	err := provider.TransactionWithOptions(ctx, nil, func(tx *gorm.DB) error {
+		if err := tx.Create(&TestUser{Name: "Jane", Email: "jane@example.com"}).Error; err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Expected abort error, got %v", err)
+	}
+
+	var count int64
+	provider.db.Model(&TestUser{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected rollback to leave 0 users, got %d", count)
+	}
+}
+
+func TestTransactionWithOptionsAppliesSessionVarsInside(t *testing.T) {
+	cassette := &Cassette{Dialect: "postgres", ServerVersion: "16.2", Interactions: []Interaction{
+		{Query: "BEGIN"},
+		{Query: "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE READ ONLY DEFERRABLE"},
+		{Query: "SELECT set_config($1, $2, true)", Args: []interface{}{"app.tenant_id", "acme"}},
+		{Query: "SELECT set_config($1, $2, true)", Args: []interface{}{"app.tenant_id", "acme"}},
+		{Query: `SELECT count(*) FROM "test_users"`, Columns: []string{"count"}, Rows: [][]interface{}{{3}}},
+		{Query: "COMMIT"},
+	}}
+	provider, err := NewReplayProvider(cassette)
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+	var statements []string
+	provider.db.Callback().Raw().After("gorm:raw").Register("test:statements", func(db *gorm.DB) {
+		statements = append(statements, db.Statement.SQL.String())
+	})
+
+	// The transaction is opened with its options, not as a savepoint of a
+	// session variable transaction, and the variables follow its mode
+	ctx := WithDeferrableTx(WithSessionVars(context.Background(), map[string]string{"app.tenant_id": "acme"}))
+	opts := &gpa.TxOptions{IsolationLevel: gpa.IsolationSerializable, ReadOnly: true}
+	err = NewRepository[TestUser](provider.db, provider).TransactionWithOptions(ctx, opts, func(tx gpa.Transaction[TestUser]) error {
+		_, err := tx.Count(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if remaining := provider.ReplayRemaining(); len(remaining) != 0 {
+		t.Errorf("Expected every recorded statement to run, %d remain: %v", len(remaining), remaining)
+	}
+	if len(statements) < 2 || statements[0] != "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE READ ONLY DEFERRABLE" {
+		t.Errorf("Expected the transaction mode to be set first, got %v", statements)
+	}
+}
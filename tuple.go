@@ -0,0 +1,161 @@
+// Package gpagorm provides row value (tuple) comparisons
+package gpagorm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// TupleCondition compares several fields with a row of values, as in
+// (year, month) > (2024, 6). For gpa.OpIn and gpa.OpNotIn, Values holds
+// one []interface{} row per candidate.
+type TupleCondition struct {
+	Fields []string
+	Op     gpa.Operator
+	Values []interface{}
+}
+
+// Field returns the compared fields as a row
+func (c TupleCondition) Field() string { return "(" + strings.Join(c.Fields, ", ") + ")" }
+
+// Operator returns the comparison operator
+func (c TupleCondition) Operator() gpa.Operator { return c.Op }
+
+// Value returns the compared row, or rows for IN
+func (c TupleCondition) Value() interface{} { return c.Values }
+
+// String describes the condition
+func (c TupleCondition) String() string {
+	return fmt.Sprintf("%s %s %v", c.Field(), c.Op, c.Values)
+}
+
+// WhereTuple filters on a row value comparison, e.g. for keyset pagination
+// over a composite sort key:
+//
+//	gpagorm.WhereTuple([]string{"year", "month"}, gpa.OpGreaterThan, []interface{}{2024, 6})
+//
+// Postgres, MySQL and SQLite compare row values natively; elsewhere the
+// comparison is expanded into the equivalent AND/OR conditions.
+func WhereTuple(fields []string, op gpa.Operator, values []interface{}) gpa.QueryOption {
+	return gpa.ConditionOption{Condition: TupleCondition{Fields: fields, Op: op, Values: values}}
+}
+
+// rowValues reports whether the dialect compares row values
+func rowValues(db *gorm.DB) bool {
+	return capabilitiesFor(dialectName(db), "").RowValues
+}
+
+// tupleSQL returns the SQL and arguments of a tuple condition
+func tupleSQL(db *gorm.DB, c TupleCondition) (string, []interface{}, error) {
+	if len(c.Fields) == 0 {
+		return "", nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "tuple condition needs fields")
+	}
+	fields := make([]string, len(c.Fields))
+	for i, field := range c.Fields {
+		expr, err := fieldExpr(db, field)
+		if err != nil {
+			return "", nil, err
+		}
+		fields[i] = expr
+	}
+
+	row := func(values interface{}) ([]interface{}, error) {
+		vals, ok := values.([]interface{})
+		if !ok || len(vals) != len(fields) {
+			return nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("tuple condition needs %d values per row, got %v", len(fields), values))
+		}
+		return vals, nil
+	}
+
+	switch c.Op {
+	case gpa.OpIn, gpa.OpNotIn:
+		if len(c.Values) == 0 {
+			return "", nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "tuple IN needs at least one row")
+		}
+		var terms []string
+		var args []interface{}
+		for _, value := range c.Values {
+			vals, err := row(value)
+			if err != nil {
+				return "", nil, err
+			}
+			sql, rowArgs := tupleEqual(db, fields, vals)
+			terms = append(terms, sql)
+			args = append(args, rowArgs...)
+		}
+		if rowValues(db) {
+			sql := "(" + strings.Join(fields, ", ") + ") IN (" + strings.Join(terms, ", ") + ")"
+			if c.Op == gpa.OpNotIn {
+				sql = "(" + strings.Join(fields, ", ") + ") NOT IN (" + strings.Join(terms, ", ") + ")"
+			}
+			return sql, args, nil
+		}
+		sql := "(" + strings.Join(terms, " OR ") + ")"
+		if c.Op == gpa.OpNotIn {
+			sql = "NOT " + sql
+		}
+		return sql, args, nil
+
+	case gpa.OpEqual, gpa.OpNotEqual, gpa.OpGreaterThan, gpa.OpGreaterThanOrEqual, gpa.OpLessThan, gpa.OpLessThanOrEqual:
+		vals, err := row(c.Values)
+		if err != nil {
+			return "", nil, err
+		}
+		if rowValues(db) {
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(fields)), ", ")
+			return "(" + strings.Join(fields, ", ") + ") " + string(c.Op) + " (" + placeholders + ")", vals, nil
+		}
+		return tupleExpand(db, fields, c.Op, vals)
+
+	default:
+		return "", nil, gpa.NewError(gpa.ErrorTypeInvalidArgument, "unsupported tuple operator: "+string(c.Op))
+	}
+}
+
+// tupleEqual returns a row placeholder where row values are supported, and
+// the equivalent conjunction elsewhere
+func tupleEqual(db *gorm.DB, fields []string, vals []interface{}) (string, []interface{}) {
+	if rowValues(db) {
+		return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(fields)), ", ") + ")", vals
+	}
+	terms := make([]string, len(fields))
+	for i, field := range fields {
+		terms[i] = field + " = ?"
+	}
+	return "(" + strings.Join(terms, " AND ") + ")", vals
+}
+
+// tupleExpand rewrites a row comparison into AND/OR terms: (a, b) > (x, y)
+// becomes a > x OR (a = x AND b > y)
+func tupleExpand(db *gorm.DB, fields []string, op gpa.Operator, vals []interface{}) (string, []interface{}, error) {
+	switch op {
+	case gpa.OpEqual, gpa.OpNotEqual:
+		sql, args := tupleEqual(db, fields, vals)
+		if op == gpa.OpNotEqual {
+			sql = "NOT " + sql
+		}
+		return sql, args, nil
+	}
+
+	strict := strings.TrimSuffix(string(op), "=")
+	var terms []string
+	var args []interface{}
+	for i := range fields {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, fields[j]+" = ?")
+			args = append(args, vals[j])
+		}
+		cmp := strict
+		if i == len(fields)-1 {
+			cmp = string(op)
+		}
+		parts = append(parts, fields[i]+" "+cmp+" ?")
+		args = append(args, vals[i])
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args, nil
+}
@@ -0,0 +1,72 @@
+package gpagorm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+)
+
+type testPeriod struct {
+	ID    uint
+	Year  int
+	Month int
+}
+
+func TestWhereTuple(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&testPeriod{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[testPeriod](provider.db, provider)
+	ctx := context.Background()
+
+	for _, p := range []*testPeriod{{Year: 2023, Month: 12}, {Year: 2024, Month: 5}, {Year: 2024, Month: 6}, {Year: 2024, Month: 7}, {Year: 2025, Month: 1}} {
+		if err := repo.Create(ctx, p); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	count := func(opts ...gpa.QueryOption) int64 {
+		t.Helper()
+		n, err := repo.Count(ctx, opts...)
+		if err != nil {
+			t.Fatalf("Count failed: %v", err)
+		}
+		return n
+	}
+	fields := []string{"year", "month"}
+	if n := count(WhereTuple(fields, gpa.OpGreaterThan, []interface{}{2024, 6})); n != 2 {
+		t.Errorf("Expected 2 periods after 2024-06, got %d", n)
+	}
+	if n := count(WhereTuple(fields, gpa.OpLessThanOrEqual, []interface{}{2024, 6})); n != 3 {
+		t.Errorf("Expected 3 periods up to 2024-06, got %d", n)
+	}
+	if n := count(WhereTuple(fields, gpa.OpIn, []interface{}{[]interface{}{2024, 5}, []interface{}{2025, 1}})); n != 2 {
+		t.Errorf("Expected 2 periods in list, got %d", n)
+	}
+
+	if _, err := repo.Count(ctx, WhereTuple(fields, gpa.OpGreaterThan, []interface{}{2024})); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for short row, got %v", err)
+	}
+}
+
+func TestWhereTupleExpanded(t *testing.T) {
+	db, err := gorm.Open(sqlserver.Open("sqlserver://localhost?database=test"), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry-run sqlserver: %v", err)
+	}
+	repo := NewRepository[testPeriod](db, nil)
+
+	var periods []*testPeriod
+	stmt := repo.buildQuery(context.Background(), WhereTuple([]string{"year", "month", "id"}, gpa.OpGreaterThanOrEqual, []interface{}{2024, 6, 10})).
+		Find(&periods).Statement
+	want := `(("year" > @p1) OR ("year" = @p2 AND "month" > @p3) OR ("year" = @p4 AND "month" = @p5 AND "id" >= @p6))`
+	if !strings.Contains(stmt.SQL.String(), want) || len(stmt.Vars) != 6 {
+		t.Errorf("Unexpected SQL: %s %v", stmt.SQL.String(), stmt.Vars)
+	}
+}
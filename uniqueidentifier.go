@@ -0,0 +1,237 @@
+// Package gpagorm provides SQL Server UNIQUEIDENTIFIER decoding
+package gpagorm
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+)
+
+// uniqueIdentifierConnector opens SQL Server connections whose result rows
+// carry UNIQUEIDENTIFIER values in their canonical string form. The driver
+// returns the 16 bytes as sent on the wire, with the first three groups
+// little-endian, which a scanner cannot tell from the big-endian bytes of
+// BINARY(16) on other dialects; the string form scans into UUID,
+// mssql.UniqueIdentifier and string alike.
+type uniqueIdentifierConnector struct {
+	driver.Connector
+}
+
+// Connect implements driver.Connector
+func (c uniqueIdentifierConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &uniqueIdentifierConn{Conn: conn}, nil
+}
+
+// uniqueIdentifierConn wraps the statements of a driver connection
+type uniqueIdentifierConn struct {
+	driver.Conn
+}
+
+// Prepare implements driver.Conn
+func (c *uniqueIdentifierConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements driver.ConnPrepareContext
+func (c *uniqueIdentifierConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &uniqueIdentifierStmt{Stmt: stmt}, nil
+}
+
+// BeginTx implements driver.ConnBeginTx
+func (c *uniqueIdentifierConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+// Ping implements driver.Pinger
+func (c *uniqueIdentifierConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession implements driver.SessionResetter
+func (c *uniqueIdentifierConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator
+func (c *uniqueIdentifierConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue implements driver.NamedValueChecker, keeping the
+// driver's own parameter types such as mssql.VarChar
+func (c *uniqueIdentifierConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// uniqueIdentifierStmt wraps the rows of a driver statement
+type uniqueIdentifierStmt struct {
+	driver.Stmt
+}
+
+// Query implements driver.Stmt
+func (s *uniqueIdentifierStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.Stmt.Query(args)
+	if err != nil {
+		return nil, err
+	}
+	return &uniqueIdentifierRows{Rows: rows}, nil
+}
+
+// QueryContext implements driver.StmtQueryContext
+func (s *uniqueIdentifierStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := s.Stmt.(driver.StmtQueryContext)
+	if !ok {
+		values := make([]driver.Value, len(args))
+		for i, arg := range args {
+			values[i] = arg.Value
+		}
+		return s.Query(values)
+	}
+	rows, err := queryer.QueryContext(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return &uniqueIdentifierRows{Rows: rows}, nil
+}
+
+// ExecContext implements driver.StmtExecContext
+func (s *uniqueIdentifierStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return s.Stmt.Exec(values)
+}
+
+// uniqueIdentifierRows converts the UNIQUEIDENTIFIER columns of driver rows
+type uniqueIdentifierRows struct {
+	driver.Rows
+	columns []int // UNIQUEIDENTIFIER column indexes, nil until looked up
+}
+
+// Next implements driver.Rows
+func (r *uniqueIdentifierRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return err
+	}
+	if r.columns == nil {
+		r.columns = []int{}
+		if typed, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+			for i := range dest {
+				if typed.ColumnTypeDatabaseTypeName(i) == "UNIQUEIDENTIFIER" {
+					r.columns = append(r.columns, i)
+				}
+			}
+		}
+	}
+	for _, i := range r.columns {
+		if b, ok := dest[i].([]byte); ok && len(b) == 16 {
+			dest[i] = uniqueIdentifierUUID(b).String()
+		}
+	}
+	return nil
+}
+
+// HasNextResultSet implements driver.RowsNextResultSet
+func (r *uniqueIdentifierRows) HasNextResultSet() bool {
+	next, ok := r.Rows.(driver.RowsNextResultSet)
+	return ok && next.HasNextResultSet()
+}
+
+// NextResultSet implements driver.RowsNextResultSet
+func (r *uniqueIdentifierRows) NextResultSet() error {
+	next, ok := r.Rows.(driver.RowsNextResultSet)
+	if !ok {
+		return driver.ErrSkip
+	}
+	r.columns = nil
+	return next.NextResultSet()
+}
+
+// ColumnTypeScanType implements driver.RowsColumnTypeScanType
+func (r *uniqueIdentifierRows) ColumnTypeScanType(index int) reflect.Type {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok && typed.ColumnTypeDatabaseTypeName(index) == "UNIQUEIDENTIFIER" {
+		return reflect.TypeOf("")
+	}
+	if typed, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return typed.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+// ColumnTypeDatabaseTypeName implements driver.RowsColumnTypeDatabaseTypeName
+func (r *uniqueIdentifierRows) ColumnTypeDatabaseTypeName(index int) string {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return typed.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+// ColumnTypeLength implements driver.RowsColumnTypeLength
+func (r *uniqueIdentifierRows) ColumnTypeLength(index int) (int64, bool) {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return typed.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+// ColumnTypePrecisionScale implements driver.RowsColumnTypePrecisionScale
+func (r *uniqueIdentifierRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if typed, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return typed.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+// ColumnTypeNullable implements driver.RowsColumnTypeNullable
+func (r *uniqueIdentifierRows) ColumnTypeNullable(index int) (bool, bool) {
+	if typed, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return typed.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+// uniqueIdentifierUUID reorders the wire bytes of a UNIQUEIDENTIFIER,
+// whose first three groups are little-endian, into a UUID
+func uniqueIdentifierUUID(b []byte) UUID {
+	var u UUID
+	copy(u[:], b)
+	for _, group := range [][]byte{u[0:4], u[4:6], u[6:8]} {
+		for i, j := 0, len(group)-1; i < j; i, j = i+1, j-1 {
+			group[i], group[j] = group[j], group[i]
+		}
+	}
+	return u
+}
@@ -0,0 +1,55 @@
+package gpagorm
+
+import (
+	"database/sql/driver"
+	"io"
+	"testing"
+)
+
+// guidRows returns one row of an id UNIQUEIDENTIFIER and a name column
+type guidRows struct {
+	row  []driver.Value
+	done bool
+}
+
+func (r *guidRows) Columns() []string { return []string{"id", "name"} }
+func (r *guidRows) Close() error      { return nil }
+
+func (r *guidRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
+
+func (r *guidRows) ColumnTypeDatabaseTypeName(index int) string {
+	return []string{"UNIQUEIDENTIFIER", "VARBINARY"}[index]
+}
+
+func TestUniqueIdentifierRows(t *testing.T) {
+	// 6ba7b810-9dad-11d1-80b4-00c04fd430c8 as SQL Server sends it
+	wire := []byte{0x10, 0xb8, 0xa7, 0x6b, 0xad, 0x9d, 0xd1, 0x11, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	binary := append([]byte(nil), wire...)
+	rows := &uniqueIdentifierRows{Rows: &guidRows{row: []driver.Value{wire, binary}}}
+
+	dest := make([]driver.Value, 2)
+	if err := rows.Next(dest); err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	var id UUID
+	if err := id.Scan(dest[0]); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if want := MustParseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8"); id != want {
+		t.Errorf("Expected %s, got %s", want, id)
+	}
+	// Other binary columns are left alone
+	if b, ok := dest[1].([]byte); !ok || string(b) != string(wire) {
+		t.Errorf("Expected the VARBINARY column unchanged, got %v", dest[1])
+	}
+	if err := rows.Next(dest); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last row, got %v", err)
+	}
+}
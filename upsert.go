@@ -0,0 +1,320 @@
+// Package gpagorm provides upserts with a conditional update
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// UpsertOptions configures Upsert
+type UpsertOptions struct {
+	// ConflictColumns identify a row, e.g. an external ID. They need a
+	// unique constraint; MySQL uses whichever unique key conflicts.
+	ConflictColumns []string
+	// UpdateColumns are overwritten when the row exists. Empty updates
+	// every column except the primary key, the conflict columns and the
+	// creation time.
+	UpdateColumns []string
+	// NewerColumn, when set, only overwrites an existing row if the
+	// incoming value of this column, e.g. updated_at or a version, is
+	// greater than the stored one or the stored one is NULL, so events
+	// arriving out of order cannot replace newer state.
+	NewerColumn string
+}
+
+// Upsert inserts entities, updating the rows whose conflict columns
+// already exist, in batches. With NewerColumn set it is last-write-wins:
+//
+//	n, err := repo.Upsert(ctx, events, gpagorm.UpsertOptions{
+//	    ConflictColumns: []string{"external_id"},
+//	    NewerColumn:     "updated_at",
+//	})
+//
+// Postgres and SQLite add the condition as DO UPDATE ... WHERE; MySQL
+// wraps each update in IF(), as ON DUPLICATE KEY UPDATE has no WHERE. SQL
+// Server's MERGE is not supported. A batch must not hold two entities with
+// the same conflict key on Postgres. It returns the rows affected as the
+// driver reports them; MySQL counts an updated row twice.
+func (r *Repository[T]) Upsert(ctx context.Context, entities []*T, opts UpsertOptions) (affected int64, err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationUpsert, Entity: entities}, func(ctx context.Context) error {
+		var err error
+		affected, err = r.upsert(ctx, entities, opts)
+		return err
+	})
+	return affected, err
+}
+
+// upsert implements Upsert
+func (r *Repository[T]) upsert(ctx context.Context, entities []*T, opts UpsertOptions) (int64, error) {
+	if len(entities) == 0 {
+		return 0, nil
+	}
+	db := r.session(ctx)
+	onConflict, err := r.upsertClause(db, opts)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.prepareCreate(ctx, entities); err != nil {
+		return 0, err
+	}
+	if opts.NewerColumn != "" {
+		return r.upsertNewer(ctx, db, onConflict, opts.ConflictColumns, entities)
+	}
+
+	result := db.Clauses(onConflict).CreateInBatches(entities, createBatchSize)
+	if result.Error != nil {
+		return 0, convertGormError(result.Error)
+	}
+	r.finishCreate(ctx, entities)
+	return result.RowsAffected, nil
+}
+
+// upsertNewer runs a last-write-wins upsert. Rows its condition skips
+// return nothing, so GORM's positional key assignment would hand the keys
+// of later rows to earlier entities: the returned keys are matched to the
+// entities by conflict key instead, and the keys of skipped rows are read
+// back. Only the entities that were written are reported as created; on
+// MySQL, which cannot tell skipped rows apart, every entity is.
+func (r *Repository[T]) upsertNewer(ctx context.Context, db *gorm.DB, onConflict clause.OnConflict, conflictColumns []string, entities []*T) (int64, error) {
+	s, err := r.schema()
+	if err != nil {
+		return 0, convertGormError(err)
+	}
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return 0, gpa.NewError(gpa.ErrorTypeUnsupported, "last-write-wins upsert needs a primary key on "+s.Name)
+	}
+	keyFields := make([]*schema.Field, len(conflictColumns))
+	returning := clause.Returning{Columns: []clause.Column{{Name: pk.DBName}}}
+	for i, column := range conflictColumns {
+		if keyFields[i] = s.LookUpField(column); keyFields[i] == nil || keyFields[i].DBName == "" {
+			return 0, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("unknown column %s on %s", column, s.Name))
+		}
+		returning.Columns = append(returning.Columns, clause.Column{Name: keyFields[i].DBName})
+	}
+	restored := append([]*schema.Field{pk}, keyFields...)
+	canReturn := r.checkReturning() == nil
+
+	var affected int64
+	written := make([]*T, 0, len(entities))
+	var skipped []*T
+	for start := 0; start < len(entities); start += createBatchSize {
+		batch := entities[start:min(start+createBatchSize, len(entities))]
+		saved := saveFields(ctx, restored, batch)
+		query := db.Clauses(onConflict)
+		if canReturn {
+			query = query.Clauses(returning)
+		}
+		result := query.Create(&batch)
+		if result.Error != nil {
+			restoreFields(ctx, restored, batch, saved)
+			return affected, convertGormError(result.Error)
+		}
+		affected += result.RowsAffected
+		if !canReturn {
+			restoreFields(ctx, restored, batch, saved)
+			skipped = append(skipped, batch...)
+			written = append(written, batch...)
+			continue
+		}
+
+		// The returned rows fill the first entities, whichever they are
+		keys := make(map[string]reflect.Value, result.RowsAffected)
+		for _, entity := range batch[:min(int(result.RowsAffected), len(batch))] {
+			rv := reflect.ValueOf(entity).Elem()
+			keys[conflictKey(ctx, keyFields, rv)] = copyField(ctx, pk, rv)
+		}
+		restoreFields(ctx, restored, batch, saved)
+		for _, entity := range batch {
+			rv := reflect.ValueOf(entity).Elem()
+			if id, ok := keys[conflictKey(ctx, keyFields, rv)]; ok {
+				pk.ReflectValueOf(ctx, rv).Set(id)
+				written = append(written, entity)
+			} else {
+				skipped = append(skipped, entity)
+			}
+		}
+	}
+
+	if err := r.readUpsertedKeys(ctx, db, pk, keyFields, skipped); err != nil {
+		return affected, err
+	}
+	r.finishCreate(ctx, written)
+	return affected, nil
+}
+
+// readUpsertedKeys sets the primary key of entities to that of the row
+// holding their conflict key
+func (r *Repository[T]) readUpsertedKeys(ctx context.Context, db *gorm.DB, pk *schema.Field, keyFields []*schema.Field, entities []*T) error {
+	columns := []string{db.Statement.Quote(pk.DBName)}
+	quoted := make([]string, len(keyFields))
+	for i, field := range keyFields {
+		quoted[i] = db.Statement.Quote(field.DBName)
+	}
+	columns = append(columns, quoted...)
+
+	for start := 0; start < len(entities); start += createBatchSize {
+		batch := entities[start:min(start+createBatchSize, len(entities))]
+		values := make([]interface{}, len(batch))
+		for i, entity := range batch {
+			rv := reflect.ValueOf(entity).Elem()
+			tuple := make([]interface{}, len(keyFields))
+			for j, field := range keyFields {
+				tuple[j], _ = field.ValueOf(ctx, rv)
+			}
+			values[i] = tuple
+			if len(keyFields) == 1 {
+				values[i] = tuple[0]
+			}
+		}
+		var rows []*T
+		cond := "(" + strings.Join(quoted, ", ") + ") IN ?"
+		if err := db.Session(&gorm.Session{}).Select(columns).Where(cond, values).Find(&rows).Error; err != nil {
+			return convertGormError(err)
+		}
+		keys := make(map[string]reflect.Value, len(rows))
+		for _, row := range rows {
+			rv := reflect.ValueOf(row).Elem()
+			keys[conflictKey(ctx, keyFields, rv)] = copyField(ctx, pk, rv)
+		}
+		for _, entity := range batch {
+			rv := reflect.ValueOf(entity).Elem()
+			if id, ok := keys[conflictKey(ctx, keyFields, rv)]; ok {
+				pk.ReflectValueOf(ctx, rv).Set(id)
+			}
+		}
+	}
+	return nil
+}
+
+// conflictKey returns the conflict columns of an entity as a map key
+func conflictKey(ctx context.Context, fields []*schema.Field, rv reflect.Value) string {
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		values[i], _ = field.ValueOf(ctx, rv)
+	}
+	return fmt.Sprintf("%#v", values)
+}
+
+// copyField returns a copy of the value of field on rv
+func copyField(ctx context.Context, field *schema.Field, rv reflect.Value) reflect.Value {
+	value := reflect.New(field.FieldType).Elem()
+	value.Set(field.ReflectValueOf(ctx, rv))
+	return value
+}
+
+// saveFields copies fields of entities, for restoreFields
+func saveFields[T any](ctx context.Context, fields []*schema.Field, entities []*T) [][]reflect.Value {
+	saved := make([][]reflect.Value, len(entities))
+	for i, entity := range entities {
+		rv := reflect.ValueOf(entity).Elem()
+		for _, field := range fields {
+			saved[i] = append(saved[i], copyField(ctx, field, rv))
+		}
+	}
+	return saved
+}
+
+// restoreFields sets fields of entities back to the values saveFields copied
+func restoreFields[T any](ctx context.Context, fields []*schema.Field, entities []*T, saved [][]reflect.Value) {
+	for i, entity := range entities {
+		rv := reflect.ValueOf(entity).Elem()
+		for j, field := range fields {
+			field.ReflectValueOf(ctx, rv).Set(saved[i][j])
+		}
+	}
+}
+
+// upsertClause returns the ON CONFLICT clause for opts on db's dialect
+func (r *Repository[T]) upsertClause(db *gorm.DB, opts UpsertOptions) (clause.OnConflict, error) {
+	dialect := dialectName(db)
+	if capabilitiesFor(dialect, "").Upsert == UpsertMerge {
+		return clause.OnConflict{}, gpa.NewError(gpa.ErrorTypeUnsupported, "upsert is not supported on "+dialect)
+	}
+	if len(opts.ConflictColumns) == 0 {
+		return clause.OnConflict{}, gpa.NewError(gpa.ErrorTypeInvalidArgument, "upsert needs conflict columns")
+	}
+
+	s, err := r.schema()
+	if err != nil {
+		return clause.OnConflict{}, convertGormError(err)
+	}
+	conflict := map[string]bool{}
+	onConflict := clause.OnConflict{}
+	for _, column := range opts.ConflictColumns {
+		if err := validateFieldName(column); err != nil {
+			return clause.OnConflict{}, gpa.NewErrorWithCause(gpa.ErrorTypeInvalidArgument, "invalid conflict column", err)
+		}
+		conflict[column] = true
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column})
+	}
+
+	updates := opts.UpdateColumns
+	if len(updates) == 0 {
+		for _, field := range s.Fields {
+			if field.DBName == "" || field.PrimaryKey || !field.Updatable || field.AutoCreateTime > 0 || conflict[field.DBName] {
+				continue
+			}
+			updates = append(updates, field.DBName)
+		}
+	}
+	if len(updates) == 0 {
+		return clause.OnConflict{}, gpa.NewError(gpa.ErrorTypeInvalidArgument, "upsert has no columns to update")
+	}
+	checked := updates
+	if opts.NewerColumn != "" {
+		checked = append(append([]string(nil), updates...), opts.NewerColumn)
+	}
+	for _, column := range checked {
+		if err := validateFieldName(column); err != nil {
+			return clause.OnConflict{}, gpa.NewErrorWithCause(gpa.ErrorTypeInvalidArgument, "invalid update column", err)
+		}
+		if s.LookUpField(column) == nil {
+			return clause.OnConflict{}, gpa.NewError(gpa.ErrorTypeInvalidArgument, fmt.Sprintf("unknown column %s on %s", column, s.Name))
+		}
+	}
+
+	newer := opts.NewerColumn
+	if newer == "" {
+		onConflict.DoUpdates = clause.AssignmentColumns(updates)
+		return onConflict, nil
+	}
+
+	if dialect == "mysql" {
+		// Assignments apply left to right, so the compared column goes last
+		// and every condition sees the stored value
+		cond := fmt.Sprintf("(%[1]s IS NULL OR VALUES(%[1]s) > %[1]s)", db.Statement.Quote(newer))
+		var last *clause.Assignment
+		for _, column := range updates {
+			quoted := db.Statement.Quote(column)
+			assignment := clause.Assignment{
+				Column: clause.Column{Name: column},
+				Value:  gorm.Expr(fmt.Sprintf("IF(%s, VALUES(%s), %s)", cond, quoted, quoted)),
+			}
+			if column == newer {
+				last = &assignment
+				continue
+			}
+			onConflict.DoUpdates = append(onConflict.DoUpdates, assignment)
+		}
+		if last != nil {
+			onConflict.DoUpdates = append(onConflict.DoUpdates, *last)
+		}
+		return onConflict, nil
+	}
+
+	onConflict.DoUpdates = clause.AssignmentColumns(updates)
+	stored := clause.Column{Table: clause.CurrentTable, Name: newer}
+	onConflict.Where = clause.Where{Exprs: []clause.Expression{clause.Expr{
+		SQL:  "? IS NULL OR ? > ?",
+		Vars: []interface{}{stored, clause.Column{Table: "excluded", Name: newer}, stored},
+	}}}
+	return onConflict, nil
+}
@@ -0,0 +1,154 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+)
+
+type testShipment struct {
+	ID         uint
+	ExternalID string `gorm:"uniqueIndex;size:64"`
+	Status     string
+	UpdatedAt  time.Time `gorm:"autoUpdateTime:false"`
+}
+
+func TestUpsertNewer(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&testShipment{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[testShipment](provider.db, provider)
+	ctx := context.Background()
+	base := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+	opts := UpsertOptions{ConflictColumns: []string{"external_id"}, NewerColumn: "updated_at"}
+
+	if _, err := repo.Upsert(ctx, []*testShipment{
+		{ExternalID: "a", Status: "shipped", UpdatedAt: base.Add(time.Hour)},
+		{ExternalID: "b", Status: "packed", UpdatedAt: base},
+	}, opts); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	// A stale event for a and a newer one for b arrive
+	if _, err := repo.Upsert(ctx, []*testShipment{
+		{ExternalID: "a", Status: "packed", UpdatedAt: base},
+		{ExternalID: "b", Status: "delivered", UpdatedAt: base.Add(2 * time.Hour)},
+		{ExternalID: "c", Status: "packed", UpdatedAt: base},
+	}, opts); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	shipments, err := repo.Query(ctx, gpa.OrderBy("external_id", gpa.OrderAsc))
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var got []string
+	for _, s := range shipments {
+		got = append(got, s.ExternalID+"="+s.Status)
+	}
+	if want := "a=shipped b=delivered c=packed"; strings.Join(got, " ") != want {
+		t.Errorf("Expected %s, got %s", want, strings.Join(got, " "))
+	}
+
+	// Without NewerColumn the incoming row always wins
+	if _, err := repo.Upsert(ctx, []*testShipment{{ExternalID: "a", Status: "lost", UpdatedAt: base}}, UpsertOptions{ConflictColumns: []string{"external_id"}}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if s, err := repo.QueryOne(ctx, gpa.Where("external_id", gpa.OpEqual, "a")); err != nil || s.Status != "lost" {
+		t.Errorf("Expected a to be overwritten, got %+v (%v)", s, err)
+	}
+
+	if _, err := repo.Upsert(ctx, []*testShipment{{ExternalID: "d"}}, UpsertOptions{ConflictColumns: []string{"external_id"}, NewerColumn: "version"}); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected invalid argument for an unknown column, got %v", err)
+	}
+}
+
+func TestUpsertNewerAssignsKeysByConflictColumn(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	if err := provider.db.AutoMigrate(&testShipment{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[testShipment](provider.db, provider)
+	ctx := context.Background()
+	base := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+	opts := UpsertOptions{ConflictColumns: []string{"external_id"}, NewerColumn: "updated_at"}
+
+	a := &testShipment{ExternalID: "a", Status: "shipped", UpdatedAt: base.Add(time.Hour)}
+	b := &testShipment{ExternalID: "b", Status: "packed", UpdatedAt: base}
+	if _, err := repo.Upsert(ctx, []*testShipment{a, b}, opts); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	var changed []any
+	OnEntityChanged[testShipment](provider, func(ctx context.Context, pks []any) {
+		changed = append(changed, pks...)
+	})
+	staleA := &testShipment{ExternalID: "a", Status: "packed", UpdatedAt: base}
+	newB := &testShipment{ExternalID: "b", Status: "delivered", UpdatedAt: base.Add(time.Hour)}
+	c := &testShipment{ExternalID: "c", Status: "packed", UpdatedAt: base}
+	affected, err := repo.Upsert(ctx, []*testShipment{staleA, newB, c}, opts)
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if affected != 2 {
+		t.Errorf("Expected 2 rows written, got %d", affected)
+	}
+
+	stored, err := repo.QueryOne(ctx, gpa.Where("external_id", gpa.OpEqual, "c"))
+	if err != nil {
+		t.Fatalf("QueryOne failed: %v", err)
+	}
+	if staleA.ID != a.ID || newB.ID != b.ID || c.ID != stored.ID {
+		t.Errorf("Expected the keys %d, %d and %d, got %d, %d and %d", a.ID, b.ID, stored.ID, staleA.ID, newB.ID, c.ID)
+	}
+	if got, want := fmt.Sprint(changed), fmt.Sprint([]any{b.ID, stored.ID}); got != want {
+		t.Errorf("Expected only the written rows %s to be reported, got %s", want, got)
+	}
+}
+
+func TestUpsertNewerDialects(t *testing.T) {
+	dialectors := map[string]gorm.Dialector{
+		"postgres":  postgres.New(postgres.Config{DSN: "host=localhost dbname=test"}),
+		"mysql":     mysql.New(mysql.Config{DSN: "user@tcp(localhost)/test", SkipInitializeWithVersion: true}),
+		"sqlserver": sqlserver.Open("sqlserver://localhost?database=test"),
+	}
+	opts := UpsertOptions{ConflictColumns: []string{"external_id"}, NewerColumn: "updated_at"}
+	tests := map[string]string{
+		"postgres": `ON CONFLICT ("external_id") DO UPDATE SET "status"="excluded"."status","updated_at"="excluded"."updated_at" WHERE "test_shipments"."updated_at" IS NULL OR "excluded"."updated_at" > "test_shipments"."updated_at"`,
+		"mysql":    "ON DUPLICATE KEY UPDATE `status`=IF((`updated_at` IS NULL OR VALUES(`updated_at`) > `updated_at`), VALUES(`status`), `status`),`updated_at`=IF(",
+	}
+	for dialect, want := range tests {
+		db, err := gorm.Open(dialectors[dialect], &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+		if err != nil {
+			t.Fatalf("Failed to open dry-run %s: %v", dialect, err)
+		}
+		repo := NewRepository[testShipment](db, nil)
+		onConflict, err := repo.upsertClause(db, opts)
+		if err != nil {
+			t.Fatalf("%s: upsertClause failed: %v", dialect, err)
+		}
+		stmt := db.Clauses(onConflict).Create(&testShipment{ExternalID: "a"}).Statement
+		if sql := stmt.SQL.String(); !strings.Contains(sql, want) {
+			t.Errorf("%s: expected %s in %s", dialect, want, sql)
+		}
+	}
+
+	db, err := gorm.Open(dialectors["sqlserver"], &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry-run sqlserver: %v", err)
+	}
+	if _, err := NewRepository[testShipment](db, nil).upsertClause(db, opts); !gpa.IsErrorType(err, gpa.ErrorTypeUnsupported) {
+		t.Errorf("Expected unsupported on SQL Server, got %v", err)
+	}
+}
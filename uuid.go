@@ -0,0 +1,161 @@
+// Package gpagorm provides a UUID type stored compactly per dialect
+package gpagorm
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// UUID is a 16-byte UUID stored as BINARY(16) on MySQL and BLOB on SQLite
+// instead of CHAR(36), and as the native uuid / UNIQUEIDENTIFIER types on
+// Postgres and SQL Server. Values are converted transparently when used
+// in conditions, so gpa.Where("id", gpa.OpEqual, id) works on every dialect.
+// SQL Server providers read UNIQUEIDENTIFIER columns in canonical form, as
+// the driver's raw bytes have their first three groups byte-swapped.
+type UUID [16]byte
+
+// NilUUID is the all-zero UUID
+var NilUUID UUID
+
+// NewUUID returns a random (version 4) UUID
+func NewUUID() UUID {
+	var u UUID
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(fmt.Errorf("failed to generate UUID: %w", err))
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return u
+}
+
+// ParseUUID parses the canonical 36-character form or 32 hex digits
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	var digits string
+	switch len(s) {
+	case 36:
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return u, fmt.Errorf("invalid UUID: %q", s)
+		}
+		digits = s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	case 32:
+		digits = s
+	default:
+		return u, fmt.Errorf("invalid UUID: %q", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(digits)); err != nil {
+		return u, fmt.Errorf("invalid UUID: %q", s)
+	}
+	return u, nil
+}
+
+// MustParseUUID is like ParseUUID but panics on invalid input
+func MustParseUUID(s string) UUID {
+	u, err := ParseUUID(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// String returns the canonical 36-character form
+func (u UUID) String() string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf)
+}
+
+// IsNil reports whether u is the all-zero UUID
+func (u UUID) IsNil() bool {
+	return u == NilUUID
+}
+
+// Scan implements sql.Scanner for binary and textual representations
+func (u *UUID) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*u = NilUUID
+		return nil
+	case []byte:
+		if len(v) == 16 {
+			copy(u[:], v)
+			return nil
+		}
+		return u.Scan(string(v))
+	case string:
+		parsed, err := ParseUUID(v)
+		if err != nil {
+			return err
+		}
+		*u = parsed
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into UUID", value)
+	}
+}
+
+// Value implements driver.Valuer using the canonical string form. Writes
+// and conditions built by GORM use GormValue, which picks the compact
+// binary form where the column is binary.
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+// GormValue binds the UUID in the representation of the dialect's column type
+func (u UUID) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	switch dialectName(db) {
+	case "mysql", "sqlite":
+		return clause.Expr{SQL: "?", Vars: []interface{}{u[:]}}
+	default:
+		return clause.Expr{SQL: "?", Vars: []interface{}{u.String()}}
+	}
+}
+
+// GormDataType returns the generic GORM data type
+func (UUID) GormDataType() string {
+	return "uuid"
+}
+
+// GormDBDataType returns the compact column type for each dialect
+func (UUID) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch dialectName(db) {
+	case "mysql":
+		return "BINARY(16)"
+	case "postgres":
+		return "uuid"
+	case "sqlserver":
+		return "UNIQUEIDENTIFIER"
+	default:
+		return "BLOB"
+	}
+}
+
+// MarshalText encodes the UUID in its canonical form
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText decodes the canonical form
+func (u *UUID) UnmarshalText(data []byte) error {
+	parsed, err := ParseUUID(string(data))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
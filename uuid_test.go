@@ -0,0 +1,62 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+type TestDevice struct {
+	ID   UUID `gorm:"primaryKey"`
+	Name string
+}
+
+func TestUUIDParseAndFormat(t *testing.T) {
+	const canonical = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	u, err := ParseUUID(canonical)
+	if err != nil {
+		t.Fatalf("Failed to parse UUID: %v", err)
+	}
+	if u.String() != canonical {
+		t.Errorf("Expected '%s', got '%s'", canonical, u.String())
+	}
+	if _, err := ParseUUID("not-a-uuid"); err == nil {
+		t.Error("Expected error for invalid UUID")
+	}
+
+	generated := NewUUID()
+	if generated.IsNil() || generated[6]>>4 != 4 {
+		t.Errorf("Expected version 4 UUID, got %s", generated)
+	}
+}
+
+func TestUUIDPersistence(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+
+	if err := provider.db.AutoMigrate(&TestDevice{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewRepository[TestDevice](provider.db, provider)
+	ctx := context.Background()
+
+	device := &TestDevice{ID: NewUUID(), Name: "sensor"}
+	if err := repo.Create(ctx, device); err != nil {
+		t.Fatalf("Failed to create device: %v", err)
+	}
+
+	var length int
+	provider.db.Raw("SELECT length(id) FROM test_devices").Scan(&length)
+	if length != 16 {
+		t.Errorf("Expected 16-byte binary storage, got %d bytes", length)
+	}
+
+	found, err := repo.QueryOne(ctx, gpa.Where("id", gpa.OpEqual, device.ID))
+	if err != nil {
+		t.Fatalf("Failed to query by UUID: %v", err)
+	}
+	if found.ID != device.ID {
+		t.Errorf("Expected ID %s, got %s", device.ID, found.ID)
+	}
+}
@@ -0,0 +1,50 @@
+// Package gpagorm provides view-backed repositories with write routing
+package gpagorm
+
+import (
+	"context"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// WithView returns a copy of the repository that reads from view and
+// writes to writeTable, so a denormalized view can back a typed
+// repository. With an empty writeTable, writes are rejected. Both names
+// may be schema-qualified.
+func (r *Repository[T]) WithView(view, writeTable string) *Repository[T] {
+	repo := r
+	if writeTable != "" {
+		repo = r.WithTable(writeTable)
+	}
+
+	if !isValidTableName(view) {
+		db := repo.db.Session(&gorm.Session{})
+		db.AddError(&FieldValidationError{
+			Field:  view,
+			Reason: "view name contains invalid characters or doesn't follow naming rules",
+		})
+		return &Repository[T]{db: db, provider: r.provider}
+	}
+
+	repo = repo.clone()
+	repo.view = view
+	repo.readOnly = writeTable == ""
+	return repo
+}
+
+// readSession returns the session used for reads, targeting the view if set
+func (r *Repository[T]) readSession(ctx context.Context) *gorm.DB {
+	if r.view == "" {
+		return r.session(ctx)
+	}
+	return withQualifiedTable(sessionDB(ctx, r.db, r.provider), r.view)
+}
+
+// checkWritable rejects writes through a view without a write table
+func (r *Repository[T]) checkWritable() error {
+	if r.readOnly {
+		return gpa.NewError(gpa.ErrorTypeUnsupported, "repository is backed by read-only view "+r.view)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package gpagorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestRepositoryWithView(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := provider.db.Exec("CREATE VIEW adult_users AS SELECT * FROM test_users WHERE age >= 18").Error; err != nil {
+		t.Fatalf("Failed to create view: %v", err)
+	}
+
+	repo := NewRepository[TestUser](provider.db, provider).WithView("adult_users", "test_users")
+	minor := &TestUser{Name: "Kid", Email: "kid@example.com", Age: 10}
+	adult := &TestUser{Name: "Ada", Email: "ada@example.com", Age: 30}
+	for _, user := range []*TestUser{minor, adult} {
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Failed to create through view repository: %v", err)
+		}
+	}
+
+	users, err := repo.FindAll(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read view: %v", err)
+	}
+	if len(users) != 1 || users[0].Name != "Ada" {
+		t.Errorf("Expected reads to go through the view, got %+v", users)
+	}
+	if _, err := repo.FindByID(ctx, minor.ID); !gpa.IsNotFound(err) {
+		t.Errorf("Expected row outside the view to be hidden, got %v", err)
+	}
+
+	if err := repo.UpdatePartial(ctx, minor.ID, map[string]interface{}{"age": 18}); err != nil {
+		t.Fatalf("Failed to update base table: %v", err)
+	}
+	if count, _ := repo.Count(ctx); count != 2 {
+		t.Errorf("Expected updated row to appear in the view, got %d", count)
+	}
+
+	err = repo.Transaction(ctx, func(tx gpa.Transaction[TestUser]) error {
+		count, err := tx.Count(ctx, gpa.Where("age", gpa.OpLessThan, 18))
+		if err == nil && count != 0 {
+			t.Errorf("Expected transaction reads to use the view, got %d", count)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to run transaction: %v", err)
+	}
+}
+
+func TestRepositoryWithReadOnlyView(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := provider.db.Exec("CREATE VIEW adult_users AS SELECT * FROM test_users WHERE age >= 18").Error; err != nil {
+		t.Fatalf("Failed to create view: %v", err)
+	}
+
+	repo := NewRepository[TestUser](provider.db, provider).WithView("adult_users", "")
+	err := repo.Create(ctx, &TestUser{Name: "Ada", Email: "ada@example.com", Age: 30})
+	if !gpa.IsErrorType(err, gpa.ErrorTypeUnsupported) {
+		t.Errorf("Expected writes to be rejected, got %v", err)
+	}
+	if err := repo.DeleteByCondition(ctx, gpa.WhereCondition("age", gpa.OpGreaterThan, 0)); !gpa.IsErrorType(err, gpa.ErrorTypeUnsupported) {
+		t.Errorf("Expected deletes to be rejected, got %v", err)
+	}
+	if _, err := repo.FindAll(ctx); err != nil {
+		t.Errorf("Expected reads to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+// Package gpagorm provides window function projections
+package gpagorm
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lemmego/gpa"
+	"gorm.io/gorm"
+)
+
+// windowValueColumn is the column QueryWithWindow selects the window value
+// as
+const windowValueColumn = "gpagorm_window_value"
+
+// windowOrderPattern matches a window ordering: a field and an optional
+// direction, e.g. "created_at DESC"
+var windowOrderPattern = regexp.MustCompile(`(?i)^(\S+?)(?:\s+(ASC|DESC))?$`)
+
+// windowFunctions lists the window functions Window accepts
+var windowFunctions = map[string]bool{
+	"ROW_NUMBER": true, "RANK": true, "DENSE_RANK": true, "PERCENT_RANK": true, "CUME_DIST": true, "NTILE": true,
+	"LAG": true, "LEAD": true, "FIRST_VALUE": true, "LAST_VALUE": true, "NTH_VALUE": true,
+	"SUM": true, "AVG": true, "COUNT": true, "MIN": true, "MAX": true,
+}
+
+// WindowSpec is a window function call computed for every row of a
+// QueryWithWindow, built with Window
+type WindowSpec struct {
+	function    string
+	args        []string
+	partitionBy []string
+	orderBy     []string
+}
+
+// Window returns a window function call. function is a ranking function
+// (ROW_NUMBER, RANK, DENSE_RANK, PERCENT_RANK, CUME_DIST, NTILE), a value
+// function (LAG, LEAD, FIRST_VALUE, LAST_VALUE, NTH_VALUE) or an aggregate
+// (SUM, AVG, COUNT, MIN, MAX); args are fields or integer literals:
+//
+//	gpagorm.Window("ROW_NUMBER").PartitionBy("tenant_id").OrderBy("created_at")
+//	gpagorm.Window("LAG", "amount", "1").OrderBy("created_at")
+//	gpagorm.Window("SUM", "amount").PartitionBy("account_id").OrderBy("created_at") // running total
+func Window(function string, args ...string) *WindowSpec {
+	return &WindowSpec{function: strings.ToUpper(function), args: args}
+}
+
+// PartitionBy restarts the window for every distinct value of fields
+func (w *WindowSpec) PartitionBy(fields ...string) *WindowSpec {
+	w.partitionBy = append(w.partitionBy, fields...)
+	return w
+}
+
+// OrderBy orders the rows within each partition by fields, each
+// optionally followed by ASC or DESC
+func (w *WindowSpec) OrderBy(fields ...string) *WindowSpec {
+	w.orderBy = append(w.orderBy, fields...)
+	return w
+}
+
+// WindowRow is an entity with the window value computed for its row
+type WindowRow[T any] struct {
+	Entity *T
+	// Value as the driver returns it: int64, float64, string or time.Time,
+	// or nil, e.g. for LAG on the first row of a partition
+	Value interface{}
+}
+
+// windowValue scans a window value of any type
+type windowValue struct {
+	value interface{}
+}
+
+// Scan implements sql.Scanner
+func (v *windowValue) Scan(src interface{}) error {
+	if b, ok := src.([]byte); ok {
+		src = string(b)
+	}
+	v.value = src
+	return nil
+}
+
+// windowScan is a row of QueryWithWindow
+type windowScan[T any] struct {
+	Entity T           `gorm:"embedded"`
+	Value  windowValue `gorm:"column:gpagorm_window_value;type:window;->"`
+}
+
+// QueryWithWindow queries the entities matching opts along with the value
+// of a window function over them, for rankings, running totals and
+// comparisons with the previous row without raw SQL:
+//
+//	rows, err := repo.QueryWithWindow(ctx,
+//	    gpagorm.Window("ROW_NUMBER").PartitionBy("tenant_id").OrderBy("created_at DESC"),
+//	    gpa.Where("status", gpa.OpEqual, "paid"))
+//	for _, row := range rows {
+//	    fmt.Println(row.Entity.ID, row.Value)
+//	}
+//
+// The window sees the rows opts filter, before any limit. Window functions
+// need Postgres, MySQL 8, SQL Server or SQLite 3.25.
+func (r *Repository[T]) QueryWithWindow(ctx context.Context, window *WindowSpec, opts ...gpa.QueryOption) (rows []WindowRow[T], err error) {
+	err = r.intercept(ctx, OperationInfo{Operation: OperationQueryWithWindow, Options: opts}, func(ctx context.Context) error {
+		var err error
+		rows, err = r.queryWithWindow(ctx, window, opts...)
+		return err
+	})
+	return rows, err
+}
+
+// queryWithWindow implements QueryWithWindow
+func (r *Repository[T]) queryWithWindow(ctx context.Context, window *WindowSpec, opts ...gpa.QueryOption) ([]WindowRow[T], error) {
+	if err := r.authorizeRead(ctx, opts); err != nil {
+		return nil, err
+	}
+	s, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.buildQuery(ctx, opts...).Model(new(T))
+	expr, err := window.expr(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(query.Statement.Selects) == 0 {
+		table := s.Table
+		if r.view != "" {
+			table = r.view
+		} else if r.table != "" {
+			table = r.table
+		}
+		query = query.Select(query.Statement.Quote(table) + ".*")
+	}
+	query.Statement.Selects = append(query.Statement.Selects, expr+" AS "+windowValueColumn)
+
+	var scanned []windowScan[T]
+	if err := convertGormError(query.Find(&scanned).Error); err != nil {
+		return nil, err
+	}
+	rows := make([]WindowRow[T], len(scanned))
+	for i := range scanned {
+		rows[i] = WindowRow[T]{Entity: &scanned[i].Entity, Value: scanned[i].Value.value}
+	}
+	return rows, nil
+}
+
+// expr validates the window and returns its SQL
+func (w *WindowSpec) expr(db *gorm.DB) (string, error) {
+	if w == nil || !windowFunctions[w.function] {
+		function := ""
+		if w != nil {
+			function = w.function
+		}
+		return "", gpa.NewError(gpa.ErrorTypeInvalidArgument, "unsupported window function: "+function)
+	}
+
+	args := make([]string, len(w.args))
+	for i, arg := range w.args {
+		if _, err := strconv.Atoi(arg); err == nil {
+			args[i] = arg
+			continue
+		}
+		expr, err := fieldExpr(db, arg)
+		if err != nil {
+			return "", err
+		}
+		args[i] = expr
+	}
+	if len(args) == 0 && w.function == "COUNT" {
+		args = []string{"*"}
+	}
+
+	var over []string
+	if len(w.partitionBy) > 0 {
+		fields := make([]string, len(w.partitionBy))
+		for i, field := range w.partitionBy {
+			expr, err := fieldExpr(db, field)
+			if err != nil {
+				return "", err
+			}
+			fields[i] = expr
+		}
+		over = append(over, "PARTITION BY "+strings.Join(fields, ", "))
+	}
+	if len(w.orderBy) > 0 {
+		fields := make([]string, len(w.orderBy))
+		for i, spec := range w.orderBy {
+			m := windowOrderPattern.FindStringSubmatch(strings.TrimSpace(spec))
+			if m == nil {
+				return "", &FieldValidationError{Field: spec, Reason: "window ordering must be a field with an optional direction"}
+			}
+			expr, err := fieldExpr(db, m[1])
+			if err != nil {
+				return "", err
+			}
+			direction, err := orderDirection(gpa.OrderDirection(m[2]))
+			if err != nil {
+				return "", err
+			}
+			fields[i] = expr + " " + direction
+		}
+		over = append(over, "ORDER BY "+strings.Join(fields, ", "))
+	}
+	return w.function + "(" + strings.Join(args, ", ") + ") OVER (" + strings.Join(over, " ") + ")", nil
+}
@@ -0,0 +1,68 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestQueryWithWindow(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+	ctx := context.Background()
+
+	users := []*TestUser{
+		{Name: "Ann", Email: "ann@example.com", Age: 30},
+		{Name: "Bob", Email: "bob@example.com", Age: 30},
+		{Name: "Cid", Email: "cid@example.com", Age: 40},
+		{Name: "Dee", Email: "dee@example.com", Age: 40},
+		{Name: "Eve", Email: "eve@example.com", Age: 40},
+	}
+	if err := repo.CreateBatch(ctx, users); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	format := func(rows []WindowRow[TestUser]) string {
+		parts := make([]string, len(rows))
+		for i, row := range rows {
+			parts[i] = fmt.Sprintf("%s=%v", row.Entity.Name, row.Value)
+		}
+		return strings.Join(parts, ",")
+	}
+
+	rows, err := repo.QueryWithWindow(ctx, Window("ROW_NUMBER").PartitionBy("age").OrderBy("name DESC"),
+		gpa.OrderBy("name", gpa.OrderAsc))
+	if got := format(rows); err != nil || got != "Ann=2,Bob=1,Cid=3,Dee=2,Eve=1" {
+		t.Errorf("Expected row numbers per age, got %s (%v)", got, err)
+	}
+
+	// The previous row's value, nil on the first row
+	rows, err = repo.QueryWithWindow(ctx, Window("lag", "name", "1").OrderBy("id"),
+		gpa.Where("age", gpa.OpEqual, 40), gpa.OrderBy("id", gpa.OrderAsc))
+	if got := format(rows); err != nil || got != "Cid=<nil>,Dee=Cid,Eve=Dee" {
+		t.Errorf("Expected the previous names, got %s (%v)", got, err)
+	}
+
+	rows, err = repo.QueryWithWindow(ctx, Window("COUNT").PartitionBy("age"), gpa.Where("name", gpa.OpNotEqual, "Eve"), gpa.OrderBy("id", gpa.OrderAsc))
+	if got := format(rows); err != nil || got != "Ann=2,Bob=2,Cid=2,Dee=2" {
+		t.Errorf("Expected counts per age, got %s (%v)", got, err)
+	}
+	if rows[0].Entity.Email != "ann@example.com" {
+		t.Errorf("Expected the entity to be scanned, got %+v", rows[0].Entity)
+	}
+
+	for _, window := range []*WindowSpec{
+		Window("PG_SLEEP", "1"),
+		Window("ROW_NUMBER").OrderBy("name; DROP TABLE test_users"),
+		Window("ROW_NUMBER").OrderBy("name SIDEWAYS"),
+		Window("SUM", "age) FROM secrets --"),
+		nil,
+	} {
+		if _, err := repo.QueryWithWindow(ctx, window); err == nil {
+			t.Errorf("Expected %+v to be rejected", window)
+		}
+	}
+}
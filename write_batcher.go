@@ -0,0 +1,168 @@
+// Package gpagorm provides coalescing of concurrent inserts into batches
+package gpagorm
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// WriteBatcher groups Create calls from many goroutines into multi-row
+// inserts, run in one transaction per batch. Each caller waits until its
+// batch is written, trading up to maxDelay of latency for far fewer round
+// trips in telemetry-style workloads.
+type WriteBatcher[T any] struct {
+	repo     *Repository[T]
+	maxBatch int
+	maxDelay time.Duration
+	requests chan writeRequest[T]
+	done     chan struct{}
+	closeMu  sync.RWMutex
+	closed   bool
+}
+
+// writeRequest is an entity waiting to be written
+type writeRequest[T any] struct {
+	ctx    context.Context
+	entity *T
+	result chan error
+}
+
+// NewWriteBatcher starts a batcher that writes a batch once maxBatch
+// entities are queued or maxDelay has passed since the first of them.
+// Batches are written with the context values of their first request.
+// Close must be called to flush the last batch and stop the batcher.
+func NewWriteBatcher[T any](repo *Repository[T], maxBatch int, maxDelay time.Duration) *WriteBatcher[T] {
+	if maxBatch <= 0 {
+		maxBatch = 1
+	}
+	b := &WriteBatcher[T]{
+		repo:     repo,
+		maxBatch: maxBatch,
+		maxDelay: maxDelay,
+		requests: make(chan writeRequest[T], maxBatch),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Create queues entity and waits until its batch is written. If the batch
+// fails, its entities are retried one by one so only the failing ones
+// report an error. When ctx ends first, Create returns a timeout error,
+// but the entity may still be written.
+func (b *WriteBatcher[T]) Create(ctx context.Context, entity *T) error {
+	request := writeRequest[T]{ctx: ctx, entity: entity, result: make(chan error, 1)}
+
+	b.closeMu.RLock()
+	if b.closed {
+		b.closeMu.RUnlock()
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "write batcher is closed")
+	}
+	select {
+	case b.requests <- request:
+		b.closeMu.RUnlock()
+	case <-ctx.Done():
+		b.closeMu.RUnlock()
+		return gpa.NewErrorWithCause(gpa.ErrorTypeTimeout, "operation cancelled", ctx.Err())
+	}
+
+	select {
+	case err := <-request.result:
+		return err
+	case <-ctx.Done():
+		return gpa.NewErrorWithCause(gpa.ErrorTypeTimeout, "operation cancelled", ctx.Err())
+	}
+}
+
+// Close writes the queued entities and stops the batcher
+func (b *WriteBatcher[T]) Close() error {
+	b.closeMu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.requests)
+	}
+	b.closeMu.Unlock()
+	<-b.done
+	return nil
+}
+
+// run collects requests into batches until the request channel closes
+func (b *WriteBatcher[T]) run() {
+	defer close(b.done)
+	var batch []writeRequest[T]
+	timer := time.NewTimer(b.maxDelay)
+	timer.Stop()
+
+	for {
+		select {
+		case request, ok := <-b.requests:
+			if !ok {
+				b.flush(batch)
+				return
+			}
+			batch = append(batch, request)
+			if len(batch) == 1 {
+				timer.Reset(b.maxDelay)
+			}
+			if len(batch) >= b.maxBatch {
+				timer.Stop()
+				b.flush(batch)
+				batch = nil
+			}
+		case <-timer.C:
+			b.flush(batch)
+			batch = nil
+		}
+	}
+}
+
+// flush writes a batch and reports the outcome to its callers
+func (b *WriteBatcher[T]) flush(batch []writeRequest[T]) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx := context.WithoutCancel(batch[0].ctx)
+	entities := make([]*T, len(batch))
+	for i, request := range batch {
+		entities[i] = request.entity
+	}
+	unsetKeys := b.repo.unsetPrimaryKeys(ctx, entities)
+	if err := b.repo.CreateBatch(ctx, entities); err == nil || len(batch) == 1 {
+		for _, request := range batch {
+			request.result <- err
+		}
+		return
+	}
+	// Keys assigned before the rollback would be reused verbatim and skip
+	// the sequence, so clear them before retrying
+	for _, reset := range unsetKeys {
+		reset()
+	}
+	for _, request := range batch {
+		request.result <- b.repo.Create(context.WithoutCancel(request.ctx), request.entity)
+	}
+}
+
+// unsetPrimaryKeys returns functions clearing the primary key of the
+// entities that have none yet
+func (r *Repository[T]) unsetPrimaryKeys(ctx context.Context, entities []*T) []func() {
+	s, err := r.schema()
+	if err != nil || s.PrioritizedPrimaryField == nil {
+		return nil
+	}
+	field := s.PrioritizedPrimaryField
+	var resets []func()
+	for _, entity := range entities {
+		rv := reflect.ValueOf(entity).Elem()
+		if _, zero := field.ValueOf(ctx, rv); zero {
+			resets = append(resets, func() {
+				field.ReflectValueOf(ctx, rv).Set(reflect.Zero(field.FieldType))
+			})
+		}
+	}
+	return resets
+}
@@ -0,0 +1,77 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+func TestWriteBatcher(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	batches := 0
+	provider.AddInterceptor(func(ctx context.Context, op OperationInfo, next func(context.Context) error) error {
+		if op.Operation == OperationCreateBatch {
+			batches++
+		}
+		return next(ctx)
+	})
+
+	batcher := NewWriteBatcher(repo, 10, 50*time.Millisecond)
+	var wg sync.WaitGroup
+	errs := make([]error, 25)
+	users := make([]*TestUser, 25)
+	for i := range users {
+		users[i] = &TestUser{Name: fmt.Sprintf("user%d", i), Email: fmt.Sprintf("user%d@example.com", i)}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = batcher.Create(ctx, users[i])
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil || users[i].ID == 0 {
+			t.Errorf("Expected user %d to be created, got %v", i, err)
+		}
+	}
+	if batches != 3 {
+		t.Errorf("Expected 25 creates in 3 batches, got %d", batches)
+	}
+
+	// A duplicate fails only its own caller
+	errs = make([]error, 3)
+	dupes := []*TestUser{
+		{Name: "new1", Email: "new1@example.com"},
+		{Name: "dup", Email: "user0@example.com"},
+		{Name: "new2", Email: "new2@example.com"},
+	}
+	for i := range dupes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = batcher.Create(ctx, dupes[i])
+		}(i)
+	}
+	wg.Wait()
+	if errs[0] != nil || errs[2] != nil || errs[1] == nil {
+		t.Errorf("Expected only the duplicate to fail, got %v", errs)
+	}
+	if count, _ := repo.Count(ctx); count != 27 {
+		t.Errorf("Expected 27 users, got %d", count)
+	}
+
+	if err := batcher.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if err := batcher.Create(ctx, &TestUser{Email: "late@example.com"}); !gpa.IsErrorType(err, gpa.ErrorTypeInvalidArgument) {
+		t.Errorf("Expected creates after Close to fail, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+// Package gpagorm provides asynchronous write-behind inserts
+package gpagorm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lemmego/gpa"
+)
+
+// WriteBehindConfig configures a WriteBehind queue
+type WriteBehindConfig struct {
+	// MaxBuffer bounds the number of queued entities (default 10000)
+	MaxBuffer int
+	// MaxBatch is the number of entities written per insert (default 500)
+	MaxBatch int
+	// FlushInterval is how often a partial batch is written (default 1s)
+	FlushInterval time.Duration
+	// MaxRetries is how often a failed batch is retried before it is
+	// given up and reported to OnError (default 0)
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, doubled on each
+	// further one (default 100ms)
+	RetryBackoff time.Duration
+	// DropWhenFull drops entities that do not fit the buffer and reports
+	// them to OnError, instead of failing Create
+	DropWhenFull bool
+	// OnError receives the entities that could not be written. It is
+	// called from the flushing goroutine and must not block for long.
+	OnError func(entities []interface{}, err error)
+}
+
+// WriteBehindStats counts the entities handled by a WriteBehind queue
+type WriteBehindStats struct {
+	Pending int   `json:"pending"`
+	Written int64 `json:"written"`
+	Failed  int64 `json:"failed"`
+	Dropped int64 `json:"dropped"`
+}
+
+// WriteBehind queues inserts in memory and writes them in batches in the
+// background. Create returns as soon as the entity is queued, so write
+// errors only reach OnError. It suits low-value, high-volume entities such
+// as metrics or view counters: queued entities are lost if the process
+// dies before they are flushed, so Close must be called on shutdown.
+type WriteBehind[T any] struct {
+	repo   *Repository[T]
+	config WriteBehindConfig
+
+	mu      sync.Mutex
+	buffer  []writeBehindEntry[T]
+	stats   WriteBehindStats
+	closed  bool
+	flushMu sync.Mutex // Serializes batch writes
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// writeBehindEntry is a queued entity with the context it was created in
+type writeBehindEntry[T any] struct {
+	ctx    context.Context
+	entity *T
+}
+
+// WriteBehind starts a write-behind queue for the repository. Only writes
+// made through the returned queue are deferred; the repository itself
+// keeps writing synchronously.
+func (r *Repository[T]) WriteBehind(config WriteBehindConfig) *WriteBehind[T] {
+	if config.MaxBuffer <= 0 {
+		config.MaxBuffer = 10000
+	}
+	if config.MaxBatch <= 0 {
+		config.MaxBatch = 500
+	}
+	if config.MaxBatch > config.MaxBuffer {
+		config.MaxBatch = config.MaxBuffer
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = 100 * time.Millisecond
+	}
+	w := &WriteBehind[T]{
+		repo:   r,
+		config: config,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Create queues entity for writing. When the buffer is full it returns an
+// error, or drops the entity when DropWhenFull is set. Entities are
+// written with the context values they were queued with, but not its
+// cancellation.
+func (w *WriteBehind[T]) Create(ctx context.Context, entity *T) error {
+	return w.CreateBatch(ctx, []*T{entity})
+}
+
+// CreateBatch queues entities for writing; either all of them are queued
+// or none are
+func (w *WriteBehind[T]) CreateBatch(ctx context.Context, entities []*T) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return gpa.NewError(gpa.ErrorTypeInvalidArgument, "write-behind queue is closed")
+	}
+	if len(w.buffer)+len(entities) > w.config.MaxBuffer {
+		if !w.config.DropWhenFull {
+			w.mu.Unlock()
+			return gpa.NewError(gpa.ErrorTypeDatabase, "write-behind buffer is full")
+		}
+		w.stats.Dropped += int64(len(entities))
+		w.mu.Unlock()
+		w.report(entities, gpa.NewError(gpa.ErrorTypeDatabase, "write-behind buffer is full"))
+		return nil
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, entity := range entities {
+		w.buffer = append(w.buffer, writeBehindEntry[T]{ctx: ctx, entity: entity})
+	}
+	full := len(w.buffer) >= w.config.MaxBatch
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush writes every queued entity before returning, or until ctx ends
+func (w *WriteBehind[T]) Flush(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return gpa.NewErrorWithCause(gpa.ErrorTypeTimeout, "operation cancelled", err)
+		}
+		if !w.flushBatch() {
+			return nil
+		}
+	}
+}
+
+// Close stops accepting entities, writes the queued ones and stops the
+// background flusher. Entities still queued when ctx ends are reported to
+// OnError as lost.
+func (w *WriteBehind[T]) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		<-w.done
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.stop)
+	<-w.done
+
+	err := w.Flush(ctx)
+	if err != nil {
+		w.mu.Lock()
+		lost := w.buffer
+		w.buffer = nil
+		w.stats.Failed += int64(len(lost))
+		w.mu.Unlock()
+		entities := make([]*T, len(lost))
+		for i, entry := range lost {
+			entities[i] = entry.entity
+		}
+		w.report(entities, err)
+	}
+	return err
+}
+
+// Stats returns the queue's counters
+func (w *WriteBehind[T]) Stats() WriteBehindStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := w.stats
+	stats.Pending = len(w.buffer)
+	return stats
+}
+
+// run flushes on every interval and whenever a full batch is queued
+func (w *WriteBehind[T]) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			for w.flushBatch() {
+			}
+		case <-w.wake:
+			for w.fullBatchQueued() && w.flushBatch() {
+			}
+		}
+	}
+}
+
+// fullBatchQueued reports whether at least MaxBatch entities are queued
+func (w *WriteBehind[T]) fullBatchQueued() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.buffer) >= w.config.MaxBatch
+}
+
+// flushBatch writes up to MaxBatch queued entities, retrying failed
+// batches, and reports whether there was anything to write. Entities
+// queued with different contexts are written in separate inserts, each
+// with its own context.
+func (w *WriteBehind[T]) flushBatch() bool {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	n := min(len(w.buffer), w.config.MaxBatch)
+	if n == 0 {
+		w.mu.Unlock()
+		return false
+	}
+	batch := make([]writeBehindEntry[T], n)
+	copy(batch, w.buffer)
+	w.buffer = append(w.buffer[:0], w.buffer[n:]...)
+	w.mu.Unlock()
+
+	for start := 0; start < n; {
+		ctx := batch[start].ctx
+		end := start + 1
+		for end < n && batch[end].ctx == ctx {
+			end++
+		}
+		entities := make([]*T, 0, end-start)
+		for _, entry := range batch[start:end] {
+			entities = append(entities, entry.entity)
+		}
+		w.writeRun(ctx, entities)
+		start = end
+	}
+	return true
+}
+
+// writeRun inserts entities queued with ctx, retrying on failure, and
+// records the outcome
+func (w *WriteBehind[T]) writeRun(ctx context.Context, entities []*T) {
+	resets := w.repo.unsetPrimaryKeys(ctx, entities)
+	backoff := w.config.RetryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = w.repo.CreateBatch(ctx, entities); err == nil || attempt >= w.config.MaxRetries {
+			break
+		}
+		// Keys assigned before the rollback would be reused verbatim
+		for _, reset := range resets {
+			reset()
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	w.mu.Lock()
+	if err == nil {
+		w.stats.Written += int64(len(entities))
+	} else {
+		w.stats.Failed += int64(len(entities))
+	}
+	w.mu.Unlock()
+	if err != nil {
+		w.report(entities, err)
+	}
+}
+
+// report passes entities that were not written to OnError
+func (w *WriteBehind[T]) report(entities []*T, err error) {
+	if w.config.OnError == nil {
+		return
+	}
+	values := make([]interface{}, len(entities))
+	for i, entity := range entities {
+		values[i] = entity
+	}
+	w.config.OnError(values, err)
+}
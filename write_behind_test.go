@@ -0,0 +1,153 @@
+package gpagorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteBehind(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	var mu sync.Mutex
+	batches := 0
+	provider.AddInterceptor(func(ctx context.Context, op OperationInfo, next func(context.Context) error) error {
+		if op.Operation == OperationCreateBatch {
+			mu.Lock()
+			batches++
+			mu.Unlock()
+		}
+		return next(ctx)
+	})
+
+	var failed []interface{}
+	queue := repo.WriteBehind(WriteBehindConfig{
+		MaxBuffer:     30,
+		MaxBatch:      10,
+		FlushInterval: time.Hour,
+		OnError: func(entities []interface{}, err error) {
+			mu.Lock()
+			failed = append(failed, entities...)
+			mu.Unlock()
+		},
+	})
+
+	for i := 0; i < 25; i++ {
+		user := &TestUser{Name: fmt.Sprintf("user%d", i), Email: fmt.Sprintf("user%d@example.com", i)}
+		if err := queue.Create(ctx, user); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	if err := queue.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if count, _ := repo.Count(ctx); count != 25 {
+		t.Errorf("Expected 25 users after flush, got %d", count)
+	}
+	if batches != 3 {
+		t.Errorf("Expected 3 batches, got %d", batches)
+	}
+
+	// A failing batch is reported to OnError
+	queue.Create(ctx, &TestUser{Name: "dup", Email: "user0@example.com"})
+	queue.Flush(ctx)
+	if len(failed) != 1 || failed[0].(*TestUser).Name != "dup" {
+		t.Errorf("Expected the duplicate to be reported, got %v", failed)
+	}
+	if stats := queue.Stats(); stats.Written != 25 || stats.Failed != 1 || stats.Pending != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// Close flushes what is still queued and rejects further writes
+	queue.Create(ctx, &TestUser{Name: "last", Email: "last@example.com"})
+	if err := queue.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if count, _ := repo.Count(ctx); count != 26 {
+		t.Errorf("Expected the queued user to be written on close, got %d users", count)
+	}
+	if err := queue.Create(ctx, &TestUser{Name: "late", Email: "late@example.com"}); err == nil {
+		t.Error("Expected Create after Close to fail")
+	}
+}
+
+func TestWriteBehindBoundedBuffer(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	queue := repo.WriteBehind(WriteBehindConfig{MaxBuffer: 2, FlushInterval: time.Hour})
+	defer queue.Close(ctx)
+	users := []*TestUser{
+		{Name: "a", Email: "a@example.com"},
+		{Name: "b", Email: "b@example.com"},
+		{Name: "c", Email: "c@example.com"},
+	}
+	if err := queue.CreateBatch(ctx, users[:2]); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+	if err := queue.Create(ctx, users[2]); err == nil {
+		t.Error("Expected Create on a full buffer to fail")
+	}
+
+	var dropped []interface{}
+	dropping := repo.WriteBehind(WriteBehindConfig{
+		MaxBuffer:     1,
+		FlushInterval: time.Hour,
+		DropWhenFull:  true,
+		OnError:       func(entities []interface{}, err error) { dropped = entities },
+	})
+	defer dropping.Close(ctx)
+	dropping.Create(ctx, &TestUser{Name: "d", Email: "d@example.com"})
+	if err := dropping.Create(ctx, &TestUser{Name: "e", Email: "e@example.com"}); err != nil {
+		t.Errorf("Expected a dropped entity not to fail Create, got %v", err)
+	}
+	if len(dropped) != 1 || dropping.Stats().Dropped != 1 {
+		t.Errorf("Expected one dropped entity, got %v", dropped)
+	}
+}
+
+func TestWriteBehindKeepsEachContext(t *testing.T) {
+	provider, cleanup := setupTestProvider(t)
+	defer cleanup()
+	repo := NewRepository[TestUser](provider.db, provider)
+
+	var mu sync.Mutex
+	var written []string
+	provider.AddInterceptor(func(ctx context.Context, op OperationInfo, next func(context.Context) error) error {
+		if op.Operation == OperationCreateBatch {
+			tenant := SessionVarsFromContext(ctx)["app.tenant_id"]
+			mu.Lock()
+			for _, user := range op.Entity.([]*TestUser) {
+				written = append(written, tenant+":"+user.Name)
+			}
+			mu.Unlock()
+		}
+		return next(ctx)
+	})
+
+	queue := repo.WriteBehind(WriteBehindConfig{MaxBatch: 10, FlushInterval: time.Hour})
+	acme := WithSessionVars(context.Background(), map[string]string{"app.tenant_id": "acme"})
+	globex := WithSessionVars(context.Background(), map[string]string{"app.tenant_id": "globex"})
+	queue.CreateBatch(acme, []*TestUser{{Name: "a1", Email: "a1@example.com"}, {Name: "a2", Email: "a2@example.com"}})
+	queue.Create(globex, &TestUser{Name: "g1", Email: "g1@example.com"})
+	queue.Create(acme, &TestUser{Name: "a3", Email: "a3@example.com"})
+	if err := queue.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	want := "acme:a1,acme:a2,globex:g1,acme:a3"
+	if got := strings.Join(written, ","); got != want {
+		t.Errorf("Expected each entity written with its own session vars, got %s", got)
+	}
+	if stats := queue.Stats(); stats.Written != 4 || stats.Failed != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}